@@ -51,15 +51,15 @@ func TestExpressionParserErrors(t *testing.T) {
 		},
 		"bad args 2": {
 			input: `foo ${!json("foo} bar`,
-			err:   `failed to parse expression: char 12: required one of: [boolean number quoted-string match function null array object variable-path field-path]`,
+			err:   `failed to parse expression: char 12: required one of: [boolean number quoted-string match if function null array object variable-path field-path]`,
 		},
 		"bad args 3": {
 			input: `foo ${!json(} bar`,
-			err:   `failed to parse expression: char 12: required one of: [boolean number quoted-string match function null array object variable-path field-path]`,
+			err:   `failed to parse expression: char 12: required one of: [boolean number quoted-string match if function null array object variable-path field-path]`,
 		},
 		"bad args 4": {
 			input: `foo ${!json(0,} bar`,
-			err:   `failed to parse expression: char 14: required one of: [boolean number quoted-string match function null array object variable-path field-path]`,
+			err:   `failed to parse expression: char 14: required one of: [boolean number quoted-string match if function null array object variable-path field-path]`,
 		},
 		"bad args 5": {
 			input: `foo ${!json} bar`,
@@ -148,6 +148,27 @@ func TestExpressions(t *testing.T) {
 			output:  `\"this\"`,
 			escaped: true,
 		},
+		"if expression": {
+			input:  `foo ${! if json("foo") == "bar" { "yes" } else { "no" } } baz`,
+			output: `foo yes baz`,
+			messages: []easyMsg{
+				{content: `{"foo":"bar"}`},
+			},
+		},
+		"if expression 2": {
+			input:  `${! if json("foo") == "bar" { "yes" } else { "no" } }-${!json("foo")}`,
+			output: `no-baz`,
+			messages: []easyMsg{
+				{content: `{"foo":"baz"}`},
+			},
+		},
+		"match expression": {
+			input:  `${! match json("foo") { "bar" => "first", _ => "second" } }`,
+			output: `first`,
+			messages: []easyMsg{
+				{content: `{"foo":"bar"}`},
+			},
+		},
 		"json function": {
 			input:  `${!json()}`,
 			output: `{"foo":"bar"}`,
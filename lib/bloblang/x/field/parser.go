@@ -27,31 +27,41 @@ func aFunction(input []rune) parser.Result {
 			Remaining: input,
 		}
 	}
-	i := 3
-	for ; i < len(input); i++ {
-		if input[i] == '}' {
-			res := query.ParseDeprecated(input[3:i])
-			if res.Err == nil {
-				if len(res.Remaining) > 0 {
-					return parser.Result{
-						Err: parser.ErrAtPosition(
-							i-len(res.Remaining),
-							fmt.Errorf("unexpected contents at end of expression: %v", string(res.Remaining)),
-						),
-						Remaining: input,
-					}
-				}
+	// Expressions may contain braces themselves (match and if blocks), and
+	// therefore we attempt a parse up to each closing brace until one consumes
+	// the expression entirely. If none do then the first failure is returned.
+	var firstErr *parser.Result
+	for i := 3; i < len(input); i++ {
+		if input[i] != '}' {
+			continue
+		}
+		res := query.ParseDeprecated(input[3:i])
+		if res.Err == nil {
+			if len(res.Remaining) == 0 {
 				res.Remaining = input[i+1:]
 				res.Payload = queryResolver{fn: res.Payload.(query.Function)}
-			} else {
-				res.Err = parser.ErrAtPosition(3, res.Err).Expand(func(err error) error {
-					// Scrap underlying expected error.
-					return fmt.Errorf("%v", err.Error())
-				})
-				res.Remaining = input
+				return res
+			}
+			res = parser.Result{
+				Err: parser.ErrAtPosition(
+					i-len(res.Remaining),
+					fmt.Errorf("unexpected contents at end of expression: %v", string(res.Remaining)),
+				),
+				Remaining: input,
 			}
-			return res
+		} else {
+			res.Err = parser.ErrAtPosition(3, res.Err).Expand(func(err error) error {
+				// Scrap underlying expected error.
+				return fmt.Errorf("%v", err.Error())
+			})
+			res.Remaining = input
 		}
+		if firstErr == nil {
+			firstErr = &res
+		}
+	}
+	if firstErr != nil {
+		return *firstErr
 	}
 	return parser.Result{
 		Payload:   staticResolver(string(input)),
@@ -34,7 +34,21 @@ type mappingStatement struct {
 // message.
 type Executor struct {
 	maps       map[string]query.Function
+	params     []string
 	statements []mappingStatement
+	caches     query.CacheProvider
+}
+
+// SetCaches sets a provider of cache resources to be accessed by functions of
+// the mapping.
+func (e *Executor) SetCaches(caches query.CacheProvider) {
+	e.caches = caches
+}
+
+// Params returns the names of parameters declared by the mapping, which are
+// bound as variables when the mapping is applied with arguments.
+func (e *Executor) Params() []string {
+	return e.params
 }
 
 // MapPart executes the bloblang mapping on a particular message index of a
@@ -59,11 +73,12 @@ func (e *Executor) MapPart(index int, msg Message) (types.Part, error) {
 	var newObj interface{} = query.Nothing(nil)
 	for _, stmt := range e.statements {
 		res, err := stmt.query.Exec(query.FunctionContext{
-			Maps:  e.maps,
-			Value: valuePtr,
-			Vars:  vars,
-			Index: index,
-			Msg:   msg,
+			Maps:   e.maps,
+			Value:  valuePtr,
+			Vars:   vars,
+			Index:  index,
+			Msg:    msg,
+			Caches: e.caches,
 		})
 		if err != nil {
 			return nil, xerrors.Errorf("failed to execute mapping assignment at line %v: %v", stmt.line+1, err)
@@ -105,11 +120,17 @@ func (e *Executor) MapPart(index int, msg Message) (types.Part, error) {
 
 // Exec this function with a context struct.
 func (e *Executor) Exec(ctx query.FunctionContext) (interface{}, error) {
+	// Maps are resolved from the file where this mapping was declared.
+	ctx.Maps = e.maps
+	if ctx.Caches == nil {
+		ctx.Caches = e.caches
+	}
+
 	var newObj interface{} = query.Nothing(nil)
 	for _, stmt := range e.statements {
 		res, err := stmt.query.Exec(ctx)
 		if err != nil {
-			return nil, xerrors.Errorf("failed to execute mapping assignment at line %v: %v", stmt.line, err)
+			return nil, xerrors.Errorf("failed to execute mapping assignment at line %v: %w", stmt.line, err)
 		}
 		if _, isNothing := res.(query.Nothing); isNothing {
 			// Skip assignment entirely
@@ -282,7 +303,8 @@ func parseExecutor(lineIndexes []int) parser.Type {
 		return parser.Result{
 			Remaining: res.Remaining,
 			Payload: &Executor{
-				maps, statements,
+				maps:       maps,
+				statements: statements,
 			},
 		}
 	}
@@ -445,6 +467,7 @@ func mapParser(maps map[string]query.Function) parser.Type {
 				"map-name",
 			),
 		),
+		parser.Optional(mapParamsParser()),
 		parser.SpacesAndTabs(),
 		parser.DelimitedPattern(
 			parser.Sequence(
@@ -477,7 +500,23 @@ func mapParser(maps map[string]query.Function) parser.Type {
 
 		seqSlice := res.Payload.([]interface{})
 		ident := seqSlice[2].(string)
-		stmtSlice := seqSlice[4].([]interface{})
+		stmtSlice := seqSlice[5].([]interface{})
+
+		var params []string
+		if paramSlice, ok := seqSlice[3].([]interface{}); ok {
+			for _, p := range paramSlice {
+				param := p.(string)
+				for _, existing := range params {
+					if existing == param {
+						return parser.Result{
+							Err:       fmt.Errorf("duplicate map parameter: %v", param),
+							Remaining: input,
+						}
+					}
+				}
+				params = append(params, param)
+			}
+		}
 
 		if _, exists := maps[ident]; exists {
 			return parser.Result{
@@ -491,7 +530,11 @@ func mapParser(maps map[string]query.Function) parser.Type {
 			statements[i] = v.(mappingStatement)
 		}
 
-		maps[ident] = &Executor{maps, statements}
+		maps[ident] = &Executor{
+			maps:       maps,
+			params:     params,
+			statements: statements,
+		}
 
 		return parser.Result{
 			Payload:   ident,
@@ -500,6 +543,33 @@ func mapParser(maps map[string]query.Function) parser.Type {
 	}
 }
 
+func mapParamsParser() parser.Type {
+	whitespace := parser.DiscardAll(
+		parser.OneOf(
+			parser.SpacesAndTabs(),
+			parser.NewlineAllowComment(),
+		),
+	)
+
+	return parser.DelimitedPattern(
+		parser.Sequence(
+			parser.Char('('),
+			whitespace,
+		),
+		parser.Expect(varNameParser(), "parameter-name"),
+		parser.Sequence(
+			parser.Discard(parser.SpacesAndTabs()),
+			parser.Char(','),
+			whitespace,
+		),
+		parser.Sequence(
+			whitespace,
+			parser.Char(')'),
+		),
+		false, false,
+	)
+}
+
 func letStatementParser() parser.Type {
 	p := parser.Sequence(
 		parser.Term("let"),
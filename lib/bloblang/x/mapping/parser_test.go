@@ -140,6 +140,13 @@ import "%v"
 foo = bar.apply("foo")`, goodMapFile),
 			err: fmt.Sprintf(`line 3 char 1: map name collisions from import '%v': [foo]`, goodMapFile),
 		},
+		"duplicate map parameters": {
+			mapping: `map foo(a, a) {
+  foo = $a
+}
+foo = bar.apply("foo", 1, 2)`,
+			err: `line 1 char 1: duplicate map parameter: a`,
+		},
 	}
 
 	for name, test := range tests {
@@ -165,6 +172,20 @@ func TestMappings(t *testing.T) {
   nested = this
 }`), 0777))
 
+	treeMapFile := filepath.Join(dir, "tree_map.blobl")
+	require.NoError(t, ioutil.WriteFile(treeMapFile, []byte(`map tree(depth) {
+  root = this.apply("label", $depth)
+  children = match this {
+    this.exists("children") => this.children.map_each(this.apply("tree", $depth + 1))
+    _ => deleted()
+  }
+}
+
+map label(depth) {
+  name = this.name
+  depth = $depth
+}`), 0777))
+
 	type part struct {
 		Content string
 		Meta    map[string]string
@@ -425,6 +446,72 @@ root = this.apply("foo")`, goodMapFile),
 				Content: `{"foo":"this is valid","nested":{"outter":{"inner":"hello world"}}}`,
 			},
 		},
+		"test if expressions": {
+			mapping: `root.size = if this.value > 10 { "big" } else { "small" }
+root.parity = if this.value % 2 == 0 {
+  "even"
+}`,
+			input: []part{
+				{Content: `{"value":15}`},
+			},
+			output: part{
+				Content: `{"size":"big"}`,
+			},
+		},
+		"test parameterised map": {
+			mapping: `map greet(greeting, suffix) {
+  message = "%v %v%v".format($greeting, this.name, $suffix)
+  let greeting = "overridden"
+  greeting = $greeting
+}
+root = this.apply("greet", "hello", "!")`,
+			input: []part{
+				{Content: `{"name":"world"}`},
+			},
+			output: part{
+				Content: `{"greeting":"overridden","message":"hello world!"}`,
+			},
+		},
+		"test dynamic map arguments": {
+			mapping: `map scale(factor) {
+  value = this.value * $factor
+}
+root = this.apply("scale", this.factor)`,
+			input: []part{
+				{Content: `{"factor":3,"value":5}`},
+			},
+			output: part{
+				Content: `{"value":15}`,
+			},
+		},
+		"test recursive map": {
+			mapping: `map depth(d) {
+  name = this.name
+  depth = $d
+  children = match this {
+    this.exists("children") => this.children.map_each(this.apply("depth", $d + 1))
+    _ => deleted()
+  }
+}
+root = this.apply("depth", 0)`,
+			input: []part{
+				{Content: `{"name":"a","children":[{"name":"b","children":[{"name":"c"}]},{"name":"d"}]}`},
+			},
+			output: part{
+				Content: `{"children":[{"children":[{"depth":2,"name":"c"}],"depth":1,"name":"b"},{"depth":1,"name":"d"}],"depth":0,"name":"a"}`,
+			},
+		},
+		"test imported recursive map": {
+			mapping: fmt.Sprintf(`import "%v"
+
+root = this.apply("tree", 0)`, treeMapFile),
+			input: []part{
+				{Content: `{"name":"a","children":[{"name":"b"}]}`},
+			},
+			output: part{
+				Content: `{"children":[{"depth":1,"name":"b"}],"depth":0,"name":"a"}`,
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -461,3 +548,44 @@ root = this.apply("foo")`, goodMapFile),
 		})
 	}
 }
+
+func TestMappingMapExecErrors(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		err     string
+	}{
+		"missing map arguments": {
+			mapping: `map foo(a, b) {
+  foo = $a
+}
+root = this.apply("foo", 1)`,
+			err: "failed to execute mapping assignment at line 4: map foo expects 2 arguments, received 1",
+		},
+		"unexpected map arguments": {
+			mapping: `map foo {
+  foo = this
+}
+root = this.apply("foo", 1)`,
+			err: "failed to execute mapping assignment at line 4: map foo expects 0 arguments, received 1",
+		},
+		"unbounded recursion": {
+			mapping: `map foo {
+  root = this.apply("foo")
+}
+root = this.apply("foo")`,
+			err: "failed to execute mapping assignment at line 4: map foo exceeded maximum depth of 1000 nested map applications",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			exec, err := NewExecutor(test.mapping)
+			require.NoError(t, err)
+
+			_, err = exec.MapPart(0, message.New([][]byte{[]byte(`{}`)}))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -26,3 +26,25 @@ func matchFunction(contextFn Function, cases []matchCase) Function {
 		return Nothing(nil), nil
 	})
 }
+
+func ifFunction(cases []matchCase, elseFn Function) Function {
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		for i, c := range cases {
+			condVal, err := c.caseFn.Exec(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check if condition %v: %w", i, err)
+			}
+			cond, isBool := condVal.(bool)
+			if !isBool {
+				return nil, fmt.Errorf("expected bool value from if condition %v, received %T", i, condVal)
+			}
+			if cond {
+				return c.queryFn.Exec(ctx)
+			}
+		}
+		if elseFn != nil {
+			return elseFn.Exec(ctx)
+		}
+		return Nothing(nil), nil
+	})
+}
@@ -128,6 +128,87 @@ func matchExpressionParser() parser.Type {
 	}
 }
 
+func ifExpressionParser() parser.Type {
+	whitespace := parser.DiscardAll(
+		parser.OneOf(
+			parser.SpacesAndTabs(),
+			parser.NewlineAllowComment(),
+		),
+	)
+
+	block := parser.Sequence(
+		parser.Char('{'),
+		whitespace,
+		Parse,
+		whitespace,
+		parser.Char('}'),
+	)
+
+	ifBranch := parser.Sequence(
+		parser.Term("if"),
+		parser.SpacesAndTabs(),
+		parser.MustBe(Parse),
+		parser.Discard(parser.SpacesAndTabs()),
+		parser.MustBe(block),
+	)
+
+	elseTerm := parser.Sequence(
+		parser.Discard(parser.SpacesAndTabs()),
+		parser.Term("else"),
+		parser.Discard(parser.SpacesAndTabs()),
+	)
+
+	return func(input []rune) parser.Result {
+		res := ifBranch(input)
+		if res.Err != nil {
+			return res
+		}
+
+		cases := []matchCase{}
+		addCase := func(payload interface{}) {
+			seqSlice := payload.([]interface{})
+			cases = append(cases, matchCase{
+				caseFn:  seqSlice[2].(Function),
+				queryFn: seqSlice[4].([]interface{})[2].(Function),
+			})
+		}
+		addCase(res.Payload)
+
+		var elseFn Function
+		for elseFn == nil {
+			elseRes := elseTerm(res.Remaining)
+			if elseRes.Err != nil {
+				break
+			}
+
+			i := len(input) - len(elseRes.Remaining)
+			if ifRes := parser.Term("if")(elseRes.Remaining); ifRes.Err == nil {
+				if res = ifBranch(elseRes.Remaining); res.Err != nil {
+					return parser.Result{
+						Err:       parser.ErrAtPosition(i, res.Err),
+						Remaining: input,
+					}
+				}
+				addCase(res.Payload)
+				continue
+			}
+
+			if res = parser.MustBe(block)(elseRes.Remaining); res.Err != nil {
+				return parser.Result{
+					Err:       parser.ErrAtPosition(i, res.Err),
+					Remaining: input,
+				}
+			}
+			elseFn = res.Payload.([]interface{})[2].(Function)
+		}
+
+		return parser.Result{
+			Payload:   ifFunction(cases, elseFn),
+			Remaining: res.Remaining,
+		}
+	}
+}
+
 func bracketsExpressionParser() parser.Type {
 	whitespace := parser.DiscardAll(
 		parser.OneOf(
@@ -198,6 +198,67 @@ func TestExpressions(t *testing.T) {
 			output:   `second`,
 			messages: []easyMsg{},
 		},
+		"if literal": {
+			input:    `if true { "foo" } else { "bar" }`,
+			output:   `foo`,
+			messages: []easyMsg{},
+		},
+		"if literal else": {
+			input:    `if false { "foo" } else { "bar" }`,
+			output:   `bar`,
+			messages: []easyMsg{},
+		},
+		"if no else": {
+			input:    `if false { "foo" }`,
+			output:   `null`,
+			messages: []easyMsg{},
+		},
+		"if function": {
+			input: `if json("foo") > 10 {
+  "big"
+} else if json("foo") > 5 {
+  "medium"
+} else {
+  "small"
+}`,
+			output: `medium`,
+			messages: []easyMsg{
+				{content: `{"foo":7}`},
+			},
+		},
+		"if function 2": {
+			input: `if json("foo") > 10 {
+  "big"
+} else if json("foo") > 5 {
+  "medium"
+} else {
+  "small"
+}`,
+			output: `small`,
+			messages: []easyMsg{
+				{content: `{"foo":2}`},
+			},
+		},
+		"if with methods": {
+			input:  `if this.foo.contains("bar") { this.foo } else { "baz" }.uppercase()`,
+			output: `FOO BAR`,
+			value: func() *interface{} {
+				var v interface{} = map[string]interface{}{"foo": "foo bar"}
+				return &v
+			}(),
+			messages: []easyMsg{},
+		},
+		"if in brackets": {
+			input:    `(if meta("foo") == "bar" { "yes" } else { "no" })`,
+			output:   `yes`,
+			messages: []easyMsg{{meta: map[string]string{"foo": "bar"}}},
+		},
+		"if deprecated": {
+			input:      `if meta("foo") == "bar" { "yes" } else { "no" }`,
+			deprecated: true,
+			output:     `no`,
+			messages:   []easyMsg{{meta: map[string]string{"foo": "baz"}}},
+		},
 	}
 
 	for name, test := range tests {
@@ -233,3 +294,15 @@ func TestExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestIfExpressionErrors(t *testing.T) {
+	e, err := tryParse(`if this.foo { "bar" }`, false)
+	assert.NoError(t, err)
+
+	var value interface{} = map[string]interface{}{"foo": "not a bool"}
+	_, err = e.Exec(FunctionContext{
+		Msg:   message.New(nil),
+		Value: &value,
+	})
+	assert.EqualError(t, err, "expected bool value from if condition 0, received string")
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func getCache(ctx FunctionContext, name string) (types.Cache, error) {
+	if ctx.Caches == nil {
+		return nil, errors.New("caches are not accessible within this context")
+	}
+	c, err := ctx.Caches.GetCache(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to access cache '%v': %v", name, err)
+	}
+	return c, nil
+}
+
+func getCacheExtended(ctx FunctionContext, name string) (types.CacheExtended, error) {
+	c, err := getCache(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	ec, ok := c.(types.CacheExtended)
+	if !ok {
+		return nil, fmt.Errorf("cache '%v' does not support atomic operations", name)
+	}
+	return ec, nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterFunction(
+	"cache_get", true, cacheGetFunction,
+	ExpectNArgs(2),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+)
+
+func cacheGetFunction(args ...interface{}) (Function, error) {
+	name, key := args[0].(string), args[1].(string)
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		c, err := getCache(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := c.Get(key)
+		if err != nil {
+			return nil, &ErrRecoverable{
+				Recovered: nil,
+				Err:       err,
+			}
+		}
+		return string(v), nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterFunction(
+	"cache_get_multi", true, cacheGetMultiFunction,
+	ExpectNArgs(2),
+	ExpectStringArg(0),
+)
+
+func cacheGetMultiFunction(args ...interface{}) (Function, error) {
+	name := args[0].(string)
+	keysArr, ok := args[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array param, received %T", args[1])
+	}
+	keys := make([]string, 0, len(keysArr))
+	for i, k := range keysArr {
+		s, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string key at index %v, received %T", i, k)
+		}
+		keys = append(keys, s)
+	}
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		c, err := getCacheExtended(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		values, err := c.GetMulti(keys...)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]interface{}, len(values))
+		for k, v := range values {
+			result[k] = string(v)
+		}
+		return result, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterFunction(
+	"cache_incr", true, cacheIncrFunction,
+	ExpectBetweenNAndMArgs(2, 4),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+	ExpectIntArg(2),
+	ExpectStringArg(3),
+)
+
+func cacheIncrFunction(args ...interface{}) (Function, error) {
+	name, key := args[0].(string), args[1].(string)
+	delta := int64(1)
+	if len(args) > 2 {
+		delta = args[2].(int64)
+	}
+	var ttl *time.Duration
+	if len(args) > 3 {
+		d, err := time.ParseDuration(args[3].(string))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+		ttl = &d
+	}
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		c, err := getCacheExtended(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.Incr(key, delta, ttl)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,192 @@
+package query
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCache struct {
+	types.Cache
+	values map[string][]byte
+	ttls   map[string]*time.Duration
+}
+
+func (f *fakeCache) Get(key string) ([]byte, error) {
+	v, exists := f.values[key]
+	if !exists {
+		return nil, types.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (f *fakeCache) GetMulti(keys ...string) (map[string][]byte, error) {
+	res := map[string][]byte{}
+	for _, k := range keys {
+		if v, exists := f.values[k]; exists {
+			res[k] = v
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeCache) Incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	var i int64
+	if v, exists := f.values[key]; exists {
+		var err error
+		if i, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			return 0, err
+		}
+	} else {
+		f.ttls[key] = ttl
+	}
+	i += delta
+	f.values[key] = []byte(strconv.FormatInt(i, 10))
+	return i, nil
+}
+
+func (f *fakeCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeCache) CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	return errors.New("not implemented")
+}
+
+type fakeCaches map[string]types.Cache
+
+func (f fakeCaches) GetCache(name string) (types.Cache, error) {
+	if c, exists := f[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+func TestCacheFunctions(t *testing.T) {
+	foo := &fakeCache{
+		values: map[string][]byte{
+			"a": []byte("a value"),
+			"b": []byte("b value"),
+			"c": []byte("5"),
+		},
+		ttls: map[string]*time.Duration{},
+	}
+	caches := fakeCaches{
+		"foo": foo,
+		"bar": struct{ types.Cache }{foo},
+	}
+
+	tests := map[string]struct {
+		input  string
+		output interface{}
+		err    string
+	}{
+		"get": {
+			input:  `cache_get("foo", this.key)`,
+			output: "a value",
+		},
+		"get not found": {
+			input:  `cache_get("foo", "nope").or("default")`,
+			output: "default",
+		},
+		"get missing cache": {
+			input: `cache_get("nope", this.key)`,
+			err:   "unable to access cache 'nope': cache not found",
+		},
+		"get multi": {
+			input: `cache_get_multi("foo", this.keys)`,
+			output: map[string]interface{}{
+				"a": "a value",
+				"b": "b value",
+			},
+		},
+		"get multi not supported": {
+			input: `cache_get_multi("bar", this.keys)`,
+			err:   "cache 'bar' does not support atomic operations",
+		},
+		"incr": {
+			input:  `cache_incr("foo", "c")`,
+			output: int64(6),
+		},
+		"incr delta": {
+			input:  `cache_incr("foo", "c", -10)`,
+			output: int64(-5),
+		},
+		"incr not an integer": {
+			input: `cache_incr("foo", this.key, 1)`,
+			err:   `strconv.ParseInt: parsing "a value": invalid syntax`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			foo.values["c"] = []byte("5")
+
+			e, err := tryParse(test.input, false)
+			require.NoError(t, err)
+
+			var doc interface{} = map[string]interface{}{
+				"key":  "a",
+				"keys": []interface{}{"a", "b", "z"},
+			}
+			res, err := e.Exec(FunctionContext{
+				Maps:   map[string]Function{},
+				Value:  &doc,
+				Msg:    message.New(nil),
+				Caches: caches,
+			})
+			if len(test.err) > 0 {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestCacheFunctionsTTL(t *testing.T) {
+	foo := &fakeCache{
+		values: map[string][]byte{},
+		ttls:   map[string]*time.Duration{},
+	}
+
+	e, err := tryParse(`cache_incr("foo", "a", 2, "1m")`, false)
+	require.NoError(t, err)
+
+	res, err := e.Exec(FunctionContext{
+		Caches: fakeCaches{"foo": foo},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res)
+
+	require.NotNil(t, foo.ttls["a"])
+	assert.Equal(t, time.Minute, *foo.ttls["a"])
+
+	_, err = tryParse(`cache_incr("foo", "a", 2, "nope")`, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse ttl")
+}
+
+func TestCacheFunctionsNoCaches(t *testing.T) {
+	e, err := tryParse(`cache_get("foo", "bar")`, false)
+	require.NoError(t, err)
+
+	_, err = e.Exec(FunctionContext{})
+	require.EqualError(t, err, "caches are not accessible within this context")
+}
@@ -34,14 +34,25 @@ func appendMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+// MaxMapDepth is the maximum number of nested map applications permitted
+// within a single execution, which prevents runaway recursive maps.
+const MaxMapDepth = 1000
+
+type errMapDepth string
+
+func (e errMapDepth) Error() string {
+	return fmt.Sprintf("map %v exceeded maximum depth of %v nested map applications", string(e), MaxMapDepth)
+}
+
 var _ = RegisterMethod(
 	"apply", true, applyMethod,
-	ExpectNArgs(1),
+	ExpectAtLeastOneArg(),
 	ExpectStringArg(0),
 )
 
 func applyMethod(target Function, args ...interface{}) (Function, error) {
 	targetMap := args[0].(string)
+	mapArgs := args[1:]
 	return closureFn(func(ctx FunctionContext) (interface{}, error) {
 		res, err := target.Exec(ctx)
 		if err != nil {
@@ -63,9 +74,33 @@ func applyMethod(target Function, args ...interface{}) (Function, error) {
 			}
 		}
 
+		if ctx.depth >= MaxMapDepth {
+			return nil, errMapDepth(targetMap)
+		}
+		ctx.depth++
+
 		// ISOLATED VARIABLES
 		ctx.Vars = map[string]interface{}{}
-		return m.Exec(ctx)
+
+		var params []string
+		if pm, ok := m.(ParameterisedFunction); ok {
+			params = pm.Params()
+		}
+		if len(params) != len(mapArgs) {
+			return nil, fmt.Errorf("map %v expects %v arguments, received %v", targetMap, len(params), len(mapArgs))
+		}
+		for i, p := range params {
+			ctx.Vars[p] = mapArgs[i]
+		}
+
+		if res, err = m.Exec(ctx); err != nil {
+			// Avoid wrapping a depth error once for each level of recursion.
+			var depthErr errMapDepth
+			if xerrors.As(err, &depthErr) {
+				return nil, depthErr
+			}
+		}
+		return res, err
 	}), nil
 }
 
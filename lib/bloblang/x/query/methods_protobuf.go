@@ -0,0 +1,101 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/util/protobuf"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+//------------------------------------------------------------------------------
+
+func protobufMessageFromArgs(args []interface{}) (protoreflect.MessageDescriptor, error) {
+	files, err := protobuf.LoadPath(args[1].(string))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load protobuf schemas: %w", err)
+	}
+	return protobuf.FindMessage(files, args[0].(string))
+}
+
+var _ = RegisterMethod(
+	"parse_protobuf", false, parseProtobufMethod,
+	ExpectNArgs(2),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+)
+
+func parseProtobufMethod(target Function, args ...interface{}) (Function, error) {
+	md, err := protobufMessageFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var pbBytes []byte
+		switch t := v.(type) {
+		case string:
+			pbBytes = []byte(t)
+		case []byte:
+			pbBytes = t
+		default:
+			return nil, fmt.Errorf("expected string value, received %T", v)
+		}
+		msg := dynamicpb.NewMessage(md)
+		if err = proto.Unmarshal(pbBytes, msg); err != nil {
+			return nil, fmt.Errorf("failed to parse value as protobuf message '%v': %w", md.FullName(), err)
+		}
+		jsonBytes, err := protojson.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+		dec.UseNumber()
+
+		var jObj interface{}
+		if err = dec.Decode(&jObj); err != nil {
+			return nil, err
+		}
+		return jObj, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	"encode_protobuf", false, encodeProtobufMethod,
+	ExpectNArgs(2),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+)
+
+func encodeProtobufMethod(target Function, args ...interface{}) (Function, error) {
+	md, err := protobufMessageFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		msg := dynamicpb.NewMessage(md)
+		if err = protojson.Unmarshal(jsonBytes, msg); err != nil {
+			return nil, fmt.Errorf("failed to convert value to protobuf message '%v': %w", md.FullName(), err)
+		}
+		return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	}), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtobufMethods(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_bloblang_protobuf_test_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "person.proto"), []byte(`
+syntax = "proto3";
+package test;
+message Person {
+  string name = 1;
+  int32 age = 2;
+  int64 id = 3;
+}
+`), 0644))
+
+	tests := map[string]struct {
+		input  string
+		output interface{}
+		err    string
+	}{
+		"encode": {
+			input:  fmt.Sprintf(`{"name":"ash","age":10}.encode_protobuf("test.Person", %q)`, dir),
+			output: []byte{0x0a, 3, 'a', 's', 'h', 0x10, 10},
+		},
+		"round trip": {
+			input: fmt.Sprintf(`{"name":"ash","age":10}.encode_protobuf("test.Person", %q).parse_protobuf("test.Person", %q)`, dir, dir),
+			output: map[string]interface{}{
+				"name": "ash",
+				"age":  json.Number("10"),
+			},
+		},
+		"round trip large int64": {
+			input: fmt.Sprintf(`{"id":"9223372036854775807"}.encode_protobuf("test.Person", %q).parse_protobuf("test.Person", %q)`, dir, dir),
+			output: map[string]interface{}{
+				"id": "9223372036854775807",
+			},
+		},
+		"parse content": {
+			input: fmt.Sprintf(`content().parse_protobuf("test.Person", %q)`, dir),
+			output: map[string]interface{}{
+				"name": "ash",
+			},
+		},
+		"encode bad field": {
+			input: fmt.Sprintf(`{"nope":"ash"}.encode_protobuf("test.Person", %q)`, dir),
+			err:   `failed to convert value to protobuf message 'test.Person'`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			e, err := tryParse(test.input, false)
+			require.NoError(t, err)
+
+			res, err := e.Exec(FunctionContext{
+				Maps: map[string]Function{},
+				Msg:  message.New([][]byte{{0x0a, 3, 'a', 's', 'h'}}),
+			})
+			if len(test.err) > 0 {
+				// Errors from the protobuf library are deliberately unstable
+				// and therefore only the prefix is checked.
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, res)
+		})
+	}
+
+	_, err = tryParse(fmt.Sprintf(`content().parse_protobuf("test.Nope", %q)`, dir), false)
+	assert.Error(t, err)
+
+	_, err = tryParse(`content().parse_protobuf("test.Person", "./does_not_exist")`, false)
+	assert.Error(t, err)
+}
@@ -641,6 +641,77 @@ func TestMethods(t *testing.T) {
 			messages: []easyMsg{{content: `{"bar":2,"foo":1}`}},
 			err:      `expected map, found string`,
 		},
+		"check parse_timestamp rfc3339": {
+			input:  `"2020-08-14T11:45:26.371Z".parse_timestamp()`,
+			output: 1597405526.371,
+		},
+		"check parse_timestamp layout": {
+			input:  `"14/08/2020 11:45:26".parse_timestamp("02/01/2006 15:04:05")`,
+			output: int64(1597405526),
+		},
+		"check parse_timestamp timezone": {
+			input:  `"14/08/2020 11:45:26".parse_timestamp("02/01/2006 15:04:05", "Europe/London")`,
+			output: int64(1597401926),
+		},
+		"check parse_timestamp layout with zone": {
+			input:  `"2020-08-14 11:45:26 -0700".parse_timestamp("2006-01-02 15:04:05 -0700", "Europe/London")`,
+			output: int64(1597430726),
+		},
+		"check parse_timestamp number": {
+			input:    `json("ts").parse_timestamp()`,
+			messages: []easyMsg{{content: `{"ts":1597405526}`}},
+			output:   int64(1597405526),
+		},
+		"check parse_timestamp error": {
+			input: `"not a timestamp".parse_timestamp("2006-01-02")`,
+			err:   `parsing time "not a timestamp" as "2006-01-02": cannot parse "not a timestamp" as "2006"`,
+		},
+		"check parse_timestamp bad timezone": {
+			input:    `"2020-08-14".parse_timestamp("2006-01-02", json("tz"))`,
+			messages: []easyMsg{{content: `{"tz":"Nope/Nowhere"}`}},
+			err:      `failed to load timezone: unknown time zone Nope/Nowhere`,
+		},
+		"check format_timestamp": {
+			input:  `1597405526.format_timestamp()`,
+			output: `2020-08-14T11:45:26Z`,
+		},
+		"check format_timestamp layout": {
+			input:  `1597405526.format_timestamp("2006-01-02 15:04")`,
+			output: `2020-08-14 11:45`,
+		},
+		"check format_timestamp timezone": {
+			input:  `1597405526.format_timestamp("2006-01-02 15:04 MST", "America/New_York")`,
+			output: `2020-08-14 07:45 EDT`,
+		},
+		"check format_timestamp string": {
+			input:  `"2020-08-14T11:45:26.5+01:00".format_timestamp("15:04:05.000")`,
+			output: `10:45:26.500`,
+		},
+		"check format_timestamp json number": {
+			input:    `json("ts").format_timestamp("2006-01-02")`,
+			messages: []easyMsg{{content: `{"ts":1597405526}`}},
+			output:   `2020-08-14`,
+		},
+		"check format_timestamp bad value": {
+			input: `true.format_timestamp()`,
+			err:   `expected number or string value, received bool`,
+		},
+		"check parse_duration": {
+			input:  `"1h30m".parse_duration()`,
+			output: int64(5400),
+		},
+		"check parse_duration fractional": {
+			input:  `"1500ms".parse_duration()`,
+			output: 1.5,
+		},
+		"check parse_duration error": {
+			input: `5.parse_duration()`,
+			err:   `expected string value, received int64`,
+		},
+		"check timestamp arithmetic": {
+			input:  `("2020-08-14 23:30".parse_timestamp("2006-01-02 15:04") + "1h".parse_duration()).format_timestamp("2006-01-02 15:04")`,
+			output: `2020-08-15 00:30`,
+		},
 	}
 
 	for name, test := range tests {
@@ -0,0 +1,146 @@
+package query
+
+import (
+	"fmt"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Timestamps are represented within Bloblang as a number of seconds since the
+// unix epoch, which allows them to be compared and modified with the regular
+// arithmetic operators. Durations are also represented as a number of seconds.
+
+const defaultTimestampLayout = "2006-01-02T15:04:05.999999999Z07:00"
+
+func secondsValue(s int64, ns int64) interface{} {
+	if ns == 0 {
+		return s
+	}
+	return float64(s) + float64(ns)/float64(time.Second)
+}
+
+func timestampValue(t time.Time) interface{} {
+	return secondsValue(t.Unix(), int64(t.Nanosecond()))
+}
+
+func getLocation(args []interface{}, i int) (*time.Location, error) {
+	if len(args) <= i {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(args[i].(string))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone: %w", err)
+	}
+	return loc, nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	"format_timestamp", true, formatTimestampMethod,
+	ExpectBetweenNAndMArgs(0, 2),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+)
+
+func formatTimestampMethod(target Function, args ...interface{}) (Function, error) {
+	layout := defaultTimestampLayout
+	if len(args) > 0 {
+		layout = args[0].(string)
+	}
+	loc, err := getLocation(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t, err := IGetTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		return t.In(loc).Format(layout), nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	"parse_duration", false, parseDurationMethod,
+	ExpectNArgs(0),
+)
+
+func parseDurationMethod(target Function, _ ...interface{}) (Function, error) {
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var str string
+		switch t := v.(type) {
+		case string:
+			str = t
+		case []byte:
+			str = string(t)
+		default:
+			return nil, fmt.Errorf("expected string value, received %T", v)
+		}
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return nil, err
+		}
+		return secondsValue(int64(d/time.Second), int64(d%time.Second)), nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	"parse_timestamp", true, parseTimestampMethod,
+	ExpectBetweenNAndMArgs(0, 2),
+	ExpectStringArg(0),
+	ExpectStringArg(1),
+)
+
+func parseTimestampMethod(target Function, args ...interface{}) (Function, error) {
+	layout := defaultTimestampLayout
+	if len(args) > 0 {
+		layout = args[0].(string)
+	}
+	loc, err := getLocation(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return closureFn(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var str string
+		switch t := v.(type) {
+		case string:
+			str = t
+		case []byte:
+			str = string(t)
+		case int64, uint64, float64:
+			// Already a unix timestamp.
+			ts, err := IGetTimestamp(t)
+			if err != nil {
+				return nil, err
+			}
+			return timestampValue(ts), nil
+		default:
+			return nil, fmt.Errorf("expected string or number value, received %T", v)
+		}
+		t, err := time.ParseInLocation(layout, str, loc)
+		if err != nil {
+			return nil, err
+		}
+		return timestampValue(t), nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
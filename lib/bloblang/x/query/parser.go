@@ -14,6 +14,11 @@ type Message interface {
 	Len() int
 }
 
+// CacheProvider provides access to cache resources by their name.
+type CacheProvider interface {
+	GetCache(name string) (types.Cache, error)
+}
+
 // FunctionContext provides access to a root message, its index within the batch, and
 type FunctionContext struct {
 	Value  *interface{}
@@ -22,6 +27,13 @@ type FunctionContext struct {
 	Index  int
 	Msg    Message
 	Legacy bool
+
+	// Caches provides access to cache resources, and is nil when caches are
+	// not accessible.
+	Caches CacheProvider
+
+	// depth is the number of nested map applications that led to this context.
+	depth int
 }
 
 // Function takes a set of contextual parameters and returns the result of the
@@ -31,6 +43,15 @@ type Function interface {
 	Exec(ctx FunctionContext) (interface{}, error)
 }
 
+// ParameterisedFunction is a Function that declares named parameters, which
+// are bound as variables when the function is applied as a map with arguments.
+type ParameterisedFunction interface {
+	Function
+
+	// Params returns the names of the declared parameters in order.
+	Params() []string
+}
+
 // ExecToString returns a string from a function exection.
 func ExecToString(fn Function, ctx FunctionContext) string {
 	v, err := fn.Exec(ctx)
@@ -78,6 +99,7 @@ func New(query string) (Function, error) {
 func Parse(input []rune) parser.Result {
 	rootParser := parseWithTails(parser.OneOf(
 		matchExpressionParser(),
+		ifExpressionParser(),
 		bracketsExpressionParser(),
 		literalValueParser(),
 		functionParser(),
@@ -98,6 +120,7 @@ func Parse(input []rune) parser.Result {
 func ParseDeprecated(input []rune) parser.Result {
 	rootParser := parser.OneOf(
 		matchExpressionParser(),
+		ifExpressionParser(),
 		parseWithTails(bracketsExpressionParser()),
 		parseWithTails(literalValueParser()),
 		parseWithTails(functionParser()),
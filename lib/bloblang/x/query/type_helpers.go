@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/Jeffail/gabs/v2"
 )
@@ -55,6 +56,25 @@ func IGetInt(v interface{}) (int64, error) {
 	return 0, fmt.Errorf("function returned non-numerical type: %T", v)
 }
 
+// IGetTimestamp takes a boxed value and attempts to extract a timestamp from it,
+// either a number of seconds since the unix epoch or an RFC 3339 string.
+func IGetTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		secs := int64(t)
+		return time.Unix(secs, int64((t-float64(secs))*float64(time.Second))), nil
+	case []byte:
+		return time.Parse(time.RFC3339Nano, string(t))
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	}
+	return time.Time{}, fmt.Errorf("expected number or string value, received %T", v)
+}
+
 // IGetBool takes a boxed value and attempts to extract a boolean from it.
 func IGetBool(v interface{}) (bool, error) {
 	switch t := v.(type) {
@@ -37,6 +37,7 @@ var Constructors = map[string]TypeSpec{}
 const (
 	TypeMemory = "memory"
 	TypeNone   = "none"
+	TypeWAL    = "wal"
 )
 
 //------------------------------------------------------------------------------
@@ -46,6 +47,7 @@ type Config struct {
 	Type   string       `json:"type" yaml:"type"`
 	Memory MemoryConfig `json:"memory" yaml:"memory"`
 	None   struct{}     `json:"none" yaml:"none"`
+	WAL    WALConfig    `json:"wal" yaml:"wal"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -54,6 +56,7 @@ func NewConfig() Config {
 		Type:   "none",
 		Memory: NewMemoryConfig(),
 		None:   struct{}{},
+		WAL:    NewWALConfig(),
 	}
 }
 
@@ -156,15 +159,20 @@ different options and their qualities:
 | Type      | Throughput | Consumers | Capacity |
 | --------- | ---------- | --------- | -------- |
 | Memory    | Highest    | Parallel  | RAM      |
+| WAL       | Moderate   | Single    | Disk     |
 
 #### Delivery Guarantees
 
 | Event     | Shutdown  | Crash     | Disk Corruption |
 | --------- | --------- | --------- | --------------- |
 | Memory    | Flushed\* | Lost      | Lost            |
+| WAL       | Persisted | Persisted | Truncated\*\*   |
 
 \* Makes a best attempt at flushing the remaining messages before closing
-  gracefully.`
+  gracefully.
+
+\*\* Records that fail their checksum are skipped, messages that precede them
+  are preserved.`
 
 // Descriptions returns a formatted string of collated descriptions of each type.
 func Descriptions() string {
@@ -0,0 +1,623 @@
+package single
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// WALConfig is config options for a write-ahead log buffer.
+type WALConfig struct {
+	Path        string `json:"directory" yaml:"directory"`
+	SegmentSize int    `json:"segment_size" yaml:"segment_size"`
+	Limit       int    `json:"limit" yaml:"limit"`
+	SyncWrites  bool   `json:"sync_writes" yaml:"sync_writes"`
+}
+
+// NewWALConfig creates a WALConfig object with default values.
+func NewWALConfig() WALConfig {
+	return WALConfig{
+		Path:        "",
+		SegmentSize: 64 * 1024 * 1024,   // 64MiB
+		Limit:       1024 * 1024 * 1024, // 1GiB
+		SyncWrites:  true,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+/*
+Segment record format:
+
+- Four bytes containing the length of the record payload in big endian
+- Four bytes containing the CRC32 (Castagnoli) checksum of the payload
+- The payload, which is the serialised message contents followed by the
+  serialised metadata of each part (see message.ToBytes)
+
+The tracker file contains the segment index and offset of the oldest message
+that has not yet been acknowledged, followed by a CRC32 of those two values.
+*/
+
+const (
+	walHeaderLen  = 8
+	walTrackerLen = 20
+	walSegmentExt = ".wal"
+	walTracker    = "tracker"
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrWALChecksum is returned when a record read from a write-ahead log segment
+// does not match its checksum.
+var ErrWALChecksum = errors.New("write-ahead log record failed checksum")
+
+// ErrWALRecordLength is returned when the length of a record read from a
+// write-ahead log segment exceeds the segment it was read from.
+var ErrWALRecordLength = errors.New("write-ahead log record length exceeds its segment")
+
+// WAL is a buffer implemented as a segmented write-ahead log on disk. Messages
+// are only removed from the log once they have been shifted (acknowledged),
+// and segments are deleted once every message within them has been shifted.
+// Messages that were read but not shifted before a restart are replayed.
+type WAL struct {
+	config WALConfig
+
+	logger log.Modular
+	stats  metrics.Type
+
+	mSegments     metrics.StatGauge
+	mCorrupted    metrics.StatCounter
+	mSegmentsGone metrics.StatCounter
+
+	tracker *os.File
+
+	// Index of the segments currently on disk in ascending order.
+	segments []int
+
+	writeFile   *os.File
+	writeIndex  int
+	writeOffset int64
+
+	readFile   *os.File
+	readIndex  int
+	readOffset int64
+
+	// Set after a call to NextMessage in order to identify the record that a
+	// subsequent ShiftMessage call removes.
+	pendingLen int64
+	corrupted  bool
+
+	backlogBytes int
+
+	closed bool
+	cond   *sync.Cond
+}
+
+// NewWAL creates a write-ahead log buffer at the configured directory,
+// recovering any messages left unacknowledged from a previous run.
+func NewWAL(config WALConfig, log log.Modular, stats metrics.Type) (*WAL, error) {
+	if len(config.Path) == 0 {
+		return nil, errors.New("a directory must be specified")
+	}
+	if config.SegmentSize <= walHeaderLen {
+		return nil, fmt.Errorf("segment size must be greater than %v", walHeaderLen)
+	}
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		config:        config,
+		logger:        log,
+		stats:         stats,
+		mSegments:     stats.GetGauge("segments"),
+		mCorrupted:    stats.GetCounter("corrupted"),
+		mSegmentsGone: stats.GetCounter("segments.deleted"),
+		cond:          sync.NewCond(&sync.Mutex{}),
+	}
+	if err := w.recover(); err != nil {
+		w.closeFiles()
+		return nil, err
+	}
+
+	w.logger.Infof("Storing messages to write-ahead log in: %s\n", config.Path)
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *WAL) segmentPath(index int) string {
+	return filepath.Join(w.config.Path, fmt.Sprintf("%020d%v", index, walSegmentExt))
+}
+
+// listSegments returns the indexes of all segment files within the directory
+// in ascending order.
+func (w *WAL) listSegments() ([]int, error) {
+	infos, err := ioutil.ReadDir(w.config.Path)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []int
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, walSegmentExt) {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(name, walSegmentExt))
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// readTracker returns the segment and offset recorded in the tracker file. If
+// the tracker is missing or fails its checksum ok is false.
+func (w *WAL) readTracker() (index int, offset int64, ok bool) {
+	block := make([]byte, walTrackerLen)
+	if _, err := w.tracker.ReadAt(block, 0); err != nil {
+		return 0, 0, false
+	}
+	if crc32.Checksum(block[:16], walCRCTable) != binary.BigEndian.Uint32(block[16:]) {
+		return 0, 0, false
+	}
+	return int(binary.BigEndian.Uint64(block[0:])), int64(binary.BigEndian.Uint64(block[8:])), true
+}
+
+// writeTracker records the position of the oldest unacknowledged message. The
+// tracker is not synced to disk, in the event of a crash this can only result
+// in messages being replayed that were already acknowledged.
+func (w *WAL) writeTracker() error {
+	block := make([]byte, walTrackerLen)
+	binary.BigEndian.PutUint64(block[0:], uint64(w.readIndex))
+	binary.BigEndian.PutUint64(block[8:], uint64(w.readOffset))
+	binary.BigEndian.PutUint32(block[16:], crc32.Checksum(block[:16], walCRCTable))
+	_, err := w.tracker.WriteAt(block, 0)
+	return err
+}
+
+// scanSegment walks the records of a segment and returns the offset at which
+// the last valid record ends.
+func scanSegment(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	var offset int64
+	header := make([]byte, walHeaderLen)
+	for {
+		if _, err := f.ReadAt(header, offset); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:]))
+		if offset+walHeaderLen+size > info.Size() {
+			return offset, nil
+		}
+		payload := make([]byte, size)
+		if _, err := f.ReadAt(payload, offset+walHeaderLen); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+		if crc32.Checksum(payload, walCRCTable) != binary.BigEndian.Uint32(header[4:]) {
+			return offset, nil
+		}
+		offset += walHeaderLen + size
+	}
+}
+
+// recover opens the tracker and any existing segments, truncating the tail of
+// the newest segment if a write was interrupted.
+func (w *WAL) recover() error {
+	var err error
+	if w.tracker, err = os.OpenFile(filepath.Join(w.config.Path, walTracker), os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		return err
+	}
+	if w.segments, err = w.listSegments(); err != nil {
+		return err
+	}
+
+	if len(w.segments) == 0 {
+		w.segments = []int{0}
+		if w.writeFile, err = os.OpenFile(w.segmentPath(0), os.O_RDWR|os.O_CREATE, 0644); err != nil {
+			return err
+		}
+	} else {
+		w.writeIndex = w.segments[len(w.segments)-1]
+		if w.writeFile, err = os.OpenFile(w.segmentPath(w.writeIndex), os.O_RDWR, 0644); err != nil {
+			return err
+		}
+		if w.writeOffset, err = scanSegment(w.writeFile); err != nil {
+			return err
+		}
+		info, err := w.writeFile.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Size() > w.writeOffset {
+			w.logger.Warnf("Truncating %v bytes of incomplete writes from segment %v\n", info.Size()-w.writeOffset, w.writeIndex)
+			if err = w.writeFile.Truncate(w.writeOffset); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.readIndex, w.readOffset = w.segments[0], 0
+	if index, offset, ok := w.readTracker(); ok {
+		for _, s := range w.segments {
+			if s == index {
+				w.readIndex, w.readOffset = index, offset
+				break
+			}
+		}
+	}
+
+	// Segments preceding the read index have been fully acknowledged.
+	for len(w.segments) > 1 && w.segments[0] < w.readIndex {
+		os.Remove(w.segmentPath(w.segments[0]))
+		w.segments = w.segments[1:]
+	}
+	if w.readIndex == w.writeIndex && w.readOffset > w.writeOffset {
+		w.readOffset = w.writeOffset
+	}
+	if w.readFile, err = os.Open(w.segmentPath(w.readIndex)); err != nil {
+		return err
+	}
+
+	for _, s := range w.segments {
+		if s == w.writeIndex {
+			w.backlogBytes += int(w.writeOffset)
+		} else if info, err := os.Stat(w.segmentPath(s)); err == nil {
+			w.backlogBytes += int(info.Size())
+		}
+	}
+	w.backlogBytes -= int(w.readOffset)
+	w.mSegments.Set(int64(len(w.segments)))
+
+	if w.backlogBytes > 0 {
+		w.logger.Infof("Recovered %v bytes of unacknowledged messages from write-ahead log\n", w.backlogBytes)
+	}
+	return w.writeTracker()
+}
+
+func (w *WAL) closeFiles() {
+	for _, f := range []*os.File{w.readFile, w.writeFile, w.tracker} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func encodeWALRecord(msg types.Message) ([]byte, error) {
+	metaParts := make([][]byte, msg.Len())
+	if err := msg.Iter(func(i int, p types.Part) error {
+		meta := map[string]string{}
+		p.Metadata().Iter(func(k, v string) error {
+			meta[k] = v
+			return nil
+		})
+		var err error
+		metaParts[i], err = json.Marshal(meta)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	contents := message.ToBytes(msg)
+	metadata := message.ToBytes(message.New(metaParts))
+
+	record := make([]byte, walHeaderLen+len(contents)+len(metadata))
+	payload := record[walHeaderLen:]
+	copy(payload, contents)
+	copy(payload[len(contents):], metadata)
+
+	binary.BigEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(payload, walCRCTable))
+	return record, nil
+}
+
+func decodeWALRecord(payload []byte) (types.Message, error) {
+	msg, err := message.FromBytes(payload)
+	if err != nil {
+		return nil, err
+	}
+	contentsLen := 4
+	msg.Iter(func(i int, p types.Part) error {
+		contentsLen += 4 + len(p.Get())
+		return nil
+	})
+	metaMsg, err := message.FromBytes(payload[contentsLen:])
+	if err != nil {
+		return nil, err
+	}
+	if metaMsg.Len() != msg.Len() {
+		return nil, types.ErrBlockCorrupted
+	}
+	if err = msg.Iter(func(i int, p types.Part) error {
+		meta := map[string]string{}
+		if err := json.Unmarshal(metaMsg.Get(i).Get(), &meta); err != nil {
+			return err
+		}
+		for k, v := range meta {
+			p.Metadata().Set(k, v)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
+
+// backlog returns the number of bytes stored that have not been acknowledged.
+func (w *WAL) backlog() int {
+	return w.backlogBytes
+}
+
+// rotate closes the current write segment and opens a new one.
+func (w *WAL) rotate() error {
+	if w.config.SyncWrites {
+		if err := w.writeFile.Sync(); err != nil {
+			return err
+		}
+	}
+	nextIndex := w.writeIndex + 1
+	f, err := os.OpenFile(w.segmentPath(nextIndex), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w.writeFile.Close()
+	w.writeFile = f
+	w.writeIndex = nextIndex
+	w.writeOffset = 0
+	w.segments = append(w.segments, nextIndex)
+	w.mSegments.Set(int64(len(w.segments)))
+	return nil
+}
+
+// advanceSegment moves the reader onto the next segment and deletes the
+// previous one, which can only be done once all of its messages are shifted.
+func (w *WAL) advanceSegment() error {
+	if w.readIndex == w.writeIndex {
+		return nil
+	}
+	prevIndex := w.readIndex
+	nextIndex := w.segments[1]
+
+	f, err := os.Open(w.segmentPath(nextIndex))
+	if err != nil {
+		return err
+	}
+	w.readFile.Close()
+	w.readFile = f
+	w.readIndex = nextIndex
+	w.readOffset = 0
+	w.segments = w.segments[1:]
+
+	if err = w.writeTracker(); err != nil {
+		return err
+	}
+	if err = os.Remove(w.segmentPath(prevIndex)); err != nil {
+		w.logger.Errorf("Failed to delete acknowledged segment %v: %v\n", prevIndex, err)
+	} else {
+		w.mSegmentsGone.Incr(1)
+	}
+	w.mSegments.Set(int64(len(w.segments)))
+	return nil
+}
+
+// hasNext returns true if there is a message available to read.
+func (w *WAL) hasNext() bool {
+	return w.readIndex != w.writeIndex || w.readOffset < w.writeOffset
+}
+
+//------------------------------------------------------------------------------
+
+// ShiftMessage removes the oldest message from the log. Returns the backlog
+// count.
+func (w *WAL) ShiftMessage() (int, error) {
+	w.cond.L.Lock()
+	defer func() {
+		w.cond.Broadcast()
+		w.cond.L.Unlock()
+	}()
+
+	if w.closed {
+		return w.backlog(), nil
+	}
+
+	if w.corrupted {
+		// We can't trust the length of the corrupted record, therefore the
+		// remainder of the segment is skipped.
+		w.corrupted = false
+		var skipped int64
+		if w.readIndex == w.writeIndex {
+			skipped = w.writeOffset - w.readOffset
+			w.readOffset = w.writeOffset
+		} else if info, err := w.readFile.Stat(); err == nil {
+			skipped = info.Size() - w.readOffset
+			w.readOffset = info.Size()
+		}
+		w.backlogBytes -= int(skipped)
+		w.logger.Errorf("Skipped %v bytes of corrupted data in segment %v\n", skipped, w.readIndex)
+		if err := w.advanceSegment(); err != nil {
+			return w.backlog(), err
+		}
+		return w.backlog(), w.writeTracker()
+	}
+
+	if w.pendingLen == 0 {
+		return w.backlog(), nil
+	}
+	w.readOffset += w.pendingLen
+	w.backlogBytes -= int(w.pendingLen)
+	w.pendingLen = 0
+	return w.backlog(), w.writeTracker()
+}
+
+// NextMessage reads the oldest message, blocks until there's something to
+// read. The message is preserved until ShiftMessage is called.
+func (w *WAL) NextMessage() (types.Message, error) {
+	w.cond.L.Lock()
+	defer w.cond.L.Unlock()
+
+	for {
+		for !w.hasNext() && !w.closed {
+			w.cond.Wait()
+		}
+		if w.closed {
+			return nil, types.ErrTypeClosed
+		}
+
+		header := make([]byte, walHeaderLen)
+		if _, err := w.readFile.ReadAt(header, w.readOffset); err != nil {
+			if err == io.EOF && w.readIndex != w.writeIndex {
+				// We've reached the end of a segment that is fully shifted.
+				if err = w.advanceSegment(); err != nil {
+					return nil, err
+				}
+				w.cond.Broadcast()
+				continue
+			}
+			w.corrupted = true
+			w.mCorrupted.Incr(1)
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[0:]))
+		info, err := w.readFile.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if size > int64(w.config.SegmentSize) || w.readOffset+walHeaderLen+size > info.Size() {
+			// Never trust a length read from disk enough to allocate it.
+			w.corrupted = true
+			w.mCorrupted.Incr(1)
+			return nil, ErrWALRecordLength
+		}
+		payload := make([]byte, size)
+		if _, err := w.readFile.ReadAt(payload, w.readOffset+walHeaderLen); err != nil {
+			w.corrupted = true
+			w.mCorrupted.Incr(1)
+			return nil, err
+		}
+		if crc32.Checksum(payload, walCRCTable) != binary.BigEndian.Uint32(header[4:]) {
+			w.corrupted = true
+			w.mCorrupted.Incr(1)
+			return nil, ErrWALChecksum
+		}
+
+		msg, err := decodeWALRecord(payload)
+		if err != nil {
+			w.corrupted = true
+			w.mCorrupted.Incr(1)
+			return nil, err
+		}
+		w.pendingLen = walHeaderLen + size
+		return msg, nil
+	}
+}
+
+// PushMessage appends a new message to the log, returns the backlog count.
+func (w *WAL) PushMessage(msg types.Message) (int, error) {
+	record, err := encodeWALRecord(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	w.cond.L.Lock()
+	defer func() {
+		w.cond.Broadcast()
+		w.cond.L.Unlock()
+	}()
+
+	if len(record) > w.config.SegmentSize || (w.config.Limit > 0 && len(record) > w.config.Limit) {
+		return 0, types.ErrMessageTooLarge
+	}
+	for w.config.Limit > 0 && w.backlog()+len(record) > w.config.Limit && !w.closed {
+		w.cond.Wait()
+	}
+	if w.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	if w.writeOffset > 0 && w.writeOffset+int64(len(record)) > int64(w.config.SegmentSize) {
+		if err = w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err = w.writeFile.WriteAt(record, w.writeOffset); err != nil {
+		// Remove any partial write so that the next record begins at a clean
+		// offset.
+		w.writeFile.Truncate(w.writeOffset)
+		return 0, err
+	}
+	if w.config.SyncWrites {
+		if err = w.writeFile.Sync(); err != nil {
+			w.writeFile.Truncate(w.writeOffset)
+			return 0, err
+		}
+	}
+
+	w.writeOffset += int64(len(record))
+	w.backlogBytes += len(record)
+	return w.backlog(), nil
+}
+
+// CloseOnceEmpty closes the buffer once the backlog reaches 0.
+func (w *WAL) CloseOnceEmpty() {
+	w.cond.L.Lock()
+	for w.backlog() > 0 && !w.closed {
+		w.cond.Wait()
+	}
+	w.cond.L.Unlock()
+	w.Close()
+}
+
+// Close unblocks any blocked calls and closes the underlying files. Messages
+// that have not yet been shifted remain on disk and are replayed when the log
+// is next opened.
+func (w *WAL) Close() {
+	w.cond.L.Lock()
+	defer w.cond.L.Unlock()
+
+	if w.closed {
+		return
+	}
+	w.closed = true
+	w.cond.Broadcast()
+
+	if err := w.writeTracker(); err == nil {
+		w.tracker.Sync()
+	}
+	w.writeFile.Sync()
+	w.closeFiles()
+}
+
+//------------------------------------------------------------------------------
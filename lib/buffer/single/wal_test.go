@@ -0,0 +1,369 @@
+package single
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+func newTestWAL(t *testing.T, conf WALConfig) *WAL {
+	t.Helper()
+	w, err := NewWAL(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestWALBasic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+	conf.SegmentSize = 1000
+	conf.SyncWrites = false
+
+	w := newTestWAL(t, conf)
+	defer w.Close()
+
+	n := 100
+	for i := 0; i < n; i++ {
+		msg := message.New([][]byte{
+			[]byte("hello"),
+			[]byte(fmt.Sprintf("test%v", i)),
+		})
+		msg.Get(1).Metadata().Set("index", fmt.Sprintf("%v", i))
+		if _, err = w.PushMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		msg, err := w.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := 2, msg.Len(); exp != act {
+			t.Fatalf("Wrong message length: %v != %v", exp, act)
+		}
+		if exp, act := fmt.Sprintf("test%v", i), string(msg.Get(1).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+		if exp, act := fmt.Sprintf("%v", i), msg.Get(1).Metadata().Get("index"); exp != act {
+			t.Errorf("Wrong metadata: %v != %v", exp, act)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exp, act := 0, w.backlog(); exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+
+	segments, err := w.listSegments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(segments); exp != act {
+		t.Errorf("Wrong count of remaining segments: %v != %v", exp, act)
+	}
+}
+
+func TestWALReplayUnacked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+	conf.SegmentSize = 100
+	conf.SyncWrites = false
+
+	w := newTestWAL(t, conf)
+
+	n := 20
+	for i := 0; i < n; i++ {
+		if _, err = w.PushMessage(message.New([][]byte{
+			[]byte(fmt.Sprintf("test%v", i)),
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Read and acknowledge half of the messages, then read one more without
+	// acknowledging it.
+	for i := 0; i < n/2; i++ {
+		if _, err = w.NextMessage(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = w.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	w = newTestWAL(t, conf)
+	defer w.Close()
+
+	for i := n / 2; i < n; i++ {
+		msg, err := w.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := fmt.Sprintf("test%v", i), string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWALTruncatesPartialWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+	conf.SyncWrites = false
+
+	w := newTestWAL(t, conf)
+	for _, s := range []string{"first", "second"} {
+		if _, err = w.PushMessage(message.New([][]byte{[]byte(s)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	// Simulate a crash during a write by appending garbage to the segment.
+	f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%020d%v", 0, walSegmentExt)), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte{0, 0, 0, 50, 1, 2, 3, 4, 'n', 'o', 'p', 'e'}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	w = newTestWAL(t, conf)
+	defer w.Close()
+
+	if _, err = w.PushMessage(message.New([][]byte{[]byte("third")})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"first", "second", "third"} {
+		msg, err := w.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWALCorruptedRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+	conf.SegmentSize = 40
+	conf.SyncWrites = false
+
+	w := newTestWAL(t, conf)
+	for _, s := range []string{"first", "second", "third"} {
+		if _, err = w.PushMessage(message.New([][]byte{[]byte(s)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	// Flip a byte of the first record payload.
+	segPath := filepath.Join(dir, fmt.Sprintf("%020d%v", 0, walSegmentExt))
+	block, err := ioutil.ReadFile(segPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block[walHeaderLen+8] = 'X'
+	if err = ioutil.WriteFile(segPath, block, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w = newTestWAL(t, conf)
+	defer w.Close()
+
+	if _, err = w.NextMessage(); err != ErrWALChecksum {
+		t.Errorf("Expected checksum error, received: %v", err)
+	}
+	if _, err = w.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"second", "third"} {
+		msg, err := w.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWALCorruptedRecordLength(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+	conf.SegmentSize = 40
+	conf.SyncWrites = false
+
+	w := newTestWAL(t, conf)
+	for _, s := range []string{"first", "second", "third"} {
+		if _, err = w.PushMessage(message.New([][]byte{[]byte(s)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	// Overwrite the length of the first record with a huge value.
+	segPath := filepath.Join(dir, fmt.Sprintf("%020d%v", 0, walSegmentExt))
+	block, err := ioutil.ReadFile(segPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(block, []byte{0xFF, 0xFF, 0xFF, 0xF0})
+	if err = ioutil.WriteFile(segPath, block, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w = newTestWAL(t, conf)
+	defer w.Close()
+
+	if _, err = w.NextMessage(); err != ErrWALRecordLength {
+		t.Errorf("Expected record length error, received: %v", err)
+	}
+	if _, err = w.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"second", "third"} {
+		msg, err := w.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWALTruncatesCorruptedTailLength(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+	conf.SyncWrites = false
+
+	w := newTestWAL(t, conf)
+	if _, err = w.PushMessage(message.New([][]byte{[]byte("first")})); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// Append a header claiming a record far larger than the segment.
+	f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%020d%v", 0, walSegmentExt)), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte{0xFF, 0xFF, 0xFF, 0xF0, 1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	w = newTestWAL(t, conf)
+	defer w.Close()
+
+	if _, err = w.PushMessage(message.New([][]byte{[]byte("second")})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"first", "second"} {
+		msg, err := w.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+		if _, err = w.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWALClosedUnblocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewWALConfig()
+	conf.Path = dir
+
+	w := newTestWAL(t, conf)
+
+	errChan := make(chan error)
+	go func() {
+		_, err := w.NextMessage()
+		errChan <- err
+	}()
+
+	w.Close()
+	if err = <-errChan; err != types.ErrTypeClosed {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
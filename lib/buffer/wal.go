@@ -0,0 +1,77 @@
+package buffer
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/buffer/single"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWAL] = TypeSpec{
+		constructor: NewWAL,
+		Summary: `
+Stores consumed messages in a segmented write-ahead log on disk and
+acknowledges them at the input level. Messages are only removed from the log
+once they have been acknowledged by the output, and any that remain
+unacknowledged are replayed after a restart.`,
+		Description: `
+This buffer is appropriate when consuming messages from inputs that do not
+gracefully handle back pressure, or where messages must survive the Benthos
+process being terminated unexpectedly.
+
+Messages, including their metadata, are appended to segment files within the
+configured directory. Each record is written with a checksum, and during start
+up any records at the tail of the log that were only partially written are
+truncated. A segment file is deleted once every message within it has been
+acknowledged by the output.
+
+The position of the oldest unacknowledged message is recorded in a tracker
+file, which is updated as messages are acknowledged. If Benthos is restarted
+then consumption resumes from this position, meaning messages that were read
+but not acknowledged are delivered again.
+
+### Performance
+
+When ` + "`sync_writes`" + ` is enabled each message is flushed to disk before it
+is acknowledged at the input level, which guarantees that acknowledged messages
+survive the loss of the host but significantly reduces throughput. Disabling
+it means messages survive a crash of the Benthos process but may be lost if the
+host itself fails.
+
+Messages are read from this buffer sequentially and only a single message is
+in flight at any given time.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("directory", "A path to a directory in which to store segment files, the directory is created if it does not already exist."),
+			docs.FieldAdvanced("segment_size", "The maximum size (in bytes) of each segment file."),
+			docs.FieldCommon("limit", "The maximum number of bytes of unacknowledged messages to store before applying backpressure upstream. Set to `0` to disable the limit."),
+			docs.FieldCommon("sync_writes", "Whether each message should be synced to disk before it is acknowledged at the input level."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WALConfig is config values for a write-ahead log buffer type.
+type WALConfig single.WALConfig
+
+// NewWALConfig creates a WALConfig with default values.
+func NewWALConfig() WALConfig {
+	return WALConfig(single.NewWALConfig())
+}
+
+//------------------------------------------------------------------------------
+
+// NewWAL creates a buffer stored as a write-ahead log on disk.
+func NewWAL(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	wal, err := single.NewWAL(single.WALConfig(config.WAL), log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleWrapper(config, wal, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,111 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+func TestWALBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_wal_buffer_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = TypeWAL
+	conf.WAL.Path = dir
+	conf.WAL.SyncWrites = false
+
+	buf, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = buf.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"first", "second"} {
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte(s)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	var outTr types.Transaction
+	select {
+	case outTr = <-buf.TransactionChan():
+		if exp, act := "first", string(outTr.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case outTr.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	// Read the second message but close before acknowledging it.
+	select {
+	case outTr = <-buf.TransactionChan():
+		if exp, act := "second", string(outTr.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	buf.CloseAsync()
+	close(outTr.ResponseChan)
+	if err = buf.WaitForClose(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf, err = New(conf, nil, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	tChan = make(chan types.Transaction)
+	if err = buf.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case outTr = <-buf.TransactionChan():
+		if exp, act := "second", string(outTr.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", exp, act)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case outTr.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	close(tChan)
+	if err = buf.WaitForClose(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -33,6 +33,12 @@ a binary value using the ` + "`data_key`" + ` field name.`,
 A prefix can be specified to allow multiple cache types to share a single
 DynamoDB table. An optional TTL duration (` + "`ttl`" + `) and field
 (` + "`ttl_key`" + `) can be specified if the backing table has TTL enabled.
+The TTL can also be overridden for each operation by components that support
+it, such as the ` + "[`cache` processor](/docs/components/processors/cache)" + `.
+
+Compare-and-swaps are performed with conditional writes, and increments are
+performed with a loop of compare-and-swaps, the TTL of an incremented key is
+only set when the key is created.
 
 Strong read consistency can be enabled using the ` + "`consistent_read`" + `
 configuration field.
@@ -135,6 +141,22 @@ type DynamoDB struct {
 	mDelFailedErr    metrics.StatCounter
 	mDelSuccess      metrics.StatCounter
 	mDelLatency      metrics.StatTimer
+	mMGetCount       metrics.StatCounter
+	mMGetRetry       metrics.StatCounter
+	mMGetFailed      metrics.StatCounter
+	mMGetSuccess     metrics.StatCounter
+	mMGetLatency     metrics.StatTimer
+	mIncrCount       metrics.StatCounter
+	mIncrRetry       metrics.StatCounter
+	mIncrFailed      metrics.StatCounter
+	mIncrSuccess     metrics.StatCounter
+	mIncrLatency     metrics.StatTimer
+	mCASCount        metrics.StatCounter
+	mCASRetry        metrics.StatCounter
+	mCASMismatch     metrics.StatCounter
+	mCASFailedErr    metrics.StatCounter
+	mCASSuccess      metrics.StatCounter
+	mCASLatency      metrics.StatTimer
 }
 
 // NewDynamoDB creates a new DynamoDB cache type.
@@ -174,6 +196,22 @@ func NewDynamoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.
 		mDelFailedErr:    stats.GetCounter("delete.failed.error"),
 		mDelSuccess:      stats.GetCounter("delete.success"),
 		mDelLatency:      stats.GetTimer("delete.latency"),
+		mMGetCount:       stats.GetCounter("get_multi.count"),
+		mMGetRetry:       stats.GetCounter("get_multi.retry"),
+		mMGetFailed:      stats.GetCounter("get_multi.failed.error"),
+		mMGetSuccess:     stats.GetCounter("get_multi.success"),
+		mMGetLatency:     stats.GetTimer("get_multi.latency"),
+		mIncrCount:       stats.GetCounter("incr.count"),
+		mIncrRetry:       stats.GetCounter("incr.retry"),
+		mIncrFailed:      stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:     stats.GetCounter("incr.success"),
+		mIncrLatency:     stats.GetTimer("incr.latency"),
+		mCASCount:        stats.GetCounter("compare_and_swap.count"),
+		mCASRetry:        stats.GetCounter("compare_and_swap.retry"),
+		mCASMismatch:     stats.GetCounter("compare_and_swap.failed.mismatch"),
+		mCASFailedErr:    stats.GetCounter("compare_and_swap.failed.error"),
+		mCASSuccess:      stats.GetCounter("compare_and_swap.success"),
+		mCASLatency:      stats.GetTimer("compare_and_swap.latency"),
 	}
 
 	if d.conf.TTL != "" {
@@ -249,6 +287,10 @@ func (d *DynamoDB) Get(key string) ([]byte, error) {
 }
 
 func (d *DynamoDB) get(key string) ([]byte, error) {
+	return d.getWithConsistency(key, d.conf.ConsistentRead)
+}
+
+func (d *DynamoDB) getWithConsistency(key string, consistent bool) ([]byte, error) {
 	res, err := d.client.GetItem(&dynamodb.GetItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
 			d.conf.HashKey: {
@@ -256,7 +298,7 @@ func (d *DynamoDB) get(key string) ([]byte, error) {
 			},
 		},
 		TableName:      d.table,
-		ConsistentRead: aws.Bool(d.conf.ConsistentRead),
+		ConsistentRead: aws.Bool(consistent),
 	})
 	if err != nil {
 		return nil, err
@@ -270,14 +312,103 @@ func (d *DynamoDB) get(key string) ([]byte, error) {
 	return val.B, nil
 }
 
+// dynamoDBMaxBatchGet is the maximum number of keys that can be retrieved with
+// a single BatchGetItem request.
+const dynamoDBMaxBatchGet = 100
+
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (d *DynamoDB) GetMulti(keys ...string) (map[string][]byte, error) {
+	d.mMGetCount.Incr(1)
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+
+	results := make(map[string][]byte, len(keys))
+
+	var err error
+	for i := 0; i < len(keys) && err == nil; i += dynamoDBMaxBatchGet {
+		j := i + dynamoDBMaxBatchGet
+		if j > len(keys) {
+			j = len(keys)
+		}
+
+		reqKeys := make([]map[string]*dynamodb.AttributeValue, 0, j-i)
+		for _, k := range keys[i:j] {
+			reqKeys = append(reqKeys, map[string]*dynamodb.AttributeValue{
+				d.conf.HashKey: {
+					S: aws.String(k),
+				},
+			})
+		}
+
+		for len(reqKeys) > 0 {
+			wait := boff.NextBackOff()
+			var batchResult *dynamodb.BatchGetItemOutput
+			batchResult, err = d.client.BatchGetItem(&dynamodb.BatchGetItemInput{
+				RequestItems: map[string]*dynamodb.KeysAndAttributes{
+					*d.table: {
+						ConsistentRead: aws.Bool(d.conf.ConsistentRead),
+						Keys:           reqKeys,
+					},
+				},
+			})
+			if err != nil {
+				d.log.Errorf("Get multi error: %v\n", err)
+			} else {
+				for _, item := range batchResult.Responses[*d.table] {
+					k, v := item[d.conf.HashKey], item[d.conf.DataKey]
+					if k != nil && k.S != nil && v != nil && v.B != nil {
+						results[*k.S] = v.B
+					}
+				}
+				reqKeys = nil
+				if unproc := batchResult.UnprocessedKeys[*d.table]; unproc != nil && len(unproc.Keys) > 0 {
+					reqKeys = unproc.Keys
+					err = fmt.Errorf("failed to get %v items", len(unproc.Keys))
+				}
+			}
+
+			if err != nil {
+				if wait == backoff.Stop {
+					break
+				}
+				time.Sleep(wait)
+				d.mMGetRetry.Incr(1)
+				err = nil
+			}
+		}
+	}
+
+	if err == nil {
+		d.mMGetSuccess.Incr(1)
+	} else {
+		d.mMGetFailed.Incr(1)
+		results = nil
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mMGetLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	boff.Reset()
+	d.boffPool.Put(boff)
+	return results, err
+}
+
 // Set attempts to set the value of a key.
 func (d *DynamoDB) Set(key string, value []byte) error {
+	return d.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL.
+func (d *DynamoDB) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	d.mSetCount.Incr(1)
 
 	tStarted := time.Now()
 	boff := d.boffPool.Get().(backoff.BackOff)
 
-	_, err := d.client.PutItem(d.putItemInput(key, value))
+	_, err := d.client.PutItem(d.putItemInput(key, value, ttl))
 	for err != nil {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -285,7 +416,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mSetRetry.Incr(1)
-		_, err = d.client.PutItem(d.putItemInput(key, value))
+		_, err = d.client.PutItem(d.putItemInput(key, value, ttl))
 	}
 	if err == nil {
 		d.mSetSuccess.Incr(1)
@@ -305,6 +436,16 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 // SetMulti attempts to set the value of multiple keys, if any keys fail to be
 // set an error is returned.
 func (d *DynamoDB) SetMulti(items map[string][]byte) error {
+	ttlItems := make(map[string]types.CacheTTLItem, len(items))
+	for k, v := range items {
+		ttlItems[k] = types.CacheTTLItem{Value: v}
+	}
+	return d.SetMultiWithTTL(ttlItems)
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys, each with their
+// own TTL, if any keys fail to be set an error is returned.
+func (d *DynamoDB) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
 	d.mSetMultiCount.Incr(1)
 
 	tStarted := time.Now()
@@ -314,7 +455,7 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 	for k, v := range items {
 		writeReqs = append(writeReqs, &dynamodb.WriteRequest{
 			PutRequest: &dynamodb.PutRequest{
-				Item: d.putItemInput(k, v).Item,
+				Item: d.putItemInput(k, v.Value, v.TTL).Item,
 			},
 		})
 	}
@@ -364,12 +505,18 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (d *DynamoDB) Add(key string, value []byte) error {
+	return d.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with a TTL only if the key does
+// not already exist and returns an error if the key already exists.
+func (d *DynamoDB) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	d.mAddCount.Incr(1)
 
 	tStarted := time.Now()
 	boff := d.boffPool.Get().(backoff.BackOff)
 
-	err := d.add(key, value)
+	err := d.add(key, value, ttl)
 	for err != nil && err != types.ErrKeyAlreadyExists {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -377,7 +524,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mAddRetry.Incr(1)
-		err = d.add(key, value)
+		err = d.add(key, value, ttl)
 	}
 	if err == nil {
 		d.mAddSuccess.Incr(1)
@@ -396,8 +543,8 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 	return err
 }
 
-func (d *DynamoDB) add(key string, value []byte) error {
-	input := d.putItemInput(key, value)
+func (d *DynamoDB) add(key string, value []byte, ttl *time.Duration) error {
+	input := d.putItemInput(key, value, ttl)
 
 	expr, err := expression.NewBuilder().
 		WithCondition(expression.AttributeNotExists(expression.Name(d.conf.HashKey))).
@@ -420,6 +567,158 @@ func (d *DynamoDB) add(key string, value []byte) error {
 	return nil
 }
 
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. A key that does not exist is created with the TTL, the TTL of an
+// existing key is not changed.
+func (d *DynamoDB) Incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	d.mIncrCount.Incr(1)
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+
+	// Increments are only retried when the conditional write of the result
+	// conflicted with another write, since other failures might still have
+	// been applied.
+	value, err := d.incr(key, delta, ttl)
+	for err != nil && isCASMismatch(err) {
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			break
+		}
+		time.Sleep(wait)
+		d.mIncrRetry.Incr(1)
+		value, err = d.incr(key, delta, ttl)
+	}
+	if err == nil {
+		d.mIncrSuccess.Incr(1)
+	} else {
+		d.mIncrFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mIncrLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	boff.Reset()
+	d.boffPool.Put(boff)
+	return value, err
+}
+
+func (d *DynamoDB) incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	current, err := d.getWithConsistency(key, true)
+	if err == types.ErrKeyNotFound {
+		if err = d.add(key, []byte(strconv.FormatInt(delta, 10)), ttl); err != nil {
+			return 0, err
+		}
+		return delta, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := incrValue(current, delta)
+	if err != nil {
+		return 0, err
+	}
+	if err = d.update(key, current, []byte(strconv.FormatInt(value, 10)), nil, false); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// CompareAndSwap atomically sets the value of a key with a TTL only if its
+// current value matches old, where a nil old value matches only a key that does
+// not exist.
+func (d *DynamoDB) CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	d.mCASCount.Incr(1)
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+
+	err := d.compareAndSwap(key, old, new, ttl)
+	for err != nil && !isCASMismatch(err) {
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			break
+		}
+		time.Sleep(wait)
+		d.mCASRetry.Incr(1)
+		err = d.compareAndSwap(key, old, new, ttl)
+	}
+	if err == nil {
+		d.mCASSuccess.Incr(1)
+	} else if isCASMismatch(err) {
+		d.mCASMismatch.Incr(1)
+	} else {
+		d.mCASFailedErr.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mCASLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	boff.Reset()
+	d.boffPool.Put(boff)
+	return err
+}
+
+func (d *DynamoDB) compareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	if old == nil {
+		return d.add(key, new, ttl)
+	}
+	err := d.update(key, old, new, ttl, true)
+	if err != types.ErrKeyValueMismatch {
+		return err
+	}
+	// The condition failed, determine whether it was due to the key not
+	// existing.
+	if _, err = d.getWithConsistency(key, true); err == nil {
+		return types.ErrKeyValueMismatch
+	}
+	return err
+}
+
+// update sets the value of an existing key only if its current value matches
+// old, and optionally sets its TTL.
+func (d *DynamoDB) update(key string, old, new []byte, ttl *time.Duration, setTTL bool) error {
+	update := expression.Set(expression.Name(d.conf.DataKey), expression.Value(new))
+	if setTTL {
+		if expiry, ok := d.ttlExpiry(ttl); ok {
+			update = update.Set(expression.Name(d.conf.TTLKey), expression.Value(expiry))
+		}
+	}
+
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.Name(d.conf.DataKey).Equal(expression.Value(old))).
+		WithUpdate(update).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	if _, err = d.client.UpdateItem(&dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			d.conf.HashKey: {
+				S: aws.String(key),
+			},
+		},
+		TableName:                 d.table,
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}); err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case dynamodb.ErrCodeConditionalCheckFailedException:
+				return types.ErrKeyValueMismatch
+			}
+		}
+		return err
+	}
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (d *DynamoDB) Delete(key string) error {
 	d.mDelCount.Incr(1)
@@ -464,8 +763,21 @@ func (d *DynamoDB) delete(key string) error {
 	return err
 }
 
+// ttlExpiry returns the unix timestamp to place within the TTL column for an
+// optional TTL override, or false if a TTL should not be set.
+func (d *DynamoDB) ttlExpiry(ttl *time.Duration) (int64, bool) {
+	t := d.ttl
+	if ttl != nil {
+		t = *ttl
+	}
+	if t == 0 || d.conf.TTLKey == "" {
+		return 0, false
+	}
+	return time.Now().Add(t).Unix(), true
+}
+
 // putItemInput creates a generic put item input for use in Set and Add operations
-func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput {
+func (d *DynamoDB) putItemInput(key string, value []byte, ttl *time.Duration) *dynamodb.PutItemInput {
 	input := dynamodb.PutItemInput{
 		Item: map[string]*dynamodb.AttributeValue{
 			d.conf.HashKey: {
@@ -478,9 +790,9 @@ func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput
 		TableName: d.table,
 	}
 
-	if d.ttl != 0 && d.conf.TTLKey != "" {
+	if expiry, ok := d.ttlExpiry(ttl); ok {
 		input.Item[d.conf.TTLKey] = &dynamodb.AttributeValue{
-			N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
+			N: aws.String(strconv.FormatInt(expiry, 10)),
 		}
 	}
 
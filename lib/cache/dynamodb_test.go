@@ -114,6 +114,14 @@ func TestDynamoDBIntegration(t *testing.T) {
 	t.Run("testDynamodbAddAndDelete", func(t *testing.T) {
 		testDynamodbAddAndDelete(t, conf)
 	})
+
+	t.Run("testDynamodbExtended", func(t *testing.T) {
+		c, err := NewDynamoDB(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCacheExtended(t, c.(types.CacheExtended), "extended_")
+	})
 }
 
 func testDynamodbGetAndSet(t *testing.T, conf Config) {
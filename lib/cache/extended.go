@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// compareValues checks whether the current state of a key matches an expected
+// old value for a compare-and-swap, where a nil old value expects the key to
+// not exist.
+func compareValues(exists bool, current, old []byte) error {
+	if !exists {
+		if old != nil {
+			return types.ErrKeyNotFound
+		}
+		return nil
+	}
+	if old == nil {
+		return types.ErrKeyAlreadyExists
+	}
+	if !bytes.Equal(current, old) {
+		return types.ErrKeyValueMismatch
+	}
+	return nil
+}
+
+// isCASMismatch returns true if an error returned by a compare-and-swap
+// indicates that the current state of the key did not match.
+func isCASMismatch(err error) bool {
+	switch err {
+	case types.ErrKeyNotFound, types.ErrKeyAlreadyExists, types.ErrKeyValueMismatch:
+		return true
+	}
+	return false
+}
+
+// incrValue adds a delta to the integer value of a cached key, where an empty
+// value is treated as zero.
+func incrValue(value []byte, delta int64) (int64, error) {
+	if len(value) == 0 {
+		return delta, nil
+	}
+	i, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of key is not an integer: %v", err)
+	}
+	return i + delta, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+// testCacheExtended checks the behaviour of the extended methods of a cache,
+// with all keys being prefixed in order to avoid collisions with other tests.
+func testCacheExtended(t *testing.T, c types.CacheExtended, prefix string) {
+	t.Helper()
+
+	fooKey, barKey, bazKey := prefix+"foo", prefix+"bar", prefix+"baz"
+	for _, k := range []string{fooKey, barKey, bazKey} {
+		require.NoError(t, c.Delete(k))
+	}
+
+	ttl := time.Hour
+
+	require.NoError(t, c.SetWithTTL(fooKey, []byte("foo1"), &ttl))
+	require.NoError(t, c.SetMultiWithTTL(map[string]types.CacheTTLItem{
+		barKey: {Value: []byte("bar1"), TTL: &ttl},
+	}))
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.AddWithTTL(fooKey, []byte("foo2"), &ttl))
+
+	values, err := c.GetMulti(fooKey, barKey, bazKey)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		fooKey: []byte("foo1"),
+		barKey: []byte("bar1"),
+	}, values)
+
+	assert.Equal(t, types.ErrKeyNotFound, c.CompareAndSwap(bazKey, []byte("nope"), []byte("baz1"), nil))
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.CompareAndSwap(fooKey, nil, []byte("foo2"), nil))
+	assert.Equal(t, types.ErrKeyValueMismatch, c.CompareAndSwap(fooKey, []byte("nope"), []byte("foo2"), nil))
+	require.NoError(t, c.CompareAndSwap(fooKey, []byte("foo1"), []byte("foo2"), &ttl))
+	require.NoError(t, c.CompareAndSwap(bazKey, nil, []byte("baz1"), &ttl))
+
+	values, err = c.GetMulti(fooKey, bazKey)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		fooKey: []byte("foo2"),
+		bazKey: []byte("baz1"),
+	}, values)
+
+	require.NoError(t, c.Delete(bazKey))
+
+	v, err := c.Incr(bazKey, 5, &ttl)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+
+	v, err = c.Incr(bazKey, -2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+
+	_, err = c.Incr(fooKey, 1, nil)
+	assert.Error(t, err)
+
+	for _, k := range []string{fooKey, barKey, bazKey} {
+		require.NoError(t, c.Delete(k))
+	}
+}
+
+//------------------------------------------------------------------------------
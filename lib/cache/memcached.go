@@ -2,6 +2,7 @@ package cache
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +21,12 @@ func init() {
 		Summary: `
 Connects to a cluster of memcached services, a prefix can be specified to allow
 multiple cache types to share a memcached cluster under different namespaces.`,
+		Description: `
+The TTL can be overridden for each operation by components that support it, such
+as the ` + "[`cache` processor](/docs/components/processors/cache)" + `.
+Increments use the native commands of memcached and therefore values cannot be
+decremented below zero. Failed increments are not retried, since a request that
+failed in transit might still have been applied.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("addresses", "A list of addresses of memcached servers to use."),
 			docs.FieldCommon("prefix", "An optional string to prefix item keys with in order to prevent collisions with similar services."),
@@ -83,6 +90,21 @@ type Memcached struct {
 	mDelFailedErr  metrics.StatCounter
 	mDelSuccess    metrics.StatCounter
 	mDelLatency    metrics.StatTimer
+	mMGetCount     metrics.StatCounter
+	mMGetRetry     metrics.StatCounter
+	mMGetFailed    metrics.StatCounter
+	mMGetSuccess   metrics.StatCounter
+	mMGetLatency   metrics.StatTimer
+	mIncrCount     metrics.StatCounter
+	mIncrFailed    metrics.StatCounter
+	mIncrSuccess   metrics.StatCounter
+	mIncrLatency   metrics.StatTimer
+	mCASCount      metrics.StatCounter
+	mCASRetry      metrics.StatCounter
+	mCASMismatch   metrics.StatCounter
+	mCASFailedErr  metrics.StatCounter
+	mCASSuccess    metrics.StatCounter
+	mCASLatency    metrics.StatTimer
 
 	mc          *memcache.Client
 	retryPeriod time.Duration
@@ -135,6 +157,21 @@ func NewMemcached(
 		mDelFailedErr:  stats.GetCounter("delete.failed.error"),
 		mDelSuccess:    stats.GetCounter("delete.success"),
 		mDelLatency:    stats.GetTimer("delete.latency"),
+		mMGetCount:     stats.GetCounter("get_multi.count"),
+		mMGetRetry:     stats.GetCounter("get_multi.retry"),
+		mMGetFailed:    stats.GetCounter("get_multi.failed.error"),
+		mMGetSuccess:   stats.GetCounter("get_multi.success"),
+		mMGetLatency:   stats.GetTimer("get_multi.latency"),
+		mIncrCount:     stats.GetCounter("incr.count"),
+		mIncrFailed:    stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:   stats.GetCounter("incr.success"),
+		mIncrLatency:   stats.GetTimer("incr.latency"),
+		mCASCount:      stats.GetCounter("compare_and_swap.count"),
+		mCASRetry:      stats.GetCounter("compare_and_swap.retry"),
+		mCASMismatch:   stats.GetCounter("compare_and_swap.failed.mismatch"),
+		mCASFailedErr:  stats.GetCounter("compare_and_swap.failed.error"),
+		mCASSuccess:    stats.GetCounter("compare_and_swap.success"),
+		mCASLatency:    stats.GetTimer("compare_and_swap.latency"),
 
 		retryPeriod: retryPeriod,
 		mc:          memcache.New(addresses...),
@@ -143,12 +180,34 @@ func NewMemcached(
 
 //------------------------------------------------------------------------------
 
+// memcachedMaxRelativeTTL is the largest expiration that memcached treats as a
+// number of seconds rather than a unix timestamp.
+const memcachedMaxRelativeTTL = 60 * 60 * 24 * 30
+
+// expirationFor returns the memcached expiration of an optional TTL override.
+func (m *Memcached) expirationFor(ttl *time.Duration) int32 {
+	if ttl == nil {
+		return m.conf.Memcached.TTL
+	}
+	if *ttl <= 0 {
+		return 0
+	}
+	seconds := int64(*ttl / time.Second)
+	if seconds == 0 {
+		seconds = 1
+	}
+	if seconds > memcachedMaxRelativeTTL {
+		return int32(time.Now().Add(*ttl).Unix())
+	}
+	return int32(seconds)
+}
+
 // getItemFor returns a memcache.Item object ready to be stored in memcache
-func (m *Memcached) getItemFor(key string, value []byte) *memcache.Item {
+func (m *Memcached) getItemFor(key string, value []byte, ttl *time.Duration) *memcache.Item {
 	return &memcache.Item{
 		Key:        m.conf.Memcached.Prefix + key,
 		Value:      value,
-		Expiration: m.conf.Memcached.TTL,
+		Expiration: m.expirationFor(ttl),
 	}
 }
 
@@ -179,17 +238,61 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 	return item.Value, err
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (m *Memcached) GetMulti(keys ...string) (map[string][]byte, error) {
+	m.mMGetCount.Incr(1)
+	tStarted := time.Now()
+
+	prefixedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		prefixedKeys[i] = m.conf.Memcached.Prefix + k
+	}
+
+	items, err := m.mc.GetMulti(prefixedKeys)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+		m.log.Errorf("Get multi command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mMGetRetry.Incr(1)
+		items, err = m.mc.GetMulti(prefixedKeys)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mMGetLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if err != nil {
+		m.mMGetFailed.Incr(1)
+		return nil, err
+	}
+
+	results := make(map[string][]byte, len(items))
+	for i, k := range prefixedKeys {
+		if item, exists := items[k]; exists {
+			results[keys[i]] = item.Value
+		}
+	}
+
+	m.mMGetSuccess.Incr(1)
+	return results, nil
+}
+
 // Set attempts to set the value of a key.
 func (m *Memcached) Set(key string, value []byte) error {
+	return m.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL.
+func (m *Memcached) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.mSetCount.Incr(1)
 	tStarted := time.Now()
 
-	err := m.mc.Set(m.getItemFor(key, value))
+	err := m.mc.Set(m.getItemFor(key, value, ttl))
 	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
 		m.log.Errorf("Set command failed: %v\n", err)
 		<-time.After(m.retryPeriod)
 		m.mSetRetry.Incr(1)
-		err = m.mc.Set(m.getItemFor(key, value))
+		err = m.mc.Set(m.getItemFor(key, value, ttl))
 	}
 	if err != nil {
 		m.mSetFailed.Incr(1)
@@ -216,13 +319,31 @@ func (m *Memcached) SetMulti(items map[string][]byte) error {
 	return nil
 }
 
+// SetMultiWithTTL attempts to set the value of multiple keys, each with their
+// own TTL, returns an error if any keys fail.
+func (m *Memcached) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := m.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists or if the operation fails.
 func (m *Memcached) Add(key string, value []byte) error {
+	return m.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with a TTL only if the key does
+// not already exist and returns an error if the key already exists or if the
+// operation fails.
+func (m *Memcached) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.mAddCount.Incr(1)
 	tStarted := time.Now()
 
-	err := m.mc.Add(m.getItemFor(key, value))
+	err := m.mc.Add(m.getItemFor(key, value, ttl))
 	if memcache.ErrNotStored == err {
 		m.mAddFailedDupe.Incr(1)
 
@@ -236,7 +357,7 @@ func (m *Memcached) Add(key string, value []byte) error {
 		m.log.Errorf("Add command failed: %v\n", err)
 		<-time.After(m.retryPeriod)
 		m.mAddRetry.Incr(1)
-		if err := m.mc.Add(m.getItemFor(key, value)); memcache.ErrNotStored == err {
+		if err := m.mc.Add(m.getItemFor(key, value, ttl)); memcache.ErrNotStored == err {
 			m.mAddFailedDupe.Incr(1)
 
 			latency := int64(time.Since(tStarted))
@@ -259,6 +380,114 @@ func (m *Memcached) Add(key string, value []byte) error {
 	return err
 }
 
+func (m *Memcached) incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	prefixedKey := m.conf.Memcached.Prefix + key
+	for {
+		var value uint64
+		var err error
+		if delta >= 0 {
+			value, err = m.mc.Increment(prefixedKey, uint64(delta))
+		} else {
+			value, err = m.mc.Decrement(prefixedKey, uint64(-delta))
+		}
+		if err != memcache.ErrCacheMiss {
+			return int64(value), err
+		}
+
+		start := delta
+		if start < 0 {
+			start = 0
+		}
+		err = m.mc.Add(m.getItemFor(key, []byte(strconv.FormatInt(start, 10)), ttl))
+		if err != memcache.ErrNotStored {
+			return start, err
+		}
+		// The key was created after our increment, try again.
+	}
+}
+
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. A key that does not exist is created with the TTL, the TTL of an
+// existing key is not changed.
+func (m *Memcached) Incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	m.mIncrCount.Incr(1)
+	tStarted := time.Now()
+
+	// Increments are not idempotent and are therefore not retried.
+	value, err := m.incr(key, delta, ttl)
+	if err != nil {
+		m.log.Errorf("Incr command failed: %v\n", err)
+		m.mIncrFailed.Incr(1)
+	} else {
+		m.mIncrSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mIncrLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	return value, err
+}
+
+func (m *Memcached) compareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	if old == nil {
+		if err := m.mc.Add(m.getItemFor(key, new, ttl)); err != memcache.ErrNotStored {
+			return err
+		}
+		return types.ErrKeyAlreadyExists
+	}
+
+	item, err := m.mc.Get(m.conf.Memcached.Prefix + key)
+	if err == memcache.ErrCacheMiss {
+		return types.ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err = compareValues(true, item.Value, old); err != nil {
+		return err
+	}
+
+	item.Value = new
+	item.Expiration = m.expirationFor(ttl)
+	switch err = m.mc.CompareAndSwap(item); err {
+	case memcache.ErrCASConflict:
+		return types.ErrKeyValueMismatch
+	case memcache.ErrNotStored:
+		return types.ErrKeyNotFound
+	}
+	return err
+}
+
+// CompareAndSwap atomically sets the value of a key with a TTL only if its
+// current value matches old, where a nil old value matches only a key that does
+// not exist.
+func (m *Memcached) CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	m.mCASCount.Incr(1)
+	tStarted := time.Now()
+
+	err := m.compareAndSwap(key, old, new, ttl)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil && !isCASMismatch(err); i++ {
+		m.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mCASRetry.Incr(1)
+		err = m.compareAndSwap(key, old, new, ttl)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mCASLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if isCASMismatch(err) {
+		m.mCASMismatch.Incr(1)
+	} else if err != nil {
+		m.mCASFailedErr.Incr(1)
+	} else {
+		m.mCASSuccess.Incr(1)
+	}
+	return err
+}
+
 // Delete attempts to remove a key.
 func (m *Memcached) Delete(key string) error {
 	m.mDelCount.Incr(1)
@@ -53,6 +53,16 @@ func TestMemcachedIntegration(t *testing.T) {
 	t.Run("TestMemcachedGetAndSet", func(te *testing.T) {
 		testMemcachedGetAndSet(addrs, te)
 	})
+	t.Run("TestMemcachedExtended", func(te *testing.T) {
+		conf := NewConfig()
+		conf.Memcached.Addresses = addrs
+
+		c, err := NewMemcached(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			te.Fatal(err)
+		}
+		testCacheExtended(te, c.(types.CacheExtended), "benthos_test_extended_")
+	})
 }
 
 func testMemcachedAddDuplicate(addrs []string, t *testing.T) {
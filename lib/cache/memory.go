@@ -2,6 +2,7 @@ package cache
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -37,7 +38,13 @@ memory:
 ` + "```" + `
 
 These values can be overridden during execution, at which point the configured
-TTL is respected as usual.`,
+TTL is respected as usual.
+
+The TTL of items can be overridden for each operation by components that support
+it, such as the ` + "[`cache` processor](/docs/components/processors/cache)" + `.
+Atomic operations such as increments and compare-and-swaps treat items that have
+expired as though they do not exist, even before they are removed by a
+compaction.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("ttl", "The TTL of each item in seconds. After this period an item will be eligible for removal during the next compaction."),
 			docs.FieldCommon("compaction_interval", "The period of time to wait before each compaction, at which point expired items are removed."),
@@ -76,6 +83,14 @@ func NewMemoryConfig() MemoryConfig {
 type item struct {
 	value []byte
 	ts    time.Time
+	ttl   time.Duration
+}
+
+func (i item) expired() bool {
+	if i.ts.IsZero() {
+		return false
+	}
+	return time.Since(i.ts) >= i.ttl
 }
 
 // Memory is a memory based cache implementation.
@@ -127,10 +142,7 @@ func (m *Memory) compaction() {
 	}
 	m.mCompactions.Incr(1)
 	for k, v := range m.items {
-		if v.ts.IsZero() {
-			continue
-		}
-		if time.Since(v.ts) >= m.ttl {
+		if v.expired() {
 			delete(m.items, k)
 		}
 	}
@@ -149,11 +161,38 @@ func (m *Memory) Get(key string) ([]byte, error) {
 	return k.value, nil
 }
 
+func (m *Memory) newItem(value []byte, ttl *time.Duration) item {
+	i := item{value: value, ts: time.Now(), ttl: m.ttl}
+	if ttl != nil {
+		i.ttl = *ttl
+	}
+	return i
+}
+
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (m *Memory) GetMulti(keys ...string) (map[string][]byte, error) {
+	results := make(map[string][]byte, len(keys))
+	m.RLock()
+	for _, key := range keys {
+		if k, exists := m.items[key]; exists {
+			results[key] = k.value
+		}
+	}
+	m.RUnlock()
+	return results, nil
+}
+
 // Set attempts to set the value of a key.
 func (m *Memory) Set(key string, value []byte) error {
+	return m.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL.
+func (m *Memory) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.Lock()
 	m.compaction()
-	m.items[key] = item{value: value, ts: time.Now()}
+	m.items[key] = m.newItem(value, ttl)
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
 	return nil
@@ -165,7 +204,20 @@ func (m *Memory) SetMulti(items map[string][]byte) error {
 	m.Lock()
 	m.compaction()
 	for k, v := range items {
-		m.items[k] = item{value: v, ts: time.Now()}
+		m.items[k] = m.newItem(v, nil)
+	}
+	m.mKeys.Set(int64(len(m.items)))
+	m.Unlock()
+	return nil
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys, each with their
+// own TTL, returns an error if any keys fail.
+func (m *Memory) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	m.Lock()
+	m.compaction()
+	for k, v := range items {
+		m.items[k] = m.newItem(v.Value, v.TTL)
 	}
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
@@ -175,18 +227,70 @@ func (m *Memory) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (m *Memory) Add(key string, value []byte) error {
+	return m.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with a TTL only if the key does
+// not already exist and returns an error if the key already exists.
+func (m *Memory) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.Lock()
 	if _, exists := m.items[key]; exists {
 		m.Unlock()
 		return types.ErrKeyAlreadyExists
 	}
 	m.compaction()
-	m.items[key] = item{value: value, ts: time.Now()}
+	m.items[key] = m.newItem(value, ttl)
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
 	return nil
 }
 
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. A key that does not exist is created with the TTL, the TTL of an
+// existing key is not changed.
+func (m *Memory) Incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.compaction()
+
+	i, exists := m.items[key]
+	if !exists || i.expired() {
+		i = m.newItem(nil, ttl)
+	}
+
+	value, err := incrValue(i.value, delta)
+	if err != nil {
+		return 0, err
+	}
+	i.value = []byte(strconv.FormatInt(value, 10))
+	m.items[key] = i
+	m.mKeys.Set(int64(len(m.items)))
+	return value, nil
+}
+
+// CompareAndSwap atomically sets the value of a key with a TTL only if its
+// current value matches old, where a nil old value matches only a key that does
+// not exist.
+func (m *Memory) CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.compaction()
+
+	i, exists := m.items[key]
+	if exists && i.expired() {
+		exists = false
+	}
+	if err := compareValues(exists, i.value, old); err != nil {
+		return err
+	}
+
+	m.items[key] = m.newItem(new, ttl)
+	m.mKeys.Set(int64(len(m.items)))
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (m *Memory) Delete(key string) error {
 	m.Lock()
@@ -3,6 +3,7 @@ package cache
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -164,3 +165,63 @@ func TestMemoryCacheInitValues(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestMemoryCacheExtended(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCacheExtended(t, c.(types.CacheExtended), "")
+}
+
+func TestMemoryCacheTTLOverride(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.CompactionInterval = "1ns"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := c.(types.CacheExtended)
+
+	shortTTL := time.Millisecond
+	if err = ec.SetWithTTL("foo", []byte("1"), &shortTTL); err != nil {
+		t.Fatal(err)
+	}
+	if err = ec.Set("bar", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ec.Incr("baz", 1, &shortTTL); err != nil {
+		t.Fatal(err)
+	}
+
+	<-time.After(time.Millisecond * 5)
+
+	if v, err := ec.Incr("baz", 1, nil); err != nil {
+		t.Fatal(err)
+	} else if v != 1 {
+		t.Errorf("Expected expired counter to be reset: %v", v)
+	}
+
+	// Trigger compaction
+	if err = ec.Set("buz", []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ec.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if _, err = ec.Get("bar"); err != nil {
+		t.Error(err)
+	}
+	if v, err := ec.Incr("baz", -3, nil); err != nil {
+		t.Error(err)
+	} else if v != -2 {
+		t.Errorf("Wrong result: %v != %v", v, -2)
+	}
+}
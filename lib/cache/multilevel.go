@@ -22,6 +22,12 @@ For the Add command this cache first checks all levels except the last for the
 key. If the key is not found it is added to the final cache level, if that
 succeeds all higher cache levels have the key set.
 
+Increments and compare-and-swaps are performed against the final cache level,
+and when they succeed the key is removed from all higher cache levels, which
+prevents concurrent operations from leaving stale results in those levels.
+Operations with TTL overrides, increments and compare-and-swaps are only
+supported when each cache level also supports them.
+
 ## Examples
 
 It's possible to use multilevel to create a warm cache in memory above a cold
@@ -120,6 +126,54 @@ func (l *Multilevel) setUpToLevelPassive(i int, key string, value []byte) {
 	}
 }
 
+func (l *Multilevel) getExtended(name string) (types.CacheExtended, error) {
+	c, err := l.mgr.GetCache(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to access cache '%v': %v", name, err)
+	}
+	ec, ok := c.(types.CacheExtended)
+	if !ok {
+		return nil, fmt.Errorf("cache '%v' does not support TTL overrides or atomic operations", name)
+	}
+	return ec, nil
+}
+
+// getAllExtended returns all cache levels as extended caches, or an error if
+// any level does not support them.
+func (l *Multilevel) getAllExtended() ([]types.CacheExtended, error) {
+	caches := make([]types.CacheExtended, 0, len(l.caches))
+	for _, name := range l.caches {
+		c, err := l.getExtended(name)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, c)
+	}
+	return caches, nil
+}
+
+// setUpperLevels sets a key for all levels above the final level.
+func setUpperLevels(caches []types.CacheExtended, key string, value []byte, ttl *time.Duration) error {
+	for i := len(caches) - 2; i >= 0; i-- {
+		if err := caches[i].SetWithTTL(key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteUpperLevels removes a key from all levels above the final level. Since
+// the operation on the final level has already succeeded failures are logged
+// rather than returned, which would otherwise prompt a retry of operations that
+// are not idempotent.
+func (l *Multilevel) deleteUpperLevels(caches []types.CacheExtended, key string) {
+	for i := len(caches) - 2; i >= 0; i-- {
+		if err := caches[i].Delete(key); err != nil && err != types.ErrKeyNotFound {
+			l.log.Errorf("Failed to remove key '%v' from cache '%v': %v\n", key, l.caches[i], err)
+		}
+	}
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (l *Multilevel) Get(key string) ([]byte, error) {
@@ -141,14 +195,79 @@ func (l *Multilevel) Get(key string) ([]byte, error) {
 	return nil, types.ErrKeyNotFound
 }
 
-// Set attempts to set the value of a key.
-func (l *Multilevel) Set(key string, value []byte) error {
-	for _, name := range l.caches {
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (l *Multilevel) GetMulti(keys ...string) (map[string][]byte, error) {
+	results := make(map[string][]byte, len(keys))
+	remaining := keys
+	for i, name := range l.caches {
+		if len(remaining) == 0 {
+			break
+		}
 		c, err := l.mgr.GetCache(name)
 		if err != nil {
-			return fmt.Errorf("unable to access cache '%v': %v", name, err)
+			return nil, fmt.Errorf("unable to access cache '%v': %v", name, err)
 		}
-		if err = c.Set(key, value); err != nil {
+
+		var found map[string][]byte
+		if ec, ok := c.(types.CacheExtended); ok {
+			if found, err = ec.GetMulti(remaining...); err != nil {
+				return nil, err
+			}
+		} else {
+			found = map[string][]byte{}
+			for _, k := range remaining {
+				data, err := c.Get(k)
+				if err != nil {
+					if err != types.ErrKeyNotFound {
+						return nil, err
+					}
+					continue
+				}
+				found[k] = data
+			}
+		}
+
+		var notFound []string
+		for _, k := range remaining {
+			data, exists := found[k]
+			if !exists {
+				notFound = append(notFound, k)
+				continue
+			}
+			l.setUpToLevelPassive(i, k, data)
+			results[k] = data
+		}
+		remaining = notFound
+	}
+	return results, nil
+}
+
+// Set attempts to set the value of a key.
+func (l *Multilevel) Set(key string, value []byte) error {
+	return l.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL.
+func (l *Multilevel) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	if ttl == nil {
+		for _, name := range l.caches {
+			c, err := l.mgr.GetCache(name)
+			if err != nil {
+				return fmt.Errorf("unable to access cache '%v': %v", name, err)
+			}
+			if err = c.Set(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	caches, err := l.getAllExtended()
+	if err != nil {
+		return err
+	}
+	for _, c := range caches {
+		if err = c.SetWithTTL(key, value, ttl); err != nil {
 			return err
 		}
 	}
@@ -170,9 +289,30 @@ func (l *Multilevel) SetMulti(items map[string][]byte) error {
 	return nil
 }
 
+// SetMultiWithTTL attempts to set the value of multiple keys, each with their
+// own TTL, returns an error if any keys fail.
+func (l *Multilevel) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	caches, err := l.getAllExtended()
+	if err != nil {
+		return err
+	}
+	for _, c := range caches {
+		if err = c.SetMultiWithTTL(items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (l *Multilevel) Add(key string, value []byte) error {
+	return l.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with a TTL only if the key does
+// not already exist and returns an error if the key already exists.
+func (l *Multilevel) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	for i := 0; i < len(l.caches)-1; i++ {
 		c, err := l.mgr.GetCache(l.caches[i])
 		if err != nil {
@@ -186,6 +326,16 @@ func (l *Multilevel) Add(key string, value []byte) error {
 			return types.ErrKeyAlreadyExists
 		}
 	}
+	if ttl != nil {
+		caches, err := l.getAllExtended()
+		if err != nil {
+			return err
+		}
+		if err = caches[len(caches)-1].AddWithTTL(key, value, ttl); err != nil {
+			return err
+		}
+		return setUpperLevels(caches, key, value, ttl)
+	}
 	c, err := l.mgr.GetCache(l.caches[len(l.caches)-1])
 	if err != nil {
 		return fmt.Errorf("unable to access cache '%v': %v", l.caches[len(l.caches)-1], err)
@@ -204,6 +354,37 @@ func (l *Multilevel) Add(key string, value []byte) error {
 	return nil
 }
 
+// Incr atomically adds a delta to the integer value of a key in the final cache
+// level and returns the result, the key is then removed from all higher levels.
+func (l *Multilevel) Incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	caches, err := l.getAllExtended()
+	if err != nil {
+		return 0, err
+	}
+	value, err := caches[len(caches)-1].Incr(key, delta, ttl)
+	if err != nil {
+		return 0, err
+	}
+	l.deleteUpperLevels(caches, key)
+	return value, nil
+}
+
+// CompareAndSwap atomically sets the value of a key with a TTL in the final
+// cache level only if its current value matches old, where a nil old value
+// matches only a key that does not exist. When successful the key is then
+// removed from all higher levels.
+func (l *Multilevel) CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	caches, err := l.getAllExtended()
+	if err != nil {
+		return err
+	}
+	if err = caches[len(caches)-1].CompareAndSwap(key, old, new, ttl); err != nil {
+		return err
+	}
+	l.deleteUpperLevels(caches, key)
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (l *Multilevel) Delete(key string) error {
 	for _, name := range l.caches {
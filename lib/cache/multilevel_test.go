@@ -3,6 +3,7 @@ package cache
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -453,3 +454,112 @@ func TestMultilevelCacheAddMoreCaches(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestMultilevelCacheExtended(t *testing.T) {
+	memCache1, err := NewMemory(NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	memCache2, err := NewMemory(NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := fakeMgr{
+		caches: map[string]types.Cache{
+			"foo": memCache1,
+			"bar": memCache2,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeMultilevel
+	conf.Multilevel = []string{"foo", "bar"}
+
+	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := c.(types.CacheExtended)
+
+	testCacheExtended(t, ec, "")
+
+	err = memCache2.Set("foo", []byte("test value 1"))
+	assert.Equal(t, err, nil)
+
+	vals, err := ec.GetMulti("foo", "bar")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals, map[string][]byte{"foo": []byte("test value 1")})
+
+	val, err := memCache1.Get("foo")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, []byte("test value 1"))
+
+	err = memCache1.Set("bar", []byte("stale"))
+	assert.Equal(t, err, nil)
+
+	i, err := ec.Incr("bar", 3, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, i, int64(3))
+
+	_, err = memCache1.Get("bar")
+	assert.Equal(t, err, types.ErrKeyNotFound)
+
+	val, err = memCache2.Get("bar")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, []byte("3"))
+
+	val, err = ec.Get("bar")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, []byte("3"))
+
+	err = ec.CompareAndSwap("bar", []byte("3"), []byte("test value 2"), nil)
+	assert.Equal(t, err, nil)
+
+	_, err = memCache1.Get("bar")
+	assert.Equal(t, err, types.ErrKeyNotFound)
+
+	val, err = memCache2.Get("bar")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, []byte("test value 2"))
+}
+
+func TestMultilevelCacheExtendedUnsupported(t *testing.T) {
+	memCache1, err := NewMemory(NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	memCache2, err := NewMemory(NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := fakeMgr{
+		caches: map[string]types.Cache{
+			"foo": memCache1,
+			"bar": struct{ types.Cache }{memCache2},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeMultilevel
+	conf.Multilevel = []string{"foo", "bar"}
+
+	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := c.(types.CacheExtended)
+
+	ttl := time.Minute
+	assert.Error(t, ec.SetWithTTL("foo", []byte("test value 1"), &ttl))
+	assert.Error(t, ec.CompareAndSwap("foo", nil, []byte("test value 1"), nil))
+
+	_, err = ec.Incr("foo", 1, nil)
+	assert.Error(t, err)
+
+	err = memCache2.Set("foo", []byte("test value 2"))
+	assert.Equal(t, err, nil)
+
+	vals, err := ec.GetMulti("foo", "bar")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals, map[string][]byte{"foo": []byte("test value 2")})
+}
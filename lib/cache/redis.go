@@ -21,6 +21,12 @@ func init() {
 		Summary: `
 Use a Redis instance as a cache. The expiration can be set to zero or an empty
 string in order to set no expiration.`,
+		Description: `
+The expiration can be overridden for each operation by components that support
+it, such as the ` + "[`cache` processor](/docs/components/processors/cache)" + `.
+Increments and compare-and-swaps are performed atomically with Lua scripts.
+Failed increments are not retried, since a request that failed in transit might
+still have been applied.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
 				"url", "The URL of the target Redis server. Database is optional and is supplied as the URL path.",
@@ -89,6 +95,21 @@ type Redis struct {
 	mDelNotFound   metrics.StatCounter
 	mDelSuccess    metrics.StatCounter
 	mDelLatency    metrics.StatTimer
+	mMGetCount     metrics.StatCounter
+	mMGetRetry     metrics.StatCounter
+	mMGetFailed    metrics.StatCounter
+	mMGetSuccess   metrics.StatCounter
+	mMGetLatency   metrics.StatTimer
+	mIncrCount     metrics.StatCounter
+	mIncrFailed    metrics.StatCounter
+	mIncrSuccess   metrics.StatCounter
+	mIncrLatency   metrics.StatTimer
+	mCASCount      metrics.StatCounter
+	mCASRetry      metrics.StatCounter
+	mCASMismatch   metrics.StatCounter
+	mCASFailedErr  metrics.StatCounter
+	mCASSuccess    metrics.StatCounter
+	mCASLatency    metrics.StatTimer
 
 	client      *redis.Client
 	ttl         time.Duration
@@ -175,6 +196,21 @@ func NewRedis(
 		mDelNotFound:   stats.GetCounter("delete.failed.not_found"),
 		mDelSuccess:    stats.GetCounter("delete.success"),
 		mDelLatency:    stats.GetTimer("delete.latency"),
+		mMGetCount:     stats.GetCounter("get_multi.count"),
+		mMGetRetry:     stats.GetCounter("get_multi.retry"),
+		mMGetFailed:    stats.GetCounter("get_multi.failed.error"),
+		mMGetSuccess:   stats.GetCounter("get_multi.success"),
+		mMGetLatency:   stats.GetTimer("get_multi.latency"),
+		mIncrCount:     stats.GetCounter("incr.count"),
+		mIncrFailed:    stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:   stats.GetCounter("incr.success"),
+		mIncrLatency:   stats.GetTimer("incr.latency"),
+		mCASCount:      stats.GetCounter("compare_and_swap.count"),
+		mCASRetry:      stats.GetCounter("compare_and_swap.retry"),
+		mCASMismatch:   stats.GetCounter("compare_and_swap.failed.mismatch"),
+		mCASFailedErr:  stats.GetCounter("compare_and_swap.failed.error"),
+		mCASSuccess:    stats.GetCounter("compare_and_swap.success"),
+		mCASLatency:    stats.GetTimer("compare_and_swap.latency"),
 
 		retryPeriod: retryPeriod,
 		ttl:         ttl,
@@ -185,6 +221,63 @@ func NewRedis(
 
 //------------------------------------------------------------------------------
 
+// redisIncr adds a delta to the integer value of a key, and when the key did
+// not previously exist and a TTL is provided the key is given that TTL.
+var redisIncr = redis.NewScript(`
+local existed = redis.call("EXISTS", KEYS[1])
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if existed == 0 and tonumber(ARGV[2]) > 0 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// redisCompareAndSwap sets the value of a key only if its current value matches
+// an expected value, or when ARGV[1] is 0 only if the key does not exist.
+// Returns 0 when the key was set, 1 when the key already exists, 2 when the key
+// does not exist and 3 when the values do not match.
+var redisCompareAndSwap = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if ARGV[1] == "0" then
+  if current then
+    return 1
+  end
+else
+  if not current then
+    return 2
+  end
+  if current ~= ARGV[2] then
+    return 3
+  end
+end
+if tonumber(ARGV[4]) > 0 then
+  redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+else
+  redis.call("SET", KEYS[1], ARGV[3])
+end
+return 0
+`)
+
+func (r *Redis) ttlFor(ttl *time.Duration) time.Duration {
+	if ttl != nil {
+		return *ttl
+	}
+	return r.ttl
+}
+
+// ttlMillis returns a TTL in milliseconds for use within scripts, rounding
+// positive durations below a millisecond up in order to avoid removing the TTL.
+func (r *Redis) ttlMillis(ttl *time.Duration) int64 {
+	d := r.ttlFor(ttl)
+	if d <= 0 {
+		return 0
+	}
+	if ms := int64(d / time.Millisecond); ms > 0 {
+		return ms
+	}
+	return 1
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist or if the operation failed.
 func (r *Redis) Get(key string) ([]byte, error) {
@@ -223,19 +316,65 @@ func (r *Redis) Get(key string) ([]byte, error) {
 	return []byte(res), nil
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (r *Redis) GetMulti(keys ...string) (map[string][]byte, error) {
+	r.mMGetCount.Incr(1)
+	tStarted := time.Now()
+
+	prefixedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		prefixedKeys[i] = r.prefix + k
+	}
+
+	res, err := r.client.MGet(prefixedKeys...).Result()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Get multi command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mMGetRetry.Incr(1)
+		res, err = r.client.MGet(prefixedKeys...).Result()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mMGetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mMGetFailed.Incr(1)
+		return nil, err
+	}
+
+	results := make(map[string][]byte, len(keys))
+	for i, v := range res {
+		if s, ok := v.(string); ok && i < len(keys) {
+			results[keys[i]] = []byte(s)
+		}
+	}
+
+	r.mMGetSuccess.Incr(1)
+	return results, nil
+}
+
 // Set attempts to set the value of a key.
 func (r *Redis) Set(key string, value []byte) error {
+	return r.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL.
+func (r *Redis) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	r.mSetCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
 
-	err := r.client.Set(key, value, r.ttl).Err()
+	expiration := r.ttlFor(ttl)
+
+	err := r.client.Set(key, value, expiration).Err()
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Set command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mSetRetry.Incr(1)
-		err = r.client.Set(key, value, r.ttl).Err()
+		err = r.client.Set(key, value, expiration).Err()
 	}
 	if err != nil {
 		r.mSetFailed.Incr(1)
@@ -262,15 +401,34 @@ func (r *Redis) SetMulti(items map[string][]byte) error {
 	return nil
 }
 
+// SetMultiWithTTL attempts to set the value of multiple keys, each with their
+// own TTL, returns an error if any keys fail.
+func (r *Redis) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := r.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists or if the operation fails.
 func (r *Redis) Add(key string, value []byte) error {
+	return r.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with a TTL only if the key does
+// not already exist and returns an error if the key already exists or if the
+// operation fails.
+func (r *Redis) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	r.mAddCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
+	expiration := r.ttlFor(ttl)
 
-	set, err := r.client.SetNX(key, value, r.ttl).Result()
+	set, err := r.client.SetNX(key, value, expiration).Result()
 	if err == nil && !set {
 		r.mAddFailedDupe.Incr(1)
 
@@ -284,7 +442,7 @@ func (r *Redis) Add(key string, value []byte) error {
 		r.log.Errorf("Add command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mAddRetry.Incr(1)
-		if set, err = r.client.SetNX(key, value, r.ttl).Result(); err == nil && !set {
+		if set, err = r.client.SetNX(key, value, expiration).Result(); err == nil && !set {
 			r.mAddFailedDupe.Incr(1)
 
 			latency := int64(time.Since(tStarted))
@@ -307,6 +465,80 @@ func (r *Redis) Add(key string, value []byte) error {
 	return err
 }
 
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. A key that does not exist is created with the TTL, the TTL of an
+// existing key is not changed.
+func (r *Redis) Incr(key string, delta int64, ttl *time.Duration) (int64, error) {
+	r.mIncrCount.Incr(1)
+	tStarted := time.Now()
+
+	keys := []string{r.prefix + key}
+	ttlMillis := r.ttlMillis(ttl)
+
+	// Increments are not idempotent and are therefore not retried.
+	value, err := redisIncr.Run(r.client, keys, delta, ttlMillis).Int64()
+	if err != nil {
+		r.log.Errorf("Incr command failed: %v\n", err)
+		r.mIncrFailed.Incr(1)
+	} else {
+		r.mIncrSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mIncrLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return value, err
+}
+
+// CompareAndSwap atomically sets the value of a key with a TTL only if its
+// current value matches old, where a nil old value matches only a key that does
+// not exist.
+func (r *Redis) CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error {
+	r.mCASCount.Incr(1)
+	tStarted := time.Now()
+
+	keys := []string{r.prefix + key}
+	expectExists := 1
+	if old == nil {
+		expectExists = 0
+	}
+	args := []interface{}{expectExists, old, new, r.ttlMillis(ttl)}
+
+	res, err := redisCompareAndSwap.Run(r.client, keys, args...).Int64()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mCASRetry.Incr(1)
+		res, err = redisCompareAndSwap.Run(r.client, keys, args...).Int64()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mCASLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mCASFailedErr.Incr(1)
+		return err
+	}
+
+	switch res {
+	case 1:
+		err = types.ErrKeyAlreadyExists
+	case 2:
+		err = types.ErrKeyNotFound
+	case 3:
+		err = types.ErrKeyValueMismatch
+	}
+	if err != nil {
+		r.mCASMismatch.Incr(1)
+		return err
+	}
+
+	r.mCASSuccess.Incr(1)
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (r *Redis) Delete(key string) error {
 	r.mDelCount.Incr(1)
@@ -55,6 +55,16 @@ func TestRedisIntegration(t *testing.T) {
 	t.Run("TestRedisGetAndSet", func(te *testing.T) {
 		testRedisGetAndSet(url, te)
 	})
+	t.Run("TestRedisExtended", func(te *testing.T) {
+		conf := NewConfig()
+		conf.Redis.URL = url
+
+		c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			te.Fatal(err)
+		}
+		testCacheExtended(te, c.(types.CacheExtended), "benthos_test_extended_")
+	})
 }
 
 func testRedisAddDuplicate(url string, t *testing.T) {
@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/pipeline"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/stream/manager/store"
 	"github.com/Jeffail/benthos/v3/lib/tracer"
 	"gopkg.in/yaml.v3"
 )
@@ -27,6 +28,10 @@ type Type struct {
 	Metrics            metrics.Config `json:"metrics" yaml:"metrics"`
 	Tracer             tracer.Config  `json:"tracer" yaml:"tracer"`
 	SystemCloseTimeout string         `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+
+	// StreamStore is optional, when set streams mode persists the configs of
+	// streams managed via the REST API within the store.
+	StreamStore *store.Config `json:"stream_store,omitempty" yaml:"stream_store,omitempty"`
 }
 
 // New returns a new configuration with default values.
@@ -50,11 +55,13 @@ type SanitisedConfig struct {
 	Buffer             interface{} `json:"buffer" yaml:"buffer"`
 	Pipeline           interface{} `json:"pipeline" yaml:"pipeline"`
 	Output             interface{} `json:"output" yaml:"output"`
+	DeadLetter         interface{} `json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
 	Manager            interface{} `json:"resources" yaml:"resources"`
 	Logger             interface{} `json:"logger" yaml:"logger"`
 	Metrics            interface{} `json:"metrics" yaml:"metrics"`
 	Tracer             interface{} `json:"tracer" yaml:"tracer"`
 	SystemCloseTimeout interface{} `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	StreamStore        interface{} `json:"stream_store,omitempty" yaml:"stream_store,omitempty"`
 }
 
 // Sanitised returns a sanitised copy of the Benthos configuration, meaning
@@ -78,6 +85,13 @@ func (c Type) Sanitised() (*SanitisedConfig, error) {
 		return nil, err
 	}
 
+	var deadLetterConf interface{}
+	if c.DeadLetter != nil {
+		if deadLetterConf, err = stream.SanitiseDeadLetterConfig(*c.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
 	var bufConf interface{}
 	bufConf, err = buffer.SanitiseConfig(c.Buffer)
 	if err != nil {
@@ -102,17 +116,26 @@ func (c Type) Sanitised() (*SanitisedConfig, error) {
 		return nil, err
 	}
 
+	var storeConf interface{}
+	if c.StreamStore != nil {
+		if storeConf, err = store.SanitiseConfig(*c.StreamStore); err != nil {
+			return nil, err
+		}
+	}
+
 	return &SanitisedConfig{
 		HTTP:               c.HTTP,
 		Input:              inConf,
 		Buffer:             bufConf,
 		Pipeline:           pipeConf,
 		Output:             outConf,
+		DeadLetter:         deadLetterConf,
 		Manager:            mgrConf,
 		Logger:             c.Logger,
 		Metrics:            metConf,
 		Tracer:             tracConf,
 		SystemCloseTimeout: c.SystemCloseTimeout,
+		StreamStore:        storeConf,
 	}, nil
 }
 
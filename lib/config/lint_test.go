@@ -29,6 +29,25 @@ func TestConfigLints(t *testing.T) {
   kafka: {}`,
 			lints: []string{"line 3: path 'input': Key 'kafka' found but is ignored"},
 		},
+		{
+			name: "dead letter object type",
+			conf: `dead_letter:
+  max_attempts: 3
+  output:
+    type: stdout
+    kafka: {}`,
+			lints: []string{"line 5: path 'dead_letter.output': Key 'kafka' found but is ignored"},
+		},
+		{
+			name: "stream store object type",
+			conf: `stream_store:
+  type: consul
+  consul:
+    prefix: foo/
+  directory:
+    path: ./foo`,
+			lints: []string{"line 6: path 'stream_store': Key 'directory' found but is ignored"},
+		},
 		{
 			name: "broker object type",
 			conf: `input:
@@ -78,16 +78,19 @@ const (
 	TypeKinesis         = "kinesis"
 	TypeKinesisBalanced = "kinesis_balanced"
 	TypeMQTT            = "mqtt"
+	TypeMySQLCDC        = "mysql_cdc"
 	TypeNanomsg         = "nanomsg"
 	TypeNATS            = "nats"
 	TypeNATSStream      = "nats_stream"
 	TypeNSQ             = "nsq"
+	TypePostgresCDC     = "postgres_cdc"
 	TypeReadUntil       = "read_until"
 	TypeRedisList       = "redis_list"
 	TypeRedisPubSub     = "redis_pubsub"
 	TypeRedisStreams    = "redis_streams"
 	TypeResource        = "resource"
 	TypeS3              = "s3"
+	TypeSQLSelect       = "sql_select"
 	TypeSQS             = "sqs"
 	TypeSTDIN           = "stdin"
 	TypeTCP             = "tcp"
@@ -120,17 +123,20 @@ type Config struct {
 	Kinesis         reader.KinesisConfig         `json:"kinesis" yaml:"kinesis"`
 	KinesisBalanced reader.KinesisBalancedConfig `json:"kinesis_balanced" yaml:"kinesis_balanced"`
 	MQTT            reader.MQTTConfig            `json:"mqtt" yaml:"mqtt"`
+	MySQLCDC        reader.MySQLCDCConfig        `json:"mysql_cdc" yaml:"mysql_cdc"`
 	Nanomsg         reader.ScaleProtoConfig      `json:"nanomsg" yaml:"nanomsg"`
 	NATS            reader.NATSConfig            `json:"nats" yaml:"nats"`
 	NATSStream      reader.NATSStreamConfig      `json:"nats_stream" yaml:"nats_stream"`
 	NSQ             reader.NSQConfig             `json:"nsq" yaml:"nsq"`
 	Plugin          interface{}                  `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	PostgresCDC     reader.PostgresCDCConfig     `json:"postgres_cdc" yaml:"postgres_cdc"`
 	ReadUntil       ReadUntilConfig              `json:"read_until" yaml:"read_until"`
 	RedisList       reader.RedisListConfig       `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub     reader.RedisPubSubConfig     `json:"redis_pubsub" yaml:"redis_pubsub"`
 	RedisStreams    reader.RedisStreamsConfig    `json:"redis_streams" yaml:"redis_streams"`
 	Resource        string                       `json:"resource" yaml:"resource"`
 	S3              reader.AmazonS3Config        `json:"s3" yaml:"s3"`
+	SQLSelect       reader.SQLSelectConfig       `json:"sql_select" yaml:"sql_select"`
 	SQS             reader.AmazonSQSConfig       `json:"sqs" yaml:"sqs"`
 	STDIN           STDINConfig                  `json:"stdin" yaml:"stdin"`
 	TCP             TCPConfig                    `json:"tcp" yaml:"tcp"`
@@ -163,17 +169,20 @@ func NewConfig() Config {
 		Kinesis:         reader.NewKinesisConfig(),
 		KinesisBalanced: reader.NewKinesisBalancedConfig(),
 		MQTT:            reader.NewMQTTConfig(),
+		MySQLCDC:        reader.NewMySQLCDCConfig(),
 		Nanomsg:         reader.NewScaleProtoConfig(),
 		NATS:            reader.NewNATSConfig(),
 		NATSStream:      reader.NewNATSStreamConfig(),
 		NSQ:             reader.NewNSQConfig(),
 		Plugin:          nil,
+		PostgresCDC:     reader.NewPostgresCDCConfig(),
 		ReadUntil:       NewReadUntilConfig(),
 		RedisList:       reader.NewRedisListConfig(),
 		RedisPubSub:     reader.NewRedisPubSubConfig(),
 		RedisStreams:    reader.NewRedisStreamsConfig(),
 		Resource:        "",
 		S3:              reader.NewAmazonS3Config(),
+		SQLSelect:       reader.NewSQLSelectConfig(),
 		SQS:             reader.NewAmazonSQSConfig(),
 		STDIN:           NewSTDINConfig(),
 		TCP:             NewTCPConfig(),
@@ -1,6 +1,7 @@
 package input
 
 import (
+	"context"
 	"io"
 	"os"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 )
 
@@ -18,16 +20,15 @@ func init() {
 		constructor: NewFile,
 		Summary: `
 Reads a file, where each line is processed as an individual message.`,
+		Description: `
+The file is consumed as a stream and decoded into messages with a
+[codec](#codec), which defaults to reading each line as a message.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("path", "A path pointing to a file on disk."),
-			docs.FieldCommon("multipart", `
-If set `+"`true`"+` each line is read as a message part, and an empty line
-indicates the end of a message batch, and only then is the batch flushed
-downstream.`),
-			docs.FieldCommon("max_buffer", "Must be larger than the largest line of the target file."),
-			docs.FieldCommon("delimiter", `
-A string that indicates the end of a message within the target file. If left
-empty then line feed (\n) is used.`),
+			codec.ReaderDocs,
+			docs.FieldAdvanced("max_buffer", "The maximum size of a message, which must be larger than the largest message of the target file."),
+			docs.FieldDeprecated("multipart"),
+			docs.FieldDeprecated("delimiter"),
 		},
 	}
 }
@@ -37,6 +38,7 @@ empty then line feed (\n) is used.`),
 // FileConfig contains configuration values for the File input type.
 type FileConfig struct {
 	Path      string `json:"path" yaml:"path"`
+	Codec     string `json:"codec" yaml:"codec"`
 	Multipart bool   `json:"multipart" yaml:"multipart"`
 	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
 	Delim     string `json:"delimiter" yaml:"delimiter"`
@@ -46,6 +48,7 @@ type FileConfig struct {
 func NewFileConfig() FileConfig {
 	return FileConfig{
 		Path:      "",
+		Codec:     "lines",
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
@@ -54,19 +57,38 @@ func NewFileConfig() FileConfig {
 
 //------------------------------------------------------------------------------
 
+// linesCodec returns the codec of an input that also supports the deprecated
+// fields delimiter and multipart, which modify the default lines codec.
+// TODO: V4 Remove this.
+func linesCodec(codecStr, delim string, multipart bool) string {
+	if codecStr != "lines" {
+		return codecStr
+	}
+	if len(delim) > 0 {
+		codecStr = "delim:" + delim
+	}
+	if multipart {
+		codecStr += "/multipart"
+	}
+	return codecStr
+}
+
 // NewFile creates a new File input type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	file, err := os.Open(conf.File.Path)
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.File.MaxBuffer
+	ctor, err := codec.GetReader(linesCodec(conf.File.Codec, conf.File.Delim, conf.File.Multipart), codecConf)
 	if err != nil {
 		return nil, err
 	}
-	delim := conf.File.Delim
-	if len(delim) == 0 {
-		delim = "\n"
+
+	file, err := os.Open(conf.File.Path)
+	if err != nil {
+		return nil, err
 	}
 
-	rdr, err := reader.NewLines(
-		func() (io.Reader, error) {
+	rdr := reader.NewStream(
+		func(ctx context.Context) (io.ReadCloser, error) {
 			// Swap so this only works once since we don't want to read the file
 			// multiple times.
 			if file == nil {
@@ -76,15 +98,9 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 			file = nil
 			return sendFile, nil
 		},
-		func() {},
-		reader.OptLinesSetDelimiter(delim),
-		reader.OptLinesSetMaxBuffer(conf.File.MaxBuffer),
-		reader.OptLinesSetMultipart(conf.File.Multipart),
+		func(ctx context.Context) {},
+		ctor,
 	)
-	if err != nil {
-		return nil, err
-	}
-
 	return NewAsyncReader(TypeFile, true, reader.NewAsyncPreserver(rdr), log, stats)
 }
 
@@ -5,6 +5,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 )
 
@@ -15,11 +16,14 @@ func init() {
 		constructor: NewFiles,
 		Summary: `
 Reads files from a path, where each discrete file will be consumed as a single
-message.`,
+message by default.`,
 		Description: `
-The path can either point to a single file (resulting in only a single message)
-or a directory, in which case the directory will be walked and each file found
-will become a message.
+The path can either point to a single file or a directory, in which case the
+directory will be walked and each file found will be consumed.
+
+Files are consumed as streams and can be decoded into multiple messages with a
+[codec](#codec). When files are deleted after consumption a file is only
+deleted once all of the messages decoded from it have been acknowledged.
 
 ### Metadata
 
@@ -33,6 +37,8 @@ You can access these metadata fields using
 [function interpolation](/docs/configuration/interpolation#metadata).`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("path", "A path to either a directory or a file."),
+			codec.ReaderDocs,
+			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
 			docs.FieldCommon("delete_files", "Whether to delete files once they are consumed."),
 		},
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 )
 
@@ -17,8 +18,11 @@ func init() {
 		constructor: NewHDFS,
 		Summary: `
 Reads files from a HDFS directory, where each discrete file will be consumed as
-a single message payload.`,
+a single message payload by default.`,
 		Description: `
+Files are consumed as streams and can be decoded into multiple messages with a
+[codec](#codec).
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -34,6 +38,8 @@ You can access these metadata fields using
 			docs.FieldCommon("hosts", "A list of target host addresses to connect to."),
 			docs.FieldCommon("user", "A user ID to connect as."),
 			docs.FieldCommon("directory", "The directory to consume from."),
+			codec.ReaderDocs,
+			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
 		},
 	}
 }
@@ -45,12 +51,14 @@ func NewHDFS(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if len(conf.HDFS.Directory) == 0 {
 		return nil, errors.New("invalid directory (cannot be empty)")
 	}
+	r, err := reader.NewHDFS(conf.HDFS, log, stats)
+	if err != nil {
+		return nil, err
+	}
 	return NewAsyncReader(
 		TypeHDFS,
 		true,
-		reader.NewAsyncPreserver(
-			reader.NewHDFS(conf.HDFS, log, stats),
-		),
+		reader.NewAsyncPreserver(r),
 		log, stats,
 	)
 }
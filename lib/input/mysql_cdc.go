@@ -0,0 +1,98 @@
+package input
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMySQLCDC] = TypeSpec{
+		constructor: NewMySQLCDC,
+		Summary: `
+Consumes row changes from the binary log of a MySQL (v5.6+) server by
+registering as a replica, emitting a message for each inserted, updated or
+deleted row.`,
+		Description: `
+The server must be configured with ` + "`binlog_format = ROW`" + ` and
+` + "`binlog_row_image = FULL`" + `, and the user must have the
+` + "`REPLICATION SLAVE`" + ` and ` + "`REPLICATION CLIENT`" + ` privileges,
+as well as ` + "`SELECT`" + ` on the captured tables. The field
+` + "`server_id`" + ` must be unique amongst all replicas of the server. TLS
+is not currently supported for the replication connection.
+
+Each message is a JSON document containing the row before and after the
+change, where columns are named by querying the
+` + "`information_schema`" + ` of the server:
+
+` + "```json" + `
+{"before":{"id":1,"name":"foo"},"after":{"id":1,"name":"bar"}}
+` + "```" + `
+
+The ` + "`before`" + ` image is null for inserts and the ` + "`after`" + ` image
+is null for deletes.
+
+### Snapshots
+
+When ` + "`snapshot`" + ` is set and there is no stored binlog position the
+input first emits every row of the listed tables from a consistent snapshot,
+with the operation ` + "`read`" + `, before streaming changes that occurred
+after the snapshot was taken. Taking a snapshot briefly acquires a global read
+lock and therefore also requires the ` + "`RELOAD`" + ` privilege.
+
+### Delivery Guarantees
+
+The binlog position is stored within a [cache resource](/docs/components/caches/about)
+periodically, and is only advanced past a transaction once all of its row
+changes have been acknowledged. Therefore when Benthos restarts, row changes
+that were not yet stored are consumed again. A snapshot is only considered
+complete once all of its rows have been acknowledged.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- mysql_schema
+- mysql_table
+- mysql_operation
+- mysql_binlog_file
+- mysql_binlog_pos
+` + "```" + `
+
+The operation is one of ` + "`insert`, `update`, `delete` or `read`" + `.
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("dsn", "A Data Source Name to identify the target database.", "foouser:foopassword@tcp(localhost:3306)/"),
+			docs.FieldCommon("server_id", "A replica server ID, which must be unique amongst the replicas of the server."),
+			docs.FieldCommon("tables", "A list of tables to capture in the form `schema.table`. When empty all tables are captured.", []string{"foodb.footable"}),
+			docs.FieldCommon("snapshot", "Whether to emit the existing rows of the listed tables when no binlog position is stored."),
+			docs.FieldCommon("cache", "The name of a cache resource used to store the binlog position."),
+			docs.FieldAdvanced("cache_key", "The key under which the binlog position is stored."),
+			docs.FieldAdvanced("commit_period", "The period of time between each attempt to store the binlog position."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewMySQLCDC creates a new MySQLCDC input type.
+func NewMySQLCDC(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	cache, err := mgr.GetCache(conf.MySQLCDC.Cache)
+	if err != nil {
+		return nil, err
+	}
+	r, err := reader.NewMySQLCDC(conf.MySQLCDC, cache, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeMySQLCDC, true, reader.NewAsyncPreserver(r), log, stats)
+}
+
+//------------------------------------------------------------------------------
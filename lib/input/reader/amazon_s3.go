@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -38,6 +40,8 @@ type AmazonS3Config struct {
 	sess.Config        `json:",inline" yaml:",inline"`
 	Bucket             string                  `json:"bucket" yaml:"bucket"`
 	Prefix             string                  `json:"prefix" yaml:"prefix"`
+	Codec              string                  `json:"codec" yaml:"codec"`
+	MaxBuffer          int                     `json:"max_buffer" yaml:"max_buffer"`
 	Retries            int                     `json:"retries" yaml:"retries"`
 	ForcePathStyleURLs bool                    `json:"force_path_style_urls" yaml:"force_path_style_urls"`
 	DownloadManager    S3DownloadManagerConfig `json:"download_manager" yaml:"download_manager"`
@@ -58,6 +62,8 @@ func NewAmazonS3Config() AmazonS3Config {
 		Config:             sess.NewConfig(),
 		Bucket:             "",
 		Prefix:             "",
+		Codec:              "all-bytes",
+		MaxBuffer:          1000000,
 		Retries:            3,
 		ForcePathStyleURLs: false,
 		DownloadManager: S3DownloadManagerConfig{
@@ -99,6 +105,13 @@ type AmazonS3 struct {
 
 	readMethod func() (types.Part, objKey, error)
 
+	codecCtor codec.ReaderConstructor
+	current   *s3StreamTarget
+
+	// Streamed objects that failed without SQS, which are read again.
+	failedKeys    []objKey
+	failedKeysMut sync.Mutex
+
 	session    *session.Session
 	s3         *s3.S3
 	downloader *s3manager.Downloader
@@ -147,6 +160,14 @@ func NewAmazonS3(
 	} else {
 		s.readMethod = s.read
 	}
+	if conf.Codec != "all-bytes" {
+		codecConf := codec.NewReaderConfig()
+		codecConf.MaxScanTokenSize = conf.MaxBuffer
+		var err error
+		if s.codecCtor, err = codec.GetReader(conf.Codec, codecConf); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
@@ -485,6 +506,10 @@ func (a *AmazonS3) ReadWithContext(ctx context.Context) (types.Message, AsyncAck
 		return nil, nil, types.ErrNotConnected
 	}
 
+	if a.codecCtor != nil {
+		return a.readStream(ctx)
+	}
+
 	if len(a.targetKeys) == 0 {
 		if a.sqs != nil {
 			if err := a.readSQSEvents(); err != nil {
@@ -532,6 +557,10 @@ func (a *AmazonS3) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
+	if a.codecCtor != nil {
+		return nil, errors.New("codecs other than all-bytes are not supported by this read method")
+	}
+
 	timeoutAt := time.Now().Add(a.timeout)
 
 	if len(a.targetKeys) == 0 {
@@ -669,6 +698,137 @@ func (a *AmazonS3) readFromMgr() (types.Part, objKey, error) {
 	return part, target, nil
 }
 
+type s3StreamTarget struct {
+	key    objKey
+	bucket string
+	obj    *s3.GetObjectOutput
+	reader codec.Reader
+}
+
+// getStreamTarget returns the object currently being streamed, or opens a
+// stream to the next target object if there isn't one.
+func (a *AmazonS3) getStreamTarget(ctx context.Context) (*s3StreamTarget, error) {
+	if a.current != nil {
+		return a.current, nil
+	}
+
+	a.failedKeysMut.Lock()
+	a.targetKeys = append(a.failedKeys, a.targetKeys...)
+	a.failedKeys = nil
+	a.failedKeysMut.Unlock()
+
+	if len(a.targetKeys) == 0 {
+		if a.sqs != nil {
+			if err := a.readSQSEvents(); err != nil {
+				return nil, err
+			}
+		} else {
+			// If we aren't using SQS but exhausted our targets we are done.
+			return nil, types.ErrTypeClosed
+		}
+	}
+	if len(a.targetKeys) == 0 {
+		return nil, types.ErrTimeout
+	}
+
+	target := a.targetKeys[0]
+
+	bucket := a.conf.Bucket
+	if len(target.s3Bucket) > 0 {
+		bucket = target.s3Bucket
+	}
+	obj, err := a.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(target.s3Key),
+	})
+	if err != nil {
+		target.attempts--
+		if target.attempts == 0 {
+			// Remove the target file from our list.
+			a.popTargetKey()
+			a.log.Errorf("Failed to download file '%s' from bucket '%s' after '%v' attempts: %v\n", target.s3Key, bucket, a.conf.Retries, err)
+		} else {
+			a.targetKeys[0] = target
+			return nil, fmt.Errorf("failed to download file '%s' from bucket '%s': %v", target.s3Key, bucket, err)
+		}
+		return nil, types.ErrTimeout
+	}
+	a.popTargetKey()
+
+	reader, err := a.codecCtor(obj.Body, func(ctx context.Context, err error) error {
+		if err == nil {
+			a.deleteObjects([]objKey{target})
+		} else if a.sqs != nil {
+			a.rejectObjects([]objKey{target})
+		} else {
+			a.failStreamTarget(target, bucket, err)
+		}
+		return nil
+	})
+	if err != nil {
+		obj.Body.Close()
+		return nil, fmt.Errorf("failed to decode file '%s' from bucket '%s': %v", target.s3Key, bucket, err)
+	}
+
+	a.current = &s3StreamTarget{
+		key:    target,
+		bucket: bucket,
+		obj:    obj,
+		reader: reader,
+	}
+	return a.current, nil
+}
+
+// failStreamTarget queues an object that failed to be streamed without SQS to
+// be read again, unless it has run out of attempts.
+func (a *AmazonS3) failStreamTarget(target objKey, bucket string, err error) {
+	if target.attempts--; target.attempts <= 0 {
+		a.log.Errorf("Failed to read file '%s' from bucket '%s' after '%v' attempts: %v\n", target.s3Key, bucket, a.conf.Retries, err)
+		return
+	}
+	a.failedKeysMut.Lock()
+	a.failedKeys = append(a.failedKeys, target)
+	a.failedKeysMut.Unlock()
+}
+
+// readStream attempts to read the next message decoded from a streamed object
+// of the target S3 bucket. Objects are deleted once all messages decoded from
+// them have been acknowledged. Objects that fail to be read or delivered are
+// rejected when using SQS, and otherwise are read again from the start.
+func (a *AmazonS3) readStream(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	for {
+		target, err := a.getStreamTarget(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parts, codecAckFn, err := target.reader.Next(ctx)
+		if err != nil {
+			target.reader.Close(ctx)
+			a.current = nil
+			if err == io.EOF {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to read file '%s' from bucket '%s': %v", target.key.s3Key, target.bucket, err)
+		}
+
+		msg := message.New(nil)
+		for _, part := range parts {
+			meta := part.Metadata()
+			for k, v := range target.obj.Metadata {
+				meta.Set(k, *v)
+			}
+			meta.Set("s3_key", target.key.s3Key)
+			meta.Set("s3_bucket", target.bucket)
+			addS3Metadata(part, target.obj)
+			msg.Append(part)
+		}
+		return msg, func(rctx context.Context, res types.Response) error {
+			return codecAckFn(rctx, res.Error())
+		}, nil
+	}
+}
+
 // Acknowledge confirms whether or not our unacknowledged messages have been
 // successfully propagated or not.
 func (a *AmazonS3) Acknowledge(err error) error {
@@ -691,6 +851,10 @@ func (a *AmazonS3) Acknowledge(err error) error {
 func (a *AmazonS3) CloseAsync() {
 	go func() {
 		a.targetKeysMut.Lock()
+		if a.current != nil {
+			a.current.reader.Close(context.Background())
+			a.current = nil
+		}
 		a.rejectObjects(a.targetKeys)
 		a.targetKeys = nil
 		a.targetKeysMut.Unlock()
@@ -3,13 +3,15 @@ package reader
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 )
 
 //------------------------------------------------------------------------------
@@ -17,6 +19,8 @@ import (
 // FilesConfig contains configuration for the Files input type.
 type FilesConfig struct {
 	Path        string `json:"path" yaml:"path"`
+	Codec       string `json:"codec" yaml:"codec"`
+	MaxBuffer   int    `json:"max_buffer" yaml:"max_buffer"`
 	DeleteFiles bool   `json:"delete_files" yaml:"delete_files"`
 }
 
@@ -24,22 +28,42 @@ type FilesConfig struct {
 func NewFilesConfig() FilesConfig {
 	return FilesConfig{
 		Path:        "",
+		Codec:       "all-bytes",
+		MaxBuffer:   1000000,
 		DeleteFiles: false,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+type filesTarget struct {
+	path   string
+	reader codec.Reader
+}
+
 // Files is an input type that reads file contents at a path as messages.
 type Files struct {
 	targets []string
 	delete  bool
+
+	codecCtor codec.ReaderConstructor
+
+	currentMut sync.Mutex
+	current    *filesTarget
 }
 
 // NewFiles creates a new Files input type.
 func NewFiles(conf FilesConfig) (*Files, error) {
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.MaxBuffer
+	ctor, err := codec.GetReader(conf.Codec, codecConf)
+	if err != nil {
+		return nil, err
+	}
+
 	f := Files{
-		delete: conf.DeleteFiles,
+		delete:    conf.DeleteFiles,
+		codecCtor: ctor,
 	}
 
 	if info, err := os.Stat(conf.Path); err != nil {
@@ -49,7 +73,7 @@ func NewFiles(conf FilesConfig) (*Files, error) {
 		return &f, nil
 	}
 
-	err := filepath.Walk(conf.Path, func(path string, info os.FileInfo, werr error) error {
+	err = filepath.Walk(conf.Path, func(path string, info os.FileInfo, werr error) error {
 		if werr != nil {
 			return werr
 		}
@@ -77,10 +101,14 @@ func (f *Files) ConnectWithContext(ctx context.Context) (err error) {
 
 //------------------------------------------------------------------------------
 
-// ReadWithContext a new Files message.
-func (f *Files) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+// getTarget returns the file currently being read, opening the next target
+// file if there isn't one.
+func (f *Files) getTarget() (*filesTarget, error) {
+	if f.current != nil {
+		return f.current, nil
+	}
 	if len(f.targets) == 0 {
-		return nil, nil, types.ErrTypeClosed
+		return nil, types.ErrTypeClosed
 	}
 
 	path := f.targets[0]
@@ -88,25 +116,57 @@ func (f *Files) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn,
 
 	file, openerr := os.Open(path)
 	if openerr != nil {
-		return nil, nil, fmt.Errorf("failed to read file '%v': %v", path, openerr)
+		return nil, fmt.Errorf("failed to read file '%v': %v", path, openerr)
+	}
+
+	reader, err := f.codecCtor(file, func(ctx context.Context, err error) error {
+		if f.delete && err == nil {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read file '%v': %v", path, err)
 	}
-	defer file.Close()
 
-	msgBytes, readerr := ioutil.ReadAll(file)
-	if readerr != nil {
-		return nil, nil, readerr
+	f.current = &filesTarget{
+		path:   path,
+		reader: reader,
 	}
+	return f.current, nil
+}
 
-	msg := message.New([][]byte{msgBytes})
-	msg.Get(0).Metadata().Set("path", path)
-	return msg, func(ctx context.Context, res types.Response) error {
-		if f.delete {
-			if res.Error() == nil {
-				return os.Remove(path)
+// ReadWithContext a new Files message.
+func (f *Files) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	f.currentMut.Lock()
+	defer f.currentMut.Unlock()
+
+	for {
+		target, err := f.getTarget()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parts, codecAckFn, err := target.reader.Next(ctx)
+		if err != nil {
+			target.reader.Close(ctx)
+			f.current = nil
+			if err == io.EOF {
+				continue
 			}
+			return nil, nil, fmt.Errorf("failed to read file '%v': %v", target.path, err)
 		}
-		return nil
-	}, nil
+
+		msg := message.New(nil)
+		for _, part := range parts {
+			part.Metadata().Set("path", target.path)
+			msg.Append(part)
+		}
+		return msg, func(rctx context.Context, res types.Response) error {
+			return codecAckFn(rctx, res.Error())
+		}, nil
+	}
 }
 
 // Read a new Files message.
@@ -123,6 +183,14 @@ func (f *Files) Acknowledge(err error) error {
 
 // CloseAsync shuts down the Files input and stops processing requests.
 func (f *Files) CloseAsync() {
+	go func() {
+		f.currentMut.Lock()
+		if f.current != nil {
+			f.current.reader.Close(context.Background())
+			f.current = nil
+		}
+		f.currentMut.Unlock()
+	}()
 }
 
 // WaitForClose blocks until the Files input has closed down.
@@ -218,4 +218,63 @@ func TestFilesDirectoryDelete(t *testing.T) {
 	}
 }
 
+func TestFilesCodecDelete(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_codec_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := path.Join(tmpDir, "f1")
+	if err = ioutil.WriteFile(tmpPath, []byte("foo\nbar\nbaz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewFilesConfig()
+	conf.Path = tmpDir
+	conf.Codec = "lines"
+	conf.DeleteFiles = true
+
+	f, err := NewFiles(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.ConnectWithContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var ackFns []AsyncAckFn
+	var act []string
+	for {
+		msg, ackFn, err := f.ReadWithContext(context.Background())
+		if err == types.ErrTypeClosed {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		act = append(act, string(msg.Get(0).Get()))
+		if p := msg.Get(0).Metadata().Get("path"); p != tmpPath {
+			t.Errorf("Wrong path metadata: %v != %v", p, tmpPath)
+		}
+		ackFns = append(ackFns, ackFn)
+	}
+
+	if exp := []string{"foo", "bar", "baz"}; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	for i, ackFn := range ackFns {
+		if _, err = os.Stat(tmpPath); err != nil {
+			t.Errorf("Expected file to exist before message %v was acked: %v", i, err)
+		}
+		if err = ackFn(context.Background(), response.NewAck()); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err = os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Errorf("Expected deleted file, received: %v", err)
+	}
+}
+
 //------------------------------------------------------------------------------
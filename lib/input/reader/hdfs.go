@@ -2,13 +2,17 @@ package reader
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/colinmarc/hdfs"
 )
 
@@ -19,6 +23,8 @@ type HDFSConfig struct {
 	Hosts     []string `json:"hosts" yaml:"hosts"`
 	User      string   `json:"user" yaml:"user"`
 	Directory string   `json:"directory" yaml:"directory"`
+	Codec     string   `json:"codec" yaml:"codec"`
+	MaxBuffer int      `json:"max_buffer" yaml:"max_buffer"`
 }
 
 // NewHDFSConfig creates a new Config with default values.
@@ -27,6 +33,8 @@ func NewHDFSConfig() HDFSConfig {
 		Hosts:     []string{"localhost:9000"},
 		User:      "benthos_hdfs",
 		Directory: "",
+		Codec:     "all-bytes",
+		MaxBuffer: 1000000,
 	}
 }
 
@@ -41,21 +49,39 @@ type HDFS struct {
 
 	client *hdfs.Client
 
+	codecCtor codec.ReaderConstructor
+
+	currentMut sync.Mutex
+	current    *hdfsTarget
+
 	log   log.Modular
 	stats metrics.Type
 }
 
+type hdfsTarget struct {
+	name   string
+	path   string
+	reader codec.Reader
+}
+
 // NewHDFS creates a new HDFS writer.Type.
 func NewHDFS(
 	conf HDFSConfig,
 	log log.Modular,
 	stats metrics.Type,
-) *HDFS {
-	return &HDFS{
-		conf:  conf,
-		log:   log,
-		stats: stats,
+) (*HDFS, error) {
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.MaxBuffer
+	ctor, err := codec.GetReader(conf.Codec, codecConf)
+	if err != nil {
+		return nil, err
 	}
+	return &HDFS{
+		conf:      conf,
+		codecCtor: ctor,
+		log:       log,
+		stats:     stats,
+	}, nil
 }
 
 //------------------------------------------------------------------------------
@@ -98,17 +124,12 @@ func (h *HDFS) ConnectWithContext(ctx context.Context) error {
 
 //------------------------------------------------------------------------------
 
-// ReadWithContext reads a new HDFS message.
-func (h *HDFS) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
-	msg, err := h.Read()
-	if err != nil {
-		return nil, nil, err
+// getTarget returns the file currently being read, opening the next target
+// file if there isn't one.
+func (h *HDFS) getTarget() (*hdfsTarget, error) {
+	if h.current != nil {
+		return h.current, nil
 	}
-	return msg, noopAsyncAckFn, nil
-}
-
-// Read a new HDFS message.
-func (h *HDFS) Read() (types.Message, error) {
 	if len(h.targets) == 0 {
 		return nil, types.ErrTypeClosed
 	}
@@ -117,15 +138,65 @@ func (h *HDFS) Read() (types.Message, error) {
 	h.targets = h.targets[1:]
 
 	filePath := filepath.Join(h.conf.Directory, fileName)
-	msgBytes, readerr := h.client.ReadFile(filePath)
-	if readerr != nil {
-		return nil, readerr
+	file, err := h.client.Open(filePath)
+	if err != nil {
+		return nil, err
 	}
 
-	msg := message.New([][]byte{msgBytes})
-	msg.Get(0).Metadata().Set("hdfs_name", fileName)
-	msg.Get(0).Metadata().Set("hdfs_path", filePath)
-	return msg, nil
+	reader, err := h.codecCtor(file, func(context.Context, error) error {
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read file '%v': %v", filePath, err)
+	}
+
+	h.current = &hdfsTarget{
+		name:   fileName,
+		path:   filePath,
+		reader: reader,
+	}
+	return h.current, nil
+}
+
+// ReadWithContext reads a new HDFS message.
+func (h *HDFS) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	h.currentMut.Lock()
+	defer h.currentMut.Unlock()
+
+	for {
+		target, err := h.getTarget()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parts, codecAckFn, err := target.reader.Next(ctx)
+		if err != nil {
+			target.reader.Close(ctx)
+			h.current = nil
+			if err == io.EOF {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to read file '%v': %v", target.path, err)
+		}
+
+		msg := message.New(nil)
+		for _, part := range parts {
+			part.Metadata().
+				Set("hdfs_name", target.name).
+				Set("hdfs_path", target.path)
+			msg.Append(part)
+		}
+		return msg, func(rctx context.Context, res types.Response) error {
+			return codecAckFn(rctx, res.Error())
+		}, nil
+	}
+}
+
+// Read a new HDFS message.
+func (h *HDFS) Read() (types.Message, error) {
+	msg, _, err := h.ReadWithContext(context.Background())
+	return msg, err
 }
 
 // Acknowledge instructs whether unacknowledged messages have been successfully
@@ -136,6 +207,14 @@ func (h *HDFS) Acknowledge(err error) error {
 
 // CloseAsync shuts down the HDFS input and stops processing requests.
 func (h *HDFS) CloseAsync() {
+	go func() {
+		h.currentMut.Lock()
+		if h.current != nil {
+			h.current.reader.Close(context.Background())
+			h.current = nil
+		}
+		h.currentMut.Unlock()
+	}()
 }
 
 // WaitForClose blocks until the HDFS input has closed down.
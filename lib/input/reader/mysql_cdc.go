@@ -0,0 +1,809 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-sql-driver/mysql"
+)
+
+//------------------------------------------------------------------------------
+
+// MySQLCDCConfig contains configuration fields for the MySQLCDC input type.
+type MySQLCDCConfig struct {
+	DSN          string   `json:"dsn" yaml:"dsn"`
+	ServerID     uint32   `json:"server_id" yaml:"server_id"`
+	Tables       []string `json:"tables" yaml:"tables"`
+	Snapshot     bool     `json:"snapshot" yaml:"snapshot"`
+	Cache        string   `json:"cache" yaml:"cache"`
+	CacheKey     string   `json:"cache_key" yaml:"cache_key"`
+	CommitPeriod string   `json:"commit_period" yaml:"commit_period"`
+}
+
+// NewMySQLCDCConfig creates a new MySQLCDCConfig with default values.
+func NewMySQLCDCConfig() MySQLCDCConfig {
+	return MySQLCDCConfig{
+		DSN:          "",
+		ServerID:     1000,
+		Tables:       []string{},
+		Snapshot:     false,
+		Cache:        "",
+		CacheKey:     "mysql_cdc_position",
+		CommitPeriod: "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// mysqlBinlogPos is a position within the binary log of a server.
+type mysqlBinlogPos struct {
+	file string
+	pos  uint32
+}
+
+func (p mysqlBinlogPos) String() string {
+	return fmt.Sprintf("%v:%v", p.file, p.pos)
+}
+
+func parseMySQLBinlogPos(s string) (mysqlBinlogPos, error) {
+	var p mysqlBinlogPos
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return p, fmt.Errorf("failed to parse binlog position '%v'", s)
+	}
+	pos, err := strconv.ParseUint(s[i+1:], 10, 32)
+	if err != nil {
+		return p, fmt.Errorf("failed to parse binlog position '%v': %v", s, err)
+	}
+	p.file, p.pos = s[:i], uint32(pos)
+	return p, nil
+}
+
+// mysqlCDCTxn tracks the acknowledgements of the row events of a transaction,
+// the stored position can only be advanced beyond a transaction once it has
+// been fully read and all of its row events are acknowledged.
+type mysqlCDCTxn struct {
+	pos       mysqlBinlogPos
+	pending   int
+	open      bool
+	abandoned bool
+}
+
+type mysqlCDCMessage struct {
+	part types.Part
+	txn  *mysqlCDCTxn
+}
+
+// mysqlColumnCache obtains the columns of tables from the information_schema,
+// which must be reset when the schema of a table changes.
+type mysqlColumnCache struct {
+	ctx    context.Context
+	db     *sql.DB
+	tables map[string][]mysqlColumn
+}
+
+func (c *mysqlColumnCache) get(schema, table string) ([]mysqlColumn, error) {
+	key := schema + "." + table
+	if cols, exists := c.tables[key]; exists {
+		return cols, nil
+	}
+
+	rows, err := c.db.QueryContext(
+		c.ctx,
+		"SELECT COLUMN_NAME, COLUMN_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		schema, table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain columns of table %v: %v", key, err)
+	}
+	defer rows.Close()
+
+	var cols []mysqlColumn
+	for rows.Next() {
+		var name, colType string
+		if err = rows.Scan(&name, &colType); err != nil {
+			return nil, err
+		}
+		cols = append(cols, mysqlParseColumnType(name, colType))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	c.tables[key] = cols
+	return cols, nil
+}
+
+func (c *mysqlColumnCache) reset() {
+	c.tables = map[string][]mysqlColumn{}
+}
+
+//------------------------------------------------------------------------------
+
+// MySQLCDC is an input type that reads row events from the binary log of a
+// MySQL server by registering as a replica.
+type MySQLCDC struct {
+	conf         MySQLCDCConfig
+	dsn          *mysql.Config
+	cache        types.Cache
+	tables       map[string]struct{}
+	commitPeriod time.Duration
+
+	connMut     sync.Mutex
+	db          *sql.DB
+	binlog      *mysqlConn
+	msgChan     chan mysqlCDCMessage
+	sessionDone chan struct{}
+
+	ackMut    sync.Mutex
+	txns      []*mysqlCDCTxn
+	readPos   mysqlBinlogPos
+	ackedPos  mysqlBinlogPos
+	storedPos mysqlBinlogPos
+
+	log   log.Modular
+	stats metrics.Type
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewMySQLCDC creates a new MySQLCDC input type, where the binlog position is
+// stored within a cache.
+func NewMySQLCDC(conf MySQLCDCConfig, cache types.Cache, log log.Modular, stats metrics.Type) (*MySQLCDC, error) {
+	m := &MySQLCDC{
+		conf:       conf,
+		cache:      cache,
+		tables:     map[string]struct{}{},
+		log:        log,
+		stats:      stats,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	var err error
+	if m.dsn, err = mysql.ParseDSN(conf.DSN); err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %v", err)
+	}
+	if len(conf.CacheKey) == 0 {
+		return nil, errors.New("a cache key must be specified")
+	}
+	for _, t := range conf.Tables {
+		if !strings.Contains(t, ".") {
+			return nil, fmt.Errorf("table '%v' must be qualified with a schema as schema.table", t)
+		}
+		m.tables[t] = struct{}{}
+	}
+	if conf.Snapshot && len(conf.Tables) == 0 {
+		return nil, errors.New("tables must be specified in order to take a snapshot")
+	}
+	if m.commitPeriod, err = time.ParseDuration(conf.CommitPeriod); err != nil {
+		return nil, fmt.Errorf("failed to parse commit period string: %v", err)
+	}
+
+	go m.commitLoop()
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *MySQLCDC) include(schema, table string) bool {
+	if len(m.tables) == 0 {
+		return true
+	}
+	_, exists := m.tables[schema+"."+table]
+	return exists
+}
+
+func (m *MySQLCDC) track(txn *mysqlCDCTxn) {
+	m.ackMut.Lock()
+	m.txns = append(m.txns, txn)
+	m.ackMut.Unlock()
+}
+
+func (m *MySQLCDC) addPending(txn *mysqlCDCTxn) {
+	m.ackMut.Lock()
+	txn.pending++
+	m.ackMut.Unlock()
+}
+
+// closeTxn marks a transaction as fully read, with the position that follows
+// it.
+func (m *MySQLCDC) closeTxn(txn *mysqlCDCTxn, pos mysqlBinlogPos) {
+	m.ackMut.Lock()
+	txn.pos = pos
+	txn.open = false
+	m.readPos = pos
+	m.resolveLocked()
+	m.ackMut.Unlock()
+}
+
+// abandon removes a transaction that was only partially read before a
+// session ended, it is read again in full by the next session.
+func (m *MySQLCDC) abandon(txn *mysqlCDCTxn) {
+	m.ackMut.Lock()
+	txn.open = false
+	txn.abandoned = true
+	m.resolveLocked()
+	m.ackMut.Unlock()
+}
+
+func (m *MySQLCDC) resolve(txn *mysqlCDCTxn) {
+	m.ackMut.Lock()
+	txn.pending--
+	m.resolveLocked()
+	m.ackMut.Unlock()
+}
+
+func (m *MySQLCDC) resolveLocked() {
+	for len(m.txns) > 0 {
+		txn := m.txns[0]
+		if !txn.abandoned {
+			if txn.open || txn.pending > 0 {
+				return
+			}
+			m.ackedPos = txn.pos
+		}
+		m.txns[0] = nil
+		m.txns = m.txns[1:]
+	}
+}
+
+// persist stores the highest acknowledged position within the cache.
+func (m *MySQLCDC) persist() {
+	m.ackMut.Lock()
+	pos, stored := m.ackedPos, m.storedPos
+	m.ackMut.Unlock()
+
+	if len(pos.file) == 0 || pos == stored {
+		return
+	}
+	if err := m.cache.Set(m.conf.CacheKey, []byte(pos.String())); err != nil {
+		m.log.Errorf("Failed to store binlog position: %v\n", err)
+		return
+	}
+
+	m.ackMut.Lock()
+	m.storedPos = pos
+	m.ackMut.Unlock()
+}
+
+func (m *MySQLCDC) commitLoop() {
+	commitTicker := time.NewTicker(m.commitPeriod)
+	defer commitTicker.Stop()
+
+	for {
+		select {
+		case <-commitTicker.C:
+			m.persist()
+		case <-m.closeChan:
+			m.connMut.Lock()
+			done := m.sessionDone
+			m.connMut.Unlock()
+			if done != nil {
+				<-done
+			}
+			m.persist()
+
+			m.connMut.Lock()
+			if m.db != nil {
+				m.db.Close()
+				m.db = nil
+			}
+			m.connMut.Unlock()
+			close(m.closedChan)
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mysqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func mysqlMasterStatus(ctx context.Context, q mysqlQueryer) (mysqlBinlogPos, error) {
+	var pos mysqlBinlogPos
+
+	rows, err := q.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		return pos, fmt.Errorf("failed to obtain binlog position: %v", err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return pos, err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return pos, err
+		}
+		return pos, errors.New("binary logging is not enabled on the server")
+	}
+
+	values := make([]interface{}, len(cols))
+	for i := range values {
+		values[i] = new(sql.RawBytes)
+	}
+	if err = rows.Scan(values...); err != nil {
+		return pos, err
+	}
+	if len(cols) < 2 {
+		return pos, errors.New("unexpected binlog status columns")
+	}
+	return parseMySQLBinlogPos(
+		string(*values[0].(*sql.RawBytes)) + ":" + string(*values[1].(*sql.RawBytes)),
+	)
+}
+
+// startPosition determines the position to stream the binlog from, which is
+// the end of the last transaction read by a previous session, or otherwise
+// the position stored in the cache. When neither exist a snapshot is started
+// if enabled, otherwise the current position of the server is used.
+func (m *MySQLCDC) startPosition(ctx context.Context) (mysqlBinlogPos, *sql.Conn, error) {
+	m.ackMut.Lock()
+	pos := m.readPos
+	m.ackMut.Unlock()
+	if len(pos.file) > 0 {
+		return pos, nil, nil
+	}
+
+	stored, err := m.cache.Get(m.conf.CacheKey)
+	if err == nil {
+		pos, err = parseMySQLBinlogPos(string(stored))
+		return pos, nil, err
+	}
+	if err != types.ErrKeyNotFound {
+		return pos, nil, fmt.Errorf("failed to obtain stored binlog position: %v", err)
+	}
+
+	if m.conf.Snapshot {
+		return m.beginSnapshot(ctx)
+	}
+	pos, err = mysqlMasterStatus(ctx, m.db)
+	return pos, nil, err
+}
+
+// beginSnapshot starts a consistent snapshot transaction on a dedicated
+// connection and returns the binlog position it corresponds to.
+func (m *MySQLCDC) beginSnapshot(ctx context.Context) (mysqlBinlogPos, *sql.Conn, error) {
+	var pos mysqlBinlogPos
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return pos, nil, err
+	}
+
+	for _, stmt := range []string{
+		"SET time_zone = '+00:00'",
+		"FLUSH TABLES WITH READ LOCK",
+		"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT",
+	} {
+		if _, err = conn.ExecContext(ctx, stmt); err != nil {
+			conn.Close()
+			return pos, nil, fmt.Errorf("failed to start snapshot: %v", err)
+		}
+	}
+	if pos, err = mysqlMasterStatus(ctx, conn); err != nil {
+		conn.Close()
+		return pos, nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "UNLOCK TABLES"); err != nil {
+		conn.Close()
+		return pos, nil, fmt.Errorf("failed to start snapshot: %v", err)
+	}
+	return pos, conn, nil
+}
+
+// dump opens a replica connection streaming the binlog from a position.
+func (m *MySQLCDC) dump(ctx context.Context, pos mysqlBinlogPos) (*mysqlConn, error) {
+	conn, err := dialMySQL(ctx, m.dsn)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range []string{
+		"SET @master_binlog_checksum = @@global.binlog_checksum",
+		"SET @master_heartbeat_period = 30000000000",
+	} {
+		if err = conn.exec(stmt); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err = conn.registerReplica(m.conf.ServerID); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to register as replica: %v", err)
+	}
+	if err = conn.binlogDump(m.conf.ServerID, pos); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext establishes a connection to a MySQL server and begins
+// streaming its binlog.
+func (m *MySQLCDC) ConnectWithContext(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	select {
+	case <-m.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	if m.msgChan != nil {
+		return nil
+	}
+
+	if m.db == nil {
+		db, err := sql.Open("mysql", m.conf.DSN)
+		if err != nil {
+			return err
+		}
+		if err = db.PingContext(ctx); err != nil {
+			db.Close()
+			return err
+		}
+		m.db = db
+	}
+
+	pos, snapshot, err := m.startPosition(ctx)
+	if err != nil {
+		return err
+	}
+
+	var conn *mysqlConn
+	if snapshot == nil {
+		if conn, err = m.dump(ctx, pos); err != nil {
+			return err
+		}
+		m.log.Infof("Receiving row events from MySQL binlog at position %v\n", pos)
+	} else {
+		m.log.Infof("Taking snapshot of MySQL tables %v at binlog position %v\n", m.conf.Tables, pos)
+	}
+
+	m.binlog = conn
+	m.msgChan = make(chan mysqlCDCMessage)
+	m.sessionDone = make(chan struct{})
+	go m.loop(snapshot, pos, m.msgChan, m.sessionDone)
+	return nil
+}
+
+func (m *MySQLCDC) newPart(schema, table, op string, before, after map[string]interface{}, pos mysqlBinlogPos) (types.Part, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"before": before,
+		"after":  after,
+	})
+	if err != nil {
+		return nil, err
+	}
+	part := message.NewPart(body)
+	part.Metadata().
+		Set("mysql_schema", schema).
+		Set("mysql_table", table).
+		Set("mysql_operation", op).
+		Set("mysql_binlog_file", pos.file).
+		Set("mysql_binlog_pos", strconv.FormatUint(uint64(pos.pos), 10))
+	return part, nil
+}
+
+func (m *MySQLCDC) send(ctx context.Context, msgChan chan<- mysqlCDCMessage, msg mysqlCDCMessage) error {
+	select {
+	case msgChan <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *MySQLCDC) loop(snapshot *sql.Conn, pos mysqlBinlogPos, msgChan chan<- mysqlCDCMessage, done chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-m.closeChan:
+		case <-done:
+		}
+		cancel()
+		m.connMut.Lock()
+		if m.binlog != nil {
+			m.binlog.Close()
+		}
+		m.connMut.Unlock()
+	}()
+
+	defer func() {
+		m.connMut.Lock()
+		if m.binlog != nil {
+			m.binlog.Close()
+			m.binlog = nil
+		}
+		m.connMut.Unlock()
+		close(done)
+	}()
+
+	if snapshot != nil {
+		err := m.snapshot(ctx, snapshot, pos, msgChan)
+		snapshot.Close()
+		if err != nil {
+			if ctx.Err() == nil {
+				m.log.Errorf("Failed to take snapshot: %v\n", err)
+			}
+			return
+		}
+
+		conn, err := m.dump(ctx, pos)
+		if err != nil {
+			m.log.Errorf("Failed to stream binlog: %v\n", err)
+			return
+		}
+		m.connMut.Lock()
+		m.binlog = conn
+		m.connMut.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		m.log.Infof("Receiving row events from MySQL binlog at position %v\n", pos)
+	}
+
+	m.connMut.Lock()
+	conn := m.binlog
+	m.connMut.Unlock()
+
+	if err := m.stream(ctx, conn, pos, msgChan); err != nil && ctx.Err() == nil {
+		m.log.Errorf("Failed to read binlog: %v\n", err)
+	}
+}
+
+// snapshot emits the rows of all tables from a consistent snapshot as a
+// single transaction.
+func (m *MySQLCDC) snapshot(ctx context.Context, conn *sql.Conn, pos mysqlBinlogPos, msgChan chan<- mysqlCDCMessage) error {
+	txn := &mysqlCDCTxn{open: true}
+	m.track(txn)
+
+	for _, t := range m.conf.Tables {
+		i := strings.IndexByte(t, '.')
+		schema, table := t[:i], t[i+1:]
+		if err := m.snapshotTable(ctx, conn, schema, table, pos, txn, msgChan); err != nil {
+			m.abandon(txn)
+			return err
+		}
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		m.abandon(txn)
+		return err
+	}
+
+	m.closeTxn(txn, pos)
+	return nil
+}
+
+func (m *MySQLCDC) snapshotTable(
+	ctx context.Context, conn *sql.Conn, schema, table string, pos mysqlBinlogPos,
+	txn *mysqlCDCTxn, msgChan chan<- mysqlCDCMessage,
+) error {
+	quote := func(s string) string {
+		return "`" + strings.Replace(s, "`", "``", -1) + "`"
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT * FROM "+quote(schema)+"."+quote(table))
+	if err != nil {
+		return fmt.Errorf("failed to select rows of table %v.%v: %v", schema, table, err)
+	}
+	defer rows.Close()
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	values := make([]sql.RawBytes, len(colTypes))
+	ptrs := make([]interface{}, len(colTypes))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		after := make(map[string]interface{}, len(colTypes))
+		for i, ct := range colTypes {
+			if values[i] == nil {
+				after[ct.Name()] = nil
+			} else {
+				after[ct.Name()] = mysqlSnapshotValue(ct.DatabaseTypeName(), values[i])
+			}
+		}
+
+		part, err := m.newPart(schema, table, "read", nil, after, pos)
+		if err != nil {
+			return err
+		}
+		m.addPending(txn)
+		if err = m.send(ctx, msgChan, mysqlCDCMessage{part: part, txn: txn}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// mysqlSnapshotValue converts the text representation of a column value into a
+// type that best represents it within a JSON document.
+func mysqlSnapshotValue(dbType string, raw []byte) interface{} {
+	s := string(raw)
+	switch strings.TrimPrefix(dbType, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u
+		}
+	case "FLOAT", "DOUBLE", "DECIMAL":
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(s)
+		}
+	case "JSON":
+		if json.Valid(raw) {
+			return json.RawMessage(s)
+		}
+	case "BIT":
+		r := &mysqlReader{data: raw}
+		return r.uintBE(len(raw))
+	}
+	return s
+}
+
+// stream emits the row events of the binlog, where row events are tracked as
+// part of the transaction that contains them.
+func (m *MySQLCDC) stream(ctx context.Context, conn *mysqlConn, pos mysqlBinlogPos, msgChan chan<- mysqlCDCMessage) error {
+	columns := &mysqlColumnCache{ctx: ctx, db: m.db}
+	columns.reset()
+	parser := newMySQLBinlogParser(m.include, columns.get)
+
+	file := pos.file
+	var txn *mysqlCDCTxn
+	defer func() {
+		if txn != nil {
+			m.abandon(txn)
+		}
+	}()
+
+	commit := func(logPos uint32) {
+		if txn == nil {
+			txn = &mysqlCDCTxn{}
+			m.track(txn)
+		}
+		m.closeTxn(txn, mysqlBinlogPos{file: file, pos: logPos})
+		txn = nil
+	}
+
+	for {
+		data, err := conn.readEvent()
+		if err != nil {
+			return err
+		}
+		e, err := parser.parse(data)
+		if err != nil {
+			return err
+		}
+
+		switch e.header.eventType {
+		case mysqlEventRotate:
+			file = e.nextFile
+		case mysqlEventXID:
+			commit(e.header.logPos)
+		case mysqlEventQuery:
+			switch strings.ToUpper(strings.TrimSpace(e.query)) {
+			case "BEGIN":
+			case "COMMIT":
+				commit(e.header.logPos)
+			default:
+				// Statements other than transaction boundaries are usually DDL
+				// and might change the columns of a table.
+				columns.reset()
+				if txn == nil {
+					commit(e.header.logPos)
+				}
+			}
+		}
+
+		if e.rows == nil {
+			continue
+		}
+
+		var op string
+		switch e.rows.action {
+		case 'I':
+			op = "insert"
+		case 'U':
+			op = "update"
+		case 'D':
+			op = "delete"
+		}
+		eventPos := mysqlBinlogPos{file: file, pos: e.header.logPos}
+		for _, row := range e.rows.rows {
+			part, err := m.newPart(e.rows.schema, e.rows.table, op, row.before, row.after, eventPos)
+			if err != nil {
+				return err
+			}
+			if txn == nil {
+				txn = &mysqlCDCTxn{open: true}
+				m.track(txn)
+			}
+			m.addPending(txn)
+			if err = m.send(ctx, msgChan, mysqlCDCMessage{part: part, txn: txn}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ReadWithContext attempts to read a row event from the binlog.
+func (m *MySQLCDC) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	m.connMut.Lock()
+	msgChan, done := m.msgChan, m.sessionDone
+	m.connMut.Unlock()
+
+	if msgChan == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case next := <-msgChan:
+		msg := message.New(nil)
+		msg.Append(next.part)
+		return msg, func(rctx context.Context, res types.Response) error {
+			if res.Error() == nil {
+				m.resolve(next.txn)
+			}
+			return nil
+		}, nil
+	case <-done:
+		m.connMut.Lock()
+		if m.sessionDone == done {
+			m.msgChan = nil
+			m.sessionDone = nil
+		}
+		m.connMut.Unlock()
+		return nil, nil, types.ErrNotConnected
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync shuts down the MySQLCDC input and stops processing requests.
+func (m *MySQLCDC) CloseAsync() {
+	m.closeOnce.Do(func() {
+		close(m.closeChan)
+	})
+}
+
+// WaitForClose blocks until the MySQLCDC input has closed down.
+func (m *MySQLCDC) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-m.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,449 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientLongFlag         = 0x00000004
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientTransactions     = 0x00002000
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+
+	mysqlComQuery          = 0x03
+	mysqlComBinlogDump     = 0x12
+	mysqlComRegisterSlave  = 0x15
+	mysqlMaxPacketSize     = 1<<24 - 1
+	mysqlCharsetUTF8MB4    = 45
+	mysqlPacketOK          = 0x00
+	mysqlPacketAuthMore    = 0x01
+	mysqlPacketEOF         = 0xfe
+	mysqlPacketErr         = 0xff
+	mysqlRequestPublicKey  = 0x02
+	mysqlFastAuthSuccess   = 0x03
+	mysqlPerformFullAuth   = 0x04
+	mysqlNativePassword    = "mysql_native_password"
+	mysqlCachingSHA2Passwd = "caching_sha2_password"
+)
+
+// mysqlConn is a minimal implementation of the MySQL client protocol that is
+// sufficient for registering as a replica and streaming the binary log.
+type mysqlConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  byte
+}
+
+// mysqlServerError is an error returned by the server in an ERR packet.
+type mysqlServerError struct {
+	code    uint16
+	message string
+}
+
+func (e *mysqlServerError) Error() string {
+	return fmt.Sprintf("error %v: %v", e.code, e.message)
+}
+
+func parseMySQLErrPacket(data []byte) error {
+	if len(data) < 3 {
+		return errors.New("malformed error packet")
+	}
+	e := &mysqlServerError{code: binary.LittleEndian.Uint16(data[1:3])}
+	msg := data[3:]
+	if len(msg) > 0 && msg[0] == '#' && len(msg) >= 6 {
+		msg = msg[6:]
+	}
+	e.message = string(msg)
+	return e
+}
+
+func dialMySQL(ctx context.Context, cfg *mysql.Config) (*mysqlConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, cfg.Net, cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &mysqlConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+	if err = c.handshake(cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *mysqlConn) Close() error {
+	return c.conn.Close()
+}
+
+// readPacket reads a packet from the server, joining packets that were split
+// due to exceeding the maximum packet size.
+func (c *mysqlConn) readPacket() ([]byte, error) {
+	var data []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			return nil, err
+		}
+		size := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		c.seq = header[3] + 1
+
+		start := len(data)
+		data = append(data, make([]byte, size)...)
+		if _, err := io.ReadFull(c.r, data[start:]); err != nil {
+			return nil, err
+		}
+		if size < mysqlMaxPacketSize {
+			return data, nil
+		}
+	}
+}
+
+// writePacket writes a packet to the server, splitting it if it exceeds the
+// maximum packet size.
+func (c *mysqlConn) writePacket(data []byte) error {
+	for {
+		size := len(data)
+		if size > mysqlMaxPacketSize {
+			size = mysqlMaxPacketSize
+		}
+		header := []byte{byte(size), byte(size >> 8), byte(size >> 16), c.seq}
+		c.seq++
+		if _, err := c.conn.Write(append(header, data[:size]...)); err != nil {
+			return err
+		}
+		data = data[size:]
+		if size < mysqlMaxPacketSize {
+			return nil
+		}
+	}
+}
+
+// writeCommand writes a packet that begins a new command.
+func (c *mysqlConn) writeCommand(data []byte) error {
+	c.seq = 0
+	return c.writePacket(data)
+}
+
+// readOK reads a packet that is expected to be an OK packet.
+func (c *mysqlConn) readOK() error {
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(data) == 0:
+		return errors.New("empty response packet")
+	case data[0] == mysqlPacketOK:
+		return nil
+	case data[0] == mysqlPacketErr:
+		return parseMySQLErrPacket(data)
+	}
+	return fmt.Errorf("unexpected response packet: %x", data[0])
+}
+
+//------------------------------------------------------------------------------
+
+func mysqlScrambleNative(scramble []byte, password string) []byte {
+	if len(password) == 0 {
+		return nil
+	}
+	h := sha1.New()
+	h.Write([]byte(password))
+	stage1 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(stage1)
+	stage2 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(scramble[:20])
+	h.Write(stage2)
+	res := h.Sum(nil)
+	for i := range res {
+		res[i] ^= stage1[i]
+	}
+	return res
+}
+
+func mysqlScrambleSHA256(scramble []byte, password string) []byte {
+	if len(password) == 0 {
+		return nil
+	}
+	h := sha256.New()
+	h.Write([]byte(password))
+	m1 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(m1)
+	m1Hash := h.Sum(nil)
+
+	h.Reset()
+	h.Write(m1Hash)
+	h.Write(scramble)
+	m2 := h.Sum(nil)
+	for i := range m1 {
+		m1[i] ^= m2[i]
+	}
+	return m1
+}
+
+func mysqlAuthResponse(plugin string, scramble []byte, password string) ([]byte, error) {
+	switch plugin {
+	case mysqlNativePassword:
+		if len(scramble) < 20 {
+			return nil, errors.New("authentication scramble is too short")
+		}
+		return mysqlScrambleNative(scramble, password), nil
+	case mysqlCachingSHA2Passwd:
+		return mysqlScrambleSHA256(scramble, password), nil
+	}
+	return nil, fmt.Errorf("authentication plugin not supported: %v", plugin)
+}
+
+func (c *mysqlConn) handshake(cfg *mysql.Config) error {
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && data[0] == mysqlPacketErr {
+		return parseMySQLErrPacket(data)
+	}
+
+	r := &mysqlReader{data: data}
+	if proto := r.byte(); r.err == nil && proto != 10 {
+		return fmt.Errorf("protocol version not supported: %v", proto)
+	}
+	r.string() // Server version
+	r.next(4)  // Connection ID
+	scramble := append([]byte{}, r.next(8)...)
+	r.next(1) // Filler
+	caps := uint32(r.uint16())
+	plugin := mysqlNativePassword
+	if len(r.data) > 0 {
+		r.next(1) // Character set
+		r.next(2) // Status flags
+		caps |= uint32(r.uint16()) << 16
+		authLen := int(r.byte())
+		r.next(10) // Reserved
+		if caps&mysqlClientSecureConnection != 0 {
+			n := authLen - 8
+			if n < 13 {
+				n = 13
+			}
+			scramble = append(scramble, r.next(n)...)
+			if len(scramble) > 0 && scramble[len(scramble)-1] == 0 {
+				scramble = scramble[:len(scramble)-1]
+			}
+		}
+		if caps&mysqlClientPluginAuth != 0 {
+			plugin = r.string()
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to parse handshake: %v", r.err)
+	}
+	if caps&mysqlClientProtocol41 == 0 {
+		return errors.New("server does not support protocol 4.1")
+	}
+
+	authResp, err := mysqlAuthResponse(plugin, scramble, cfg.Passwd)
+	if err != nil {
+		return err
+	}
+
+	clientCaps := uint32(mysqlClientLongPassword | mysqlClientLongFlag | mysqlClientProtocol41 |
+		mysqlClientTransactions | mysqlClientSecureConnection | mysqlClientPluginAuth)
+	if len(cfg.DBName) > 0 {
+		clientCaps |= mysqlClientConnectWithDB
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, clientCaps)
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.WriteByte(mysqlCharsetUTF8MB4)
+	buf.Write(make([]byte, 23))
+	buf.WriteString(cfg.User)
+	buf.WriteByte(0)
+	buf.WriteByte(byte(len(authResp)))
+	buf.Write(authResp)
+	if len(cfg.DBName) > 0 {
+		buf.WriteString(cfg.DBName)
+		buf.WriteByte(0)
+	}
+	buf.WriteString(plugin)
+	buf.WriteByte(0)
+	if err = c.writePacket(buf.Bytes()); err != nil {
+		return err
+	}
+	return c.authResult(plugin, scramble, cfg.Passwd, false)
+}
+
+func (c *mysqlConn) authResult(plugin string, scramble []byte, password string, switched bool) error {
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return errors.New("empty authentication response")
+	}
+
+	switch data[0] {
+	case mysqlPacketOK:
+		return nil
+	case mysqlPacketErr:
+		return parseMySQLErrPacket(data)
+	case mysqlPacketEOF:
+		// Authentication method switch request.
+		if switched {
+			return errors.New("authentication method switched more than once")
+		}
+		r := &mysqlReader{data: data[1:]}
+		plugin = r.string()
+		if r.err != nil {
+			return fmt.Errorf("failed to parse authentication switch: %v", r.err)
+		}
+		scramble = bytes.TrimRight(r.data, "\x00")
+		authResp, err := mysqlAuthResponse(plugin, scramble, password)
+		if err != nil {
+			return err
+		}
+		if err = c.writePacket(authResp); err != nil {
+			return err
+		}
+		return c.authResult(plugin, scramble, password, true)
+	case mysqlPacketAuthMore:
+		if plugin != mysqlCachingSHA2Passwd || len(data) < 2 {
+			return fmt.Errorf("unexpected authentication data for plugin %v", plugin)
+		}
+		switch data[1] {
+		case mysqlFastAuthSuccess:
+			return c.readOK()
+		case mysqlPerformFullAuth:
+			return c.fullAuthSHA2(scramble, password)
+		}
+	}
+	return fmt.Errorf("unexpected authentication response: %x", data[0])
+}
+
+// fullAuthSHA2 performs a full caching_sha2_password authentication by
+// encrypting the password with the public key of the server.
+func (c *mysqlConn) fullAuthSHA2(scramble []byte, password string) error {
+	if err := c.writePacket([]byte{mysqlRequestPublicKey}); err != nil {
+		return err
+	}
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[0] != mysqlPacketAuthMore {
+		return errors.New("failed to obtain public key from server")
+	}
+
+	block, _ := pem.Decode(data[1:])
+	if block == nil {
+		return errors.New("failed to decode public key from server")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key from server: %v", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("public key from server is not an RSA key")
+	}
+
+	plain := make([]byte, len(password)+1)
+	copy(plain, password)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	enc, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaPub, plain, nil)
+	if err != nil {
+		return err
+	}
+	if err = c.writePacket(enc); err != nil {
+		return err
+	}
+	return c.readOK()
+}
+
+//------------------------------------------------------------------------------
+
+// exec executes a query that is not expected to return rows.
+func (c *mysqlConn) exec(query string) error {
+	if err := c.writeCommand(append([]byte{mysqlComQuery}, query...)); err != nil {
+		return err
+	}
+	return c.readOK()
+}
+
+// registerReplica registers the connection as a replica with a server ID.
+func (c *mysqlConn) registerReplica(serverID uint32) error {
+	var buf bytes.Buffer
+	buf.WriteByte(mysqlComRegisterSlave)
+	binary.Write(&buf, binary.LittleEndian, serverID)
+	buf.Write([]byte{0, 0, 0})                         // Hostname, user and password
+	binary.Write(&buf, binary.LittleEndian, uint16(0)) // Port
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // Replication rank
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // Master ID
+	if err := c.writeCommand(buf.Bytes()); err != nil {
+		return err
+	}
+	return c.readOK()
+}
+
+// binlogDump requests the server to stream the binary log from a position.
+func (c *mysqlConn) binlogDump(serverID uint32, pos mysqlBinlogPos) error {
+	var buf bytes.Buffer
+	buf.WriteByte(mysqlComBinlogDump)
+	binary.Write(&buf, binary.LittleEndian, pos.pos)
+	binary.Write(&buf, binary.LittleEndian, uint16(0)) // Flags
+	binary.Write(&buf, binary.LittleEndian, serverID)
+	buf.WriteString(pos.file)
+	return c.writeCommand(buf.Bytes())
+}
+
+// readEvent reads the next event of a binary log stream.
+func (c *mysqlConn) readEvent() ([]byte, error) {
+	data, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty binlog packet")
+	}
+	switch data[0] {
+	case mysqlPacketOK:
+		return data[1:], nil
+	case mysqlPacketErr:
+		return nil, parseMySQLErrPacket(data)
+	case mysqlPacketEOF:
+		return nil, io.EOF
+	}
+	return nil, fmt.Errorf("unexpected binlog packet: %x", data[0])
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,917 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	mysqlEventQuery             = 2
+	mysqlEventRotate            = 4
+	mysqlEventFormatDescription = 15
+	mysqlEventXID               = 16
+	mysqlEventTableMap          = 19
+	mysqlEventWriteRowsV1       = 23
+	mysqlEventUpdateRowsV1      = 24
+	mysqlEventDeleteRowsV1      = 25
+	mysqlEventWriteRowsV2       = 30
+	mysqlEventUpdateRowsV2      = 31
+	mysqlEventDeleteRowsV2      = 32
+)
+
+const (
+	mysqlTypeDecimal    = 0
+	mysqlTypeTiny       = 1
+	mysqlTypeShort      = 2
+	mysqlTypeLong       = 3
+	mysqlTypeFloat      = 4
+	mysqlTypeDouble     = 5
+	mysqlTypeNull       = 6
+	mysqlTypeTimestamp  = 7
+	mysqlTypeLongLong   = 8
+	mysqlTypeInt24      = 9
+	mysqlTypeDate       = 10
+	mysqlTypeTime       = 11
+	mysqlTypeDateTime   = 12
+	mysqlTypeYear       = 13
+	mysqlTypeVarchar    = 15
+	mysqlTypeBit        = 16
+	mysqlTypeTimestamp2 = 17
+	mysqlTypeDateTime2  = 18
+	mysqlTypeTime2      = 19
+	mysqlTypeJSON       = 245
+	mysqlTypeNewDecimal = 246
+	mysqlTypeEnum       = 247
+	mysqlTypeSet        = 248
+	mysqlTypeBlob       = 252
+	mysqlTypeVarString  = 253
+	mysqlTypeString     = 254
+	mysqlTypeGeometry   = 255
+)
+
+var errMySQLMalformed = errors.New("malformed binlog data")
+
+//------------------------------------------------------------------------------
+
+// mysqlReader reads the little endian encoded values of the MySQL protocol.
+type mysqlReader struct {
+	data []byte
+	err  error
+}
+
+func (r *mysqlReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data) < n {
+		r.err = errMySQLMalformed
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *mysqlReader) uint(n int) uint64 {
+	var v uint64
+	for i, b := range r.next(n) {
+		v |= uint64(b) << (8 * uint(i))
+	}
+	return v
+}
+
+func (r *mysqlReader) uintBE(n int) uint64 {
+	var v uint64
+	for _, b := range r.next(n) {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func (r *mysqlReader) byte() byte {
+	return byte(r.uint(1))
+}
+
+func (r *mysqlReader) uint16() uint16 {
+	return uint16(r.uint(2))
+}
+
+func (r *mysqlReader) uint32() uint32 {
+	return uint32(r.uint(4))
+}
+
+func (r *mysqlReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.err = errMySQLMalformed
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+// lenenc reads a length encoded integer.
+func (r *mysqlReader) lenenc() uint64 {
+	switch b := r.byte(); b {
+	case 0xfc:
+		return r.uint(2)
+	case 0xfd:
+		return r.uint(3)
+	case 0xfe:
+		return r.uint(8)
+	default:
+		return uint64(b)
+	}
+}
+
+func mysqlBitSet(bitmap []byte, i int) bool {
+	return bitmap[i/8]&(1<<(uint(i)%8)) != 0
+}
+
+//------------------------------------------------------------------------------
+
+type mysqlEventHeader struct {
+	timestamp uint32
+	eventType byte
+	serverID  uint32
+	size      uint32
+	logPos    uint32
+	flags     uint16
+}
+
+// mysqlColumn describes a column of a table, as the binary log does not
+// contain column names or signedness.
+type mysqlColumn struct {
+	name     string
+	unsigned bool
+	values   []string
+}
+
+type mysqlTableMap struct {
+	schema string
+	table  string
+	types  []byte
+	meta   []uint16
+}
+
+type mysqlRowsEvent struct {
+	schema string
+	table  string
+
+	// action is one of 'I' (insert), 'U' (update) or 'D' (delete).
+	action byte
+	rows   []mysqlRow
+}
+
+type mysqlRow struct {
+	before map[string]interface{}
+	after  map[string]interface{}
+}
+
+type mysqlEvent struct {
+	header mysqlEventHeader
+
+	// Set for rotate events.
+	nextFile string
+	nextPos  uint64
+
+	// Set for query events.
+	query string
+
+	// Set for rows events of included tables.
+	rows *mysqlRowsEvent
+}
+
+// mysqlBinlogParser parses events of a binary log stream, and retains the
+// table maps required for parsing rows events.
+type mysqlBinlogParser struct {
+	checksumLen int
+	tables      map[uint64]*mysqlTableMap
+
+	include func(schema, table string) bool
+	columns func(schema, table string) ([]mysqlColumn, error)
+}
+
+func newMySQLBinlogParser(
+	include func(schema, table string) bool,
+	columns func(schema, table string) ([]mysqlColumn, error),
+) *mysqlBinlogParser {
+	return &mysqlBinlogParser{
+		tables:  map[uint64]*mysqlTableMap{},
+		include: include,
+		columns: columns,
+	}
+}
+
+func (p *mysqlBinlogParser) parse(data []byte) (mysqlEvent, error) {
+	var e mysqlEvent
+
+	r := &mysqlReader{data: data}
+	e.header = mysqlEventHeader{
+		timestamp: r.uint32(),
+		eventType: r.byte(),
+		serverID:  r.uint32(),
+		size:      r.uint32(),
+		logPos:    r.uint32(),
+		flags:     r.uint16(),
+	}
+	if r.err != nil {
+		return e, r.err
+	}
+
+	if e.header.eventType == mysqlEventFormatDescription {
+		p.formatDescription(r.data)
+	}
+
+	body := r.data
+	if len(body) < p.checksumLen {
+		return e, errMySQLMalformed
+	}
+	body = body[:len(body)-p.checksumLen]
+
+	var err error
+	switch e.header.eventType {
+	case mysqlEventRotate:
+		br := &mysqlReader{data: body}
+		e.nextPos = br.uint(8)
+		e.nextFile = string(br.data)
+		err = br.err
+	case mysqlEventQuery:
+		e.query, err = mysqlParseQuery(body)
+	case mysqlEventTableMap:
+		err = p.tableMap(body)
+	case mysqlEventWriteRowsV1, mysqlEventWriteRowsV2,
+		mysqlEventUpdateRowsV1, mysqlEventUpdateRowsV2,
+		mysqlEventDeleteRowsV1, mysqlEventDeleteRowsV2:
+		e.rows, err = p.rowsEvent(e.header.eventType, body)
+	}
+	return e, err
+}
+
+// formatDescription determines whether events carry a checksum, which is the
+// case for servers from 5.6.1 that have binlog_checksum enabled.
+func (p *mysqlBinlogParser) formatDescription(body []byte) {
+	p.checksumLen = 0
+	if len(body) < 57 {
+		return
+	}
+	version := string(bytes.TrimRight(body[2:52], "\x00"))
+	if !mysqlVersionAtLeast(version, 5, 6, 1) {
+		return
+	}
+	if alg := body[len(body)-5]; alg == 1 {
+		p.checksumLen = 4
+	}
+}
+
+func mysqlVersionAtLeast(version string, major, minor, patch int) bool {
+	var v [3]int
+	for i, s := range strings.SplitN(version, ".", 3) {
+		end := 0
+		for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			end++
+		}
+		v[i], _ = strconv.Atoi(s[:end])
+	}
+	for i, want := range []int{major, minor, patch} {
+		if v[i] != want {
+			return v[i] > want
+		}
+	}
+	return true
+}
+
+func mysqlParseQuery(body []byte) (string, error) {
+	r := &mysqlReader{data: body}
+	r.next(8) // Thread ID and execution time
+	schemaLen := int(r.byte())
+	r.next(2) // Error code
+	statusLen := int(r.uint16())
+	r.next(statusLen)
+	r.next(schemaLen + 1)
+	return string(r.data), r.err
+}
+
+func (p *mysqlBinlogParser) tableMap(body []byte) error {
+	r := &mysqlReader{data: body}
+	id := r.uint(6)
+	r.next(2) // Flags
+
+	t := &mysqlTableMap{}
+	t.schema = string(r.next(int(r.byte())))
+	r.next(1)
+	t.table = string(r.next(int(r.byte())))
+	r.next(1)
+
+	n := int(r.lenenc())
+	t.types = append([]byte{}, r.next(n)...)
+
+	meta := &mysqlReader{data: r.next(int(r.lenenc()))}
+	if r.err != nil {
+		return r.err
+	}
+
+	t.meta = make([]uint16, n)
+	for i, typ := range t.types {
+		switch typ {
+		case mysqlTypeString, mysqlTypeNewDecimal:
+			t.meta[i] = uint16(meta.uintBE(2))
+		case mysqlTypeVarchar, mysqlTypeVarString, mysqlTypeBit:
+			t.meta[i] = meta.uint16()
+		case mysqlTypeBlob, mysqlTypeDouble, mysqlTypeFloat, mysqlTypeGeometry, mysqlTypeJSON,
+			mysqlTypeTime2, mysqlTypeDateTime2, mysqlTypeTimestamp2:
+			t.meta[i] = uint16(meta.byte())
+		}
+	}
+	if meta.err != nil {
+		return meta.err
+	}
+
+	p.tables[id] = t
+	return nil
+}
+
+func (p *mysqlBinlogParser) rowsEvent(eventType byte, body []byte) (*mysqlRowsEvent, error) {
+	r := &mysqlReader{data: body}
+	id := r.uint(6)
+	r.next(2) // Flags
+	if eventType >= mysqlEventWriteRowsV2 {
+		if extra := int(r.uint16()); extra > 2 {
+			r.next(extra - 2)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	t, exists := p.tables[id]
+	if !exists {
+		return nil, fmt.Errorf("table map for table ID %v not found", id)
+	}
+	if p.include != nil && !p.include(t.schema, t.table) {
+		return nil, nil
+	}
+
+	var cols []mysqlColumn
+	if p.columns != nil {
+		var err error
+		if cols, err = p.columns(t.schema, t.table); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &mysqlRowsEvent{schema: t.schema, table: t.table}
+	switch eventType {
+	case mysqlEventWriteRowsV1, mysqlEventWriteRowsV2:
+		e.action = 'I'
+	case mysqlEventUpdateRowsV1, mysqlEventUpdateRowsV2:
+		e.action = 'U'
+	default:
+		e.action = 'D'
+	}
+
+	n := int(r.lenenc())
+	if r.err == nil && n > len(t.types) {
+		return nil, fmt.Errorf("rows event has %v columns but table map has %v", n, len(t.types))
+	}
+	present := r.next((n + 7) / 8)
+	presentAfter := present
+	if e.action == 'U' {
+		presentAfter = r.next((n + 7) / 8)
+	}
+
+	for r.err == nil && len(r.data) > 0 {
+		var row mysqlRow
+		var err error
+		if e.action != 'I' {
+			if row.before, err = p.image(r, t, cols, n, present); err != nil {
+				return nil, err
+			}
+		}
+		if e.action != 'D' {
+			if row.after, err = p.image(r, t, cols, n, presentAfter); err != nil {
+				return nil, err
+			}
+		}
+		e.rows = append(e.rows, row)
+	}
+	return e, r.err
+}
+
+func (p *mysqlBinlogParser) image(r *mysqlReader, t *mysqlTableMap, cols []mysqlColumn, n int, present []byte) (map[string]interface{}, error) {
+	count := 0
+	for i := 0; i < n; i++ {
+		if mysqlBitSet(present, i) {
+			count++
+		}
+	}
+	nulls := r.next((count + 7) / 8)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	image := make(map[string]interface{}, count)
+	ni := 0
+	for i := 0; i < n; i++ {
+		if !mysqlBitSet(present, i) {
+			continue
+		}
+		col := mysqlColumn{name: fmt.Sprintf("column_%v", i+1)}
+		if i < len(cols) {
+			col = cols[i]
+		}
+		if mysqlBitSet(nulls, ni) {
+			image[col.name] = nil
+		} else {
+			v, err := mysqlValue(r, t.types[i], t.meta[i], col)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode column %v: %v", col.name, err)
+			}
+			image[col.name] = v
+		}
+		ni++
+	}
+	return image, r.err
+}
+
+//------------------------------------------------------------------------------
+
+// mysqlValue decodes a column value of a row image into a type that best
+// represents it within a JSON document.
+func mysqlValue(r *mysqlReader, typ byte, meta uint16, col mysqlColumn) (interface{}, error) {
+	switch typ {
+	case mysqlTypeTiny:
+		return mysqlInt(r.uint(1), 1, col.unsigned), r.err
+	case mysqlTypeShort:
+		return mysqlInt(r.uint(2), 2, col.unsigned), r.err
+	case mysqlTypeInt24:
+		return mysqlInt(r.uint(3), 3, col.unsigned), r.err
+	case mysqlTypeLong:
+		return mysqlInt(r.uint(4), 4, col.unsigned), r.err
+	case mysqlTypeLongLong:
+		return mysqlInt(r.uint(8), 8, col.unsigned), r.err
+	case mysqlTypeFloat:
+		return mysqlFloat(float64(math.Float32frombits(uint32(r.uint(4))))), r.err
+	case mysqlTypeDouble:
+		return mysqlFloat(math.Float64frombits(r.uint(8))), r.err
+	case mysqlTypeYear:
+		if y := r.byte(); y > 0 {
+			return int64(y) + 1900, r.err
+		}
+		return int64(0), r.err
+	case mysqlTypeNewDecimal:
+		return mysqlDecimal(r, int(meta>>8), int(meta&0xff))
+	case mysqlTypeBit:
+		nbits := int(meta>>8)*8 + int(meta&0xff)
+		return r.uintBE((nbits + 7) / 8), r.err
+	case mysqlTypeDate:
+		v := r.uint(3)
+		return fmt.Sprintf("%04d-%02d-%02d", v>>9, (v>>5)%16, v%32), r.err
+	case mysqlTypeTime:
+		v := int64(int32(uint32(r.uint(3))<<8) >> 8)
+		sign := ""
+		if v < 0 {
+			sign, v = "-", -v
+		}
+		return fmt.Sprintf("%v%02d:%02d:%02d", sign, v/10000, (v%10000)/100, v%100), r.err
+	case mysqlTypeTime2:
+		return mysqlTime2(r, int(meta))
+	case mysqlTypeDateTime:
+		v := r.uint(8)
+		d, t := v/1000000, v%1000000
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", d/10000, (d%10000)/100, d%100, t/10000, (t%10000)/100, t%100), r.err
+	case mysqlTypeDateTime2:
+		intPart := int64(r.uintBE(5)) - 0x8000000000
+		frac := mysqlFracMicros(r, int(meta))
+		return mysqlFormatDateTime(intPart, frac, int(meta)), r.err
+	case mysqlTypeTimestamp:
+		return mysqlFormatTimestamp(int64(r.uint(4)), 0, 0), r.err
+	case mysqlTypeTimestamp2:
+		secs := int64(r.uintBE(4))
+		frac := mysqlFracMicros(r, int(meta))
+		return mysqlFormatTimestamp(secs, frac, int(meta)), r.err
+	case mysqlTypeVarchar, mysqlTypeVarString:
+		size := 1
+		if meta > 255 {
+			size = 2
+		}
+		return string(r.next(int(r.uint(size)))), r.err
+	case mysqlTypeString:
+		realType, length := byte(meta>>8), int(meta&0xff)
+		if realType&0x30 != 0x30 {
+			length |= int((realType&0x30)^0x30) << 4
+			realType |= 0x30
+		}
+		switch realType {
+		case mysqlTypeEnum:
+			return mysqlEnum(r.uint(length), col.values), r.err
+		case mysqlTypeSet:
+			return mysqlSet(r.uint(length), col.values), r.err
+		}
+		size := 1
+		if length > 255 {
+			size = 2
+		}
+		return string(r.next(int(r.uint(size)))), r.err
+	case mysqlTypeBlob, mysqlTypeGeometry:
+		return string(r.next(int(r.uint(int(meta))))), r.err
+	case mysqlTypeJSON:
+		b := r.next(int(r.uint(int(meta))))
+		if r.err != nil {
+			return nil, r.err
+		}
+		return mysqlDecodeJSON(b)
+	}
+	return nil, fmt.Errorf("column type not supported: %v", typ)
+}
+
+func mysqlInt(v uint64, size int, unsigned bool) interface{} {
+	if unsigned {
+		return v
+	}
+	shift := uint(64 - size*8)
+	return int64(v<<shift) >> shift
+}
+
+func mysqlFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return f
+}
+
+func mysqlEnum(i uint64, values []string) interface{} {
+	if i == 0 {
+		return ""
+	}
+	if int(i) <= len(values) {
+		return values[i-1]
+	}
+	return i
+}
+
+func mysqlSet(mask uint64, values []string) interface{} {
+	if len(values) == 0 {
+		return mask
+	}
+	var members []string
+	for i, v := range values {
+		if mask&(1<<uint(i)) != 0 {
+			members = append(members, v)
+		}
+	}
+	return strings.Join(members, ",")
+}
+
+var mysqlDigitsToBytes = [10]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// mysqlDecimal decodes the binary representation of a DECIMAL column as
+// described in strings/decimal.c of the MySQL source.
+func mysqlDecimal(r *mysqlReader, precision, scale int) (interface{}, error) {
+	if scale > precision || precision > 65 {
+		return nil, fmt.Errorf("invalid decimal precision %v and scale %v", precision, scale)
+	}
+	integral := precision - scale
+	uncompIntegral, compIntegral := integral/9, integral%9
+	uncompFractional, compFractional := scale/9, scale%9
+	size := uncompIntegral*4 + mysqlDigitsToBytes[compIntegral] + uncompFractional*4 + mysqlDigitsToBytes[compFractional]
+
+	raw := r.next(size)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if size == 0 {
+		return json.Number("0"), nil
+	}
+	buf := &mysqlReader{data: append([]byte{}, raw...)}
+	positive := buf.data[0]&0x80 != 0
+	buf.data[0] ^= 0x80
+	if !positive {
+		for i := range buf.data {
+			buf.data[i] ^= 0xff
+		}
+	}
+
+	var digits strings.Builder
+	if n := mysqlDigitsToBytes[compIntegral]; n > 0 {
+		digits.WriteString(strconv.FormatUint(buf.uintBE(n), 10))
+	}
+	for i := 0; i < uncompIntegral; i++ {
+		fmt.Fprintf(&digits, "%09d", buf.uintBE(4))
+	}
+
+	res := strings.TrimLeft(digits.String(), "0")
+	if len(res) == 0 {
+		res = "0"
+	}
+	if !positive {
+		res = "-" + res
+	}
+
+	if scale > 0 {
+		digits.Reset()
+		for i := 0; i < uncompFractional; i++ {
+			fmt.Fprintf(&digits, "%09d", buf.uintBE(4))
+		}
+		if n := mysqlDigitsToBytes[compFractional]; n > 0 {
+			fmt.Fprintf(&digits, "%0*d", compFractional, buf.uintBE(n))
+		}
+		res += "." + digits.String()
+	}
+	return json.Number(res), nil
+}
+
+// mysqlFracMicros reads the fractional seconds of a temporal column with a
+// given precision and returns them as microseconds.
+func mysqlFracMicros(r *mysqlReader, fsp int) int64 {
+	switch fsp {
+	case 1, 2:
+		return int64(r.uintBE(1)) * 10000
+	case 3, 4:
+		return int64(r.uintBE(2)) * 100
+	case 5, 6:
+		return int64(r.uintBE(3))
+	}
+	return 0
+}
+
+func mysqlFormatFrac(micros int64, fsp int) string {
+	if fsp <= 0 {
+		return ""
+	}
+	if fsp > 6 {
+		fsp = 6
+	}
+	return "." + fmt.Sprintf("%06d", micros)[:fsp]
+}
+
+func mysqlFormatDateTime(intPart, micros int64, fsp int) string {
+	ymd, hms := intPart>>17, intPart%(1<<17)
+	ym := ymd >> 5
+	return fmt.Sprintf(
+		"%04d-%02d-%02d %02d:%02d:%02d",
+		ym/13, ym%13, ymd%(1<<5), hms>>12, (hms>>6)%(1<<6), hms%(1<<6),
+	) + mysqlFormatFrac(micros, fsp)
+}
+
+func mysqlFormatTimestamp(secs, micros int64, fsp int) string {
+	if secs == 0 && micros == 0 {
+		return "0000-00-00 00:00:00" + mysqlFormatFrac(0, fsp)
+	}
+	return time.Unix(secs, 0).UTC().Format("2006-01-02 15:04:05") + mysqlFormatFrac(micros, fsp)
+}
+
+// mysqlFormatTime formats a packed time value, where the upper bits are the
+// hours, minutes and seconds and the lower 24 bits are microseconds.
+func mysqlFormatTime(packed int64, fsp int) string {
+	sign := ""
+	if packed < 0 {
+		sign, packed = "-", -packed
+	}
+	hms, micros := packed>>24, packed%(1<<24)
+	return fmt.Sprintf(
+		"%v%02d:%02d:%02d", sign, (hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6),
+	) + mysqlFormatFrac(micros, fsp)
+}
+
+func mysqlTime2(r *mysqlReader, fsp int) (interface{}, error) {
+	var packed int64
+	switch fsp {
+	case 1, 2, 3, 4:
+		intPart := int64(r.uintBE(3)) - 0x800000
+		size, mul := 1, int64(10000)
+		if fsp > 2 {
+			size, mul = 2, 100
+		}
+		frac, max := int64(r.uintBE(size)), int64(1)<<(8*uint(size))
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= max
+		}
+		packed = intPart<<24 + frac*mul
+	case 5, 6:
+		packed = int64(r.uintBE(6)) - 0x800000000000
+	default:
+		packed = (int64(r.uintBE(3)) - 0x800000) << 24
+	}
+	return mysqlFormatTime(packed, fsp), r.err
+}
+
+//------------------------------------------------------------------------------
+
+// mysqlDecodeJSON decodes the binary representation of a JSON column as
+// described in sql-common/json_binary.cc of the MySQL source.
+func mysqlDecodeJSON(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return mysqlJSONValue(data[0], data[1:])
+}
+
+func mysqlJSONVarLen(data []byte) (int, int, error) {
+	var n int
+	for i := 0; i < 5 && i < len(data); i++ {
+		n |= int(data[i]&0x7f) << (7 * uint(i))
+		if data[i]&0x80 == 0 {
+			return n, i + 1, nil
+		}
+	}
+	return 0, 0, errMySQLMalformed
+}
+
+func mysqlJSONValue(t byte, data []byte) (interface{}, error) {
+	r := &mysqlReader{data: data}
+	switch t {
+	case 0x00, 0x01, 0x02, 0x03:
+		return mysqlJSONContainer(data, t == 0x01 || t == 0x03, t < 0x02)
+	case 0x04:
+		switch r.byte() {
+		case 0x01:
+			return true, r.err
+		case 0x02:
+			return false, r.err
+		}
+		return nil, r.err
+	case 0x05:
+		return int64(int16(r.uint16())), r.err
+	case 0x06:
+		return uint64(r.uint16()), r.err
+	case 0x07:
+		return int64(int32(r.uint32())), r.err
+	case 0x08:
+		return uint64(r.uint32()), r.err
+	case 0x09:
+		return int64(r.uint(8)), r.err
+	case 0x0a:
+		return r.uint(8), r.err
+	case 0x0b:
+		return mysqlFloat(math.Float64frombits(r.uint(8))), r.err
+	case 0x0c:
+		n, size, err := mysqlJSONVarLen(data)
+		if err != nil {
+			return nil, err
+		}
+		r.next(size)
+		return string(r.next(n)), r.err
+	case 0x0f:
+		typ := r.byte()
+		n, size, err := mysqlJSONVarLen(r.data)
+		if err != nil {
+			return nil, err
+		}
+		r.next(size)
+		return mysqlJSONOpaque(typ, r.next(n), r.err)
+	}
+	return nil, fmt.Errorf("json value type not recognised: %v", t)
+}
+
+func mysqlJSONOpaque(typ byte, data []byte, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	r := &mysqlReader{data: data}
+	switch typ {
+	case mysqlTypeNewDecimal:
+		precision, scale := int(r.byte()), int(r.byte())
+		if r.err != nil {
+			return nil, r.err
+		}
+		return mysqlDecimal(r, precision, scale)
+	case mysqlTypeDate, mysqlTypeDateTime, mysqlTypeTimestamp:
+		packed := int64(r.uint(8))
+		s := mysqlFormatDateTime(packed>>24, packed%(1<<24), 6)
+		if typ == mysqlTypeDate {
+			s = s[:10]
+		}
+		return s, r.err
+	case mysqlTypeTime:
+		return mysqlFormatTime(int64(r.uint(8)), 6), r.err
+	}
+	return string(data), nil
+}
+
+func mysqlJSONContainer(data []byte, large, isObject bool) (interface{}, error) {
+	offSize := 2
+	if large {
+		offSize = 4
+	}
+	readOff := func(b []byte) int {
+		r := &mysqlReader{data: b}
+		return int(r.uint(offSize))
+	}
+
+	if len(data) < 2*offSize {
+		return nil, errMySQLMalformed
+	}
+	count, size := readOff(data), readOff(data[offSize:])
+	if size > len(data) {
+		return nil, errMySQLMalformed
+	}
+	data = data[:size]
+
+	keyEntrySize, valueEntrySize := offSize+2, 1+offSize
+	valueEntries := 2 * offSize
+	if isObject {
+		valueEntries += count * keyEntrySize
+	}
+	if valueEntries+count*valueEntrySize > size {
+		return nil, errMySQLMalformed
+	}
+
+	keys := make([]string, count)
+	if isObject {
+		for i := range keys {
+			e := 2*offSize + i*keyEntrySize
+			keyOff := readOff(data[e:])
+			keyLen := int(binary.LittleEndian.Uint16(data[e+offSize:]))
+			if keyOff+keyLen > size {
+				return nil, errMySQLMalformed
+			}
+			keys[i] = string(data[keyOff : keyOff+keyLen])
+		}
+	}
+
+	values := make([]interface{}, count)
+	for i := range values {
+		e := valueEntries + i*valueEntrySize
+		t := data[e]
+
+		inlined := t == 0x04 || t == 0x05 || t == 0x06 || (large && (t == 0x07 || t == 0x08))
+		var err error
+		if inlined {
+			values[i], err = mysqlJSONValue(t, data[e+1:e+1+offSize])
+		} else {
+			off := readOff(data[e+1:])
+			if off >= size {
+				return nil, errMySQLMalformed
+			}
+			values[i], err = mysqlJSONValue(t, data[off:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !isObject {
+		return values, nil
+	}
+	obj := make(map[string]interface{}, count)
+	for i, k := range keys {
+		obj[k] = values[i]
+	}
+	return obj, nil
+}
+
+//------------------------------------------------------------------------------
+
+// mysqlParseColumnType parses the COLUMN_TYPE of a column from the
+// information_schema, e.g. "int(10) unsigned" or "enum('a','b')".
+func mysqlParseColumnType(name, columnType string) mysqlColumn {
+	col := mysqlColumn{name: name}
+	lower := strings.ToLower(columnType)
+	if strings.HasPrefix(lower, "enum(") || strings.HasPrefix(lower, "set(") {
+		s := columnType[strings.IndexByte(columnType, '(')+1:]
+		for len(s) > 0 && s[0] == '\'' {
+			var v strings.Builder
+			i := 1
+			for ; i < len(s); i++ {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						v.WriteByte('\'')
+						i++
+						continue
+					}
+					break
+				}
+				v.WriteByte(s[i])
+			}
+			col.values = append(col.values, v.String())
+			if i+1 >= len(s) {
+				break
+			}
+			s = s[i+1:]
+			if s[0] != ',' {
+				break
+			}
+			s = s[1:]
+		}
+	} else {
+		col.unsigned = strings.Contains(lower, "unsigned")
+	}
+	return col
+}
+
+//------------------------------------------------------------------------------
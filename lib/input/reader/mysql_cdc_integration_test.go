@@ -0,0 +1,125 @@
+// +build integration
+
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ory/dockertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMySQLCDCAuthIntegration authenticates the replication client against
+// servers that advertise different default authentication plugins, with users
+// of each plugin, which covers plugin switches as well as both the full and
+// cached caching_sha2_password exchanges.
+func TestMySQLCDCAuthIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Second * 60
+
+	servers := map[string]struct {
+		tag     string
+		cmd     []string
+		plugins []string
+	}{
+		"8.0 caching_sha2_password default": {
+			tag:     "8.0",
+			plugins: []string{mysqlCachingSHA2Passwd, mysqlNativePassword},
+		},
+		"8.0 mysql_native_password default": {
+			tag:     "8.0",
+			cmd:     []string{"--default-authentication-plugin=mysql_native_password"},
+			plugins: []string{mysqlCachingSHA2Passwd, mysqlNativePassword},
+		},
+		"5.7": {
+			tag:     "5.7",
+			plugins: []string{mysqlNativePassword},
+		},
+	}
+
+	for name, server := range servers {
+		server := server
+		t.Run(name, func(t *testing.T) {
+			resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+				Repository:   "mysql",
+				Tag:          server.tag,
+				ExposedPorts: []string{"3306/tcp"},
+				Env: []string{
+					"MYSQL_ROOT_PASSWORD=testpass",
+					"MYSQL_DATABASE=testdb",
+				},
+				Cmd: server.cmd,
+			})
+			if err != nil {
+				t.Fatalf("Could not start resource: %s", err)
+			}
+			defer func() {
+				if err = pool.Purge(resource); err != nil {
+					t.Logf("Failed to clean up docker resource: %v", err)
+				}
+			}()
+			resource.Expire(900)
+
+			addr := fmt.Sprintf("localhost:%v", resource.GetPort("3306/tcp"))
+
+			var db *sql.DB
+			if err = pool.Retry(func() error {
+				if db, err = sql.Open("mysql", fmt.Sprintf("root:testpass@tcp(%v)/testdb", addr)); err != nil {
+					return err
+				}
+				if err = db.Ping(); err != nil {
+					db.Close()
+					return err
+				}
+				return nil
+			}); err != nil {
+				t.Fatalf("Could not connect to docker resource: %s", err)
+			}
+			defer db.Close()
+
+			for _, plugin := range server.plugins {
+				user := "user_" + plugin
+				_, err = db.Exec(fmt.Sprintf("CREATE USER '%v'@'%%' IDENTIFIED WITH %v BY 'userpass'", user, plugin))
+				require.NoError(t, err, plugin)
+
+				cfg := mysql.NewConfig()
+				cfg.Net = "tcp"
+				cfg.Addr = addr
+				cfg.User = user
+				cfg.Passwd = "userpass"
+				cfg.DBName = "testdb"
+
+				// Flushing privileges clears the caching_sha2_password cache
+				// of the server, therefore the first connection performs a
+				// full authentication and the second is served from the
+				// cache.
+				_, err = db.Exec("FLUSH PRIVILEGES")
+				require.NoError(t, err)
+				for i := 0; i < 2; i++ {
+					conn, err := dialMySQL(context.Background(), cfg)
+					require.NoError(t, err, plugin)
+					assert.NoError(t, conn.exec("SET @benthos_test = 1"), plugin)
+					conn.Close()
+				}
+
+				cfg.Passwd = "nope"
+				_, err = dialMySQL(context.Background(), cfg)
+				require.Error(t, err, plugin)
+				assert.Contains(t, err.Error(), "Access denied", plugin)
+			}
+		})
+	}
+}
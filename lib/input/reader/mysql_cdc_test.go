@@ -0,0 +1,309 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mysqlBuilder struct {
+	bytes.Buffer
+}
+
+func (b *mysqlBuilder) uint(v uint64, n int) *mysqlBuilder {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	b.Write(buf[:n])
+	return b
+}
+
+func (b *mysqlBuilder) uintBE(v uint64, n int) *mysqlBuilder {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	b.Write(buf[8-n:])
+	return b
+}
+
+func (b *mysqlBuilder) raw(bs ...byte) *mysqlBuilder {
+	b.Write(bs)
+	return b
+}
+
+func (b *mysqlBuilder) str(s string) *mysqlBuilder {
+	b.WriteByte(byte(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+func mysqlTestEvent(eventType byte, logPos uint32, body []byte) []byte {
+	b := &mysqlBuilder{}
+	b.uint(1600000000, 4).raw(eventType).uint(1, 4)
+	b.uint(uint64(19+len(body)), 4).uint(uint64(logPos), 4).uint(0, 2)
+	b.Write(body)
+	return b.Bytes()
+}
+
+func TestMySQLCDCDecimal(t *testing.T) {
+	tests := []struct {
+		precision, scale int
+		data             []byte
+		expected         string
+	}{
+		{14, 4, []byte{0x81, 0x0D, 0xFB, 0x38, 0xD2, 0x04, 0xD2}, "1234567890.1234"},
+		{14, 4, []byte{0x7E, 0xF2, 0x04, 0xC7, 0x2D, 0xFB, 0x2D}, "-1234567890.1234"},
+		{5, 2, []byte{0x80, 0x00, 0x00}, "0.00"},
+		{5, 0, []byte{0x80, 0x00, 0x2A}, "42"},
+	}
+
+	for _, test := range tests {
+		r := &mysqlReader{data: test.data}
+		v, err := mysqlDecimal(r, test.precision, test.scale)
+		require.NoError(t, err)
+		assert.Equal(t, json.Number(test.expected), v)
+		assert.Empty(t, r.data)
+	}
+
+	_, err := mysqlDecimal(&mysqlReader{data: []byte{0x80}}, 14, 4)
+	assert.Error(t, err)
+}
+
+func TestMySQLCDCTemporal(t *testing.T) {
+	ym := uint64(2020*13 + 1)
+	ymd := ym<<5 | 2
+	hms := uint64(3<<12 | 4<<6 | 5)
+	b := &mysqlBuilder{}
+	b.uintBE((ymd<<17|hms)+0x8000000000, 5).uintBE(123456, 3)
+
+	v, err := mysqlValue(&mysqlReader{data: b.Bytes()}, mysqlTypeDateTime2, 6, mysqlColumn{})
+	require.NoError(t, err)
+	assert.Equal(t, "2020-01-02 03:04:05.123456", v)
+
+	b = &mysqlBuilder{}
+	b.uintBE(uint64(12<<12|34<<6|56)+0x800000, 3).uintBE(7890, 2)
+	v, err = mysqlValue(&mysqlReader{data: b.Bytes()}, mysqlTypeTime2, 3, mysqlColumn{})
+	require.NoError(t, err)
+	assert.Equal(t, "12:34:56.789", v)
+
+	b = &mysqlBuilder{}
+	b.uintBE(0x800000-uint64(1<<12|2<<6|3), 3)
+	v, err = mysqlValue(&mysqlReader{data: b.Bytes()}, mysqlTypeTime2, 0, mysqlColumn{})
+	require.NoError(t, err)
+	assert.Equal(t, "-01:02:03", v)
+
+	b = &mysqlBuilder{}
+	b.uintBE(1600000000, 4)
+	v, err = mysqlValue(&mysqlReader{data: b.Bytes()}, mysqlTypeTimestamp2, 0, mysqlColumn{})
+	require.NoError(t, err)
+	assert.Equal(t, "2020-09-13 12:26:40", v)
+
+	b = &mysqlBuilder{}
+	b.uint(2020<<9|12<<5|31, 3)
+	v, err = mysqlValue(&mysqlReader{data: b.Bytes()}, mysqlTypeDate, 0, mysqlColumn{})
+	require.NoError(t, err)
+	assert.Equal(t, "2020-12-31", v)
+}
+
+func TestMySQLCDCJSON(t *testing.T) {
+	arr := &mysqlBuilder{}
+	arr.uint(2, 2).uint(12, 2)
+	arr.raw(0x04).uint(1, 2)
+	arr.raw(0x0c).uint(10, 2)
+	arr.str("x")
+
+	obj := &mysqlBuilder{}
+	obj.raw(0x00)
+	obj.uint(2, 2).uint(uint64(20+arr.Len()), 2)
+	obj.uint(18, 2).uint(1, 2)
+	obj.uint(19, 2).uint(1, 2)
+	obj.raw(0x05).uint(1, 2)
+	obj.raw(0x02).uint(20, 2)
+	obj.raw('a', 'b')
+	obj.Write(arr.Bytes())
+
+	v, err := mysqlDecodeJSON(obj.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": int64(1),
+		"b": []interface{}{true, "x"},
+	}, v)
+
+	_, err = mysqlDecodeJSON([]byte{0x00, 0x05, 0x00})
+	assert.Error(t, err)
+}
+
+func TestMySQLCDCRowsEvent(t *testing.T) {
+	cols := []mysqlColumn{
+		mysqlParseColumnType("id", "int(10) unsigned"),
+		mysqlParseColumnType("name", "varchar(255)"),
+		mysqlParseColumnType("size", "enum('small','large')"),
+		mysqlParseColumnType("flag", "tinyint(1)"),
+	}
+	p := newMySQLBinlogParser(func(schema, table string) bool {
+		return schema == "foodb"
+	}, func(schema, table string) ([]mysqlColumn, error) {
+		return cols, nil
+	})
+
+	tm := &mysqlBuilder{}
+	tm.uint(42, 6).uint(0, 2)
+	tm.str("foodb").raw(0).str("footable").raw(0)
+	tm.raw(4, mysqlTypeLong, mysqlTypeVarchar, mysqlTypeString, mysqlTypeTiny)
+	tm.raw(4).uint(255, 2).raw(mysqlTypeEnum, 1)
+	tm.raw(0x0f)
+
+	e, err := p.parse(mysqlTestEvent(mysqlEventTableMap, 100, tm.Bytes()))
+	require.NoError(t, err)
+	assert.Nil(t, e.rows)
+
+	rows := &mysqlBuilder{}
+	rows.uint(42, 6).uint(0, 2).uint(2, 2)
+	rows.raw(4, 0x0f, 0x0f)
+	rows.raw(0x08).uint(0xFFFFFFFF, 4).str("foo").raw(1)
+	rows.raw(0x00).uint(0xFFFFFFFF, 4).str("bar").raw(2).raw(0xFF)
+
+	e, err = p.parse(mysqlTestEvent(mysqlEventUpdateRowsV2, 200, rows.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(200), e.header.logPos)
+	require.NotNil(t, e.rows)
+	assert.Equal(t, "foodb", e.rows.schema)
+	assert.Equal(t, "footable", e.rows.table)
+	assert.Equal(t, byte('U'), e.rows.action)
+	require.Len(t, e.rows.rows, 1)
+	assert.Equal(t, map[string]interface{}{
+		"id":   uint64(0xFFFFFFFF),
+		"name": "foo",
+		"size": "small",
+		"flag": nil,
+	}, e.rows.rows[0].before)
+	assert.Equal(t, map[string]interface{}{
+		"id":   uint64(0xFFFFFFFF),
+		"name": "bar",
+		"size": "large",
+		"flag": int64(-1),
+	}, e.rows.rows[0].after)
+
+	excluded := &mysqlBuilder{}
+	excluded.uint(43, 6).uint(0, 2)
+	excluded.str("bardb").raw(0).str("bartable").raw(0)
+	excluded.raw(1, mysqlTypeLong).raw(0).raw(0)
+	_, err = p.parse(mysqlTestEvent(mysqlEventTableMap, 300, excluded.Bytes()))
+	require.NoError(t, err)
+
+	rows = &mysqlBuilder{}
+	rows.uint(43, 6).uint(0, 2).uint(2, 2)
+	rows.raw(1, 0x01).raw(0x00).uint(1, 4)
+	e, err = p.parse(mysqlTestEvent(mysqlEventWriteRowsV2, 400, rows.Bytes()))
+	require.NoError(t, err)
+	assert.Nil(t, e.rows)
+
+	rows = &mysqlBuilder{}
+	rows.uint(44, 6).uint(0, 2).uint(2, 2)
+	_, err = p.parse(mysqlTestEvent(mysqlEventWriteRowsV2, 500, rows.Bytes()))
+	assert.Error(t, err)
+}
+
+func TestMySQLCDCRotateAndQuery(t *testing.T) {
+	p := newMySQLBinlogParser(nil, nil)
+
+	rotate := &mysqlBuilder{}
+	rotate.uint(4, 8).raw([]byte("mysql-bin.000002")...)
+	e, err := p.parse(mysqlTestEvent(mysqlEventRotate, 0, rotate.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "mysql-bin.000002", e.nextFile)
+	assert.Equal(t, uint64(4), e.nextPos)
+
+	query := &mysqlBuilder{}
+	query.uint(7, 4).uint(0, 4).raw(5).uint(0, 2).uint(0, 2)
+	query.raw([]byte("foodb")...).raw(0).raw([]byte("BEGIN")...)
+	e, err = p.parse(mysqlTestEvent(mysqlEventQuery, 120, query.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "BEGIN", e.query)
+}
+
+func TestMySQLCDCColumnType(t *testing.T) {
+	col := mysqlParseColumnType("foo", "bigint(20) unsigned")
+	assert.Equal(t, "foo", col.name)
+	assert.True(t, col.unsigned)
+	assert.Empty(t, col.values)
+
+	col = mysqlParseColumnType("foo", "set('a','it''s','c,d')")
+	assert.False(t, col.unsigned)
+	assert.Equal(t, []string{"a", "it's", "c,d"}, col.values)
+
+	assert.Equal(t, "a,c,d", mysqlSet(5, col.values))
+	assert.Equal(t, "it's", mysqlEnum(2, col.values))
+	assert.Equal(t, "", mysqlEnum(0, col.values))
+}
+
+func TestMySQLCDCBinlogPos(t *testing.T) {
+	pos, err := parseMySQLBinlogPos("mysql-bin.000003:1234")
+	require.NoError(t, err)
+	assert.Equal(t, mysqlBinlogPos{file: "mysql-bin.000003", pos: 1234}, pos)
+	assert.Equal(t, "mysql-bin.000003:1234", pos.String())
+
+	pos, err = parseMySQLBinlogPos("c:\\logs\\bin.000001:4")
+	require.NoError(t, err)
+	assert.Equal(t, "c:\\logs\\bin.000001", pos.file)
+
+	for _, s := range []string{"", "foo", ":12", "foo:bar", "foo:-1"} {
+		_, err = parseMySQLBinlogPos(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestMySQLCDCTxnResolve(t *testing.T) {
+	m := &MySQLCDC{}
+
+	first := &mysqlCDCTxn{open: true}
+	m.track(first)
+	m.addPending(first)
+	m.addPending(first)
+
+	second := &mysqlCDCTxn{open: true}
+	m.track(second)
+	m.addPending(second)
+
+	m.closeTxn(first, mysqlBinlogPos{file: "foo", pos: 10})
+	m.resolve(second)
+	m.closeTxn(second, mysqlBinlogPos{file: "foo", pos: 20})
+	assert.Equal(t, mysqlBinlogPos{}, m.ackedPos)
+	assert.Equal(t, mysqlBinlogPos{file: "foo", pos: 20}, m.readPos)
+
+	m.resolve(first)
+	assert.Equal(t, mysqlBinlogPos{}, m.ackedPos)
+
+	m.resolve(first)
+	assert.Equal(t, mysqlBinlogPos{file: "foo", pos: 20}, m.ackedPos)
+	assert.Empty(t, m.txns)
+
+	third := &mysqlCDCTxn{open: true}
+	m.track(third)
+	m.addPending(third)
+	m.abandon(third)
+	assert.Empty(t, m.txns)
+
+	m.resolve(third)
+	assert.Equal(t, mysqlBinlogPos{file: "foo", pos: 20}, m.ackedPos)
+}
+
+func TestMySQLCDCConfigErrors(t *testing.T) {
+	conf := NewMySQLCDCConfig()
+	conf.DSN = "foo:bar@tcp(localhost:3306)/"
+	conf.Tables = []string{"footable"}
+	_, err := NewMySQLCDC(conf, nil, nil, nil)
+	assert.Error(t, err)
+
+	conf.Tables = nil
+	conf.Snapshot = true
+	_, err = NewMySQLCDC(conf, nil, nil, nil)
+	assert.Error(t, err)
+
+	conf = NewMySQLCDCConfig()
+	conf.DSN = "not a dsn"
+	_, err = NewMySQLCDC(conf, nil, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,386 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// PostgresCDCConfig contains configuration fields for the PostgresCDC input
+// type.
+type PostgresCDCConfig struct {
+	DSN          string   `json:"dsn" yaml:"dsn"`
+	Slot         string   `json:"slot" yaml:"slot"`
+	CreateSlot   bool     `json:"create_slot" yaml:"create_slot"`
+	Plugin       string   `json:"plugin" yaml:"plugin"`
+	Publications []string `json:"publications" yaml:"publications"`
+	Limit        int      `json:"limit" yaml:"limit"`
+	PollInterval string   `json:"poll_interval" yaml:"poll_interval"`
+}
+
+// NewPostgresCDCConfig creates a new PostgresCDCConfig with default values.
+func NewPostgresCDCConfig() PostgresCDCConfig {
+	return PostgresCDCConfig{
+		DSN:          "",
+		Slot:         "benthos_slot",
+		CreateSlot:   false,
+		Plugin:       "pgoutput",
+		Publications: []string{"benthos_publication"},
+		Limit:        1000,
+		PollInterval: "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// pgCDCTxn tracks the acknowledgements of the row changes of a transaction,
+// the slot can only be advanced beyond a transaction once all of its row
+// changes are acknowledged.
+type pgCDCTxn struct {
+	endLSN  uint64
+	pending int
+}
+
+type pgCDCPendingPart struct {
+	part types.Part
+	txn  *pgCDCTxn
+}
+
+// PostgresCDC is an input type that reads row changes from a PostgreSQL
+// logical replication slot.
+type PostgresCDC struct {
+	conf         PostgresCDCConfig
+	query        string
+	pollInterval time.Duration
+	decoder      pgDecoder
+
+	dbMut        sync.Mutex
+	db           *sql.DB
+	pending      []pgCDCPendingPart
+	readLSN      uint64
+	confirmedLSN uint64
+
+	ackMut   sync.Mutex
+	txns     []*pgCDCTxn
+	ackedLSN uint64
+
+	log   log.Modular
+	stats metrics.Type
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+}
+
+// NewPostgresCDC creates a new PostgresCDC input type.
+func NewPostgresCDC(conf PostgresCDCConfig, log log.Modular, stats metrics.Type) (*PostgresCDC, error) {
+	p := &PostgresCDC{
+		conf:       conf,
+		log:        log,
+		stats:      stats,
+		closedChan: make(chan struct{}),
+	}
+
+	if len(conf.Slot) == 0 {
+		return nil, errors.New("a replication slot must be specified")
+	}
+	if conf.Limit < 1 {
+		return nil, errors.New("limit must be greater than zero")
+	}
+
+	var err error
+	if p.decoder, err = newPGDecoder(conf.Plugin); err != nil {
+		return nil, err
+	}
+
+	switch conf.Plugin {
+	case "pgoutput":
+		if len(conf.Publications) == 0 {
+			return nil, errors.New("at least one publication must be specified for the pgoutput plugin")
+		}
+		p.query = fmt.Sprintf(
+			"SELECT lsn::text, xid::text, data FROM pg_logical_slot_peek_binary_changes($1, NULL, $2, 'proto_version', '1', 'publication_names', '%v')",
+			strings.Replace(strings.Join(conf.Publications, ","), "'", "''", -1),
+		)
+	case "wal2json":
+		p.query = "SELECT lsn::text, xid::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2, 'format-version', '2')"
+	}
+
+	if p.pollInterval, err = time.ParseDuration(conf.PollInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse poll interval string: %v", err)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func parsePGLSN(s string) (uint64, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("failed to parse LSN '%v': %v", s, err)
+	}
+	return uint64(hi)<<32 | uint64(lo), nil
+}
+
+func formatPGLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext establishes a connection to a PostgreSQL server, creating
+// the replication slot if it does not exist and create_slot is enabled.
+func (p *PostgresCDC) ConnectWithContext(ctx context.Context) error {
+	p.dbMut.Lock()
+	defer p.dbMut.Unlock()
+
+	if p.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open("postgres", p.conf.DSN)
+	if err != nil {
+		return err
+	}
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
+
+	if p.conf.CreateSlot {
+		var exists bool
+		if err = db.QueryRowContext(
+			ctx, "SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", p.conf.Slot,
+		).Scan(&exists); err != nil {
+			db.Close()
+			return fmt.Errorf("failed to check replication slot: %v", err)
+		}
+		if !exists {
+			if _, err = db.ExecContext(
+				ctx, "SELECT pg_create_logical_replication_slot($1, $2)", p.conf.Slot, p.conf.Plugin,
+			); err != nil {
+				db.Close()
+				return fmt.Errorf("failed to create replication slot: %v", err)
+			}
+			p.log.Infof("Created replication slot '%v' with plugin '%v'\n", p.conf.Slot, p.conf.Plugin)
+		}
+	}
+
+	p.log.Infof("Receiving row changes from PostgreSQL replication slot: %v\n", p.conf.Slot)
+
+	p.db = db
+	return nil
+}
+
+// confirm advances the replication slot to the highest LSN where all prior
+// row changes have been acknowledged.
+func (p *PostgresCDC) confirm(ctx context.Context) error {
+	p.ackMut.Lock()
+	lsn := p.ackedLSN
+	p.ackMut.Unlock()
+
+	if lsn <= p.confirmedLSN {
+		return nil
+	}
+	if _, err := p.db.ExecContext(
+		ctx, "SELECT pg_replication_slot_advance($1, $2::pg_lsn)", p.conf.Slot, formatPGLSN(lsn),
+	); err != nil {
+		return fmt.Errorf("failed to advance replication slot: %v", err)
+	}
+	p.confirmedLSN = lsn
+	return nil
+}
+
+// track adds a transaction to be tracked for acknowledgements.
+func (p *PostgresCDC) track(txn *pgCDCTxn) {
+	p.ackMut.Lock()
+	p.txns = append(p.txns, txn)
+	p.resolveLocked()
+	p.ackMut.Unlock()
+}
+
+// resolve marks a row change of a transaction as acknowledged.
+func (p *PostgresCDC) resolve(txn *pgCDCTxn) {
+	p.ackMut.Lock()
+	txn.pending--
+	p.resolveLocked()
+	p.ackMut.Unlock()
+}
+
+func (p *PostgresCDC) resolveLocked() {
+	for len(p.txns) > 0 && p.txns[0].pending <= 0 {
+		p.ackedLSN = p.txns[0].endLSN
+		p.txns[0] = nil
+		p.txns = p.txns[1:]
+	}
+}
+
+func (p *PostgresCDC) newPart(change pgChange, lsn, xid string) (types.Part, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"before": change.before,
+		"after":  change.after,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	switch change.action {
+	case 'I':
+		op = "insert"
+	case 'U':
+		op = "update"
+	case 'D':
+		op = "delete"
+	}
+
+	part := message.NewPart(body)
+	part.Metadata().
+		Set("postgres_schema", change.schema).
+		Set("postgres_table", change.table).
+		Set("postgres_operation", op).
+		Set("postgres_lsn", lsn).
+		Set("postgres_xid", xid)
+	return part, nil
+}
+
+// fetch peeks the next row changes from the replication slot. Since peeking
+// does not consume changes from the slot any transactions that have already
+// been read are skipped.
+func (p *PostgresCDC) fetch(ctx context.Context) error {
+	if err := p.confirm(ctx); err != nil {
+		return err
+	}
+
+	rows, err := p.db.QueryContext(ctx, p.query, p.conf.Slot, p.conf.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to read replication slot: %v", err)
+	}
+	defer rows.Close()
+
+	var txnParts []types.Part
+	for rows.Next() {
+		var lsnStr, xid string
+		var data []byte
+		if err = rows.Scan(&lsnStr, &xid, &data); err != nil {
+			return err
+		}
+
+		lsn, err := parsePGLSN(lsnStr)
+		if err != nil {
+			return err
+		}
+
+		change, err := p.decoder.decode(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode change at LSN %v: %v", lsnStr, err)
+		}
+
+		switch change.action {
+		case 'B':
+			txnParts = nil
+		case 'C':
+			if lsn > p.readLSN {
+				txn := &pgCDCTxn{endLSN: lsn, pending: len(txnParts)}
+				for _, part := range txnParts {
+					p.pending = append(p.pending, pgCDCPendingPart{part: part, txn: txn})
+				}
+				p.track(txn)
+				p.readLSN = lsn
+			}
+			txnParts = nil
+		case 'I', 'U', 'D':
+			part, err := p.newPart(change, lsnStr, xid)
+			if err != nil {
+				return err
+			}
+			txnParts = append(txnParts, part)
+		}
+	}
+	return rows.Err()
+}
+
+// ReadWithContext attempts to read a row change from the replication slot.
+func (p *PostgresCDC) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	p.dbMut.Lock()
+	if p.db == nil {
+		p.dbMut.Unlock()
+		return nil, nil, types.ErrNotConnected
+	}
+
+	if len(p.pending) == 0 {
+		if err := p.fetch(ctx); err != nil {
+			p.log.Errorf("Failed to read row changes: %v\n", err)
+			p.db.Close()
+			p.db = nil
+			p.dbMut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+	}
+
+	if len(p.pending) == 0 {
+		p.dbMut.Unlock()
+		select {
+		case <-time.After(p.pollInterval):
+		case <-ctx.Done():
+		}
+		return nil, nil, types.ErrTimeout
+	}
+
+	next := p.pending[0]
+	p.pending[0] = pgCDCPendingPart{}
+	p.pending = p.pending[1:]
+	p.dbMut.Unlock()
+
+	msg := message.New(nil)
+	msg.Append(next.part)
+	return msg, func(rctx context.Context, res types.Response) error {
+		if res.Error() == nil {
+			p.resolve(next.txn)
+		}
+		return nil
+	}, nil
+}
+
+// CloseAsync shuts down the PostgresCDC input and stops processing requests.
+func (p *PostgresCDC) CloseAsync() {
+	p.closeOnce.Do(func() {
+		go func() {
+			p.dbMut.Lock()
+			if p.db != nil {
+				ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+				if err := p.confirm(ctx); err != nil {
+					p.log.Errorf("Failed to confirm row changes: %v\n", err)
+				}
+				done()
+				p.db.Close()
+				p.db = nil
+			}
+			p.dbMut.Unlock()
+			close(p.closedChan)
+		}()
+	})
+}
+
+// WaitForClose blocks until the PostgresCDC input has closed down.
+func (p *PostgresCDC) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-p.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,305 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+//------------------------------------------------------------------------------
+
+// pgChange is a single decoded entry of a logical replication slot.
+type pgChange struct {
+	// action is one of 'B' (begin), 'C' (commit), 'I' (insert), 'U' (update)
+	// or 'D' (delete), and is zero for entries that are ignored.
+	action byte
+	schema string
+	table  string
+	before map[string]interface{}
+	after  map[string]interface{}
+}
+
+// pgDecoder decodes the entries of a logical replication slot as emitted by an
+// output plugin.
+type pgDecoder interface {
+	decode(data []byte) (pgChange, error)
+}
+
+func newPGDecoder(plugin string) (pgDecoder, error) {
+	switch plugin {
+	case "pgoutput":
+		return &pgoutputDecoder{relations: map[uint32]pgRelation{}}, nil
+	case "wal2json":
+		return wal2jsonDecoder{}, nil
+	}
+	return nil, fmt.Errorf("plugin not recognised: %v", plugin)
+}
+
+//------------------------------------------------------------------------------
+
+type pgColumn struct {
+	name    string
+	typeOID uint32
+}
+
+type pgRelation struct {
+	schema  string
+	table   string
+	columns []pgColumn
+}
+
+// pgoutputDecoder decodes version 1 of the pgoutput protocol as described in
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+type pgoutputDecoder struct {
+	relations map[uint32]pgRelation
+}
+
+var errPGOutputMalformed = errors.New("malformed pgoutput message")
+
+type pgoutputReader struct {
+	data []byte
+	err  error
+}
+
+func (r *pgoutputReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errPGOutputMalformed
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *pgoutputReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.err = errPGOutputMalformed
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+func (r *pgoutputReader) tuple(rel pgRelation) map[string]interface{} {
+	n := int(r.uint16())
+	if r.err == nil && n > len(rel.columns) {
+		r.err = fmt.Errorf("tuple has %v columns but relation %v.%v has %v", n, rel.schema, rel.table, len(rel.columns))
+	}
+	values := make(map[string]interface{}, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		col := rel.columns[i]
+		switch kind := r.byte(); kind {
+		case 'n':
+			values[col.name] = nil
+		case 'u':
+			// Unchanged TOAST values are not sent and are therefore omitted.
+		case 't':
+			size := r.uint32()
+			if b := r.next(int(size)); b != nil {
+				values[col.name] = pgTextValue(col.typeOID, string(b))
+			}
+		default:
+			if r.err == nil {
+				r.err = fmt.Errorf("tuple column kind not recognised: %c", kind)
+			}
+		}
+	}
+	return values
+}
+
+func (d *pgoutputDecoder) relation(r *pgoutputReader) (pgRelation, error) {
+	id := r.uint32()
+	if r.err != nil {
+		return pgRelation{}, r.err
+	}
+	rel, exists := d.relations[id]
+	if !exists {
+		return rel, fmt.Errorf("relation %v was not described before use", id)
+	}
+	return rel, nil
+}
+
+func (d *pgoutputDecoder) decode(data []byte) (pgChange, error) {
+	var change pgChange
+	if len(data) == 0 {
+		return change, errPGOutputMalformed
+	}
+
+	r := &pgoutputReader{data: data[1:]}
+	switch data[0] {
+	case 'B', 'C':
+		change.action = data[0]
+	case 'R':
+		id := r.uint32()
+		rel := pgRelation{
+			schema: r.string(),
+			table:  r.string(),
+		}
+		r.byte() // Replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			r.byte() // Flags
+			col := pgColumn{name: r.string(), typeOID: r.uint32()}
+			r.uint32() // Type modifier
+			rel.columns = append(rel.columns, col)
+		}
+		if r.err == nil {
+			d.relations[id] = rel
+		}
+	case 'I':
+		rel, err := d.relation(r)
+		if err != nil {
+			return change, err
+		}
+		if kind := r.byte(); r.err == nil && kind != 'N' {
+			return change, fmt.Errorf("unexpected insert tuple kind: %c", kind)
+		}
+		change.action = 'I'
+		change.schema, change.table = rel.schema, rel.table
+		change.after = r.tuple(rel)
+	case 'U':
+		rel, err := d.relation(r)
+		if err != nil {
+			return change, err
+		}
+		change.action = 'U'
+		change.schema, change.table = rel.schema, rel.table
+		kind := r.byte()
+		if kind == 'K' || kind == 'O' {
+			change.before = r.tuple(rel)
+			kind = r.byte()
+		}
+		if r.err == nil && kind != 'N' {
+			return change, fmt.Errorf("unexpected update tuple kind: %c", kind)
+		}
+		change.after = r.tuple(rel)
+	case 'D':
+		rel, err := d.relation(r)
+		if err != nil {
+			return change, err
+		}
+		if kind := r.byte(); r.err == nil && kind != 'K' && kind != 'O' {
+			return change, fmt.Errorf("unexpected delete tuple kind: %c", kind)
+		}
+		change.action = 'D'
+		change.schema, change.table = rel.schema, rel.table
+		change.before = r.tuple(rel)
+	}
+	return change, r.err
+}
+
+// pgTextValue converts the text representation of a column value into a type
+// that best represents it within a JSON document.
+func pgTextValue(typeOID uint32, text string) interface{} {
+	switch typeOID {
+	case 16: // bool
+		return text == "t"
+	case 20, 21, 23, 26: // int8, int2, int4, oid
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i
+		}
+	case 700, 701, 1700: // float4, float8, numeric
+		if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return json.Number(text)
+		}
+	case 114, 3802: // json, jsonb
+		if json.Valid([]byte(text)) {
+			return json.RawMessage(text)
+		}
+	}
+	return text
+}
+
+//------------------------------------------------------------------------------
+
+// wal2jsonDecoder decodes entries emitted by wal2json with the option
+// format-version set to 2.
+type wal2jsonDecoder struct{}
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type wal2jsonChange struct {
+	Action   string           `json:"action"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+func wal2jsonValues(cols []wal2jsonColumn) map[string]interface{} {
+	values := make(map[string]interface{}, len(cols))
+	for _, c := range cols {
+		values[c.Name] = c.Value
+	}
+	return values
+}
+
+func (wal2jsonDecoder) decode(data []byte) (pgChange, error) {
+	var change pgChange
+
+	var w wal2jsonChange
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&w); err != nil {
+		return change, fmt.Errorf("failed to parse wal2json entry: %v", err)
+	}
+
+	switch w.Action {
+	case "B", "C":
+		change.action = w.Action[0]
+	case "I":
+		change.action = 'I'
+		change.after = wal2jsonValues(w.Columns)
+	case "U":
+		change.action = 'U'
+		if len(w.Identity) > 0 {
+			change.before = wal2jsonValues(w.Identity)
+		}
+		change.after = wal2jsonValues(w.Columns)
+	case "D":
+		change.action = 'D'
+		change.before = wal2jsonValues(w.Identity)
+	default:
+		return change, nil
+	}
+	change.schema, change.table = w.Schema, w.Table
+	return change, nil
+}
+
+//------------------------------------------------------------------------------
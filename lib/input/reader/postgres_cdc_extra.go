@@ -0,0 +1,9 @@
+// +build !wasm
+
+package reader
+
+// Import extra drivers that aren't supported by WASM builds.
+import (
+	// SQL Drivers
+	_ "github.com/lib/pq"
+)
@@ -0,0 +1,213 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pgoutputBuilder struct {
+	bytes.Buffer
+}
+
+func (b *pgoutputBuilder) uint16(v uint16) *pgoutputBuilder {
+	binary.Write(b, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgoutputBuilder) uint32(v uint32) *pgoutputBuilder {
+	binary.Write(b, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgoutputBuilder) str(s string) *pgoutputBuilder {
+	b.WriteString(s)
+	b.WriteByte(0)
+	return b
+}
+
+func (b *pgoutputBuilder) text(s string) *pgoutputBuilder {
+	b.WriteByte('t')
+	b.uint32(uint32(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+func TestPostgresCDCPGOutputDecode(t *testing.T) {
+	d, err := newPGDecoder("pgoutput")
+	require.NoError(t, err)
+
+	rel := &pgoutputBuilder{}
+	rel.WriteByte('R')
+	rel.uint32(16385).str("public").str("foo")
+	rel.WriteByte('d')
+	rel.uint16(5)
+	rel.WriteByte(1)
+	rel.str("id").uint32(23).uint32(0xFFFFFFFF)
+	rel.WriteByte(0)
+	rel.str("name").uint32(25).uint32(0xFFFFFFFF)
+	rel.WriteByte(0)
+	rel.str("active").uint32(16).uint32(0xFFFFFFFF)
+	rel.WriteByte(0)
+	rel.str("doc").uint32(3802).uint32(0xFFFFFFFF)
+	rel.WriteByte(0)
+	rel.str("big").uint32(25).uint32(0xFFFFFFFF)
+
+	change, err := d.decode(rel.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), change.action)
+
+	ins := &pgoutputBuilder{}
+	ins.WriteByte('I')
+	ins.uint32(16385)
+	ins.WriteByte('N')
+	ins.uint16(5).text("1").text("bar").text("t").text(`{"a":[1,2]}`)
+	ins.WriteByte('n')
+
+	change, err = d.decode(ins.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, byte('I'), change.action)
+	assert.Equal(t, "public", change.schema)
+	assert.Equal(t, "foo", change.table)
+	assert.Nil(t, change.before)
+
+	after, err := json.Marshal(change.after)
+	require.NoError(t, err)
+	assert.Equal(t, `{"active":true,"big":null,"doc":{"a":[1,2]},"id":1,"name":"bar"}`, string(after))
+
+	upd := &pgoutputBuilder{}
+	upd.WriteByte('U')
+	upd.uint32(16385)
+	upd.WriteByte('K')
+	upd.uint16(1).text("1")
+	upd.WriteByte('N')
+	upd.uint16(5).text("2").text("baz").text("f").WriteByte('n')
+	upd.WriteByte('u')
+
+	change, err = d.decode(upd.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, byte('U'), change.action)
+	assert.Equal(t, map[string]interface{}{"id": int64(1)}, change.before)
+	assert.Equal(t, map[string]interface{}{
+		"id":     int64(2),
+		"name":   "baz",
+		"active": false,
+		"doc":    nil,
+	}, change.after)
+
+	del := &pgoutputBuilder{}
+	del.WriteByte('D')
+	del.uint32(16385)
+	del.WriteByte('K')
+	del.uint16(1).text("2")
+
+	change, err = d.decode(del.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, byte('D'), change.action)
+	assert.Equal(t, map[string]interface{}{"id": int64(2)}, change.before)
+	assert.Nil(t, change.after)
+
+	change, err = d.decode([]byte{'B', 0, 0})
+	require.NoError(t, err)
+	assert.Equal(t, byte('B'), change.action)
+
+	unknown := &pgoutputBuilder{}
+	unknown.WriteByte('I')
+	unknown.uint32(1)
+	unknown.WriteByte('N')
+	_, err = d.decode(unknown.Bytes())
+	assert.EqualError(t, err, "relation 1 was not described before use")
+
+	_, err = d.decode(ins.Bytes()[:len(ins.Bytes())-5])
+	assert.Equal(t, errPGOutputMalformed, err)
+}
+
+func TestPostgresCDCWal2JSONDecode(t *testing.T) {
+	d, err := newPGDecoder("wal2json")
+	require.NoError(t, err)
+
+	change, err := d.decode([]byte(`{"action":"B"}`))
+	require.NoError(t, err)
+	assert.Equal(t, byte('B'), change.action)
+
+	change, err = d.decode([]byte(`{"action":"U","schema":"public","table":"foo","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"bar"}],"identity":[{"name":"id","type":"integer","value":1}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, byte('U'), change.action)
+	assert.Equal(t, "public", change.schema)
+	assert.Equal(t, "foo", change.table)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, change.before)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1"), "name": "bar"}, change.after)
+
+	change, err = d.decode([]byte(`{"action":"D","schema":"public","table":"foo","identity":[{"name":"id","type":"integer","value":1}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, byte('D'), change.action)
+	assert.Nil(t, change.after)
+
+	change, err = d.decode([]byte(`{"action":"M","transactional":false,"prefix":"foo","content":"bar"}`))
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), change.action)
+
+	_, err = d.decode([]byte(`nope`))
+	assert.Error(t, err)
+}
+
+func TestPostgresCDCLSN(t *testing.T) {
+	lsn, err := parsePGLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", formatPGLSN(lsn))
+
+	_, err = parsePGLSN("nope")
+	assert.Error(t, err)
+}
+
+func TestPostgresCDCConfigErrors(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	conf.Plugin = "nope"
+	_, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "plugin not recognised: nope")
+
+	conf = NewPostgresCDCConfig()
+	conf.Publications = nil
+	_, err = NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewPostgresCDCConfig()
+	conf.Publications = []string{"foo", "bar's"}
+	p, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Contains(t, p.query, `'publication_names', 'foo,bar''s'`)
+}
+
+func TestPostgresCDCResolve(t *testing.T) {
+	p, err := NewPostgresCDC(NewPostgresCDCConfig(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	txnA := &pgCDCTxn{endLSN: 10, pending: 2}
+	txnB := &pgCDCTxn{endLSN: 20, pending: 0}
+	txnC := &pgCDCTxn{endLSN: 30, pending: 1}
+
+	p.track(txnA)
+	p.track(txnB)
+	p.track(txnC)
+	assert.Equal(t, uint64(0), p.ackedLSN)
+
+	p.resolve(txnC)
+	assert.Equal(t, uint64(0), p.ackedLSN)
+
+	p.resolve(txnA)
+	assert.Equal(t, uint64(0), p.ackedLSN)
+
+	p.resolve(txnA)
+	assert.Equal(t, uint64(30), p.ackedLSN)
+	assert.Empty(t, p.txns)
+
+	p.track(&pgCDCTxn{endLSN: 40})
+	assert.Equal(t, uint64(40), p.ackedLSN)
+}
@@ -0,0 +1,446 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLSelectConfig contains configuration fields for the SQLSelect input type.
+type SQLSelectConfig struct {
+	Driver           string   `json:"driver" yaml:"driver"`
+	DSN              string   `json:"dsn" yaml:"dsn"`
+	Table            string   `json:"table" yaml:"table"`
+	Columns          []string `json:"columns" yaml:"columns"`
+	Where            string   `json:"where" yaml:"where"`
+	CheckpointColumn string   `json:"checkpoint_column" yaml:"checkpoint_column"`
+	CheckpointStart  string   `json:"checkpoint_start" yaml:"checkpoint_start"`
+	Cache            string   `json:"cache" yaml:"cache"`
+	CacheKey         string   `json:"cache_key" yaml:"cache_key"`
+	Limit            int      `json:"limit" yaml:"limit"`
+	PollInterval     string   `json:"poll_interval" yaml:"poll_interval"`
+	MaxBatchCount    int      `json:"max_batch_count" yaml:"max_batch_count"`
+}
+
+// NewSQLSelectConfig creates a new SQLSelectConfig with default values.
+func NewSQLSelectConfig() SQLSelectConfig {
+	return SQLSelectConfig{
+		Driver:           "mysql",
+		DSN:              "",
+		Table:            "",
+		Columns:          []string{"*"},
+		Where:            "",
+		CheckpointColumn: "",
+		CheckpointStart:  "",
+		Cache:            "",
+		CacheKey:         "sql_select_checkpoint",
+		Limit:            1000,
+		PollInterval:     "5s",
+		MaxBatchCount:    1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// sqlSelectRow is a selected row along with its checkpoint value, and the
+// checkpoint that can be stored once the row has been acknowledged, which only
+// advances once every row sharing a checkpoint value has been selected.
+type sqlSelectRow struct {
+	part       types.Part
+	checkpoint string
+	commit     string
+}
+
+// sqlSelectPending tracks whether the rows of a message have been
+// acknowledged, the stored checkpoint is only advanced beyond rows that have
+// been acknowledged along with all rows that precede them.
+type sqlSelectPending struct {
+	checkpoint string
+	acked      bool
+}
+
+// SQLSelect is an input type that periodically selects rows of a table with a
+// checkpoint column greater than the last row consumed.
+type SQLSelect struct {
+	conf         SQLSelectConfig
+	cache        types.Cache
+	pollInterval time.Duration
+
+	dbMut       sync.Mutex
+	db          *sql.DB
+	started     bool
+	readPoint   string
+	readSkip    int
+	commitPoint string
+	buffered    []sqlSelectRow
+	exhausted   bool
+	lastPoll    time.Time
+
+	ackMut      sync.Mutex
+	pending     []*sqlSelectPending
+	storedPoint string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mPolls metrics.StatCounter
+}
+
+// NewSQLSelect creates a new SQLSelect input type, where the checkpoint is
+// stored within a cache.
+func NewSQLSelect(conf SQLSelectConfig, cache types.Cache, log log.Modular, stats metrics.Type) (*SQLSelect, error) {
+	s := &SQLSelect{
+		conf:   conf,
+		cache:  cache,
+		log:    log,
+		stats:  stats,
+		mPolls: stats.GetCounter("polls"),
+	}
+
+	switch conf.Driver {
+	case "mysql", "postgres", "sqlite3":
+	default:
+		return nil, fmt.Errorf("driver not supported: %v", conf.Driver)
+	}
+	if len(conf.Table) == 0 {
+		return nil, errors.New("a table must be specified")
+	}
+	if len(conf.Columns) == 0 {
+		return nil, errors.New("at least one column must be specified")
+	}
+	if len(conf.CheckpointColumn) == 0 {
+		return nil, errors.New("a checkpoint column must be specified")
+	}
+	if len(conf.CacheKey) == 0 {
+		return nil, errors.New("a cache key must be specified")
+	}
+	if conf.Limit < 1 {
+		return nil, fmt.Errorf("limit '%v' must be > 0", conf.Limit)
+	}
+	if conf.MaxBatchCount < 1 {
+		return nil, fmt.Errorf("max_batch_count '%v' must be > 0", conf.MaxBatchCount)
+	}
+
+	var err error
+	if s.pollInterval, err = time.ParseDuration(conf.PollInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse poll interval string: %v", err)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// query returns the select statement, with the checkpoint condition only
+// included when there is a checkpoint to compare against. When rows sharing the
+// checkpoint value have already been selected the statement includes the
+// checkpoint value and skips those rows instead.
+func (s *SQLSelect) query(withCheckpoint bool) string {
+	var conds []string
+	if withCheckpoint {
+		arg := "?"
+		if s.conf.Driver == "postgres" {
+			arg = "$1"
+		}
+		op := " > "
+		if s.readSkip > 0 {
+			op = " >= "
+		}
+		conds = append(conds, s.conf.CheckpointColumn+op+arg)
+	}
+	if len(s.conf.Where) > 0 {
+		conds = append(conds, "("+s.conf.Where+")")
+	}
+
+	cols := strings.Join(s.conf.Columns, ", ")
+	if cols != "*" {
+		// The checkpoint column is always selected in order to track it.
+		cols += ", " + s.conf.CheckpointColumn + " AS benthos_checkpoint"
+	}
+
+	q := "SELECT " + cols + " FROM " + s.conf.Table
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	q += fmt.Sprintf(" ORDER BY %v ASC LIMIT %v", s.conf.CheckpointColumn, s.conf.Limit)
+	if withCheckpoint && s.readSkip > 0 {
+		q += fmt.Sprintf(" OFFSET %v", s.readSkip)
+	}
+	return q
+}
+
+// checkpointString converts the value of a checkpoint column into a string
+// that can be compared against the column in a subsequent query.
+func (s *SQLSelect) checkpointString(v interface{}) string {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case time.Time:
+		if s.conf.Driver == "mysql" {
+			return t.Format("2006-01-02 15:04:05.999999")
+		}
+		return t.Format(time.RFC3339Nano)
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// poll selects the next rows after the read checkpoint.
+func (s *SQLSelect) poll(ctx context.Context, db *sql.DB) error {
+	s.mPolls.Incr(1)
+	s.lastPoll = time.Now()
+
+	var args []interface{}
+	if len(s.readPoint) > 0 {
+		args = append(args, s.readPoint)
+	}
+
+	rows, err := db.QueryContext(ctx, s.query(len(args) > 0), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	checkpointIndex := -1
+	for i, name := range columnNames {
+		if name == "benthos_checkpoint" {
+			checkpointIndex = i
+			break
+		}
+		if strings.EqualFold(name, s.conf.CheckpointColumn) {
+			checkpointIndex = i
+		}
+	}
+	if checkpointIndex < 0 {
+		return fmt.Errorf("checkpoint column %v not found within results", s.conf.CheckpointColumn)
+	}
+
+	var results []sqlSelectRow
+	for rows.Next() {
+		values := make([]interface{}, len(columnNames))
+		valuesWrapped := make([]interface{}, len(columnNames))
+		for i := range values {
+			valuesWrapped[i] = &values[i]
+		}
+		if err = rows.Scan(valuesWrapped...); err != nil {
+			return err
+		}
+
+		checkpoint := s.checkpointString(values[checkpointIndex])
+		if len(checkpoint) == 0 {
+			return fmt.Errorf("checkpoint column %v contains a null value", s.conf.CheckpointColumn)
+		}
+
+		jObj := map[string]interface{}{}
+		for i, v := range values {
+			if columnNames[i] == "benthos_checkpoint" {
+				continue
+			}
+			if b, ok := v.([]byte); ok {
+				jObj[columnNames[i]] = string(b)
+			} else {
+				jObj[columnNames[i]] = v
+			}
+		}
+
+		part := message.NewPart(nil)
+		if err = part.SetJSON(jObj); err != nil {
+			return err
+		}
+		results = append(results, sqlSelectRow{part: part, checkpoint: checkpoint})
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	tied := 0
+	s.exhausted = len(results) < s.conf.Limit
+	if !s.exhausted {
+		// Rows that share the checkpoint of the last row might be split across
+		// pages, and are therefore selected again with the next page.
+		last := results[len(results)-1].checkpoint
+		trimmed := results
+		for len(trimmed) > 0 && trimmed[len(trimmed)-1].checkpoint == last {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if len(trimmed) == 0 {
+			// Every row of the page shares the checkpoint, and so the next page
+			// skips the rows of the checkpoint that have been selected.
+			if last == s.readPoint {
+				tied = s.readSkip
+			}
+			tied += len(results)
+		} else {
+			results = trimmed
+		}
+	}
+
+	commit := s.commitPoint
+	for i := range results {
+		if tied == 0 && (i == len(results)-1 || results[i+1].checkpoint != results[i].checkpoint) {
+			commit = results[i].checkpoint
+		}
+		results[i].commit = commit
+	}
+	s.commitPoint = commit
+	if len(results) > 0 {
+		s.readPoint = results[len(results)-1].checkpoint
+		s.readSkip = tied
+	}
+	s.buffered = append(s.buffered, results...)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *SQLSelect) resolve(p *sqlSelectPending) error {
+	s.ackMut.Lock()
+	defer s.ackMut.Unlock()
+
+	p.acked = true
+
+	point := s.storedPoint
+	for len(s.pending) > 0 && s.pending[0].acked {
+		point = s.pending[0].checkpoint
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+	}
+	if point == s.storedPoint {
+		return nil
+	}
+	if err := s.cache.Set(s.conf.CacheKey, []byte(point)); err != nil {
+		return fmt.Errorf("failed to store checkpoint: %v", err)
+	}
+	s.storedPoint = point
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext attempts to establish a connection to the target
+// database.
+func (s *SQLSelect) ConnectWithContext(ctx context.Context) error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open(s.conf.Driver, s.conf.DSN)
+	if err != nil {
+		return err
+	}
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
+
+	if !s.started {
+		point, err := s.cache.Get(s.conf.CacheKey)
+		if err == nil {
+			s.readPoint = string(point)
+		} else if err == types.ErrKeyNotFound {
+			s.readPoint = s.conf.CheckpointStart
+		} else {
+			db.Close()
+			return fmt.Errorf("failed to obtain stored checkpoint: %v", err)
+		}
+		s.storedPoint = s.readPoint
+		s.commitPoint = s.readPoint
+		s.started = true
+	}
+
+	s.log.Infof("Selecting rows of table %v from checkpoint '%v'\n", s.conf.Table, s.readPoint)
+	s.db = db
+	return nil
+}
+
+// ReadWithContext attempts to read a batch of rows, polling the table when all
+// previously selected rows have been read.
+func (s *SQLSelect) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	s.dbMut.Lock()
+	db := s.db
+	s.dbMut.Unlock()
+
+	if db == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	if len(s.buffered) == 0 {
+		if s.exhausted {
+			select {
+			case <-time.After(time.Until(s.lastPoll.Add(s.pollInterval))):
+			case <-ctx.Done():
+				return nil, nil, types.ErrTimeout
+			}
+		}
+		if err := s.poll(ctx, db); err != nil {
+			s.log.Errorf("Failed to select rows: %v\n", err)
+			s.dbMut.Lock()
+			if s.db == db {
+				s.db.Close()
+				s.db = nil
+			}
+			s.dbMut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		if len(s.buffered) == 0 {
+			return nil, nil, types.ErrTimeout
+		}
+	}
+
+	n := s.conf.MaxBatchCount
+	if n > len(s.buffered) {
+		n = len(s.buffered)
+	}
+	msg := message.New(nil)
+	for _, row := range s.buffered[:n] {
+		msg.Append(row.part)
+	}
+	p := &sqlSelectPending{checkpoint: s.buffered[n-1].commit}
+	s.buffered = s.buffered[n:]
+
+	s.ackMut.Lock()
+	s.pending = append(s.pending, p)
+	s.ackMut.Unlock()
+
+	return msg, func(rctx context.Context, res types.Response) error {
+		if res.Error() != nil {
+			return nil
+		}
+		return s.resolve(p)
+	}, nil
+}
+
+// CloseAsync shuts down the SQLSelect input and stops processing requests.
+func (s *SQLSelect) CloseAsync() {
+	s.dbMut.Lock()
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+	s.dbMut.Unlock()
+}
+
+// WaitForClose blocks until the SQLSelect input has closed down.
+func (s *SQLSelect) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
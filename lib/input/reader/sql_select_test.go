@@ -0,0 +1,253 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLSelectQuery(t *testing.T) {
+	conf := NewSQLSelectConfig()
+	conf.Driver = "postgres"
+	conf.Table = "foo"
+	conf.CheckpointColumn = "updated_at"
+	conf.Limit = 10
+
+	s, err := NewSQLSelect(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM foo ORDER BY updated_at ASC LIMIT 10", s.query(false))
+	assert.Equal(t, "SELECT * FROM foo WHERE updated_at > $1 ORDER BY updated_at ASC LIMIT 10", s.query(true))
+
+	s.readSkip = 10
+	assert.Equal(t, "SELECT * FROM foo WHERE updated_at >= $1 ORDER BY updated_at ASC LIMIT 10 OFFSET 10", s.query(true))
+
+	conf.Driver = "mysql"
+	conf.Columns = []string{"id", "name"}
+	conf.Where = "active = true OR id = 0"
+
+	s, err = NewSQLSelect(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t,
+		"SELECT id, name, updated_at AS benthos_checkpoint FROM foo WHERE updated_at > ? AND (active = true OR id = 0) ORDER BY updated_at ASC LIMIT 10",
+		s.query(true),
+	)
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	assert.Equal(t, "2020-01-02 03:04:05.6", s.checkpointString(ts))
+	assert.Equal(t, "12", s.checkpointString(int64(12)))
+	assert.Equal(t, "foo", s.checkpointString([]byte("foo")))
+	assert.Equal(t, "", s.checkpointString(nil))
+}
+
+func TestSQLSelectConfigErrors(t *testing.T) {
+	tests := map[string]func(c *SQLSelectConfig){
+		"bad driver":        func(c *SQLSelectConfig) { c.Driver = "nope" },
+		"no table":          func(c *SQLSelectConfig) { c.Table = "" },
+		"no columns":        func(c *SQLSelectConfig) { c.Columns = nil },
+		"no checkpoint":     func(c *SQLSelectConfig) { c.CheckpointColumn = "" },
+		"no cache key":      func(c *SQLSelectConfig) { c.CacheKey = "" },
+		"bad limit":         func(c *SQLSelectConfig) { c.Limit = 0 },
+		"bad batch count":   func(c *SQLSelectConfig) { c.MaxBatchCount = 0 },
+		"bad poll interval": func(c *SQLSelectConfig) { c.PollInterval = "nope" },
+	}
+
+	for name, fn := range tests {
+		conf := NewSQLSelectConfig()
+		conf.Table = "foo"
+		conf.CheckpointColumn = "id"
+		fn(&conf)
+		_, err := NewSQLSelect(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func readSQLSelect(t *testing.T, s *SQLSelect) (types.Message, AsyncAckFn) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	for {
+		msg, ackFn, err := s.ReadWithContext(ctx)
+		if err == types.ErrTimeout && ctx.Err() == nil {
+			continue
+		}
+		require.NoError(t, err)
+		return msg, ackFn
+	}
+}
+
+func TestSQLSelectSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_sql_select_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dsn := "file:" + filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, stmt := range []string{
+		`CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, version INTEGER)`,
+		`INSERT INTO foo (id, name, version) VALUES (1, 'a', 1), (2, 'b', 2), (3, 'c', 2), (4, 'd', 3), (5, 'e', 4)`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	mCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := NewSQLSelectConfig()
+	conf.Driver = "sqlite3"
+	conf.DSN = dsn
+	conf.Table = "foo"
+	conf.Columns = []string{"id", "name"}
+	conf.CheckpointColumn = "version"
+	conf.Limit = 3
+	conf.MaxBatchCount = 2
+	conf.PollInterval = "10ms"
+
+	s, err := NewSQLSelect(conf, mCache, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, s.ConnectWithContext(context.Background()))
+
+	// The first page ends within the rows of version 2, which are therefore
+	// selected again with the next page.
+	msg, firstAck := readSQLSelect(t, s)
+	require.Equal(t, 1, msg.Len())
+	assert.Equal(t, `{"id":1,"name":"a"}`, string(msg.Get(0).Get()))
+
+	msg, secondAck := readSQLSelect(t, s)
+	require.Equal(t, 2, msg.Len())
+	assert.Equal(t, `{"id":2,"name":"b"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, `{"id":3,"name":"c"}`, string(msg.Get(1).Get()))
+
+	msg, thirdAck := readSQLSelect(t, s)
+	require.Equal(t, 2, msg.Len())
+	assert.Equal(t, `{"id":4,"name":"d"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, `{"id":5,"name":"e"}`, string(msg.Get(1).Get()))
+
+	require.NoError(t, secondAck(context.Background(), response.NewAck()))
+	_, err = mCache.Get(conf.CacheKey)
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, firstAck(context.Background(), response.NewAck()))
+	checkpoint, err := mCache.Get(conf.CacheKey)
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(checkpoint))
+
+	require.NoError(t, thirdAck(context.Background(), response.NewError(types.ErrTimeout)))
+	checkpoint, err = mCache.Get(conf.CacheKey)
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(checkpoint))
+
+	s.CloseAsync()
+	require.NoError(t, s.WaitForClose(time.Second))
+
+	// A new input resumes from the stored checkpoint.
+	s, err = NewSQLSelect(conf, mCache, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, s.ConnectWithContext(context.Background()))
+	defer func() {
+		s.CloseAsync()
+		assert.NoError(t, s.WaitForClose(time.Second))
+	}()
+
+	msg, ackFn := readSQLSelect(t, s)
+	require.Equal(t, 2, msg.Len())
+	assert.Equal(t, `{"id":4,"name":"d"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, `{"id":5,"name":"e"}`, string(msg.Get(1).Get()))
+	require.NoError(t, ackFn(context.Background(), response.NewAck()))
+
+	_, err = db.Exec(`INSERT INTO foo (id, name, version) VALUES (6, 'f', 5)`)
+	require.NoError(t, err)
+
+	msg, ackFn = readSQLSelect(t, s)
+	require.Equal(t, 1, msg.Len())
+	assert.Equal(t, `{"id":6,"name":"f"}`, string(msg.Get(0).Get()))
+	require.NoError(t, ackFn(context.Background(), response.NewAck()))
+
+	checkpoint, err = mCache.Get(conf.CacheKey)
+	require.NoError(t, err)
+	assert.Equal(t, "5", string(checkpoint))
+}
+
+func TestSQLSelectTiedPage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_sql_select_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dsn := "file:" + filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, stmt := range []string{
+		`CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, version INTEGER)`,
+		`INSERT INTO foo (id, name, version) VALUES (1, 'a', 1), (2, 'b', 1), (3, 'c', 1), (4, 'd', 1), (5, 'e', 1), (6, 'f', 2)`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	mCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := NewSQLSelectConfig()
+	conf.Driver = "sqlite3"
+	conf.DSN = dsn
+	conf.Table = "foo"
+	conf.Columns = []string{"id", "name"}
+	conf.CheckpointColumn = "version"
+	conf.Limit = 2
+	conf.PollInterval = "10ms"
+
+	s, err := NewSQLSelect(conf, mCache, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, s.ConnectWithContext(context.Background()))
+	defer func() {
+		s.CloseAsync()
+		assert.NoError(t, s.WaitForClose(time.Second))
+	}()
+
+	// Pages where every row shares a checkpoint are selected by skipping the
+	// rows of the checkpoint already selected, and the checkpoint is only
+	// stored once all of its rows have been acknowledged.
+	for _, exp := range []struct {
+		row        string
+		checkpoint string
+	}{
+		{row: `{"id":1,"name":"a"}`},
+		{row: `{"id":2,"name":"b"}`},
+		{row: `{"id":3,"name":"c"}`},
+		{row: `{"id":4,"name":"d"}`},
+		{row: `{"id":5,"name":"e"}`, checkpoint: "1"},
+		{row: `{"id":6,"name":"f"}`, checkpoint: "2"},
+	} {
+		msg, ackFn := readSQLSelect(t, s)
+		require.Equal(t, 1, msg.Len())
+		assert.Equal(t, exp.row, string(msg.Get(0).Get()))
+		require.NoError(t, ackFn(context.Background(), response.NewAck()))
+
+		checkpoint, err := mCache.Get(conf.CacheKey)
+		if exp.checkpoint == "" {
+			assert.Equal(t, types.ErrKeyNotFound, err)
+		} else {
+			require.NoError(t, err)
+			assert.Equal(t, exp.checkpoint, string(checkpoint))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
+)
+
+//------------------------------------------------------------------------------
+
+type streamMsg struct {
+	msg   types.Message
+	ackFn codec.ReaderAckFn
+}
+
+// Stream is a reader implementation that continuously reads messages from an
+// io.ReadCloser with a codec.
+type Stream struct {
+	handleCtor func(ctx context.Context) (io.ReadCloser, error)
+	onClose    func(ctx context.Context)
+	codecCtor  codec.ReaderConstructor
+
+	mut        sync.Mutex
+	handle     codec.Reader
+	shutdownFn func()
+	errChan    chan error
+	msgChan    chan streamMsg
+}
+
+// NewStream creates a new reader input type able to create a feed of messages
+// from an io.ReadCloser decoded with a codec.
+//
+// Callers must provide a constructor function for the target io.ReadCloser,
+// which is called on start up and again each time a reader is exhausted. If the
+// constructor is called but there is no more content to create a reader for
+// then the error `io.EOF` should be returned and the Stream will close.
+//
+// Callers must also provide an onClose function, which will be called if the
+// Stream has been instructed to shut down. This function should unblock any
+// blocked Read calls.
+func NewStream(
+	handleCtor func(ctx context.Context) (io.ReadCloser, error),
+	onClose func(ctx context.Context),
+	codecCtor codec.ReaderConstructor,
+) *Stream {
+	return &Stream{
+		handleCtor: handleCtor,
+		onClose:    onClose,
+		codecCtor:  codecCtor,
+		shutdownFn: func() {},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Stream) closeHandle() {
+	if r.handle != nil {
+		r.handle.Close(context.Background())
+		r.handle = nil
+	}
+	r.shutdownFn()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a new codec reader for an io.ReadCloser.
+func (r *Stream) Connect() error {
+	return r.ConnectWithContext(context.Background())
+}
+
+// ConnectWithContext attempts to establish a new codec reader for an
+// io.ReadCloser.
+func (r *Stream) ConnectWithContext(ctx context.Context) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.closeHandle()
+
+	rawHandle, err := r.handleCtor(ctx)
+	if err != nil {
+		if err == io.EOF {
+			return types.ErrTypeClosed
+		}
+		return err
+	}
+
+	handle, err := r.codecCtor(rawHandle, func(context.Context, error) error {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	readerCtx, shutdownFn := context.WithCancel(context.Background())
+	msgChan := make(chan streamMsg)
+	errChan := make(chan error)
+
+	go func() {
+		defer func() {
+			shutdownFn()
+			close(errChan)
+			close(msgChan)
+		}()
+
+		for {
+			parts, ackFn, err := handle.Next(readerCtx)
+			if err != nil {
+				if err == io.EOF {
+					return
+				}
+				select {
+				case errChan <- err:
+				case <-readerCtx.Done():
+				}
+				return
+			}
+
+			msg := message.New(nil)
+			msg.Append(parts...)
+			select {
+			case msgChan <- streamMsg{msg: msg, ackFn: ackFn}:
+			case <-readerCtx.Done():
+				return
+			}
+		}
+	}()
+
+	r.handle = handle
+	r.msgChan = msgChan
+	r.errChan = errChan
+	r.shutdownFn = shutdownFn
+	return nil
+}
+
+// ReadWithContext attempts to read a new message from the io.ReadCloser.
+func (r *Stream) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	r.mut.Lock()
+	msgChan := r.msgChan
+	errChan := r.errChan
+	r.mut.Unlock()
+
+	select {
+	case m, open := <-msgChan:
+		if !open {
+			return nil, nil, types.ErrNotConnected
+		}
+		return m.msg, func(rctx context.Context, res types.Response) error {
+			return m.ackFn(rctx, res.Error())
+		}, nil
+	case err, open := <-errChan:
+		if !open {
+			return nil, nil, types.ErrNotConnected
+		}
+		return nil, nil, err
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// Read attempts to read a new message from the io.ReadCloser.
+func (r *Stream) Read() (types.Message, error) {
+	msg, _, err := r.ReadWithContext(context.Background())
+	return msg, err
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not.
+func (r *Stream) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the reader input and stops processing requests.
+func (r *Stream) CloseAsync() {
+	go func() {
+		r.mut.Lock()
+		r.onClose(context.Background())
+		r.closeHandle()
+		r.mut.Unlock()
+	}()
+}
+
+// WaitForClose blocks until the reader input has closed down.
+func (r *Stream) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,91 @@
+package reader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamCodec(t *testing.T) {
+	handles := []string{
+		"a,b\n1,2\n3,4\n",
+		"a,b\n5,6\n",
+	}
+
+	ctor, err := codec.GetReader("csv", codec.NewReaderConfig())
+	require.NoError(t, err)
+
+	r := NewStream(
+		func(ctx context.Context) (io.ReadCloser, error) {
+			if len(handles) == 0 {
+				return nil, io.EOF
+			}
+			h := handles[0]
+			handles = handles[1:]
+			return ioutil.NopCloser(bytes.NewReader([]byte(h))), nil
+		},
+		func(ctx context.Context) {},
+		ctor,
+	)
+	defer func() {
+		r.CloseAsync()
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	var results []string
+	for {
+		err := r.ConnectWithContext(context.Background())
+		if err == types.ErrTypeClosed {
+			break
+		}
+		require.NoError(t, err)
+
+		for {
+			msg, ackFn, err := r.ReadWithContext(context.Background())
+			if err == types.ErrNotConnected {
+				break
+			}
+			require.NoError(t, err)
+			results = append(results, string(message.GetAllBytes(msg)[0]))
+			require.NoError(t, ackFn(context.Background(), response.NewAck()))
+		}
+	}
+
+	assert.Equal(t, []string{
+		`{"a":"1","b":"2"}`,
+		`{"a":"3","b":"4"}`,
+		`{"a":"5","b":"6"}`,
+	}, results)
+}
+
+func TestStreamCodecError(t *testing.T) {
+	ctor, err := codec.GetReader("length-prefixed", codec.NewReaderConfig())
+	require.NoError(t, err)
+
+	r := NewStream(
+		func(ctx context.Context) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader([]byte("\x00\x00\x00\x05foo"))), nil
+		},
+		func(ctx context.Context) {},
+		ctor,
+	)
+	defer func() {
+		r.CloseAsync()
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	require.NoError(t, r.ConnectWithContext(context.Background()))
+
+	_, _, err = r.ReadWithContext(context.Background())
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
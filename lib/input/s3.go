@@ -6,6 +6,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 )
 
@@ -50,6 +51,19 @@ at-least-once crash resiliency, but also means that if the S3 item takes longer
 to process than the visibility timeout of your queue then the same items might
 be processed multiple times.
 
+### Codecs
+
+By default each object is downloaded in full and consumed as a single message.
+Any [codec](#codec) other than ` + "`all-bytes`" + ` results in objects being
+streamed and decoded into messages as they are downloaded, which allows large
+objects to be processed without loading them fully into memory. In this case
+the download manager is not used, and an object (along with its SQS message) is
+only deleted once all of the messages decoded from it have been acknowledged.
+If an object fails to be read part way through, or any of its messages are
+rejected, then its SQS message is made visible again so that the object is
+consumed again. Without SQS the object is read again from the start, up to the
+number of ` + "`retries`" + `.
+
 ### Credentials
 
 By default Benthos will use a shared credentials file when connecting to AWS
@@ -79,6 +93,7 @@ You can access these metadata fields using
 			append(docs.FieldSpecs{
 				docs.FieldCommon("bucket", "The bucket to consume from. If `sqs_bucket_path` is set this field is still required as a fallback."),
 				docs.FieldCommon("prefix", "An optional path prefix, if set only objects with the prefix are consumed. This field is ignored when SQS is used."),
+				codec.ReaderDocs,
 				docs.FieldCommon("sqs_url", "An optional SQS URL to connect to. When specified this queue will control which objects are downloaded from the target bucket."),
 				docs.FieldCommon("sqs_body_path", "A [dot path](/docs/configuration/field_paths) whereby object keys are found in SQS messages, this field is only required when an `sqs_url` is specified."),
 				docs.FieldCommon("sqs_bucket_path", "An optional [dot path](/docs/configuration/field_paths) whereby the bucket of an object can be found in consumed SQS messages."),
@@ -86,6 +101,7 @@ You can access these metadata fields using
 				docs.FieldAdvanced("sqs_max_messages", "The maximum number of SQS messages to consume from each request."),
 				docs.FieldAdvanced("sqs_endpoint", "A custom endpoint to use when connecting to SQS."),
 			}, session.FieldSpecs()...),
+			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
 			docs.FieldAdvanced("retries", "The maximum number of times to attempt an object download."),
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldAdvanced("delete_objects", "Whether to delete downloaded objects from the bucket."),
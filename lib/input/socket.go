@@ -1,6 +1,7 @@
 package input
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 )
 
@@ -20,23 +22,21 @@ func init() {
 		Summary: `
 Connects to a (tcp/unix) socket and consumes a continuous stream of messages.`,
 		Description: `
-If multipart is set to false each line of data is read as a separate message. If
-multipart is set to true each line is read as a message part, and an empty line
-indicates the end of a message.
+The stream of data is decoded into messages with a [codec](#codec), which
+defaults to reading each line as a message.
 
 Messages consumed by this input can be processed in parallel, meaning a single
 instance of this input can utilise any number of threads within a
-` + "`pipeline`" + ` section of a config.
-
-If the delimiter field is left empty then line feed (\n) is used.`,
+` + "`pipeline`" + ` section of a config.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("network", "A network type to assume (unix|tcp).").HasOptions(
 				"unix", "tcp",
 			),
 			docs.FieldCommon("address", "The address to connect to.", "/tmp/benthos.sock", "127.0.0.1:6000"),
-			docs.FieldAdvanced("multipart", "Whether messages should be consumed as multiple parts. If so, each line is consumed as a message parts and the full message ends with an empty line."),
+			codec.ReaderDocs,
 			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
-			docs.FieldAdvanced("delimiter", "The delimiter to use to detect the end of each message. If left empty line breaks are used."),
+			docs.FieldDeprecated("multipart"),
+			docs.FieldDeprecated("delimiter"),
 		},
 	}
 }
@@ -47,6 +47,7 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 type SocketConfig struct {
 	Network   string `json:"network" yaml:"network"`
 	Address   string `json:"address" yaml:"address"`
+	Codec     string `json:"codec" yaml:"codec"`
 	Multipart bool   `json:"multipart" yaml:"multipart"`
 	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
 	Delim     string `json:"delimiter" yaml:"delimiter"`
@@ -57,6 +58,7 @@ func NewSocketConfig() SocketConfig {
 	return SocketConfig{
 		Network:   "unix",
 		Address:   "/tmp/benthos.sock",
+		Codec:     "lines",
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
@@ -67,18 +69,21 @@ func NewSocketConfig() SocketConfig {
 
 // NewSocket creates a new Socket input type.
 func NewSocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	delim := conf.Socket.Delim
-	if len(delim) == 0 {
-		delim = "\n"
-	}
 	switch conf.Socket.Network {
 	case "tcp", "unix":
 	default:
 		return nil, fmt.Errorf("socket network '%v' is not supported by this input", conf.Socket.Network)
 	}
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.Socket.MaxBuffer
+	ctor, err := codec.GetReader(linesCodec(conf.Socket.Codec, conf.Socket.Delim, conf.Socket.Multipart), codecConf)
+	if err != nil {
+		return nil, err
+	}
+
 	var conn net.Conn
-	rdr, err := reader.NewLines(
-		func() (io.Reader, error) {
+	rdr := reader.NewStream(
+		func(ctx context.Context) (io.ReadCloser, error) {
 			if conn != nil {
 				conn.Close()
 				conn = nil
@@ -87,19 +92,14 @@ func NewSocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Ty
 			conn, err = net.Dial(conf.Socket.Network, conf.Socket.Address)
 			return conn, err
 		},
-		func() {
+		func(ctx context.Context) {
 			if conn != nil {
 				conn.Close()
 				conn = nil
 			}
 		},
-		reader.OptLinesSetDelimiter(delim),
-		reader.OptLinesSetMaxBuffer(conf.Socket.MaxBuffer),
-		reader.OptLinesSetMultipart(conf.Socket.Multipart),
+		ctor,
 	)
-	if err != nil {
-		return nil, err
-	}
 	return NewAsyncReader(
 		TypeSocket,
 		true,
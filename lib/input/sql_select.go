@@ -0,0 +1,97 @@
+package input
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSQLSelect] = TypeSpec{
+		constructor: NewSQLSelect,
+		Summary: `
+Periodically selects new rows of a table by tracking a monotonically increasing
+checkpoint column, emitting each row as a JSON object.`,
+		Description: `
+Rows are selected in the order of the ` + "`checkpoint_column`" + `, which
+would typically be an auto incrementing ID or an update timestamp, with a
+query resembling:
+
+` + "```sql" + `
+SELECT id, name, updated_at FROM footable
+WHERE updated_at > ? AND (active = true)
+ORDER BY updated_at ASC LIMIT 1000
+` + "```" + `
+
+When a query results in fewer rows than the ` + "`limit`" + ` the table is
+polled again after the ` + "`poll_interval`" + `, otherwise the next page of
+rows is selected immediately. Rows that share a checkpoint value with the last
+row of a full page are selected again with the next page. If every row of a full
+page shares the same checkpoint value then the following pages skip the rows of
+that value already selected with an ` + "`OFFSET`" + `, which relies on the
+database returning rows that share a checkpoint value in a consistent order,
+and is therefore best avoided by using a limit that exceeds the number of rows
+that share any single checkpoint value.
+
+Rows are only considered once they are committed, and therefore a checkpoint
+column that is assigned before a transaction commits, such as a timestamp set by
+the application, can result in rows committed out of order being skipped.
+
+### Delivery Guarantees
+
+The checkpoint of the last row consumed is stored within a
+[cache resource](/docs/components/caches/about) once the row, along with all
+rows that precede it or share its checkpoint value, has been acknowledged.
+Therefore when Benthos restarts, rows that were not yet acknowledged are
+selected again.
+
+### Drivers
+
+The following is a list of supported drivers and their respective DSN formats:
+
+- ` + "`mysql`: `[username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]`" + `
+- ` + "`postgres`: `postgresql://[user[:password]@][netloc][:port][/dbname][?param1=value1&...]`" + `
+- ` + "`sqlite3`: `file:/path/to/database.db`" + `
+
+Timestamp columns of the ` + "`mysql`" + ` driver are emitted as strings
+unless the DSN contains the parameter ` + "`parseTime=true`" + `.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("driver", "A database [driver](#drivers) to use.").HasOptions("mysql", "postgres", "sqlite3"),
+			docs.FieldCommon(
+				"dsn", "A Data Source Name to identify the target database.",
+				"foouser:foopassword@tcp(localhost:3306)/foodb",
+			),
+			docs.FieldCommon("table", "The table to select rows from.", "footable"),
+			docs.FieldCommon("columns", "A list of columns to select.", []string{"*"}, []string{"id", "name", "updated_at"}),
+			docs.FieldCommon("where", "An optional condition that rows must match in order to be selected.", "active = true"),
+			docs.FieldCommon("checkpoint_column", "A column with monotonically increasing values, which determines the rows that have not yet been consumed.", "id", "updated_at"),
+			docs.FieldAdvanced("checkpoint_start", "An optional checkpoint value to select rows after when there is no stored checkpoint. When empty all rows are selected."),
+			docs.FieldCommon("cache", "The name of a cache resource used to store the checkpoint."),
+			docs.FieldAdvanced("cache_key", "The key under which the checkpoint is stored."),
+			docs.FieldAdvanced("limit", "The maximum number of rows to select with each query."),
+			docs.FieldCommon("poll_interval", "The period of time to wait before polling the table again once all new rows have been consumed."),
+			docs.FieldAdvanced("max_batch_count", "The maximum number of rows to emit within each message batch."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQLSelect creates a new SQLSelect input type.
+func NewSQLSelect(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	cache, err := mgr.GetCache(conf.SQLSelect.Cache)
+	if err != nil {
+		return nil, err
+	}
+	r, err := reader.NewSQLSelect(conf.SQLSelect, cache, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeSQLSelect, true, reader.NewAsyncPreserver(r), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -1,6 +1,7 @@
 package input
 
 import (
+	"context"
 	"io"
 	"os"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 )
 
@@ -19,14 +21,13 @@ func init() {
 		Summary: `
 Consumes data piped to stdin as line delimited messages.`,
 		Description: `
-If the multipart option is set to true then lines are interpretted as message
-parts, and an empty line indicates the end of the message.
-
-If the delimiter field is left empty then line feed (\n) is used.`,
+The data is decoded into messages with a [codec](#codec), which defaults to
+reading each line as a message.`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldAdvanced("multipart", "Whether messages should be consumed as multiple parts. If so, each line is consumed as a message parts and the full message ends with an empty line."),
+			codec.ReaderDocs,
 			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
-			docs.FieldAdvanced("delimiter", "The delimiter to use to detect the end of each message. If left empty line breaks are used."),
+			docs.FieldDeprecated("multipart"),
+			docs.FieldDeprecated("delimiter"),
 		},
 	}
 }
@@ -35,6 +36,7 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 
 // STDINConfig contains config fields for the STDIN input type.
 type STDINConfig struct {
+	Codec     string `json:"codec" yaml:"codec"`
 	Multipart bool   `json:"multipart" yaml:"multipart"`
 	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
 	Delim     string `json:"delimiter" yaml:"delimiter"`
@@ -43,6 +45,7 @@ type STDINConfig struct {
 // NewSTDINConfig creates a STDINConfig populated with default values.
 func NewSTDINConfig() STDINConfig {
 	return STDINConfig{
+		Codec:     "lines",
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
@@ -53,14 +56,16 @@ func NewSTDINConfig() STDINConfig {
 
 // NewSTDIN creates a new STDIN input type.
 func NewSTDIN(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	delim := conf.STDIN.Delim
-	if len(delim) == 0 {
-		delim = "\n"
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.STDIN.MaxBuffer
+	ctor, err := codec.GetReader(linesCodec(conf.STDIN.Codec, conf.STDIN.Delim, conf.STDIN.Multipart), codecConf)
+	if err != nil {
+		return nil, err
 	}
 
 	stdin := os.Stdin
-	rdr, err := reader.NewLines(
-		func() (io.Reader, error) {
+	rdr := reader.NewStream(
+		func(ctx context.Context) (io.ReadCloser, error) {
 			// Swap so this only works once since we don't want to read stdin
 			// multiple times.
 			if stdin == nil {
@@ -70,14 +75,9 @@ func NewSTDIN(conf Config, mgr types.Manager, log log.Modular, stats metrics.Typ
 			stdin = nil
 			return sendStdin, nil
 		},
-		func() {},
-		reader.OptLinesSetDelimiter(delim),
-		reader.OptLinesSetMaxBuffer(conf.STDIN.MaxBuffer),
-		reader.OptLinesSetMultipart(conf.STDIN.Multipart),
+		func(ctx context.Context) {},
+		ctor,
 	)
-	if err != nil {
-		return nil, err
-	}
 	return NewAsyncReader(
 		TypeSTDIN, true,
 		reader.NewAsyncCutOff(reader.NewAsyncPreserver(rdr)),
@@ -1,6 +1,7 @@
 package input
 
 import (
+	"context"
 	"io"
 	"net"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/codec"
 )
 
 //------------------------------------------------------------------------------
@@ -18,15 +20,12 @@ func init() {
 		Description: `
 Connects to a TCP server and consumes a continuous stream of messages.
 
-If multipart is set to false each line of data is read as a separate message. If
-multipart is set to true each line is read as a message part, and an empty line
-indicates the end of a message.
+The stream of data is decoded into messages with a codec, which defaults to
+reading each line as a message.
 
 Messages consumed by this input can be processed in parallel, meaning a single
 instance of this input can utilise any number of threads within a
-` + "`pipeline`" + ` section of a config.
-
-If the delimiter field is left empty then line feed (\n) is used.`,
+` + "`pipeline`" + ` section of a config.`,
 		Deprecated: true,
 	}
 }
@@ -36,6 +35,7 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 // TCPConfig contains configuration values for the TCP input type.
 type TCPConfig struct {
 	Address   string `json:"address" yaml:"address"`
+	Codec     string `json:"codec" yaml:"codec"`
 	Multipart bool   `json:"multipart" yaml:"multipart"`
 	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
 	Delim     string `json:"delimiter" yaml:"delimiter"`
@@ -45,6 +45,7 @@ type TCPConfig struct {
 func NewTCPConfig() TCPConfig {
 	return TCPConfig{
 		Address:   "localhost:4194",
+		Codec:     "lines",
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
@@ -56,13 +57,16 @@ func NewTCPConfig() TCPConfig {
 // NewTCP creates a new TCP input type.
 func NewTCP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	log.Warnln("The tcp input is deprecated, please use socket instead.")
-	delim := conf.TCP.Delim
-	if len(delim) == 0 {
-		delim = "\n"
+	codecConf := codec.NewReaderConfig()
+	codecConf.MaxScanTokenSize = conf.TCP.MaxBuffer
+	ctor, err := codec.GetReader(linesCodec(conf.TCP.Codec, conf.TCP.Delim, conf.TCP.Multipart), codecConf)
+	if err != nil {
+		return nil, err
 	}
+
 	var conn net.Conn
-	rdr, err := reader.NewLines(
-		func() (io.Reader, error) {
+	rdr := reader.NewStream(
+		func(ctx context.Context) (io.ReadCloser, error) {
 			if conn != nil {
 				conn.Close()
 				conn = nil
@@ -71,19 +75,14 @@ func NewTCP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type)
 			conn, err = net.Dial("tcp", conf.TCP.Address)
 			return conn, err
 		},
-		func() {
+		func(ctx context.Context) {
 			if conn != nil {
 				conn.Close()
 				conn = nil
 			}
 		},
-		reader.OptLinesSetDelimiter(delim),
-		reader.OptLinesSetMaxBuffer(conf.TCP.MaxBuffer),
-		reader.OptLinesSetMultipart(conf.TCP.Multipart),
+		ctor,
 	)
-	if err != nil {
-		return nil, err
-	}
 	return NewAsyncReader(
 		TypeTCP,
 		true,
@@ -0,0 +1,91 @@
+package batch
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Error is an error returned by outputs when only some of the messages of a
+// batch failed to be delivered, and records which of them failed along with
+// their individual errors. Components that understand this error are able to
+// act on only the failed messages, whereas all others treat the entire batch
+// as having failed.
+type Error struct {
+	msg        types.Message
+	partErrors map[int]error
+}
+
+// NewError creates a new batch error for a message batch.
+func NewError(msg types.Message) *Error {
+	return &Error{
+		msg:        msg,
+		partErrors: map[int]error{},
+	}
+}
+
+// RejectedError wraps the error of a message that was rejected by the target of
+// an output, for example due to a malformed document, and therefore will fail
+// again if it is retried.
+type RejectedError struct {
+	Err error
+}
+
+// Error returns the error message of the wrapped error.
+func (r *RejectedError) Error() string {
+	return r.Err.Error()
+}
+
+// IsRejected returns true if an error of a message indicates that it was
+// rejected and should not be retried.
+func IsRejected(err error) bool {
+	_, ok := err.(*RejectedError)
+	return ok
+}
+
+// Failed records an error for a message of the batch by its index.
+func (e *Error) Failed(i int, err error) *Error {
+	e.partErrors[i] = err
+	return e
+}
+
+// Rejected records an error for a message of the batch by its index, where the
+// message was rejected and should not be retried.
+func (e *Error) Rejected(i int, err error) *Error {
+	e.partErrors[i] = &RejectedError{Err: err}
+	return e
+}
+
+// Message returns the message batch that the errors refer to.
+func (e *Error) Message() types.Message {
+	return e.msg
+}
+
+// IndexedErrors returns the number of messages of the batch that failed.
+func (e *Error) IndexedErrors() int {
+	return len(e.partErrors)
+}
+
+// WalkParts calls a closure for each message of the batch along with its
+// error, which is nil if the message was delivered successfully. Walking stops
+// if the closure returns false.
+func (e *Error) WalkParts(fn func(i int, p types.Part, err error) bool) {
+	for i := 0; i < e.msg.Len(); i++ {
+		if !fn(i, e.msg.Get(i), e.partErrors[i]) {
+			return
+		}
+	}
+}
+
+// Error returns a summary of the failed messages.
+func (e *Error) Error() string {
+	for i := 0; i < e.msg.Len(); i++ {
+		if err, exists := e.partErrors[i]; exists {
+			if len(e.partErrors) == 1 {
+				return err.Error()
+			}
+			return fmt.Sprintf("%v messages of batch failed, first error: %v", len(e.partErrors), err)
+		}
+	}
+	return "batch failed"
+}
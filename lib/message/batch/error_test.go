@@ -0,0 +1,58 @@
+package batch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+
+	err := NewError(msg).Failed(1, errors.New("bar failed"))
+	assert.Equal(t, msg, err.Message())
+	assert.Equal(t, 1, err.IndexedErrors())
+	assert.EqualError(t, err, "bar failed")
+
+	err.Failed(2, errors.New("baz failed"))
+	assert.Equal(t, 2, err.IndexedErrors())
+	assert.EqualError(t, err, "2 messages of batch failed, first error: bar failed")
+
+	var walked []string
+	err.WalkParts(func(i int, p types.Part, err error) bool {
+		if err != nil {
+			walked = append(walked, string(p.Get())+": "+err.Error())
+		} else {
+			walked = append(walked, string(p.Get()))
+		}
+		return true
+	})
+	assert.Equal(t, []string{"foo", "bar: bar failed", "baz: baz failed"}, walked)
+
+	walked = nil
+	err.WalkParts(func(i int, p types.Part, err error) bool {
+		walked = append(walked, string(p.Get()))
+		return err == nil
+	})
+	assert.Equal(t, []string{"foo", "bar"}, walked)
+}
+
+func TestErrorRejected(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+
+	err := NewError(msg).
+		Failed(0, errors.New("foo failed")).
+		Rejected(1, errors.New("bar rejected"))
+	assert.Equal(t, 2, err.IndexedErrors())
+
+	var rejected []bool
+	err.WalkParts(func(i int, p types.Part, err error) bool {
+		rejected = append(rejected, IsRejected(err))
+		return true
+	})
+	assert.Equal(t, []bool{false, true}, rejected)
+	assert.EqualError(t, &RejectedError{Err: errors.New("bar rejected")}, "bar rejected")
+}
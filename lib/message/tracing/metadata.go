@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+// Format is an opentracing propagation format specific to Benthos.
+type Format int
+
+// MetadataFormat is the propagation format used for carrying span contexts
+// within the metadata of message parts. Carriers of this format implement both
+// opentracing.TextMapReader and opentracing.TextMapWriter. Tracers that do not
+// support this format do not propagate span contexts through metadata.
+const MetadataFormat Format = 0
+
+// metadataCarrier reads and writes span contexts from the metadata of a message
+// part, where the part is copied before its metadata is first modified.
+type metadataCarrier struct {
+	part   types.Part
+	copied bool
+}
+
+// ForeachKey iterates the metadata keys and values of the part.
+func (m *metadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	return m.part.Metadata().Iter(handler)
+}
+
+// Set a metadata key of the part.
+func (m *metadataCarrier) Set(key, val string) {
+	if !m.copied {
+		m.part = message.WithContext(message.GetContext(m.part), m.part.Copy())
+		m.copied = true
+	}
+	m.part.Metadata().Set(key, val)
+}
+
+//------------------------------------------------------------------------------
+
+// ExtractSpanContext attempts to extract a span context from the metadata of a
+// message part, returning nil if the part does not contain one.
+func ExtractSpanContext(p types.Part) opentracing.SpanContext {
+	spanCtx, err := opentracing.GlobalTracer().Extract(MetadataFormat, &metadataCarrier{part: p})
+	if err != nil {
+		return nil
+	}
+	return spanCtx
+}
+
+// InjectSpans writes the context of each span into the metadata of the
+// respective message part, allowing downstream services to continue a trace.
+// Modified parts are copied and returned within a new message, and therefore
+// the original message is unchanged. When the tracer does not support
+// propagation through metadata the original message is returned.
+func InjectSpans(spans []opentracing.Span, msg types.Message) types.Message {
+	var parts []types.Part
+	msg.Iter(func(i int, p types.Part) error {
+		if i >= len(spans) || spans[i] == nil {
+			return nil
+		}
+		carrier := &metadataCarrier{part: p}
+		if err := spans[i].Tracer().Inject(spans[i].Context(), MetadataFormat, carrier); err != nil || !carrier.copied {
+			return nil
+		}
+		if parts == nil {
+			parts = make([]types.Part, msg.Len())
+			msg.Iter(func(j int, q types.Part) error {
+				parts[j] = q
+				return nil
+			})
+		}
+		parts[i] = carrier.part
+		return nil
+	})
+	if parts == nil {
+		return msg
+	}
+
+	newMsg := message.New(nil)
+	newMsg.SetAll(parts)
+	return newMsg
+}
+
+//------------------------------------------------------------------------------
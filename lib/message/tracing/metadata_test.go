@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withMockTracer() (*mocktracer.MockTracer, func()) {
+	tracer := mocktracer.New()
+	propagator := &mocktracer.TextMapPropagator{}
+	tracer.RegisterInjector(MetadataFormat, propagator)
+	tracer.RegisterExtractor(MetadataFormat, propagator)
+
+	prev := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	return tracer, func() {
+		opentracing.SetGlobalTracer(prev)
+	}
+}
+
+func TestMetadataPropagation(t *testing.T) {
+	tracer, done := withMockTracer()
+	defer done()
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+
+	spans := CreateChildSpans("output_foo", msg)
+	injected := InjectSpans(spans, msg)
+	for _, s := range spans {
+		s.Finish()
+	}
+
+	// The original message is unchanged.
+	assert.Equal(t, "", msg.Get(0).Metadata().Get("mockpfx-ids-spanid"))
+	assert.Equal(t, "", msg.Get(1).Metadata().Get("mockpfx-ids-spanid"))
+	require.Equal(t, 2, injected.Len())
+	assert.Equal(t, "foo", string(injected.Get(0).Get()))
+
+	finished := tracer.FinishedSpans()
+	require.Len(t, finished, 2)
+
+	InitSpans("input_bar", injected)
+	FinishSpans(injected)
+
+	finished = tracer.FinishedSpans()
+	require.Len(t, finished, 4)
+	for i := 0; i < 2; i++ {
+		parent := finished[i]
+		child := finished[i+2]
+		assert.Equal(t, "input_bar", child.OperationName)
+		assert.Equal(t, parent.SpanContext.TraceID, child.SpanContext.TraceID)
+		assert.Equal(t, parent.SpanContext.SpanID, child.ParentID)
+	}
+}
+
+func TestInitSpansWithoutMetadata(t *testing.T) {
+	tracer, done := withMockTracer()
+	defer done()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	InitSpans("input_foo", msg)
+	FinishSpans(msg)
+
+	finished := tracer.FinishedSpans()
+	require.Len(t, finished, 1)
+	assert.Equal(t, 0, finished[0].ParentID)
+}
+
+func TestInjectSpansUnsupported(t *testing.T) {
+	tracer := mocktracer.New()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	spans := []opentracing.Span{tracer.StartSpan("foo")}
+	assert.True(t, msg == InjectSpans(spans, msg))
+}
@@ -110,7 +110,8 @@ func IterateWithChildSpans(operationName string, msg types.Message, iter func(in
 }
 
 // InitSpans sets up OpenTracing spans on each message part if one does not
-// already exist.
+// already exist. Spans are created as children of a span context within the
+// metadata of a part when the tracer is able to extract one.
 func InitSpans(operationName string, msg types.Message) {
 	tracedParts := make([]types.Part, msg.Len())
 	msg.Iter(func(i int, p types.Part) error {
@@ -118,7 +119,12 @@ func InitSpans(operationName string, msg types.Message) {
 			tracedParts[i] = p
 			return nil
 		}
-		span := opentracing.StartSpan(operationName)
+		var span opentracing.Span
+		if parent := ExtractSpanContext(p); parent != nil {
+			span = opentracing.StartSpan(operationName, opentracing.ChildOf(parent))
+		} else {
+			span = opentracing.StartSpan(operationName)
+		}
 		ctx := opentracing.ContextWithSpan(message.GetContext(p), span)
 		tracedParts[i] = message.WithContext(ctx, p)
 		return nil
@@ -41,14 +41,15 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each metric type.
 const (
-	TypeBlackList  = "blacklist"
-	TypeCloudWatch = "cloudwatch"
-	TypeHTTPServer = "http_server"
-	TypePrometheus = "prometheus"
-	TypeRename     = "rename"
-	TypeStatsd     = "statsd"
-	TypeStdout     = "stdout"
-	TypeWhiteList  = "whitelist"
+	TypeBlackList     = "blacklist"
+	TypeCloudWatch    = "cloudwatch"
+	TypeHTTPServer    = "http_server"
+	TypeOpenTelemetry = "open_telemetry"
+	TypePrometheus    = "prometheus"
+	TypeRename        = "rename"
+	TypeStatsd        = "statsd"
+	TypeStdout        = "stdout"
+	TypeWhiteList     = "whitelist"
 )
 
 //------------------------------------------------------------------------------
@@ -56,29 +57,31 @@ const (
 // Config is the all encompassing configuration struct for all metric output
 // types.
 type Config struct {
-	Type       string           `json:"type" yaml:"type"`
-	Blacklist  BlacklistConfig  `json:"blacklist" yaml:"blacklist"`
-	CloudWatch CloudWatchConfig `json:"cloudwatch" yaml:"cloudwatch"`
-	HTTP       HTTPConfig       `json:"http_server" yaml:"http_server"`
-	Prometheus PrometheusConfig `json:"prometheus" yaml:"prometheus"`
-	Rename     RenameConfig     `json:"rename" yaml:"rename"`
-	Statsd     StatsdConfig     `json:"statsd" yaml:"statsd"`
-	Stdout     StdoutConfig     `json:"stdout" yaml:"stdout"`
-	Whitelist  WhitelistConfig  `json:"whitelist" yaml:"whitelist"`
+	Type          string              `json:"type" yaml:"type"`
+	Blacklist     BlacklistConfig     `json:"blacklist" yaml:"blacklist"`
+	CloudWatch    CloudWatchConfig    `json:"cloudwatch" yaml:"cloudwatch"`
+	HTTP          HTTPConfig          `json:"http_server" yaml:"http_server"`
+	OpenTelemetry OpenTelemetryConfig `json:"open_telemetry" yaml:"open_telemetry"`
+	Prometheus    PrometheusConfig    `json:"prometheus" yaml:"prometheus"`
+	Rename        RenameConfig        `json:"rename" yaml:"rename"`
+	Statsd        StatsdConfig        `json:"statsd" yaml:"statsd"`
+	Stdout        StdoutConfig        `json:"stdout" yaml:"stdout"`
+	Whitelist     WhitelistConfig     `json:"whitelist" yaml:"whitelist"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:       "http_server",
-		Blacklist:  NewBlacklistConfig(),
-		CloudWatch: NewCloudWatchConfig(),
-		HTTP:       NewHTTPConfig(),
-		Prometheus: NewPrometheusConfig(),
-		Rename:     NewRenameConfig(),
-		Statsd:     NewStatsdConfig(),
-		Stdout:     NewStdoutConfig(),
-		Whitelist:  NewWhitelistConfig(),
+		Type:          "http_server",
+		Blacklist:     NewBlacklistConfig(),
+		CloudWatch:    NewCloudWatchConfig(),
+		HTTP:          NewHTTPConfig(),
+		OpenTelemetry: NewOpenTelemetryConfig(),
+		Prometheus:    NewPrometheusConfig(),
+		Rename:        NewRenameConfig(),
+		Statsd:        NewStatsdConfig(),
+		Stdout:        NewStdoutConfig(),
+		Whitelist:     NewWhitelistConfig(),
 	}
 }
 
@@ -0,0 +1,379 @@
+// +build !wasm
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+)
+
+//------------------------------------------------------------------------------
+
+// otelTimerBounds are the bucket bounds of timer histograms in nanoseconds,
+// ranging from ten microseconds to ten seconds.
+var otelTimerBounds = []float64{
+	1e4, 5e4, 1e5, 5e5, 1e6, 5e6, 1e7, 5e7, 1e8, 5e8, 1e9, 5e9, 1e10,
+}
+
+// otelSeries is a single metric stat for a set of label values.
+type otelSeries struct {
+	attrs []otlp.KeyValue
+	value int64
+
+	histMut sync.Mutex
+	count   uint64
+	sum     float64
+	min     float64
+	max     float64
+	buckets []uint64
+}
+
+// Incr increments a metric by an amount.
+func (o *otelSeries) Incr(count int64) error {
+	atomic.AddInt64(&o.value, count)
+	return nil
+}
+
+// Decr decrements a metric by an amount.
+func (o *otelSeries) Decr(count int64) error {
+	atomic.AddInt64(&o.value, -count)
+	return nil
+}
+
+// Set sets a gauge metric.
+func (o *otelSeries) Set(value int64) error {
+	atomic.StoreInt64(&o.value, value)
+	return nil
+}
+
+// Timing sets a timing metric.
+func (o *otelSeries) Timing(delta int64) error {
+	v := float64(delta)
+
+	o.histMut.Lock()
+	if o.count == 0 || v < o.min {
+		o.min = v
+	}
+	if o.count == 0 || v > o.max {
+		o.max = v
+	}
+	o.count++
+	o.sum += v
+	o.buckets[sort.SearchFloat64s(otelTimerBounds, v)]++
+	o.histMut.Unlock()
+	return nil
+}
+
+func (o *otelSeries) dataPoint(typ otlp.MetricType, start, now time.Time) otlp.DataPoint {
+	p := otlp.DataPoint{
+		Attributes: o.attrs,
+		Time:       now,
+	}
+	switch typ {
+	case otlp.MetricSum:
+		p.Start = start
+		p.Value = atomic.LoadInt64(&o.value)
+	case otlp.MetricGauge:
+		p.Value = atomic.LoadInt64(&o.value)
+	case otlp.MetricHistogram:
+		p.Start = start
+		p.Bounds = otelTimerBounds
+
+		o.histMut.Lock()
+		p.Count = o.count
+		p.Sum = o.sum
+		p.Min = o.min
+		p.Max = o.max
+		p.BucketCounts = make([]uint64, len(o.buckets))
+		copy(p.BucketCounts, o.buckets)
+		o.histMut.Unlock()
+	}
+	return p
+}
+
+//------------------------------------------------------------------------------
+
+// otelMetric is a named metric with a series for each set of label values.
+type otelMetric struct {
+	name       string
+	typ        otlp.MetricType
+	labelNames []string
+
+	seriesMut sync.Mutex
+	series    map[string]*otelSeries
+}
+
+func (o *otelMetric) with(labelValues ...string) *otelSeries {
+	key := strings.Join(labelValues, "\x00")
+
+	o.seriesMut.Lock()
+	defer o.seriesMut.Unlock()
+
+	if s, exists := o.series[key]; exists {
+		return s
+	}
+
+	s := &otelSeries{}
+	for i, name := range o.labelNames {
+		var value string
+		if i < len(labelValues) {
+			value = labelValues[i]
+		}
+		s.attrs = append(s.attrs, otlp.KeyValue{Key: name, Value: value})
+	}
+	if o.typ == otlp.MetricHistogram {
+		s.buckets = make([]uint64, len(otelTimerBounds)+1)
+	}
+	o.series[key] = s
+	return s
+}
+
+func (o *otelMetric) toOTLP(start, now time.Time) otlp.Metric {
+	m := otlp.Metric{
+		Name:      o.name,
+		Type:      o.typ,
+		Monotonic: o.typ == otlp.MetricSum,
+	}
+	if o.typ == otlp.MetricHistogram {
+		m.Unit = "ns"
+	}
+
+	o.seriesMut.Lock()
+	keys := make([]string, 0, len(o.series))
+	for k := range o.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		m.Points = append(m.Points, o.series[k].dataPoint(o.typ, start, now))
+	}
+	o.seriesMut.Unlock()
+	return m
+}
+
+type otelCounterVec struct {
+	m *otelMetric
+}
+
+func (o otelCounterVec) With(labelValues ...string) StatCounter {
+	return o.m.with(labelValues...)
+}
+
+type otelTimerVec struct {
+	m *otelMetric
+}
+
+func (o otelTimerVec) With(labelValues ...string) StatTimer {
+	return o.m.with(labelValues...)
+}
+
+type otelGaugeVec struct {
+	m *otelMetric
+}
+
+func (o otelGaugeVec) With(labelValues ...string) StatGauge {
+	return o.m.with(labelValues...)
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetry is a stats object with capability to push metrics to an
+// OpenTelemetry collector.
+type OpenTelemetry struct {
+	client   *otlp.Client
+	resource otlp.Resource
+	prefix   string
+	start    time.Time
+
+	metricsMut sync.Mutex
+	metrics    map[string]*otelMetric
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log log.Modular
+}
+
+// NewOpenTelemetry creates and returns a new OpenTelemetry object.
+func NewOpenTelemetry(config Config, opts ...func(Type)) (Type, error) {
+	conf := config.OpenTelemetry
+
+	flushPeriod, err := time.ParseDuration(conf.FlushPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flush period: %v", err)
+	}
+	if flushPeriod <= 0 {
+		return nil, errors.New("flush period must be greater than zero")
+	}
+
+	o := &OpenTelemetry{
+		prefix:     conf.Prefix,
+		start:      time.Now(),
+		metrics:    map[string]*otelMetric{},
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log.Noop(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.resource.Attributes = append(o.resource.Attributes, otlp.KeyValue{
+		Key:   "service.name",
+		Value: conf.ServiceName,
+	})
+	tagKeys := make([]string, 0, len(conf.Tags))
+	for k := range conf.Tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		o.resource.Attributes = append(o.resource.Attributes, otlp.KeyValue{
+			Key:   k,
+			Value: conf.Tags[k],
+		})
+	}
+
+	if o.client, err = otlp.NewClient(conf.Config); err != nil {
+		return nil, err
+	}
+
+	go o.loop(flushPeriod)
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *OpenTelemetry) getMetric(path string, typ otlp.MetricType, labelNames []string) *otelMetric {
+	name := path
+	if len(o.prefix) > 0 {
+		name = o.prefix + "." + path
+	}
+	key := fmt.Sprintf("%v:%v", typ, name)
+
+	o.metricsMut.Lock()
+	defer o.metricsMut.Unlock()
+
+	if m, exists := o.metrics[key]; exists {
+		return m
+	}
+	m := &otelMetric{
+		name:       name,
+		typ:        typ,
+		labelNames: labelNames,
+		series:     map[string]*otelSeries{},
+	}
+	o.metrics[key] = m
+	return m
+}
+
+// GetCounter returns a stat counter object for a path.
+func (o *OpenTelemetry) GetCounter(path string) StatCounter {
+	return o.getMetric(path, otlp.MetricSum, nil).with()
+}
+
+// GetCounterVec returns a stat counter object for a path with the labels
+func (o *OpenTelemetry) GetCounterVec(path string, n []string) StatCounterVec {
+	return otelCounterVec{m: o.getMetric(path, otlp.MetricSum, n)}
+}
+
+// GetTimer returns a stat timer object for a path.
+func (o *OpenTelemetry) GetTimer(path string) StatTimer {
+	return o.getMetric(path, otlp.MetricHistogram, nil).with()
+}
+
+// GetTimerVec returns a stat timer object for a path with the labels
+func (o *OpenTelemetry) GetTimerVec(path string, n []string) StatTimerVec {
+	return otelTimerVec{m: o.getMetric(path, otlp.MetricHistogram, n)}
+}
+
+// GetGauge returns a stat gauge object for a path.
+func (o *OpenTelemetry) GetGauge(path string) StatGauge {
+	return o.getMetric(path, otlp.MetricGauge, nil).with()
+}
+
+// GetGaugeVec returns a stat timer object for a path with the labels
+func (o *OpenTelemetry) GetGaugeVec(path string, n []string) StatGaugeVec {
+	return otelGaugeVec{m: o.getMetric(path, otlp.MetricGauge, n)}
+}
+
+//------------------------------------------------------------------------------
+
+func (o *OpenTelemetry) snapshot(now time.Time) []otlp.Metric {
+	o.metricsMut.Lock()
+	keys := make([]string, 0, len(o.metrics))
+	for k := range o.metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	metrics := make([]*otelMetric, 0, len(keys))
+	for _, k := range keys {
+		metrics = append(metrics, o.metrics[k])
+	}
+	o.metricsMut.Unlock()
+
+	otlpMetrics := make([]otlp.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if om := m.toOTLP(o.start, now); len(om.Points) > 0 {
+			otlpMetrics = append(otlpMetrics, om)
+		}
+	}
+	return otlpMetrics
+}
+
+func (o *OpenTelemetry) push() {
+	metrics := o.snapshot(time.Now())
+	if len(metrics) == 0 {
+		return
+	}
+	payload := otlp.EncodeMetrics(o.resource, otlp.Scope{Name: "benthos"}, metrics)
+	if err := o.client.ExportMetrics(context.Background(), payload); err != nil {
+		o.log.Errorf("Failed to push metrics: %v\n", err)
+	}
+}
+
+func (o *OpenTelemetry) loop(flushPeriod time.Duration) {
+	defer close(o.closedChan)
+
+	ticker := time.NewTicker(flushPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.push()
+		case <-o.closeChan:
+			o.push()
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SetLogger sets the logger used to print connection errors.
+func (o *OpenTelemetry) SetLogger(log log.Modular) {
+	o.log = log
+}
+
+// Close stops the OpenTelemetry object from aggregating metrics and pushes any
+// remaining metrics.
+func (o *OpenTelemetry) Close() error {
+	o.closeOnce.Do(func() {
+		close(o.closeChan)
+	})
+	<-o.closedChan
+	return o.client.Close()
+}
+
+//------------------------------------------------------------------------------
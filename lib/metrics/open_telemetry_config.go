@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOpenTelemetry] = TypeSpec{
+		constructor: NewOpenTelemetry,
+		Summary: `
+Push metrics to an [OpenTelemetry collector](https://opentelemetry.io/docs/collector/)
+using the OpenTelemetry Protocol (OTLP) over gRPC or HTTP.`,
+		Description: `
+Counters are exported as cumulative monotonic sums, gauges as gauges and timers
+as cumulative histograms of nanoseconds with buckets ranging from ten
+microseconds to ten seconds. Metric names are the dot separated paths of
+[the list](/docs/components/metrics/about#paths) prefixed with the
+` + "`prefix`" + `, and labels are exported as attributes.`,
+		FieldSpecs: append(otlp.FieldSpecs(),
+			docs.FieldCommon("prefix", "A string prefix to add to all metrics."),
+			docs.FieldCommon("service_name", "A name to provide for this service."),
+			docs.FieldAdvanced("tags", "A map of attributes to add to the resource of all metrics.", map[string]string{
+				"deployment.environment": "production",
+			}),
+			docs.FieldCommon("flush_period", "The period of time between each push of metrics."),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetryConfig is config for the OpenTelemetry metrics type.
+type OpenTelemetryConfig struct {
+	otlp.Config `json:",inline" yaml:",inline"`
+	Prefix      string            `json:"prefix" yaml:"prefix"`
+	ServiceName string            `json:"service_name" yaml:"service_name"`
+	Tags        map[string]string `json:"tags" yaml:"tags"`
+	FlushPeriod string            `json:"flush_period" yaml:"flush_period"`
+}
+
+// NewOpenTelemetryConfig creates an OpenTelemetryConfig struct with default
+// values.
+func NewOpenTelemetryConfig() OpenTelemetryConfig {
+	return OpenTelemetryConfig{
+		Config:      otlp.NewConfig(),
+		Prefix:      "benthos",
+		ServiceName: "benthos",
+		Tags:        map[string]string{},
+		FlushPeriod: "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
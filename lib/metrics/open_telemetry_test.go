@@ -0,0 +1,130 @@
+// +build !wasm
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenTelemetrySnapshot(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.FlushPeriod = "1h"
+
+	m, err := New(conf)
+	require.NoError(t, err)
+	defer m.Close()
+
+	m.GetCounter("foo.counter").Incr(3)
+	m.GetCounter("foo.counter").Incr(2)
+	m.GetGauge("foo.gauge").Set(10)
+	m.GetGauge("foo.gauge").Decr(3)
+
+	timer := m.GetTimer("foo.timer")
+	timer.Timing(int64(time.Millisecond * 2))
+	timer.Timing(int64(time.Millisecond * 3))
+	timer.Timing(int64(time.Second * 20))
+
+	vec := m.GetCounterVec("foo.counter_vec", []string{"a", "b"})
+	vec.With("x", "y").Incr(1)
+	vec.With("x").Incr(2)
+	vec.With("x", "y").Incr(1)
+
+	now := time.Now()
+	metrics := m.(*OpenTelemetry).snapshot(now)
+	require.Len(t, metrics, 4)
+
+	byName := map[string]otlp.Metric{}
+	for _, om := range metrics {
+		byName[om.Name] = om
+	}
+
+	counter := byName["benthos.foo.counter"]
+	assert.Equal(t, otlp.MetricSum, counter.Type)
+	assert.True(t, counter.Monotonic)
+	require.Len(t, counter.Points, 1)
+	assert.Equal(t, int64(5), counter.Points[0].Value)
+	assert.Equal(t, now, counter.Points[0].Time)
+
+	gauge := byName["benthos.foo.gauge"]
+	assert.Equal(t, otlp.MetricGauge, gauge.Type)
+	require.Len(t, gauge.Points, 1)
+	assert.Equal(t, int64(7), gauge.Points[0].Value)
+
+	hist := byName["benthos.foo.timer"]
+	assert.Equal(t, otlp.MetricHistogram, hist.Type)
+	assert.Equal(t, "ns", hist.Unit)
+	require.Len(t, hist.Points, 1)
+	p := hist.Points[0]
+	assert.Equal(t, uint64(3), p.Count)
+	assert.Equal(t, float64(time.Millisecond*2), p.Min)
+	assert.Equal(t, float64(time.Second*20), p.Max)
+	require.Len(t, p.BucketCounts, len(otelTimerBounds)+1)
+	assert.Equal(t, uint64(2), p.BucketCounts[5])
+	assert.Equal(t, uint64(1), p.BucketCounts[len(otelTimerBounds)])
+
+	counterVec := byName["benthos.foo.counter_vec"]
+	require.Len(t, counterVec.Points, 2)
+	assert.Equal(t, []otlp.KeyValue{{Key: "a", Value: "x"}, {Key: "b", Value: ""}}, counterVec.Points[0].Attributes)
+	assert.Equal(t, int64(2), counterVec.Points[0].Value)
+	assert.Equal(t, []otlp.KeyValue{{Key: "a", Value: "x"}, {Key: "b", Value: "y"}}, counterVec.Points[1].Attributes)
+	assert.Equal(t, int64(2), counterVec.Points[1].Value)
+}
+
+func TestOpenTelemetryPush(t *testing.T) {
+	reqChan := make(chan []byte, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		reqChan <- body
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = otlp.ProtocolHTTP
+	conf.OpenTelemetry.Address = strings.TrimPrefix(ts.URL, "http://")
+	conf.OpenTelemetry.ServiceName = "foo_service"
+	conf.OpenTelemetry.FlushPeriod = "1h"
+
+	m, err := New(conf)
+	require.NoError(t, err)
+
+	m.GetCounterVec("foo.counter", []string{"label"}).With("bar_value").Incr(1)
+	require.NoError(t, m.Close())
+
+	select {
+	case body := <-reqChan:
+		assert.Contains(t, string(body), "foo_service")
+		assert.Contains(t, string(body), "benthos.foo.counter")
+		assert.Contains(t, string(body), "bar_value")
+	default:
+		t.Fatal("expected metrics to be pushed on close")
+	}
+}
+
+func TestOpenTelemetryConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.FlushPeriod = "nope"
+	_, err := New(conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = "nope"
+	_, err = New(conf)
+	assert.Error(t, err)
+}
@@ -194,11 +194,12 @@ func (w *AsyncWriter) loop() {
 
 			w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 			spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
-			latency, err := w.latencyMeasuringWrite(ts.Payload)
+			payload := tracing.InjectSpans(spans, ts.Payload)
+			latency, err := w.latencyMeasuringWrite(payload)
 
 			// If our writer says it is not connected.
 			if err == types.ErrNotConnected {
-				latency, err = connectLoop(ts.Payload)
+				latency, err = connectLoop(payload)
 			}
 
 			// Close immediately if our writer is closed.
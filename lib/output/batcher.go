@@ -8,6 +8,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -78,7 +79,7 @@ func (m *Batcher) loop() {
 		nextTimedBatchChan = time.After(tNext)
 	}
 
-	var pendingResChans []chan<- types.Response
+	var pendingTrans []types.Transaction
 	for atomic.LoadInt32(&m.running) == 1 {
 		if nextTimedBatchChan == nil {
 			if tNext := m.batcher.UntilNext(); tNext >= 0 {
@@ -108,7 +109,7 @@ func (m *Batcher) loop() {
 					}
 					return nil
 				})
-				pendingResChans = append(pendingResChans, tran.ResponseChan)
+				pendingTrans = append(pendingTrans, tran)
 			}
 		case <-nextTimedBatchChan:
 			flushBatch = true
@@ -134,7 +135,7 @@ func (m *Batcher) loop() {
 			return
 		}
 
-		go func(rChan chan types.Response, upstreamResChans []chan<- types.Response) {
+		go func(rChan chan types.Response, upstreamTrans []types.Transaction) {
 			select {
 			case <-m.fullyCloseChan:
 				return
@@ -142,19 +143,69 @@ func (m *Batcher) loop() {
 				if !open {
 					return
 				}
-				for _, c := range upstreamResChans {
+				resps := splitBatchResponse(res, upstreamTrans)
+				for i, tran := range upstreamTrans {
 					select {
 					case <-m.fullyCloseChan:
 						return
-					case c <- res:
+					case tran.ResponseChan <- resps[i]:
 					}
 				}
 			}
-		}(resChan, pendingResChans)
-		pendingResChans = nil
+		}(resChan, pendingTrans)
+		pendingTrans = nil
 	}
 }
 
+// splitBatchResponse returns a response for each transaction that a flushed
+// batch was built from. When the response is a batch error that refers to each
+// message of the transactions in order the errors are mapped back to the
+// messages of each transaction, otherwise the response applies to all of them.
+func splitBatchResponse(res types.Response, trans []types.Transaction) []types.Response {
+	resps := make([]types.Response, len(trans))
+	for i := range resps {
+		resps[i] = res
+	}
+
+	bErr, ok := res.Error().(*batch.Error)
+	if !ok {
+		return resps
+	}
+	total := 0
+	for _, tran := range trans {
+		total += tran.Payload.Len()
+	}
+	if bErr.Message().Len() != total {
+		return resps
+	}
+
+	partErrs := make([]error, 0, total)
+	bErr.WalkParts(func(_ int, _ types.Part, err error) bool {
+		partErrs = append(partErrs, err)
+		return true
+	})
+
+	offset := 0
+	for i, tran := range trans {
+		var tErr *batch.Error
+		for j := 0; j < tran.Payload.Len(); j++ {
+			if err := partErrs[offset+j]; err != nil {
+				if tErr == nil {
+					tErr = batch.NewError(tran.Payload)
+				}
+				tErr.Failed(j, err)
+			}
+		}
+		offset += tran.Payload.Len()
+		if tErr == nil {
+			resps[i] = response.NewAck()
+		} else {
+			resps[i] = response.NewError(tErr)
+		}
+	}
+	return resps
+}
+
 // Connected returns a boolean indicating whether this output is currently
 // connected to its target.
 func (m *Batcher) Connected() bool {
@@ -245,3 +245,84 @@ func TestBatcherTimed(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestBatcherSplitsBatchError(t *testing.T) {
+	policyConf := batch.NewPolicyConfig()
+	policyConf.Count = 3
+	batcher, err := batch.NewPolicy(policyConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &mockOutput{}
+	b := NewBatcher(batcher, out, log.Noop(), metrics.Noop())
+
+	tInChan := make(chan types.Transaction)
+	if err := b.Consume(tInChan); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []types.Message{
+		message.New([][]byte{[]byte("foo")}),
+		message.New([][]byte{[]byte("bar"), []byte("baz")}),
+	}
+	resChans := []chan types.Response{
+		make(chan types.Response, 1),
+		make(chan types.Response, 1),
+	}
+	for i, msg := range inputs {
+		select {
+		case tInChan <- types.NewTransaction(msg, resChans[i]):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	var outTran types.Transaction
+	select {
+	case outTran = <-out.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if exp, act := 3, outTran.Payload.Len(); exp != act {
+		t.Fatalf("Wrong batch size: %v != %v", act, exp)
+	}
+
+	bazErr := errors.New("baz failed")
+	outTran.ResponseChan <- response.NewError(batch.NewError(outTran.Payload).Failed(2, bazErr))
+
+	select {
+	case res := <-resChans[0]:
+		if res.Error() != nil {
+			t.Errorf("Unexpected error: %v", res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChans[1]:
+		bErr, ok := res.Error().(*batch.Error)
+		if !ok {
+			t.Fatalf("Wrong error type: %T", res.Error())
+		}
+		if bErr.Message() != inputs[1] {
+			t.Error("Batch error does not refer to the transaction message")
+		}
+		var partErrs []error
+		bErr.WalkParts(func(_ int, _ types.Part, err error) bool {
+			partErrs = append(partErrs, err)
+			return true
+		})
+		if exp, act := []error{nil, bazErr}, partErrs; !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong part errors: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	close(tInChan)
+	if err := b.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
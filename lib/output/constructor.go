@@ -85,6 +85,7 @@ const (
 	TypeRetry           = "retry"
 	TypeS3              = "s3"
 	TypeSNS             = "sns"
+	TypeSQL             = "sql"
 	TypeSQS             = "sqs"
 	TypeSTDOUT          = "stdout"
 	TypeSwitch          = "switch"
@@ -135,6 +136,7 @@ type Config struct {
 	Retry           RetryConfig                  `json:"retry" yaml:"retry"`
 	S3              writer.AmazonS3Config        `json:"s3" yaml:"s3"`
 	SNS             writer.SNSConfig             `json:"sns" yaml:"sns"`
+	SQL             writer.SQLConfig             `json:"sql" yaml:"sql"`
 	SQS             writer.AmazonSQSConfig       `json:"sqs" yaml:"sqs"`
 	STDOUT          STDOUTConfig                 `json:"stdout" yaml:"stdout"`
 	Switch          SwitchConfig                 `json:"switch" yaml:"switch"`
@@ -185,6 +187,7 @@ func NewConfig() Config {
 		Retry:           NewRetryConfig(),
 		S3:              writer.NewAmazonS3Config(),
 		SNS:             writer.NewSNSConfig(),
+		SQL:             writer.NewSQLConfig(),
 		SQS:             writer.NewAmazonSQSConfig(),
 		STDOUT:          NewSTDOUTConfig(),
 		Switch:          NewSwitchConfig(),
@@ -23,9 +23,46 @@ func init() {
 Publishes messages into an Elasticsearch index. If the index does not exist then
 it is created with a dynamic mapping.`,
 		Description: `
-Both the ` + "`id` and `index`" + ` fields can be dynamically set using function
-interpolations described [here](/docs/configuration/interpolation#functions). When
-sending batched messages these interpolations are performed per message part.
+The ` + "`id`, `index`, `action` and `routing`" + ` fields can be dynamically set
+using function interpolations described
+[here](/docs/configuration/interpolation#functions). When sending batched
+messages these interpolations are performed per message part.
+
+### Actions
+
+Messages are written using the bulk API, where the ` + "`action`" + ` of each
+message determines how it is applied to the index. The actions ` + "`index`" + `
+and ` + "`create`" + ` write the message as a document, ` + "`update`" + `
+merges the message into an existing document, ` + "`upsert`" + ` does the same
+but creates the document when it does not exist, and ` + "`delete`" + ` removes
+the document with the given ID, ignoring the contents of the message.
+
+When a ` + "`script`" + ` is specified the actions ` + "`update` and `upsert`" + `
+run the script against the document instead, where the message is provided to
+the script as ` + "`params`" + `. Script upserts execute the script regardless
+of whether the document already exists.
+
+### Errors
+
+Each message of a batch is acknowledged individually by Elasticsearch, and only
+those that failed with a retryable status (` + "`429`" + ` and ` + "`5xx`" + `
+statuses) are retried according to the backoff settings. Messages that are
+rejected (for example due to a version conflict or a mapping error) are not
+retried.
+
+When this output is used within a stream that has a
+[` + "`dead_letter`" + ` output](/docs/configuration/error_handling#dead-letter-queues)
+configured, only the messages that were rejected or exhausted their retries are
+routed to it along with their individual errors, whereas the rest of the batch
+is considered delivered. Rejected messages are routed immediately without
+further attempts.
+
+Without a ` + "`dead_letter`" + ` output a batch with rejected messages is
+retried indefinitely, which can be avoided by setting ` + "`drop_rejected`" + `
+to ` + "`true`" + `. Rejected messages are then dropped with a logged error and
+a batch where every failed message was rejected is considered delivered. This
+takes precedence over a ` + "`dead_letter`" + ` output, which no longer receives
+rejected messages.
 
 ### AWS
 
@@ -42,11 +79,15 @@ false for connections to succeed.`,
 			docs.FieldCommon("index", "The index to place messages.").SupportsInterpolation(false),
 			docs.FieldAdvanced("pipeline", "An optional pipeline id to preprocess incoming documents.").SupportsInterpolation(false),
 			docs.FieldCommon("id", "The ID for indexed messages. Interpolation should be used in order to create a unique ID for each message.").SupportsInterpolation(false),
+			docs.FieldCommon("action", "The bulk action to perform for each message.").HasOptions("index", "create", "update", "upsert", "delete").SupportsInterpolation(false),
+			docs.FieldAdvanced("routing", "An optional routing value for each message, which determines the shard that a document is stored within.").SupportsInterpolation(false),
+			docs.FieldAdvanced("script", "An optional [painless script](https://www.elastic.co/guide/en/elasticsearch/reference/current/modules-scripting-painless.html) to execute for the `update` and `upsert` actions, where the contents of the message are provided as `params`.", "ctx._source.count += params.count"),
 			docs.FieldCommon("type", "The document type."),
 			docs.FieldAdvanced("sniff", "Prompts Benthos to sniff for brokers to connect to when establishing a connection."),
 			docs.FieldAdvanced("healthcheck", "Whether to enable healthchecks."),
 			docs.FieldAdvanced("timeout", "The maximum time to wait before abandoning a request (and trying again)."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			docs.FieldAdvanced("drop_rejected", "Whether to drop messages that are rejected by Elasticsearch, for example due to a mapping error, rather than returning them as errors."),
 		}.Merge(retries.FieldSpecs()).Add(
 			auth.BasicAuthFieldSpec(),
 			batch.FieldSpec(),
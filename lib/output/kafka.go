@@ -29,7 +29,28 @@ acknowledgement from all replicas or just a single broker.
 Both the ` + "`key` and `topic`" + ` fields can be dynamically set using
 function interpolations described [here](/docs/configuration/interpolation#functions).
 When sending batched messages these interpolations are performed per message
-part.`,
+part.
+
+### Transactions
+
+When ` + "`transaction.enabled`" + ` is set to ` + "`true`" + ` each message batch
+is written within a single Kafka transaction using an idempotent producer, where
+either all messages of the batch are committed or none of them are. The field
+` + "`transaction.id`" + ` must be unique to this output and stable across
+restarts, as it is used by the brokers to fence off previous instances of the
+producer. An output that has been fenced off by a newer instance fails all
+further writes until it is restarted. Brokers older than version 2.7.0 do not
+report fencing distinctly, in which case the output aborts the transaction and
+initialises the producer again.
+
+When ` + "`transaction.consumer_group`" + ` is also set the offsets of messages
+consumed by a [` + "`kafka_balanced`" + `](/docs/components/inputs/kafka_balanced)
+input with the same consumer group are committed within the same transaction,
+which results in exactly-once delivery from Kafka to Kafka. Offsets are
+determined from the metadata fields ` + "`kafka_topic`, `kafka_partition` and `kafka_offset`" + `
+of each message, and therefore these fields must be preserved by the pipeline.
+Consumers of the output topics should use the ` + "`read_committed`" + ` isolation
+level in order to only observe committed messages.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return sanitiseWithBatch(conf.Kafka, conf.Kafka.Batching)
 		},
@@ -49,7 +70,13 @@ part.`,
 			docs.FieldAdvanced("ack_replicas", "Ensure that messages have been copied across all replicas before acknowledging receipt."),
 			docs.FieldAdvanced("max_msg_bytes", "The maximum size in bytes of messages sent to the target topic."),
 			docs.FieldAdvanced("timeout", "The maximum period of time to wait for message sends before abandoning the request and retrying."),
-			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use."),
+			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use. Transactions require a version of at least `0.11.0.0`."),
+			docs.FieldAdvanced("transaction", "Optionally write each message batch within a Kafka transaction.").WithChildren(
+				docs.FieldCommon("enabled", "Whether to write message batches within transactions."),
+				docs.FieldCommon("id", "A transactional ID that uniquely identifies this producer across restarts."),
+				docs.FieldCommon("timeout", "The maximum period of time a transaction may remain open before it is aborted by the brokers."),
+				docs.FieldCommon("consumer_group", "An optional consumer group of a `kafka_balanced` input, the offsets of consumed messages are committed within the same transaction when set."),
+			),
 			batch.FieldSpec(),
 		}, retries.FieldSpecs()...),
 	}
@@ -124,7 +124,8 @@ func (w *Writer) loop() {
 
 		w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
-		latency, err := w.latencyMeasuringWrite(ts.Payload)
+		payload := tracing.InjectSpans(spans, ts.Payload)
+		latency, err := w.latencyMeasuringWrite(payload)
 
 		// If our writer says it is not connected.
 		if err == types.ErrNotConnected {
@@ -144,7 +145,7 @@ func (w *Writer) loop() {
 					if !throt.Retry() {
 						return
 					}
-				} else if latency, err = w.latencyMeasuringWrite(ts.Payload); err != types.ErrNotConnected {
+				} else if latency, err = w.latencyMeasuringWrite(payload); err != types.ErrNotConnected {
 					atomic.StoreInt32(&w.isConnected, 1)
 					mConn.Incr(1)
 					break
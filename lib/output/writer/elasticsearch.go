@@ -38,13 +38,17 @@ type ElasticsearchConfig struct {
 	Sniff          bool                 `json:"sniff" yaml:"sniff"`
 	Healthcheck    bool                 `json:"healthcheck" yaml:"healthcheck"`
 	ID             string               `json:"id" yaml:"id"`
+	Action         string               `json:"action" yaml:"action"`
 	Index          string               `json:"index" yaml:"index"`
 	Pipeline       string               `json:"pipeline" yaml:"pipeline"`
+	Routing        string               `json:"routing" yaml:"routing"`
+	Script         string               `json:"script" yaml:"script"`
 	Type           string               `json:"type" yaml:"type"`
 	Timeout        string               `json:"timeout" yaml:"timeout"`
 	Auth           auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
 	AWS            OptionalAWSConfig    `json:"aws" yaml:"aws"`
 	MaxInFlight    int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	DropRejected   bool                 `json:"drop_rejected" yaml:"drop_rejected"`
 	retries.Config `json:",inline" yaml:",inline"`
 	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
@@ -64,8 +68,11 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 		Sniff:       true,
 		Healthcheck: true,
 		ID:          `${!count("elastic_ids")}-${!timestamp_unix()}`,
+		Action:      "index",
 		Index:       "benthos_index",
 		Pipeline:    "",
+		Routing:     "",
+		Script:      "",
 		Type:        "doc",
 		Timeout:     "5s",
 		Auth:        auth.NewBasicAuthConfig(),
@@ -73,9 +80,10 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 			Enabled: false,
 			Config:  sess.NewConfig(),
 		},
-		MaxInFlight: 1,
-		Config:      rConf,
-		Batching:    batching,
+		MaxInFlight:  1,
+		DropRejected: false,
+		Config:       rConf,
+		Batching:     batching,
 	}
 }
 
@@ -95,11 +103,15 @@ type Elasticsearch struct {
 	timeout time.Duration
 
 	idStr             field.Expression
+	actionStr         field.Expression
 	indexStr          field.Expression
 	pipelineStr       field.Expression
+	routingStr        field.Expression
 	interpolatedIndex bool
 
-	eJSONErr metrics.StatCounter
+	eJSONErr     metrics.StatCounter
+	eRejected    metrics.StatCounter
+	eRetryFailed metrics.StatCounter
 
 	client *elastic.Client
 }
@@ -113,18 +125,27 @@ func NewElasticsearch(conf ElasticsearchConfig, log log.Modular, stats metrics.T
 		sniff:       conf.Sniff,
 		healthcheck: conf.Healthcheck,
 		eJSONErr:    stats.GetCounter("error.json"),
+
+		eRejected:    stats.GetCounter("error.rejected"),
+		eRetryFailed: stats.GetCounter("error.retries_exhausted"),
 	}
 
 	var err error
 	if e.idStr, err = field.New(conf.ID); err != nil {
 		return nil, fmt.Errorf("failed to parse id expression: %v", err)
 	}
+	if e.actionStr, err = field.New(conf.Action); err != nil {
+		return nil, fmt.Errorf("failed to parse action expression: %v", err)
+	}
 	if e.indexStr, err = field.New(conf.Index); err != nil {
 		return nil, fmt.Errorf("failed to parse index expression: %v", err)
 	}
 	if e.pipelineStr, err = field.New(conf.Pipeline); err != nil {
 		return nil, fmt.Errorf("failed to parse pipeline expression: %v", err)
 	}
+	if e.routingStr, err = field.New(conf.Routing); err != nil {
+		return nil, fmt.Errorf("failed to parse routing expression: %v", err)
+	}
 
 	for _, u := range conf.URLs {
 		for _, splitURL := range strings.Split(u, ",") {
@@ -196,17 +217,67 @@ func (e *Elasticsearch) Connect() error {
 }
 
 func shouldRetry(s int) bool {
+	if s == http.StatusTooManyRequests {
+		return true
+	}
 	if s >= 500 && s <= 599 {
 		return true
 	}
 	return false
 }
 
-type pendingBulkIndex struct {
-	Index    string
-	Pipeline string
-	Type     string
-	Doc      interface{}
+// buildRequest creates a bulk request for a message part according to its
+// action.
+func (e *Elasticsearch) buildRequest(i int, msg types.Message) (elastic.BulkableRequest, error) {
+	action := e.actionStr.String(i, msg)
+	index := e.indexStr.String(i, msg)
+	routing := e.routingStr.String(i, msg)
+	id := e.idStr.String(i, msg)
+
+	if action == "delete" {
+		return elastic.NewBulkDeleteRequest().
+			Index(index).
+			Routing(routing).
+			Type(e.conf.Type).
+			Id(id), nil
+	}
+
+	doc, err := msg.Get(i).JSON()
+	if err != nil {
+		e.eJSONErr.Incr(1)
+		return nil, fmt.Errorf("failed to marshal message into JSON document: %v", err)
+	}
+
+	switch action {
+	case "index", "create":
+		return elastic.NewBulkIndexRequest().
+			OpType(action).
+			Index(index).
+			Pipeline(e.pipelineStr.String(i, msg)).
+			Routing(routing).
+			Type(e.conf.Type).
+			Id(id).
+			Doc(doc), nil
+	case "update", "upsert":
+		req := elastic.NewBulkUpdateRequest().
+			Index(index).
+			Routing(routing).
+			Type(e.conf.Type).
+			Id(id)
+		if len(e.conf.Script) == 0 {
+			return req.Doc(doc).DocAsUpsert(action == "upsert"), nil
+		}
+		params, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected JSON object for script params, found: %T", doc)
+		}
+		req = req.Script(elastic.NewScript(e.conf.Script).Params(params))
+		if action == "upsert" {
+			req = req.ScriptedUpsert(true).Upsert(map[string]interface{}{})
+		}
+		return req, nil
+	}
+	return nil, fmt.Errorf("action not recognised: %v", action)
 }
 
 // WriteWithContext will attempt to write a message to Elasticsearch, wait for
@@ -217,95 +288,94 @@ func (e *Elasticsearch) WriteWithContext(ctx context.Context, msg types.Message)
 
 // Write will attempt to write a message to Elasticsearch, wait for
 // acknowledgement, and returns an error if applicable.
+//
+// Messages of a batch that fail with a retryable status are retried
+// individually until the backoff policy is exhausted. If any messages of the
+// batch could not be delivered a *batch.Error is returned that describes which
+// of them failed, where messages that were rejected with a status that cannot
+// succeed if retried are recorded as rejected. When drop_rejected is enabled
+// the rejected messages are dropped instead, and a batch where every failed
+// message was rejected is considered delivered.
 func (e *Elasticsearch) Write(msg types.Message) error {
 	if e.client == nil {
 		return types.ErrNotConnected
 	}
 
-	if msg.Len() == 1 {
-		index := e.indexStr.String(0, msg)
-		_, err := e.client.Index().
-			Index(index).
-			Pipeline(e.pipelineStr.String(0, msg)).
-			Type(e.conf.Type).
-			Id(e.idStr.String(0, msg)).
-			BodyString(string(msg.Get(0).Get())).
-			Do(context.Background())
-		if err == nil {
-			// Flush to make sure the document got written.
-			_, err = e.client.Flush().Index(index).Do(context.Background())
-		}
-		return err
-	}
-
 	e.backoff.Reset()
 
-	requests := map[string]*pendingBulkIndex{}
-	msg.Iter(func(i int, part types.Part) error {
-		jObj, ierr := part.JSON()
-		if ierr != nil {
-			e.eJSONErr.Incr(1)
-			e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
-			return nil
-		}
-		requests[e.idStr.String(i, msg)] = &pendingBulkIndex{
-			Index:    e.indexStr.String(i, msg),
-			Pipeline: e.pipelineStr.String(i, msg),
-			Type:     e.conf.Type,
-			Doc:      jObj,
+	bErr := batch.NewError(msg)
+	requests := make([]elastic.BulkableRequest, msg.Len())
+	var pending []int
+	var retryFailed bool
+	for i := range requests {
+		req, err := e.buildRequest(i, msg)
+		if err != nil {
+			e.log.Errorf("Failed to create request for message: %v\n", err)
+			bErr.Rejected(i, err)
+			continue
 		}
-		return nil
-	})
-
-	b := e.client.Bulk()
-	for k, v := range requests {
-		b.Add(
-			elastic.NewBulkIndexRequest().
-				Index(v.Index).
-				Pipeline(v.Pipeline).
-				Type(v.Type).
-				Id(k).
-				Doc(v.Doc),
-		)
+		requests[i] = req
+		pending = append(pending, i)
 	}
 
-	for b.NumberOfActions() != 0 {
+	for len(pending) > 0 {
+		b := e.client.Bulk()
+		for _, i := range pending {
+			b.Add(requests[i])
+		}
+
 		result, err := b.Do(context.Background())
 		if err != nil {
 			return err
 		}
 
-		failed := result.Failed()
-		if len(failed) == 0 {
-			e.backoff.Reset()
-			continue
+		var retry []int
+		var retryErrs []error
+		for j, item := range result.Items {
+			if j >= len(pending) {
+				break
+			}
+			for _, res := range item {
+				if res.Error == nil {
+					continue
+				}
+				itemErr := fmt.Errorf("rejected with code [%v]: %v", res.Status, res.Error.Reason)
+				if !shouldRetry(res.Status) {
+					e.log.Errorf("Elasticsearch message '%v' rejected with code [%v]: %v\n", res.Id, res.Status, res.Error.Reason)
+					e.eRejected.Incr(1)
+					bErr.Rejected(pending[j], itemErr)
+				} else {
+					e.log.Errorf("Elasticsearch message '%v' failed with code [%v]: %v\n", res.Id, res.Status, res.Error.Reason)
+					retry = append(retry, pending[j])
+					retryErrs = append(retryErrs, itemErr)
+				}
+			}
+		}
+		if len(retry) == 0 {
+			break
 		}
 
 		wait := e.backoff.NextBackOff()
-		for i := 0; i < len(failed); i++ {
-			if !shouldRetry(failed[i].Status) {
-				e.log.Errorf("Elasticsearch message '%v' rejected with code [%s]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
-				return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
-			}
-			e.log.Errorf("Elasticsearch message '%v' failed with code [%s]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
-			id := failed[i].Id
-			req := requests[id]
-			b.Add(
-				elastic.NewBulkIndexRequest().
-					Index(req.Index).
-					Pipeline(req.Pipeline).
-					Type(req.Type).
-					Id(id).
-					Doc(req.Doc),
-			)
-		}
 		if wait == backoff.Stop {
-			return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
+			e.eRetryFailed.Incr(int64(len(retry)))
+			for k, i := range retry {
+				bErr.Failed(i, retryErrs[k])
+			}
+			retryFailed = true
+			break
 		}
 		time.Sleep(wait)
+		pending = retry
 	}
 
-	return nil
+	if bErr.IndexedErrors() == 0 {
+		return nil
+	}
+	if e.conf.DropRejected && !retryFailed {
+		e.log.Warnf("Dropping %v messages rejected by Elasticsearch\n", bErr.IndexedErrors())
+		return nil
+	}
+	return bErr
 }
 
 // CloseAsync shuts down the Elasticsearch writer and stops processing messages.
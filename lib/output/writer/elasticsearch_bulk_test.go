@@ -0,0 +1,277 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type esBulkAction struct {
+	Action string
+	Meta   map[string]interface{}
+	Doc    map[string]interface{}
+}
+
+// esBulkServer mocks the bulk endpoint of Elasticsearch, where the status of
+// each item is determined by a closure.
+func esBulkServer(t *testing.T, statusFn func(call int, a esBulkAction) int) (*httptest.Server, func() [][]esBulkAction) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var calls [][]esBulkAction
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		var actions []esBulkAction
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			for k, v := range line {
+				a := esBulkAction{Action: k, Meta: v}
+				if k != "delete" {
+					require.True(t, scanner.Scan())
+					require.NoError(t, json.Unmarshal(scanner.Bytes(), &a.Doc))
+				}
+				actions = append(actions, a)
+			}
+		}
+
+		mut.Lock()
+		call := len(calls)
+		calls = append(calls, actions)
+		mut.Unlock()
+
+		items := []interface{}{}
+		for _, a := range actions {
+			item := map[string]interface{}{
+				"_index": a.Meta["_index"],
+				"_id":    a.Meta["_id"],
+			}
+			status := statusFn(call, a)
+			item["status"] = status
+			if status >= 300 {
+				item["error"] = map[string]interface{}{
+					"type":   "test_error",
+					"reason": "test reason",
+				}
+			}
+			items = append(items, map[string]interface{}{a.Action: item})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"took":   1,
+			"errors": true,
+			"items":  items,
+		})
+	}))
+
+	return server, func() [][]esBulkAction {
+		mut.Lock()
+		defer mut.Unlock()
+		return calls
+	}
+}
+
+func esTestWriter(t *testing.T, url string, fn func(c *ElasticsearchConfig)) *Elasticsearch {
+	t.Helper()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{url}
+	conf.Sniff = false
+	conf.Healthcheck = false
+	conf.ID = `${!meta("id")}`
+	conf.Action = `${!meta("action")}`
+	conf.Routing = `${!meta("routing")}`
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	conf.Backoff.MaxElapsedTime = "100ms"
+	if fn != nil {
+		fn(&conf)
+	}
+
+	e, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, e.Connect())
+	return e
+}
+
+func TestElasticsearchBulkActions(t *testing.T) {
+	server, calls := esBulkServer(t, func(call int, a esBulkAction) int {
+		switch a.Meta["_id"] {
+		case "c":
+			if call == 0 {
+				return http.StatusTooManyRequests
+			}
+		case "d":
+			return http.StatusConflict
+		}
+		return http.StatusOK
+	})
+	defer server.Close()
+
+	e := esTestWriter(t, server.URL, nil)
+
+	msg := message.New([][]byte{
+		[]byte(`{"a":"foo"}`),
+		[]byte(`{}`),
+		[]byte(`{"c":"bar"}`),
+		[]byte(`{"d":"baz"}`),
+	})
+	msg.Get(0).Metadata().Set("id", "a").Set("action", "index").Set("routing", "r1")
+	msg.Get(1).Metadata().Set("id", "b").Set("action", "delete")
+	msg.Get(2).Metadata().Set("id", "c").Set("action", "upsert")
+	msg.Get(3).Metadata().Set("id", "d").Set("action", "create")
+
+	err := e.Write(msg)
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	var failed []int
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+			assert.True(t, batch.IsRejected(err), "%T", err)
+		}
+		return true
+	})
+	assert.Equal(t, []int{3}, failed)
+
+	allCalls := calls()
+	require.Len(t, allCalls, 2)
+
+	first := allCalls[0]
+	require.Len(t, first, 4)
+
+	assert.Equal(t, "index", first[0].Action)
+	assert.Equal(t, "r1", first[0].Meta["routing"])
+	assert.Equal(t, map[string]interface{}{"a": "foo"}, first[0].Doc)
+
+	assert.Equal(t, "delete", first[1].Action)
+	assert.Nil(t, first[1].Meta["routing"])
+
+	assert.Equal(t, "update", first[2].Action)
+	assert.Equal(t, map[string]interface{}{
+		"doc":           map[string]interface{}{"c": "bar"},
+		"doc_as_upsert": true,
+	}, first[2].Doc)
+
+	assert.Equal(t, "create", first[3].Action)
+
+	// Only the message that failed with a retryable status is sent again.
+	second := allCalls[1]
+	require.Len(t, second, 1)
+	assert.Equal(t, "c", second[0].Meta["_id"])
+}
+
+func TestElasticsearchScriptUpsert(t *testing.T) {
+	server, calls := esBulkServer(t, func(call int, a esBulkAction) int {
+		return http.StatusOK
+	})
+	defer server.Close()
+
+	e := esTestWriter(t, server.URL, func(c *ElasticsearchConfig) {
+		c.Script = "ctx._source.count += params.count"
+	})
+
+	msg := message.New([][]byte{
+		[]byte(`{"count":5}`),
+		[]byte(`{"count":3}`),
+		[]byte(`not json`),
+		[]byte(`{}`),
+	})
+	msg.Get(0).Metadata().Set("id", "a").Set("action", "upsert")
+	msg.Get(1).Metadata().Set("id", "b").Set("action", "update")
+	msg.Get(2).Metadata().Set("id", "c").Set("action", "update")
+	msg.Get(3).Metadata().Set("id", "d").Set("action", "nope")
+
+	err := e.Write(msg)
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+
+	allCalls := calls()
+	require.Len(t, allCalls, 1)
+	require.Len(t, allCalls[0], 2)
+
+	assert.Equal(t, map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": "ctx._source.count += params.count",
+			"params": map[string]interface{}{"count": float64(5)},
+		},
+		"scripted_upsert": true,
+		"upsert":          map[string]interface{}{},
+	}, allCalls[0][0].Doc)
+
+	assert.Equal(t, map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": "ctx._source.count += params.count",
+			"params": map[string]interface{}{"count": float64(3)},
+		},
+	}, allCalls[0][1].Doc)
+}
+
+func TestElasticsearchDropRejected(t *testing.T) {
+	server, _ := esBulkServer(t, func(call int, a esBulkAction) int {
+		switch a.Meta["_id"] {
+		case "rejected":
+			return http.StatusBadRequest
+		case "unavailable":
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	defer server.Close()
+
+	newMsg := func(ids ...string) types.Message {
+		msg := message.New(nil)
+		for _, id := range ids {
+			p := message.NewPart([]byte(`{}`))
+			p.Metadata().Set("id", id).Set("action", "index")
+			msg.Append(p)
+		}
+		return msg
+	}
+
+	// Rejected messages are returned as errors by default.
+	e := esTestWriter(t, server.URL, nil)
+	err := e.Write(newMsg("a", "rejected"))
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	e = esTestWriter(t, server.URL, func(c *ElasticsearchConfig) {
+		c.DropRejected = true
+	})
+	assert.NoError(t, e.Write(newMsg("a", "rejected")))
+
+	// Messages that exhausted their retries are never dropped.
+	err = e.Write(newMsg("a", "rejected", "unavailable"))
+	require.Error(t, err)
+
+	bErr, ok = err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+}
@@ -3,6 +3,7 @@ package writer
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -39,7 +40,8 @@ type KafkaConfig struct {
 	SASL           sasl.Config `json:"sasl" yaml:"sasl"`
 	MaxInFlight    int         `json:"max_in_flight" yaml:"max_in_flight"`
 	retries.Config `json:",inline" yaml:",inline"`
-	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Batching       batch.PolicyConfig     `json:"batching" yaml:"batching"`
+	Transaction    KafkaTransactionConfig `json:"transaction" yaml:"transaction"`
 
 	// TODO: V4 remove this.
 	RoundRobinPartitions bool `json:"round_robin_partitions" yaml:"round_robin_partitions"`
@@ -70,6 +72,7 @@ func NewKafkaConfig() KafkaConfig {
 		MaxInFlight:          1,
 		Config:               rConf,
 		Batching:             batching,
+		Transaction:          NewKafkaTransactionConfig(),
 	}
 }
 
@@ -96,6 +99,8 @@ type Kafka struct {
 	topic field.Expression
 
 	producer    sarama.SyncProducer
+	txnProducer *kafkaTxnProducer
+	txnTimeout  time.Duration
 	compression sarama.CompressionCodec
 	partitioner sarama.PartitionerConstructor
 
@@ -154,6 +159,18 @@ func NewKafka(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metric
 		return nil, err
 	}
 
+	if conf.Transaction.Enabled {
+		if len(conf.Transaction.ID) == 0 {
+			return nil, errors.New("a transaction id must be specified when transactions are enabled")
+		}
+		if !k.version.IsAtLeast(sarama.V0_11_0_0) {
+			return nil, errKafkaTxnVersion
+		}
+		if k.txnTimeout, err = time.ParseDuration(conf.Transaction.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction timeout string: %v", err)
+		}
+	}
+
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
 			if trimmed := strings.TrimSpace(splitAddr); len(trimmed) > 0 {
@@ -236,7 +253,7 @@ func (k *Kafka) Connect() error {
 	k.connMut.Lock()
 	defer k.connMut.Unlock()
 
-	if k.producer != nil {
+	if k.producer != nil || k.txnProducer != nil {
 		return nil
 	}
 
@@ -266,7 +283,23 @@ func (k *Kafka) Connect() error {
 	}
 
 	var err error
-	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
+	if k.conf.Transaction.Enabled {
+		// Transactions require idempotent writes, which are only guaranteed
+		// when all in sync replicas acknowledge each write.
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+
+		var client sarama.Client
+		if client, err = sarama.NewClient(k.addresses, config); err == nil {
+			k.txnProducer = newKafkaTxnProducer(
+				client, k.conf.Transaction, k.txnTimeout, k.timeout,
+				k.compression, k.partitioner, k.log,
+			)
+		}
+	} else {
+		k.producer, err = sarama.NewSyncProducer(k.addresses, config)
+	}
 
 	if err == nil {
 		k.log.Infof("Sending Kafka messages to addresses: %s\n", k.addresses)
@@ -285,10 +318,11 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 func (k *Kafka) Write(msg types.Message) error {
 	k.connMut.RLock()
 	producer := k.producer
+	txnProducer := k.txnProducer
 	version := k.version
 	k.connMut.RUnlock()
 
-	if producer == nil {
+	if producer == nil && txnProducer == nil {
 		return types.ErrNotConnected
 	}
 
@@ -307,6 +341,10 @@ func (k *Kafka) Write(msg types.Message) error {
 		return nil
 	})
 
+	if txnProducer != nil {
+		return k.writeTransaction(txnProducer, msgs, kafkaTxnOffsets(msg))
+	}
+
 	err := producer.SendMessages(msgs)
 	for err != nil {
 		pErrs, ok := err.(sarama.ProducerErrors)
@@ -345,6 +383,39 @@ func (k *Kafka) Write(msg types.Message) error {
 	return nil
 }
 
+// writeTransaction attempts to write a slice of messages within a single
+// transaction, which is retried in its entirety on failure unless the producer
+// has been fenced.
+func (k *Kafka) writeTransaction(txnProducer *kafkaTxnProducer, msgs []*sarama.ProducerMessage, offsets map[string]map[int32]int64) error {
+	err := txnProducer.Send(msgs, offsets)
+	for err != nil {
+		if err == errKafkaTxnFenced {
+			return err
+		}
+		k.log.Errorf("Failed to send messages within transaction: %v\n", err)
+
+		tNext := k.backoff.NextBackOff()
+		if tNext == backoff.Stop {
+			k.backoff.Reset()
+			return err
+		}
+		<-time.After(tNext)
+
+		// Recheck connection is alive
+		k.connMut.RLock()
+		txnProducer = k.txnProducer
+		k.connMut.RUnlock()
+
+		if txnProducer == nil {
+			return types.ErrNotConnected
+		}
+		err = txnProducer.Send(msgs, offsets)
+	}
+
+	k.backoff.Reset()
+	return nil
+}
+
 // CloseAsync shuts down the Kafka writer and stops processing messages.
 func (k *Kafka) CloseAsync() {
 	go func() {
@@ -353,6 +424,10 @@ func (k *Kafka) CloseAsync() {
 			k.producer.Close()
 			k.producer = nil
 		}
+		if nil != k.txnProducer {
+			k.txnProducer.Close()
+			k.txnProducer = nil
+		}
 		k.connMut.Unlock()
 	}()
 }
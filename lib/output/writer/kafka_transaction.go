@@ -0,0 +1,513 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+// KafkaTransactionConfig contains configuration fields for writing messages to
+// Kafka within transactions.
+type KafkaTransactionConfig struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	ID            string `json:"id" yaml:"id"`
+	Timeout       string `json:"timeout" yaml:"timeout"`
+	ConsumerGroup string `json:"consumer_group" yaml:"consumer_group"`
+}
+
+// NewKafkaTransactionConfig creates a new KafkaTransactionConfig with default
+// values.
+func NewKafkaTransactionConfig() KafkaTransactionConfig {
+	return KafkaTransactionConfig{
+		Enabled:       false,
+		ID:            "",
+		Timeout:       "60s",
+		ConsumerGroup: "",
+	}
+}
+
+var errKafkaTxnVersion = errors.New("transactions require a target_version of at least 0.11.0.0")
+
+var errKafkaTxnFenced = errors.New("transactional producer has been fenced by a newer producer with the same transaction id")
+
+// kafkaErrProducerFenced is the PRODUCER_FENCED error code returned by brokers
+// from version 2.7.0 onwards, which is not yet known to sarama. Older brokers
+// return INVALID_PRODUCER_EPOCH instead, which is also returned once the
+// coordinator has aborted a transaction that exceeded its timeout, and is
+// therefore not treated as fencing.
+const kafkaErrProducerFenced sarama.KError = 90
+
+//------------------------------------------------------------------------------
+
+// kafkaTxnOffsets extracts the offsets to commit for each topic partition
+// consumed in order to create a message batch, which are read from the
+// metadata fields added by Kafka inputs. The offset committed for a partition
+// is the offset following the highest offset consumed.
+func kafkaTxnOffsets(msg types.Message) map[string]map[int32]int64 {
+	offsets := map[string]map[int32]int64{}
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		topic := meta.Get("kafka_topic")
+		if len(topic) == 0 {
+			return nil
+		}
+		partition, err := strconv.ParseInt(meta.Get("kafka_partition"), 10, 32)
+		if err != nil {
+			return nil
+		}
+		offset, err := strconv.ParseInt(meta.Get("kafka_offset"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		partitions, exists := offsets[topic]
+		if !exists {
+			partitions = map[int32]int64{}
+			offsets[topic] = partitions
+		}
+		if existing, exists := partitions[int32(partition)]; !exists || existing < offset+1 {
+			partitions[int32(partition)] = offset + 1
+		}
+		return nil
+	})
+	return offsets
+}
+
+//------------------------------------------------------------------------------
+
+// kafkaTxnProducer writes batches of messages to Kafka, where each batch is
+// written within a single transaction of an idempotent producer. Consumer
+// group offsets can optionally be committed as part of the same transaction,
+// which allows consumed messages to be written exactly once.
+type kafkaTxnProducer struct {
+	client        sarama.Client
+	conf          KafkaTransactionConfig
+	txnTimeout    time.Duration
+	timeout       time.Duration
+	compression   sarama.CompressionCodec
+	partitionerFn sarama.PartitionerConstructor
+	log           log.Modular
+
+	mut           sync.Mutex
+	fenced        bool
+	coordinator   *sarama.Broker
+	producerID    int64
+	producerEpoch int16
+	sequences     map[string]map[int32]int32
+	partitioners  map[string]sarama.Partitioner
+}
+
+func newKafkaTxnProducer(
+	client sarama.Client,
+	conf KafkaTransactionConfig,
+	txnTimeout, timeout time.Duration,
+	compression sarama.CompressionCodec,
+	partitionerFn sarama.PartitionerConstructor,
+	log log.Modular,
+) *kafkaTxnProducer {
+	return &kafkaTxnProducer{
+		client:        client,
+		conf:          conf,
+		txnTimeout:    txnTimeout,
+		timeout:       timeout,
+		compression:   compression,
+		partitionerFn: partitionerFn,
+		log:           log,
+		partitioners:  map[string]sarama.Partitioner{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// kErr converts the error code of a response into an error. An error code
+// signalling that a newer producer with the same transactional ID has been
+// initialised marks the producer as fenced, as any further attempts to write
+// would either fail or, after initialising again, fence off the newer
+// producer in turn.
+func (p *kafkaTxnProducer) kErr(err sarama.KError) error {
+	if err == sarama.ErrNoError {
+		return nil
+	}
+	if err == kafkaErrProducerFenced {
+		p.fenced = true
+	}
+	return err
+}
+
+// init locates the transaction coordinator and obtains a producer ID and
+// epoch. Initialising the producer also fences off any previous producer with
+// the same transactional ID and aborts any transaction it left open.
+func (p *kafkaTxnProducer) init() error {
+	if p.coordinator != nil {
+		return nil
+	}
+
+	controller, err := p.client.Controller()
+	if err != nil {
+		return fmt.Errorf("failed to obtain broker: %v", err)
+	}
+
+	findRes, err := controller.FindCoordinator(&sarama.FindCoordinatorRequest{
+		Version:         1,
+		CoordinatorKey:  p.conf.ID,
+		CoordinatorType: sarama.CoordinatorTransaction,
+	})
+	if err == nil {
+		err = p.kErr(findRes.Err)
+	}
+	if err != nil {
+		// The cached controller may have left the cluster, in which case the
+		// next attempt should use a fresh one.
+		if _, rerr := p.client.RefreshController(); rerr != nil {
+			p.log.Debugf("Failed to refresh controller: %v\n", rerr)
+		}
+		return fmt.Errorf("failed to find transaction coordinator: %v", err)
+	}
+
+	coordinator := findRes.Coordinator
+	if err = coordinator.Open(p.client.Config()); err != nil && err != sarama.ErrAlreadyConnected {
+		return fmt.Errorf("failed to connect to transaction coordinator: %v", err)
+	}
+
+	txnID := p.conf.ID
+	initRes, err := coordinator.InitProducerID(&sarama.InitProducerIDRequest{
+		TransactionalID:    &txnID,
+		TransactionTimeout: p.txnTimeout,
+	})
+	if err == nil {
+		err = p.kErr(initRes.Err)
+	}
+	if err != nil {
+		coordinator.Close()
+		return fmt.Errorf("failed to initialise transactional producer: %v", err)
+	}
+
+	p.coordinator = coordinator
+	p.producerID = initRes.ProducerID
+	p.producerEpoch = initRes.ProducerEpoch
+	p.sequences = map[string]map[int32]int32{}
+
+	p.log.Debugf("Initialised transactional producer with ID %v and epoch %v\n", p.producerID, p.producerEpoch)
+	return nil
+}
+
+// reset closes the connection to the transaction coordinator, after which
+// the producer is initialised again with a new epoch. This is required after
+// a failed transaction as the sequence numbers of partitions can no longer be
+// relied upon.
+func (p *kafkaTxnProducer) reset() {
+	if p.coordinator != nil {
+		p.coordinator.Close()
+		p.coordinator = nil
+	}
+}
+
+// refresh updates the metadata of the topics written to and of the consumer
+// group, since a failed transaction might have been caused by partition
+// leaders or coordinators moving to other brokers.
+func (p *kafkaTxnProducer) refresh(grouped map[string]map[int32][]*sarama.ProducerMessage) {
+	topics := make([]string, 0, len(grouped))
+	for topic := range grouped {
+		topics = append(topics, topic)
+	}
+	if err := p.client.RefreshMetadata(topics...); err != nil {
+		p.log.Debugf("Failed to refresh metadata: %v\n", err)
+	}
+	if len(p.conf.ConsumerGroup) > 0 {
+		if err := p.client.RefreshCoordinator(p.conf.ConsumerGroup); err != nil {
+			p.log.Debugf("Failed to refresh consumer group coordinator: %v\n", err)
+		}
+	}
+}
+
+func (p *kafkaTxnProducer) partition(msg *sarama.ProducerMessage) error {
+	partitions, err := p.client.Partitions(msg.Topic)
+	if err != nil {
+		return fmt.Errorf("failed to obtain partitions of topic '%v': %v", msg.Topic, err)
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("topic '%v' has no partitions", msg.Topic)
+	}
+	partitioner, exists := p.partitioners[msg.Topic]
+	if !exists {
+		partitioner = p.partitionerFn(msg.Topic)
+		p.partitioners[msg.Topic] = partitioner
+	}
+	choice, err := partitioner.Partition(msg, int32(len(partitions)))
+	if err != nil {
+		return err
+	}
+	if choice < 0 || int(choice) >= len(partitions) {
+		return fmt.Errorf("partitioner chose an invalid partition: %v", choice)
+	}
+	msg.Partition = partitions[choice]
+	return nil
+}
+
+func (p *kafkaTxnProducer) recordBatch(topic string, partition int32, msgs []*sarama.ProducerMessage) (*sarama.RecordBatch, error) {
+	now := time.Now().Truncate(time.Millisecond)
+	batch := &sarama.RecordBatch{
+		Version:         2,
+		Codec:           p.compression,
+		FirstTimestamp:  now,
+		MaxTimestamp:    now,
+		ProducerID:      p.producerID,
+		ProducerEpoch:   p.producerEpoch,
+		FirstSequence:   p.sequences[topic][partition],
+		IsTransactional: true,
+		LastOffsetDelta: int32(len(msgs) - 1),
+	}
+	for i, msg := range msgs {
+		rec := &sarama.Record{
+			OffsetDelta: int64(i),
+		}
+		var err error
+		if msg.Key != nil {
+			if rec.Key, err = msg.Key.Encode(); err != nil {
+				return nil, err
+			}
+		}
+		if msg.Value != nil {
+			if rec.Value, err = msg.Value.Encode(); err != nil {
+				return nil, err
+			}
+		}
+		for j := range msg.Headers {
+			rec.Headers = append(rec.Headers, &msg.Headers[j])
+		}
+		batch.Records = append(batch.Records, rec)
+	}
+	return batch, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Send writes a slice of messages to Kafka within a single transaction, along
+// with consumer group offsets when a consumer group is configured. Either all
+// messages and offsets are committed or the transaction is aborted and an
+// error is returned. Once the producer has been fenced errKafkaTxnFenced is
+// returned for all further calls.
+func (p *kafkaTxnProducer) Send(msgs []*sarama.ProducerMessage, offsets map[string]map[int32]int64) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.fenced {
+		return errKafkaTxnFenced
+	}
+	if err := p.init(); err != nil {
+		return err
+	}
+
+	grouped := map[string]map[int32][]*sarama.ProducerMessage{}
+	for _, msg := range msgs {
+		if err := p.partition(msg); err != nil {
+			return err
+		}
+		partitions, exists := grouped[msg.Topic]
+		if !exists {
+			partitions = map[int32][]*sarama.ProducerMessage{}
+			grouped[msg.Topic] = partitions
+		}
+		partitions[msg.Partition] = append(partitions[msg.Partition], msg)
+	}
+
+	err := p.send(grouped, offsets)
+	if err == nil {
+		err = p.endTxn(true)
+	}
+	if err != nil {
+		if p.fenced {
+			p.log.Errorf("Transactional producer has been fenced: %v\n", err)
+			p.reset()
+			return errKafkaTxnFenced
+		}
+		if aerr := p.endTxn(false); aerr != nil {
+			p.log.Debugf("Failed to abort transaction: %v\n", aerr)
+		}
+		p.reset()
+		p.refresh(grouped)
+		return err
+	}
+
+	for topic, partitions := range grouped {
+		seqs, exists := p.sequences[topic]
+		if !exists {
+			seqs = map[int32]int32{}
+			p.sequences[topic] = seqs
+		}
+		for partition, pMsgs := range partitions {
+			seqs[partition] += int32(len(pMsgs))
+		}
+	}
+	return nil
+}
+
+// send adds partitions to the current transaction and writes messages and
+// offsets to them without ending the transaction.
+func (p *kafkaTxnProducer) send(grouped map[string]map[int32][]*sarama.ProducerMessage, offsets map[string]map[int32]int64) error {
+	txnID := p.conf.ID
+
+	topicPartitions := map[string][]int32{}
+	for topic, partitions := range grouped {
+		for partition := range partitions {
+			topicPartitions[topic] = append(topicPartitions[topic], partition)
+		}
+	}
+	if len(topicPartitions) > 0 {
+		addRes, err := p.coordinator.AddPartitionsToTxn(&sarama.AddPartitionsToTxnRequest{
+			TransactionalID: txnID,
+			ProducerID:      p.producerID,
+			ProducerEpoch:   p.producerEpoch,
+			TopicPartitions: topicPartitions,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add partitions to transaction: %v", err)
+		}
+		for topic, pErrs := range addRes.Errors {
+			for _, pErr := range pErrs {
+				if err = p.kErr(pErr.Err); err != nil {
+					return fmt.Errorf("failed to add topic '%v' partition '%v' to transaction: %v", topic, pErr.Partition, err)
+				}
+			}
+		}
+	}
+
+	requests := map[*sarama.Broker]*sarama.ProduceRequest{}
+	for topic, partitions := range grouped {
+		for partition, pMsgs := range partitions {
+			leader, err := p.client.Leader(topic, partition)
+			if err != nil {
+				return fmt.Errorf("failed to obtain leader of topic '%v' partition '%v': %v", topic, partition, err)
+			}
+			req, exists := requests[leader]
+			if !exists {
+				req = &sarama.ProduceRequest{
+					TransactionalID: &txnID,
+					RequiredAcks:    sarama.WaitForAll,
+					Timeout:         int32(p.timeout / time.Millisecond),
+					Version:         3,
+				}
+				requests[leader] = req
+			}
+			batch, err := p.recordBatch(topic, partition, pMsgs)
+			if err != nil {
+				return err
+			}
+			req.AddBatch(topic, partition, batch)
+		}
+	}
+
+	for leader, req := range requests {
+		res, err := leader.Produce(req)
+		if err != nil {
+			return fmt.Errorf("failed to produce messages: %v", err)
+		}
+		for topic, partitions := range grouped {
+			for partition := range partitions {
+				if l, _ := p.client.Leader(topic, partition); l != leader {
+					continue
+				}
+				block := res.GetBlock(topic, partition)
+				if block == nil {
+					return fmt.Errorf("missing produce response for topic '%v' partition '%v'", topic, partition)
+				}
+				if err = p.kErr(block.Err); err != nil {
+					return fmt.Errorf("failed to produce messages to topic '%v' partition '%v': %v", topic, partition, err)
+				}
+			}
+		}
+	}
+
+	if len(p.conf.ConsumerGroup) > 0 && len(offsets) > 0 {
+		if err := p.sendOffsets(offsets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endTxn either commits or aborts the current transaction.
+func (p *kafkaTxnProducer) endTxn(commit bool) error {
+	endRes, err := p.coordinator.EndTxn(&sarama.EndTxnRequest{
+		TransactionalID:   p.conf.ID,
+		ProducerID:        p.producerID,
+		ProducerEpoch:     p.producerEpoch,
+		TransactionResult: commit,
+	})
+	if err == nil {
+		err = p.kErr(endRes.Err)
+	}
+	if err != nil {
+		if commit {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+		return fmt.Errorf("failed to abort transaction: %v", err)
+	}
+	return nil
+}
+
+func (p *kafkaTxnProducer) sendOffsets(offsets map[string]map[int32]int64) error {
+	txnID := p.conf.ID
+
+	addRes, err := p.coordinator.AddOffsetsToTxn(&sarama.AddOffsetsToTxnRequest{
+		TransactionalID: txnID,
+		ProducerID:      p.producerID,
+		ProducerEpoch:   p.producerEpoch,
+		GroupID:         p.conf.ConsumerGroup,
+	})
+	if err == nil {
+		err = p.kErr(addRes.Err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add offsets to transaction: %v", err)
+	}
+
+	groupCoordinator, err := p.client.Coordinator(p.conf.ConsumerGroup)
+	if err != nil {
+		return fmt.Errorf("failed to obtain consumer group coordinator: %v", err)
+	}
+
+	topics := map[string][]*sarama.PartitionOffsetMetadata{}
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			topics[topic] = append(topics[topic], &sarama.PartitionOffsetMetadata{
+				Partition: partition,
+				Offset:    offset,
+			})
+		}
+	}
+
+	commitRes, err := groupCoordinator.TxnOffsetCommit(&sarama.TxnOffsetCommitRequest{
+		TransactionalID: txnID,
+		GroupID:         p.conf.ConsumerGroup,
+		ProducerID:      p.producerID,
+		ProducerEpoch:   p.producerEpoch,
+		Topics:          topics,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets: %v", err)
+	}
+	for topic, pErrs := range commitRes.Topics {
+		for _, pErr := range pErrs {
+			if err = p.kErr(pErr.Err); err != nil {
+				return fmt.Errorf("failed to commit offset of topic '%v' partition '%v': %v", topic, pErr.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Close the producer and its underlying client.
+func (p *kafkaTxnProducer) Close() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.reset()
+	return p.client.Close()
+}
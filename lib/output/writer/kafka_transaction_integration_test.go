@@ -0,0 +1,308 @@
+// +build integration
+
+package writer
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func kafkaTxnFreePort(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func newKafkaTxnIntegrationWriter(t *testing.T, addrs []string, topic string) *Kafka {
+	t.Helper()
+
+	conf := NewKafkaConfig()
+	conf.Addresses = addrs
+	conf.Topic = topic
+	conf.TargetVersion = "2.1.0"
+	conf.Backoff.MaxElapsedTime = "2m"
+	conf.Transaction.Enabled = true
+	conf.Transaction.ID = topic + "_txn"
+	conf.Transaction.ConsumerGroup = topic + "_group"
+
+	w, err := NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect())
+	return w
+}
+
+// writeKafkaTxnOpen writes a message within a transaction that is left open,
+// as if the producer had crashed before committing it.
+func writeKafkaTxnOpen(t *testing.T, w *Kafka, topic, content string) {
+	t.Helper()
+
+	p := w.txnProducer
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	require.NoError(t, p.init())
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(content),
+	}
+	require.NoError(t, p.partition(msg))
+	require.NoError(t, p.send(map[string]map[int32][]*sarama.ProducerMessage{
+		topic: {msg.Partition: {msg}},
+	}, nil))
+}
+
+func readKafkaTxnTopic(t *testing.T, addrs []string, topic string, isolation sarama.IsolationLevel, expected int) []string {
+	t.Helper()
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+	config.Consumer.IsolationLevel = isolation
+
+	consumer, err := sarama.NewConsumer(addrs, config)
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	partConsumer, err := consumer.ConsumePartition(topic, 0, sarama.OffsetOldest)
+	require.NoError(t, err)
+	defer partConsumer.Close()
+
+	// Keep reading for a while after the expected messages have arrived in
+	// order to catch any duplicates.
+	var contents []string
+	timeout := time.After(time.Second * 30)
+	for {
+		select {
+		case msg := <-partConsumer.Messages():
+			contents = append(contents, string(msg.Value))
+			if len(contents) == expected {
+				timeout = time.After(time.Second)
+			}
+		case <-timeout:
+			return contents
+		}
+	}
+}
+
+func kafkaTxnCoordinatorID(t *testing.T, w *Kafka) int32 {
+	t.Helper()
+
+	p := w.txnProducer
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	require.NoError(t, p.init())
+	return p.coordinator.ID()
+}
+
+func kafkaTxnCommittedOffset(t *testing.T, addrs []string, group, topic string) int64 {
+	t.Helper()
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+
+	client, err := sarama.NewClient(addrs, config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	coordinator, err := client.Coordinator(group)
+	require.NoError(t, err)
+
+	req := &sarama.OffsetFetchRequest{Version: 1, ConsumerGroup: group}
+	req.AddPartition(topic, 0)
+	res, err := coordinator.FetchOffset(req)
+	require.NoError(t, err)
+
+	block := res.GetBlock(topic, 0)
+	require.NotNil(t, block)
+	require.Equal(t, sarama.ErrNoError, block.Err)
+	return block.Offset
+}
+
+// TestKafkaTransactionIntegration runs the transactional producer against a
+// cluster of two brokers, where all internal topics are replicated across
+// both brokers so that transaction coordinators and partition leaders are
+// able to move when a broker is stopped.
+func TestKafkaTransactionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Minute * 2
+
+	networks, _ := pool.Client.ListNetworks()
+	hostIP := ""
+	for _, network := range networks {
+		if network.Name == "bridge" {
+			hostIP = network.IPAM.Config[0].Gateway
+		}
+	}
+
+	zkResource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "wurstmeister/zookeeper",
+		Tag:        "latest",
+	})
+	if err != nil {
+		t.Fatalf("Could not start zookeeper resource: %s", err)
+	}
+	defer func() {
+		if err = pool.Purge(zkResource); err != nil {
+			t.Logf("Failed to clean up zookeeper docker resource: %v", err)
+		}
+	}()
+	zkResource.Expire(900)
+	zkAddr := fmt.Sprintf("%v:2181", zkResource.Container.NetworkSettings.IPAddress)
+
+	var addrs []string
+	brokers := map[int32]*dockertest.Resource{}
+	for id := int32(1); id <= 2; id++ {
+		port := kafkaTxnFreePort(t)
+		resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+			Repository:   "wurstmeister/kafka",
+			Tag:          "latest",
+			ExposedPorts: []string{port + "/tcp"},
+			PortBindings: map[docker.Port][]docker.PortBinding{
+				docker.Port(port + "/tcp"): {{HostIP: "", HostPort: port}},
+			},
+			Env: []string{
+				fmt.Sprintf("KAFKA_BROKER_ID=%v", id),
+				"KAFKA_LISTENERS=PLAINTEXT://:" + port,
+				"KAFKA_ADVERTISED_LISTENERS=PLAINTEXT://" + hostIP + ":" + port,
+				"KAFKA_ZOOKEEPER_CONNECT=" + zkAddr,
+				"KAFKA_DEFAULT_REPLICATION_FACTOR=2",
+				"KAFKA_MIN_INSYNC_REPLICAS=1",
+				"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR=2",
+				"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR=2",
+				"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR=1",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Could not start kafka resource: %s", err)
+		}
+		defer func() {
+			if err = pool.Purge(resource); err != nil {
+				t.Logf("Failed to clean up kafka docker resource: %v", err)
+			}
+		}()
+		resource.Expire(900)
+
+		brokers[id] = resource
+		addrs = append(addrs, hostIP+":"+port)
+	}
+
+	if err = pool.Retry(func() error {
+		config := sarama.NewConfig()
+		config.Version = sarama.V2_1_0_0
+		client, cerr := sarama.NewClient(addrs, config)
+		if cerr != nil {
+			return cerr
+		}
+		defer client.Close()
+		if n := len(client.Brokers()); n != len(brokers) {
+			return fmt.Errorf("expected %v brokers, found %v", len(brokers), n)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Could not connect to docker resource: %s", err)
+	}
+
+	t.Run("commit and abort", func(t *testing.T) {
+		topic := "txn_commit_abort"
+
+		w := newKafkaTxnIntegrationWriter(t, addrs, topic)
+		defer w.CloseAsync()
+
+		msg := message.New([][]byte{[]byte("a")})
+		msg.Get(0).Metadata().Set("kafka_topic", topic).Set("kafka_partition", "0").Set("kafka_offset", "0")
+		require.NoError(t, w.Write(msg))
+
+		id, epoch := w.txnProducer.producerID, w.txnProducer.producerEpoch
+
+		// Initialising the producer again after a crash bumps the epoch and
+		// aborts the transaction left open.
+		writeKafkaTxnOpen(t, w, topic, "open")
+		w.txnProducer.reset()
+
+		msg = message.New([][]byte{[]byte("b")})
+		msg.Get(0).Metadata().Set("kafka_topic", topic).Set("kafka_partition", "0").Set("kafka_offset", "1")
+		require.NoError(t, w.Write(msg))
+
+		assert.Equal(t, id, w.txnProducer.producerID)
+		assert.Equal(t, epoch+1, w.txnProducer.producerEpoch)
+
+		assert.Equal(t, []string{"a", "b"}, readKafkaTxnTopic(t, addrs, topic, sarama.ReadCommitted, 2))
+		assert.Equal(t, []string{"a", "open", "b"}, readKafkaTxnTopic(t, addrs, topic, sarama.ReadUncommitted, 3))
+		assert.Equal(t, int64(2), kafkaTxnCommittedOffset(t, addrs, topic+"_group", topic))
+	})
+
+	t.Run("fencing", func(t *testing.T) {
+		topic := "txn_fencing"
+
+		first := newKafkaTxnIntegrationWriter(t, addrs, topic)
+		defer first.CloseAsync()
+
+		require.NoError(t, first.Write(message.New([][]byte{[]byte("a")})))
+		writeKafkaTxnOpen(t, first, topic, "zombie")
+
+		// A second producer with the same transactional ID fences off the
+		// first and aborts its open transaction.
+		second := newKafkaTxnIntegrationWriter(t, addrs, topic)
+		defer second.CloseAsync()
+
+		require.NoError(t, second.Write(message.New([][]byte{[]byte("b")})))
+		assert.Equal(t, first.txnProducer.producerID, second.txnProducer.producerID)
+		assert.True(t, second.txnProducer.producerEpoch > first.txnProducer.producerEpoch)
+
+		first.txnProducer.mut.Lock()
+		assert.Error(t, first.txnProducer.endTxn(true))
+		assert.True(t, first.txnProducer.fenced)
+		first.txnProducer.mut.Unlock()
+
+		// The fenced producer must neither write nor fence off the second
+		// producer in turn.
+		assert.Equal(t, errKafkaTxnFenced, first.Write(message.New([][]byte{[]byte("c")})))
+		require.NoError(t, second.Write(message.New([][]byte{[]byte("d")})))
+
+		assert.Equal(t, []string{"a", "b", "d"}, readKafkaTxnTopic(t, addrs, topic, sarama.ReadCommitted, 3))
+	})
+
+	t.Run("coordinator moves", func(t *testing.T) {
+		topic := "txn_coordinator"
+
+		w := newKafkaTxnIntegrationWriter(t, addrs, topic)
+		defer w.CloseAsync()
+
+		require.NoError(t, w.Write(message.New([][]byte{[]byte("a")})))
+
+		// Stopping the broker hosting the transaction coordinator moves the
+		// coordinator to the remaining broker, which the producer must find
+		// in order to continue writing.
+		coordinatorID := kafkaTxnCoordinatorID(t, w)
+		require.NoError(t, pool.Client.StopContainer(brokers[coordinatorID].Container.ID, 30))
+
+		require.NoError(t, w.Write(message.New([][]byte{[]byte("b")})))
+		require.NoError(t, w.Write(message.New([][]byte{[]byte("c")})))
+		assert.NotEqual(t, coordinatorID, kafkaTxnCoordinatorID(t, w))
+
+		assert.Equal(t, []string{"a", "b", "c"}, readKafkaTxnTopic(t, addrs, topic, sarama.ReadCommitted, 3))
+	})
+}
@@ -0,0 +1,279 @@
+package writer
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKafkaTxnMockBroker(t *testing.T, produceErr sarama.KError) *sarama.MockBroker {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(kafkaTxnMockHandlers(t, broker, produceErr))
+	return broker
+}
+
+func kafkaTxnMockHandlers(t *testing.T, broker *sarama.MockBroker, produceErr sarama.KError) map[string]sarama.MockResponse {
+	t.Helper()
+
+	return map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("foo", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockSequence(
+			sarama.NewMockWrapper(&sarama.FindCoordinatorResponse{
+				Version:     1,
+				Coordinator: sarama.NewBroker(broker.Addr()),
+			}),
+			sarama.NewMockWrapper(&sarama.FindCoordinatorResponse{
+				Coordinator: sarama.NewBroker(broker.Addr()),
+			}),
+		),
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{
+			ProducerID:    10,
+			ProducerEpoch: 1,
+		}),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{}),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).
+			SetVersion(3).
+			SetError("foo", 0, produceErr),
+		"AddOffsetsToTxnRequest": sarama.NewMockWrapper(&sarama.AddOffsetsToTxnResponse{}),
+		"TxnOffsetCommitRequest": sarama.NewMockWrapper(&sarama.TxnOffsetCommitResponse{}),
+		"EndTxnRequest":          sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	}
+}
+
+func newKafkaTxnWriter(t *testing.T, addr string) *Kafka {
+	t.Helper()
+
+	conf := NewKafkaConfig()
+	conf.Addresses = []string{addr}
+	conf.Topic = "foo"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	conf.Backoff.MaxElapsedTime = "5ms"
+	conf.Transaction.Enabled = true
+	conf.Transaction.ID = "foo_txn"
+	conf.Transaction.ConsumerGroup = "bar_group"
+
+	w, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect())
+	return w
+}
+
+func TestKafkaTransactionConfigErrors(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.Enabled = true
+
+	_, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a transaction id must be specified when transactions are enabled")
+
+	conf.Transaction.ID = "foo"
+	conf.TargetVersion = "0.10.2.0"
+	_, err = NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	assert.Equal(t, errKafkaTxnVersion, err)
+
+	conf.TargetVersion = "1.0.0"
+	conf.Transaction.Timeout = "nope"
+	_, err = NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestKafkaTransactionOffsets(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e"),
+	})
+	msg.Get(0).Metadata().Set("kafka_topic", "foo").Set("kafka_partition", "0").Set("kafka_offset", "5")
+	msg.Get(1).Metadata().Set("kafka_topic", "foo").Set("kafka_partition", "0").Set("kafka_offset", "3")
+	msg.Get(2).Metadata().Set("kafka_topic", "foo").Set("kafka_partition", "1").Set("kafka_offset", "2")
+	msg.Get(3).Metadata().Set("kafka_topic", "bar").Set("kafka_partition", "0").Set("kafka_offset", "10")
+	msg.Get(4).Metadata().Set("kafka_topic", "bar").Set("kafka_partition", "nope")
+
+	assert.Equal(t, map[string]map[int32]int64{
+		"foo": {0: 6, 1: 3},
+		"bar": {0: 11},
+	}, kafkaTxnOffsets(msg))
+}
+
+func TestKafkaTransactionCommit(t *testing.T) {
+	broker := newKafkaTxnMockBroker(t, sarama.ErrNoError)
+	defer broker.Close()
+
+	w := newKafkaTxnWriter(t, broker.Addr())
+
+	msg := message.New([][]byte{[]byte("hello"), []byte("world")})
+	msg.Get(0).Metadata().Set("kafka_topic", "in").Set("kafka_partition", "2").Set("kafka_offset", "7")
+	msg.Get(1).Metadata().Set("kafka_topic", "in").Set("kafka_partition", "2").Set("kafka_offset", "8")
+	require.NoError(t, w.Write(msg))
+
+	w.CloseAsync()
+
+	var initReqs, produceReqs int
+	var offsetReq *sarama.TxnOffsetCommitRequest
+	var endReqs []*sarama.EndTxnRequest
+	for _, rr := range broker.History() {
+		switch req := rr.Request.(type) {
+		case *sarama.InitProducerIDRequest:
+			initReqs++
+			require.NotNil(t, req.TransactionalID)
+			assert.Equal(t, "foo_txn", *req.TransactionalID)
+		case *sarama.AddPartitionsToTxnRequest:
+			assert.Equal(t, map[string][]int32{"foo": {0}}, req.TopicPartitions)
+		case *sarama.ProduceRequest:
+			produceReqs++
+			require.NotNil(t, req.TransactionalID)
+			assert.Equal(t, "foo_txn", *req.TransactionalID)
+		case *sarama.AddOffsetsToTxnRequest:
+			assert.Equal(t, "bar_group", req.GroupID)
+		case *sarama.TxnOffsetCommitRequest:
+			offsetReq = req
+		case *sarama.EndTxnRequest:
+			endReqs = append(endReqs, req)
+		}
+	}
+
+	assert.Equal(t, 1, initReqs)
+	assert.Equal(t, 1, produceReqs)
+
+	require.NotNil(t, offsetReq)
+	assert.Equal(t, "bar_group", offsetReq.GroupID)
+	assert.Equal(t, int64(10), offsetReq.ProducerID)
+	require.Len(t, offsetReq.Topics["in"], 1)
+	assert.Equal(t, int32(2), offsetReq.Topics["in"][0].Partition)
+	assert.Equal(t, int64(9), offsetReq.Topics["in"][0].Offset)
+
+	require.Len(t, endReqs, 1)
+	assert.True(t, endReqs[0].TransactionResult)
+}
+
+func TestKafkaTransactionAbort(t *testing.T) {
+	broker := newKafkaTxnMockBroker(t, sarama.ErrInvalidMessage)
+	defer broker.Close()
+
+	w := newKafkaTxnWriter(t, broker.Addr())
+
+	msg := message.New([][]byte{[]byte("hello")})
+	msg.Get(0).Metadata().Set("kafka_topic", "in").Set("kafka_partition", "0").Set("kafka_offset", "1")
+	require.Error(t, w.Write(msg))
+
+	w.CloseAsync()
+
+	var initReqs int
+	var commits, aborts int
+	for _, rr := range broker.History() {
+		switch req := rr.Request.(type) {
+		case *sarama.InitProducerIDRequest:
+			initReqs++
+		case *sarama.TxnOffsetCommitRequest:
+			t.Error("Unexpected offset commit")
+		case *sarama.EndTxnRequest:
+			if req.TransactionResult {
+				commits++
+			} else {
+				aborts++
+			}
+		}
+	}
+
+	assert.Equal(t, 0, commits)
+	assert.True(t, aborts > 0)
+	assert.Equal(t, aborts, initReqs)
+}
+
+func TestKafkaTransactionFenced(t *testing.T) {
+	broker := newKafkaTxnMockBroker(t, kafkaErrProducerFenced)
+	defer broker.Close()
+
+	w := newKafkaTxnWriter(t, broker.Addr())
+
+	msg := message.New([][]byte{[]byte("hello")})
+	assert.Equal(t, errKafkaTxnFenced, w.Write(msg))
+
+	// A fenced producer must not initialise again, which would fence off the
+	// newer producer in turn.
+	reqs := len(broker.History())
+	assert.Equal(t, errKafkaTxnFenced, w.Write(msg))
+	assert.Equal(t, reqs, len(broker.History()))
+
+	w.CloseAsync()
+
+	var initReqs, endReqs int
+	for _, rr := range broker.History() {
+		switch rr.Request.(type) {
+		case *sarama.InitProducerIDRequest:
+			initReqs++
+		case *sarama.EndTxnRequest:
+			endReqs++
+		}
+	}
+	assert.Equal(t, 1, initReqs)
+	assert.Equal(t, 0, endReqs)
+}
+
+func TestKafkaTransactionInvalidEpoch(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	// Without a consumer group only the transaction coordinator is looked up,
+	// and so each attempt to initialise is answered the same way.
+	handlers := kafkaTxnMockHandlers(t, broker, sarama.ErrInvalidProducerEpoch)
+	handlers["FindCoordinatorRequest"] = sarama.NewMockWrapper(&sarama.FindCoordinatorResponse{
+		Version:     1,
+		Coordinator: sarama.NewBroker(broker.Addr()),
+	})
+	broker.SetHandlerByMap(handlers)
+
+	conf := NewKafkaConfig()
+	conf.Addresses = []string{broker.Addr()}
+	conf.Topic = "foo"
+	conf.Backoff.MaxElapsedTime = "1ms"
+	conf.Transaction.Enabled = true
+	conf.Transaction.ID = "foo_txn"
+
+	w, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect())
+	defer w.CloseAsync()
+
+	requests := func() (inits, aborts int) {
+		for _, rr := range broker.History() {
+			switch req := rr.Request.(type) {
+			case *sarama.InitProducerIDRequest:
+				inits++
+			case *sarama.EndTxnRequest:
+				if !req.TransactionResult {
+					aborts++
+				}
+			}
+		}
+		return
+	}
+
+	// An invalid epoch is also returned after the coordinator has aborted a
+	// transaction that timed out, and so the transaction is aborted and the
+	// producer is initialised again rather than treated as fenced.
+	msg := message.New([][]byte{[]byte("hello")})
+	err = w.Write(msg)
+	require.Error(t, err)
+	assert.NotEqual(t, errKafkaTxnFenced, err)
+
+	inits, aborts := requests()
+	assert.True(t, aborts > 0)
+	assert.Equal(t, aborts, inits)
+
+	err = w.Write(msg)
+	require.Error(t, err)
+	assert.NotEqual(t, errKafkaTxnFenced, err)
+
+	nextInits, nextAborts := requests()
+	assert.True(t, nextInits > inits)
+	assert.Equal(t, nextAborts, nextInits)
+}
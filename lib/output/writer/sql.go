@@ -0,0 +1,492 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/bloblang/x/mapping"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff"
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/xerrors"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLConfig contains configuration fields for the SQL output type.
+type SQLConfig struct {
+	Driver          string   `json:"driver" yaml:"driver"`
+	DSN             string   `json:"dsn" yaml:"dsn"`
+	Table           string   `json:"table" yaml:"table"`
+	Columns         []string `json:"columns" yaml:"columns"`
+	ArgsMapping     string   `json:"args_mapping" yaml:"args_mapping"`
+	ConflictColumns []string `json:"conflict_columns" yaml:"conflict_columns"`
+	MaxInFlight     int      `json:"max_in_flight" yaml:"max_in_flight"`
+	retries.Config  `json:",inline" yaml:",inline"`
+	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewSQLConfig creates a new SQLConfig with default values.
+func NewSQLConfig() SQLConfig {
+	rConf := retries.NewConfig()
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
+
+	return SQLConfig{
+		Driver:          "mysql",
+		DSN:             "",
+		Table:           "",
+		Columns:         []string{},
+		ArgsMapping:     "",
+		ConflictColumns: []string{},
+		MaxInFlight:     1,
+		Config:          rConf,
+		Batching:        batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// sqlTransientChecks are functions that determine whether a driver specific
+// error is transient and therefore the write should be attempted again.
+var sqlTransientChecks = []func(err error) bool{
+	func(err error) bool {
+		var myErr *mysql.MySQLError
+		if !xerrors.As(err, &myErr) {
+			return false
+		}
+		switch myErr.Number {
+		case 1040, // Too many connections
+			1205, // Lock wait timeout exceeded
+			1213: // Deadlock found
+			return true
+		}
+		return false
+	},
+	func(err error) bool {
+		msg := err.Error()
+		return strings.Contains(msg, "database is locked") ||
+			strings.Contains(msg, "database table is locked")
+	},
+}
+
+func sqlIsTransient(err error) bool {
+	if err == driver.ErrBadConn || err == mysql.ErrInvalidConn {
+		return true
+	}
+	var netErr net.Error
+	if xerrors.As(err, &netErr) {
+		return true
+	}
+	for _, check := range sqlTransientChecks {
+		if check(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlMaxParams is the maximum number of parameters that each driver supports
+// within a single statement.
+var sqlMaxParams = map[string]int{
+	"mysql":    65535,
+	"postgres": 65535,
+	"sqlite3":  999,
+}
+
+// sqlUnavailable contains the reasons why supported drivers are not available
+// within the current build.
+var sqlUnavailable = map[string]string{}
+
+// sqlInsertBuilder builds multiple row INSERT or UPSERT statements for a
+// particular driver.
+type sqlInsertBuilder struct {
+	driver          string
+	table           string
+	columns         []string
+	conflictColumns []string
+}
+
+func (b sqlInsertBuilder) placeholder(n int) string {
+	if b.driver == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// build returns a statement that inserts a number of rows.
+func (b sqlInsertBuilder) build(rows int) string {
+	isConflict := map[string]struct{}{}
+	for _, c := range b.conflictColumns {
+		isConflict[c] = struct{}{}
+	}
+	var updateCols []string
+	for _, c := range b.columns {
+		if _, exists := isConflict[c]; !exists {
+			updateCols = append(updateCols, c)
+		}
+	}
+	upsert := len(b.conflictColumns) > 0
+
+	var buf strings.Builder
+	buf.WriteString("INSERT ")
+	if upsert && len(updateCols) == 0 && b.driver == "mysql" {
+		buf.WriteString("IGNORE ")
+	}
+	buf.WriteString("INTO ")
+	buf.WriteString(b.table)
+	buf.WriteString(" (")
+	buf.WriteString(strings.Join(b.columns, ", "))
+	buf.WriteString(") VALUES ")
+
+	n := 1
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteByte('(')
+		for j := range b.columns {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(b.placeholder(n))
+			n++
+		}
+		buf.WriteByte(')')
+	}
+
+	if !upsert {
+		return buf.String()
+	}
+
+	if b.driver == "mysql" {
+		if len(updateCols) > 0 {
+			buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+			for i, c := range updateCols {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				buf.WriteString(c + " = VALUES(" + c + ")")
+			}
+		}
+		return buf.String()
+	}
+
+	buf.WriteString(" ON CONFLICT (")
+	buf.WriteString(strings.Join(b.conflictColumns, ", "))
+	buf.WriteString(") DO ")
+	if len(updateCols) == 0 {
+		buf.WriteString("NOTHING")
+		return buf.String()
+	}
+	buf.WriteString("UPDATE SET ")
+	for i, c := range updateCols {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(c + " = EXCLUDED." + c)
+	}
+	return buf.String()
+}
+
+// dedupe removes rows that share the values of the conflict columns, since a
+// statement cannot update the same row twice with some drivers. When
+// conflicting rows are updated the values of the last of the rows are kept,
+// otherwise the first of the rows is kept as it would be by the database.
+func (b sqlInsertBuilder) dedupe(rows [][]interface{}) [][]interface{} {
+	if len(b.conflictColumns) == 0 {
+		return rows
+	}
+	colIndexes := map[string]int{}
+	for i, c := range b.columns {
+		colIndexes[c] = i
+	}
+	var keyIndexes []int
+	for _, c := range b.conflictColumns {
+		i, exists := colIndexes[c]
+		if !exists {
+			// Rows that are missing a conflict column cannot be compared.
+			return rows
+		}
+		keyIndexes = append(keyIndexes, i)
+	}
+	update := len(keyIndexes) < len(b.columns)
+
+	deduped := make([][]interface{}, 0, len(rows))
+	seen := map[string]int{}
+	for _, row := range rows {
+		keyValues := make([]interface{}, len(keyIndexes))
+		for i, j := range keyIndexes {
+			keyValues[i] = row[j]
+		}
+		key := fmt.Sprintf("%#v", keyValues)
+		if i, exists := seen[key]; exists {
+			if update {
+				deduped[i] = row
+			}
+			continue
+		}
+		seen[key] = len(deduped)
+		deduped = append(deduped, row)
+	}
+	return deduped
+}
+
+//------------------------------------------------------------------------------
+
+// SQL is a writer type that inserts the messages of a batch as rows of a table
+// within a single transaction.
+type SQL struct {
+	conf        SQLConfig
+	builder     sqlInsertBuilder
+	argsMapping *mapping.Executor
+	rowsPerStmt int
+	backoffCtor func() backoff.BackOff
+
+	dbMut sync.RWMutex
+	db    *sql.DB
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRetries     metrics.StatCounter
+	mRetryFailed metrics.StatCounter
+}
+
+// NewSQL creates a new SQL writer type.
+func NewSQL(conf SQLConfig, log log.Modular, stats metrics.Type) (*SQL, error) {
+	s := &SQL{
+		conf:         conf,
+		log:          log,
+		stats:        stats,
+		mRetries:     stats.GetCounter("retries"),
+		mRetryFailed: stats.GetCounter("error.retries_exhausted"),
+	}
+
+	maxParams, exists := sqlMaxParams[conf.Driver]
+	if !exists {
+		return nil, fmt.Errorf("driver not supported: %v", conf.Driver)
+	}
+	if reason, unavailable := sqlUnavailable[conf.Driver]; unavailable {
+		return nil, fmt.Errorf("driver %v is not available: %v", conf.Driver, reason)
+	}
+	if len(conf.Table) == 0 {
+		return nil, errors.New("a table must be specified")
+	}
+	if len(conf.Columns) == 0 {
+		return nil, errors.New("at least one column must be specified")
+	}
+	if len(conf.ArgsMapping) == 0 {
+		return nil, errors.New("an args_mapping must be specified")
+	}
+
+	var err error
+	if s.argsMapping, err = mapping.NewExecutor(conf.ArgsMapping); err != nil {
+		return nil, fmt.Errorf("failed to parse args mapping: %v", err)
+	}
+	if s.backoffCtor, err = conf.Config.GetCtor(); err != nil {
+		return nil, err
+	}
+
+	s.builder = sqlInsertBuilder{
+		driver:          conf.Driver,
+		table:           conf.Table,
+		columns:         conf.Columns,
+		conflictColumns: conf.ConflictColumns,
+	}
+	s.rowsPerStmt = maxParams / len(conf.Columns)
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext attempts to establish a connection to the target
+// database.
+func (s *SQL) ConnectWithContext(ctx context.Context) error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open(s.conf.Driver, s.conf.DSN)
+	if err != nil {
+		return err
+	}
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
+
+	s.log.Infof("Inserting messages as rows of table %v\n", s.conf.Table)
+	s.db = db
+	return nil
+}
+
+// Connect attempts to establish a connection to the target database.
+func (s *SQL) Connect() error {
+	return s.ConnectWithContext(context.Background())
+}
+
+// sqlArg converts a value of a JSON document into a query argument, where
+// objects and arrays are serialised as JSON.
+func sqlArg(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < (1<<53) {
+			return int64(t), nil
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+	return v, nil
+}
+
+// args executes the args mapping for each message of a batch and returns the
+// resulting arguments of each row.
+func (s *SQL) args(msg types.Message) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0, msg.Len())
+	for i := 0; i < msg.Len(); i++ {
+		p, err := s.argsMapping.MapPart(i, msg)
+		if err != nil {
+			return nil, fmt.Errorf("args mapping failed: %v", err)
+		}
+		if p == nil {
+			continue
+		}
+		v, err := p.JSON()
+		if err != nil {
+			return nil, fmt.Errorf("args mapping returned non-structured result: %v", err)
+		}
+		values, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("args mapping returned non-array result: %T", v)
+		}
+		if len(values) != len(s.conf.Columns) {
+			return nil, fmt.Errorf("args mapping returned %v values for %v columns", len(values), len(s.conf.Columns))
+		}
+		row := make([]interface{}, len(values))
+		for j, value := range values {
+			if row[j], err = sqlArg(value); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// insert writes rows within a single transaction, using as many statements as
+// are required in order to remain within the parameter limit of the driver.
+func (s *SQL) insert(ctx context.Context, db *sql.DB, rows [][]interface{}) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for len(rows) > 0 {
+		n := len(rows)
+		if n > s.rowsPerStmt {
+			n = s.rowsPerStmt
+		}
+		args := make([]interface{}, 0, n*len(s.conf.Columns))
+		for _, row := range rows[:n] {
+			args = append(args, row...)
+		}
+		if _, err = tx.ExecContext(ctx, s.builder.build(n), args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+		rows = rows[n:]
+	}
+	return tx.Commit()
+}
+
+// Write attempts to write a message batch as rows of a table.
+func (s *SQL) Write(msg types.Message) error {
+	return s.WriteWithContext(context.Background(), msg)
+}
+
+// WriteWithContext attempts to write a message batch as rows of a table,
+// retrying the transaction when it fails with a transient error.
+func (s *SQL) WriteWithContext(ctx context.Context, msg types.Message) error {
+	s.dbMut.RLock()
+	db := s.db
+	s.dbMut.RUnlock()
+
+	if db == nil {
+		return types.ErrNotConnected
+	}
+
+	rows, err := s.args(msg)
+	if err != nil {
+		return err
+	}
+	rows = s.builder.dedupe(rows)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	boff := s.backoffCtor()
+	for {
+		if err = s.insert(ctx, db, rows); err == nil || !sqlIsTransient(err) {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			s.mRetryFailed.Incr(1)
+			return err
+		}
+		s.mRetries.Incr(1)
+		s.log.Warnf("Retrying failed insert: %v\n", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (s *SQL) CloseAsync() {
+	s.dbMut.Lock()
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+	s.dbMut.Unlock()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (s *SQL) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,29 @@
+// +build !wasm
+
+package writer
+
+// Import extra drivers that aren't supported by WASM builds.
+import (
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+
+	// SQL Drivers
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	sqlTransientChecks = append(sqlTransientChecks, func(err error) bool {
+		var pqErr *pq.Error
+		if !xerrors.As(err, &pqErr) {
+			return false
+		}
+		switch pqErr.Code.Class() {
+		case "08", // Connection exception
+			"40", // Transaction rollback, including deadlocks
+			"53", // Insufficient resources
+			"57": // Operator intervention
+			return true
+		}
+		return false
+	})
+}
@@ -0,0 +1,7 @@
+// +build !cgo
+
+package writer
+
+func init() {
+	sqlUnavailable["sqlite3"] = "Benthos must be built with cgo enabled"
+}
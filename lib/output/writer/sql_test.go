@@ -0,0 +1,274 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLInsertBuilder(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    string
+		conflicts []string
+		expected  string
+	}{
+		{
+			name:     "mysql insert",
+			driver:   "mysql",
+			expected: "INSERT INTO foo (a, b, c) VALUES (?, ?, ?), (?, ?, ?)",
+		},
+		{
+			name:     "postgres insert",
+			driver:   "postgres",
+			expected: "INSERT INTO foo (a, b, c) VALUES ($1, $2, $3), ($4, $5, $6)",
+		},
+		{
+			name:      "mysql upsert",
+			driver:    "mysql",
+			conflicts: []string{"a"},
+			expected:  "INSERT INTO foo (a, b, c) VALUES (?, ?, ?), (?, ?, ?) ON DUPLICATE KEY UPDATE b = VALUES(b), c = VALUES(c)",
+		},
+		{
+			name:      "mysql upsert all conflicts",
+			driver:    "mysql",
+			conflicts: []string{"a", "b", "c"},
+			expected:  "INSERT IGNORE INTO foo (a, b, c) VALUES (?, ?, ?), (?, ?, ?)",
+		},
+		{
+			name:      "postgres upsert",
+			driver:    "postgres",
+			conflicts: []string{"a", "b"},
+			expected:  "INSERT INTO foo (a, b, c) VALUES ($1, $2, $3), ($4, $5, $6) ON CONFLICT (a, b) DO UPDATE SET c = EXCLUDED.c",
+		},
+		{
+			name:      "sqlite upsert all conflicts",
+			driver:    "sqlite3",
+			conflicts: []string{"a", "b", "c"},
+			expected:  "INSERT INTO foo (a, b, c) VALUES (?, ?, ?), (?, ?, ?) ON CONFLICT (a, b, c) DO NOTHING",
+		},
+	}
+
+	for _, test := range tests {
+		b := sqlInsertBuilder{
+			driver:          test.driver,
+			table:           "foo",
+			columns:         []string{"a", "b", "c"},
+			conflictColumns: test.conflicts,
+		}
+		assert.Equal(t, test.expected, b.build(2), test.name)
+	}
+}
+
+func TestSQLInsertBuilderDedupe(t *testing.T) {
+	rows := [][]interface{}{
+		{int64(1), "a", "first"},
+		{int64(2), "a", "first"},
+		{int64(1), "a", "second"},
+		{"1", "a", "string"},
+		{int64(1), "b", "first"},
+		{int64(1), "a", "third"},
+	}
+
+	tests := []struct {
+		name      string
+		conflicts []string
+		expected  [][]interface{}
+	}{
+		{
+			name:     "no conflicts",
+			expected: rows,
+		},
+		{
+			name:      "update",
+			conflicts: []string{"a", "b"},
+			expected: [][]interface{}{
+				{int64(1), "a", "third"},
+				{int64(2), "a", "first"},
+				{"1", "a", "string"},
+				{int64(1), "b", "first"},
+			},
+		},
+		{
+			name:      "ignore",
+			conflicts: []string{"a", "b", "c"},
+			expected: [][]interface{}{
+				{int64(1), "a", "first"},
+				{int64(2), "a", "first"},
+				{int64(1), "a", "second"},
+				{"1", "a", "string"},
+				{int64(1), "b", "first"},
+				{int64(1), "a", "third"},
+			},
+		},
+		{
+			name:      "unknown column",
+			conflicts: []string{"a", "d"},
+			expected:  rows,
+		},
+	}
+
+	for _, test := range tests {
+		b := sqlInsertBuilder{
+			driver:          "postgres",
+			table:           "foo",
+			columns:         []string{"a", "b", "c"},
+			conflictColumns: test.conflicts,
+		}
+		assert.Equal(t, test.expected, b.dedupe(rows), test.name)
+	}
+}
+
+func TestSQLIsTransient(t *testing.T) {
+	assert.True(t, sqlIsTransient(driver.ErrBadConn))
+	assert.True(t, sqlIsTransient(&mysql.MySQLError{Number: 1213}))
+	assert.False(t, sqlIsTransient(&mysql.MySQLError{Number: 1062}))
+	assert.True(t, sqlIsTransient(&pq.Error{Code: "40P01"}))
+	assert.False(t, sqlIsTransient(&pq.Error{Code: "23505"}))
+	assert.True(t, sqlIsTransient(errors.New("database is locked")))
+	assert.False(t, sqlIsTransient(errors.New("no such table: foo")))
+}
+
+func TestSQLConfigErrors(t *testing.T) {
+	tests := map[string]func(c *SQLConfig){
+		"bad driver":     func(c *SQLConfig) { c.Driver = "nope" },
+		"no table":       func(c *SQLConfig) { c.Table = "" },
+		"no columns":     func(c *SQLConfig) { c.Columns = nil },
+		"no mapping":     func(c *SQLConfig) { c.ArgsMapping = "" },
+		"bad mapping":    func(c *SQLConfig) { c.ArgsMapping = "root = [" },
+		"bad backoff":    func(c *SQLConfig) { c.Backoff.InitialInterval = "nope" },
+		"valid fallback": nil,
+	}
+
+	for name, fn := range tests {
+		conf := NewSQLConfig()
+		conf.Table = "foo"
+		conf.Columns = []string{"a"}
+		conf.ArgsMapping = "root = [ this.a ]"
+		if fn == nil {
+			_, err := NewSQL(conf, log.Noop(), metrics.Noop())
+			assert.NoError(t, err, name)
+			continue
+		}
+		fn(&conf)
+		_, err := NewSQL(conf, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSQLiteWrite(t *testing.T) {
+	if reason, unavailable := sqlUnavailable["sqlite3"]; unavailable {
+		t.Skipf("Skipping sqlite3 test: %v", reason)
+	}
+
+	dir, err := ioutil.TempDir("", "benthos_sql_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dsn := "file:" + filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT, doc TEXT)`)
+	require.NoError(t, err)
+
+	conf := NewSQLConfig()
+	conf.Driver = "sqlite3"
+	conf.DSN = dsn
+	conf.Table = "foo"
+	conf.Columns = []string{"id", "name", "doc"}
+	conf.ArgsMapping = `root = if this.skip == true { deleted() } else { [ this.id, this.name, this.doc ] }`
+	conf.ConflictColumns = []string{"id"}
+
+	s, err := NewSQL(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	s.rowsPerStmt = 2
+
+	require.NoError(t, s.ConnectWithContext(context.Background()))
+	defer func() {
+		s.CloseAsync()
+		assert.NoError(t, s.WaitForClose(time.Second))
+	}()
+
+	require.NoError(t, s.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo","doc":{"a":[1,2]}}`),
+		[]byte(`{"id":2,"name":"bar"}`),
+		[]byte(`{"id":3,"name":"baz","skip":true}`),
+		[]byte(`{"id":4,"name":"qux","doc":"text"}`),
+	})))
+	require.NoError(t, s.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":2,"name":"qix","doc":null}`),
+		[]byte(`{"id":2,"name":"quz","doc":null}`),
+	})))
+
+	rows, err := db.Query(`SELECT id, name, doc FROM foo ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type row struct {
+		id   int64
+		name string
+		doc  sql.NullString
+	}
+	var results []row
+	for rows.Next() {
+		var r row
+		require.NoError(t, rows.Scan(&r.id, &r.name, &r.doc))
+		results = append(results, r)
+	}
+	require.NoError(t, rows.Err())
+
+	assert.Equal(t, []row{
+		{id: 1, name: "foo", doc: sql.NullString{String: `{"a":[1,2]}`, Valid: true}},
+		{id: 2, name: "quz"},
+		{id: 4, name: "qux", doc: sql.NullString{String: "text", Valid: true}},
+	}, results)
+
+	err = s.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":5}`),
+		[]byte(`{"id":"nope","name":"foo"}`),
+	}))
+	require.Error(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM foo`).Scan(&count))
+	assert.Equal(t, 3, count, "failed batch should be rolled back")
+}
+
+func TestSQLArgsMappingErrors(t *testing.T) {
+	conf := NewSQLConfig()
+	conf.Table = "foo"
+	conf.Columns = []string{"a", "b"}
+	conf.ArgsMapping = `root = this.values`
+
+	s, err := NewSQL(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	for _, body := range []string{
+		`{"values":[1]}`,
+		`{"values":{"a":1,"b":2}}`,
+		`{"values":"foo"}`,
+	} {
+		_, err = s.args(message.New([][]byte{[]byte(body)}))
+		assert.Error(t, err, body)
+	}
+
+	rows, err := s.args(message.New([][]byte{[]byte(`{"values":[1.5,{"c":true}]}`)}))
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{1.5, `{"c":true}`}}, rows)
+}
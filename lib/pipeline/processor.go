@@ -47,6 +47,7 @@ func NewProcessor(
 	return &Processor{
 		running:       1,
 		msgProcessors: msgProcessors,
+		log:           log,
 		stats:         stats,
 		messagesOut:   make(chan types.Transaction),
 		responsesIn:   make(chan types.Response),
@@ -69,14 +70,25 @@ func (p *Processor) loop() {
 		close(p.closed)
 	}()
 
+	var flushChan <-chan time.Time
+	if period := p.flushPeriod(); period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		flushChan = ticker.C
+	}
+
 	var open bool
 	for atomic.LoadInt32(&p.running) == 1 {
 		var tran types.Transaction
 		select {
 		case tran, open = <-p.messagesIn:
 			if !open {
+				p.flush(true)
 				return
 			}
+		case <-flushChan:
+			p.flush(false)
+			continue
 		case <-p.closeChan:
 			return
 		}
@@ -107,6 +119,41 @@ func (p *Processor) loop() {
 	}
 }
 
+// flushPeriod returns the shortest flush period of all processors that
+// implement processor.Flusher, or zero if none of them need to be flushed
+// periodically.
+func (p *Processor) flushPeriod() time.Duration {
+	var period time.Duration
+	for _, proc := range p.msgProcessors {
+		if f, ok := proc.(processor.Flusher); ok {
+			if fp := f.FlushPeriod(); fp > 0 && (period == 0 || fp < period) {
+				period = fp
+			}
+		}
+	}
+	return period
+}
+
+// flush collects held messages from all processors that implement
+// processor.Flusher, executes them through the remaining processors of the
+// pipeline and dispatches the results. Flushed messages have already been
+// acknowledged at their source and therefore have no response to propagate.
+func (p *Processor) flush(final bool) {
+	for i, proc := range p.msgProcessors {
+		f, ok := proc.(processor.Flusher)
+		if !ok {
+			continue
+		}
+		msgs := f.Flush(final)
+		if len(msgs) == 0 {
+			continue
+		}
+		if msgs, _ = processor.ExecuteAll(p.msgProcessors[i+1:], msgs...); len(msgs) > 0 {
+			p.dispatchMessages(msgs, make(chan types.Response, 1))
+		}
+	}
+}
+
 // dispatchMessages attempts to send a multiple messages results of processors
 // over the shared messages channel. This send is retried until success.
 func (p *Processor) dispatchMessages(msgs []types.Message, ogResChan chan<- types.Response) {
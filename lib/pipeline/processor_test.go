@@ -404,3 +404,124 @@ func TestProcessorMultiMsgsOddSync(t *testing.T) {
 		t.Error("Expected mockproc to have waited for close")
 	}
 }
+
+type mockFlushProcessor struct {
+	period  time.Duration
+	mut     sync.Mutex
+	held    []types.Message
+	flushed chan bool
+}
+
+func (m *mockFlushProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	m.mut.Lock()
+	m.held = append(m.held, msg)
+	m.mut.Unlock()
+	return nil, response.NewAck()
+}
+
+func (m *mockFlushProcessor) FlushPeriod() time.Duration {
+	return m.period
+}
+
+func (m *mockFlushProcessor) Flush(final bool) []types.Message {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if len(m.held) == 0 {
+		return nil
+	}
+	m.flushed <- final
+	msgs := m.held
+	m.held = nil
+	return msgs
+}
+
+func (m *mockFlushProcessor) CloseAsync() {}
+
+func (m *mockFlushProcessor) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+type mockSuffixProcessor struct{}
+
+func (m mockSuffixProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	newMsg := msg.Copy()
+	newMsg.Get(0).Set(append(newMsg.Get(0).Get(), []byte("-processed")...))
+	return []types.Message{newMsg}, nil
+}
+
+func (m mockSuffixProcessor) CloseAsync() {}
+
+func (m mockSuffixProcessor) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestProcessorPipelineFlush(t *testing.T) {
+	tests := map[string]time.Duration{
+		"periodic": time.Millisecond * 10,
+		"final":    0,
+	}
+
+	for name, period := range tests {
+		t.Run(name, func(t *testing.T) {
+			flushProc := &mockFlushProcessor{period: period, flushed: make(chan bool, 1)}
+			proc := NewProcessor(
+				log.Noop(),
+				metrics.Noop(),
+				flushProc,
+				mockSuffixProcessor{},
+			)
+
+			tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+			if err := proc.Consume(tChan); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+			select {
+			case res := <-resChan:
+				if res.Error() != nil {
+					t.Error(res.Error())
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+
+			final := period == 0
+			if final {
+				close(tChan)
+			}
+
+			select {
+			case tran := <-proc.TransactionChan():
+				if exp, act := "foo-processed", string(tran.Payload.Get(0).Get()); exp != act {
+					t.Errorf("Wrong flushed content: %v != %v", act, exp)
+				}
+				tran.ResponseChan <- response.NewAck()
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for flushed message")
+			}
+			if exp, act := final, <-flushProc.flushed; exp != act {
+				t.Errorf("Wrong final flag: %v != %v", act, exp)
+			}
+
+			if !final {
+				close(tChan)
+			}
+			select {
+			case _, open := <-proc.TransactionChan():
+				if open {
+					t.Error("Expected transaction chan to be closed")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+			if err := proc.WaitForClose(time.Second); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package processor
 import (
 	"fmt"
 	"io/ioutil"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/schemaregistry"
 	"github.com/Jeffail/benthos/v3/lib/x/docs"
 	"github.com/linkedin/goavro/v2"
 	"github.com/opentracing/opentracing-go"
@@ -38,12 +41,26 @@ specifies how the source documents are encoded.
 ### ` + "`from_json`" + `
 
 Attempts to convert JSON documents into Avro documents according to the
-specified encoding.`,
+specified encoding.
+
+## Schema Registry
+
+When a ` + "`schema_registry`" + ` URL is configured schemas are obtained from
+the registry rather than the ` + "`schema`" + ` or ` + "`schema_path`" + `
+fields, and documents are expected to be binary encoded and framed with the
+registry wire format, which is a zero magic byte followed by a four byte schema
+ID.
+
+The ` + "`to_json`" + ` operator fetches (and caches) the schema referenced by
+the ID of each message, and sets the metadata field ` + "`schema_id`" + ` on
+the resulting message. The ` + "`from_json`" + ` operator encodes documents
+with the latest schema of the configured subject.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
 			docs.FieldCommon("encoding", "An Avro encoding format to use for conversions to and from a schema.").HasOptions("textual", "binary", "single"),
 			docs.FieldCommon("schema", "A full Avro schema to use."),
 			docs.FieldCommon("schema_path", "The path of a schema document to apply. Use either this or the `schema` field."),
+			schemaregistry.FieldSpec(),
 			partsFieldSpec,
 		},
 	}
@@ -53,21 +70,23 @@ specified encoding.`,
 
 // AvroConfig contains configuration fields for the Avro processor.
 type AvroConfig struct {
-	Parts      []int  `json:"parts" yaml:"parts"`
-	Operator   string `json:"operator" yaml:"operator"`
-	Encoding   string `json:"encoding" yaml:"encoding"`
-	Schema     string `json:"schema" yaml:"schema"`
-	SchemaPath string `json:"schema_path" yaml:"schema_path"`
+	Parts          []int                 `json:"parts" yaml:"parts"`
+	Operator       string                `json:"operator" yaml:"operator"`
+	Encoding       string                `json:"encoding" yaml:"encoding"`
+	Schema         string                `json:"schema" yaml:"schema"`
+	SchemaPath     string                `json:"schema_path" yaml:"schema_path"`
+	SchemaRegistry schemaregistry.Config `json:"schema_registry" yaml:"schema_registry"`
 }
 
 // NewAvroConfig returns a AvroConfig with default values.
 func NewAvroConfig() AvroConfig {
 	return AvroConfig{
-		Parts:      []int{},
-		Operator:   "to_json",
-		Encoding:   "textual",
-		Schema:     "",
-		SchemaPath: "",
+		Parts:          []int{},
+		Operator:       "to_json",
+		Encoding:       "textual",
+		Schema:         "",
+		SchemaPath:     "",
+		SchemaRegistry: schemaregistry.NewConfig(),
 	}
 }
 
@@ -169,6 +188,94 @@ func strToAvroOperator(opStr, encoding string, codec *goavro.Codec) (avroOperato
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
+//------------------------------------------------------------------------------
+
+type avroRegistryCodecs struct {
+	client *schemaregistry.Client
+
+	mut    sync.Mutex
+	codecs map[int]*goavro.Codec
+}
+
+func (a *avroRegistryCodecs) codecFor(schema *schemaregistry.Schema) (*goavro.Codec, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if codec, exists := a.codecs[schema.ID]; exists {
+		return codec, nil
+	}
+	if schema.Type != schemaregistry.TypeAvro {
+		return nil, fmt.Errorf("schema %v is of type %v, expected %v", schema.ID, schema.Type, schemaregistry.TypeAvro)
+	}
+	codec, err := goavro.NewCodec(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %v: %v", schema.ID, err)
+	}
+	a.codecs[schema.ID] = codec
+	return codec, nil
+}
+
+func newAvroRegistryToJSONOperator(codecs *avroRegistryCodecs) avroOperator {
+	return func(part types.Part) error {
+		id, payload, err := schemaregistry.DecodeHeader(part.Get())
+		if err != nil {
+			return err
+		}
+		schema, err := codecs.client.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("failed to obtain schema %v: %v", id, err)
+		}
+		codec, err := codecs.codecFor(schema)
+		if err != nil {
+			return err
+		}
+		jObj, _, err := codec.NativeFromBinary(payload)
+		if err != nil {
+			return fmt.Errorf("failed to convert Avro document to JSON: %v", err)
+		}
+		if err = part.SetJSON(jObj); err != nil {
+			return fmt.Errorf("failed to set JSON: %v", err)
+		}
+		part.Metadata().Set("schema_id", strconv.Itoa(id))
+		return nil
+	}
+}
+
+func newAvroRegistryFromJSONOperator(codecs *avroRegistryCodecs) avroOperator {
+	return func(part types.Part) error {
+		jObj, err := part.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to parse message as JSON: %v", err)
+		}
+		schema, err := codecs.client.GetLatest("")
+		if err != nil {
+			return fmt.Errorf("failed to obtain latest schema: %v", err)
+		}
+		codec, err := codecs.codecFor(schema)
+		if err != nil {
+			return err
+		}
+		var binary []byte
+		if binary, err = codec.BinaryFromNative(nil, jObj); err != nil {
+			return fmt.Errorf("failed to convert JSON to Avro schema: %v", err)
+		}
+		part.Set(schemaregistry.EncodeHeader(schema.ID, binary))
+		return nil
+	}
+}
+
+func strToAvroRegistryOperator(opStr string, codecs *avroRegistryCodecs) (avroOperator, error) {
+	switch opStr {
+	case "to_json":
+		return newAvroRegistryToJSONOperator(codecs), nil
+	case "from_json":
+		return newAvroRegistryFromJSONOperator(codecs), nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", opStr)
+}
+
+//------------------------------------------------------------------------------
+
 func loadSchema(schemaPath string) (string, error) {
 	t := &http.Transport{}
 	t.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
@@ -197,6 +304,7 @@ func loadSchema(schemaPath string) (string, error) {
 type Avro struct {
 	parts    []int
 	operator avroOperator
+	registry *schemaregistry.Client
 
 	conf  Config
 	log   log.Modular
@@ -226,6 +334,20 @@ func NewAvro(
 	var schema string
 	var err error
 
+	if len(conf.Avro.SchemaRegistry.URL) > 0 {
+		if a.registry, err = schemaregistry.New(conf.Avro.SchemaRegistry, log, stats); err != nil {
+			return nil, fmt.Errorf("failed to create schema registry client: %v", err)
+		}
+		if a.operator, err = strToAvroRegistryOperator(conf.Avro.Operator, &avroRegistryCodecs{
+			client: a.registry,
+			codecs: map[int]*goavro.Codec{},
+		}); err != nil {
+			a.registry.Close()
+			return nil, err
+		}
+		return a, nil
+	}
+
 	if schemaPath := conf.Avro.SchemaPath; schemaPath != "" {
 		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://")) {
 			return nil, fmt.Errorf("invalid schema_path provided, must start with file:// or http://")
@@ -276,6 +398,9 @@ func (p *Avro) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (p *Avro) CloseAsync() {
+	if p.registry != nil {
+		p.registry.Close()
+	}
 }
 
 // WaitForClose blocks until the processor has closed down.
@@ -1,8 +1,11 @@
 package processor
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strconv"
@@ -331,3 +334,82 @@ func TestAvroSchemaPathNotExist(t *testing.T) {
 		t.Error("expected error from loading non existant schema file")
 	}
 }
+
+func TestAvroSchemaRegistry(t *testing.T) {
+	schema := `{
+	"type": "record",
+	"name": "foo",
+	"fields": [
+		{ "name": "name", "type": "string" },
+		{ "name": "age", "type": "int" }
+	]
+}`
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schemas/ids/7":
+			w.Write([]byte(`{"schema":` + string(schemaBytes) + `}`))
+		case "/subjects/people-value/versions/latest":
+			w.Write([]byte(`{"subject":"people-value","version":1,"id":7,"schema":` + string(schemaBytes) + `}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	encConf := NewConfig()
+	encConf.Type = TypeAvro
+	encConf.Avro.Operator = "from_json"
+	encConf.Avro.SchemaRegistry.URL = ts.URL
+	encConf.Avro.SchemaRegistry.Subject = "people-value"
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.CloseAsync()
+
+	decConf := NewConfig()
+	decConf.Type = TypeAvro
+	decConf.Avro.Operator = "to_json"
+	decConf.Avro.SchemaRegistry.URL = ts.URL
+
+	dec, err := New(decConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dec.CloseAsync()
+
+	msgs, res := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"ash","age":10}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	encoded := msgs[0].Get(0).Get()
+	if exp, act := []byte{0, 0, 0, 0, 7, 6, 'a', 's', 'h', 20}, encoded; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected encoding: %v != %v", exp, act)
+	}
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := `{"age":10,"name":"ash"}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Unexpected output: %v != %v", exp, act)
+	}
+	if exp, act := "7", msgs[0].Get(0).Metadata().Get("schema_id"); exp != act {
+		t.Errorf("Unexpected schema_id: %v != %v", exp, act)
+	}
+
+	msgs, _ = dec.ProcessMessage(message.New([][]byte{
+		[]byte{0, 0, 0, 0, 8, 6, 'a', 's', 'h', 20},
+	}))
+	if fail := msgs[0].Get(0).Metadata().Get(FailFlagKey); len(fail) == 0 {
+		t.Error("Expected failure flag for unknown schema")
+	}
+}
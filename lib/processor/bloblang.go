@@ -146,6 +146,7 @@ func NewBloblang(
 	if err != nil {
 		return nil, xerrors.Errorf("failed to parse mapping: %w", err)
 	}
+	exec.SetCaches(mgr)
 
 	return &Bloblang{
 		exec: exec,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -178,3 +179,35 @@ func TestBloblangJSONError(t *testing.T) {
 	assert.Equal(t, `this is not valid json`, string(resPart.Get()))
 	assert.Equal(t, `failed to execute mapping assignment at line 2: invalid character 'h' in literal true (expecting 'r')`, resPart.Metadata().Get(types.FailFlagKey))
 }
+
+func TestBloblangCaches(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, memCache.Set("foo", []byte("foo value")))
+
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Bloblang = `
+	value = cache_get("foocache", this.key)
+	count = cache_incr("foocache", this.counter)
+`
+	proc, err := NewBloblang(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	outMsgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"foo","counter":"bar"}`),
+		[]byte(`{"key":"foo","counter":"bar"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, outMsgs, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`{"count":1,"value":"foo value"}`),
+		[]byte(`{"count":2,"value":"foo value"}`),
+	}, message.GetAllBytes(outMsgs[0]))
+}
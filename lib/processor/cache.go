@@ -2,6 +2,7 @@ package processor
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/bloblang/x/field"
@@ -21,10 +22,17 @@ func init() {
 Performs operations against a [cache resource](/docs/components/caches/about)
 for each message, allowing you to store or retrieve data within message payloads.`,
 		Description: `
-This processor will interpolate functions within the ` + "`key` and `value`" + `
-fields individually for each message. This allows you to specify dynamic keys
-and values based on the contents of the message payloads and metadata. You can
-find a list of functions [here](/docs/configuration/interpolation#functions).
+This processor will interpolate functions within the ` + "`key`, `value`," + `
+` + "`old_value` and `ttl`" + ` fields individually for each message. This allows
+you to specify dynamic keys and values based on the contents of the message
+payloads and metadata. You can find a list of functions
+[here](/docs/configuration/interpolation#functions).
+
+The ` + "`ttl`" + ` field overrides the default TTL of the cache for the keys
+that are written, which allows keys with different lifetimes to be stored
+within the same cache. The ` + "`ttl`" + ` field and the ` + "`incr`, `decr`" + `
+and ` + "`compare_and_swap`" + ` operators are supported by the
+` + "`dynamodb`, `memcached`, `memory`, `multilevel` and `redis`" + ` caches.
 
 ## Operators
 
@@ -45,15 +53,42 @@ Retrieve the contents of a cached key and replace the original message payload
 with the result. If the key does not exist the action fails with an error, which
 can be detected with [processor error handling](/docs/configuration/error_handling).
 
+When the cache supports it the keys of all messages of a batch are retrieved
+with a single request.
+
 ### ` + "`delete`" + `
 
 Delete a key and its contents from the cache.  If the key does not exist the
-action is a no-op and will not fail with an error.`,
+action is a no-op and will not fail with an error.
+
+### ` + "`incr`" + `
+
+Atomically add the integer ` + "`value`" + `, which defaults to 1, to the
+value of a key and replace the original message payload with the result. A
+key that does not exist is created with a value of zero before being
+incremented, and its TTL is only set when it is created.
+
+### ` + "`decr`" + `
+
+Atomically subtract the integer ` + "`value`" + `, which defaults to 1, from
+the value of a key and replace the original message payload with the result. A
+key that does not exist is created with a value of zero before being
+decremented, and its TTL is only set when it is created.
+
+### ` + "`compare_and_swap`" + `
+
+Atomically set a key in the cache to a value only if the current value of the
+key matches ` + "`old_value`" + `. When ` + "`old_value`" + ` is empty the
+key is only set if it does not already exist. If the current value does not
+match the action fails with an error, which can be detected with
+[processor error handling](/docs/configuration/error_handling).`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("cache", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
-			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete"),
+			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete", "incr", "decr", "compare_and_swap"),
 			docs.FieldCommon("key", "A key to use with the cache.").SupportsInterpolation(false),
 			docs.FieldCommon("value", "A value to use with the cache (when applicable).").SupportsInterpolation(false),
+			docs.FieldAdvanced("old_value", "The expected current value of a key for the `compare_and_swap` operator.").SupportsInterpolation(false),
+			docs.FieldAdvanced("ttl", "An optional TTL to set for written keys, overriding the default TTL of the cache.", "60s", "${! meta(\"ttl\") }").SupportsInterpolation(false),
 			partsFieldSpec,
 		},
 		Footnotes: `
@@ -94,6 +129,25 @@ using the [` + "`process_map`" + `](/docs/components/processors/process_map) pro
         key: '${!json("message.document_id")}'
     postmap:
       message.document: .
+` + "```" + `
+
+### Counting
+
+The ` + "`incr`" + ` operator can be used in order to count messages within
+windows of time. Here we count the messages of each user within each minute by
+including the current minute in the key, and expire the key once the minute
+has passed:
+
+` + "``` yaml" + `
+- process_map:
+    processors:
+    - cache:
+        cache: TODO
+        operator: incr
+        key: '${!json("user.id")}-${!timestamp("2006-01-02T15:04")}'
+        ttl: 2m
+    postmap:
+      user.messages_this_minute: .
 ` + "```" + ``,
 	}
 }
@@ -107,6 +161,8 @@ type CacheConfig struct {
 	Operator string `json:"operator" yaml:"operator"`
 	Key      string `json:"key" yaml:"key"`
 	Value    string `json:"value" yaml:"value"`
+	OldValue string `json:"old_value" yaml:"old_value"`
+	TTL      string `json:"ttl" yaml:"ttl"`
 }
 
 // NewCacheConfig returns a CacheConfig with default values.
@@ -117,6 +173,8 @@ func NewCacheConfig() CacheConfig {
 		Operator: "set",
 		Key:      "",
 		Value:    "",
+		OldValue: "",
+		TTL:      "",
 	}
 }
 
@@ -131,10 +189,13 @@ type Cache struct {
 
 	parts []int
 
-	key   field.Expression
-	value field.Expression
+	key      field.Expression
+	value    field.Expression
+	oldValue field.Expression
+	ttl      field.Expression
 
 	cache    types.Cache
+	getMulti func(keys ...string) (map[string][]byte, error)
 	operator cacheOperator
 
 	mCount            metrics.StatCounter
@@ -168,6 +229,26 @@ func NewCache(
 		return nil, fmt.Errorf("failed to parse value expression: %v", err)
 	}
 
+	oldValue, err := field.New(conf.Cache.OldValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old value expression: %v", err)
+	}
+
+	var ttl field.Expression
+	if len(conf.Cache.TTL) > 0 {
+		if _, ok := c.(types.CacheExtended); !ok {
+			return nil, fmt.Errorf("cache '%v' does not support TTL overrides", conf.Cache.Cache)
+		}
+		if ttl, err = field.New(conf.Cache.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl expression: %v", err)
+		}
+	}
+
+	var getMulti func(keys ...string) (map[string][]byte, error)
+	if ec, ok := c.(types.CacheExtended); ok && conf.Cache.Operator == "get" {
+		getMulti = ec.GetMulti
+	}
+
 	return &Cache{
 		conf:  conf,
 		log:   log,
@@ -175,10 +256,13 @@ func NewCache(
 
 		parts: conf.Cache.Parts,
 
-		key:   key,
-		value: value,
+		key:      key,
+		value:    value,
+		oldValue: oldValue,
+		ttl:      ttl,
 
 		cache:    c,
+		getMulti: getMulti,
 		operator: op,
 
 		mCount:            stats.GetCounter("count"),
@@ -191,36 +275,69 @@ func NewCache(
 
 //------------------------------------------------------------------------------
 
-type cacheOperator func(key string, value []byte) ([]byte, bool, error)
+type cacheOperator func(key string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error)
 
 func newCacheSetOperator(cache types.Cache) cacheOperator {
-	return func(key string, value []byte) ([]byte, bool, error) {
+	return func(key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+		if ttl != nil {
+			return nil, false, cache.(types.CacheExtended).SetWithTTL(key, value, ttl)
+		}
 		err := cache.Set(key, value)
 		return nil, false, err
 	}
 }
 
 func newCacheAddOperator(cache types.Cache) cacheOperator {
-	return func(key string, value []byte) ([]byte, bool, error) {
+	return func(key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+		if ttl != nil {
+			return nil, false, cache.(types.CacheExtended).AddWithTTL(key, value, ttl)
+		}
 		err := cache.Add(key, value)
 		return nil, false, err
 	}
 }
 
 func newCacheGetOperator(cache types.Cache) cacheOperator {
-	return func(key string, _ []byte) ([]byte, bool, error) {
+	return func(key string, _, _ []byte, _ *time.Duration) ([]byte, bool, error) {
 		result, err := cache.Get(key)
 		return result, true, err
 	}
 }
 
 func newCacheDeleteOperator(cache types.Cache) cacheOperator {
-	return func(key string, _ []byte) ([]byte, bool, error) {
+	return func(key string, _, _ []byte, _ *time.Duration) ([]byte, bool, error) {
 		err := cache.Delete(key)
 		return nil, false, err
 	}
 }
 
+func newCacheIncrOperator(cache types.CacheExtended, sign int64) cacheOperator {
+	return func(key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+		delta := int64(1)
+		if len(value) > 0 {
+			var err error
+			if delta, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return nil, false, fmt.Errorf("failed to parse value as an integer: %v", err)
+			}
+		}
+		result, err := cache.Incr(key, sign*delta, ttl)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.FormatInt(result, 10)), true, nil
+	}
+}
+
+func newCacheCompareAndSwapOperator(cache types.CacheExtended) cacheOperator {
+	return func(key string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error) {
+		if len(oldValue) == 0 {
+			oldValue = nil
+		}
+		err := cache.CompareAndSwap(key, oldValue, value, ttl)
+		return nil, false, err
+	}
+}
+
 func cacheOperatorFromString(operator string, cache types.Cache) (cacheOperator, error) {
 	switch operator {
 	case "set":
@@ -231,23 +348,90 @@ func cacheOperatorFromString(operator string, cache types.Cache) (cacheOperator,
 		return newCacheGetOperator(cache), nil
 	case "delete":
 		return newCacheDeleteOperator(cache), nil
+	case "incr", "decr", "compare_and_swap":
+		ec, ok := cache.(types.CacheExtended)
+		if !ok {
+			return nil, fmt.Errorf("cache does not support operator: %v", operator)
+		}
+		switch operator {
+		case "incr":
+			return newCacheIncrOperator(ec, 1), nil
+		case "decr":
+			return newCacheIncrOperator(ec, -1), nil
+		}
+		return newCacheCompareAndSwapOperator(ec), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
 
 //------------------------------------------------------------------------------
 
+// prefetch retrieves the values of the keys of all targeted messages of a batch
+// with a single request, returns nil if the values should instead be retrieved
+// for each message.
+func (c *Cache) prefetch(msg types.Message) map[string][]byte {
+	if c.getMulti == nil || msg.Len() < 2 {
+		return nil
+	}
+	indexes := c.parts
+	if len(indexes) == 0 {
+		indexes = make([]int, msg.Len())
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+	keys := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		keys = append(keys, c.key.String(i, msg))
+	}
+	values, err := c.getMulti(keys...)
+	if err != nil {
+		c.log.Debugf("Failed to retrieve multiple keys, falling back to individual retrievals: %v\n", err)
+		return nil
+	}
+	return values
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	c.mCount.Incr(1)
 	newMsg := msg.Copy()
 
+	prefetched := c.prefetch(newMsg)
+
 	proc := func(index int, span opentracing.Span, part types.Part) error {
 		key := c.key.String(index, newMsg)
+
+		if prefetched != nil {
+			result, exists := prefetched[key]
+			if !exists {
+				c.mErr.Incr(1)
+				c.log.Debugf("Operator failed for key '%s': %v\n", key, types.ErrKeyNotFound)
+				return types.ErrKeyNotFound
+			}
+			part.Set(result)
+			return nil
+		}
+
 		value := c.value.Bytes(index, newMsg)
+		oldValue := c.oldValue.Bytes(index, newMsg)
+
+		var ttl *time.Duration
+		if c.ttl != nil {
+			ttlStr := c.ttl.String(index, newMsg)
+			if len(ttlStr) > 0 {
+				d, err := time.ParseDuration(ttlStr)
+				if err != nil {
+					c.mErr.Incr(1)
+					c.log.Debugf("TTL '%s' failed to parse for key '%s': %v\n", ttlStr, key, err)
+					return fmt.Errorf("failed to parse ttl: %v", err)
+				}
+				ttl = &d
+			}
+		}
 
-		result, useResult, err := c.operator(key, value)
+		result, useResult, err := c.operator(key, value, oldValue, ttl)
 		if err != nil {
 			if err != types.ErrKeyAlreadyExists {
 				c.mErr.Incr(1)
@@ -3,12 +3,15 @@ package processor
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCacheSet(t *testing.T) {
@@ -325,3 +328,191 @@ func TestCacheDelete(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", err, types.ErrKeyNotFound)
 	}
 }
+
+func TestCacheGetNotExtended(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": struct{ types.Cache }{memCache},
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "get"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	output, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, output, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`{"key":"2"}`),
+	}, message.GetAllBytes(output[0]))
+	assert.False(t, HasFailed(output[0].Get(0)))
+	assert.True(t, HasFailed(output[0].Get(1)))
+
+	conf.Cache.Operator = "incr"
+	_, err = NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.Cache.Operator = "set"
+	conf.Cache.TTL = "1m"
+	_, err = NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestCacheIncrDecr(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\").or(\"\")}"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "incr"
+	incr, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf.Cache.Operator = "decr"
+	decr, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	output, res := incr.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"1","value":5}`),
+		[]byte(`{"key":"2","value":2}`),
+		[]byte(`{"key":"2","value":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, output, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`1`),
+		[]byte(`6`),
+		[]byte(`2`),
+		[]byte(`{"key":"2","value":"nope"}`),
+	}, message.GetAllBytes(output[0]))
+	assert.True(t, HasFailed(output[0].Get(3)))
+
+	output, res = decr.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","value":10}`),
+		[]byte(`{"key":"3"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, output, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`-4`),
+		[]byte(`-1`),
+	}, message.GetAllBytes(output[0]))
+}
+
+func TestCacheCompareAndSwap(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.OldValue = "${!json(\"old\").or(\"\")}"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "compare_and_swap"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	output, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","old":"foo 2","value":"foo 3"}`),
+		[]byte(`{"key":"1","old":"foo 1","value":"foo 4"}`),
+		[]byte(`{"key":"2","value":"bar 1"}`),
+		[]byte(`{"key":"2","value":"bar 2"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, output, 1)
+
+	assert.True(t, HasFailed(output[0].Get(0)))
+	assert.False(t, HasFailed(output[0].Get(1)))
+	assert.False(t, HasFailed(output[0].Get(2)))
+	assert.True(t, HasFailed(output[0].Get(3)))
+
+	v, err := memCache.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, "foo 4", string(v))
+
+	v, err = memCache.Get("2")
+	require.NoError(t, err)
+	assert.Equal(t, "bar 1", string(v))
+}
+
+func TestCacheTTL(t *testing.T) {
+	cacheConf := cache.NewConfig()
+	cacheConf.Memory.CompactionInterval = "1ns"
+	memCache, err := cache.NewMemory(cacheConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "foo"
+	conf.Cache.TTL = "${!json(\"ttl\").or(\"\")}"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "set"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	output, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","ttl":"1ms"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"3","ttl":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, output, 1)
+	assert.True(t, HasFailed(output[0].Get(2)))
+
+	<-time.After(time.Millisecond * 5)
+
+	// Trigger compaction
+	require.NoError(t, memCache.Set("4", []byte("bar")))
+
+	_, err = memCache.Get("1")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	_, err = memCache.Get("2")
+	assert.NoError(t, err)
+
+	_, err = memCache.Get("3")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
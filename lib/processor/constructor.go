@@ -96,6 +96,7 @@ const (
 	TypeHTTP         = "http"
 	TypeInsertPart   = "insert_part"
 	TypeJMESPath     = "jmespath"
+	TypeJoin         = "join"
 	TypeJSON         = "json"
 	TypeJSONSchema   = "json_schema"
 	TypeLambda       = "lambda"
@@ -106,11 +107,13 @@ const (
 	TypeNoop         = "noop"
 	TypeNumber       = "number"
 	TypeParallel     = "parallel"
+	TypeParquet      = "parquet"
 	TypeParseLog     = "parse_log"
 	TypeProcessBatch = "process_batch"
 	TypeProcessDAG   = "process_dag"
 	TypeProcessField = "process_field"
 	TypeProcessMap   = "process_map"
+	TypeProtobuf     = "protobuf"
 	TypeRateLimit    = "rate_limit"
 	TypeRedis        = "redis"
 	TypeResource     = "resource"
@@ -127,6 +130,7 @@ const (
 	TypeThrottle     = "throttle"
 	TypeUnarchive    = "unarchive"
 	TypeWhile        = "while"
+	TypeWindow       = "window"
 	TypeWorkflow     = "workflow"
 	TypeXML          = "xml"
 )
@@ -161,6 +165,7 @@ type Config struct {
 	HTTP         HTTPConfig         `json:"http" yaml:"http"`
 	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
 	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
+	Join         JoinConfig         `json:"join" yaml:"join"`
 	JSON         JSONConfig         `json:"json" yaml:"json"`
 	JSONSchema   JSONSchemaConfig   `json:"json_schema" yaml:"json_schema"`
 	Lambda       LambdaConfig       `json:"lambda" yaml:"lambda"`
@@ -171,11 +176,13 @@ type Config struct {
 	Number       NumberConfig       `json:"number" yaml:"number"`
 	Plugin       interface{}        `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 	Parallel     ParallelConfig     `json:"parallel" yaml:"parallel"`
+	Parquet      ParquetConfig      `json:"parquet" yaml:"parquet"`
 	ParseLog     ParseLogConfig     `json:"parse_log" yaml:"parse_log"`
 	ProcessBatch ForEachConfig      `json:"process_batch" yaml:"process_batch"`
 	ProcessDAG   ProcessDAGConfig   `json:"process_dag" yaml:"process_dag"`
 	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
 	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
+	Protobuf     ProtobufConfig     `json:"protobuf" yaml:"protobuf"`
 	RateLimit    RateLimitConfig    `json:"rate_limit" yaml:"rate_limit"`
 	Redis        RedisConfig        `json:"redis" yaml:"redis"`
 	Resource     string             `json:"resource" yaml:"resource"`
@@ -192,6 +199,7 @@ type Config struct {
 	Throttle     ThrottleConfig     `json:"throttle" yaml:"throttle"`
 	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
 	While        WhileConfig        `json:"while" yaml:"while"`
+	Window       WindowConfig       `json:"window" yaml:"window"`
 	Workflow     WorkflowConfig     `json:"workflow" yaml:"workflow"`
 	XML          XMLConfig          `json:"xml" yaml:"xml"`
 }
@@ -225,6 +233,7 @@ func NewConfig() Config {
 		HTTP:         NewHTTPConfig(),
 		InsertPart:   NewInsertPartConfig(),
 		JMESPath:     NewJMESPathConfig(),
+		Join:         NewJoinConfig(),
 		JSON:         NewJSONConfig(),
 		JSONSchema:   NewJSONSchemaConfig(),
 		Lambda:       NewLambdaConfig(),
@@ -235,11 +244,13 @@ func NewConfig() Config {
 		Number:       NewNumberConfig(),
 		Plugin:       nil,
 		Parallel:     NewParallelConfig(),
+		Parquet:      NewParquetConfig(),
 		ParseLog:     NewParseLogConfig(),
 		ProcessBatch: NewForEachConfig(),
 		ProcessDAG:   NewProcessDAGConfig(),
 		ProcessField: NewProcessFieldConfig(),
 		ProcessMap:   NewProcessMapConfig(),
+		Protobuf:     NewProtobufConfig(),
 		RateLimit:    NewRateLimitConfig(),
 		Redis:        NewRedisConfig(),
 		Resource:     "",
@@ -256,6 +267,7 @@ func NewConfig() Config {
 		Throttle:     NewThrottleConfig(),
 		Unarchive:    NewUnarchiveConfig(),
 		While:        NewWhileConfig(),
+		Window:       NewWindowConfig(),
 		Workflow:     NewWorkflowConfig(),
 		XML:          NewXMLConfig(),
 	}
@@ -0,0 +1,610 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/bloblang/x/mapping"
+	"github.com/Jeffail/benthos/v3/lib/bloblang/x/query"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+	"golang.org/x/xerrors"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJoin] = TypeSpec{
+		constructor: NewJoin,
+		Summary: `
+Correlates messages from two sides of a stream that share a key and arrive
+within a time window of each other, merging each matched pair into a single
+message.`,
+		Description: `
+Each message is assigned to either the ` + "`left`" + ` or ` + "`right`" + ` side
+of the join by the [Bloblang](/docs/guides/bloblang/about) query
+` + "`side`" + `, and a join key is resolved with the query ` + "`key`" + `.
+
+When a message arrives and the opposite side for its key is pending within the
+[cache resource](/docs/components/caches/about) ` + "`cache`" + ` the pending
+half is removed from the cache and the mapping ` + "`result_map`" + ` is
+executed on a document of the form ` + "`{\"left\":<left message>,\"right\":<right message>}`" + `
+in order to produce the joined message. Metadata from both halves is retained,
+with the values of the most recent message taking precedence.
+
+Otherwise the message is stored in the cache until a match arrives, and is not
+emitted downstream. If the same side of a key is received again whilst a message
+is still pending then the newer message is not stored, and is instead emitted
+with an error flag set.
+
+Processors sharing the same cache, either within the same pipeline or across
+multiple Benthos instances, claim a pending message before removing it from the
+cache and therefore a pending message is only ever matched once.
+
+### Expiry
+
+Pending messages that are not matched within the ` + "`window`" + ` period are
+removed from the cache. When ` + "`expired`" + ` is set to ` + "`emit`" + ` the
+expired message is emitted with an error flag set, which means it can be routed
+to a fallback destination using
+[error handling patterns](/docs/configuration/error_handling), and the metadata
+field ` + "`join_side`" + ` indicates the side it belonged to.
+
+The expiry of pending messages is tracked in memory by the processor that
+stored them and is only checked when messages are processed. Messages left
+pending in the cache when the service restarts can still be matched, and if
+they are too old to be matched they are expired when the key is next seen.
+It is therefore recommended to also configure a TTL on the cache itself.`,
+		Footnotes: `
+## Examples
+
+### Orders and Payments
+
+Joining orders and payments consumed from separate Kafka topics by their order
+ID, allowing payments to arrive up to an hour before or after the order:
+
+` + "```yaml" + `
+input:
+  kafka_balanced:
+    addresses: [ TODO ]
+    topics: [ orders, payments ]
+    consumer_group: benthos_join
+
+pipeline:
+  processors:
+  - join:
+      cache: pending
+      key: this.order_id
+      side: 'match meta("kafka_topic") { "orders" => "left", _ => "right" }'
+      window: 1h
+      result_map: |
+        root = this.left
+        root.payment = this.right
+
+resources:
+  caches:
+    pending:
+      redis:
+        url: tcp://localhost:6379
+        expiration: 2h
+` + "```" + ``,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("cache", "The [`cache` resource](/docs/components/caches/about) to store pending messages in."),
+			docs.FieldCommon("key", "A [Bloblang query](/docs/guides/bloblang/about) that resolves the join key of a message.", "this.order_id", `meta("kafka_key")`),
+			docs.FieldCommon("side", "A [Bloblang query](/docs/guides/bloblang/about) that resolves the side of the join a message belongs to, which must be either `left` or `right`.", `match meta("kafka_topic") { "orders" => "left", _ => "right" }`),
+			docs.FieldCommon("window", "The maximum period to wait for a match before a pending message is expired."),
+			docs.FieldCommon("result_map", "A [Bloblang mapping](/docs/guides/bloblang/about) executed on each matched pair in order to produce the joined message."),
+			docs.FieldCommon("expired", "What to do with pending messages that expire without a match.").HasOptions("emit", "drop"),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JoinConfig contains configuration fields for the Join processor.
+type JoinConfig struct {
+	Cache     string `json:"cache" yaml:"cache"`
+	Key       string `json:"key" yaml:"key"`
+	Side      string `json:"side" yaml:"side"`
+	Window    string `json:"window" yaml:"window"`
+	ResultMap string `json:"result_map" yaml:"result_map"`
+	Expired   string `json:"expired" yaml:"expired"`
+}
+
+// NewJoinConfig returns a JoinConfig with default values.
+func NewJoinConfig() JoinConfig {
+	return JoinConfig{
+		Cache:     "",
+		Key:       "",
+		Side:      "",
+		Window:    "1m",
+		ResultMap: "root = this.left.merge(this.right)",
+		Expired:   "emit",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	joinSideLeft  = "left"
+	joinSideRight = "right"
+)
+
+// Claims held by another processor are retried for a short period before
+// giving up.
+const (
+	joinClaimAttempts    = 10
+	joinClaimRetryPeriod = time.Millisecond * 10
+)
+
+// ErrJoinExpired is flagged on pending join messages that expire without being
+// matched.
+var ErrJoinExpired = errors.New("join window expired without a match")
+
+// ErrJoinDuplicate is flagged on join messages that arrive whilst a message of
+// the same key and side is already pending.
+var ErrJoinDuplicate = errors.New("a message of the same join key and side is already pending")
+
+// joinEntry is the serialised form of a pending message stored in the cache.
+type joinEntry struct {
+	ReceivedAt int64             `json:"received_at"`
+	Content    []byte            `json:"content"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+type joinPending struct {
+	side   string
+	expiry time.Time
+}
+
+// Join is a processor that correlates messages from two sides of a stream by
+// a key using a cache to store pending messages.
+type Join struct {
+	log   log.Modular
+	stats metrics.Type
+
+	cache     types.Cache
+	key       query.Function
+	side      query.Function
+	resultMap *mapping.Executor
+	window    time.Duration
+	emitExp   bool
+
+	mut     sync.Mutex
+	pending map[string]joinPending
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mMatched   metrics.StatCounter
+	mStored    metrics.StatCounter
+	mExpired   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewJoin returns a Join processor.
+func NewJoin(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	c, err := mgr.GetCache(conf.Join.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Join{
+		log:     log,
+		stats:   stats,
+		cache:   c,
+		pending: map[string]joinPending{},
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mMatched:   stats.GetCounter("matched"),
+		mStored:    stats.GetCounter("stored"),
+		mExpired:   stats.GetCounter("expired"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	if j.key, err = query.New(conf.Join.Key); err != nil {
+		return nil, xerrors.Errorf("failed to parse key query: %w", err)
+	}
+	if j.side, err = query.New(conf.Join.Side); err != nil {
+		return nil, xerrors.Errorf("failed to parse side query: %w", err)
+	}
+	if j.resultMap, err = mapping.NewExecutor(conf.Join.ResultMap); err != nil {
+		return nil, xerrors.Errorf("failed to parse result mapping: %w", err)
+	}
+	if j.window, err = time.ParseDuration(conf.Join.Window); err != nil {
+		return nil, fmt.Errorf("failed to parse window: %v", err)
+	}
+
+	switch conf.Join.Expired {
+	case "emit":
+		j.emitExp = true
+	case "drop":
+	default:
+		return nil, fmt.Errorf("expired behaviour not recognised: %v", conf.Join.Expired)
+	}
+	return j, nil
+}
+
+//------------------------------------------------------------------------------
+
+func joinCacheKey(key, side string) string {
+	return key + ":" + side
+}
+
+func joinClaimKey(cacheKey string) string {
+	return cacheKey + ":claimed"
+}
+
+func joinOpposite(side string) string {
+	if side == joinSideLeft {
+		return joinSideRight
+	}
+	return joinSideLeft
+}
+
+func newJoinEntry(p types.Part, receivedAt time.Time) joinEntry {
+	e := joinEntry{
+		ReceivedAt: receivedAt.UnixNano(),
+		Content:    p.Get(),
+		Metadata:   map[string]string{},
+	}
+	p.Metadata().Iter(func(k, v string) error {
+		e.Metadata[k] = v
+		return nil
+	})
+	return e
+}
+
+func (e joinEntry) toPart() types.Part {
+	p := message.NewPart(e.Content)
+	for k, v := range e.Metadata {
+		p.Metadata().Set(k, v)
+	}
+	return p
+}
+
+func joinDocument(p types.Part) interface{} {
+	if jObj, err := p.JSON(); err == nil {
+		return jObj
+	}
+	return string(p.Get())
+}
+
+// claim adds a marker key to the cache, which fails if the marker already
+// exists. The marker expires after the window when supported by the cache.
+func (j *Join) claim(claimKey string) error {
+	if ce, ok := j.cache.(types.CacheExtended); ok {
+		return ce.AddWithTTL(claimKey, []byte("t"), &j.window)
+	}
+	return j.cache.Add(claimKey, []byte("t"))
+}
+
+// claimWait claims a marker key as with claim, but waits for a claim made by
+// another processor to be released, since claims are only held whilst a few
+// cache operations are made.
+func (j *Join) claimWait(claimKey string) error {
+	var err error
+	for i := 0; i < joinClaimAttempts; i++ {
+		if err = j.claim(claimKey); err != types.ErrKeyAlreadyExists {
+			return err
+		}
+		time.Sleep(joinClaimRetryPeriod)
+	}
+	return err
+}
+
+func (j *Join) isExpired(e *joinEntry, now time.Time) bool {
+	return now.Sub(time.Unix(0, e.ReceivedAt)) > j.window
+}
+
+// takePending attempts to remove and return a pending message from the cache.
+// The message is claimed before it is removed so that when the cache is shared
+// only one processor can take it.
+func (j *Join) takePending(cacheKey string) (*joinEntry, error) {
+	if _, err := j.cache.Get(cacheKey); err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	claimKey := joinClaimKey(cacheKey)
+	if err := j.claim(claimKey); err != nil {
+		if err == types.ErrKeyAlreadyExists {
+			// Claimed by another processor sharing the cache.
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		if err := j.cache.Delete(claimKey); err != nil {
+			j.log.Errorf("Failed to release claim of pending message '%v': %v\n", cacheKey, err)
+		}
+	}()
+
+	// The message may have been taken before our claim was made.
+	data, err := j.cache.Get(cacheKey)
+	if err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var e joinEntry
+	if err = json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse pending message: %v", err)
+	}
+	if err = j.cache.Delete(cacheKey); err != nil {
+		return nil, err
+	}
+	delete(j.pending, cacheKey)
+	return &e, nil
+}
+
+// takeMatch is called after a message has been stored, and takes a pending
+// message of the opposite side that was stored by another processor sharing
+// the cache whilst neither was able to see the other. Both sides are claimed in
+// a fixed order so that only one processor is able to match them. The stored
+// message is removed unless the taken message has expired.
+func (j *Join) takeMatch(key, side string, now time.Time) (*joinEntry, error) {
+	for _, s := range []string{joinSideLeft, joinSideRight} {
+		claimKey := joinClaimKey(joinCacheKey(key, s))
+		if err := j.claimWait(claimKey); err != nil {
+			if err == types.ErrKeyAlreadyExists {
+				// The claim was never released, in which case the stored
+				// message remains pending.
+				return nil, nil
+			}
+			return nil, err
+		}
+		defer func() {
+			if err := j.cache.Delete(claimKey); err != nil {
+				j.log.Errorf("Failed to release claim of pending message '%v': %v\n", claimKey, err)
+			}
+		}()
+	}
+
+	ownKey, otherKey := joinCacheKey(key, side), joinCacheKey(key, joinOpposite(side))
+	if _, err := j.cache.Get(ownKey); err != nil {
+		if err == types.ErrKeyNotFound {
+			// The stored message has already been matched.
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, err := j.cache.Get(otherKey)
+	if err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var e joinEntry
+	if err = json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse pending message: %v", err)
+	}
+	if err = j.cache.Delete(otherKey); err != nil {
+		return nil, err
+	}
+	delete(j.pending, otherKey)
+	if !j.isExpired(&e, now) {
+		if err = j.cache.Delete(ownKey); err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+func (j *Join) expiredPart(e *joinEntry, side string) types.Part {
+	p := e.toPart()
+	p.Metadata().Set("join_side", side)
+	FlagErr(p, ErrJoinExpired)
+	return p
+}
+
+// expire removes all pending messages tracked by this processor that have
+// passed their expiry.
+func (j *Join) expire(now time.Time) []types.Part {
+	var cacheKeys []string
+	for k, p := range j.pending {
+		if !p.expiry.After(now) {
+			cacheKeys = append(cacheKeys, k)
+		}
+	}
+	sort.Strings(cacheKeys)
+
+	var parts []types.Part
+	for _, k := range cacheKeys {
+		side := j.pending[k].side
+		e, err := j.takePending(k)
+		delete(j.pending, k)
+		if err != nil {
+			j.mErr.Incr(1)
+			j.log.Errorf("Failed to expire pending message '%v': %v\n", k, err)
+			continue
+		}
+		if e == nil {
+			// Matched by another processor sharing the cache.
+			continue
+		}
+		j.mExpired.Incr(1)
+		if j.emitExp {
+			parts = append(parts, j.expiredPart(e, side))
+		}
+	}
+	return parts
+}
+
+func (j *Join) join(key, side string, part types.Part, other *joinEntry) (types.Part, error) {
+	otherPart := other.toPart()
+
+	docs := map[string]interface{}{}
+	docs[side] = joinDocument(part)
+	docs[joinOpposite(side)] = joinDocument(otherPart)
+
+	joined := message.NewPart(nil)
+	if err := joined.SetJSON(docs); err != nil {
+		return nil, err
+	}
+	meta := joined.Metadata()
+	otherPart.Metadata().Iter(func(k, v string) error {
+		meta.Set(k, v)
+		return nil
+	})
+	part.Metadata().Iter(func(k, v string) error {
+		meta.Set(k, v)
+		return nil
+	})
+	meta.Set("join_key", key)
+
+	msg := message.New(nil)
+	msg.Append(joined)
+	return j.resultMap.MapPart(0, msg)
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (j *Join) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	j.mCount.Incr(1)
+
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	now := time.Now()
+	newParts := j.expire(now)
+
+	msg.Iter(func(i int, part types.Part) error {
+		doc := joinDocument(part)
+		ctx := query.FunctionContext{
+			Value: &doc,
+			Maps:  map[string]query.Function{},
+			Vars:  map[string]interface{}{},
+			Index: i,
+			Msg:   msg,
+		}
+
+		fail := func(err error) {
+			j.mErr.Incr(1)
+			j.log.Errorf("%v\n", err)
+			p := part.Copy()
+			FlagErr(p, err)
+			newParts = append(newParts, p)
+		}
+
+		keyV, err := j.key.Exec(ctx)
+		if err != nil {
+			fail(fmt.Errorf("failed to resolve join key: %v", err))
+			return nil
+		}
+		key := query.IToString(keyV)
+
+		sideV, err := j.side.Exec(ctx)
+		if err != nil {
+			fail(fmt.Errorf("failed to resolve join side: %v", err))
+			return nil
+		}
+		side := query.IToString(sideV)
+		if side != joinSideLeft && side != joinSideRight {
+			fail(fmt.Errorf("join side must be either left or right, received: %v", side))
+			return nil
+		}
+
+		otherSide := joinOpposite(side)
+		other, err := j.takePending(joinCacheKey(key, otherSide))
+		if err != nil {
+			fail(fmt.Errorf("failed to access cache: %v", err))
+			return nil
+		}
+		if other != nil && j.isExpired(other, now) {
+			j.mExpired.Incr(1)
+			if j.emitExp {
+				newParts = append(newParts, j.expiredPart(other, otherSide))
+			}
+			other = nil
+		}
+
+		if other == nil {
+			cacheKey := joinCacheKey(key, side)
+			entryBytes, err := json.Marshal(newJoinEntry(part, now))
+			if err == nil {
+				err = j.cache.Add(cacheKey, entryBytes)
+			}
+			if err == types.ErrKeyAlreadyExists {
+				fail(ErrJoinDuplicate)
+				return nil
+			}
+			if err != nil {
+				fail(fmt.Errorf("failed to store pending message: %v", err))
+				return nil
+			}
+
+			// A message of the opposite side may have been stored by another
+			// processor sharing the cache since it was checked for.
+			if other, err = j.takeMatch(key, side, now); err != nil {
+				j.mErr.Incr(1)
+				j.log.Errorf("Failed to match stored message '%v': %v\n", cacheKey, err)
+				other = nil
+			}
+			if other != nil && j.isExpired(other, now) {
+				j.mExpired.Incr(1)
+				if j.emitExp {
+					newParts = append(newParts, j.expiredPart(other, otherSide))
+				}
+				other = nil
+			}
+			if other == nil {
+				j.pending[cacheKey] = joinPending{
+					side:   side,
+					expiry: now.Add(j.window),
+				}
+				j.mStored.Incr(1)
+				return nil
+			}
+		}
+
+		joined, err := j.join(key, side, part, other)
+		if err != nil {
+			fail(fmt.Errorf("failed to map joined message: %v", err))
+			return nil
+		}
+		j.mMatched.Incr(1)
+		if joined != nil {
+			newParts = append(newParts, joined)
+		}
+		return nil
+	})
+
+	if len(newParts) == 0 {
+		return nil, response.NewAck()
+	}
+
+	newMsg := message.New(nil)
+	newMsg.SetAll(newParts)
+
+	j.mBatchSent.Incr(1)
+	j.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (j *Join) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (j *Join) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
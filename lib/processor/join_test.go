@@ -0,0 +1,323 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJoinTestMgr(t *testing.T) (*fakeMgr, types.Cache) {
+	t.Helper()
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}, memCache
+}
+
+func TestJoinMatch(t *testing.T) {
+	mgr, memCache := newJoinTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = `match meta("topic") { "orders" => "left", _ => "right" }`
+	conf.Join.ResultMap = `
+root = this.left
+root.paid = this.right.amount
+`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	order := message.New([][]byte{[]byte(`{"id":"a","item":"bananas"}`)})
+	order.Get(0).Metadata().Set("topic", "orders").Set("foo", "from order")
+
+	msgs, res := proc.ProcessMessage(order)
+	require.Len(t, msgs, 0)
+	require.NoError(t, res.Error())
+
+	_, err = memCache.Get("a:left")
+	require.NoError(t, err)
+
+	payment := message.New([][]byte{[]byte(`{"id":"a","amount":12}`)})
+	payment.Get(0).Metadata().Set("topic", "payments").Set("bar", "from payment")
+
+	msgs, res = proc.ProcessMessage(payment)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+
+	part := msgs[0].Get(0)
+	assert.Equal(t, `{"id":"a","item":"bananas","paid":12}`, string(part.Get()))
+	assert.Equal(t, "from order", part.Metadata().Get("foo"))
+	assert.Equal(t, "from payment", part.Metadata().Get("bar"))
+	assert.Equal(t, "payments", part.Metadata().Get("topic"))
+	assert.Equal(t, "a", part.Metadata().Get("join_key"))
+
+	_, err = memCache.Get("a:left")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestJoinBatch(t *testing.T) {
+	mgr, _ := newJoinTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = "this.side"
+	conf.Join.ResultMap = `
+root.l = this.left.l
+root.r = this.right.r
+`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"right","r":1}`),
+		[]byte(`{"id":"b","side":"left","l":2}`),
+		[]byte(`{"id":"a","side":"left","l":3}`),
+		[]byte(`{"id":"c","side":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, `{"l":3,"r":1}`, string(msgs[0].Get(0).Get()))
+
+	assert.Equal(t, `{"id":"c","side":"nope"}`, string(msgs[0].Get(1).Get()))
+	assert.NotEqual(t, "", msgs[0].Get(1).Metadata().Get(FailFlagKey))
+}
+
+func TestJoinExpired(t *testing.T) {
+	mgr, memCache := newJoinTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = "this.side"
+	conf.Join.Window = "10ms"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"left"}`),
+	}))
+	require.Len(t, msgs, 0)
+
+	<-time.After(time.Millisecond * 50)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"b","side":"left"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+
+	part := msgs[0].Get(0)
+	assert.Equal(t, `{"id":"a","side":"left"}`, string(part.Get()))
+	assert.Equal(t, "left", part.Metadata().Get("join_side"))
+	assert.Equal(t, ErrJoinExpired.Error(), part.Metadata().Get(FailFlagKey))
+
+	_, err = memCache.Get("a:left")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestJoinExpiredDrop(t *testing.T) {
+	mgr, _ := newJoinTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = "this.side"
+	conf.Join.Window = "10ms"
+	conf.Join.Expired = "drop"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"left"}`),
+	}))
+	require.Len(t, msgs, 0)
+
+	<-time.After(time.Millisecond * 50)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"right"}`),
+	}))
+	require.Len(t, msgs, 0)
+	require.NoError(t, res.Error())
+}
+
+func TestJoinDuplicate(t *testing.T) {
+	mgr, _ := newJoinTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = "this.side"
+	conf.Join.ResultMap = `
+root.l = this.left.l
+root.r = this.right.r
+`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"left","l":1}`),
+		[]byte(`{"id":"a","side":"left","l":2}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.Equal(t, `{"id":"a","side":"left","l":2}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, ErrJoinDuplicate.Error(), msgs[0].Get(0).Metadata().Get(FailFlagKey))
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"right","r":3}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"l":1,"r":3}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestJoinClaimed(t *testing.T) {
+	mgr, memCache := newJoinTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = "this.side"
+
+	procA, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	procB, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := procA.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"left"}`),
+	}))
+	require.Len(t, msgs, 0)
+
+	// Simulate another processor having claimed the pending message.
+	require.NoError(t, memCache.Add("a:left:claimed", []byte("t")))
+
+	msgs, _ = procB.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"right"}`),
+	}))
+	require.Len(t, msgs, 0)
+
+	_, err = memCache.Get("a:left")
+	assert.NoError(t, err)
+	_, err = memCache.Get("a:right")
+	assert.NoError(t, err)
+
+	// Once released the claim is made and removed.
+	require.NoError(t, memCache.Delete("a:left:claimed"))
+	require.NoError(t, memCache.Delete("a:right"))
+
+	msgs, _ = procB.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","side":"right"}`),
+	}))
+	require.Len(t, msgs, 1)
+
+	_, err = memCache.Get("a:left")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+	_, err = memCache.Get("a:left:claimed")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+// joinSlowCache delays adding keys in order to widen the window where two
+// processors check for each other before storing their messages.
+type joinSlowCache struct {
+	types.Cache
+}
+
+func (c joinSlowCache) Add(key string, value []byte) error {
+	<-time.After(time.Millisecond)
+	return c.Cache.Add(key, value)
+}
+
+func TestJoinConcurrent(t *testing.T) {
+	_, memCache := newJoinTestMgr(t)
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": joinSlowCache{Cache: memCache},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Cache = "foocache"
+	conf.Join.Key = "this.id"
+	conf.Join.Side = "this.side"
+
+	procA, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	procB, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	// Both halves of each key are processed at the same time by separate
+	// processors sharing the cache, and must be matched exactly once.
+	n := 50
+	matched := map[string]int{}
+	for i := 0; i < n; i++ {
+		results := make(chan []types.Message, 2)
+		var wg sync.WaitGroup
+		for _, proc := range []Type{procA, procB} {
+			wg.Add(1)
+			side := "left"
+			if proc == procB {
+				side = "right"
+			}
+			go func(proc Type, doc string) {
+				defer wg.Done()
+				msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(doc)}))
+				results <- msgs
+			}(proc, fmt.Sprintf(`{"id":"%v","side":"%v"}`, i, side))
+		}
+		wg.Wait()
+		close(results)
+
+		for msgs := range results {
+			for _, m := range msgs {
+				m.Iter(func(i int, p types.Part) error {
+					assert.False(t, HasFailed(p))
+					matched[p.Metadata().Get("join_key")]++
+					return nil
+				})
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		key := strconv.Itoa(i)
+		assert.Equal(t, 1, matched[key], key)
+		for _, side := range []string{"left", "right"} {
+			_, err = memCache.Get(key + ":" + side)
+			assert.Equal(t, types.ErrKeyNotFound, err, key+":"+side)
+		}
+	}
+}
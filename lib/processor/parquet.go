@@ -0,0 +1,283 @@
+package processor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/parquet"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParquet] = TypeSpec{
+		constructor: NewParquet,
+		Summary: `
+Encodes all the JSON messages of a batch into a single message containing a
+[Parquet](https://parquet.apache.org/) file.`,
+		Description: `
+Each message of the batch must be a JSON object, where the fields of the object
+are the columns of a row. Only flat schemas are supported, and therefore values
+that are objects or arrays can only be written to ` + "`UTF8`" + ` or
+` + "`BYTE_ARRAY`" + ` columns, in which case they are serialised as JSON.
+
+The resulting file contains a single row group and adopts the metadata of the
+_first_ message part of the batch. Combined with an output such as
+` + "[`s3`](/docs/components/outputs/s3)" + `,
+` + "[`files`](/docs/components/outputs/files)" + ` or
+` + "[`hdfs`](/docs/components/outputs/hdfs)" + ` this results in one Parquet
+file written per batch.
+
+### Schema
+
+When a schema is not specified it is inferred from the first batch processed and
+is then used for all subsequent batches. Inferred columns are optional and
+sorted by name. Numbers are inferred as ` + "`INT64`" + ` columns unless a
+value has a fractional part, in which case the column is ` + "`DOUBLE`" + `, and
+fields with values of differing types, objects or arrays are inferred as
+` + "`UTF8`" + ` columns.
+
+When a later batch contains fields that are missing from the inferred schema
+the schema is extended with optional columns for them, and an ` + "`INT64`" + `
+column is widened to ` + "`DOUBLE`" + ` when a batch contains fractional values
+for it. Since files written before a schema is extended lack those columns it
+is recommended to specify a schema explicitly for production pipelines, in
+which case batches containing fields that are not within the schema fail to be
+encoded.
+
+Valid column types are ` + "`BOOLEAN`, `INT32`, `INT64`, `FLOAT`, `DOUBLE`, `BYTE_ARRAY` and `UTF8`" + `.
+
+If a batch fails to be encoded then all of its messages are flagged as failed
+and are left unchanged. This can be handled with
+[standard error handling methods](/docs/configuration/error_handling).`,
+		UsesBatches: true,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"schema", "A list of columns, each with a `name`, a `type` and an `optional` flag. If empty the schema is inferred from the batches processed.",
+				[]interface{}{
+					map[string]interface{}{"name": "id", "type": "INT64"},
+					map[string]interface{}{"name": "name", "type": "UTF8", "optional": true},
+				},
+			),
+			docs.FieldCommon("compression", "The compression codec to apply to each column.").HasOptions("uncompressed", "snappy", "gzip", "zstd"),
+		},
+		Footnotes: `
+## Examples
+
+In order to write batches of JSON documents to S3 as Parquet files we can batch
+at the output and encode each batch with a ` + "`parquet`" + ` processor:
+
+` + "```yaml" + `
+output:
+  s3:
+    bucket: TODO
+    path: ${!count("files")}-${!timestamp_unix_nano()}.parquet
+    batching:
+      count: 1000
+      period: 1m
+      processors:
+        - parquet:
+            compression: snappy
+            schema:
+              - name: id
+                type: INT64
+              - name: name
+                type: UTF8
+                optional: true
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetConfig contains configuration fields for the Parquet processor.
+type ParquetConfig struct {
+	Schema      []parquet.Column `json:"schema" yaml:"schema"`
+	Compression string           `json:"compression" yaml:"compression"`
+}
+
+// NewParquetConfig returns a ParquetConfig with default values.
+func NewParquetConfig() ParquetConfig {
+	return ParquetConfig{
+		Schema:      []parquet.Column{},
+		Compression: "snappy",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Parquet is a processor that encodes a batch of JSON documents as a single
+// Parquet file.
+type Parquet struct {
+	conf ParquetConfig
+
+	inferSchema bool
+	columns     []parquet.Column
+	encoder     *parquet.Encoder
+	encoderMut  sync.Mutex
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewParquet returns a Parquet processor.
+func NewParquet(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	p := &Parquet{
+		conf:  conf.Parquet,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSucc:      stats.GetCounter("success"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	if len(conf.Parquet.Schema) > 0 {
+		var err error
+		if p.encoder, err = parquet.NewEncoder(conf.Parquet.Schema, conf.Parquet.Compression); err != nil {
+			return nil, fmt.Errorf("failed to create encoder: %v", err)
+		}
+	} else if err := parquet.ValidateCompression(conf.Parquet.Compression); err != nil {
+		return nil, err
+	} else {
+		p.inferSchema = true
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// getEncoder returns the encoder of the processor. When a schema has not been
+// configured it is inferred from each batch of documents and merged with the
+// schema inferred from previous batches.
+func (p *Parquet) getEncoder(docs []interface{}) (*parquet.Encoder, error) {
+	p.encoderMut.Lock()
+	defer p.encoderMut.Unlock()
+
+	if !p.inferSchema {
+		return p.encoder, nil
+	}
+
+	columns, err := parquet.InferColumns(docs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to infer schema: %v", err)
+	}
+	merged, changed := parquet.MergeColumns(p.columns, columns)
+	if p.encoder != nil && !changed {
+		return p.encoder, nil
+	}
+
+	encoder, err := parquet.NewEncoder(merged, p.conf.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encoder from inferred schema: %v", err)
+	}
+	if p.encoder == nil {
+		p.log.Debugf("Inferred parquet schema: %v\n", merged)
+	} else {
+		p.log.Infof("Extended inferred parquet schema: %v\n", merged)
+	}
+	p.encoder, p.columns = encoder, merged
+	return p.encoder, nil
+}
+
+func (p *Parquet) encode(msg types.Message) (types.Part, error) {
+	docs := make([]interface{}, msg.Len())
+	rows := make([]map[string]interface{}, msg.Len())
+	err := msg.Iter(func(i int, part types.Part) error {
+		doc, jerr := part.JSON()
+		if jerr != nil {
+			return fmt.Errorf("failed to parse message %v as JSON: %v", i, jerr)
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected message %v to be a JSON object, found %T", i, doc)
+		}
+		docs[i], rows[i] = doc, obj
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := p.getEncoder(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := encoder.Encode(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %v", err)
+	}
+
+	newPart := msg.Get(0).Copy()
+	newPart.Set(file)
+	return newPart, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Parquet) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	if msg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	p.mSent.Incr(1)
+	p.mBatchSent.Incr(1)
+
+	newMsg := msg.Copy()
+
+	spans := tracing.CreateChildSpans(TypeParquet, newMsg)
+	newPart, err := p.encode(msg)
+	if err != nil {
+		newMsg.Iter(func(i int, part types.Part) error {
+			FlagErr(part, err)
+			spans[i].LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+			return nil
+		})
+		p.log.Errorf("Failed to create parquet file: %v\n", err)
+		p.mErr.Incr(1)
+	} else {
+		p.mSucc.Incr(1)
+		newMsg.SetAll([]types.Part{newPart})
+	}
+	for _, s := range spans {
+		s.Finish()
+	}
+
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Parquet) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Parquet) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,142 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/util/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Parquet.Compression = "nope"
+
+	_, err := NewParquet(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Parquet.Schema = []parquet.Column{{Name: "a", Type: "NOPE"}}
+
+	_, err = NewParquet(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestParquetSchema(t *testing.T) {
+	conf := NewConfig()
+	conf.Parquet.Compression = "uncompressed"
+	conf.Parquet.Schema = []parquet.Column{
+		{Name: "id", Type: parquet.TypeInt64},
+		{Name: "name", Type: parquet.TypeUTF8, Optional: true},
+	}
+
+	proc, err := NewParquet(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo"}`),
+		[]byte(`{"id":2}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+
+	part := msgs[0].Get(0)
+	assert.False(t, HasFailed(part))
+	assert.Equal(t, "bar", part.Metadata().Get("foo"))
+
+	file := part.Get()
+	assert.Equal(t, "PAR1", string(file[:4]))
+	assert.Equal(t, "PAR1", string(file[len(file)-4:]))
+
+	footerLen := binary.LittleEndian.Uint32(file[len(file)-8:])
+	footer := file[len(file)-8-int(footerLen) : len(file)-8]
+	assert.True(t, bytes.Contains(footer, []byte("id")))
+	assert.True(t, bytes.Contains(footer, []byte("name")))
+	assert.True(t, bytes.Contains(file, []byte("foo")))
+
+	// Fields that are not within a configured schema fail the batch.
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":3,"unknown":true}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, `{"id":3,"unknown":true}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestParquetInferredSchema(t *testing.T) {
+	conf := NewConfig()
+
+	proc, err := NewParquet(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo"}`),
+		[]byte(`{"id":2}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+
+	// New fields and fractional values extend the inferred schema.
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":1.5,"added":true}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, []parquet.Column{
+		{Name: "id", Type: parquet.TypeDouble, Optional: true},
+		{Name: "name", Type: parquet.TypeUTF8, Optional: true},
+		{Name: "added", Type: parquet.TypeBoolean, Optional: true},
+	}, proc.(*Parquet).columns)
+
+	file := msgs[0].Get(0).Get()
+	footerLen := binary.LittleEndian.Uint32(file[len(file)-8:])
+	footer := file[len(file)-8-int(footerLen) : len(file)-8]
+	assert.True(t, bytes.Contains(footer, []byte("added")))
+
+	// Existing columns keep their type, and therefore a later batch with an
+	// incompatible type fails.
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, `{"id":"nope"}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestParquetBadInput(t *testing.T) {
+	conf := NewConfig()
+
+	proc, err := NewParquet(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := [][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`not json`),
+		[]byte(`[1,2,3]`),
+	}
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 3, msgs[0].Len())
+	for i, exp := range input {
+		assert.True(t, HasFailed(msgs[0].Get(i)))
+		assert.Equal(t, exp, msgs[0].Get(i).Get())
+	}
+}
@@ -0,0 +1,462 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/protobuf"
+	"github.com/Jeffail/benthos/v3/lib/util/schemaregistry"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeProtobuf] = TypeSpec{
+		constructor: NewProtobuf,
+		Summary: `
+Performs conversions between JSON documents and protobuf messages.`,
+		Description: `
+EXPERIMENTAL: This processor is considered experimental and is therefore subject
+to change outside of major version releases.
+
+Schemas are either loaded from disk or obtained from a schema registry.
+
+## Loading Schemas
+
+The fields ` + "`import_paths`" + ` and ` + "`descriptor_sets`" + ` load
+schemas from disk. Each import path is a directory that is walked for ` + "`.proto`" + `
+files, where imports between files are resolved relative to the directory, and
+each descriptor set is a file containing a compiled ` + "`FileDescriptorSet`" + `,
+which can be created with ` + "`protoc --include_imports --descriptor_set_out`" + `.
+The well known types (` + "`google/protobuf/*.proto`" + `) can be imported
+without being present on disk.
+
+The message type to convert is selected with the field ` + "`message`" + `,
+and messages are plain binary encoded protobuf.
+
+## Schema Registry
+
+When a ` + "`schema_registry`" + ` URL is configured schemas are obtained from a
+[Confluent compatible schema registry](https://docs.confluent.io/current/schema-registry/index.html),
+and messages are expected to be framed with the registry wire format, which is a
+zero magic byte followed by a four byte schema ID and a list of indexes that
+identify the message type within the schema.
+
+Schemas that reference other schemas are resolved by fetching the referenced
+subject versions. When decoding messages the type is derived from the schema ID
+and message indexes of each message, and the metadata field ` + "`schema_id`" + `
+is set on the resulting message. When encoding documents the latest schema of
+the configured subject is used, and the field ` + "`message`" + ` is optional,
+defaulting to the first message of the schema.
+
+## Operators
+
+### ` + "`to_json`" + `
+
+Converts protobuf messages into JSON documents.
+
+### ` + "`from_json`" + `
+
+Converts JSON documents into protobuf messages.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
+			docs.FieldCommon("message", "The fully qualified name of the message type to convert.", "foo.bar.Person"),
+			docs.FieldCommon("import_paths", "A list of directories containing `.proto` files to load.", []string{"./schemas"}),
+			docs.FieldAdvanced("descriptor_sets", "A list of files containing compiled `FileDescriptorSet`s to load.", []string{"./schemas.pb"}),
+			schemaregistry.FieldSpec(),
+			partsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProtobufConfig contains configuration fields for the Protobuf processor.
+type ProtobufConfig struct {
+	Parts          []int                 `json:"parts" yaml:"parts"`
+	Operator       string                `json:"operator" yaml:"operator"`
+	Message        string                `json:"message" yaml:"message"`
+	ImportPaths    []string              `json:"import_paths" yaml:"import_paths"`
+	DescriptorSets []string              `json:"descriptor_sets" yaml:"descriptor_sets"`
+	SchemaRegistry schemaregistry.Config `json:"schema_registry" yaml:"schema_registry"`
+}
+
+// NewProtobufConfig returns a ProtobufConfig with default values.
+func NewProtobufConfig() ProtobufConfig {
+	return ProtobufConfig{
+		Parts:          []int{},
+		Operator:       "to_json",
+		Message:        "",
+		ImportPaths:    []string{},
+		DescriptorSets: []string{},
+		SchemaRegistry: schemaregistry.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type protobufOperator func(part types.Part) error
+
+func newProtobufToJSONOperator(md protoreflect.MessageDescriptor) protobufOperator {
+	return func(part types.Part) error {
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(part.Get(), msg); err != nil {
+			return fmt.Errorf("failed to unmarshal protobuf message '%v': %v", md.FullName(), err)
+		}
+		return protobufSetJSON(part, msg)
+	}
+}
+
+func newProtobufFromJSONOperator(md protoreflect.MessageDescriptor) protobufOperator {
+	return func(part types.Part) error {
+		msg := dynamicpb.NewMessage(md)
+		if err := protojson.Unmarshal(part.Get(), msg); err != nil {
+			return fmt.Errorf("failed to convert JSON to protobuf message '%v': %v", md.FullName(), err)
+		}
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal protobuf message '%v': %v", md.FullName(), err)
+		}
+		part.Set(payload)
+		return nil
+	}
+}
+
+func strToProtobufOperator(opStr string, md protoreflect.MessageDescriptor) (protobufOperator, error) {
+	switch opStr {
+	case "to_json":
+		return newProtobufToJSONOperator(md), nil
+	case "from_json":
+		return newProtobufFromJSONOperator(md), nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", opStr)
+}
+
+//------------------------------------------------------------------------------
+
+// protobufRegistrySchemas builds and caches file descriptors of schemas
+// obtained from a registry.
+type protobufRegistrySchemas struct {
+	client *schemaregistry.Client
+
+	mut   sync.Mutex
+	files map[int]protoreflect.FileDescriptor
+}
+
+func (p *protobufRegistrySchemas) collectReferences(refs []schemaregistry.Reference, sources map[string]string) error {
+	for _, ref := range refs {
+		if _, exists := sources[ref.Name]; exists {
+			continue
+		}
+		schema, err := p.client.GetBySubjectVersion(ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("failed to obtain referenced schema '%v': %v", ref.Name, err)
+		}
+		sources[ref.Name] = schema.Schema
+		if err = p.collectReferences(schema.References, sources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *protobufRegistrySchemas) fileFor(schema *schemaregistry.Schema) (protoreflect.FileDescriptor, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if fd, exists := p.files[schema.ID]; exists {
+		return fd, nil
+	}
+	if schema.Type != schemaregistry.TypeProtobuf {
+		return nil, fmt.Errorf("schema %v is of type %v, expected %v", schema.ID, schema.Type, schemaregistry.TypeProtobuf)
+	}
+
+	rootPath := fmt.Sprintf("schema_registry/%v.proto", schema.ID)
+	sources := map[string]string{
+		rootPath: schema.Schema,
+	}
+	if err := p.collectReferences(schema.References, sources); err != nil {
+		return nil, err
+	}
+
+	files, err := protobuf.ParseFiles(sources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %v: %v", schema.ID, err)
+	}
+	fd, err := files.FindFileByPath(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	p.files[schema.ID] = fd
+	return fd, nil
+}
+
+func protobufMessageByIndexes(fd protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	var md protoreflect.MessageDescriptor
+	msgs := fd.Messages()
+	for _, i := range indexes {
+		if i < 0 || i >= msgs.Len() {
+			return nil, fmt.Errorf("message index %v is out of bounds", indexes)
+		}
+		md = msgs.Get(i)
+		msgs = md.Messages()
+	}
+	if md == nil {
+		return nil, fmt.Errorf("message index %v is out of bounds", indexes)
+	}
+	return md, nil
+}
+
+func protobufMessageIndexes(md protoreflect.MessageDescriptor) []int {
+	var indexes []int
+	var d protoreflect.Descriptor = md
+	for {
+		indexes = append([]int{d.Index()}, indexes...)
+		if d = d.Parent(); d == nil {
+			break
+		}
+		if _, isFile := d.(protoreflect.FileDescriptor); isFile {
+			break
+		}
+	}
+	return indexes
+}
+
+// protobufSetJSON sets the contents of a part to a JSON serialised protobuf
+// message. The output of protojson is deliberately unstable and therefore it
+// is parsed and set as a structured document.
+func protobufSetJSON(part types.Part, msg proto.Message) error {
+	jBytes, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to convert protobuf message to JSON: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(jBytes))
+	dec.UseNumber()
+
+	var jObj interface{}
+	if err = dec.Decode(&jObj); err != nil {
+		return fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	if err = part.SetJSON(jObj); err != nil {
+		return fmt.Errorf("failed to set JSON: %v", err)
+	}
+	return nil
+}
+
+func newProtobufRegistryToJSONOperator(schemas *protobufRegistrySchemas) protobufOperator {
+	return func(part types.Part) error {
+		id, remaining, err := schemaregistry.DecodeHeader(part.Get())
+		if err != nil {
+			return err
+		}
+		indexes, payload, err := schemaregistry.DecodeMessageIndexes(remaining)
+		if err != nil {
+			return err
+		}
+		schema, err := schemas.client.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("failed to obtain schema %v: %v", id, err)
+		}
+		fd, err := schemas.fileFor(schema)
+		if err != nil {
+			return err
+		}
+		md, err := protobufMessageByIndexes(fd, indexes)
+		if err != nil {
+			return err
+		}
+
+		msg := dynamicpb.NewMessage(md)
+		if err = proto.Unmarshal(payload, msg); err != nil {
+			return fmt.Errorf("failed to unmarshal protobuf message '%v': %v", md.FullName(), err)
+		}
+		if err = protobufSetJSON(part, msg); err != nil {
+			return err
+		}
+		part.Metadata().Set("schema_id", strconv.Itoa(id))
+		return nil
+	}
+}
+
+func newProtobufRegistryFromJSONOperator(message string, schemas *protobufRegistrySchemas) protobufOperator {
+	return func(part types.Part) error {
+		schema, err := schemas.client.GetLatest("")
+		if err != nil {
+			return fmt.Errorf("failed to obtain latest schema: %v", err)
+		}
+		fd, err := schemas.fileFor(schema)
+		if err != nil {
+			return err
+		}
+
+		var md protoreflect.MessageDescriptor
+		if len(message) > 0 {
+			for i := 0; md == nil && i < fd.Messages().Len(); i++ {
+				md = protobufFindNested(fd.Messages().Get(i), protoreflect.FullName(message))
+			}
+			if md == nil {
+				return fmt.Errorf("message '%v' not found in schema %v", message, schema.ID)
+			}
+		} else if fd.Messages().Len() > 0 {
+			md = fd.Messages().Get(0)
+		} else {
+			return fmt.Errorf("schema %v does not contain any messages", schema.ID)
+		}
+
+		msg := dynamicpb.NewMessage(md)
+		if err = protojson.Unmarshal(part.Get(), msg); err != nil {
+			return fmt.Errorf("failed to convert JSON to protobuf message '%v': %v", md.FullName(), err)
+		}
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal protobuf message '%v': %v", md.FullName(), err)
+		}
+		part.Set(schemaregistry.EncodeHeader(
+			schema.ID, schemaregistry.EncodeMessageIndexes(protobufMessageIndexes(md), payload),
+		))
+		return nil
+	}
+}
+
+func protobufFindNested(md protoreflect.MessageDescriptor, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	if md.FullName() == name {
+		return md
+	}
+	for i := 0; i < md.Messages().Len(); i++ {
+		if found := protobufFindNested(md.Messages().Get(i), name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func strToProtobufRegistryOperator(opStr, message string, schemas *protobufRegistrySchemas) (protobufOperator, error) {
+	switch opStr {
+	case "to_json":
+		return newProtobufRegistryToJSONOperator(schemas), nil
+	case "from_json":
+		return newProtobufRegistryFromJSONOperator(message, schemas), nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", opStr)
+}
+
+//------------------------------------------------------------------------------
+
+// Protobuf is a processor that performs conversions between JSON documents and
+// protobuf messages.
+type Protobuf struct {
+	parts    []int
+	operator protobufOperator
+	registry *schemaregistry.Client
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewProtobuf returns a Protobuf processor.
+func NewProtobuf(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	p := &Protobuf{
+		parts: conf.Protobuf.Parts,
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	if len(conf.Protobuf.SchemaRegistry.URL) == 0 {
+		if len(conf.Protobuf.ImportPaths) == 0 && len(conf.Protobuf.DescriptorSets) == 0 {
+			return nil, fmt.Errorf("either import_paths, descriptor_sets or a schema_registry url must be specified")
+		}
+		if len(conf.Protobuf.Message) == 0 {
+			return nil, fmt.Errorf("a message type must be specified")
+		}
+		files, err := protobuf.LoadFiles(conf.Protobuf.ImportPaths, conf.Protobuf.DescriptorSets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schemas: %v", err)
+		}
+		md, err := protobuf.FindMessage(files, conf.Protobuf.Message)
+		if err != nil {
+			return nil, err
+		}
+		if p.operator, err = strToProtobufOperator(conf.Protobuf.Operator, md); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	if p.registry, err = schemaregistry.New(conf.Protobuf.SchemaRegistry, log, stats); err != nil {
+		return nil, fmt.Errorf("failed to create schema registry client: %v", err)
+	}
+	if p.operator, err = strToProtobufRegistryOperator(conf.Protobuf.Operator, conf.Protobuf.Message, &protobufRegistrySchemas{
+		client: p.registry,
+		files:  map[int]protoreflect.FileDescriptor{},
+	}); err != nil {
+		p.registry.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Protobuf) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.operator(part); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Operator failed: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeProtobuf, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Protobuf) CloseAsync() {
+	if p.registry != nil {
+		p.registry.Close()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Protobuf) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
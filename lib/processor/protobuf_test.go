@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func protobufTestRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mainSchema, err := json.Marshal(`
+syntax = "proto3";
+package test;
+
+import "common.proto";
+import "google/protobuf/timestamp.proto";
+
+message Unused {}
+
+message Envelope {
+  message Person {
+    string name = 1;
+    common.Status status = 2;
+    google.protobuf.Timestamp born = 3;
+  }
+}
+`)
+	require.NoError(t, err)
+
+	commonSchema, err := json.Marshal(`
+syntax = "proto3";
+package common;
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+`)
+	require.NoError(t, err)
+
+	mainBody := `{"schemaType":"PROTOBUF","schema":` + string(mainSchema) + `,"references":[{"name":"common.proto","subject":"common","version":1}]}`
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schemas/ids/3":
+			w.Write([]byte(mainBody))
+		case "/subjects/people-value/versions/latest":
+			w.Write([]byte(`{"subject":"people-value","version":2,"id":3,` + mainBody[1:]))
+		case "/subjects/common/versions/1":
+			w.Write([]byte(`{"subject":"common","version":1,"id":1,"schemaType":"PROTOBUF","schema":` + string(commonSchema) + `}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestProtobufSchemaRegistry(t *testing.T) {
+	ts := protobufTestRegistry(t)
+	defer ts.Close()
+
+	encConf := NewConfig()
+	encConf.Type = TypeProtobuf
+	encConf.Protobuf.Operator = "from_json"
+	encConf.Protobuf.Message = "test.Envelope.Person"
+	encConf.Protobuf.SchemaRegistry.URL = ts.URL
+	encConf.Protobuf.SchemaRegistry.Subject = "people-value"
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer enc.CloseAsync()
+
+	decConf := NewConfig()
+	decConf.Type = TypeProtobuf
+	decConf.Protobuf.Operator = "to_json"
+	decConf.Protobuf.SchemaRegistry.URL = ts.URL
+
+	dec, err := New(decConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer dec.CloseAsync()
+
+	msgs, res := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"ash","status":"ACTIVE","born":"2020-01-01T00:00:00Z"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	encoded := msgs[0].Get(0).Get()
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.Equal(t, []byte{0, 0, 0, 0, 3, 4, 2, 0}, encoded[:8])
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	part := msgs[0].Get(0)
+	assert.Equal(t, "", part.Metadata().Get(FailFlagKey))
+	assert.Equal(t, `{"born":"2020-01-01T00:00:00Z","name":"ash","status":"ACTIVE"}`, string(part.Get()))
+	assert.Equal(t, "3", part.Metadata().Get("schema_id"))
+}
+
+func TestProtobufSchemaRegistryErrors(t *testing.T) {
+	ts := protobufTestRegistry(t)
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.SchemaRegistry.URL = ts.URL
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer proc.CloseAsync()
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`not framed`),
+		{0, 0, 0, 0, 9, 0},
+		{0, 0, 0, 0, 3, 2, 10},
+	}))
+	require.Len(t, msgs, 1)
+	for i := 0; i < 3; i++ {
+		assert.NotEqual(t, "", msgs[0].Get(i).Metadata().Get(FailFlagKey), i)
+	}
+
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = "test.Nope"
+	conf.Protobuf.SchemaRegistry.Subject = "people-value"
+
+	proc, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer proc.CloseAsync()
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"ash"}`),
+	}))
+	require.Len(t, msgs, 1)
+	assert.NotEqual(t, "", msgs[0].Get(0).Metadata().Get(FailFlagKey))
+}
+
+func TestProtobufImportPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_protobuf_test_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "common"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "common", "status.proto"), []byte(`
+syntax = "proto3";
+package common;
+enum Status {
+  UNKNOWN = 0;
+  ACTIVE = 1;
+}
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "person.proto"), []byte(`
+syntax = "proto3";
+package test;
+import "common/status.proto";
+message Person {
+  string name = 1;
+  common.Status status = 2;
+  repeated int64 ids = 3;
+}
+`), 0644))
+
+	encConf := NewConfig()
+	encConf.Type = TypeProtobuf
+	encConf.Protobuf.Operator = "from_json"
+	encConf.Protobuf.Message = "test.Person"
+	encConf.Protobuf.ImportPaths = []string{dir}
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	decConf := encConf
+	decConf.Protobuf.Operator = "to_json"
+
+	dec, err := New(decConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := enc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"ash","status":"ACTIVE","ids":[1,2]}`),
+		[]byte(`{"nope":"ash"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, []byte{0x0a, 3, 'a', 's', 'h', 0x10, 1, 0x1a, 2, 1, 2}, msgs[0].Get(0).Get())
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.NotEqual(t, "", msgs[0].Get(1).Metadata().Get(FailFlagKey))
+
+	msgs, res = dec.ProcessMessage(message.New([][]byte{msgs[0].Get(0).Get()}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"ids":["1","2"],"name":"ash","status":"ACTIVE"}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestProtobufBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.Protobuf.SchemaRegistry.URL = "http://localhost:8081"
+	conf.Protobuf.Operator = "nope"
+
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.ImportPaths = []string{"./does_not_exist"}
+	conf.Protobuf.Message = "test.Person"
+
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package processor
 
 import (
+	"time"
+
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -19,4 +21,19 @@ type Type interface {
 	types.Closable
 }
 
+// Flusher is an optional interface implemented by processors that hold
+// messages between calls to ProcessMessage, allowing a pipeline to emit those
+// messages when no further messages arrive.
+type Flusher interface {
+	// FlushPeriod returns the interval at which Flush should be called while
+	// the pipeline is running, or zero if it should only be called when the
+	// pipeline is shutting down.
+	FlushPeriod() time.Duration
+
+	// Flush returns any held messages that are ready to be emitted. When final
+	// is true the pipeline is shutting down and all held messages should be
+	// returned.
+	Flush(final bool) []types.Message
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,601 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/bloblang/x/mapping"
+	"github.com/Jeffail/benthos/v3/lib/bloblang/x/query"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+	"golang.org/x/xerrors"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWindow] = TypeSpec{
+		constructor: NewWindow,
+		Summary: `
+Groups messages into tumbling, sliding or session windows based on an event
+timestamp, emitting a single aggregated message for each window once it
+closes.`,
+		Description: `
+Each message is assigned to a group by the [Bloblang](/docs/guides/bloblang/about)
+query ` + "`key`" + ` and to one or more windows by the event time resolved
+by the query ` + "`timestamp`" + `, which must return either a number of
+seconds since the unix epoch or an RFC 3339 formatted string.
+
+The processor tracks a watermark, which is the greatest event time observed
+minus the ` + "`allowed_lateness`" + `. Once the watermark passes the end of a
+window the window is closed and an aggregated message is emitted. Messages that
+arrive with an event time that only belongs to windows that have already
+closed are dropped.
+
+Messages where either the ` + "`key`" + ` or ` + "`timestamp`" + ` query fails
+are not added to a window, and are instead flagged as failed and emitted
+downstream, where they can be handled using the
+[error handling patterns](/docs/configuration/error_handling).
+
+Messages added to a window are acknowledged immediately and are
+therefore not emitted downstream. When a window closes the mapping
+` + "`result_map`" + ` is executed on a document of the following form in
+order to produce the aggregated message:
+
+` + "```json" + `
+{
+  "key": "the group key",
+  "start": "2020-05-24T10:00:00Z",
+  "end": "2020-05-24T10:01:00Z",
+  "count": 2,
+  "messages": [ <message 1>, <message 2> ]
+}
+` + "```" + `
+
+Where messages that are valid JSON are included as structured documents and
+all others are included as strings. The group key and window boundaries are
+also added to the resulting message as the metadata fields
+` + "`window_key`, `window_start` and `window_end`" + `.
+
+### Window Types
+
+- ` + "`tumbling`" + ` windows are fixed size, do not overlap, and are aligned to
+  the unix epoch.
+- ` + "`sliding`" + ` windows are fixed size and begin every ` + "`slide`" + `
+  period, meaning a message can belong to multiple windows.
+- ` + "`session`" + ` windows group messages of a key that arrive within a
+  ` + "`gap`" + ` of each other, and close once no messages for that key have
+  been seen for the duration of the gap.
+
+### Flushing
+
+The watermark is only advanced when messages are processed, and therefore the
+last windows of a stream would remain open when traffic stops. When the field
+` + "`idle_timeout`" + ` is set all open windows are closed and emitted once no
+messages have been processed for that duration, and the watermark is advanced
+to the end of the latest of those windows, meaning messages that later arrive
+for them are dropped as late. All open windows are also closed and emitted when
+the pipeline shuts down gracefully.
+
+### Caveats
+
+Windows are held in memory and are therefore lost if the service is restarted,
+and when the pipeline is configured with multiple threads each thread maintains
+its own independent windows. Windows are only flushed when the processor is a
+direct child of a pipeline, when nested within other processors such as
+` + "`switch`" + ` windows are only closed by the watermark.`,
+		Footnotes: `
+## Examples
+
+### Per Minute Counts
+
+Counting the number of events of each type in one minute windows, allowing
+events to arrive up to ten seconds late:
+
+` + "```yaml" + `
+pipeline:
+  processors:
+  - window:
+      key: this.type
+      timestamp: this.occurred_at
+      type: tumbling
+      size: 1m
+      allowed_lateness: 10s
+      result_map: |
+        root.type = this.key
+        root.minute = this.start
+        root.count = this.count
+` + "```" + ``,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("key", "A [Bloblang query](/docs/guides/bloblang/about) that resolves the group of a message. When empty all messages belong to the same group.", "this.user.id", `meta("kafka_key")`),
+			docs.FieldCommon("timestamp", "A [Bloblang query](/docs/guides/bloblang/about) that resolves the event time of a message, as either a number of seconds since the unix epoch or an RFC 3339 string.", "this.occurred_at", `meta("kafka_timestamp_unix")`),
+			docs.FieldCommon("type", "The type of windows to create.").HasOptions("tumbling", "sliding", "session"),
+			docs.FieldCommon("size", "The duration of each window, used by `tumbling` and `sliding` windows."),
+			docs.FieldCommon("slide", "The period between the start of each `sliding` window."),
+			docs.FieldCommon("gap", "The maximum duration of inactivity within a `session` window."),
+			docs.FieldCommon("allowed_lateness", "The duration that the watermark lags behind the greatest event time observed, allowing messages to arrive out of order."),
+			docs.FieldAdvanced("idle_timeout", "An optional duration after which all open windows are closed if no messages have been processed, where an empty string disables this behaviour.", "30s", "5m"),
+			docs.FieldCommon("result_map", "A [Bloblang mapping](/docs/guides/bloblang/about) executed on each closed window in order to produce the aggregated message."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WindowConfig contains configuration fields for the Window processor.
+type WindowConfig struct {
+	Key             string `json:"key" yaml:"key"`
+	Timestamp       string `json:"timestamp" yaml:"timestamp"`
+	Type            string `json:"type" yaml:"type"`
+	Size            string `json:"size" yaml:"size"`
+	Slide           string `json:"slide" yaml:"slide"`
+	Gap             string `json:"gap" yaml:"gap"`
+	AllowedLateness string `json:"allowed_lateness" yaml:"allowed_lateness"`
+	IdleTimeout     string `json:"idle_timeout" yaml:"idle_timeout"`
+	ResultMap       string `json:"result_map" yaml:"result_map"`
+}
+
+// NewWindowConfig returns a WindowConfig with default values.
+func NewWindowConfig() WindowConfig {
+	return WindowConfig{
+		Key:             "",
+		Timestamp:       "timestamp_unix()",
+		Type:            "tumbling",
+		Size:            "1m",
+		Slide:           "",
+		Gap:             "",
+		AllowedLateness: "0s",
+		IdleTimeout:     "",
+		ResultMap:       "root = this",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type windowBounds struct {
+	start time.Time
+	end   time.Time
+}
+
+type windowState struct {
+	key      string
+	bounds   windowBounds
+	messages []interface{}
+}
+
+// Window is a processor that aggregates messages into event time windows.
+type Window struct {
+	log   log.Modular
+	stats metrics.Type
+
+	key       query.Function
+	timestamp query.Function
+	resultMap *mapping.Executor
+
+	windowType string
+	size       time.Duration
+	slide      time.Duration
+	gap        time.Duration
+	lateness   time.Duration
+	idle       time.Duration
+
+	mut           sync.Mutex
+	windows       map[string][]*windowState
+	maxEvent      time.Time
+	watermark     time.Time
+	lastProcessed time.Time
+
+	mCount      metrics.StatCounter
+	mErr        metrics.StatCounter
+	mLate       metrics.StatCounter
+	mOpen       metrics.StatGauge
+	mWatermark  metrics.StatGauge
+	mSent       metrics.StatCounter
+	mBatchSent  metrics.StatCounter
+	mWindowsOut metrics.StatCounter
+}
+
+// NewWindow returns a Window processor.
+func NewWindow(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	w := &Window{
+		log:        log,
+		stats:      stats,
+		windowType: conf.Window.Type,
+		windows:    map[string][]*windowState{},
+
+		mCount:      stats.GetCounter("count"),
+		mErr:        stats.GetCounter("error"),
+		mLate:       stats.GetCounter("late"),
+		mOpen:       stats.GetGauge("windows.open"),
+		mWatermark:  stats.GetGauge("watermark"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+		mWindowsOut: stats.GetCounter("windows.closed"),
+	}
+
+	var err error
+	if len(conf.Window.Key) > 0 {
+		if w.key, err = query.New(conf.Window.Key); err != nil {
+			return nil, xerrors.Errorf("failed to parse key query: %w", err)
+		}
+	}
+	if w.timestamp, err = query.New(conf.Window.Timestamp); err != nil {
+		return nil, xerrors.Errorf("failed to parse timestamp query: %w", err)
+	}
+	if w.resultMap, err = mapping.NewExecutor(conf.Window.ResultMap); err != nil {
+		return nil, xerrors.Errorf("failed to parse result mapping: %w", err)
+	}
+
+	parseDur := func(name, s string) (time.Duration, error) {
+		if len(s) == 0 {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %v: %v", name, err)
+		}
+		if d < 0 {
+			return 0, fmt.Errorf("%v must not be negative", name)
+		}
+		return d, nil
+	}
+	if w.size, err = parseDur("size", conf.Window.Size); err != nil {
+		return nil, err
+	}
+	if w.slide, err = parseDur("slide", conf.Window.Slide); err != nil {
+		return nil, err
+	}
+	if w.gap, err = parseDur("gap", conf.Window.Gap); err != nil {
+		return nil, err
+	}
+	if w.lateness, err = parseDur("allowed_lateness", conf.Window.AllowedLateness); err != nil {
+		return nil, err
+	}
+	if w.idle, err = parseDur("idle_timeout", conf.Window.IdleTimeout); err != nil {
+		return nil, err
+	}
+
+	switch w.windowType {
+	case "tumbling":
+		if w.size == 0 {
+			return nil, errors.New("tumbling windows require a size")
+		}
+	case "sliding":
+		if w.size == 0 || w.slide == 0 {
+			return nil, errors.New("sliding windows require both a size and a slide")
+		}
+	case "session":
+		if w.gap == 0 {
+			return nil, errors.New("session windows require a gap")
+		}
+	default:
+		return nil, fmt.Errorf("window type not recognised: %v", w.windowType)
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+func windowTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case []byte:
+		return time.Parse(time.RFC3339Nano, string(t))
+	}
+	f, err := query.IGetNumber(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected number or string timestamp, found %T", v)
+	}
+	secs, frac := math.Modf(f)
+	return time.Unix(int64(secs), int64(frac*1e9)).UTC(), nil
+}
+
+// truncateToEpoch rounds a time down to a multiple of d since the unix epoch.
+func truncateToEpoch(t time.Time, d time.Duration) time.Time {
+	n := t.UnixNano()
+	r := n % int64(d)
+	if r < 0 {
+		r += int64(d)
+	}
+	return time.Unix(0, n-r).UTC()
+}
+
+// assign returns the bounds of each window that an event time belongs to.
+func (w *Window) assign(t time.Time) []windowBounds {
+	switch w.windowType {
+	case "tumbling":
+		start := truncateToEpoch(t, w.size)
+		return []windowBounds{{start: start, end: start.Add(w.size)}}
+	case "sliding":
+		var bounds []windowBounds
+		for start := truncateToEpoch(t, w.slide); start.Add(w.size).After(t); start = start.Add(-w.slide) {
+			bounds = append([]windowBounds{{start: start, end: start.Add(w.size)}}, bounds...)
+		}
+		return bounds
+	}
+	return []windowBounds{{start: t, end: t.Add(w.gap)}}
+}
+
+// addEvent adds a message to each open window it belongs to, returning false
+// if the message is too late to be included in any window.
+func (w *Window) addEvent(key string, t time.Time, doc interface{}) bool {
+	added := false
+	for _, b := range w.assign(t) {
+		if !b.end.After(w.watermark) {
+			continue
+		}
+		added = true
+
+		if w.windowType == "session" {
+			w.addSession(key, b, doc)
+			continue
+		}
+
+		var state *windowState
+		for _, s := range w.windows[key] {
+			if s.bounds == b {
+				state = s
+				break
+			}
+		}
+		if state == nil {
+			state = &windowState{key: key, bounds: b}
+			w.windows[key] = append(w.windows[key], state)
+		}
+		state.messages = append(state.messages, doc)
+	}
+	return added
+}
+
+// addSession adds a message to a session window, merging any existing
+// sessions of the key that overlap with it.
+func (w *Window) addSession(key string, b windowBounds, doc interface{}) {
+	merged := &windowState{key: key, bounds: b, messages: []interface{}{doc}}
+
+	var remaining []*windowState
+	for _, s := range w.windows[key] {
+		if s.bounds.start.After(merged.bounds.end) || merged.bounds.start.After(s.bounds.end) {
+			remaining = append(remaining, s)
+			continue
+		}
+		if s.bounds.start.Before(merged.bounds.start) {
+			merged.bounds.start = s.bounds.start
+		}
+		if s.bounds.end.After(merged.bounds.end) {
+			merged.bounds.end = s.bounds.end
+		}
+		merged.messages = append(s.messages, merged.messages...)
+	}
+	w.windows[key] = append(remaining, merged)
+}
+
+// closeWindows removes and returns all windows that end at or before the
+// watermark, ordered by their end time.
+func (w *Window) closeWindows() []*windowState {
+	var closed []*windowState
+	for key, states := range w.windows {
+		var remaining []*windowState
+		for _, s := range states {
+			if !s.bounds.end.After(w.watermark) {
+				closed = append(closed, s)
+			} else {
+				remaining = append(remaining, s)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(w.windows, key)
+		} else {
+			w.windows[key] = remaining
+		}
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		if closed[i].bounds.end.Equal(closed[j].bounds.end) {
+			return closed[i].key < closed[j].key
+		}
+		return closed[i].bounds.end.Before(closed[j].bounds.end)
+	})
+	return closed
+}
+
+func (w *Window) aggregate(state *windowState) (types.Part, error) {
+	start := state.bounds.start.UTC().Format(time.RFC3339Nano)
+	end := state.bounds.end.UTC().Format(time.RFC3339Nano)
+
+	var doc interface{} = map[string]interface{}{
+		"key":      state.key,
+		"start":    start,
+		"end":      end,
+		"count":    int64(len(state.messages)),
+		"messages": state.messages,
+	}
+
+	part := message.NewPart(nil)
+	if err := part.SetJSON(doc); err != nil {
+		return nil, err
+	}
+	part.Metadata().
+		Set("window_key", state.key).
+		Set("window_start", start).
+		Set("window_end", end)
+
+	msg := message.New(nil)
+	msg.Append(part)
+
+	return w.resultMap.MapPart(0, msg)
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *Window) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.lastProcessed = time.Now()
+
+	var failed []types.Part
+	fail := func(p types.Part, err error) {
+		w.mErr.Incr(1)
+		failedPart := p.Copy()
+		FlagErr(failedPart, err)
+		failed = append(failed, failedPart)
+	}
+
+	msg.Iter(func(i int, p types.Part) error {
+		var doc interface{}
+		if jObj, err := p.JSON(); err == nil {
+			doc = jObj
+		} else {
+			doc = string(p.Get())
+		}
+
+		ctx := query.FunctionContext{
+			Value: &doc,
+			Maps:  map[string]query.Function{},
+			Vars:  map[string]interface{}{},
+			Index: i,
+			Msg:   msg,
+		}
+
+		var key string
+		if w.key != nil {
+			keyV, err := w.key.Exec(ctx)
+			if err != nil {
+				w.log.Errorf("Failed to resolve window key: %v\n", err)
+				fail(p, err)
+				return nil
+			}
+			key = query.IToString(keyV)
+		}
+
+		tsV, err := w.timestamp.Exec(ctx)
+		if err != nil {
+			w.log.Errorf("Failed to resolve event timestamp: %v\n", err)
+			fail(p, err)
+			return nil
+		}
+		ts, err := windowTimestamp(tsV)
+		if err != nil {
+			w.log.Errorf("Failed to parse event timestamp: %v\n", err)
+			fail(p, err)
+			return nil
+		}
+
+		if !w.addEvent(key, ts, doc) {
+			w.mLate.Incr(1)
+			w.log.Debugf("Dropping message with event time %v as it is behind the watermark %v\n", ts, w.watermark)
+			return nil
+		}
+		if ts.After(w.maxEvent) {
+			w.maxEvent = ts
+			if wm := ts.Add(-w.lateness); wm.After(w.watermark) {
+				w.watermark = wm
+			}
+		}
+		return nil
+	})
+
+	w.mWatermark.Set(w.watermark.Unix())
+
+	if msgs := w.emit(w.closeWindows(), failed); len(msgs) > 0 {
+		return msgs, nil
+	}
+	return nil, response.NewAck()
+}
+
+// emit aggregates closed windows into a single message, followed by a message
+// of any parts that failed to be added to a window.
+func (w *Window) emit(closed []*windowState, failed []types.Part) []types.Message {
+	var open int64
+	for _, states := range w.windows {
+		open += int64(len(states))
+	}
+	w.mOpen.Set(open)
+
+	var msgs []types.Message
+	if len(closed) > 0 {
+		newMsg := message.New(nil)
+		for _, state := range closed {
+			p, err := w.aggregate(state)
+			if err != nil {
+				w.mErr.Incr(1)
+				w.log.Errorf("Failed to aggregate window: %v\n", err)
+				continue
+			}
+			if p != nil {
+				newMsg.Append(p)
+			}
+		}
+		w.mWindowsOut.Incr(int64(len(closed)))
+		if newMsg.Len() > 0 {
+			w.mBatchSent.Incr(1)
+			w.mSent.Incr(int64(newMsg.Len()))
+			msgs = append(msgs, newMsg)
+		}
+	}
+	if len(failed) > 0 {
+		failedMsg := message.New(nil)
+		failedMsg.SetAll(failed)
+		w.mBatchSent.Incr(1)
+		w.mSent.Incr(int64(failedMsg.Len()))
+		msgs = append(msgs, failedMsg)
+	}
+	return msgs
+}
+
+// FlushPeriod returns the idle timeout of the processor, as windows only need
+// to be flushed once no messages have been processed for that duration.
+func (w *Window) FlushPeriod() time.Duration {
+	return w.idle
+}
+
+// Flush closes all open windows and returns their aggregated messages if
+// either no messages have been processed for the idle timeout or final is
+// true. The watermark is advanced to the end of the latest closed window.
+func (w *Window) Flush(final bool) []types.Message {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if len(w.windows) == 0 {
+		return nil
+	}
+	if !final && (w.idle == 0 || time.Since(w.lastProcessed) < w.idle) {
+		return nil
+	}
+
+	for _, states := range w.windows {
+		for _, s := range states {
+			if s.bounds.end.After(w.watermark) {
+				w.watermark = s.bounds.end
+			}
+		}
+	}
+	w.mWatermark.Set(w.watermark.Unix())
+
+	return w.emit(w.closeWindows(), nil)
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (w *Window) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (w *Window) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,262 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func windowResults(t *testing.T, proc Type, inputs ...string) []string {
+	t.Helper()
+
+	var results []string
+	for _, input := range inputs {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if len(msgs) == 0 {
+			require.NotNil(t, res)
+			require.NoError(t, res.Error())
+			continue
+		}
+		require.Nil(t, res)
+		for _, m := range msgs {
+			m.Iter(func(i int, p types.Part) error {
+				results = append(results, string(p.Get()))
+				return nil
+			})
+		}
+	}
+	return results
+}
+
+func TestWindowTumbling(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Key = "this.type"
+	conf.Window.Timestamp = "this.ts"
+	conf.Window.Size = "10s"
+	conf.Window.ResultMap = `
+root.key = this.key
+root.start = this.start
+root.count = this.count
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	results := windowResults(t, proc,
+		`{"type":"a","ts":100}`,
+		`{"type":"b","ts":101}`,
+		`{"type":"a","ts":105}`,
+		`{"type":"a","ts":111}`,
+		`{"type":"b","ts":99}`,
+		`{"type":"a","ts":125}`,
+	)
+
+	assert.Equal(t, []string{
+		`{"count":2,"key":"a","start":"1970-01-01T00:01:40Z"}`,
+		`{"count":1,"key":"b","start":"1970-01-01T00:01:40Z"}`,
+		`{"count":1,"key":"a","start":"1970-01-01T00:01:50Z"}`,
+	}, results)
+}
+
+func TestWindowAllowedLateness(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Timestamp = "this.ts"
+	conf.Window.Size = "10s"
+	conf.Window.AllowedLateness = "5s"
+	conf.Window.ResultMap = `root = this.messages.map_each(this.id)`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	results := windowResults(t, proc,
+		`{"id":1,"ts":"1970-01-01T00:01:41Z"}`,
+		`{"id":2,"ts":"1970-01-01T00:01:52Z"}`,
+		`{"id":3,"ts":"1970-01-01T00:01:48Z"}`,
+		`{"id":4,"ts":"1970-01-01T00:01:56Z"}`,
+		`{"id":5,"ts":"1970-01-01T00:01:49Z"}`,
+	)
+
+	assert.Equal(t, []string{`[1,3]`}, results)
+}
+
+func TestWindowSliding(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Type = "sliding"
+	conf.Window.Timestamp = "this.ts"
+	conf.Window.Size = "10s"
+	conf.Window.Slide = "5s"
+	conf.Window.ResultMap = `
+root.start = this.start
+root.ids = this.messages.map_each(this.id)
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	results := windowResults(t, proc,
+		`{"id":1,"ts":102}`,
+		`{"id":2,"ts":107}`,
+		`{"id":3,"ts":112}`,
+		`{"id":4,"ts":130}`,
+	)
+
+	assert.Equal(t, []string{
+		`{"ids":[1],"start":"1970-01-01T00:01:35Z"}`,
+		`{"ids":[1,2],"start":"1970-01-01T00:01:40Z"}`,
+		`{"ids":[2,3],"start":"1970-01-01T00:01:45Z"}`,
+		`{"ids":[3],"start":"1970-01-01T00:01:50Z"}`,
+	}, results)
+}
+
+func TestWindowSession(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Type = "session"
+	conf.Window.Key = "this.user"
+	conf.Window.Timestamp = "this.ts"
+	conf.Window.Gap = "10s"
+	conf.Window.ResultMap = `
+root.user = this.key
+root.start = this.start
+root.end = this.end
+root.count = this.count
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	results := windowResults(t, proc,
+		`{"user":"a","ts":100}`,
+		`{"user":"a","ts":108}`,
+		`{"user":"a","ts":115}`,
+		`{"user":"b","ts":120}`,
+		`{"user":"a","ts":140}`,
+	)
+
+	assert.Equal(t, []string{
+		`{"count":3,"end":"1970-01-01T00:02:05Z","start":"1970-01-01T00:01:40Z","user":"a"}`,
+		`{"count":1,"end":"1970-01-01T00:02:10Z","start":"1970-01-01T00:02:00Z","user":"b"}`,
+	}, results)
+}
+
+func TestWindowMetadata(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Key = `meta("group")`
+	conf.Window.Timestamp = "content().number()"
+	conf.Window.Size = "1m"
+	conf.Window.ResultMap = `root = this.count`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	for _, ts := range []string{"60", "70"} {
+		msg := message.New([][]byte{[]byte(ts)})
+		msg.Get(0).Metadata().Set("group", "foo")
+		msgs, _ := proc.ProcessMessage(msg)
+		require.Len(t, msgs, 0)
+	}
+
+	msg := message.New([][]byte{[]byte("120")})
+	msg.Get(0).Metadata().Set("group", "foo")
+	msgs, res := proc.ProcessMessage(msg)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+
+	part := msgs[0].Get(0)
+	assert.Equal(t, "foo", part.Metadata().Get("window_key"))
+	assert.Equal(t, "1970-01-01T00:01:00Z", part.Metadata().Get("window_start"))
+	assert.Equal(t, "1970-01-01T00:02:00Z", part.Metadata().Get("window_end"))
+}
+
+func TestWindowFailedMessages(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Timestamp = "this.ts"
+	conf.Window.Size = "10s"
+	conf.Window.ResultMap = `root = this.count`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":100}`),
+		[]byte(`{"nope":true}`),
+		[]byte(`{"ts":"not a timestamp"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, `{"nope":true}`, string(msgs[0].Get(0).Get()))
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, `{"ts":"not a timestamp"}`, string(msgs[0].Get(1).Get()))
+	assert.True(t, HasFailed(msgs[0].Get(1)))
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ts":110}`),
+		[]byte(`{"nope":true}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "1", string(msgs[0].Get(0).Get()))
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+	assert.True(t, HasFailed(msgs[1].Get(0)))
+}
+
+func TestWindowFlush(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Timestamp = "this.ts"
+	conf.Window.Size = "10s"
+	conf.Window.IdleTimeout = "10ms"
+	conf.Window.ResultMap = `root = this.messages.map_each(this.ts)`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	flusher, ok := proc.(Flusher)
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond*10, flusher.FlushPeriod())
+
+	assert.Empty(t, windowResults(t, proc, `{"ts":100}`, `{"ts":101}`))
+	assert.Empty(t, flusher.Flush(false))
+
+	<-time.After(time.Millisecond * 20)
+	msgs := flusher.Flush(false)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `[100,101]`, string(msgs[0].Get(0).Get()))
+	assert.Empty(t, flusher.Flush(false))
+
+	// The flushed window is closed and therefore late messages are dropped.
+	assert.Empty(t, windowResults(t, proc, `{"ts":105}`, `{"ts":112}`))
+	assert.Empty(t, flusher.Flush(false))
+
+	msgs = flusher.Flush(true)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `[112]`, string(msgs[0].Get(0).Get()))
+	assert.Empty(t, flusher.Flush(true))
+}
+
+func TestWindowBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Type = "sliding"
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Window.Type = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -35,6 +35,7 @@ var Constructors = map[string]TypeSpec{}
 // String constants representing each ratelimit type.
 const (
 	TypeLocal = "local"
+	TypeRedis = "redis"
 )
 
 //------------------------------------------------------------------------------
@@ -43,6 +44,7 @@ const (
 type Config struct {
 	Type   string      `json:"type" yaml:"type"`
 	Local  LocalConfig `json:"local" yaml:"local"`
+	Redis  RedisConfig `json:"redis" yaml:"redis"`
 	Plugin interface{} `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 }
 
@@ -51,6 +53,7 @@ func NewConfig() Config {
 	return Config{
 		Type:   "local",
 		Local:  NewLocalConfig(),
+		Redis:  NewRedisConfig(),
 		Plugin: nil,
 	}
 }
@@ -0,0 +1,277 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+	"github.com/go-redis/redis/v7"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedis] = TypeSpec{
+		constructor: NewRedis,
+		Summary: `
+A distributed rate limit that uses a token bucket stored within Redis, allowing
+a limit to be shared across any number of running instances of Benthos.`,
+		Description: `
+The bucket holds up to ` + "`count`" + ` tokens and is refilled at a
+steady rate such that ` + "`count`" + ` tokens are added each
+` + "`interval`" + `. Each access of the rate limit consumes a token, where
+the state of the bucket is updated atomically by a Lua script and the clock of
+the Redis server is used, therefore the clocks of Benthos instances do not need
+to be synchronised. Rate limits sharing the same ` + "`key`" + ` should
+also share the same ` + "`count` and `interval`" + `.
+
+### Fallback
+
+When Redis is unreachable the rate limit falls back to a local limit of
+` + "`fallback_count`" + ` accesses per ` + "`interval`" + ` for each
+instance, and attempts to reach Redis again after one second. When
+` + "`fallback_count`" + ` is zero an error is returned instead, which causes
+components to wait before attempting to access the rate limit again.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"url", "The URL of the target Redis server. Database is optional and is supplied as the URL path.",
+				"tcp://localhost:6379", "tcp://localhost:6379/1",
+			),
+			docs.FieldCommon("key", "The key used to store the state of the rate limit, which must be shared by all instances of the rate limit."),
+			docs.FieldCommon("count", "The maximum number of requests to allow for a given period of time."),
+			docs.FieldCommon("interval", "The time window to limit requests by."),
+			docs.FieldCommon("fallback_count", "The maximum number of requests to allow per interval for each instance when Redis is unreachable."),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a response from Redis before falling back."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedisConfig is a config struct containing rate limit fields for a Redis
+// rate limit.
+type RedisConfig struct {
+	URL           string `json:"url" yaml:"url"`
+	Key           string `json:"key" yaml:"key"`
+	Count         int    `json:"count" yaml:"count"`
+	Interval      string `json:"interval" yaml:"interval"`
+	FallbackCount int    `json:"fallback_count" yaml:"fallback_count"`
+	Timeout       string `json:"timeout" yaml:"timeout"`
+}
+
+// NewRedisConfig returns a Redis rate limit configuration struct with default
+// values.
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		URL:           "tcp://localhost:6379",
+		Key:           "benthos_rate_limit",
+		Count:         1000,
+		Interval:      "1s",
+		FallbackCount: 0,
+		Timeout:       "500ms",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// redisTokenBucket consumes a token from a bucket, where KEYS[1] is the key of
+// the bucket, ARGV[1] is the capacity and ARGV[2] is the interval in
+// milliseconds over which the bucket is refilled. Returns the number of
+// milliseconds to wait before a token is available, or zero when a token was
+// consumed.
+var redisTokenBucket = redis.NewScript(`
+redis.replicate_commands()
+
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local rate = capacity / interval
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], interval * 2)
+return wait
+`)
+
+// redisRetryPeriod is the period of time to wait after a failed attempt to
+// reach Redis before attempting again.
+const redisRetryPeriod = time.Second
+
+// Redis is a rate limit that tracks a token bucket within Redis, and can
+// therefore be shared by multiple instances of Benthos.
+type Redis struct {
+	client   *redis.Client
+	key      string
+	count    int
+	interval time.Duration
+
+	fallback   *Local
+	failMut    sync.Mutex
+	failedAt   time.Time
+	retryAfter time.Duration
+
+	log log.Modular
+
+	mErr      metrics.StatCounter
+	mFallback metrics.StatCounter
+}
+
+// NewRedis creates a Redis rate limit from a configuration struct. This type is
+// safe to share and call from parallel goroutines.
+func NewRedis(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (types.RateLimit, error) {
+	if conf.Redis.Count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	if conf.Redis.FallbackCount < 0 {
+		return nil, errors.New("fallback count must not be negative")
+	}
+	if len(conf.Redis.Key) == 0 {
+		return nil, errors.New("a key must be specified")
+	}
+	interval, err := time.ParseDuration(conf.Redis.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	if interval < time.Millisecond {
+		return nil, errors.New("interval must be at least one millisecond")
+	}
+
+	var timeout time.Duration
+	if tout := conf.Redis.Timeout; len(tout) > 0 {
+		if timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	url, err := url.Parse(conf.Redis.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var pass string
+	if url.User != nil {
+		pass, _ = url.User.Password()
+	}
+
+	var redisDB int
+	if len(url.Path) > 1 {
+		if redisDB, err = strconv.Atoi(url.Path[1:]); err != nil {
+			return nil, fmt.Errorf("invalid Redis DB, can't parse '%s'", url.Path)
+		}
+	}
+
+	r := &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:         url.Host,
+			Network:      url.Scheme,
+			DB:           redisDB,
+			Password:     pass,
+			DialTimeout:  timeout,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		}),
+		key:        conf.Redis.Key,
+		count:      conf.Redis.Count,
+		interval:   interval,
+		retryAfter: redisRetryPeriod,
+		log:        logger,
+		mErr:       stats.GetCounter("error"),
+		mFallback:  stats.GetCounter("fallback"),
+	}
+	if conf.Redis.FallbackCount > 0 {
+		r.fallback = &Local{
+			bucket:      conf.Redis.FallbackCount,
+			lastRefresh: time.Now(),
+			size:        conf.Redis.FallbackCount,
+			period:      interval,
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Access the rate limited resource. Returns a duration or an error if the rate
+// limit check fails. The returned duration is either zero (meaning the resource
+// can be accessed) or a reasonable length of time to wait before requesting
+// again.
+func (r *Redis) Access() (time.Duration, error) {
+	r.failMut.Lock()
+	failing := !r.failedAt.IsZero() && time.Since(r.failedAt) < r.retryAfter
+	r.failMut.Unlock()
+
+	if !failing {
+		wait, err := redisTokenBucket.Run(
+			r.client, []string{r.key}, r.count, r.interval.Milliseconds(),
+		).Int64()
+		if err == nil {
+			r.failMut.Lock()
+			if !r.failedAt.IsZero() {
+				r.log.Infoln("Reconnected to Redis rate limit")
+				r.failedAt = time.Time{}
+			}
+			r.failMut.Unlock()
+			return time.Duration(wait) * time.Millisecond, nil
+		}
+
+		r.mErr.Incr(1)
+		r.failMut.Lock()
+		if r.failedAt.IsZero() {
+			r.log.Errorf("Failed to access Redis rate limit: %v\n", err)
+		}
+		r.failedAt = time.Now()
+		r.failMut.Unlock()
+
+		if r.fallback == nil {
+			return 0, err
+		}
+	}
+
+	if r.fallback == nil {
+		return 0, errors.New("redis rate limit is unreachable")
+	}
+	r.mFallback.Incr(1)
+	return r.fallback.Access()
+}
+
+// CloseAsync shuts down the rate limit.
+func (r *Redis) CloseAsync() {
+	r.client.Close()
+}
+
+// WaitForClose blocks until the rate limit has closed down.
+func (r *Redis) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,123 @@
+// +build integration
+
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/ory/dockertest"
+)
+
+func TestRedisRateLimitIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+
+	resource, err := pool.Run("redis", "latest", nil)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	defer func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+	}()
+
+	url := fmt.Sprintf("tcp://localhost:%v/1", resource.GetPort("6379/tcp"))
+
+	newRateLimit := func(key string, count int, interval string) types.RateLimit {
+		t.Helper()
+
+		conf := NewConfig()
+		conf.Type = TypeRedis
+		conf.Redis.URL = url
+		conf.Redis.Key = key
+		conf.Redis.Count = count
+		conf.Redis.Interval = interval
+
+		rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rl
+	}
+
+	if err = pool.Retry(func() error {
+		rl := newRateLimit("benthos_test_connect", 1, "1s")
+		defer rl.CloseAsync()
+		_, aErr := rl.Access()
+		return aErr
+	}); err != nil {
+		t.Fatalf("Could not connect to docker resource: %s", err)
+	}
+
+	t.Run("testRedisRateLimitShared", func(t *testing.T) {
+		// Two instances share the same bucket.
+		first := newRateLimit("benthos_test_shared", 10, "10s")
+		defer first.CloseAsync()
+		second := newRateLimit("benthos_test_shared", 10, "10s")
+		defer second.CloseAsync()
+
+		for i := 0; i < 10; i++ {
+			rl := first
+			if i%2 == 1 {
+				rl = second
+			}
+			period, err := rl.Access()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if period > 0 {
+				t.Errorf("Period above zero on access %v: %v", i, period)
+			}
+		}
+
+		for _, rl := range []types.RateLimit{first, second} {
+			period, err := rl.Access()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if period == 0 {
+				t.Error("Expected limit on final request")
+			} else if period > time.Second*10 {
+				t.Errorf("Period beyond interval: %v", period)
+			}
+		}
+	})
+
+	t.Run("testRedisRateLimitRefill", func(t *testing.T) {
+		rl := newRateLimit("benthos_test_refill", 5, "50ms")
+		defer rl.CloseAsync()
+
+		for i := 0; i < 5; i++ {
+			if _, err := rl.Access(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		period, err := rl.Access()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period == 0 {
+			t.Error("Expected limit on final request")
+		}
+
+		<-time.After(period)
+
+		if period, err = rl.Access(); err != nil {
+			t.Fatal(err)
+		} else if period != 0 {
+			t.Errorf("Rate limited after waiting: %v", period)
+		}
+	})
+}
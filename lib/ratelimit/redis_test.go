@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRedisRateLimitConfErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.Count = -1
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from bad count")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.Interval = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from bad interval")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.FallbackCount = -1
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from bad fallback count")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.URL = "tcp://localhost:6379/nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from bad database")
+	}
+}
+
+func unreachableRedisURL(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return "tcp://" + addr
+}
+
+func TestRedisRateLimitUnreachable(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.URL = unreachableRedisURL(t)
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.CloseAsync()
+
+	if _, err = rl.Access(); err == nil {
+		t.Error("expected error from unreachable redis")
+	}
+	if _, err = rl.Access(); err == nil {
+		t.Error("expected error from unreachable redis")
+	}
+}
+
+func TestRedisRateLimitFallback(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.URL = unreachableRedisURL(t)
+	conf.Redis.FallbackCount = 5
+	conf.Redis.Interval = "10ms"
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.CloseAsync()
+
+	for i := 0; i < conf.Redis.FallbackCount; i++ {
+		period, err := rl.Access()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period > 0 {
+			t.Errorf("Period above zero: %v", period)
+		}
+	}
+
+	if period, _ := rl.Access(); period == 0 {
+		t.Error("Expected limit on final request")
+	} else if period > time.Second {
+		t.Errorf("Period beyond interval: %v", period)
+	}
+
+	<-time.After(time.Millisecond * 15)
+
+	if period, err := rl.Access(); err != nil {
+		t.Error(err)
+	} else if period != 0 {
+		t.Errorf("Rate limited after refresh: %v", period)
+	}
+}
+
+//------------------------------------------------------------------------------
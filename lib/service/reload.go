@@ -0,0 +1,117 @@
+package service
+
+import (
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+)
+
+//------------------------------------------------------------------------------
+
+// watchConfigFile polls a config file for changes to its modification time or
+// size, and signals a reload each time a change is detected.
+func watchConfigFile(path string, period time.Duration, reloadChan chan<- struct{}, stopChan <-chan struct{}, logger log.Modular) {
+	stat := func() (time.Time, int64, bool) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		return info.ModTime(), info.Size(), true
+	}
+	lastMod, lastSize, _ := stat()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopChan:
+			return
+		}
+
+		// The file might be temporarily missing whilst an editor replaces
+		// it, in which case we check again at the next tick.
+		mod, size, exists := stat()
+		if !exists || (mod.Equal(lastMod) && size == lastSize) {
+			continue
+		}
+		lastMod, lastSize = mod, size
+
+		logger.Infoln("Config file has changed, reloading config.")
+		select {
+		case reloadChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// configReloader applies changes to a config file to a running stream. Reloads
+// are executed in the background so that the service continues to handle
+// signals whilst replaced layers of the stream are drained.
+type configReloader struct {
+	path    string
+	strict  bool
+	strm    *stream.Type
+	timeout time.Duration
+	logger  log.Modular
+
+	// The config the service is running with, only the stream sections of which
+	// are updated by a reload.
+	applied config.Type
+}
+
+// loop executes a reload for each signal received until the stop channel is
+// closed. Signals received whilst a reload is in progress are merged into a
+// single reload executed once it finishes.
+func (r *configReloader) loop(reloadChan <-chan struct{}, stopChan <-chan struct{}) {
+	for {
+		select {
+		case <-reloadChan:
+		case <-stopChan:
+			return
+		}
+		r.reload()
+	}
+}
+
+// reload reads the config file and applies changes to its stream sections to
+// the running stream. Changes to any other sections are ignored as they
+// require a restart. If the new config fails to be read or constructed then
+// the stream continues to run with its previous config.
+func (r *configReloader) reload() {
+	newConf := config.New()
+	lints, err := config.Read(r.path, true, &newConf)
+	if err != nil {
+		r.logger.Errorf("Failed to read config for reload: %v\n", err)
+		return
+	}
+	if len(lints) > 0 {
+		lintlog := r.logger.NewModule(".linter")
+		for _, lint := range lints {
+			lintlog.Infoln(lint)
+		}
+		if r.strict {
+			r.logger.Errorln("Config reload rejected due to linter errors, to allow reloads with linter errors run Benthos with --chilled")
+			return
+		}
+	}
+
+	prevRoot, newRoot := r.applied, newConf
+	prevRoot.Config, newRoot.Config = stream.Config{}, stream.Config{}
+	if !reflect.DeepEqual(prevRoot, newRoot) {
+		r.logger.Warnln("Changes to sections other than input, buffer, pipeline, output and dead_letter require a restart and have been ignored.")
+	}
+
+	if err = r.strm.Reload(newConf.Config, r.timeout); err != nil {
+		r.logger.Errorf("Failed to reload config, the previous config remains active: %v\n", err)
+		return
+	}
+	r.applied.Config = newConf.Config
+	r.logger.Infoln("Config reloaded.")
+}
+
+//------------------------------------------------------------------------------
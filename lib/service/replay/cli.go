@@ -0,0 +1,99 @@
+package replay
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/urfave/cli/v2"
+)
+
+// CliCommand is a cli.Command definition for replaying messages from a dead
+// letter queue.
+func CliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "replay",
+		Usage: "Replay messages from a dead letter queue into a stream",
+		Description: `
+   Reads messages from the input of a replay definition, filters them with an
+   optional Bloblang condition, modifies them with an optional Bloblang mapping
+   and writes them to the output of the definition, which is usually the input
+   of the stream that originally failed to process them:
+
+   benthos replay ./replay.yaml
+   benthos replay --dry-run ./replay.yaml
+
+   The command exits once the input has been exhausted. When --dry-run is set
+   the messages that would have been replayed are printed to stdout instead.
+
+   For more information check out the docs at:
+   https://benthos.dev/docs/configuration/error_handling#replaying-dead-letters`[4:],
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Value: false,
+				Usage: "print messages that would be replayed to stdout rather than writing them to the output.",
+			},
+			&cli.StringFlag{
+				Name:  "log",
+				Value: "INFO",
+				Usage: "the level at which components write logs to stderr.",
+			},
+			&cli.StringFlag{
+				Name:  "shutdown-timeout",
+				Value: "20s",
+				Usage: "the maximum period of time to wait for the replay to stop after an interrupt.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			path := c.Args().First()
+			if len(path) == 0 {
+				fmt.Fprintln(os.Stderr, "A replay definition file must be specified")
+				os.Exit(1)
+			}
+
+			conf, err := Read(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Replay definition read error: %v\n", err)
+				os.Exit(1)
+			}
+
+			timeout, err := time.ParseDuration(c.String("shutdown-timeout"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to parse shutdown timeout: %v\n", err)
+				os.Exit(1)
+			}
+
+			logConf := log.NewConfig()
+			logConf.LogLevel = c.String("log")
+			logger := log.New(os.Stderr, logConf)
+
+			dryRun := c.Bool("dry-run")
+			res, err := Run(conf, dryRun, timeout, logger, metrics.Noop())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Replay failed: %v\n", err)
+				os.Exit(1)
+			}
+
+			verb := "Replayed"
+			if dryRun {
+				verb = "Would have replayed"
+			}
+			fmt.Fprintf(os.Stderr, "%v %v messages\n", verb, res.Replayed)
+			if res.Failed > 0 {
+				fmt.Fprintf(os.Stderr, "Failed to process %v messages, which were not replayed\n", res.Failed)
+			}
+			if res.Interrupted {
+				fmt.Fprintln(os.Stderr, "Replay was interrupted before the input was exhausted")
+				os.Exit(1)
+			}
+			if res.Failed > 0 {
+				os.Exit(1)
+			}
+			os.Exit(0)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/condition"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// deadLetterMetadataPrefix is the prefix shared by the metadata keys that are
+// added to messages routed to a dead letter output.
+const deadLetterMetadataPrefix = "dead_letter_"
+
+//------------------------------------------------------------------------------
+
+// Config contains the fields of a replay definition, describing where messages
+// are read from, which of them are replayed, how they are modified and where
+// they are written to.
+type Config struct {
+	Input     input.Config   `json:"input" yaml:"input"`
+	Condition string         `json:"condition" yaml:"condition"`
+	Mapping   string         `json:"mapping" yaml:"mapping"`
+	RateLimit string         `json:"rate_limit" yaml:"rate_limit"`
+	Output    output.Config  `json:"output" yaml:"output"`
+	Manager   manager.Config `json:"resources" yaml:"resources"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Input:     input.NewConfig(),
+		Condition: "",
+		Mapping:   "",
+		RateLimit: "",
+		Output:    output.NewConfig(),
+		Manager:   manager.NewConfig(),
+	}
+}
+
+// Read attempts to read a replay definition from a file path, environment
+// variable interpolations are resolved before the definition is parsed.
+func Read(path string) (Config, error) {
+	conf := NewConfig()
+	confBytes, err := config.ReadWithJSONPointers(path, true)
+	if err != nil {
+		return conf, err
+	}
+	err = yaml.Unmarshal(confBytes, &conf)
+	return conf, err
+}
+
+//------------------------------------------------------------------------------
+
+// StreamConfig returns a stream config that executes the replay. When dryRun
+// is true the output is replaced with stdout, so that the messages that would
+// have been replayed can be inspected without writing them to the target.
+func (c Config) StreamConfig(dryRun bool) stream.Config {
+	conf := stream.NewConfig()
+	conf.Input = c.Input
+
+	if len(c.Condition) > 0 {
+		procConf := processor.NewConfig()
+		procConf.Type = processor.TypeFilterParts
+		procConf.FilterParts.Type = condition.TypeBloblang
+		procConf.FilterParts.Bloblang = condition.BloblangConfig(c.Condition)
+		conf.Pipeline.Processors = append(conf.Pipeline.Processors, procConf)
+	}
+	if len(c.Mapping) > 0 {
+		procConf := processor.NewConfig()
+		procConf.Type = processor.TypeBloblang
+		procConf.Bloblang = processor.BloblangConfig(c.Mapping)
+		conf.Pipeline.Processors = append(conf.Pipeline.Processors, procConf)
+	}
+
+	// Remove the metadata added by the dead letter path so that replayed
+	// messages don't carry it back into the target stream.
+	stripConf := processor.NewConfig()
+	stripConf.Type = processor.TypeMetadata
+	stripConf.Metadata.Operator = "delete_prefix"
+	stripConf.Metadata.Value = deadLetterMetadataPrefix
+	conf.Pipeline.Processors = append(conf.Pipeline.Processors, stripConf)
+
+	if len(c.RateLimit) > 0 {
+		procConf := processor.NewConfig()
+		procConf.Type = processor.TypeRateLimit
+		procConf.RateLimit.Resource = c.RateLimit
+		conf.Pipeline.Processors = append(conf.Pipeline.Processors, procConf)
+	}
+
+	if dryRun {
+		conf.Output = output.NewConfig()
+		conf.Output.Type = output.TypeSTDOUT
+	} else {
+		conf.Output = c.Output
+	}
+	return conf
+}
+
+//------------------------------------------------------------------------------
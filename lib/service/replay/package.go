@@ -0,0 +1,3 @@
+// Package replay implements the Benthos command for replaying messages from a
+// dead letter queue back into a stream.
+package replay
@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// counter is a processor that counts the message parts that reach the end of
+// the replay pipeline. Parts that failed a processing step, such as the
+// mapping, are dropped rather than being sent to the output.
+type counter struct {
+	count  int64
+	failed int64
+	log    log.Modular
+}
+
+func (c *counter) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	newMsg := message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		if processor.HasFailed(p) {
+			c.log.Errorf("Dropping message that failed processing: %v\n", p.Metadata().Get(processor.FailFlagKey))
+			atomic.AddInt64(&c.failed, 1)
+			return nil
+		}
+		newMsg.Append(p)
+		return nil
+	})
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+	atomic.AddInt64(&c.count, int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+func (c *counter) CloseAsync() {}
+
+func (c *counter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Result describes the outcome of a replay.
+type Result struct {
+	// Replayed is the number of messages that passed the replay condition and
+	// were sent to the output.
+	Replayed int64
+
+	// Failed is the number of messages that passed the replay condition but
+	// failed processing, and were therefore not sent to the output.
+	Failed int64
+
+	// Interrupted is true when the replay was stopped by a termination signal
+	// before the input was exhausted.
+	Interrupted bool
+}
+
+// Run executes a replay, blocking until either the input has been exhausted
+// and all messages have been written, or a termination signal is received.
+func Run(conf Config, dryRun bool, timeout time.Duration, logger log.Modular, stats metrics.Type) (Result, error) {
+	var res Result
+
+	mgr, err := manager.New(conf.Manager, types.NoopMgr(), logger, stats)
+	if err != nil {
+		return res, fmt.Errorf("failed to create resources: %v", err)
+	}
+	defer func() {
+		mgr.CloseAsync()
+		if err := mgr.WaitForClose(timeout); err != nil {
+			logger.Warnf("Failed to cleanly close resources: %v\n", err)
+		}
+	}()
+
+	count := &counter{log: logger}
+	closedChan := make(chan struct{})
+	strm, err := stream.New(
+		conf.StreamConfig(dryRun),
+		stream.OptAddProcessors(func() (types.Processor, error) {
+			return count, nil
+		}),
+		stream.OptSetLogger(logger),
+		stream.OptSetStats(stats),
+		stream.OptSetManager(mgr),
+		stream.OptOnClose(func() {
+			close(closedChan)
+		}),
+	)
+	if err != nil {
+		return res, fmt.Errorf("failed to create replay stream: %v", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	select {
+	case <-closedChan:
+	case <-sigChan:
+		logger.Infoln("Received termination signal, stopping replay.")
+		res.Interrupted = true
+		if err = strm.Stop(timeout); err != nil {
+			err = fmt.Errorf("failed to stop replay stream: %v", err)
+		}
+	}
+
+	res.Replayed = atomic.LoadInt64(&count.count)
+	res.Failed = atomic.LoadInt64(&count.failed)
+	return res, err
+}
+
+//------------------------------------------------------------------------------
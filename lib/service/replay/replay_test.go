@@ -0,0 +1,156 @@
+package replay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayStreamConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Output.Type = "kafka"
+
+	sConf := conf.StreamConfig(false)
+	require.Len(t, sConf.Pipeline.Processors, 1)
+	assert.Equal(t, processor.TypeMetadata, sConf.Pipeline.Processors[0].Type)
+	assert.Equal(t, "delete_prefix", sConf.Pipeline.Processors[0].Metadata.Operator)
+	assert.Equal(t, "dead_letter_", sConf.Pipeline.Processors[0].Metadata.Value)
+	assert.Equal(t, "kafka", sConf.Output.Type)
+
+	conf.Condition = `meta("dead_letter_component") == "output"`
+	conf.Mapping = `root = this`
+	conf.RateLimit = "foo"
+
+	sConf = conf.StreamConfig(true)
+	require.Len(t, sConf.Pipeline.Processors, 4)
+	assert.Equal(t, processor.TypeFilterParts, sConf.Pipeline.Processors[0].Type)
+	assert.Equal(t, processor.TypeBloblang, sConf.Pipeline.Processors[1].Type)
+	assert.Equal(t, processor.TypeMetadata, sConf.Pipeline.Processors[2].Type)
+	assert.Equal(t, processor.TypeRateLimit, sConf.Pipeline.Processors[3].Type)
+	assert.Equal(t, "foo", sConf.Pipeline.Processors[3].RateLimit.Resource)
+	assert.Equal(t, "stdout", sConf.Output.Type)
+}
+
+func TestReplayFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_replay_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "dlq.jsonl")
+	outPath := filepath.Join(dir, "out.jsonl")
+	defPath := filepath.Join(dir, "replay.yaml")
+
+	require.NoError(t, ioutil.WriteFile(inPath, []byte(`{"id":1,"status":"failed"}
+{"id":2,"status":"ok"}
+{"id":3,"status":"failed"}
+`), 0644))
+
+	require.NoError(t, ioutil.WriteFile(defPath, []byte(`
+input:
+  file:
+    path: `+inPath+`
+condition: this.status == "failed"
+mapping: |
+  root = this
+  root.status = "replayed"
+rate_limit: foo
+output:
+  file:
+    path: `+outPath+`
+resources:
+  rate_limits:
+    foo:
+      local:
+        count: 100
+        interval: 1s
+`), 0644))
+
+	conf, err := Read(defPath)
+	require.NoError(t, err)
+
+	res, err := Run(conf, false, time.Second*5, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res.Replayed)
+	assert.False(t, res.Interrupted)
+
+	outBytes, err := ioutil.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"status":"replayed"}
+{"id":3,"status":"replayed"}
+`, string(outBytes))
+}
+
+func TestReplayFailedMapping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_replay_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "dlq.jsonl")
+	outPath := filepath.Join(dir, "out.jsonl")
+
+	require.NoError(t, ioutil.WriteFile(inPath, []byte(`{"id":1,"status":"failed"}
+{"id":2}
+{"id":3,"status":"failed"}
+`), 0644))
+
+	conf := NewConfig()
+	conf.Input.Type = "file"
+	conf.Input.File.Path = inPath
+	conf.Mapping = `root = this
+root.status = this.status.uppercase()
+meta dead_letter_error = "nope"`
+	conf.Output.Type = "file"
+	conf.Output.File.Path = outPath
+
+	res, err := Run(conf, false, time.Second*5, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res.Replayed)
+	assert.Equal(t, int64(1), res.Failed)
+
+	outBytes, err := ioutil.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"status":"FAILED"}
+{"id":3,"status":"FAILED"}
+`, string(outBytes))
+}
+
+func TestReplayStripsDeadLetterMetadata(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping = `meta dead_letter_error = "nope"
+meta dead_letter_component = "output"
+meta foo = "bar"`
+
+	procs := []types.Processor{}
+	for _, pConf := range conf.StreamConfig(true).Pipeline.Processors {
+		proc, err := processor.New(pConf, types.NoopMgr(), log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		procs = append(procs, proc)
+	}
+
+	msgs, res := processor.ExecuteAll(procs, message.New([][]byte{[]byte(`{}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	meta := msgs[0].Get(0).Metadata()
+	assert.Equal(t, "", meta.Get("dead_letter_error"))
+	assert.Equal(t, "", meta.Get("dead_letter_component"))
+	assert.Equal(t, "bar", meta.Get("foo"))
+}
+
+func TestReplayBadMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping = `root = this.`
+
+	_, err := Run(conf, true, time.Second, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
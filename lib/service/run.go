@@ -11,6 +11,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/service/blobl"
+	"github.com/Jeffail/benthos/v3/lib/service/replay"
 	"github.com/Jeffail/benthos/v3/lib/service/test"
 	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
 	"github.com/urfave/cli/v2"
@@ -161,6 +162,12 @@ func Run() {
 				Value: false,
 				Usage: "continue to execute a config containing linter errors",
 			},
+			&cli.BoolFlag{
+				Name:    "watcher",
+				Aliases: []string{"w"},
+				Value:   false,
+				Usage:   "watch the config file for changes and hot reload the stream, reloads can also be triggered with SIGHUP",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if c.Bool("version") {
@@ -171,7 +178,7 @@ func Run() {
 				cli.ShowAppHelp(c)
 				os.Exit(1)
 			}
-			os.Exit(cmdService(c.String("config"), !c.Bool("chilled"), false, nil))
+			os.Exit(cmdService(c.String("config"), !c.Bool("chilled"), false, c.Bool("watcher"), nil))
 			return nil
 		},
 		Commands: []*cli.Command{
@@ -258,7 +265,7 @@ func Run() {
    For more information check out the docs at:
    https://benthos.dev/docs/guides/streams_mode/about`[4:],
 				Action: func(c *cli.Context) error {
-					os.Exit(cmdService(c.String("config"), !c.Bool("chilled"), true, c.Args().Slice()))
+					os.Exit(cmdService(c.String("config"), !c.Bool("chilled"), true, false, c.Args().Slice()))
 					return nil
 				},
 			},
@@ -321,6 +328,7 @@ func Run() {
 			},
 			test.CliCommand(testSuffix),
 			blobl.CliCommand(),
+			replay.CliCommand(),
 		},
 	}
 
@@ -349,7 +357,7 @@ func Run() {
 		}
 
 		deprecatedExecute(*configPath, testSuffix)
-		os.Exit(cmdService(*configPath, false, false, nil))
+		os.Exit(cmdService(*configPath, false, false, false, nil))
 		return nil
 	}
 
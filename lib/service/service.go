@@ -17,6 +17,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/stream"
 	strmmgr "github.com/Jeffail/benthos/v3/lib/stream/manager"
+	"github.com/Jeffail/benthos/v3/lib/stream/manager/store"
 	"github.com/Jeffail/benthos/v3/lib/tracer"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
@@ -110,6 +111,7 @@ func cmdService(
 	confPath string,
 	strict bool,
 	streamsMode bool,
+	watchConfig bool,
 	streamsConfigs []string,
 ) int {
 	lints := readConfig(confPath)
@@ -184,17 +186,37 @@ func cmdService(
 		return 1
 	}
 
+	if watchConfig && len(confPath) == 0 {
+		logger.Errorln("A config file must be specified with -c in order to watch it for changes")
+		return 1
+	}
+
 	var dataStream stoppableStreams
+	var reloadableStream *stream.Type
 	dataStreamClosedChan := make(chan struct{})
 
 	// Create data streams.
 	if streamsMode {
-		streamMgr := strmmgr.New(
-			strmmgr.OptSetAPITimeout(time.Second*5),
+		mgrOpts := []func(*strmmgr.Type){
+			strmmgr.OptSetAPITimeout(time.Second * 5),
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
-		)
+		}
+		if conf.StreamStore != nil {
+			syncPeriod, err := time.ParseDuration(conf.StreamStore.SyncPeriod)
+			if err != nil {
+				logger.Errorf("Failed to parse stream store sync period: %v\n", err)
+				return 1
+			}
+			streamStore, err := store.New(*conf.StreamStore, manager, logger.NewModule(".stream_store"), stats)
+			if err != nil {
+				logger.Errorf("Failed to create stream store: %v\n", err)
+				return 1
+			}
+			mgrOpts = append(mgrOpts, strmmgr.OptSetStore(streamStore, syncPeriod))
+		}
+		streamMgr := strmmgr.New(mgrOpts...)
 		streamConfs := map[string]stream.Config{}
 		var streamLints []string
 		for _, path := range streamsConfigs {
@@ -221,6 +243,10 @@ func cmdService(
 
 		dataStream = streamMgr
 		for id, conf := range streamConfs {
+			if _, err = streamMgr.Read(id); err == nil {
+				logger.Warnf("Stream (%v) loaded from a config file already exists within the stream store, the stored config takes precedence\n", id)
+				continue
+			}
 			if err = streamMgr.Create(id, conf); err != nil {
 				logger.Errorf("Failed to create stream (%v): %v\n", id, err)
 				return 1
@@ -228,18 +254,26 @@ func cmdService(
 		}
 		logger.Infoln("Launching benthos in streams mode, use CTRL+C to close.")
 	} else {
-		if dataStream, err = stream.New(
-			conf.Config,
+		strmOpts := []func(*stream.Type){
 			stream.OptSetLogger(logger),
 			stream.OptSetStats(stats),
 			stream.OptSetManager(manager),
 			stream.OptOnClose(func() {
 				close(dataStreamClosedChan)
 			}),
-		); err != nil {
+		}
+		if watchConfig {
+			strmOpts = append(strmOpts, stream.OptEnableReload())
+		}
+		var strm *stream.Type
+		if strm, err = stream.New(conf.Config, strmOpts...); err != nil {
 			logger.Errorf("Service closing due to: %v\n", err)
 			return 1
 		}
+		dataStream = strm
+		if watchConfig {
+			reloadableStream = strm
+		}
 		logger.Infoln("Launching a benthos instance, use CTRL+C to close.")
 	}
 
@@ -305,16 +339,46 @@ func cmdService(
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	reloadChan := make(chan struct{}, 1)
+	if reloadableStream != nil {
+		signal.Notify(sigChan, syscall.SIGHUP)
+
+		watcherStopChan := make(chan struct{})
+		defer close(watcherStopChan)
+		go watchConfigFile(confPath, time.Second, reloadChan, watcherStopChan, logger)
+		logger.Infof("Watching config file '%v' for changes.\n", confPath)
+
+		reloader := &configReloader{
+			path:    confPath,
+			strict:  strict,
+			strm:    reloadableStream,
+			timeout: exitTimeout,
+			logger:  logger,
+			applied: conf,
+		}
+		go reloader.loop(reloadChan, watcherStopChan)
+	}
+
 	// Wait for termination signal
-	select {
-	case <-sigChan:
-		logger.Infoln("Received SIGTERM, the service is closing.")
-	case <-dataStreamClosedChan:
-		logger.Infoln("Pipeline has terminated. Shutting down the service.")
-	case <-httpServerClosedChan:
-		logger.Infoln("HTTP Server has terminated. Shutting down the service.")
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP {
+				logger.Infoln("Received SIGHUP, reloading config.")
+				select {
+				case reloadChan <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			logger.Infoln("Received SIGTERM, the service is closing.")
+		case <-dataStreamClosedChan:
+			logger.Infoln("Pipeline has terminated. Shutting down the service.")
+		case <-httpServerClosedChan:
+			logger.Infoln("HTTP Server has terminated. Shutting down the service.")
+		}
+		return 0
 	}
-	return 0
 }
 
 //------------------------------------------------------------------------------
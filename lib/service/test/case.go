@@ -1,7 +1,13 @@
 package test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/metadata"
@@ -12,21 +18,31 @@ import (
 
 //------------------------------------------------------------------------------
 
-// InputPart defines an input part for a test case.
+// InputPart defines an input part for a test case. The content of the part can
+// instead be loaded from a fixture file, either raw or as JSON lines where each
+// line of the file becomes a part of its own.
 type InputPart struct {
-	Content  string            `yaml:"content"`
-	Metadata map[string]string `yaml:"metadata"`
+	Content       string            `yaml:"content"`
+	FilePath      string            `yaml:"file_path"`
+	JSONLinesPath string            `yaml:"json_lines_path"`
+	Metadata      map[string]string `yaml:"metadata"`
 }
 
 // Case contains a definition of a single Benthos config test case.
 type Case struct {
-	Name             string            `yaml:"name"`
-	Environment      map[string]string `yaml:"environment"`
-	TargetProcessors string            `yaml:"target_processors"`
-	InputBatch       []InputPart       `yaml:"input_batch"`
-	OutputBatches    [][]ConditionsMap `yaml:"output_batches"`
+	Name             string                       `yaml:"name"`
+	Environment      map[string]string            `yaml:"environment"`
+	TargetProcessors string                       `yaml:"target_processors"`
+	TargetStream     *StreamTarget                `yaml:"target_stream"`
+	InputBatch       []InputPart                  `yaml:"input_batch"`
+	OutputBatches    [][]ConditionsMap            `yaml:"output_batches"`
+	Outputs          map[string]OutputExpectation `yaml:"outputs"`
+	Mocks            Mocks                        `yaml:"mocks"`
 
 	line int
+
+	// The directory that relative fixture paths are resolved from.
+	dir string
 }
 
 // NewCase returns a default test case.
@@ -90,10 +106,104 @@ type ProcProvider interface {
 	Provide(jsonPtr string, environment map[string]string) ([]types.Processor, error)
 }
 
-// Execute attempts to execute a test case against a Benthos configuration.
+// MockedProcProvider returns compiled processors extracted from a Benthos
+// config using a JSON Pointer, where components and resources of the config are
+// replaced with mocks.
+type MockedProcProvider interface {
+	ProvideMocked(jsonPtr string, environment map[string]string, mocks Mocks) ([]types.Processor, error)
+}
+
+// StreamProvider returns a running stream extracted from a Benthos config with
+// inputs and outputs replaced by mocks.
+type StreamProvider interface {
+	ProvideStream(target StreamTarget, environment map[string]string, mocks Mocks) (*MockedStream, error)
+}
+
+func (c *Case) resolvePath(path string) string {
+	if len(c.dir) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+func (c *Case) inputMessage() (types.Message, error) {
+	var parts []types.Part
+	for _, v := range c.InputBatch {
+		var contents [][]byte
+		switch {
+		case len(v.FilePath) > 0:
+			fileBytes, err := ioutil.ReadFile(c.resolvePath(v.FilePath))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input file: %v", err)
+			}
+			contents = append(contents, fileBytes)
+		case len(v.JSONLinesPath) > 0:
+			fileBytes, err := ioutil.ReadFile(c.resolvePath(v.JSONLinesPath))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input file: %v", err)
+			}
+			for i, line := range bytes.Split(fileBytes, []byte("\n")) {
+				if line = bytes.TrimSpace(line); len(line) == 0 {
+					continue
+				}
+				if !json.Valid(line) {
+					return nil, fmt.Errorf("line %v of input file '%v' is not valid JSON", i+1, v.JSONLinesPath)
+				}
+				contents = append(contents, line)
+			}
+		default:
+			contents = append(contents, []byte(v.Content))
+		}
+		for _, content := range contents {
+			part := message.NewPart(content)
+			part.SetMetadata(metadata.New(v.Metadata).Copy())
+			parts = append(parts, part)
+		}
+	}
+
+	inputMsg := message.New(nil)
+	inputMsg.SetAll(parts)
+	return inputMsg, nil
+}
+
+// Execute attempts to execute a test case against a Benthos configuration. When
+// the case targets a stream the provider must also implement StreamProvider,
+// and when the case has mocks it must implement MockedProcProvider.
 func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error) {
+	var inputMsg types.Message
+	if inputMsg, err = c.inputMessage(); err != nil {
+		return nil, err
+	}
+
+	mocks := c.Mocks
+	if len(mocks.HTTP) > 0 {
+		var server *httpMockServer
+		if server, err = newHTTPMockServer(mocks.HTTP); err != nil {
+			return nil, err
+		}
+		defer server.Close()
+		mocks.httpURL = server.url
+	}
+
+	if c.TargetStream != nil {
+		strmProvider, ok := provider.(StreamProvider)
+		if !ok {
+			return nil, errors.New("provider does not support stream targets")
+		}
+		return c.executeStream(strmProvider, inputMsg, mocks)
+	}
+
 	var procSet []types.Processor
-	if procSet, err = provider.Provide(c.TargetProcessors, c.Environment); err != nil {
+	if mocks.isEmpty() {
+		procSet, err = provider.Provide(c.TargetProcessors, c.Environment)
+	} else {
+		mockedProvider, ok := provider.(MockedProcProvider)
+		if !ok {
+			return nil, errors.New("provider does not support mocks")
+		}
+		procSet, err = mockedProvider.ProvideMocked(c.TargetProcessors, c.Environment, mocks)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
 	}
 
@@ -105,15 +215,6 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 		})
 	}
 
-	parts := make([]types.Part, len(c.InputBatch))
-	for i, v := range c.InputBatch {
-		part := message.NewPart([]byte(v.Content))
-		part.SetMetadata(metadata.New(v.Metadata))
-		parts[i] = part
-	}
-
-	inputMsg := message.New(nil)
-	inputMsg.SetAll(parts)
 	outputBatches, result := processor.ExecuteAll(procSet, inputMsg)
 	if result != nil {
 		if len(c.OutputBatches) == 0 {
@@ -155,3 +256,60 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 }
 
 //------------------------------------------------------------------------------
+
+func (c *Case) executeStream(provider StreamProvider, inputMsg types.Message, mocks Mocks) (failures []CaseFailure, err error) {
+	target := *c.TargetStream
+
+	var inputName string
+	if inputName, err = target.targetInput(); err != nil {
+		return nil, err
+	}
+	var timeout time.Duration
+	if timeout, err = target.timeout(); err != nil {
+		return nil, err
+	}
+	mockOutputs := target.outputs()
+	for k := range c.Outputs {
+		if _, exists := mockOutputs[k]; !exists {
+			return nil, fmt.Errorf("output '%v' is not a mocked output", k)
+		}
+	}
+
+	var strm *MockedStream
+	if strm, err = provider.ProvideStream(target, c.Environment, mocks); err != nil {
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	reportFailure := func(reason string) {
+		failures = append(failures, CaseFailure{
+			Name:     c.Name,
+			TestLine: c.line,
+			Reason:   reason,
+		})
+	}
+
+	if err = strm.Send(inputName, inputMsg, timeout); err != nil {
+		reportFailure(fmt.Sprintf("input '%v': %v", inputName, err))
+	}
+	if err = strm.Close(timeout); err != nil {
+		return nil, fmt.Errorf("failed to shut down stream: %v", err)
+	}
+
+	for _, name := range target.outputNames() {
+		parts := strm.Received(name)
+		exp, exists := c.Outputs[name]
+		if !exists {
+			// Messages must not reach outputs without expectations.
+			for _, p := range parts {
+				reportFailure(fmt.Sprintf("output '%v': unexpected message: %s", name, p.Get()))
+			}
+			continue
+		}
+		for _, reason := range exp.Check(parts) {
+			reportFailure(fmt.Sprintf("output '%v': %v", name, reason))
+		}
+	}
+	return failures, nil
+}
+
+//------------------------------------------------------------------------------
@@ -1,10 +1,13 @@
 package test
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
 
+	"github.com/Jeffail/benthos/v3/lib/bloblang/x/query"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	yaml "gopkg.in/yaml.v3"
 )
@@ -51,6 +54,18 @@ func (c *ConditionsMap) UnmarshalYAML(value *yaml.Node) error {
 				return fmt.Errorf("line %v: %v", v.Line, err)
 			}
 			cond = val
+		case "json_contains":
+			val := JSONContainsCondition{}
+			if err := v.Decode(&val); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			cond = val
+		case "bloblang":
+			val := BloblangCondition{}
+			if err := v.Decode(&val); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			cond = val
 		default:
 			return fmt.Errorf("line %v: message part condition type not recognised: %v", v.Line, k)
 		}
@@ -121,3 +136,146 @@ func (m MetadataEqualsCondition) Check(p types.Part) error {
 }
 
 //------------------------------------------------------------------------------
+
+// JSONContainsCondition parses the contents of a message as JSON and checks
+// whether it contains a structure. Objects contain a structure when each of its
+// keys is contained, arrays when each of its elements is contained by any
+// element, and all other values must be equal.
+type JSONContainsCondition struct {
+	structure interface{}
+}
+
+// NewJSONContainsCondition returns a JSONContainsCondition for a structure,
+// which must be serialisable as JSON.
+func NewJSONContainsCondition(structure interface{}) (JSONContainsCondition, error) {
+	// Round trip the structure in order to match the types of parsed messages.
+	var c JSONContainsCondition
+	jBytes, err := json.Marshal(structure)
+	if err != nil {
+		return c, fmt.Errorf("failed to serialise structure as JSON: %v", err)
+	}
+	err = json.Unmarshal(jBytes, &c.structure)
+	return c, err
+}
+
+// UnmarshalYAML extracts a JSONContainsCondition from a YAML node.
+func (j *JSONContainsCondition) UnmarshalYAML(value *yaml.Node) error {
+	var structure interface{}
+	if err := value.Decode(&structure); err != nil {
+		return err
+	}
+	c, err := NewJSONContainsCondition(structure)
+	if err != nil {
+		return err
+	}
+	*j = c
+	return nil
+}
+
+func jsonContains(structure, value interface{}) bool {
+	switch s := structure.(type) {
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, sv := range s {
+			vv, exists := v[k]
+			if !exists || !jsonContains(sv, vv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		v, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+	elements:
+		for _, se := range s {
+			for _, ve := range v {
+				if jsonContains(se, ve) {
+					continue elements
+				}
+			}
+			return false
+		}
+		return true
+	}
+	return structure == value
+}
+
+// Check this condition against a message part.
+func (j JSONContainsCondition) Check(p types.Part) error {
+	expBytes, _ := json.Marshal(j.structure)
+
+	// Parse the raw contents rather than using any structured form of the part
+	// in order to match the types of the structure.
+	var value interface{}
+	if err := json.Unmarshal(p.Get(), &value); err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	if !jsonContains(j.structure, value) {
+		return fmt.Errorf("content mismatch\n  expected to contain: %v\n             received: %v", blue(string(expBytes)), red(string(p.Get())))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// BloblangCondition executes a Bloblang query against a message and checks
+// that the result is a boolean true.
+type BloblangCondition struct {
+	query string
+	fn    query.Function
+}
+
+// NewBloblangCondition parses a Bloblang query into a BloblangCondition.
+func NewBloblangCondition(q string) (BloblangCondition, error) {
+	fn, err := query.New(q)
+	if err != nil {
+		return BloblangCondition{}, fmt.Errorf("failed to parse bloblang query: %v", err)
+	}
+	return BloblangCondition{query: q, fn: fn}, nil
+}
+
+// UnmarshalYAML extracts a BloblangCondition from a YAML node.
+func (b *BloblangCondition) UnmarshalYAML(value *yaml.Node) error {
+	var q string
+	if err := value.Decode(&q); err != nil {
+		return err
+	}
+	c, err := NewBloblangCondition(q)
+	if err != nil {
+		return err
+	}
+	*b = c
+	return nil
+}
+
+// Check this condition against a message part.
+func (b BloblangCondition) Check(p types.Part) error {
+	msg := message.New(nil)
+	msg.Append(p)
+
+	var valuePtr *interface{}
+	if jObj, err := p.JSON(); err == nil {
+		valuePtr = &jObj
+	}
+
+	result, err := b.fn.Exec(query.FunctionContext{
+		Value: valuePtr,
+		Maps:  map[string]query.Function{},
+		Vars:  map[string]interface{}{},
+		Msg:   msg,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	if resultBool, _ := result.(bool); !resultBool {
+		return fmt.Errorf("predicate failed\n     query: %v\n  received: %v", blue(b.query), red(string(p.Get())))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
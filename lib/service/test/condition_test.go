@@ -269,3 +269,122 @@ func TestMetadataEqualsCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONContainsCondition(t *testing.T) {
+	color.NoColor = true
+
+	var cond JSONContainsCondition
+	if err := yaml.Unmarshal([]byte(`{ a: { b: 5 }, c: [ foo, { d: true } ] }`), &cond); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name     string
+		input    string
+		expected error
+	}
+
+	tests := []testCase{
+		{
+			name:     "positive 1",
+			input:    `{"a":{"b":5},"c":["foo",{"d":true}]}`,
+			expected: nil,
+		},
+		{
+			name:     "positive 2",
+			input:    `{"a":{"b":5,"e":"bar"},"c":[{"d":true,"f":1},"baz","foo"],"g":null}`,
+			expected: nil,
+		},
+		{
+			name:     "negative 1",
+			input:    `{"a":{"b":6},"c":["foo",{"d":true}]}`,
+			expected: errors.New("content mismatch\n  expected to contain: {\"a\":{\"b\":5},\"c\":[\"foo\",{\"d\":true}]}\n             received: {\"a\":{\"b\":6},\"c\":[\"foo\",{\"d\":true}]}"),
+		},
+		{
+			name:     "negative 2",
+			input:    `{"a":{"b":5},"c":["foo"]}`,
+			expected: errors.New("content mismatch\n  expected to contain: {\"a\":{\"b\":5},\"c\":[\"foo\",{\"d\":true}]}\n             received: {\"a\":{\"b\":5},\"c\":[\"foo\"]}"),
+		},
+		{
+			name:     "negative 3",
+			input:    `not json`,
+			expected: errors.New("failed to parse message as JSON: invalid character 'o' in literal null (expecting 'u')"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			actErr := cond.Check(message.NewPart([]byte(test.input)))
+			if test.expected == nil && actErr == nil {
+				return
+			}
+			if test.expected == nil || actErr == nil {
+				tt.Errorf("Wrong result, expected %v, received %v", test.expected, actErr)
+				return
+			}
+			if exp, act := test.expected.Error(), actErr.Error(); exp != act {
+				tt.Errorf("Wrong result, expected %v, received %v", exp, act)
+			}
+		})
+	}
+}
+
+func TestBloblangCondition(t *testing.T) {
+	color.NoColor = true
+
+	if _, err := NewBloblangCondition(`this.foo ==`); err == nil {
+		t.Error("Expected error from bad query")
+	}
+
+	var cond BloblangCondition
+	if err := yaml.Unmarshal([]byte(`this.foo == "bar" && meta("baz") == "qux"`), &cond); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name     string
+		input    string
+		meta     map[string]string
+		expected error
+	}
+
+	tests := []testCase{
+		{
+			name:     "positive 1",
+			input:    `{"foo":"bar"}`,
+			meta:     map[string]string{"baz": "qux"},
+			expected: nil,
+		},
+		{
+			name:     "negative 1",
+			input:    `{"foo":"bar"}`,
+			expected: errors.New("failed to execute query: metadata value not found"),
+		},
+		{
+			name:     "negative 2",
+			input:    `{"foo":"nope"}`,
+			meta:     map[string]string{"baz": "qux"},
+			expected: errors.New("predicate failed\n     query: this.foo == \"bar\" && meta(\"baz\") == \"qux\"\n  received: {\"foo\":\"nope\"}"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			part := message.NewPart([]byte(test.input))
+			for k, v := range test.meta {
+				part.Metadata().Set(k, v)
+			}
+			actErr := cond.Check(part)
+			if test.expected == nil && actErr == nil {
+				return
+			}
+			if test.expected == nil || actErr == nil {
+				tt.Errorf("Wrong result, expected %v, received %v", test.expected, actErr)
+				return
+			}
+			if exp, act := test.expected.Error(), actErr.Error(); exp != act {
+				tt.Errorf("Wrong result, expected %v, received %v", exp, act)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"golang.org/x/sync/errgroup"
@@ -37,11 +38,26 @@ func (d Definition) Execute(filepath string) ([]CaseFailure, error) {
 	return d.execute(filepath, log.Noop())
 }
 
-func (d Definition) execute(filepath string, logger log.Modular) ([]CaseFailure, error) {
-	procsProvider := NewProcessorsProvider(filepath, OptProcessorsProviderSetLogger(logger))
+func (d Definition) execute(confPath string, logger log.Modular) ([]CaseFailure, error) {
+	procsProvider := NewProcessorsProvider(confPath, OptProcessorsProviderSetLogger(logger))
+
+	// Fixture paths are relative to the config, which shares a directory with
+	// its test definition.
+	cases := make([]Case, len(d.Cases))
+	for i, c := range d.Cases {
+		c.dir = filepath.Dir(confPath)
+		cases[i] = c
+	}
+
 	if d.Parallel {
 		// Warm the cache of processor configs.
-		for _, c := range d.Cases {
+		for _, c := range cases {
+			if c.TargetStream != nil {
+				if _, err := procsProvider.getStreamConfs(*c.TargetStream, c.Environment, Mocks{}); err != nil {
+					return nil, err
+				}
+				continue
+			}
 			if _, err := procsProvider.getConfs(c.TargetProcessors, c.Environment); err != nil {
 				return nil, err
 			}
@@ -50,7 +66,7 @@ func (d Definition) execute(filepath string, logger log.Modular) ([]CaseFailure,
 
 	var totalFailures []CaseFailure
 	if !d.Parallel {
-		for i, c := range d.Cases {
+		for i, c := range cases {
 			failures, err := c.Execute(procsProvider)
 			if err != nil {
 				return nil, fmt.Errorf("test case %v failed: %v", i, err)
@@ -60,8 +76,8 @@ func (d Definition) execute(filepath string, logger log.Modular) ([]CaseFailure,
 	} else {
 		var g errgroup.Group
 
-		failureSlices := make([][]CaseFailure, len(d.Cases))
-		for i, c := range d.Cases {
+		failureSlices := make([][]CaseFailure, len(cases))
+		for i, c := range cases {
 			i := i
 			c := c
 			g.Go(func() error {
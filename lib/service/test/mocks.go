@@ -0,0 +1,194 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// HTTPMock is a canned response returned for HTTP requests that match a method
+// and path.
+type HTTPMock struct {
+	Method  string            `yaml:"method"`
+	Path    string            `yaml:"path"`
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+// matches returns true if a request matches the method and path of the mock,
+// where an empty method matches any and the path may contain glob patterns.
+func (h HTTPMock) matches(r *http.Request) bool {
+	if len(h.Method) > 0 && !strings.EqualFold(h.Method, r.Method) {
+		return false
+	}
+	if matched, err := path.Match(h.Path, r.URL.Path); err == nil {
+		return matched
+	}
+	return h.Path == r.URL.Path
+}
+
+// Mocks describes components and resources of a config that are replaced with
+// mocks during a test case.
+type Mocks struct {
+	Caches     map[string]map[string]string      `yaml:"caches"`
+	HTTP       []HTTPMock                        `yaml:"http"`
+	Processors map[string]map[string]interface{} `yaml:"processors"`
+
+	// The base URL of the server responding to HTTP mocks.
+	httpURL string
+}
+
+func (m Mocks) isEmpty() bool {
+	return len(m.Caches) == 0 && len(m.HTTP) == 0 && len(m.Processors) == 0
+}
+
+// apply the mocks to a generic config structure.
+func (m Mocks) apply(root interface{}) error {
+	ptrs := []string{}
+	for k := range m.Processors {
+		ptrs = append(ptrs, k)
+	}
+	sort.Strings(ptrs)
+
+	for _, ptr := range ptrs {
+		node, err := config.JSONPointer(ptr, root)
+		if err != nil {
+			return fmt.Errorf("failed to resolve mock processor '%v': %v", ptr, err)
+		}
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("failed to resolve mock processor '%v': path does not target a processor config", ptr)
+		}
+		for k := range obj {
+			delete(obj, k)
+		}
+		for k, v := range m.Processors[ptr] {
+			obj[k] = v
+		}
+	}
+
+	if len(m.Caches) > 0 {
+		rootObj, ok := root.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected config to be an object, found %T", root)
+		}
+		caches := childObj(childObj(rootObj, "resources"), "caches")
+		for k := range m.Caches {
+			caches[k] = map[string]interface{}{
+				"type": "memory",
+			}
+		}
+	}
+
+	if len(m.httpURL) > 0 {
+		rewriteHTTPURLs(root, m.httpURL)
+	}
+	return nil
+}
+
+// preloadCaches sets the values of mock caches.
+func (m Mocks) preloadCaches(mgr types.Manager) error {
+	for name, values := range m.Caches {
+		c, err := mgr.GetCache(name)
+		if err != nil {
+			return fmt.Errorf("failed to access mock cache '%v': %v", name, err)
+		}
+		for k, v := range values {
+			if err = c.Set(k, []byte(v)); err != nil {
+				return fmt.Errorf("failed to preload mock cache '%v': %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func childObj(obj map[string]interface{}, key string) map[string]interface{} {
+	child, ok := obj[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		obj[key] = child
+	}
+	return child
+}
+
+// rewriteHTTPURLs walks a generic config structure and replaces the scheme and
+// host of any url field using HTTP with a base URL.
+func rewriteHTTPURLs(node interface{}, baseURL string) {
+	switch t := node.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			if s, ok := v.(string); ok && k == "url" {
+				if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+					hostStart := strings.Index(s, "://") + 3
+					if pathStart := strings.Index(s[hostStart:], "/"); pathStart >= 0 {
+						t[k] = baseURL + s[hostStart+pathStart:]
+					} else {
+						t[k] = baseURL
+					}
+				}
+				continue
+			}
+			rewriteHTTPURLs(v, baseURL)
+		}
+	case []interface{}:
+		for _, v := range t {
+			rewriteHTTPURLs(v, baseURL)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// httpMockServer responds to requests with the first matching HTTP mock.
+type httpMockServer struct {
+	url    string
+	server *http.Server
+}
+
+func newHTTPMockServer(mocks []HTTPMock) (*httpMockServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start HTTP mock server: %v", err)
+	}
+
+	h := &httpMockServer{
+		url: "http://" + listener.Addr().String(),
+	}
+	h.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, mock := range mocks {
+				if !mock.matches(r) {
+					continue
+				}
+				for k, v := range mock.Headers {
+					w.Header().Set(k, v)
+				}
+				status := mock.Status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(mock.Body))
+				return
+			}
+			http.Error(w, fmt.Sprintf("no HTTP mock matched %v %v", r.Method, r.URL.Path), http.StatusNotFound)
+		}),
+	}
+	go h.server.Serve(listener)
+	return h, nil
+}
+
+func (h *httpMockServer) Close() error {
+	return h.server.Close()
+}
+
+//------------------------------------------------------------------------------
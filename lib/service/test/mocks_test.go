@@ -0,0 +1,304 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fatih/color"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const mocksTestConfig = `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: foo
+
+pipeline:
+  processors:
+  - cache:
+      cache: users
+      operator: get
+      key: ${! json("id") }
+  - http:
+      request:
+        url: https://api.example.com/users/${! content() }?verbose=true
+        verb: GET
+      parallel: true
+  - sql:
+      driver: mysql
+      dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+      query: "SELECT * FROM footable WHERE name = ?;"
+      args: [ '${! json("name") }' ]
+
+output:
+  http_client:
+    url: https://api.example.com/sink
+    verb: POST
+
+resources:
+  caches:
+    users:
+      redis:
+        url: tcp://localhost:6379
+`
+
+const mocksTestDefinition = `
+parallel: %v
+tests:
+  - name: processors with mocks
+    target_processors: /pipeline/processors
+    mocks:
+      caches:
+        users:
+          foo: "1"
+          bar: "2"
+      http:
+        - method: GET
+          path: /users/2
+          body: '{"name":"not this one"}'
+        - method: GET
+          path: /users/*
+          headers:
+            Content-Type: application/json
+          body: '{"name":"bob"}'
+      processors:
+        /pipeline/processors/2:
+          bloblang: 'root = this.merge({"rows":[{"age":30}]})'
+    input_batch:
+      - content: '{"id":"foo"}'
+    output_batches:
+      - - json_contains: { name: bob, rows: [ { age: 30 } ] }
+
+  - name: stream with mocks and fixtures
+    target_stream: {}
+    mocks:
+      caches:
+        users:
+          foo: "1"
+          bar: "2"
+      http:
+        - path: /users/1
+          body: '{"name":"bob"}'
+        - path: /users/2
+          body: '{"name":"alice"}'
+      processors:
+        /pipeline/processors/2:
+          bloblang: 'root = this'
+    input_batch:
+      - json_lines_path: ./fixtures/users.jsonl
+        metadata:
+          source: fixture
+    outputs:
+      output:
+        messages:
+          - json_contains: { name: bob }
+            metadata_equals:
+              source: fixture
+          - json_contains: { name: alice }
+
+  - name: raw fixture
+    target_processors: /pipeline/processors/0
+    mocks:
+      caches:
+        users:
+          foo: "1"
+    input_batch:
+      - file_path: ./fixtures/foo.json
+    output_batches:
+      - - content_equals: "1"
+`
+
+func TestMocksDefinition(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml":          mocksTestConfig,
+		"fixtures/users.jsonl":  "{\"id\":\"foo\"}\n\n{\"id\":\"bar\"}\n",
+		"fixtures/foo.json":     `{"id":"foo"}`,
+		"fixtures/invalid.json": "{\"id\":\"foo\"}\nnope\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	for _, parallel := range []string{"false", "true"} {
+		var def Definition
+		if err = yaml.Unmarshal([]byte(fmt.Sprintf(mocksTestDefinition, parallel)), &def); err != nil {
+			t.Fatal(err)
+		}
+
+		fails, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fails) > 0 {
+			t.Errorf("Unexpected failures (parallel: %v): %v", parallel, fails)
+		}
+	}
+
+	badFixtures := []InputPart{
+		{FilePath: "./fixtures/nope.json"},
+		{JSONLinesPath: "./fixtures/nope.jsonl"},
+		{JSONLinesPath: "./fixtures/invalid.json"},
+	}
+	for _, part := range badFixtures {
+		def := Definition{
+			Cases: []Case{{
+				TargetProcessors: "/pipeline/processors/0",
+				InputBatch:       []InputPart{part},
+			}},
+		}
+		if _, err = def.Execute(filepath.Join(testDir, "config1.yaml")); err == nil {
+			t.Errorf("Expected error from fixture: %+v", part)
+		}
+	}
+}
+
+func TestMocksApply(t *testing.T) {
+	var root interface{}
+	if err := yaml.Unmarshal([]byte(mocksTestConfig), &root); err != nil {
+		t.Fatal(err)
+	}
+
+	mocks := Mocks{
+		Caches: map[string]map[string]string{
+			"users":  {"foo": "bar"},
+			"others": {},
+		},
+		Processors: map[string]map[string]interface{}{
+			"/pipeline/processors/2": {"noop": map[string]interface{}{}},
+		},
+		httpURL: "http://127.0.0.1:4195",
+	}
+	if err := mocks.apply(root); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]interface{}{
+		"input": map[string]interface{}{
+			"kafka": map[string]interface{}{
+				"addresses": []interface{}{"localhost:9092"},
+				"topic":     "foo",
+			},
+		},
+		"pipeline": map[string]interface{}{
+			"processors": []interface{}{
+				map[string]interface{}{
+					"cache": map[string]interface{}{
+						"cache":    "users",
+						"operator": "get",
+						"key":      `${! json("id") }`,
+					},
+				},
+				map[string]interface{}{
+					"http": map[string]interface{}{
+						"request": map[string]interface{}{
+							"url":  "http://127.0.0.1:4195/users/${! content() }?verbose=true",
+							"verb": "GET",
+						},
+						"parallel": true,
+					},
+				},
+				map[string]interface{}{
+					"noop": map[string]interface{}{},
+				},
+			},
+		},
+		"output": map[string]interface{}{
+			"http_client": map[string]interface{}{
+				"url":  "http://127.0.0.1:4195/sink",
+				"verb": "POST",
+			},
+		},
+		"resources": map[string]interface{}{
+			"caches": map[string]interface{}{
+				"users": map[string]interface{}{
+					"type": "memory",
+				},
+				"others": map[string]interface{}{
+					"type": "memory",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exp, root) {
+		t.Errorf("Wrong mocked config: %v != %v", root, exp)
+	}
+
+	mocks = Mocks{
+		Processors: map[string]map[string]interface{}{
+			"/pipeline/processors/5": {"noop": map[string]interface{}{}},
+		},
+	}
+	if err := mocks.apply(root); err == nil {
+		t.Error("Expected error from bad processor path")
+	}
+}
+
+func TestHTTPMockServer(t *testing.T) {
+	server, err := newHTTPMockServer([]HTTPMock{
+		{
+			Method: "POST",
+			Path:   "/foo",
+			Status: http.StatusCreated,
+			Body:   "created foo",
+		},
+		{
+			Path: "/foo/*",
+			Headers: map[string]string{
+				"X-Foo": "bar",
+			},
+			Body: "any foo",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		header string
+		body   string
+	}{
+		{method: "POST", path: "/foo", status: http.StatusCreated, body: "created foo"},
+		{method: "GET", path: "/foo/bar?baz=qux", status: http.StatusOK, header: "bar", body: "any foo"},
+		{method: "DELETE", path: "/foo/bar", status: http.StatusOK, header: "bar", body: "any foo"},
+		{method: "GET", path: "/foo", status: http.StatusNotFound, body: "no HTTP mock matched GET /foo\n"},
+		{method: "GET", path: "/foo/bar/baz", status: http.StatusNotFound, body: "no HTTP mock matched GET /foo/bar/baz\n"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, server.url+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := test.status, res.StatusCode; exp != act {
+			t.Errorf("Wrong status for %v %v: %v != %v", test.method, test.path, act, exp)
+		}
+		if exp, act := test.header, res.Header.Get("X-Foo"); exp != act {
+			t.Errorf("Wrong header for %v %v: %v != %v", test.method, test.path, act, exp)
+		}
+		if exp, act := test.body, string(body); exp != act {
+			t.Errorf("Wrong body for %v %v: %v != %v", test.method, test.path, act, exp)
+		}
+	}
+}
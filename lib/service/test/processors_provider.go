@@ -18,12 +18,17 @@ import (
 type cachedConfig struct {
 	mgr   manager.Config
 	procs []processor.Config
+	strm  config.Type
+	mocks Mocks
 }
 
 // ProcessorsProvider consumes a Benthos config and, given a JSON Pointer,
-// extracts and constructs the target processors from the config file.
+// extracts and constructs the target processors from the config file. It can
+// also construct the full stream of the config with inputs and outputs replaced
+// by mocks.
 type ProcessorsProvider struct {
 	targetPath    string
+	cachedBytes   map[string][]byte
 	cachedConfigs map[string]cachedConfig
 
 	logger log.Modular
@@ -33,6 +38,7 @@ type ProcessorsProvider struct {
 func NewProcessorsProvider(targetPath string, opts ...func(*ProcessorsProvider)) *ProcessorsProvider {
 	p := &ProcessorsProvider{
 		targetPath:    targetPath,
+		cachedBytes:   map[string][]byte{},
 		cachedConfigs: map[string]cachedConfig{},
 		logger:        log.Noop(),
 	}
@@ -64,13 +70,76 @@ func (p *ProcessorsProvider) Provide(jsonPtr string, environment map[string]stri
 	return p.initProcs(confs)
 }
 
+// ProvideMocked attempts to extract an array of processors from a Benthos
+// config, where components and resources of the config are replaced with mocks.
+func (p *ProcessorsProvider) ProvideMocked(jsonPtr string, environment map[string]string, mocks Mocks) ([]types.Processor, error) {
+	confs, err := p.getMockedConfs(jsonPtr, environment, mocks)
+	if err != nil {
+		return nil, err
+	}
+	return p.initProcs(confs)
+}
+
+// ProvideStream attempts to construct the full stream of a Benthos config, with
+// the inputs and outputs of the stream target replaced with mocks, as well as
+// any other mocked components and resources.
+func (p *ProcessorsProvider) ProvideStream(target StreamTarget, environment map[string]string, mocks Mocks) (*MockedStream, error) {
+	confs, err := p.getStreamConfs(target, environment, mocks)
+	if err != nil {
+		return nil, err
+	}
+	return newMockedStream(confs.strm, target, confs.mocks, p.logger)
+}
+
 //------------------------------------------------------------------------------
 
+// readConfig reads the target config file with environment variables set
+// during the parse.
+func (p *ProcessorsProvider) readConfig(environment map[string]string) ([]byte, error) {
+	// Set custom environment vars.
+	ogEnvVars := map[string]string{}
+	if environment != nil {
+		for k, v := range environment {
+			ogEnvVars[k] = os.Getenv(k)
+			os.Setenv(k, v)
+		}
+	}
+
+	// Reset env vars back to original values after config parse.
+	defer func() {
+		for k, v := range ogEnvVars {
+			os.Setenv(k, v)
+		}
+	}()
+
+	configBytes, err := config.ReadWithJSONPointers(p.targetPath, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+	return configBytes, nil
+}
+
+func (p *ProcessorsProvider) getConfigBytes(environment map[string]string) ([]byte, error) {
+	cacheKey := confTargetID("", environment)
+	if configBytes, exists := p.cachedBytes[cacheKey]; exists {
+		return configBytes, nil
+	}
+	configBytes, err := p.readConfig(environment)
+	if err != nil {
+		return nil, err
+	}
+	p.cachedBytes[cacheKey] = configBytes
+	return configBytes, nil
+}
+
 func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, error) {
 	mgr, err := manager.New(confs.mgr, types.NoopMgr(), p.logger, metrics.Noop())
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialise resources: %v", err)
 	}
+	if err = confs.mocks.preloadCaches(mgr); err != nil {
+		return nil, err
+	}
 
 	procs := make([]types.Processor, len(confs.procs))
 	for i, conf := range confs.procs {
@@ -93,26 +162,43 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		return confs, nil
 	}
 
-	// Set custom environment vars.
-	ogEnvVars := map[string]string{}
-	if environment != nil {
-		for k, v := range environment {
-			ogEnvVars[k] = os.Getenv(k)
-			os.Setenv(k, v)
-		}
+	configBytes, err := p.getConfigBytes(environment)
+	if err != nil {
+		return confs, err
+	}
+	if confs, err = p.extractConfs(configBytes, jsonPtr, Mocks{}); err != nil {
+		return confs, err
 	}
 
-	// Reset env vars back to original values after config parse.
-	defer func() {
-		for k, v := range ogEnvVars {
-			os.Setenv(k, v)
-		}
-	}()
+	p.cachedConfigs[cacheKey] = confs
+	return confs, nil
+}
 
-	configBytes, err := config.ReadWithJSONPointers(p.targetPath, true)
+// getMockedConfs extracts processor configs with mocks applied, since mocks
+// are unique to each test case the result is not cached.
+func (p *ProcessorsProvider) getMockedConfs(jsonPtr string, environment map[string]string, mocks Mocks) (cachedConfig, error) {
+	configBytes, err := p.getConfigBytes(environment)
 	if err != nil {
+		return cachedConfig{}, err
+	}
+	return p.extractConfs(configBytes, jsonPtr, mocks)
+}
+
+func (p *ProcessorsProvider) extractConfs(configBytes []byte, jsonPtr string, mocks Mocks) (confs cachedConfig, err error) {
+	confs.mocks = mocks
+
+	var root interface{}
+	if err = yaml.Unmarshal(configBytes, &root); err != nil {
 		return confs, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
 	}
+	if !mocks.isEmpty() {
+		if err = mocks.apply(root); err != nil {
+			return confs, err
+		}
+		if configBytes, err = yaml.Marshal(root); err != nil {
+			return confs, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+		}
+	}
 
 	mgrWrapper := struct {
 		Manager manager.Config `yaml:"resources"`
@@ -124,11 +210,6 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 	}
 	confs.mgr = mgrWrapper.Manager
 
-	var root interface{}
-	if err = yaml.Unmarshal(configBytes, &root); err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
-	}
-
 	var procs interface{}
 	if procs, err = config.JSONPointer(jsonPtr, root); err != nil {
 		return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", p.targetPath, err)
@@ -151,8 +232,30 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		}
 		confs.procs = append(confs.procs, procConf)
 	}
+	return confs, nil
+}
 
-	p.cachedConfigs[cacheKey] = confs
+func (p *ProcessorsProvider) getStreamConfs(target StreamTarget, environment map[string]string, mocks Mocks) (cachedConfig, error) {
+	cacheKey := confTargetID(fmt.Sprintf("stream-%v-%v", target.inputs(), target.outputs()), environment)
+
+	// Mocks are unique to each test case and are therefore not cached.
+	confs, exists := p.cachedConfigs[cacheKey]
+	if exists && mocks.isEmpty() {
+		return confs, nil
+	}
+
+	configBytes, err := p.getConfigBytes(environment)
+	if err != nil {
+		return confs, err
+	}
+	confs.mocks = mocks
+	if confs.strm, err = mockedConfig(configBytes, target, mocks); err != nil {
+		return confs, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+
+	if mocks.isEmpty() {
+		p.cachedConfigs[cacheKey] = confs
+	}
 	return confs, nil
 }
 
@@ -0,0 +1,371 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// StreamTarget describes a test case that executes the full stream of a config,
+// including its buffer, pipeline, outputs and resources. Inputs and outputs
+// identified by JSON Pointers are replaced with mocks that are referenced by
+// name.
+type StreamTarget struct {
+	MockInputs  map[string]string `yaml:"mock_inputs"`
+	MockOutputs map[string]string `yaml:"mock_outputs"`
+	Input       string            `yaml:"input"`
+	Timeout     string            `yaml:"timeout"`
+}
+
+func (s StreamTarget) inputs() map[string]string {
+	if len(s.MockInputs) == 0 {
+		return map[string]string{"input": "/input"}
+	}
+	return s.MockInputs
+}
+
+func (s StreamTarget) outputs() map[string]string {
+	if len(s.MockOutputs) == 0 {
+		return map[string]string{"output": "/output"}
+	}
+	return s.MockOutputs
+}
+
+// outputNames returns the names of all mocked outputs in alphabetical order.
+func (s StreamTarget) outputNames() []string {
+	names := []string{}
+	for k := range s.outputs() {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// targetInput returns the name of the mock input that receives the input batch
+// of a test case.
+func (s StreamTarget) targetInput() (string, error) {
+	inputs := s.inputs()
+	if len(s.Input) > 0 {
+		if _, exists := inputs[s.Input]; !exists {
+			return "", fmt.Errorf("input '%v' is not a mocked input", s.Input)
+		}
+		return s.Input, nil
+	}
+	if len(inputs) > 1 {
+		return "", errors.New("an input must be specified when more than one input is mocked")
+	}
+	for k := range inputs {
+		return k, nil
+	}
+	return "", errors.New("no inputs are mocked")
+}
+
+func (s StreamTarget) timeout() (time.Duration, error) {
+	if len(s.Timeout) == 0 {
+		return time.Second * 5, nil
+	}
+	tout, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+	return tout, nil
+}
+
+func mockPipeName(kind, name string) string {
+	return fmt.Sprintf("benthos_test_mock_%v_%v", kind, name)
+}
+
+// applyMocks replaces the configs of components targeted by mocks with inproc
+// configs, processors of the original components are preserved.
+func applyMocks(root interface{}, kind string, mocks map[string]string) error {
+	names := []string{}
+	for k := range mocks {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		node, err := config.JSONPointer(mocks[name], root)
+		if err != nil {
+			return fmt.Errorf("failed to resolve mock %v '%v': %v", kind, name, err)
+		}
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("failed to resolve mock %v '%v': path '%v' does not target an %v config", kind, name, mocks[name], kind)
+		}
+		for k := range obj {
+			if k != "processors" {
+				delete(obj, k)
+			}
+		}
+		obj["type"] = "inproc"
+		obj["inproc"] = mockPipeName(kind, name)
+	}
+	return nil
+}
+
+// mockedConfig parses a config with the inputs and outputs of a stream target
+// replaced with mocks, along with any other mocks of the test case.
+func mockedConfig(configBytes []byte, target StreamTarget, mocks Mocks) (config.Type, error) {
+	conf := config.New()
+
+	var root interface{}
+	if err := yaml.Unmarshal(configBytes, &root); err != nil {
+		return conf, err
+	}
+	if err := applyMocks(root, "input", target.inputs()); err != nil {
+		return conf, err
+	}
+	if err := applyMocks(root, "output", target.outputs()); err != nil {
+		return conf, err
+	}
+	if err := mocks.apply(root); err != nil {
+		return conf, err
+	}
+
+	mockedBytes, err := yaml.Marshal(root)
+	if err != nil {
+		return conf, err
+	}
+	err = yaml.Unmarshal(mockedBytes, &conf)
+	return conf, err
+}
+
+//------------------------------------------------------------------------------
+
+type mockOutput struct {
+	partsMut sync.Mutex
+	parts    []types.Part
+}
+
+func (m *mockOutput) loop(tChan <-chan types.Transaction, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for tran := range tChan {
+		m.partsMut.Lock()
+		tran.Payload.Iter(func(_ int, p types.Part) error {
+			m.parts = append(m.parts, p.Copy())
+			return nil
+		})
+		m.partsMut.Unlock()
+		tran.ResponseChan <- response.NewAck()
+	}
+}
+
+func (m *mockOutput) received() []types.Part {
+	m.partsMut.Lock()
+	defer m.partsMut.Unlock()
+	return append([]types.Part(nil), m.parts...)
+}
+
+// MockedStream is a running stream where the inputs and outputs of a stream
+// target have been replaced with mocks.
+type MockedStream struct {
+	strm    *stream.Type
+	mgr     *manager.Type
+	inputs  map[string]chan types.Transaction
+	outputs map[string]*mockOutput
+
+	outputsWG sync.WaitGroup
+}
+
+func newMockedStream(conf config.Type, target StreamTarget, mocks Mocks, logger log.Modular) (*MockedStream, error) {
+	tout, err := target.timeout()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MockedStream{
+		inputs:  map[string]chan types.Transaction{},
+		outputs: map[string]*mockOutput{},
+	}
+	if m.mgr, err = manager.New(conf.Manager, types.NoopMgr(), logger, metrics.Noop()); err != nil {
+		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+	}
+	if err = mocks.preloadCaches(m.mgr); err != nil {
+		m.mgr.CloseAsync()
+		return nil, err
+	}
+
+	for name := range target.inputs() {
+		tChan := make(chan types.Transaction)
+		m.mgr.SetPipe(mockPipeName("input", name), tChan)
+		m.inputs[name] = tChan
+	}
+
+	if m.strm, err = stream.New(
+		conf.Config,
+		stream.OptSetManager(m.mgr),
+		stream.OptSetLogger(logger),
+		stream.OptSetStats(metrics.Noop()),
+	); err != nil {
+		m.mgr.CloseAsync()
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	// Mock outputs register their pipes asynchronously.
+	deadline := time.Now().Add(tout)
+	for _, name := range target.outputNames() {
+		var tChan <-chan types.Transaction
+		for {
+			if tChan, err = m.mgr.GetPipe(mockPipeName("output", name)); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				m.Close(tout)
+				return nil, fmt.Errorf("timed out waiting for mock output '%v' to start", name)
+			}
+			<-time.After(time.Millisecond * 10)
+		}
+		out := &mockOutput{}
+		m.outputs[name] = out
+		m.outputsWG.Add(1)
+		go out.loop(tChan, &m.outputsWG)
+	}
+	return m, nil
+}
+
+// Send a message batch through a mock input and wait for it to be
+// acknowledged. Returns an error if the batch is rejected or the timeout is
+// reached.
+func (m *MockedStream) Send(input string, msg types.Message, timeout time.Duration) error {
+	tChan, exists := m.inputs[input]
+	if !exists {
+		return fmt.Errorf("mock input '%v' was not found", input)
+	}
+
+	resChan := make(chan types.Response, 1)
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(timeout):
+		return errors.New("timed out sending message batch")
+	}
+
+	select {
+	case res := <-resChan:
+		if err := res.Error(); err != nil {
+			return fmt.Errorf("message batch was rejected: %v", err)
+		}
+	case <-time.After(timeout):
+		return errors.New("timed out waiting for message batch to be acknowledged")
+	}
+	return nil
+}
+
+// Received returns the messages received by a mock output in the order that
+// they arrived.
+func (m *MockedStream) Received(output string) []types.Part {
+	if out, exists := m.outputs[output]; exists {
+		return out.received()
+	}
+	return nil
+}
+
+// Close the stream and its resources, and wait for any messages still in
+// flight to reach the mock outputs.
+func (m *MockedStream) Close(timeout time.Duration) error {
+	started := time.Now()
+	var err error
+	if m.strm != nil {
+		err = m.strm.Stop(timeout)
+	}
+
+	m.mgr.CloseAsync()
+	if mErr := m.mgr.WaitForClose(timeout - time.Since(started)); err == nil {
+		err = mErr
+	}
+
+	outputsDone := make(chan struct{})
+	go func() {
+		m.outputsWG.Wait()
+		close(outputsDone)
+	}()
+	select {
+	case <-outputsDone:
+	case <-time.After(timeout - time.Since(started)):
+		if err == nil {
+			err = types.ErrTimeout
+		}
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// OutputExpectation describes the messages expected to reach a mock output.
+type OutputExpectation struct {
+	Count    *int            `yaml:"count"`
+	Ordered  bool            `yaml:"ordered"`
+	Messages []ConditionsMap `yaml:"messages"`
+}
+
+// NewOutputExpectation returns a default output expectation.
+func NewOutputExpectation() OutputExpectation {
+	return OutputExpectation{
+		Ordered: true,
+	}
+}
+
+// UnmarshalYAML extracts an OutputExpectation from a YAML node.
+func (o *OutputExpectation) UnmarshalYAML(value *yaml.Node) error {
+	type expAlias OutputExpectation
+	aliased := expAlias(NewOutputExpectation())
+
+	if err := value.Decode(&aliased); err != nil {
+		return err
+	}
+
+	*o = OutputExpectation(aliased)
+	return nil
+}
+
+// Check the messages received by an output against the expectation, and
+// returns a list of failure reasons.
+func (o OutputExpectation) Check(parts []types.Part) (reasons []string) {
+	expCount := len(o.Messages)
+	if o.Count != nil {
+		expCount = *o.Count
+	}
+	if expCount != len(parts) {
+		reasons = append(reasons, fmt.Sprintf("wrong message count, expected %v, got %v", expCount, len(parts)))
+	}
+
+	if o.Ordered {
+		for i, conds := range o.Messages {
+			if i >= len(parts) {
+				break
+			}
+			for _, condErr := range conds.CheckAll(parts[i]) {
+				reasons = append(reasons, fmt.Sprintf("message %v: %v", i, condErr))
+			}
+		}
+		return
+	}
+
+	matched := make([]bool, len(parts))
+expectations:
+	for i, conds := range o.Messages {
+		for j, part := range parts {
+			if !matched[j] && len(conds.CheckAll(part)) == 0 {
+				matched[j] = true
+				continue expectations
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("no message matched the conditions of expected message %v", i))
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,298 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/fatih/color"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const streamTestConfig = `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: foo
+
+pipeline:
+  processors:
+  - bloblang: |
+      root = this
+      root.processed = true
+
+output:
+  switch:
+    outputs:
+    - condition:
+        bloblang: this.type == "error"
+      output:
+        resource: dlq
+    - condition:
+        bloblang: this.type == "fan"
+      output:
+        broker:
+          pattern: fan_out
+          outputs:
+          - http_client:
+              url: http://localhost:1/a
+          - http_client:
+              url: http://localhost:1/b
+    - condition:
+        static: true
+      output:
+        s3:
+          bucket: foo
+
+resources:
+  outputs:
+    dlq:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: dlq
+`
+
+const streamTestDefinition = `
+parallel: %v
+tests:
+  - name: routes errors to the dlq
+    target_stream:
+      mock_outputs:
+        dlq: /resources/outputs/dlq
+        fan_a: /output/switch/outputs/1/output/broker/outputs/0
+        fan_b: /output/switch/outputs/1/output/broker/outputs/1
+        archive: /output/switch/outputs/2/output
+    input_batch:
+      - content: '{"type":"error","id":1}'
+      - content: '{"type":"ok","id":2}'
+    outputs:
+      dlq:
+        messages:
+          - json_contains: { id: 1, processed: true }
+          - bloblang: this.id == 2
+
+  - name: fans out to both outputs
+    target_stream:
+      mock_outputs:
+        dlq: /resources/outputs/dlq
+        fan_a: /output/switch/outputs/1/output/broker/outputs/0
+        fan_b: /output/switch/outputs/1/output/broker/outputs/1
+        archive: /output/switch/outputs/2/output
+    input_batch:
+      - content: '{"type":"fan","id":1}'
+      - content: '{"type":"ok","id":2}'
+    outputs:
+      fan_a:
+        count: 2
+      fan_b:
+        ordered: false
+        messages:
+          - json_contains: { id: 2 }
+          - json_contains: { id: 1 }
+
+  - name: wrong routing
+    target_stream:
+      mock_outputs:
+        dlq: /resources/outputs/dlq
+        fan_a: /output/switch/outputs/1/output/broker/outputs/0
+        fan_b: /output/switch/outputs/1/output/broker/outputs/1
+        archive: /output/switch/outputs/2/output
+      timeout: 10s
+    input_batch:
+      - content: '{"type":"ok","id":1}'
+    outputs:
+      dlq:
+        count: 1
+      archive:
+        messages:
+          - json_contains: { id: 2 }
+
+  - name: whole output mocked
+    target_stream:
+      mock_outputs:
+        output: /output
+        dlq: /resources/outputs/dlq
+    input_batch:
+      - content: '{"type":"ok","id":1}'
+    outputs:
+      output:
+        messages:
+          - content_equals: '{"id":1,"processed":true,"type":"ok"}'
+`
+
+func TestStreamTargetDefinition(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": streamTestConfig,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	for _, parallel := range []string{"false", "true"} {
+		var def Definition
+		if err = yaml.Unmarshal([]byte(fmt.Sprintf(streamTestDefinition, parallel)), &def); err != nil {
+			t.Fatal(err)
+		}
+
+		fails, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exp := []CaseFailure{
+			{
+				Name:     "wrong routing",
+				TestLine: 39,
+				Reason:   "output 'archive': message 0: json_contains: content mismatch\n  expected to contain: {\"id\":2}\n             received: {\"id\":1,\"processed\":true,\"type\":\"ok\"}",
+			},
+			{
+				Name:     "wrong routing",
+				TestLine: 39,
+				Reason:   "output 'dlq': wrong message count, expected 1, got 0",
+			},
+		}
+		if !reflect.DeepEqual(exp, fails) {
+			t.Errorf("Wrong failures (parallel: %v): %v != %v", parallel, fails, exp)
+		}
+	}
+}
+
+func TestStreamTargetErrors(t *testing.T) {
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": streamTestConfig,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	provider := NewProcessorsProvider(filepath.Join(testDir, "config1.yaml"))
+
+	tests := map[string]Case{
+		"bad mock path": {
+			TargetStream: &StreamTarget{
+				MockOutputs: map[string]string{"foo": "/output/nope"},
+			},
+		},
+		"unknown input": {
+			TargetStream: &StreamTarget{
+				Input: "nope",
+			},
+		},
+		"unknown output expectation": {
+			TargetStream: &StreamTarget{},
+			Outputs: map[string]OutputExpectation{
+				"nope": NewOutputExpectation(),
+			},
+		},
+		"bad timeout": {
+			TargetStream: &StreamTarget{
+				Timeout: "nope",
+			},
+		},
+	}
+
+	for name, c := range tests {
+		if _, err := c.Execute(provider); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+
+	if _, err := (&Case{TargetStream: &StreamTarget{}}).Execute(mockProvider{}); err == nil {
+		t.Error("Expected error from provider without stream support")
+	}
+
+	mockedCase := &Case{
+		TargetProcessors: "/pipeline/processors",
+		Mocks: Mocks{
+			Caches: map[string]map[string]string{"foo": {}},
+		},
+	}
+	if _, err := mockedCase.Execute(mockProvider{}); err == nil {
+		t.Error("Expected error from provider without mocks support")
+	}
+}
+
+func TestOutputExpectationCheck(t *testing.T) {
+	color.NoColor = true
+
+	parts := []types.Part{
+		message.NewPart([]byte("foo")),
+		message.NewPart([]byte("bar")),
+	}
+
+	count := 3
+	tests := []struct {
+		name     string
+		exp      OutputExpectation
+		expected []string
+	}{
+		{
+			name: "ordered match",
+			exp: OutputExpectation{
+				Ordered: true,
+				Messages: []ConditionsMap{
+					{"content_equals": ContentEqualsCondition("foo")},
+					{"content_equals": ContentEqualsCondition("bar")},
+				},
+			},
+		},
+		{
+			name: "ordered mismatch",
+			exp: OutputExpectation{
+				Ordered: true,
+				Messages: []ConditionsMap{
+					{"content_equals": ContentEqualsCondition("bar")},
+					{"content_equals": ContentEqualsCondition("foo")},
+				},
+			},
+			expected: []string{
+				"message 0: content_equals: content mismatch\n  expected: bar\n  received: foo",
+				"message 1: content_equals: content mismatch\n  expected: foo\n  received: bar",
+			},
+		},
+		{
+			name: "unordered match",
+			exp: OutputExpectation{
+				Messages: []ConditionsMap{
+					{"content_equals": ContentEqualsCondition("bar")},
+					{"content_equals": ContentEqualsCondition("foo")},
+				},
+			},
+		},
+		{
+			name: "unordered mismatch",
+			exp: OutputExpectation{
+				Messages: []ConditionsMap{
+					{"content_equals": ContentEqualsCondition("foo")},
+					{"content_equals": ContentEqualsCondition("foo")},
+				},
+			},
+			expected: []string{
+				"no message matched the conditions of expected message 1",
+			},
+		},
+		{
+			name: "count mismatch",
+			exp: OutputExpectation{
+				Count: &count,
+			},
+			expected: []string{
+				"wrong message count, expected 3, got 2",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if act := test.exp.Check(parts); !reflect.DeepEqual(test.expected, act) {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.expected)
+		}
+	}
+}
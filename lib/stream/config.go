@@ -16,6 +16,10 @@ type Config struct {
 	Buffer   buffer.Config   `json:"buffer" yaml:"buffer"`
 	Pipeline pipeline.Config `json:"pipeline" yaml:"pipeline"`
 	Output   output.Config   `json:"output" yaml:"output"`
+
+	// DeadLetter is optional, when set messages that fail processing or fail
+	// to be delivered to the output are routed to a dead letter output.
+	DeadLetter *DeadLetterConfig `json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
 }
 
 // NewConfig returns a new configuration with default values.
@@ -55,16 +59,25 @@ func (c Config) Sanitised() (interface{}, error) {
 		return nil, err
 	}
 
+	var deadLetterConf interface{}
+	if c.DeadLetter != nil {
+		if deadLetterConf, err = SanitiseDeadLetterConfig(*c.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
 	return struct {
-		Input    interface{} `json:"input" yaml:"input"`
-		Buffer   interface{} `json:"buffer" yaml:"buffer"`
-		Pipeline interface{} `json:"pipeline" yaml:"pipeline"`
-		Output   interface{} `json:"output" yaml:"output"`
+		Input      interface{} `json:"input" yaml:"input"`
+		Buffer     interface{} `json:"buffer" yaml:"buffer"`
+		Pipeline   interface{} `json:"pipeline" yaml:"pipeline"`
+		Output     interface{} `json:"output" yaml:"output"`
+		DeadLetter interface{} `json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
 	}{
-		Input:    inConf,
-		Buffer:   bufConf,
-		Pipeline: pipeConf,
-		Output:   outConf,
+		Input:      inConf,
+		Buffer:     bufConf,
+		Pipeline:   pipeConf,
+		Output:     outConf,
+		DeadLetter: deadLetterConf,
 	}, nil
 }
 
@@ -0,0 +1,484 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// Metadata keys added to messages that are routed to a dead letter output.
+const (
+	DeadLetterErrorKey      = "dead_letter_error"
+	DeadLetterComponentKey  = "dead_letter_component"
+	DeadLetterAttemptsKey   = "dead_letter_attempts"
+	DeadLetterReceivedAtKey = "dead_letter_received_at"
+	DeadLetterFailedAtKey   = "dead_letter_failed_at"
+)
+
+// deadLetterPathKey is a metadata key used for tracking the path of the
+// pipeline processor that first flagged a message part as failed. It is removed
+// before messages leave the stream.
+const deadLetterPathKey = "benthos_dead_letter_path"
+
+// deadLetterReceivedKey is a metadata key used for tracking the time at which a
+// message part was received by the input layer. It is removed before messages
+// leave the stream.
+const deadLetterReceivedKey = "benthos_dead_letter_received_at"
+
+//------------------------------------------------------------------------------
+
+// DeadLetterConfig contains configuration fields for routing messages that
+// fail processing or fail to be delivered to a dead letter output.
+type DeadLetterConfig struct {
+	MaxAttempts int           `json:"max_attempts" yaml:"max_attempts"`
+	Output      output.Config `json:"output" yaml:"output"`
+}
+
+// NewDeadLetterConfig returns a DeadLetterConfig with default values.
+func NewDeadLetterConfig() DeadLetterConfig {
+	return DeadLetterConfig{
+		MaxAttempts: 1,
+		Output:      output.NewConfig(),
+	}
+}
+
+// SanitiseDeadLetterConfig returns a sanitised version of the Config, meaning
+// sections that aren't relevant to behaviour are removed.
+func SanitiseDeadLetterConfig(conf DeadLetterConfig) (interface{}, error) {
+	outConf, err := output.SanitiseConfig(conf.Output)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"max_attempts": conf.MaxAttempts,
+		"output":       outConf,
+	}, nil
+}
+
+// UnmarshalYAML ensures that when parsing configs the default values are still
+// applied.
+func (c *DeadLetterConfig) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias DeadLetterConfig
+	aliased := confAlias(NewDeadLetterConfig())
+
+	if err := value.Decode(&aliased); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+
+	*c = DeadLetterConfig(aliased)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// failPathProcessor wraps a pipeline processor and records its path within the
+// metadata of message parts that it flags as failed, which is later added to
+// messages routed to a dead letter output.
+type failPathProcessor struct {
+	types.Processor
+	path string
+}
+
+// newFailPathProcessor wraps a pipeline processor in a failPathProcessor. If
+// the processor holds messages between calls then the wrapper also implements
+// processor.Flusher so that the pipeline continues to flush it.
+func newFailPathProcessor(proc types.Processor, path string) types.Processor {
+	f := &failPathProcessor{Processor: proc, path: path}
+	if flusher, ok := proc.(processor.Flusher); ok {
+		return &failPathFlusher{failPathProcessor: f, flusher: flusher}
+	}
+	return f
+}
+
+func (f *failPathProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	msgs, res := f.Processor.ProcessMessage(msg)
+	f.markFailed(msgs)
+	return msgs, res
+}
+
+func (f *failPathProcessor) markFailed(msgs []types.Message) {
+	for _, m := range msgs {
+		m.Iter(func(i int, p types.Part) error {
+			meta := p.Metadata()
+			if processor.HasFailed(p) {
+				if len(meta.Get(deadLetterPathKey)) == 0 {
+					meta.Set(deadLetterPathKey, f.path)
+				}
+			} else if len(meta.Get(deadLetterPathKey)) > 0 {
+				// The failure has been recovered from (by a catch block for
+				// example) and therefore the path is stale.
+				meta.Delete(deadLetterPathKey)
+			}
+			return nil
+		})
+	}
+}
+
+// failPathFlusher is a failPathProcessor for processors that implement
+// processor.Flusher.
+type failPathFlusher struct {
+	*failPathProcessor
+	flusher processor.Flusher
+}
+
+func (f *failPathFlusher) FlushPeriod() time.Duration {
+	return f.flusher.FlushPeriod()
+}
+
+func (f *failPathFlusher) Flush(final bool) []types.Message {
+	msgs := f.flusher.Flush(final)
+	f.markFailed(msgs)
+	return msgs
+}
+
+//------------------------------------------------------------------------------
+
+// receivedAtProcessor records the time at which message parts were received by
+// the input layer within their metadata, which is later added to messages
+// routed to a dead letter output. Since the time is stored within metadata it
+// is retained by buffers that persist messages.
+type receivedAtProcessor struct{}
+
+func (receivedAtProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	now := time.Now().Format(time.RFC3339Nano)
+	newMsg := msg.Copy()
+	newMsg.Iter(func(i int, p types.Part) error {
+		if len(p.Metadata().Get(deadLetterReceivedKey)) == 0 {
+			p.Metadata().Set(deadLetterReceivedKey, now)
+		}
+		return nil
+	})
+	return []types.Message{newMsg}, nil
+}
+
+func (receivedAtProcessor) CloseAsync() {}
+
+func (receivedAtProcessor) WaitForClose(time.Duration) error {
+	return nil
+}
+
+// partReceivedAt returns the time at which a message part was received by the
+// input layer, or the current time if it was not recorded.
+func partReceivedAt(p types.Part) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, p.Metadata().Get(deadLetterReceivedKey)); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+//------------------------------------------------------------------------------
+
+// deadLetterRouter is an output that wraps the output layer of a stream,
+// routing message parts that have failed processing, or that could not be
+// delivered to the output within a maximum number of attempts, to a dead
+// letter output instead.
+type deadLetterRouter struct {
+	maxAttempts int
+
+	log   log.Modular
+	stats metrics.Type
+
+	transactions <-chan types.Transaction
+
+	out       output.Type
+	outTsChan chan types.Transaction
+	dlq       output.Type
+	dlqTsChan chan types.Transaction
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+func newDeadLetterRouter(
+	maxAttempts int,
+	out, dlq output.Type,
+	log log.Modular,
+	stats metrics.Type,
+) (*deadLetterRouter, error) {
+	if maxAttempts < 1 {
+		return nil, errors.New("dead letter max_attempts must be greater than zero")
+	}
+	ctx, done := context.WithCancel(context.Background())
+	r := &deadLetterRouter{
+		maxAttempts: maxAttempts,
+		log:         log,
+		stats:       stats,
+		out:         out,
+		outTsChan:   make(chan types.Transaction),
+		dlq:         dlq,
+		dlqTsChan:   make(chan types.Transaction),
+		ctx:         ctx,
+		close:       done,
+		closedChan:  make(chan struct{}),
+	}
+	if err := out.Consume(r.outTsChan); err != nil {
+		return nil, err
+	}
+	if err := dlq.Consume(r.dlqTsChan); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the router to read.
+func (r *deadLetterRouter) Consume(ts <-chan types.Transaction) error {
+	if r.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	r.transactions = ts
+	go r.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether both the output and the dead
+// letter output are currently connected to their targets.
+func (r *deadLetterRouter) Connected() bool {
+	return r.out.Connected() && r.dlq.Connected()
+}
+
+//------------------------------------------------------------------------------
+
+func annotateDeadLetter(p types.Part, errStr, component string, attempts int, receivedAt time.Time) {
+	p.Metadata().
+		Delete(deadLetterPathKey).
+		Delete(deadLetterReceivedKey).
+		Set(DeadLetterErrorKey, errStr).
+		Set(DeadLetterComponentKey, component).
+		Set(DeadLetterAttemptsKey, strconv.Itoa(attempts)).
+		Set(DeadLetterReceivedAtKey, receivedAt.Format(time.RFC3339Nano)).
+		Set(DeadLetterFailedAtKey, time.Now().Format(time.RFC3339Nano))
+}
+
+// split separates the parts of a message that have failed processing from
+// those that have not. Failed parts are copied and annotated, and the times at
+// which the remaining parts were received are returned alongside them.
+func (r *deadLetterRouter) split(msg types.Message) (okMsg types.Message, okReceived []time.Time, deadMsg types.Message) {
+	okMsg, deadMsg = message.New(nil), message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		receivedAt := partReceivedAt(p)
+		if !processor.HasFailed(p) {
+			if len(p.Metadata().Get(deadLetterReceivedKey)) > 0 {
+				p = message.WithContext(message.GetContext(p), p.Copy())
+				p.Metadata().Delete(deadLetterReceivedKey)
+			}
+			okMsg.Append(p)
+			okReceived = append(okReceived, receivedAt)
+			return nil
+		}
+		component := p.Metadata().Get(deadLetterPathKey)
+		if len(component) == 0 {
+			component = "input"
+		}
+		deadPart := p.Copy()
+		annotateDeadLetter(deadPart, p.Metadata().Get(types.FailFlagKey), component, 0, receivedAt)
+		deadMsg.Append(deadPart)
+		return nil
+	})
+	return okMsg, okReceived, deadMsg
+}
+
+// send attempts to deliver a message to an output and blocks until a response
+// is received. Returns false if the router was closed before a response was
+// received.
+func (r *deadLetterRouter) send(tsChan chan<- types.Transaction, msg types.Message) (bool, error) {
+	rChan := make(chan types.Response)
+	select {
+	case tsChan <- types.NewTransaction(msg, rChan):
+	case <-r.ctx.Done():
+		return false, nil
+	}
+	select {
+	case res, open := <-rChan:
+		if !open {
+			return false, nil
+		}
+		return true, res.Error()
+	case <-r.ctx.Done():
+	}
+	return false, nil
+}
+
+func (r *deadLetterRouter) loop() {
+	var (
+		wg = sync.WaitGroup{}
+
+		mProcFailed = r.stats.GetCounter("processing_failed")
+		mOutFailed  = r.stats.GetCounter("output_failed")
+		mSent       = r.stats.GetCounter("sent")
+		mErr        = r.stats.GetCounter("error")
+	)
+
+	defer func() {
+		wg.Wait()
+		close(r.outTsChan)
+		close(r.dlqTsChan)
+		close(r.closedChan)
+	}()
+
+	resolve := func(tran types.Transaction, okMsg types.Message, okReceived []time.Time, deadMsg types.Message, rChan <-chan types.Response) {
+		defer wg.Done()
+
+		for attempts := 1; rChan != nil; attempts++ {
+			var res types.Response
+			var open bool
+			select {
+			case res, open = <-rChan:
+				if !open {
+					return
+				}
+			case <-r.ctx.Done():
+				return
+			}
+			err := res.Error()
+			if err == nil {
+				break
+			}
+
+			// When the output reports which messages of the batch failed only
+			// those messages are retried or routed. Outputs may hand back a
+			// copy of the batch (with tracing metadata injected for example)
+			// and so messages are matched by their index. Messages that were
+			// rejected would fail again and are routed without a retry.
+			var partErrs []error
+			if bErr, ok := err.(*batch.Error); ok && bErr.Message().Len() == okMsg.Len() {
+				failedMsg := message.New(nil)
+				var failedReceived []time.Time
+				bErr.WalkParts(func(i int, _ types.Part, pErr error) bool {
+					if pErr == nil {
+						return true
+					}
+					if batch.IsRejected(pErr) {
+						mOutFailed.Incr(1)
+						deadPart := okMsg.Get(i).Copy()
+						annotateDeadLetter(deadPart, pErr.Error(), "output", attempts, okReceived[i])
+						deadMsg.Append(deadPart)
+						return true
+					}
+					failedMsg.Append(okMsg.Get(i))
+					failedReceived = append(failedReceived, okReceived[i])
+					partErrs = append(partErrs, pErr)
+					return true
+				})
+				okReceived = failedReceived
+				if okMsg = failedMsg; okMsg.Len() == 0 {
+					break
+				}
+			}
+
+			if attempts >= r.maxAttempts {
+				mOutFailed.Incr(int64(okMsg.Len()))
+				r.log.Debugf("Routing %v messages to dead letter output after %v failed attempts: %v\n", okMsg.Len(), attempts, err)
+				okMsg.Iter(func(i int, p types.Part) error {
+					errStr := err.Error()
+					if i < len(partErrs) {
+						errStr = partErrs[i].Error()
+					}
+					deadPart := p.Copy()
+					annotateDeadLetter(deadPart, errStr, "output", attempts, okReceived[i])
+					deadMsg.Append(deadPart)
+					return nil
+				})
+				break
+			}
+
+			nextChan := make(chan types.Response)
+			select {
+			case r.outTsChan <- types.NewTransaction(okMsg, nextChan):
+			case <-r.ctx.Done():
+				return
+			}
+			rChan = nextChan
+		}
+
+		var err error
+		if deadMsg.Len() > 0 {
+			var open bool
+			if open, err = r.send(r.dlqTsChan, deadMsg); !open {
+				return
+			}
+			if err != nil {
+				mErr.Incr(1)
+				r.log.Errorf("Failed to send messages to dead letter output: %v\n", err)
+			} else {
+				mSent.Incr(int64(deadMsg.Len()))
+			}
+		}
+
+		select {
+		case tran.ResponseChan <- response.NewError(err):
+		case <-r.ctx.Done():
+		}
+	}
+
+	for {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-r.transactions:
+			if !open {
+				return
+			}
+		case <-r.ctx.Done():
+			return
+		}
+
+		okMsg, okReceived, deadMsg := r.split(tran.Payload)
+		mProcFailed.Incr(int64(deadMsg.Len()))
+
+		// The first attempt is made before moving on to the next transaction
+		// in order to preserve ordering.
+		var rChan chan types.Response
+		if okMsg.Len() > 0 {
+			rChan = make(chan types.Response)
+			select {
+			case r.outTsChan <- types.NewTransaction(okMsg, rChan):
+			case <-r.ctx.Done():
+				return
+			}
+		}
+
+		wg.Add(1)
+		go resolve(tran, okMsg, okReceived, deadMsg, rChan)
+	}
+}
+
+// CloseAsync shuts down the router and both outputs.
+func (r *deadLetterRouter) CloseAsync() {
+	r.close()
+	r.out.CloseAsync()
+	r.dlq.CloseAsync()
+}
+
+// WaitForClose blocks until the router and both outputs have closed down.
+func (r *deadLetterRouter) WaitForClose(timeout time.Duration) error {
+	started := time.Now()
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	if err := r.out.WaitForClose(timeout - time.Since(started)); err != nil {
+		return err
+	}
+	return r.dlq.WaitForClose(timeout - time.Since(started))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,502 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/tracer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+type mockDLQOutput struct {
+	ts <-chan types.Transaction
+}
+
+func (m *mockDLQOutput) Consume(ts <-chan types.Transaction) error {
+	m.ts = ts
+	return nil
+}
+
+func (m *mockDLQOutput) Connected() bool {
+	return true
+}
+
+func (m *mockDLQOutput) CloseAsync() {}
+
+func (m *mockDLQOutput) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func (m *mockDLQOutput) next(t *testing.T) types.Transaction {
+	t.Helper()
+	select {
+	case tran := <-m.ts:
+		return tran
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return types.Transaction{}
+}
+
+func newDLQTestRouter(t *testing.T, maxAttempts int) (*deadLetterRouter, *mockDLQOutput, *mockDLQOutput, chan types.Transaction) {
+	t.Helper()
+
+	out, dlq := &mockDLQOutput{}, &mockDLQOutput{}
+	r, err := newDeadLetterRouter(maxAttempts, out, dlq, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, r.Consume(tChan))
+	return r, out, dlq, tChan
+}
+
+func sendDLQTestTran(t *testing.T, tChan chan types.Transaction, msg types.Message) <-chan types.Response {
+	t.Helper()
+	rChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msg, rChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return rChan
+}
+
+func awaitDLQTestRes(t *testing.T, rChan <-chan types.Response) types.Response {
+	t.Helper()
+	select {
+	case res := <-rChan:
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestDeadLetterConfigDefaults(t *testing.T) {
+	var conf Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+dead_letter:
+  output:
+    drop: {}
+`), &conf))
+
+	require.NotNil(t, conf.DeadLetter)
+	assert.Equal(t, 1, conf.DeadLetter.MaxAttempts)
+	assert.Equal(t, "drop", conf.DeadLetter.Output.Type)
+
+	conf = NewConfig()
+	assert.Nil(t, conf.DeadLetter)
+}
+
+func TestDeadLetterProcessingFailed(t *testing.T) {
+	r, out, dlq, tChan := newDLQTestRouter(t, 1)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	processor.FlagErr(msg.Get(1), errors.New("bar failed"))
+	msg.Get(1).Metadata().Set(deadLetterPathKey, "pipeline.processors.2")
+
+	rChan := sendDLQTestTran(t, tChan, msg)
+
+	outTran := out.next(t)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(outTran.Payload))
+	outTran.ResponseChan <- response.NewAck()
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("bar")}, message.GetAllBytes(dlqTran.Payload))
+	meta := dlqTran.Payload.Get(0).Metadata()
+	assert.Equal(t, "bar failed", meta.Get(DeadLetterErrorKey))
+	assert.Equal(t, "pipeline.processors.2", meta.Get(DeadLetterComponentKey))
+	assert.Equal(t, "0", meta.Get(DeadLetterAttemptsKey))
+	assert.NotEmpty(t, meta.Get(DeadLetterReceivedAtKey))
+	assert.NotEmpty(t, meta.Get(DeadLetterFailedAtKey))
+	assert.Empty(t, meta.Get(deadLetterPathKey))
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+
+	// The original message remains unchanged.
+	assert.Empty(t, msg.Get(1).Metadata().Get(DeadLetterErrorKey))
+}
+
+func TestDeadLetterOutputFailed(t *testing.T) {
+	r, out, dlq, tChan := newDLQTestRouter(t, 2)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	msg.Get(0).Metadata().Set("kafka_timestamp_unix", "1590000000")
+	rChan := sendDLQTestTran(t, tChan, msg)
+
+	for i := 0; i < 2; i++ {
+		outTran := out.next(t)
+		assert.Equal(t, [][]byte{[]byte("foo")}, message.GetAllBytes(outTran.Payload))
+		outTran.ResponseChan <- response.NewError(errors.New("nope"))
+	}
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("foo")}, message.GetAllBytes(dlqTran.Payload))
+	meta := dlqTran.Payload.Get(0).Metadata()
+	assert.Equal(t, "nope", meta.Get(DeadLetterErrorKey))
+	assert.Equal(t, "output", meta.Get(DeadLetterComponentKey))
+	assert.Equal(t, "2", meta.Get(DeadLetterAttemptsKey))
+	assert.Equal(t, "1590000000", meta.Get("kafka_timestamp_unix"))
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+}
+
+func TestDeadLetterReceivedAt(t *testing.T) {
+	r, out, dlq, tChan := newDLQTestRouter(t, 1)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	msgs, res := receivedAtProcessor{}.ProcessMessage(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	receivedAt := msgs[0].Get(0).Metadata().Get(deadLetterReceivedKey)
+	require.NotEmpty(t, receivedAt)
+	processor.FlagErr(msgs[0].Get(1), errors.New("bar failed"))
+
+	<-time.After(time.Millisecond * 10)
+	rChan := sendDLQTestTran(t, tChan, msgs[0])
+
+	outTran := out.next(t)
+	assert.Equal(t, [][]byte{[]byte("foo")}, message.GetAllBytes(outTran.Payload))
+	assert.Empty(t, outTran.Payload.Get(0).Metadata().Get(deadLetterReceivedKey))
+	outTran.ResponseChan <- response.NewError(errors.New("nope"))
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("bar"), []byte("foo")}, message.GetAllBytes(dlqTran.Payload))
+	dlqTran.Payload.Iter(func(i int, p types.Part) error {
+		assert.Equal(t, receivedAt, p.Metadata().Get(DeadLetterReceivedAtKey))
+		assert.Empty(t, p.Metadata().Get(deadLetterReceivedKey))
+		return nil
+	})
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+
+	// Parts that already have a receive time keep it.
+	msgs, _ = receivedAtProcessor{}.ProcessMessage(msgs[0])
+	assert.Equal(t, receivedAt, msgs[0].Get(0).Metadata().Get(deadLetterReceivedKey))
+}
+
+func TestDeadLetterOutputRecovered(t *testing.T) {
+	r, out, _, tChan := newDLQTestRouter(t, 3)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	rChan := sendDLQTestTran(t, tChan, message.New([][]byte{[]byte("foo")}))
+
+	outTran := out.next(t)
+	outTran.ResponseChan <- response.NewError(errors.New("nope"))
+
+	outTran = out.next(t)
+	outTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+}
+
+func TestDeadLetterOutputBatchError(t *testing.T) {
+	r, out, dlq, tChan := newDLQTestRouter(t, 2)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	rChan := sendDLQTestTran(t, tChan, message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+
+	outTran := out.next(t)
+	outTran.ResponseChan <- response.NewError(
+		batch.NewError(outTran.Payload).
+			Failed(0, errors.New("foo failed")).
+			Failed(2, errors.New("baz failed")),
+	)
+
+	// Only the failed messages are retried.
+	outTran = out.next(t)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(outTran.Payload))
+	outTran.ResponseChan <- response.NewError(
+		batch.NewError(outTran.Payload).Failed(1, errors.New("baz failed again")),
+	)
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("baz")}, message.GetAllBytes(dlqTran.Payload))
+	meta := dlqTran.Payload.Get(0).Metadata()
+	assert.Equal(t, "baz failed again", meta.Get(DeadLetterErrorKey))
+	assert.Equal(t, "output", meta.Get(DeadLetterComponentKey))
+	assert.Equal(t, "2", meta.Get(DeadLetterAttemptsKey))
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+}
+
+func TestDeadLetterOutputRejected(t *testing.T) {
+	r, out, dlq, tChan := newDLQTestRouter(t, 3)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	rChan := sendDLQTestTran(t, tChan, message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+
+	outTran := out.next(t)
+	outTran.ResponseChan <- response.NewError(
+		batch.NewError(outTran.Payload).
+			Rejected(0, errors.New("foo rejected")).
+			Failed(2, errors.New("baz failed")),
+	)
+
+	// Only the retryable message is retried.
+	outTran = out.next(t)
+	assert.Equal(t, [][]byte{[]byte("baz")}, message.GetAllBytes(outTran.Payload))
+	outTran.ResponseChan <- response.NewAck()
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("foo")}, message.GetAllBytes(dlqTran.Payload))
+	meta := dlqTran.Payload.Get(0).Metadata()
+	assert.Equal(t, "foo rejected", meta.Get(DeadLetterErrorKey))
+	assert.Equal(t, "1", meta.Get(DeadLetterAttemptsKey))
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+}
+
+func TestDeadLetterOutputBothFailed(t *testing.T) {
+	r, out, dlq, tChan := newDLQTestRouter(t, 1)
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	rChan := sendDLQTestTran(t, tChan, message.New([][]byte{[]byte("foo")}))
+
+	outTran := out.next(t)
+	outTran.ResponseChan <- response.NewError(errors.New("nope"))
+
+	dlqTran := dlq.next(t)
+	dlqTran.ResponseChan <- response.NewError(errors.New("also nope"))
+
+	assert.EqualError(t, awaitDLQTestRes(t, rChan).Error(), "also nope")
+}
+
+func TestDeadLetterBatchedOutputBatchError(t *testing.T) {
+	policyConf := batch.NewPolicyConfig()
+	policyConf.Count = 2
+	policy, err := batch.NewPolicy(policyConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	child, dlq := &mockDLQOutput{}, &mockDLQOutput{}
+	r, err := newDeadLetterRouter(1, output.NewBatcher(policy, child, log.Noop(), metrics.Noop()), dlq, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, r.Consume(tChan))
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	rChanFoo := sendDLQTestTran(t, tChan, message.New([][]byte{[]byte("foo")}))
+	rChanBar := sendDLQTestTran(t, tChan, message.New([][]byte{[]byte("bar"), []byte("baz")}))
+
+	// The batcher merges both transactions into a single batch.
+	outTran := child.next(t)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}, message.GetAllBytes(outTran.Payload))
+	outTran.ResponseChan <- response.NewError(
+		batch.NewError(outTran.Payload).Failed(2, errors.New("baz failed")),
+	)
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("baz")}, message.GetAllBytes(dlqTran.Payload))
+	assert.Equal(t, "baz failed", dlqTran.Payload.Get(0).Metadata().Get(DeadLetterErrorKey))
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChanFoo).Error())
+	assert.NoError(t, awaitDLQTestRes(t, rChanBar).Error())
+}
+
+type mockDLQBatchErrSink struct {
+	failIndex int
+	received  chan types.Message
+}
+
+func (m *mockDLQBatchErrSink) ConnectWithContext(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockDLQBatchErrSink) WriteWithContext(ctx context.Context, msg types.Message) error {
+	m.received <- msg
+	return batch.NewError(msg).Failed(m.failIndex, errors.New("part failed"))
+}
+
+func (m *mockDLQBatchErrSink) CloseAsync() {}
+
+func (m *mockDLQBatchErrSink) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestDeadLetterTracedOutputBatchError(t *testing.T) {
+	prev := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(prev)
+
+	tConf := tracer.NewConfig()
+	tConf.Type = tracer.TypeOpenTelemetry
+	tConf.OpenTelemetry.Protocol = otlp.ProtocolHTTP
+	tConf.OpenTelemetry.Address = "localhost:1"
+	tConf.OpenTelemetry.Timeout = "10ms"
+	tConf.OpenTelemetry.FlushInterval = "1h"
+	tr, err := tracer.New(tConf)
+	require.NoError(t, err)
+	defer tr.Close()
+
+	sink := &mockDLQBatchErrSink{failIndex: 1, received: make(chan types.Message, 1)}
+	out, err := output.NewAsyncWriter("foo", 1, sink, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	dlq := &mockDLQOutput{}
+	r, err := newDeadLetterRouter(1, out, dlq, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, r.Consume(tChan))
+	defer func() {
+		close(tChan)
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	rChan := sendDLQTestTran(t, tChan, msg)
+
+	// The output receives a copy of the batch with tracing metadata.
+	select {
+	case received := <-sink.received:
+		assert.False(t, received == msg)
+		assert.NotEmpty(t, received.Get(0).Metadata().Get("traceparent"))
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	dlqTran := dlq.next(t)
+	assert.Equal(t, [][]byte{[]byte("bar")}, message.GetAllBytes(dlqTran.Payload))
+	assert.Equal(t, "part failed", dlqTran.Payload.Get(0).Metadata().Get(DeadLetterErrorKey))
+	dlqTran.ResponseChan <- response.NewAck()
+
+	assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+}
+
+func TestDeadLetterFailPathProcessor(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = processor.TypeBloblang
+	conf.Bloblang = `root = this.nope.uppercase()`
+
+	proc, err := processor.New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	fProc := &failPathProcessor{Processor: proc, path: "pipeline.processors.0"}
+
+	msgs, res := fProc.ProcessMessage(message.New([][]byte{[]byte(`{"nope":"foo"}`), []byte(`{}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get(deadLetterPathKey))
+	assert.Equal(t, "pipeline.processors.0", msgs[0].Get(1).Metadata().Get(deadLetterPathKey))
+
+	fProc.path = "pipeline.processors.1"
+	msgs, res = fProc.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "pipeline.processors.0", msgs[0].Get(1).Metadata().Get(deadLetterPathKey))
+}
+
+func TestDeadLetterPipelineWindowFlush(t *testing.T) {
+	for _, idleTimeout := range []string{"10ms", ""} {
+		idleTimeout := idleTimeout
+		t.Run("idle_timeout "+idleTimeout, func(t *testing.T) {
+			conf := NewConfig()
+			dlConf := NewDeadLetterConfig()
+			conf.DeadLetter = &dlConf
+
+			procConf := processor.NewConfig()
+			procConf.Type = processor.TypeWindow
+			procConf.Window.Timestamp = "this.ts"
+			procConf.Window.Size = "10s"
+			procConf.Window.IdleTimeout = idleTimeout
+			procConf.Window.ResultMap = `root = this.messages.map_each(this.ts)`
+			conf.Pipeline.Processors = append(conf.Pipeline.Processors, procConf)
+
+			strm := &Type{
+				manager: types.NoopMgr(),
+				stats:   metrics.Noop(),
+				logger:  log.Noop(),
+			}
+			pipe, err := strm.newPipelineLayer(conf)
+			require.NoError(t, err)
+
+			tChan := make(chan types.Transaction)
+			require.NoError(t, pipe.Consume(tChan))
+
+			rChan := sendDLQTestTran(t, tChan, message.New([][]byte{
+				[]byte(`{"ts":100}`),
+				[]byte(`{"ts":101}`),
+			}))
+			assert.NoError(t, awaitDLQTestRes(t, rChan).Error())
+
+			// Without an idle timeout the window is only flushed once the
+			// pipeline shuts down.
+			if idleTimeout == "" {
+				close(tChan)
+			}
+
+			var tran types.Transaction
+			select {
+			case tran = <-pipe.TransactionChan():
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+			assert.Equal(t, [][]byte{[]byte(`[100,101]`)}, message.GetAllBytes(tran.Payload))
+			tran.ResponseChan <- response.NewAck()
+
+			if idleTimeout != "" {
+				close(tChan)
+			}
+			require.NoError(t, pipe.WaitForClose(time.Second))
+		})
+	}
+}
+
+//------------------------------------------------------------------------------
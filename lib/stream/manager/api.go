@@ -138,7 +138,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 
 	for i, id := range toDelete {
 		go func(sid string, j int) {
-			errDelete[j] = m.Delete(sid, time.Until(deadline))
+			errDelete[j] = m.deleteAndStore(sid, time.Until(deadline))
 			wg.Done()
 		}(id, i)
 	}
@@ -146,7 +146,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	for id, conf := range toUpdate {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
-			errUpdate[j] = m.Update(sid, *sconf, time.Until(deadline))
+			errUpdate[j] = m.updateAndStore(sid, *sconf, time.Until(deadline))
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -155,7 +155,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	for id, conf := range toCreate {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
-			errCreate[j] = m.Create(sid, *sconf)
+			errCreate[j] = m.createAndStore(sid, *sconf)
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -273,7 +273,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
-		serverErr = m.Create(id, conf)
+		serverErr = m.createAndStore(id, conf)
 	case "GET":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
@@ -300,16 +300,16 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
-		serverErr = m.Update(id, conf, time.Until(deadline))
+		serverErr = m.updateAndStore(id, conf, time.Until(deadline))
 	case "DELETE":
-		serverErr = m.Delete(id, time.Until(deadline))
+		serverErr = m.deleteAndStore(id, time.Until(deadline))
 	case "PATCH":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
 			if conf, requestErr = patchConfig(info.Config()); requestErr != nil {
 				return
 			}
-			serverErr = m.Update(id, conf, time.Until(deadline))
+			serverErr = m.updateAndStore(id, conf, time.Until(deadline))
 		}
 	default:
 		requestErr = fmt.Errorf("verb not supported: %v", r.Method)
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// CacheConfig contains configuration fields for the cache store type.
+type CacheConfig struct {
+	Resource string `json:"resource" yaml:"resource"`
+	Prefix   string `json:"prefix" yaml:"prefix"`
+}
+
+// NewCacheConfig creates a CacheConfig populated with default values.
+func NewCacheConfig() CacheConfig {
+	return CacheConfig{
+		Resource: "",
+		Prefix:   "benthos_streams_",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// cacheIndexRetries is the maximum number of attempts made at updating the
+// index of a cache store when it is modified concurrently.
+const cacheIndexRetries = 10
+
+// Cache is a store that persists stream configs within a cache resource. Since
+// caches cannot list their keys the IDs of all stored streams are kept within
+// an index key, which is updated with compare-and-swaps when the cache
+// supports them.
+type Cache struct {
+	cache    types.Cache
+	prefix   string
+	indexKey string
+	log      log.Modular
+}
+
+// NewCache creates a new cache store type.
+func NewCache(conf CacheConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.Resource) == 0 {
+		return nil, errors.New("a cache resource must be specified")
+	}
+	c, err := mgr.GetCache(conf.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain cache resource '%v': %v", conf.Resource, err)
+	}
+	if _, ok := c.(types.CacheExtended); !ok {
+		log.Warnf("Cache resource '%v' does not support compare-and-swaps, concurrent changes to streams from multiple replicas may be lost.\n", conf.Resource)
+	}
+	return &Cache{
+		cache:    c,
+		prefix:   conf.Prefix,
+		indexKey: conf.Prefix + "index",
+		log:      log,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Cache) readIndex() ([]byte, []string, error) {
+	indexBytes, err := c.cache.Get(c.indexKey)
+	if err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	var ids []string
+	if err = json.Unmarshal(indexBytes, &ids); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse index: %v", err)
+	}
+	return indexBytes, ids, nil
+}
+
+// updateIndex applies a modification to the list of stream IDs within the
+// index, retrying when the index is concurrently modified.
+func (c *Cache) updateIndex(fn func(ids []string) []string) error {
+	ec, isExtended := c.cache.(types.CacheExtended)
+	for i := 0; i < cacheIndexRetries; i++ {
+		oldBytes, ids, err := c.readIndex()
+		if err != nil {
+			return err
+		}
+		newBytes, err := json.Marshal(fn(ids))
+		if err != nil {
+			return err
+		}
+		if !isExtended {
+			return c.cache.Set(c.indexKey, newBytes)
+		}
+		err = ec.CompareAndSwap(c.indexKey, oldBytes, newBytes, nil)
+		switch err {
+		case nil:
+			return nil
+		case types.ErrKeyNotFound, types.ErrKeyAlreadyExists, types.ErrKeyValueMismatch:
+			continue
+		}
+		return err
+	}
+	return errors.New("index was modified concurrently too many times")
+}
+
+// ReadAll returns the encoded configs of all streams within the index mapped
+// by their IDs.
+func (c *Cache) ReadAll() (map[string][]byte, error) {
+	_, ids, err := c.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	confs := make(map[string][]byte, len(ids))
+	if ec, ok := c.cache.(types.CacheExtended); ok && len(ids) > 0 {
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = c.prefix + id
+		}
+		values, err := ec.GetMulti(keys...)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if v, exists := values[c.prefix+id]; exists {
+				confs[id] = v
+			}
+		}
+		return confs, nil
+	}
+
+	for _, id := range ids {
+		v, err := c.cache.Get(c.prefix + id)
+		if err != nil {
+			if err == types.ErrKeyNotFound {
+				continue
+			}
+			return nil, err
+		}
+		confs[id] = v
+	}
+	return confs, nil
+}
+
+// Set writes the encoded config of a stream to a key and adds its ID to the
+// index.
+func (c *Cache) Set(id string, conf []byte) error {
+	if err := c.cache.Set(c.prefix+id, conf); err != nil {
+		return err
+	}
+	return c.updateIndex(func(ids []string) []string {
+		for _, existing := range ids {
+			if existing == id {
+				return ids
+			}
+		}
+		return append(ids, id)
+	})
+}
+
+// Delete removes the ID of a stream from the index and then removes the key of
+// its config.
+func (c *Cache) Delete(id string) error {
+	if err := c.updateIndex(func(ids []string) []string {
+		newIDs := make([]string, 0, len(ids))
+		for _, existing := range ids {
+			if existing != id {
+				newIDs = append(newIDs, existing)
+			}
+		}
+		return newIDs
+	}); err != nil {
+		return err
+	}
+	if err := c.cache.Delete(c.prefix + id); err != nil && err != types.ErrKeyNotFound {
+		return err
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
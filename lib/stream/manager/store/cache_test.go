@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMgr struct {
+	types.DudMgr
+	caches map[string]types.Cache
+}
+
+func (f fakeMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+func TestCacheStore(t *testing.T) {
+	cacheConf := cache.NewConfig()
+	cacheConf.Type = cache.TypeMemory
+
+	memCache, err := cache.New(cacheConf, types.DudMgr{}, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mgr := fakeMgr{caches: map[string]types.Cache{
+		"extended": memCache,
+		"basic":    struct{ types.Cache }{memCache},
+	}}
+
+	for _, res := range []string{"extended", "basic"} {
+		res := res
+		t.Run(res, func(t *testing.T) {
+			conf := NewCacheConfig()
+			conf.Resource = res
+			conf.Prefix = res + "_"
+
+			s, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			testStore(t, s)
+		})
+	}
+
+	conf := NewCacheConfig()
+	_, err = NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.Resource = "does_not_exist"
+	_, err = NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// Type is a persistent store of stream configs, where each config is an
+// encoded document identified by the ID of its stream.
+type Type interface {
+	// ReadAll returns the encoded configs of all streams within the store
+	// mapped by their IDs.
+	ReadAll() (map[string][]byte, error)
+
+	// Set stores the encoded config of a stream, replacing any existing config
+	// of the same ID.
+	Set(id string, conf []byte) error
+
+	// Delete removes the config of a stream from the store. Deleting a stream
+	// that does not exist is not an error.
+	Delete(id string) error
+}
+
+// Watcher is an optional interface implemented by stores that are able to
+// wait for changes to their stream configs, which allows changes to be synced
+// without waiting for the sync period.
+type Watcher interface {
+	// Watch blocks until the stream configs within the store might have
+	// changed since the last call to ReadAll, or until the context is done in
+	// which case the error of the context is returned.
+	Watch(ctx context.Context) error
+}
+
+//------------------------------------------------------------------------------
+
+// String constants representing each store type.
+const (
+	TypeCache     = "cache"
+	TypeConsul    = "consul"
+	TypeDirectory = "directory"
+	TypeEtcd      = "etcd"
+)
+
+// Config is the all encompassing configuration struct for all store types.
+type Config struct {
+	Type       string          `json:"type" yaml:"type"`
+	SyncPeriod string          `json:"sync_period" yaml:"sync_period"`
+	Cache      CacheConfig     `json:"cache" yaml:"cache"`
+	Consul     ConsulConfig    `json:"consul" yaml:"consul"`
+	Directory  DirectoryConfig `json:"directory" yaml:"directory"`
+	Etcd       EtcdConfig      `json:"etcd" yaml:"etcd"`
+}
+
+// NewConfig returns a configuration struct fully populated with default values.
+func NewConfig() Config {
+	return Config{
+		Type:       TypeDirectory,
+		SyncPeriod: "5s",
+		Cache:      NewCacheConfig(),
+		Consul:     NewConsulConfig(),
+		Directory:  NewDirectoryConfig(),
+		Etcd:       NewEtcdConfig(),
+	}
+}
+
+// SanitiseConfig returns a sanitised version of the Config, meaning sections
+// that aren't relevant to behaviour are removed.
+func SanitiseConfig(conf Config) (interface{}, error) {
+	var typeConf interface{}
+	switch conf.Type {
+	case TypeCache:
+		typeConf = conf.Cache
+	case TypeConsul:
+		typeConf = conf.Consul
+	case TypeDirectory:
+		typeConf = conf.Directory
+	case TypeEtcd:
+		typeConf = conf.Etcd
+	default:
+		return nil, fmt.Errorf("stream store type '%v' was not recognised", conf.Type)
+	}
+	return map[string]interface{}{
+		"type":        conf.Type,
+		"sync_period": conf.SyncPeriod,
+		conf.Type:     typeConf,
+	}, nil
+}
+
+// UnmarshalYAML ensures that when parsing configs the default values are still
+// applied.
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias Config
+	aliased := confAlias(NewConfig())
+
+	if err := value.Decode(&aliased); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+
+	*c = Config(aliased)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// New creates a store type based on a configuration.
+func New(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	switch conf.Type {
+	case TypeCache:
+		return NewCache(conf.Cache, mgr, log, stats)
+	case TypeConsul:
+		return NewConsul(conf.Consul, log, stats)
+	case TypeDirectory:
+		return NewDirectory(conf.Directory, log, stats)
+	case TypeEtcd:
+		return NewEtcd(conf.Etcd, log, stats)
+	}
+	return nil, fmt.Errorf("stream store type '%v' was not recognised", conf.Type)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// ConsulConfig contains configuration fields for the consul store type.
+type ConsulConfig struct {
+	Address string `json:"address" yaml:"address"`
+	Prefix  string `json:"prefix" yaml:"prefix"`
+	Token   string `json:"token" yaml:"token"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// NewConsulConfig creates a ConsulConfig populated with default values.
+func NewConsulConfig() ConsulConfig {
+	return ConsulConfig{
+		Address: "http://localhost:8500",
+		Prefix:  "benthos/streams/",
+		Token:   "",
+		Timeout: "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// consulMaxWait is the maximum wait of a blocking query.
+const consulMaxWait = time.Minute * 5
+
+// Consul is a store that persists stream configs as keys within the KV store
+// of Consul, or any service that implements the Consul KV HTTP API. Changes
+// are watched with blocking queries.
+type Consul struct {
+	address string
+	prefix  string
+	token   string
+	client  *http.Client
+	log     log.Modular
+
+	// Blocking queries are bound by their wait time rather than a timeout.
+	watchClient *http.Client
+	indexMut    sync.Mutex
+	index       string
+}
+
+// NewConsul creates a new consul store type.
+func NewConsul(conf ConsulConfig, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.Address) == 0 {
+		return nil, errors.New("a consul address must be specified")
+	}
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+	return &Consul{
+		address: strings.TrimSuffix(conf.Address, "/"),
+		prefix:  conf.Prefix,
+		token:   conf.Token,
+		client:  &http.Client{Timeout: timeout},
+		log:     log,
+
+		watchClient: &http.Client{},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Consul) newRequest(method, key, query string, body []byte) (*http.Request, error) {
+	u := c.address + "/v1/kv/" + (&url.URL{Path: key}).EscapedPath()
+	if len(query) > 0 {
+		u += "?" + query
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	return req, nil
+}
+
+func (c *Consul) do(method, key, query string, body []byte) (*http.Response, error) {
+	req, err := c.newRequest(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.Do(req)
+}
+
+func consulErr(res *http.Response) error {
+	body, _ := ioutil.ReadAll(res.Body)
+	return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(body))
+}
+
+// ReadAll returns the encoded configs of all streams under the key prefix
+// mapped by their IDs.
+func (c *Consul) ReadAll() (map[string][]byte, error) {
+	res, err := c.do("GET", c.prefix, "recurse=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	confs := map[string][]byte{}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return nil, consulErr(res)
+	}
+
+	c.indexMut.Lock()
+	c.index = res.Header.Get("X-Consul-Index")
+	c.indexMut.Unlock()
+
+	if res.StatusCode == http.StatusNotFound {
+		return confs, nil
+	}
+
+	var pairs []struct {
+		Key   string
+		Value []byte
+	}
+	if err = json.NewDecoder(res.Body).Decode(&pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	for _, p := range pairs {
+		id := strings.TrimPrefix(p.Key, c.prefix)
+		if len(id) == 0 || strings.HasSuffix(id, "/") || p.Value == nil {
+			continue
+		}
+		confs[id] = p.Value
+	}
+	return confs, nil
+}
+
+// Watch blocks until the index of the key prefix changes from the index
+// returned by the last call to ReadAll, or until the context is done.
+func (c *Consul) Watch(ctx context.Context) error {
+	c.indexMut.Lock()
+	index := c.index
+	c.indexMut.Unlock()
+
+	if len(index) == 0 {
+		// Without an index to compare against we fall back to polling.
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	for {
+		wait := consulMaxWait
+		if deadline, ok := ctx.Deadline(); ok {
+			if wait = time.Until(deadline); wait <= 0 {
+				return ctx.Err()
+			}
+		}
+
+		req, err := c.newRequest("GET", c.prefix, fmt.Sprintf(
+			"recurse=true&index=%v&wait=%vms", url.QueryEscape(index), wait.Milliseconds(),
+		), nil)
+		if err != nil {
+			return err
+		}
+		res, err := c.watchClient.Do(req.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+			err = consulErr(res)
+			res.Body.Close()
+			return err
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.Header.Get("X-Consul-Index") != index {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Set writes the encoded config of a stream to a key.
+func (c *Consul) Set(id string, conf []byte) error {
+	res, err := c.do("PUT", c.prefix+id, "", conf)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return consulErr(res)
+	}
+	return nil
+}
+
+// Delete removes the key of a stream config.
+func (c *Consul) Delete(id string) error {
+	res, err := c.do("DELETE", c.prefix+id, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return consulErr(res)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
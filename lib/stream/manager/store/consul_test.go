@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulStore(t *testing.T) {
+	var kvMut sync.Mutex
+	kv := map[string][]byte{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "footoken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		kvMut.Lock()
+		defer kvMut.Unlock()
+
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case "GET":
+			if r.URL.Query().Get("recurse") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			type pair struct {
+				Key   string
+				Value []byte
+			}
+			pairs := []pair{}
+			for k, v := range kv {
+				if strings.HasPrefix(k, key) {
+					pairs = append(pairs, pair{Key: k, Value: v})
+				}
+			}
+			if len(pairs) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			sort.Slice(pairs, func(i, j int) bool {
+				return pairs[i].Key < pairs[j].Key
+			})
+			pairs = append(pairs, pair{Key: key + "folder/"})
+			json.NewEncoder(w).Encode(pairs)
+		case "PUT":
+			body, _ := ioutil.ReadAll(r.Body)
+			kv[key] = body
+			w.Write([]byte("true"))
+		case "DELETE":
+			delete(kv, key)
+			w.Write([]byte("true"))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConsulConfig()
+	conf.Address = ts.URL
+	conf.Token = "footoken"
+
+	s, err := NewConsul(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	testStore(t, s)
+
+	kvMut.Lock()
+	assert.Equal(t, []byte("bar: 1"), kv["benthos/streams/bar"])
+	kvMut.Unlock()
+
+	conf.Token = "badtoken"
+	s, err = NewConsul(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, err = s.ReadAll()
+	assert.Error(t, err)
+	assert.Error(t, s.Set("foo", []byte("foo: 1")))
+}
+
+func TestConsulStoreWatch(t *testing.T) {
+	var kvMut sync.Mutex
+	kv := map[string][]byte{}
+	index := 1
+	changed := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+		kvMut.Lock()
+		switch r.Method {
+		case "GET":
+			if reqIndex := r.URL.Query().Get("index"); reqIndex == strconv.Itoa(index) {
+				wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+				if err != nil {
+					kvMut.Unlock()
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				waitChan := changed
+				kvMut.Unlock()
+				select {
+				case <-waitChan:
+				case <-time.After(wait):
+				}
+				kvMut.Lock()
+			}
+			w.Header().Set("X-Consul-Index", strconv.Itoa(index))
+			w.WriteHeader(http.StatusNotFound)
+		case "PUT":
+			body, _ := ioutil.ReadAll(r.Body)
+			kv[key] = body
+			index++
+			close(changed)
+			changed = make(chan struct{})
+			w.Write([]byte("true"))
+		}
+		kvMut.Unlock()
+	}))
+	defer ts.Close()
+
+	conf := NewConsulConfig()
+	conf.Address = ts.URL
+
+	s, err := NewConsul(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	w := s.(Watcher)
+
+	// Without a prior read the watch waits for the context.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	assert.Equal(t, context.DeadlineExceeded, w.Watch(ctx))
+	done()
+
+	_, err = s.ReadAll()
+	require.NoError(t, err)
+
+	ctx, done = context.WithTimeout(context.Background(), time.Millisecond*50)
+	assert.Equal(t, context.DeadlineExceeded, w.Watch(ctx))
+	done()
+
+	go func() {
+		<-time.After(time.Millisecond * 20)
+		s.Set("foo", []byte("foo: 1"))
+	}()
+
+	ctx, done = context.WithTimeout(context.Background(), time.Second*5)
+	assert.NoError(t, w.Watch(ctx))
+	done()
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// DirectoryConfig contains configuration fields for the directory store type.
+type DirectoryConfig struct {
+	Path string `json:"path" yaml:"path"`
+}
+
+// NewDirectoryConfig creates a DirectoryConfig populated with default values.
+func NewDirectoryConfig() DirectoryConfig {
+	return DirectoryConfig{
+		Path: "./streams",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Directory is a store that persists each stream config as a YAML file within
+// a directory, named after the ID of the stream.
+type Directory struct {
+	path string
+	log  log.Modular
+}
+
+// NewDirectory creates a new directory store type.
+func NewDirectory(conf DirectoryConfig, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a directory path must be specified")
+	}
+	return &Directory{
+		path: filepath.Clean(conf.Path),
+		log:  log,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (d *Directory) pathFor(id string) (string, error) {
+	if len(id) == 0 || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("stream id '%v' cannot be used as a file name", id)
+	}
+	return filepath.Join(d.path, id+".yaml"), nil
+}
+
+// ReadAll returns the encoded configs of all streams within the directory
+// mapped by their IDs.
+func (d *Directory) ReadAll() (map[string][]byte, error) {
+	infos, err := ioutil.ReadDir(d.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string][]byte{}, nil
+		}
+		return nil, err
+	}
+
+	confs := map[string][]byte{}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".yaml") {
+			continue
+		}
+		confBytes, err := ioutil.ReadFile(filepath.Join(d.path, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		confs[strings.TrimSuffix(info.Name(), ".yaml")] = confBytes
+	}
+	return confs, nil
+}
+
+// Set writes the encoded config of a stream to a file, the file is written
+// under a temporary name and then renamed so that readers never observe a
+// partially written config.
+func (d *Directory) Set(id string, conf []byte) error {
+	path, err := d.pathFor(id)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(d.path, 0755); err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(d.path, "."+id+".yaml.tmp")
+	if err = ioutil.WriteFile(tmpPath, conf, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Delete removes the config file of a stream.
+func (d *Directory) Delete(id string) error {
+	path, err := d.pathFor(id)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
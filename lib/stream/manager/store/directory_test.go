@@ -0,0 +1,44 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_stream_store_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := NewDirectoryConfig()
+	conf.Path = filepath.Join(dir, "streams")
+
+	s, err := NewDirectory(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	testStore(t, s)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(conf.Path, "ignored.txt"), []byte("nope"), 0644))
+	confs, err := s.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, keysOf(confs))
+
+	for _, id := range []string{"", "..", "foo/bar"} {
+		assert.Error(t, s.Set(id, []byte("nope")), id)
+	}
+}
+
+func keysOf(m map[string][]byte) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
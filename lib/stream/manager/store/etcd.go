@@ -0,0 +1,253 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// EtcdConfig contains configuration fields for the etcd store type.
+type EtcdConfig struct {
+	Address string `json:"address" yaml:"address"`
+	Prefix  string `json:"prefix" yaml:"prefix"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// NewEtcdConfig creates an EtcdConfig populated with default values.
+func NewEtcdConfig() EtcdConfig {
+	return EtcdConfig{
+		Address: "http://localhost:2379",
+		Prefix:  "benthos/streams/",
+		Timeout: "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Etcd is a store that persists stream configs as keys within etcd by using
+// the JSON gateway of the etcd v3 API. Changes are watched with the streaming
+// watch API.
+type Etcd struct {
+	address string
+	prefix  string
+	client  *http.Client
+	log     log.Modular
+
+	// Watches are streamed and therefore bound only by their context.
+	watchClient *http.Client
+	revisionMut sync.Mutex
+	revision    int64
+}
+
+// NewEtcd creates a new etcd store type.
+func NewEtcd(conf EtcdConfig, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an etcd address must be specified")
+	}
+	if len(conf.Prefix) == 0 {
+		return nil, errors.New("an etcd key prefix must be specified")
+	}
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+	return &Etcd{
+		address: strings.TrimSuffix(conf.Address, "/"),
+		prefix:  conf.Prefix,
+		client:  &http.Client{Timeout: timeout},
+		log:     log,
+
+		watchClient: &http.Client{},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// etcdRangeEnd returns the key immediately following all keys with a prefix,
+// which is the exclusive end of a range request.
+func etcdRangeEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// etcdKV is a key/value pair as encoded by the etcd JSON gateway, where byte
+// slices are base64 encoded.
+type etcdKV struct {
+	Key      []byte `json:"key,omitempty"`
+	Value    []byte `json:"value,omitempty"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+// etcdHeader is the response header of the etcd JSON gateway, where 64 bit
+// integers are encoded as strings.
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+// etcdWatchRequest creates a watch as encoded by the etcd JSON gateway.
+type etcdWatchRequest struct {
+	CreateRequest struct {
+		Key           []byte `json:"key"`
+		RangeEnd      []byte `json:"range_end"`
+		StartRevision int64  `json:"start_revision,string"`
+	} `json:"create_request"`
+}
+
+// etcdWatchResponse is a message of the stream of a watch.
+type etcdWatchResponse struct {
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Result struct {
+		Canceled bool              `json:"canceled"`
+		Events   []json.RawMessage `json:"events"`
+	} `json:"result"`
+}
+
+func (e *Etcd) do(path string, reqBody interface{}, resBody interface{}) error {
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.address+path, "application/json", bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+	if resBody == nil {
+		return nil
+	}
+	if err = json.NewDecoder(res.Body).Decode(resBody); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// ReadAll returns the encoded configs of all streams under the key prefix
+// mapped by their IDs.
+func (e *Etcd) ReadAll() (map[string][]byte, error) {
+	var res struct {
+		Header etcdHeader `json:"header"`
+		KVs    []etcdKV   `json:"kvs"`
+	}
+	if err := e.do("/v3/kv/range", etcdKV{
+		Key:      []byte(e.prefix),
+		RangeEnd: etcdRangeEnd(e.prefix),
+	}, &res); err != nil {
+		return nil, err
+	}
+
+	e.revisionMut.Lock()
+	e.revision = res.Header.Revision
+	e.revisionMut.Unlock()
+
+	confs := map[string][]byte{}
+	for _, kv := range res.KVs {
+		if id := strings.TrimPrefix(string(kv.Key), e.prefix); len(id) > 0 {
+			confs[id] = kv.Value
+		}
+	}
+	return confs, nil
+}
+
+// Watch blocks until a key with the prefix is changed after the revision of
+// the last call to ReadAll, or until the context is done.
+func (e *Etcd) Watch(ctx context.Context) error {
+	e.revisionMut.Lock()
+	revision := e.revision
+	e.revisionMut.Unlock()
+
+	if revision == 0 {
+		// Without a revision to watch from we fall back to polling.
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	var watchReq etcdWatchRequest
+	watchReq.CreateRequest.Key = []byte(e.prefix)
+	watchReq.CreateRequest.RangeEnd = etcdRangeEnd(e.prefix)
+	watchReq.CreateRequest.StartRevision = revision + 1
+
+	reqBytes, err := json.Marshal(watchReq)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.address+"/v3/watch", bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.watchClient.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		var watchRes etcdWatchResponse
+		if err = dec.Decode(&watchRes); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to decode watch response: %v", err)
+		}
+		if watchRes.Error != nil {
+			return fmt.Errorf("watch failed: %v", watchRes.Error.Message)
+		}
+		// A watch is canceled when its revision has been compacted, in which
+		// case the store must be read again.
+		if len(watchRes.Result.Events) > 0 || watchRes.Result.Canceled {
+			return nil
+		}
+	}
+}
+
+// Set writes the encoded config of a stream to a key.
+func (e *Etcd) Set(id string, conf []byte) error {
+	return e.do("/v3/kv/put", etcdKV{
+		Key:   []byte(e.prefix + id),
+		Value: conf,
+	}, nil)
+}
+
+// Delete removes the key of a stream config.
+func (e *Etcd) Delete(id string) error {
+	return e.do("/v3/kv/deleterange", etcdKV{
+		Key: []byte(e.prefix + id),
+	}, nil)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdRangeEnd(t *testing.T) {
+	assert.Equal(t, []byte("benthos/streams0"), etcdRangeEnd("benthos/streams/"))
+	assert.Equal(t, []byte("b"), etcdRangeEnd("a\xff"))
+	assert.Equal(t, []byte{0}, etcdRangeEnd("\xff"))
+}
+
+func TestEtcdStore(t *testing.T) {
+	var kvMut sync.Mutex
+	kv := map[string][]byte{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req etcdKV
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		kvMut.Lock()
+		defer kvMut.Unlock()
+
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			res := struct {
+				KVs []etcdKV `json:"kvs"`
+			}{}
+			for k, v := range kv {
+				if k >= string(req.Key) && k < string(req.RangeEnd) {
+					res.KVs = append(res.KVs, etcdKV{Key: []byte(k), Value: v})
+				}
+			}
+			json.NewEncoder(w).Encode(res)
+		case "/v3/kv/put":
+			kv[string(req.Key)] = req.Value
+			w.Write([]byte("{}"))
+		case "/v3/kv/deleterange":
+			delete(kv, string(req.Key))
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewEtcdConfig()
+	conf.Address = ts.URL
+
+	s, err := NewEtcd(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	kvMut.Lock()
+	kv["benthos/other"] = []byte("ignored")
+	kvMut.Unlock()
+
+	testStore(t, s)
+
+	kvMut.Lock()
+	assert.Equal(t, []byte("bar: 1"), kv["benthos/streams/bar"])
+	kvMut.Unlock()
+}
+
+func TestEtcdStoreWatch(t *testing.T) {
+	var kvMut sync.Mutex
+	revision := int64(5)
+	changed := make(chan struct{})
+
+	var watchReqs []etcdWatchRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			kvMut.Lock()
+			fmt.Fprintf(w, `{"header":{"revision":"%v"}}`, revision)
+			kvMut.Unlock()
+		case "/v3/kv/put":
+			kvMut.Lock()
+			revision++
+			close(changed)
+			changed = make(chan struct{})
+			kvMut.Unlock()
+			w.Write([]byte("{}"))
+		case "/v3/watch":
+			var req etcdWatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			kvMut.Lock()
+			watchReqs = append(watchReqs, req)
+			waitChan := changed
+			kvMut.Unlock()
+
+			w.Write([]byte(`{"result":{"header":{"revision":"5"},"created":true}}` + "\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-waitChan:
+				w.Write([]byte(`{"result":{"header":{"revision":"6"},"events":[{"kv":{"key":"Zm9v"}}]}}` + "\n"))
+			case <-r.Context().Done():
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewEtcdConfig()
+	conf.Address = ts.URL
+
+	s, err := NewEtcd(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	w := s.(Watcher)
+
+	// Without a prior read the watch waits for the context.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	assert.Equal(t, context.DeadlineExceeded, w.Watch(ctx))
+	done()
+
+	_, err = s.ReadAll()
+	require.NoError(t, err)
+
+	ctx, done = context.WithTimeout(context.Background(), time.Millisecond*50)
+	assert.Equal(t, context.DeadlineExceeded, w.Watch(ctx))
+	done()
+
+	go func() {
+		<-time.After(time.Millisecond * 20)
+		s.Set("foo", []byte("foo: 1"))
+	}()
+
+	ctx, done = context.WithTimeout(context.Background(), time.Second*5)
+	assert.NoError(t, w.Watch(ctx))
+	done()
+
+	kvMut.Lock()
+	require.Len(t, watchReqs, 2)
+	assert.Equal(t, int64(6), watchReqs[1].CreateRequest.StartRevision)
+	assert.Equal(t, []byte("benthos/streams/"), watchReqs[1].CreateRequest.Key)
+	kvMut.Unlock()
+}
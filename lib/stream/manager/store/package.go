@@ -0,0 +1,4 @@
+// Package store contains implementations of persistent stores for the stream
+// configs of a stream manager, allowing streams created via the REST API to
+// survive restarts and be shared across replicas.
+package store
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, s Type) {
+	t.Helper()
+
+	confs, err := s.ReadAll()
+	require.NoError(t, err)
+	assert.Empty(t, confs)
+
+	require.NoError(t, s.Set("foo", []byte("foo: 1")))
+	require.NoError(t, s.Set("bar", []byte("bar: 1")))
+	require.NoError(t, s.Set("foo", []byte("foo: 2")))
+
+	confs, err = s.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("foo: 2"),
+		"bar": []byte("bar: 1"),
+	}, confs)
+
+	require.NoError(t, s.Delete("foo"))
+	require.NoError(t, s.Delete("does_not_exist"))
+
+	confs, err = s.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"bar": []byte("bar: 1"),
+	}, confs)
+}
@@ -0,0 +1,218 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/stream/manager/store"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+func encodeStreamConfig(conf stream.Config) ([]byte, error) {
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(sanit)
+}
+
+func decodeStreamConfig(confBytes []byte) (stream.Config, error) {
+	conf := stream.NewConfig()
+	err := yaml.Unmarshal(confBytes, &conf)
+	return conf, err
+}
+
+//------------------------------------------------------------------------------
+
+// sync reads all stream configs from the store and reconciles the running
+// streams to match them. Streams that were never within the store, such as
+// those loaded from static files, are left untouched.
+func (m *Type) sync() {
+	m.syncMut.Lock()
+	defer m.syncMut.Unlock()
+
+	confs, err := m.store.ReadAll()
+	if err != nil {
+		m.logger.Errorf("Failed to read streams from store: %v\n", err)
+		return
+	}
+
+	for id, confBytes := range confs {
+		_, err := m.Read(id)
+		exists := err == nil
+		if exists && bytes.Equal(m.synced[id], confBytes) {
+			continue
+		}
+
+		conf, err := decodeStreamConfig(confBytes)
+		if err != nil {
+			m.logger.Errorf("Failed to parse stream '%v' config from store: %v\n", id, err)
+			continue
+		}
+		if exists {
+			err = m.Update(id, conf, m.apiTimeout)
+		} else {
+			err = m.Create(id, conf)
+		}
+		if err != nil {
+			m.logger.Errorf("Failed to sync stream '%v' from store: %v\n", id, err)
+			continue
+		}
+		m.synced[id] = confBytes
+		m.logger.Infof("Stream '%v' synced from store\n", id)
+	}
+
+	for id := range m.synced {
+		if _, exists := confs[id]; exists {
+			continue
+		}
+		if err := m.Delete(id, m.apiTimeout); err != nil && err != ErrStreamDoesNotExist {
+			m.logger.Errorf("Failed to delete stream '%v' removed from store: %v\n", id, err)
+			continue
+		}
+		delete(m.synced, id)
+		m.logger.Infof("Stream '%v' removed from store\n", id)
+	}
+}
+
+// syncLoop syncs the store every sync period, or sooner when the store is able
+// to watch for changes.
+func (m *Type) syncLoop() {
+	defer close(m.closedChan)
+
+	watcher, watchable := m.store.(store.Watcher)
+	if !watchable {
+		ticker := time.NewTicker(m.syncPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sync()
+			case <-m.closeChan:
+				return
+			}
+		}
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	go func() {
+		select {
+		case <-m.closeChan:
+			done()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		watchCtx, watchDone := context.WithTimeout(ctx, m.syncPeriod)
+		err := watcher.Watch(watchCtx)
+		watchDone()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && err != context.DeadlineExceeded {
+			m.logger.Errorf("Failed to watch store for changes: %v\n", err)
+			select {
+			case <-time.After(m.syncPeriod):
+			case <-ctx.Done():
+				return
+			}
+		}
+		m.sync()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// createAndStore creates a stream and, when a store is configured, persists its
+// config.
+func (m *Type) createAndStore(id string, conf stream.Config) error {
+	if m.store == nil {
+		return m.Create(id, conf)
+	}
+
+	m.syncMut.Lock()
+	defer m.syncMut.Unlock()
+
+	if err := m.Create(id, conf); err != nil {
+		return err
+	}
+	if err := m.storeSet(id, conf); err != nil {
+		// The stream is removed so that it does not diverge from the store.
+		if dErr := m.Delete(id, m.apiTimeout); dErr != nil {
+			m.logger.Errorf("Failed to roll back stream '%v' after store failure: %v\n", id, dErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// updateAndStore updates a stream and, when a store is configured, persists its
+// new config.
+func (m *Type) updateAndStore(id string, conf stream.Config, timeout time.Duration) error {
+	if m.store == nil {
+		return m.Update(id, conf, timeout)
+	}
+
+	m.syncMut.Lock()
+	defer m.syncMut.Unlock()
+
+	info, err := m.Read(id)
+	if err != nil {
+		return err
+	}
+	prevConf := info.Config()
+
+	if err = m.Update(id, conf, timeout); err != nil {
+		return err
+	}
+	if err = m.storeSet(id, conf); err != nil {
+		// The previous config is restored so that the stream does not diverge
+		// from the store.
+		if uErr := m.Update(id, prevConf, timeout); uErr != nil {
+			m.logger.Errorf("Failed to roll back stream '%v' after store failure: %v\n", id, uErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteAndStore deletes a stream and, when a store is configured, removes its
+// config from the store.
+func (m *Type) deleteAndStore(id string, timeout time.Duration) error {
+	if m.store == nil {
+		return m.Delete(id, timeout)
+	}
+
+	m.syncMut.Lock()
+	defer m.syncMut.Unlock()
+
+	if err := m.Delete(id, timeout); err != nil {
+		return err
+	}
+	if err := m.store.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete stream config from store: %v", err)
+	}
+	delete(m.synced, id)
+	return nil
+}
+
+func (m *Type) storeSet(id string, conf stream.Config) error {
+	confBytes, err := encodeStreamConfig(conf)
+	if err != nil {
+		return fmt.Errorf("failed to encode stream config: %v", err)
+	}
+	if err = m.store.Set(id, confBytes); err != nil {
+		return fmt.Errorf("failed to write stream config to store: %v", err)
+	}
+	m.synced[id] = confBytes
+	return nil
+}
+
+//------------------------------------------------------------------------------
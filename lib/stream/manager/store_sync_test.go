@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	sync.Mutex
+	confs map[string][]byte
+}
+
+func (s *memStore) ReadAll() (map[string][]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	confs := make(map[string][]byte, len(s.confs))
+	for k, v := range s.confs {
+		confs[k] = v
+	}
+	return confs, nil
+}
+
+func (s *memStore) Set(id string, conf []byte) error {
+	s.Lock()
+	s.confs[id] = conf
+	s.Unlock()
+	return nil
+}
+
+func (s *memStore) Delete(id string) error {
+	s.Lock()
+	delete(s.confs, id)
+	s.Unlock()
+	return nil
+}
+
+func TestTypeStoreSync(t *testing.T) {
+	st := &memStore{confs: map[string][]byte{}}
+
+	newMgr := func() *Type {
+		return New(
+			OptSetLogger(log.Noop()),
+			OptSetStats(metrics.Noop()),
+			OptSetManager(types.NoopMgr()),
+			OptSetAPITimeout(time.Second),
+			OptSetStore(st, time.Hour),
+		)
+	}
+
+	mgrA := newMgr()
+	defer mgrA.Stop(time.Second)
+	r := router(mgrA)
+
+	conf := harmlessConf()
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, genRequest("POST", "/streams/foo", conf))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	storedConfs, _ := st.ReadAll()
+	require.Contains(t, storedConfs, "foo")
+
+	// A new replica immediately runs the streams from the store.
+	mgrB := newMgr()
+	defer mgrB.Stop(time.Second)
+	info, err := mgrB.Read("foo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Config().Pipeline.Threads)
+
+	// Streams not created from the store are left alone.
+	require.NoError(t, mgrB.Create("bar", harmlessConf()))
+
+	conf.Pipeline.Threads = 2
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, genRequest("PUT", "/streams/foo", conf))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	mgrB.sync()
+	info, err = mgrB.Read("foo")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Config().Pipeline.Threads)
+
+	// Syncing without changes does not restart streams.
+	mgrB.sync()
+	infoAgain, err := mgrB.Read("foo")
+	require.NoError(t, err)
+	assert.True(t, info == infoAgain)
+
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, genRequest("DELETE", "/streams/foo", nil))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	storedConfs, _ = st.ReadAll()
+	_, exists := storedConfs["foo"]
+	assert.False(t, exists)
+
+	mgrB.sync()
+	_, err = mgrB.Read("foo")
+	assert.Equal(t, ErrStreamDoesNotExist, err)
+	_, err = mgrB.Read("bar")
+	assert.NoError(t, err)
+}
+
+type failingStore struct {
+	memStore
+	setErr error
+}
+
+func (s *failingStore) Set(id string, conf []byte) error {
+	if s.setErr != nil {
+		return s.setErr
+	}
+	return s.memStore.Set(id, conf)
+}
+
+func TestTypeStoreRollback(t *testing.T) {
+	st := &failingStore{memStore: memStore{confs: map[string][]byte{}}}
+
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.NoopMgr()),
+		OptSetAPITimeout(time.Second),
+		OptSetStore(st, time.Hour),
+	)
+	defer mgr.Stop(time.Second)
+
+	conf := harmlessConf()
+	require.NoError(t, mgr.createAndStore("foo", conf))
+
+	st.setErr = errors.New("nope")
+
+	// A failed write removes the created stream.
+	assert.Error(t, mgr.createAndStore("bar", conf))
+	_, err := mgr.Read("bar")
+	assert.Equal(t, ErrStreamDoesNotExist, err)
+
+	// A failed write restores the previous config.
+	newConf := harmlessConf()
+	newConf.Pipeline.Threads = 2
+	assert.Error(t, mgr.updateAndStore("foo", newConf, time.Second))
+	info, err := mgr.Read("foo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Config().Pipeline.Threads)
+}
+
+type watchedStore struct {
+	memStore
+	changed chan struct{}
+}
+
+func (s *watchedStore) Watch(ctx context.Context) error {
+	select {
+	case <-s.changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestTypeStoreWatch(t *testing.T) {
+	st := &watchedStore{
+		memStore: memStore{confs: map[string][]byte{}},
+		changed:  make(chan struct{}),
+	}
+
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.NoopMgr()),
+		OptSetAPITimeout(time.Second),
+		OptSetStore(st, time.Hour),
+	)
+	defer mgr.Stop(time.Second)
+
+	confBytes, err := encodeStreamConfig(harmlessConf())
+	require.NoError(t, err)
+	require.NoError(t, st.Set("foo", confBytes))
+
+	// The change is synced without waiting for the sync period.
+	st.changed <- struct{}{}
+	for i := 0; ; i++ {
+		if _, err = mgr.Read("foo"); err == nil {
+			break
+		}
+		require.True(t, i < 100, "stream was not synced")
+		<-time.After(time.Millisecond * 10)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/stream/manager/store"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -98,6 +99,14 @@ type Type struct {
 
 	pipelineProcCtors []StreamProcConstructorFunc
 
+	store      store.Type
+	syncPeriod time.Duration
+	synced     map[string][]byte
+	syncMut    sync.Mutex
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
 	lock sync.Mutex
 }
 
@@ -109,11 +118,20 @@ func New(opts ...func(*Type)) *Type {
 		stats:      metrics.DudType{},
 		apiTimeout: time.Second * 5,
 		logger:     log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		synced:     map[string][]byte{},
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(t)
 	}
 	t.registerEndpoints()
+	if t.store != nil {
+		t.sync()
+		go t.syncLoop()
+	} else {
+		close(t.closedChan)
+	}
 	return t
 }
 
@@ -159,6 +177,18 @@ func OptAddProcessors(procs ...StreamProcConstructorFunc) func(*Type) {
 	}
 }
 
+// OptSetStore sets a store used for persisting the configs of streams that are
+// created, updated and deleted via the REST API. The store is read when the
+// manager is created and then periodically, or whenever a change is observed
+// when the store is able to watch for changes, and the running streams are
+// reconciled to match the streams within the store.
+func OptSetStore(s store.Type, syncPeriod time.Duration) func(*Type) {
+	return func(t *Type) {
+		t.store = s
+		t.syncPeriod = syncPeriod
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Errors specifically returned by a stream manager.
@@ -290,6 +320,11 @@ func (m *Type) Delete(id string, timeout time.Duration) error {
 // Stop attempts to gracefully shut down all active streams and close the
 // stream manager.
 func (m *Type) Stop(timeout time.Duration) error {
+	m.closeOnce.Do(func() {
+		close(m.closeChan)
+	})
+	<-m.closedChan
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -0,0 +1,295 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrReloadNotEnabled is returned when attempting to reload a stream that was
+// created without reloads enabled.
+var ErrReloadNotEnabled = errors.New("reloads are not enabled for this stream")
+
+// closeLayers closes a list of layers, any of which may be nil, and waits for
+// them to finish closing within a timeout.
+func closeLayers(timeout time.Duration, layers ...types.Closable) error {
+	for _, l := range layers {
+		if l != nil {
+			l.CloseAsync()
+		}
+	}
+	started := time.Now()
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		remaining := timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err := l.WaitForClose(remaining); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForLayers waits for a list of layers, any of which may be nil, to close
+// on their own within a timeout.
+func waitForLayers(timeout time.Duration, layers ...types.Closable) error {
+	started := time.Now()
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		remaining := timeout - time.Since(started)
+		if remaining < 0 {
+			return types.ErrTimeout
+		}
+		if err := l.WaitForClose(remaining); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// persistentBuffer returns true if a buffer config stores messages outside of
+// the process, in which case two instances of it must never run at once.
+func persistentBuffer(conf buffer.Config) bool {
+	return conf.Type == buffer.TypeWAL
+}
+
+// Reload replaces the configuration of a running stream, only the layers with a
+// changed configuration are rebuilt whilst the remaining layers continue to
+// run. The input layer is rebuilt when the input config changes, the buffer
+// layer is rebuilt when the buffer config changes, the pipeline layer is
+// rebuilt when the pipeline config changes, and the output layer is rebuilt
+// when either the output or dead letter configs change.
+//
+// All replacement layers are constructed before any are swapped in, and if any
+// fail to construct then they are closed and an error is returned, leaving the
+// stream running with its previous configuration. Replaced layers are closed
+// gracefully, where messages already within them are drained before they shut
+// down, and the timeout is the maximum period to wait for this to happen.
+//
+// A persistent buffer cannot run alongside its replacement, and therefore when
+// a buffer is replaced where either the previous or new buffer is persistent
+// the previous buffer is drained and closed before the new one is constructed.
+// If the new buffer then fails to construct the previous buffer is restored.
+func (t *Type) Reload(conf Config, timeout time.Duration) error {
+	if !t.reloadable {
+		return ErrReloadNotEnabled
+	}
+
+	t.reloadMut.Lock()
+	defer t.reloadMut.Unlock()
+
+	inputChanged := !reflect.DeepEqual(t.conf.Input, conf.Input) ||
+		(t.conf.DeadLetter == nil) != (conf.DeadLetter == nil)
+	bufferChanged := !reflect.DeepEqual(t.conf.Buffer, conf.Buffer)
+	pipelineChanged := !reflect.DeepEqual(t.conf.Pipeline, conf.Pipeline) ||
+		(t.conf.DeadLetter == nil) != (conf.DeadLetter == nil)
+	outputChanged := !reflect.DeepEqual(t.conf.Output, conf.Output) ||
+		!reflect.DeepEqual(t.conf.DeadLetter, conf.DeadLetter)
+
+	if !inputChanged && !bufferChanged && !pipelineChanged && !outputChanged {
+		return nil
+	}
+
+	drainBuffer := bufferChanged && (persistentBuffer(t.conf.Buffer) || persistentBuffer(conf.Buffer))
+
+	var err error
+	var newInput input.Type
+	var newBuffer buffer.Type
+	var newPipeline pipeline.Type
+	var newOutput output.Type
+
+	rollback := func() {
+		// Layers that have not yet consumed from a channel only shut down
+		// once their input closes, therefore they are started with a closed
+		// channel.
+		closedChan := make(chan types.Transaction)
+		close(closedChan)
+		for _, l := range []types.Consumer{newBuffer, newPipeline, newOutput} {
+			if l != nil {
+				l.Consume(closedChan)
+			}
+		}
+		if cerr := closeLayers(timeout, newInput, newBuffer, newPipeline, newOutput); cerr != nil {
+			t.logger.Errorf("Failed to close new layers after failed reload: %v\n", cerr)
+		}
+	}
+
+	if inputChanged {
+		if newInput, err = t.newInputLayer(conf); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create input: %v", err)
+		}
+	}
+	if bufferChanged && !drainBuffer {
+		if newBuffer, err = t.newBufferLayer(conf); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create buffer: %v", err)
+		}
+	}
+	if pipelineChanged {
+		if newPipeline, err = t.newPipelineLayer(conf); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create pipeline: %v", err)
+		}
+	}
+	if outputChanged {
+		if newOutput, err = t.newOutputLayer(conf); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create output: %v", err)
+		}
+	}
+
+	// A persistent buffer is replaced before any other layer, which allows the
+	// previous buffer to drain through the previous pipeline and output.
+	if drainBuffer {
+		if newBuffer, err = t.replaceBufferDrained(conf, timeout); err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	// Swap the layers from the end of the stream to the start, this ensures
+	// that a new destination is available before each upstream layer changes.
+	var prevLayers []types.Closable
+	if outputChanged {
+		var tranChan <-chan types.Transaction
+		if tranChan, err = t.outputSplice.swap(); err == nil {
+			err = newOutput.Consume(tranChan)
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("failed to start output: %v", err)
+		}
+		prevLayers = append(prevLayers, t.outputLayer)
+	}
+	if pipelineChanged {
+		release := t.outputSplice.reserve()
+		defer release()
+
+		var tranChan <-chan types.Transaction
+		if tranChan, err = t.bufferSplice.swap(); err == nil {
+			tranChan, err = chainProcessing(tranChan, nil, newPipeline)
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("failed to start pipeline: %v", err)
+		}
+		t.outputSplice.addSource(tranChan)
+		prevLayers = append(prevLayers, t.pipelineLayer)
+	}
+	if bufferChanged && !drainBuffer {
+		release := t.bufferSplice.reserve()
+		defer release()
+
+		var tranChan <-chan types.Transaction
+		if tranChan, err = t.inputSplice.swap(); err == nil {
+			tranChan, err = chainProcessing(tranChan, newBuffer, nil)
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("failed to start buffer: %v", err)
+		}
+		t.bufferSplice.addSource(tranChan)
+		prevLayers = append(prevLayers, t.bufferLayer)
+	}
+	if inputChanged {
+		t.inputSplice.addSource(newInput.TransactionChan())
+		t.inputLayer.CloseAsync()
+		prevLayers = append(prevLayers, t.inputLayer)
+	}
+
+	t.layersMut.Lock()
+	if inputChanged {
+		t.inputLayer = newInput
+	}
+	if bufferChanged {
+		t.bufferLayer = newBuffer
+	}
+	if pipelineChanged {
+		t.pipelineLayer = newPipeline
+	}
+	if outputChanged {
+		t.outputLayer = newOutput
+	}
+	t.conf = conf
+	t.layersMut.Unlock()
+
+	// Previous layers that consumed from a splice shut down once the splice
+	// closes their channel, and we give them a chance to drain before forcing
+	// them to close.
+	if err = waitForLayers(timeout, prevLayers...); err != nil {
+		t.logger.Warnf("Replaced layers failed to drain within the timeout, forcing them to close: %v\n", err)
+		if err = closeLayers(timeout, prevLayers...); err != nil {
+			t.logger.Errorf("Failed to close replaced layers: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// replaceBufferDrained drains and closes the current buffer layer before
+// constructing and starting a buffer from a new config. If the new buffer fails
+// to construct then a buffer from the current config is started in its place
+// and an error is returned.
+func (t *Type) replaceBufferDrained(conf Config, timeout time.Duration) (buffer.Type, error) {
+	release := t.bufferSplice.reserve()
+	defer release()
+
+	tranChan, err := t.inputSplice.swap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start buffer: %v", err)
+	}
+
+	// Upstream layers block on the splice until a new buffer consumes from it.
+	if t.bufferLayer != nil {
+		if err = waitForLayers(timeout, t.bufferLayer); err != nil {
+			t.logger.Warnf("Replaced buffer failed to drain within the timeout, forcing it to close: %v\n", err)
+			if err = closeLayers(timeout, t.bufferLayer); err != nil {
+				t.logger.Errorf("Failed to close replaced buffer: %v\n", err)
+			}
+		}
+	}
+
+	var restoreErr error
+	newBuffer, err := t.newBufferLayer(conf)
+	if err != nil {
+		restoreErr = fmt.Errorf("failed to create buffer: %v", err)
+		if newBuffer, err = t.newBufferLayer(t.conf); err != nil {
+			t.logger.Errorf("Failed to restore previous buffer: %v\n", err)
+			return nil, restoreErr
+		}
+	}
+
+	var outChan <-chan types.Transaction
+	if outChan, err = chainProcessing(tranChan, newBuffer, nil); err != nil {
+		closeLayers(timeout, newBuffer)
+		return nil, fmt.Errorf("failed to start buffer: %v", err)
+	}
+	t.bufferSplice.addSource(outChan)
+
+	if restoreErr != nil {
+		t.layersMut.Lock()
+		t.bufferLayer = newBuffer
+		t.layersMut.Unlock()
+		return nil, restoreErr
+	}
+	return newBuffer, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,276 @@
+package stream
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+func reloadTestConf(inPipe, mapping, outPipe string) Config {
+	conf := NewConfig()
+	conf.Input.Type = "inproc"
+	conf.Input.Inproc = input.InprocConfig(inPipe)
+
+	procConf := processor.NewConfig()
+	procConf.Type = "bloblang"
+	procConf.Bloblang = processor.BloblangConfig(mapping)
+	conf.Pipeline.Processors = append(conf.Pipeline.Processors, procConf)
+
+	conf.Output.Type = "inproc"
+	conf.Output.Inproc = output.InprocConfig(outPipe)
+	return conf
+}
+
+func TestStreamReload(t *testing.T) {
+	mgr, err := manager.New(manager.NewConfig(), types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	inChans := map[string]chan types.Transaction{
+		"in_a": make(chan types.Transaction),
+		"in_b": make(chan types.Transaction),
+	}
+	for k, v := range inChans {
+		mgr.SetPipe(k, v)
+	}
+
+	var closed int32
+	strm, err := New(
+		reloadTestConf("in_a", `root.a = content().string()`, "out_a"),
+		OptSetManager(mgr),
+		OptEnableReload(),
+		OptOnClose(func() {
+			atomic.StoreInt32(&closed, 1)
+		}),
+	)
+	require.NoError(t, err)
+
+	resChan := make(chan types.Response, 1)
+	sendAndReceive := func(inPipe, outPipe, content, expected string) {
+		t.Helper()
+
+		var outChan <-chan types.Transaction
+		for i := 0; i < 500; i++ {
+			if outChan, err = mgr.GetPipe(outPipe); err == nil {
+				break
+			}
+			<-time.After(time.Millisecond * 10)
+		}
+		require.NoError(t, err)
+
+		select {
+		case inChans[inPipe] <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out sending")
+		}
+
+		var tran types.Transaction
+		select {
+		case tran = <-outChan:
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out receiving")
+		}
+		assert.Equal(t, expected, string(tran.Payload.Get(0).Get()))
+
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out acknowledging")
+		}
+		select {
+		case res := <-resChan:
+			assert.NoError(t, res.Error())
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out receiving response")
+		}
+	}
+
+	sendAndReceive("in_a", "out_a", "foo", `{"a":"foo"}`)
+
+	inputLayer, outputLayer := strm.inputLayer, strm.outputLayer
+
+	// Only the pipeline changes.
+	require.NoError(t, strm.Reload(reloadTestConf("in_a", `root.b = content().string()`, "out_a"), time.Second*5))
+	assert.True(t, inputLayer == strm.inputLayer)
+	assert.True(t, outputLayer == strm.outputLayer)
+	sendAndReceive("in_a", "out_a", "foo", `{"b":"foo"}`)
+
+	// A config that fails to construct leaves the previous config running.
+	badConf := reloadTestConf("in_a", `root.c = content().string()`, "out_a")
+	badConf.Pipeline.Processors[0].Bloblang = "root = this.nope("
+	assert.Error(t, strm.Reload(badConf, time.Second*5))
+	sendAndReceive("in_a", "out_a", "foo", `{"b":"foo"}`)
+
+	// Only the output changes.
+	require.NoError(t, strm.Reload(reloadTestConf("in_a", `root.b = content().string()`, "out_b"), time.Second*5))
+	assert.True(t, inputLayer == strm.inputLayer)
+	assert.False(t, outputLayer == strm.outputLayer)
+	sendAndReceive("in_a", "out_b", "foo", `{"b":"foo"}`)
+
+	// Only the input changes.
+	require.NoError(t, strm.Reload(reloadTestConf("in_b", `root.b = content().string()`, "out_b"), time.Second*5))
+	assert.False(t, inputLayer == strm.inputLayer)
+	sendAndReceive("in_b", "out_b", "foo", `{"b":"foo"}`)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&closed))
+	require.NoError(t, strm.Stop(time.Second*5))
+	for i := 0; i < 500 && atomic.LoadInt32(&closed) == 0; i++ {
+		<-time.After(time.Millisecond * 10)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&closed))
+}
+
+func TestStreamReloadPersistentBuffer(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_reload_wal_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	mgr, err := manager.New(manager.NewConfig(), types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	inChan := make(chan types.Transaction)
+	mgr.SetPipe("in_a", inChan)
+
+	walConf := func(mapping string, segmentSize int) Config {
+		conf := reloadTestConf("in_a", mapping, "out_a")
+		conf.Buffer.Type = buffer.TypeWAL
+		conf.Buffer.WAL.Path = tmpDir
+		conf.Buffer.WAL.SegmentSize = segmentSize
+		return conf
+	}
+
+	strm, err := New(walConf(`root.a = content().string()`, 1024), OptSetManager(mgr), OptEnableReload())
+	require.NoError(t, err)
+
+	var outChan <-chan types.Transaction
+	for i := 0; i < 500; i++ {
+		if outChan, err = mgr.GetPipe("out_a"); err == nil {
+			break
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	require.NoError(t, err)
+
+	send := func(content string) {
+		t.Helper()
+		resChan := make(chan types.Response, 1)
+		select {
+		case inChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out sending")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out receiving response")
+		}
+	}
+	receive := func(expected string) types.Transaction {
+		t.Helper()
+		select {
+		case tran := <-outChan:
+			assert.Equal(t, expected, string(tran.Payload.Get(0).Get()))
+			return tran
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out receiving")
+		}
+		return types.Transaction{}
+	}
+	ack := func(tran types.Transaction) {
+		t.Helper()
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out acknowledging")
+		}
+	}
+	reloadAsync := func(conf Config) <-chan error {
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- strm.Reload(conf, time.Second*5)
+		}()
+		return errChan
+	}
+	expectNothing := func() {
+		t.Helper()
+		select {
+		case tran := <-outChan:
+			t.Fatalf("unexpected message: %s", tran.Payload.Get(0).Get())
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+
+	bufferLayer := strm.bufferLayer
+
+	// Only the pipeline changes whilst a message is in flight, the buffer must
+	// not be rebuilt as it would replay the unacknowledged message.
+	send("foo")
+	tran := receive(`{"a":"foo"}`)
+	errChan := reloadAsync(walConf(`root.b = content().string()`, 1024))
+	ack(tran)
+	require.NoError(t, <-errChan)
+	assert.True(t, bufferLayer == strm.bufferLayer)
+
+	send("bar")
+	ack(receive(`{"b":"bar"}`))
+	expectNothing()
+
+	// The buffer changes whilst a message is in flight, the previous buffer is
+	// drained before the new one opens the same directory.
+	send("baz")
+	tran = receive(`{"b":"baz"}`)
+	errChan = reloadAsync(walConf(`root.b = content().string()`, 2048))
+	expectNothing()
+	ack(tran)
+	require.NoError(t, <-errChan)
+	assert.False(t, bufferLayer == strm.bufferLayer)
+
+	send("qux")
+	ack(receive(`{"b":"qux"}`))
+	expectNothing()
+
+	// A buffer that fails to construct results in the previous buffer being
+	// restored.
+	bufferLayer = strm.bufferLayer
+	assert.Error(t, strm.Reload(walConf(`root.c = content().string()`, 1), time.Second*5))
+	assert.False(t, bufferLayer == strm.bufferLayer)
+	assert.Equal(t, 2048, strm.conf.Buffer.WAL.SegmentSize)
+
+	send("quz")
+	ack(receive(`{"b":"quz"}`))
+	expectNothing()
+
+	require.NoError(t, strm.Stop(time.Second*5))
+}
+
+func TestStreamReloadNotEnabled(t *testing.T) {
+	mgr, err := manager.New(manager.NewConfig(), types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr.SetPipe("in_a", make(chan types.Transaction))
+
+	conf := reloadTestConf("in_a", `root = content()`, "out_a")
+	strm, err := New(conf, OptSetManager(mgr))
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrReloadNotEnabled, strm.Reload(conf, time.Second))
+	require.NoError(t, strm.Stop(time.Second*5))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,119 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// errSpliceClosed is returned when attempting to swap the destination of a
+// splice after all of its sources have closed.
+var errSpliceClosed = errors.New("stream has terminated")
+
+// splice joins the transaction channels of one or more upstream layers to a
+// single downstream layer, where both the upstream sources and the downstream
+// destination can be replaced whilst the stream is running.
+//
+// The destination channel is closed once all sources have closed, which allows
+// a stream with splices to shut down gracefully in the same way as a stream
+// without them.
+type splice struct {
+	mut      sync.Mutex
+	sources  int
+	closed   bool
+	outChan  chan types.Transaction
+	swapChan chan struct{}
+	sending  *sync.WaitGroup
+}
+
+func newSplice() *splice {
+	return &splice{
+		outChan:  make(chan types.Transaction),
+		swapChan: make(chan struct{}),
+		sending:  &sync.WaitGroup{},
+	}
+}
+
+// TransactionChan returns the current destination channel of the splice.
+func (s *splice) TransactionChan() <-chan types.Transaction {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.outChan
+}
+
+// addSource begins forwarding transactions from a channel until it is closed.
+func (s *splice) addSource(tranChan <-chan types.Transaction) {
+	s.mut.Lock()
+	s.sources++
+	s.mut.Unlock()
+
+	go func() {
+		for tran := range tranChan {
+			s.send(tran)
+		}
+
+		s.mut.Lock()
+		if s.sources--; s.sources == 0 && !s.closed {
+			s.closed = true
+			close(s.outChan)
+		}
+		s.mut.Unlock()
+	}()
+}
+
+// reserve prevents the splice from closing until the returned func is called,
+// which allows a source to be replaced without the splice closing when the
+// previous source closes before its replacement is added.
+func (s *splice) reserve() func() {
+	tmpChan := make(chan types.Transaction)
+	s.addSource(tmpChan)
+	return func() {
+		close(tmpChan)
+	}
+}
+
+func (s *splice) send(tran types.Transaction) {
+	for {
+		s.mut.Lock()
+		outChan, swapChan, sending := s.outChan, s.swapChan, s.sending
+		sending.Add(1)
+		s.mut.Unlock()
+
+		select {
+		case outChan <- tran:
+			sending.Done()
+			return
+		case <-swapChan:
+			// The destination was swapped whilst we were blocked, try again
+			// with the new destination.
+			sending.Done()
+		}
+	}
+}
+
+// swap replaces the destination channel of the splice and returns the new
+// channel. The previous channel is closed once any pending sends to it have
+// finished, which allows the previous downstream layer to drain and close.
+func (s *splice) swap() (<-chan types.Transaction, error) {
+	s.mut.Lock()
+	if s.closed {
+		s.mut.Unlock()
+		return nil, errSpliceClosed
+	}
+	prevOutChan, prevSending := s.outChan, s.sending
+	close(s.swapChan)
+	s.outChan = make(chan types.Transaction)
+	s.swapChan = make(chan struct{})
+	s.sending = &sync.WaitGroup{}
+	outChan := s.outChan
+	s.mut.Unlock()
+
+	prevSending.Wait()
+	close(prevOutChan)
+	return outChan, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpliceSwap(t *testing.T) {
+	s := newSplice()
+
+	srcA, srcB := make(chan types.Transaction), make(chan types.Transaction)
+	s.addSource(srcA)
+
+	firstOut := s.TransactionChan()
+
+	tran := func(content string) types.Transaction {
+		return types.NewTransaction(message.New([][]byte{[]byte(content)}), nil)
+	}
+	receive := func(c <-chan types.Transaction) string {
+		t.Helper()
+		select {
+		case tr, open := <-c:
+			require.True(t, open)
+			return string(tr.Payload.Get(0).Get())
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return ""
+	}
+
+	srcA <- tran("foo")
+	assert.Equal(t, "foo", receive(firstOut))
+
+	// A send that is blocked on the previous destination moves to the new
+	// destination after a swap.
+	srcA <- tran("bar")
+	secondOut, err := s.swap()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", receive(secondOut))
+
+	select {
+	case _, open := <-firstOut:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	s.addSource(srcB)
+	close(srcA)
+
+	srcB <- tran("baz")
+	assert.Equal(t, "baz", receive(secondOut))
+
+	close(srcB)
+	select {
+	case _, open := <-secondOut:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	_, err = s.swap()
+	assert.Equal(t, errSpliceClosed, err)
+}
@@ -2,8 +2,10 @@ package stream
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
 	"runtime/pprof"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/buffer"
@@ -12,6 +14,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -26,6 +29,16 @@ type Type struct {
 	pipelineLayer pipeline.Type
 	outputLayer   output.Type
 
+	// Splices are only used when reloads are enabled, and allow layers to be
+	// swapped whilst the stream is running.
+	reloadable   bool
+	inputSplice  *splice
+	bufferSplice *splice
+	outputSplice *splice
+
+	layersMut sync.RWMutex
+	reloadMut sync.Mutex
+
 	complementaryProcs []types.ProcessorConstructorFunc
 
 	manager types.Manager
@@ -52,13 +65,17 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	}
 
 	healthCheck := func(w http.ResponseWriter, r *http.Request) {
+		t.layersMut.RLock()
+		inputLayer, outputLayer := t.inputLayer, t.outputLayer
+		t.layersMut.RUnlock()
+
 		connected := true
-		if !t.inputLayer.Connected() {
+		if !inputLayer.Connected() {
 			connected = false
 			w.WriteHeader(http.StatusServiceUnavailable)
 			w.Write([]byte("input not connected\n"))
 		}
-		if !t.outputLayer.Connected() {
+		if !outputLayer.Connected() {
 			connected = false
 			w.WriteHeader(http.StatusServiceUnavailable)
 			w.Write([]byte("output not connected\n"))
@@ -118,6 +135,14 @@ func OptSetManager(mgr types.Manager) func(*Type) {
 	}
 }
 
+// OptEnableReload enables the configuration of the stream to be changed with
+// Reload whilst it is running.
+func OptEnableReload() func(*Type) {
+	return func(t *Type) {
+		t.reloadable = true
+	}
+}
+
 // OptOnClose sets a closure to be called when the stream closes.
 func OptOnClose(onClose func()) func(*Type) {
 	return func(t *Type) {
@@ -127,35 +152,106 @@ func OptOnClose(onClose func()) func(*Type) {
 
 //------------------------------------------------------------------------------
 
-func (t *Type) start() (err error) {
-	// Constructors
-	if t.inputLayer, err = input.New(
-		t.conf.Input, t.manager,
+// newInputLayer records the receive time of messages when a dead letter output
+// is configured.
+func (t *Type) newInputLayer(conf Config) (input.Type, error) {
+	var pipelines []types.PipelineConstructorFunc
+	if conf.DeadLetter != nil {
+		log, stats := t.logger.NewModule(".input"), metrics.Namespaced(t.stats, "input")
+		pipelines = append(pipelines, func(i *int) (types.Pipeline, error) {
+			return pipeline.NewProcessor(log, stats, receivedAtProcessor{}), nil
+		})
+	}
+	return input.New(
+		conf.Input, t.manager,
 		t.logger.NewModule(".input"), metrics.Namespaced(t.stats, "input"),
-	); err != nil {
-		return
+		pipelines...,
+	)
+}
+
+// newBufferLayer returns a nil buffer when the buffer type is none.
+func (t *Type) newBufferLayer(conf Config) (buffer.Type, error) {
+	if conf.Buffer.Type == buffer.TypeNone {
+		return nil, nil
 	}
-	if t.conf.Buffer.Type != buffer.TypeNone {
-		if t.bufferLayer, err = buffer.New(
-			t.conf.Buffer, t.manager,
-			t.logger.NewModule(".buffer"), metrics.Namespaced(t.stats, "buffer"),
-		); err != nil {
-			return
+	return buffer.New(
+		conf.Buffer, t.manager,
+		t.logger.NewModule(".buffer"), metrics.Namespaced(t.stats, "buffer"),
+	)
+}
+
+// newPipelineLayer returns a nil pipeline when there are no processors.
+func (t *Type) newPipelineLayer(conf Config) (pipeline.Type, error) {
+	if tLen := len(t.complementaryProcs) + len(conf.Pipeline.Processors); tLen == 0 {
+		return nil, nil
+	}
+	pipeConf, procCtors := conf.Pipeline, t.complementaryProcs
+	if conf.DeadLetter != nil {
+		pipeConf, procCtors = t.failPathProcessors(conf)
+	}
+	return pipeline.New(
+		pipeConf, t.manager,
+		t.logger.NewModule(".pipeline"), metrics.Namespaced(t.stats, "pipeline"),
+		procCtors...,
+	)
+}
+
+func (t *Type) newOutputLayer(conf Config) (output.Type, error) {
+	outputLayer, err := output.New(
+		conf.Output, t.manager,
+		t.logger.NewModule(".output"), metrics.Namespaced(t.stats, "output"),
+	)
+	if err != nil || conf.DeadLetter == nil {
+		return outputLayer, err
+	}
+	dlqLayer, err := output.New(
+		conf.DeadLetter.Output, t.manager,
+		t.logger.NewModule(".dead_letter.output"), metrics.Namespaced(t.stats, "dead_letter.output"),
+	)
+	if err != nil {
+		outputLayer.CloseAsync()
+		return nil, err
+	}
+	return newDeadLetterRouter(
+		conf.DeadLetter.MaxAttempts, outputLayer, dlqLayer,
+		t.logger.NewModule(".dead_letter"), metrics.Namespaced(t.stats, "dead_letter"),
+	)
+}
+
+// chainProcessing connects the buffer and pipeline layers, either of which may
+// be nil, to a transaction channel and returns the resulting channel.
+func chainProcessing(
+	tranChan <-chan types.Transaction,
+	bufferLayer buffer.Type,
+	pipelineLayer pipeline.Type,
+) (<-chan types.Transaction, error) {
+	if bufferLayer != nil {
+		if err := bufferLayer.Consume(tranChan); err != nil {
+			return nil, err
 		}
+		tranChan = bufferLayer.TransactionChan()
 	}
-	if tLen := len(t.complementaryProcs) + len(t.conf.Pipeline.Processors); tLen > 0 {
-		if t.pipelineLayer, err = pipeline.New(
-			t.conf.Pipeline, t.manager,
-			t.logger.NewModule(".pipeline"), metrics.Namespaced(t.stats, "pipeline"),
-			t.complementaryProcs...,
-		); err != nil {
-			return
+	if pipelineLayer != nil {
+		if err := pipelineLayer.Consume(tranChan); err != nil {
+			return nil, err
 		}
+		tranChan = pipelineLayer.TransactionChan()
 	}
-	if t.outputLayer, err = output.New(
-		t.conf.Output, t.manager,
-		t.logger.NewModule(".output"), metrics.Namespaced(t.stats, "output"),
-	); err != nil {
+	return tranChan, nil
+}
+
+func (t *Type) start() (err error) {
+	// Constructors
+	if t.inputLayer, err = t.newInputLayer(t.conf); err != nil {
+		return
+	}
+	if t.bufferLayer, err = t.newBufferLayer(t.conf); err != nil {
+		return
+	}
+	if t.pipelineLayer, err = t.newPipelineLayer(t.conf); err != nil {
+		return
+	}
+	if t.outputLayer, err = t.newOutputLayer(t.conf); err != nil {
 		return
 	}
 
@@ -163,34 +259,82 @@ func (t *Type) start() (err error) {
 	var nextTranChan <-chan types.Transaction
 
 	nextTranChan = t.inputLayer.TransactionChan()
-	if t.bufferLayer != nil {
-		if err = t.bufferLayer.Consume(nextTranChan); err != nil {
-			return
-		}
-		nextTranChan = t.bufferLayer.TransactionChan()
+	if t.reloadable {
+		t.inputSplice = newSplice()
+		t.inputSplice.addSource(nextTranChan)
+		nextTranChan = t.inputSplice.TransactionChan()
 	}
-	if t.pipelineLayer != nil {
-		if err = t.pipelineLayer.Consume(nextTranChan); err != nil {
-			return
-		}
-		nextTranChan = t.pipelineLayer.TransactionChan()
+	if nextTranChan, err = chainProcessing(nextTranChan, t.bufferLayer, nil); err != nil {
+		return
+	}
+	if t.reloadable {
+		t.bufferSplice = newSplice()
+		t.bufferSplice.addSource(nextTranChan)
+		nextTranChan = t.bufferSplice.TransactionChan()
+	}
+	if nextTranChan, err = chainProcessing(nextTranChan, nil, t.pipelineLayer); err != nil {
+		return
+	}
+	if t.reloadable {
+		t.outputSplice = newSplice()
+		t.outputSplice.addSource(nextTranChan)
+		nextTranChan = t.outputSplice.TransactionChan()
 	}
 	if err = t.outputLayer.Consume(nextTranChan); err != nil {
 		return
 	}
 
-	go func(out output.Type) {
+	go func() {
 		for {
+			t.layersMut.RLock()
+			out := t.outputLayer
+			t.layersMut.RUnlock()
+
 			if err := out.WaitForClose(time.Second); err == nil {
-				t.onClose()
-				return
+				// The output may have been replaced by a reload, in which
+				// case the stream is still running.
+				t.layersMut.RLock()
+				replaced := out != t.outputLayer
+				t.layersMut.RUnlock()
+				if !replaced {
+					t.onClose()
+					return
+				}
 			}
 		}
-	}(t.outputLayer)
+	}()
 
 	return nil
 }
 
+// failPathProcessors returns a pipeline config and processor constructors where
+// each configured processor is wrapped in order to record its path on message
+// parts that it flags as failed, which is required by the dead letter output.
+func (t *Type) failPathProcessors(conf Config) (pipeline.Config, []types.ProcessorConstructorFunc) {
+	pipeConf := conf.Pipeline
+	pipeConf.Processors = nil
+
+	pipeLog := t.logger.NewModule(".pipeline")
+	pipeStats := metrics.Namespaced(t.stats, "pipeline")
+
+	procs := 0
+	var procCtors []types.ProcessorConstructorFunc
+	for j, procConf := range conf.Pipeline.Processors {
+		path := fmt.Sprintf("pipeline.processors.%v", j)
+		procConf := procConf
+		procCtors = append(procCtors, func() (types.Processor, error) {
+			prefix := fmt.Sprintf("processor.%v", procs)
+			procs++
+			proc, err := processor.New(procConf, t.manager, pipeLog.NewModule("."+prefix), metrics.Namespaced(pipeStats, prefix))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
+			}
+			return newFailPathProcessor(proc, path), nil
+		})
+	}
+	return pipeConf, append(procCtors, t.complementaryProcs...)
+}
+
 // stopGracefully attempts to close the stream in the most graceful way by only
 // closing the input layer and waiting for all other layers to terminate by
 // proxy. This should guarantee that all in-flight and buffered data is resolved
@@ -343,6 +487,9 @@ func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 // Initially the attempt is graceful, but as the timeout draws close the attempt
 // becomes progressively less graceful.
 func (t *Type) Stop(timeout time.Duration) error {
+	t.reloadMut.Lock()
+	defer t.reloadMut.Unlock()
+
 	tOutUnordered := timeout / 4
 	tOutGraceful := timeout - tOutUnordered
 
@@ -139,9 +139,12 @@ func testHDFSReaderBasic(hosts []string, user string, t *testing.T) {
 	rconf.Hosts = hosts
 	rconf.Directory = "/"
 
-	r := reader.NewHDFS(rconf, log.Noop(), metrics.Noop())
+	r, err := reader.NewHDFS(rconf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if err := r.Connect(); err != nil {
+	if err = r.Connect(); err != nil {
 		t.Fatal(err)
 	}
 
@@ -221,9 +224,12 @@ func testHDFSReaderParallelWriters(hosts []string, user string, t *testing.T) {
 	rconf.Hosts = hosts
 	rconf.Directory = "/subdir"
 
-	r := reader.NewHDFS(rconf, log.Noop(), metrics.Noop())
+	r, err := reader.NewHDFS(rconf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if err := r.Connect(); err != nil {
+	if err = r.Connect(); err != nil {
 		t.Fatal(err)
 	}
 
@@ -0,0 +1,236 @@
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/ory/dockertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func TestMySQLCDCIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Second * 60
+
+	// Servers are varied in order to cover binlog events with and without
+	// checksums, as well as the authentication plugins of replication users.
+	servers := map[string]struct {
+		tag    string
+		plugin string
+		cmd    []string
+	}{
+		"8.0 caching_sha2_password": {
+			tag:    "8.0",
+			plugin: "caching_sha2_password",
+		},
+		"8.0 mysql_native_password no checksum": {
+			tag:    "8.0",
+			plugin: "mysql_native_password",
+			cmd:    []string{"--binlog-checksum=NONE"},
+		},
+		"5.7 mysql_native_password": {
+			tag:    "5.7",
+			plugin: "mysql_native_password",
+		},
+	}
+
+	for name, server := range servers {
+		server := server
+		t.Run(name, func(t *testing.T) {
+			resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+				Repository:   "mysql",
+				Tag:          server.tag,
+				ExposedPorts: []string{"3306/tcp"},
+				Env: []string{
+					"MYSQL_ROOT_PASSWORD=testpass",
+					"MYSQL_DATABASE=testdb",
+				},
+				Cmd: append([]string{"--server-id=1", "--log-bin=mysql-bin", "--binlog-format=ROW"}, server.cmd...),
+			})
+			if err != nil {
+				t.Fatalf("Could not start resource: %s", err)
+			}
+			defer func() {
+				if err = pool.Purge(resource); err != nil {
+					t.Logf("Failed to clean up docker resource: %v", err)
+				}
+			}()
+			resource.Expire(900)
+
+			var db *sql.DB
+			if err = pool.Retry(func() error {
+				if db, err = sql.Open("mysql", fmt.Sprintf("root:testpass@tcp(localhost:%v)/testdb", resource.GetPort("3306/tcp"))); err != nil {
+					return err
+				}
+				if err = db.Ping(); err != nil {
+					db.Close()
+					return err
+				}
+				return nil
+			}); err != nil {
+				t.Fatalf("Could not connect to docker resource: %s", err)
+			}
+			defer db.Close()
+
+			for _, stmt := range []string{
+				fmt.Sprintf(`CREATE USER 'cdc'@'%%' IDENTIFIED WITH %v BY 'cdcpass'`, server.plugin),
+				`GRANT SELECT, RELOAD, LOCK TABLES, REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO 'cdc'@'%'`,
+				`CREATE TABLE footable (id int unsigned PRIMARY KEY, name varchar(50), doc json)`,
+				`INSERT INTO footable (id, name, doc) VALUES (1, 'foo', '{"a":[1,2]}')`,
+			} {
+				_, err = db.Exec(stmt)
+				require.NoError(t, err)
+			}
+
+			dsn := fmt.Sprintf("cdc:cdcpass@tcp(localhost:%v)/testdb", resource.GetPort("3306/tcp"))
+			testMySQLCDCSnapshotAndStream(dsn, db, t)
+		})
+	}
+}
+
+func readMySQLCDC(t *testing.T, r *reader.MySQLCDC) (types.Message, reader.AsyncAckFn) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	for {
+		msg, ackFn, err := r.ReadWithContext(ctx)
+		if err == types.ErrTimeout && ctx.Err() == nil {
+			continue
+		}
+		require.NoError(t, err)
+		return msg, ackFn
+	}
+}
+
+func testMySQLCDCSnapshotAndStream(dsn string, db *sql.DB, t *testing.T) {
+	cacheConf := cache.NewConfig()
+	posCache, err := cache.NewMemory(cacheConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := reader.NewMySQLCDCConfig()
+	conf.DSN = dsn
+	conf.Tables = []string{"testdb.footable"}
+	conf.Snapshot = true
+	conf.CommitPeriod = "100ms"
+
+	r, err := reader.NewMySQLCDC(conf, posCache, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, r.ConnectWithContext(context.Background()))
+
+	msg, ackFn := readMySQLCDC(t, r)
+	assert.Equal(t, `{"after":{"doc":{"a":[1,2]},"id":1,"name":"foo"},"before":null}`, string(msg.Get(0).Get()))
+	assert.Equal(t, "read", msg.Get(0).Metadata().Get("mysql_operation"))
+	require.NoError(t, ackFn(context.Background(), response.NewAck()))
+
+	for _, stmt := range []string{
+		`INSERT INTO footable (id, name, doc) VALUES (2, 'bar', NULL)`,
+		`UPDATE footable SET name = 'baz' WHERE id = 2`,
+		`DELETE FROM footable WHERE id = 2`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	expected := []struct {
+		op   string
+		body string
+	}{
+		{"insert", `{"after":{"doc":null,"id":2,"name":"bar"},"before":null}`},
+		{"update", `{"after":{"doc":null,"id":2,"name":"baz"},"before":{"doc":null,"id":2,"name":"bar"}}`},
+		{"delete", `{"after":null,"before":{"doc":null,"id":2,"name":"baz"}}`},
+	}
+
+	var ackFns []reader.AsyncAckFn
+	for _, exp := range expected {
+		msg, ackFn := readMySQLCDC(t, r)
+		require.Equal(t, 1, msg.Len())
+		assert.Equal(t, exp.body, string(msg.Get(0).Get()))
+		assert.Equal(t, exp.op, msg.Get(0).Metadata().Get("mysql_operation"))
+		assert.Equal(t, "testdb", msg.Get(0).Metadata().Get("mysql_schema"))
+		assert.Equal(t, "footable", msg.Get(0).Metadata().Get("mysql_table"))
+		ackFns = append(ackFns, ackFn)
+	}
+
+	// Only the first two changes are acknowledged, therefore the delete is
+	// consumed again after reconnecting.
+	require.NoError(t, ackFns[0](context.Background(), response.NewAck()))
+	require.NoError(t, ackFns[1](context.Background(), response.NewAck()))
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second*5))
+
+	r, err = reader.NewMySQLCDC(conf, posCache, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, r.ConnectWithContext(context.Background()))
+	defer func() {
+		r.CloseAsync()
+		assert.NoError(t, r.WaitForClose(time.Second*5))
+	}()
+
+	msg, ackFn = readMySQLCDC(t, r)
+	assert.Equal(t, expected[2].body, string(msg.Get(0).Get()))
+	assert.Equal(t, "delete", msg.Get(0).Metadata().Get("mysql_operation"))
+	require.NoError(t, ackFn(context.Background(), response.NewAck()))
+	binlogFile := msg.Get(0).Metadata().Get("mysql_binlog_file")
+
+	// Changes following a rotation of the binlog are consumed from the new
+	// binlog file by a running reader.
+	for _, stmt := range []string{
+		`FLUSH BINARY LOGS`,
+		`INSERT INTO footable (id, name, doc) VALUES (3, 'qux', NULL)`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	msg, ackFn = readMySQLCDC(t, r)
+	assert.Equal(t, `{"after":{"doc":null,"id":3,"name":"qux"},"before":null}`, string(msg.Get(0).Get()))
+	assert.NotEqual(t, binlogFile, msg.Get(0).Metadata().Get("mysql_binlog_file"))
+	require.NoError(t, ackFn(context.Background(), response.NewAck()))
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second*5))
+
+	// A reader that reconnects after further rotations resumes from the
+	// position committed within the rotated binlog file.
+	for _, stmt := range []string{
+		`FLUSH BINARY LOGS`,
+		`INSERT INTO footable (id, name, doc) VALUES (4, 'quz', NULL)`,
+		`FLUSH BINARY LOGS`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	r, err = reader.NewMySQLCDC(conf, posCache, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, r.ConnectWithContext(context.Background()))
+
+	msg, _ = readMySQLCDC(t, r)
+	assert.Equal(t, `{"after":{"doc":null,"id":4,"name":"quz"},"before":null}`, string(msg.Get(0).Get()))
+}
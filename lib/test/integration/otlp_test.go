@@ -0,0 +1,163 @@
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/ory/dockertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const otlpCollectorConfig = `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+      http:
+        endpoint: 0.0.0.0:4318
+
+exporters:
+  file:
+    path: /data/received.json
+
+service:
+  pipelines:
+    traces:
+      receivers: [ otlp ]
+      exporters: [ file ]
+    metrics:
+      receivers: [ otlp ]
+      exporters: [ file ]
+`
+
+func TestOTLPCollectorIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "benthos_otlp_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The collector runs as a non-root user and must be able to write to the
+	// mounted directory.
+	require.NoError(t, os.Chmod(dir, 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(otlpCollectorConfig), 0644))
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Second * 30
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "otel/opentelemetry-collector-contrib",
+		Tag:          "0.85.0",
+		ExposedPorts: []string{"4317/tcp", "4318/tcp"},
+		Mounts:       []string{dir + ":/data"},
+		Cmd:          []string{"--config=/data/config.yaml"},
+	})
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	defer func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+	}()
+	resource.Expire(900)
+
+	ports := map[string]string{
+		otlp.ProtocolGRPC: resource.GetPort("4317/tcp"),
+		otlp.ProtocolHTTP: resource.GetPort("4318/tcp"),
+	}
+	for protocol, port := range ports {
+		conf := otlp.NewConfig()
+		conf.Address = "localhost:" + port
+		conf.Protocol = protocol
+		conf.Timeout = "1s"
+
+		client, err := otlp.NewClient(conf)
+		require.NoError(t, err)
+
+		now := time.Now()
+		res := otlp.Resource{
+			Attributes: []otlp.KeyValue{{Key: "service.name", Value: "benthos"}},
+		}
+		scope := otlp.Scope{Name: "benthos"}
+
+		traces := otlp.EncodeTraces(res, scope, []otlp.Span{{
+			TraceID: [16]byte{1, 2, 3},
+			SpanID:  [8]byte{4, 5, 6},
+			Name:    protocol + "_span",
+			Kind:    otlp.SpanKindConsumer,
+			Start:   now.Add(-time.Second),
+			End:     now,
+			Attributes: []otlp.KeyValue{
+				{Key: "str", Value: "foo"},
+				{Key: "int", Value: int64(5)},
+			},
+			Events: []otlp.Event{{Time: now, Name: "log"}},
+			Status: otlp.StatusOK,
+		}})
+		metrics := otlp.EncodeMetrics(res, scope, []otlp.Metric{
+			{
+				Name:      protocol + "_counter",
+				Type:      otlp.MetricSum,
+				Monotonic: true,
+				Points:    []otlp.DataPoint{{Start: now.Add(-time.Second), Time: now, Value: 5}},
+			},
+			{
+				Name: protocol + "_timer",
+				Type: otlp.MetricHistogram,
+				Points: []otlp.DataPoint{{
+					Start: now.Add(-time.Second), Time: now,
+					Count: 3, Sum: 6, Min: 1, Max: 3,
+					Bounds:       []float64{2},
+					BucketCounts: []uint64{2, 1},
+				}},
+			},
+		})
+
+		// The collector may still be starting, therefore retry the first
+		// export until it is accepted.
+		require.NoError(t, pool.Retry(func() error {
+			return client.ExportTraces(context.Background(), traces)
+		}), protocol)
+		require.NoError(t, client.ExportMetrics(context.Background(), metrics), protocol)
+		require.NoError(t, client.Close())
+	}
+
+	expected := []string{
+		`"name":"grpc_span"`, `"name":"http_span"`,
+		`"name":"grpc_counter"`, `"name":"http_counter"`,
+		`"name":"grpc_timer"`, `"name":"http_timer"`,
+	}
+	var received string
+	assert.NoError(t, pool.Retry(func() error {
+		receivedBytes, err := ioutil.ReadFile(filepath.Join(dir, "received.json"))
+		if err != nil {
+			return err
+		}
+		received = string(receivedBytes)
+		for _, exp := range expected {
+			if !strings.Contains(received, exp) {
+				return fmt.Errorf("collector has not received %v", exp)
+			}
+		}
+		return nil
+	}), received)
+}
@@ -0,0 +1,118 @@
+// +build integration
+
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/parquet"
+	"github.com/ory/dockertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parquetReaderScript reads every Parquet file of a directory with pyarrow and
+// writes the schema and rows of each as a JSON file alongside it.
+const parquetReaderScript = `
+import glob, json
+import pyarrow.parquet as pq
+
+for path in glob.glob('/data/*.parquet'):
+    table = pq.read_table(path)
+    result = {
+        'schema': [[f.name, str(f.type), f.nullable] for f in table.schema],
+        'rows': table.to_pylist(),
+    }
+    with open(path[:-len('.parquet')] + '.json', 'w') as f:
+        json.dump(result, f, default=lambda b: b.decode('utf-8'))
+`
+
+func TestParquetPyArrowIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "benthos_parquet_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	columns := []parquet.Column{
+		{Name: "bool", Type: parquet.TypeBoolean},
+		{Name: "int32", Type: parquet.TypeInt32, Optional: true},
+		{Name: "int64", Type: parquet.TypeInt64},
+		{Name: "float", Type: parquet.TypeFloat, Optional: true},
+		{Name: "double", Type: parquet.TypeDouble},
+		{Name: "bytes", Type: parquet.TypeByteArray, Optional: true},
+		{Name: "utf8", Type: parquet.TypeUTF8, Optional: true},
+	}
+	rows := []map[string]interface{}{
+		{"bool": true, "int32": 1, "int64": 10, "float": 1.5, "double": 0.25, "bytes": "foo", "utf8": "bar"},
+		{"bool": false, "int64": -20, "double": 2},
+		{"bool": true, "int32": -3, "int64": 1 << 40, "float": -2, "double": 1e10, "bytes": "", "utf8": "baz"},
+	}
+
+	compressions := []string{"uncompressed", "snappy", "gzip", "zstd"}
+	for _, compression := range compressions {
+		enc, err := parquet.NewEncoder(columns, compression)
+		require.NoError(t, err)
+
+		file, err := enc.Encode(rows)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, compression+".parquet"), file, 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "read.py"), []byte(parquetReaderScript), 0644))
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Minute * 5
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "python",
+		Tag:        "3.8-slim",
+		Mounts:     []string{dir + ":/data"},
+		Cmd:        []string{"sh", "-c", "pip install --quiet pyarrow && python /data/read.py"},
+	})
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	defer func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+	}()
+	resource.Expire(900)
+
+	exitCode, err := pool.Client.WaitContainer(resource.Container.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, exitCode, "pyarrow failed to read the files")
+
+	exp := `{
+		"schema": [
+			["bool", "bool", false],
+			["int32", "int32", true],
+			["int64", "int64", false],
+			["float", "float", true],
+			["double", "double", false],
+			["bytes", "binary", true],
+			["utf8", "string", true]
+		],
+		"rows": [
+			{"bool": true, "int32": 1, "int64": 10, "float": 1.5, "double": 0.25, "bytes": "foo", "utf8": "bar"},
+			{"bool": false, "int32": null, "int64": -20, "float": null, "double": 2.0, "bytes": null, "utf8": null},
+			{"bool": true, "int32": -3, "int64": 1099511627776, "float": -2.0, "double": 1e10, "bytes": "", "utf8": "baz"}
+		]
+	}`
+	for _, compression := range compressions {
+		result, err := ioutil.ReadFile(filepath.Join(dir, compression+".json"))
+		require.NoError(t, err, compression)
+		assert.JSONEq(t, exp, string(result), compression)
+	}
+}
@@ -40,8 +40,9 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each tracer type.
 const (
-	TypeJaeger = "jaeger"
-	TypeNone   = "none"
+	TypeJaeger        = "jaeger"
+	TypeNone          = "none"
+	TypeOpenTelemetry = "open_telemetry"
 )
 
 //------------------------------------------------------------------------------
@@ -56,17 +57,19 @@ type Type interface {
 
 // Config is the all encompassing configuration struct for all tracer types.
 type Config struct {
-	Type   string       `json:"type" yaml:"type"`
-	Jaeger JaegerConfig `json:"jaeger" yaml:"jaeger"`
-	None   struct{}     `json:"none" yaml:"none"`
+	Type          string              `json:"type" yaml:"type"`
+	Jaeger        JaegerConfig        `json:"jaeger" yaml:"jaeger"`
+	None          struct{}            `json:"none" yaml:"none"`
+	OpenTelemetry OpenTelemetryConfig `json:"open_telemetry" yaml:"open_telemetry"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:   TypeNone,
-		Jaeger: NewJaegerConfig(),
-		None:   struct{}{},
+		Type:          TypeNone,
+		Jaeger:        NewJaegerConfig(),
+		None:          struct{}{},
+		OpenTelemetry: NewOpenTelemetryConfig(),
 	}
 }
 
@@ -0,0 +1,146 @@
+// +build !wasm
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+// The maximum number of spans to queue between exports, where spans are dropped
+// once the queue is full, and the maximum number of spans within each export.
+const (
+	otelMaxQueuedSpans = 8192
+	otelMaxExportSpans = 512
+)
+
+// OpenTelemetry is a tracer with the capability to export spans to an
+// OpenTelemetry collector.
+type OpenTelemetry struct {
+	client   *otlp.Client
+	resource otlp.Resource
+
+	spansMut sync.Mutex
+	spans    []otlp.Span
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewOpenTelemetry creates and returns a new OpenTelemetry object.
+func NewOpenTelemetry(config Config, opts ...func(Type)) (Type, error) {
+	conf := config.OpenTelemetry
+	if conf.SampleRatio < 0 || conf.SampleRatio > 1 {
+		return nil, errors.New("sample ratio must be between 0 and 1")
+	}
+
+	flushInterval, err := time.ParseDuration(conf.FlushInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flush interval '%s': %v", conf.FlushInterval, err)
+	}
+	if flushInterval <= 0 {
+		return nil, errors.New("flush interval must be greater than zero")
+	}
+
+	o := &OpenTelemetry{
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.resource.Attributes = append(o.resource.Attributes, otlp.KeyValue{
+		Key:   "service.name",
+		Value: conf.ServiceName,
+	})
+	tagKeys := make([]string, 0, len(conf.Tags))
+	for k := range conf.Tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		o.resource.Attributes = append(o.resource.Attributes, otlp.KeyValue{
+			Key:   k,
+			Value: conf.Tags[k],
+		})
+	}
+
+	if o.client, err = otlp.NewClient(conf.Config); err != nil {
+		return nil, err
+	}
+
+	opentracing.SetGlobalTracer(newOTelTracer(conf.SampleRatio, o.record))
+	go o.loop(flushInterval)
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *OpenTelemetry) record(span otlp.Span) {
+	o.spansMut.Lock()
+	if len(o.spans) < otelMaxQueuedSpans {
+		o.spans = append(o.spans, span)
+	}
+	o.spansMut.Unlock()
+}
+
+func (o *OpenTelemetry) loop(flushInterval time.Duration) {
+	defer close(o.closedChan)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.flush()
+		case <-o.closeChan:
+			o.flush()
+			return
+		}
+	}
+}
+
+func (o *OpenTelemetry) flush() {
+	o.spansMut.Lock()
+	spans := o.spans
+	o.spans = nil
+	o.spansMut.Unlock()
+
+	scope := otlp.Scope{Name: "benthos"}
+	for len(spans) > 0 {
+		batch := spans
+		if len(batch) > otelMaxExportSpans {
+			batch = batch[:otelMaxExportSpans]
+		}
+		spans = spans[len(batch):]
+
+		// Spans that fail to export are dropped as there is no means of
+		// reporting the error.
+		_ = o.client.ExportTraces(context.Background(), otlp.EncodeTraces(o.resource, scope, batch))
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Close stops the tracer after exporting any remaining spans.
+func (o *OpenTelemetry) Close() error {
+	o.closeOnce.Do(func() {
+		close(o.closeChan)
+	})
+	<-o.closedChan
+	return o.client.Close()
+}
+
+//------------------------------------------------------------------------------
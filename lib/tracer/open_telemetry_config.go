@@ -0,0 +1,68 @@
+package tracer
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOpenTelemetry] = TypeSpec{
+		constructor: NewOpenTelemetry,
+		Summary: `
+Send spans to an [OpenTelemetry collector](https://opentelemetry.io/docs/collector/)
+using the OpenTelemetry Protocol (OTLP) over gRPC or HTTP.`,
+		Description: `
+### Propagation
+
+Span contexts are propagated in the
+[W3C Trace Context](https://www.w3.org/TR/trace-context/) format. Inputs
+extract the ` + "`traceparent` and `tracestate`" + ` metadata keys of messages,
+such as Kafka headers or AMQP properties, and create their spans as children
+of the extracted span context. Outputs write the context of their own spans into
+the ` + "`traceparent` and `tracestate`" + ` metadata keys of messages, which
+are sent along with the message by outputs that support metadata. The
+` + "`http_server`" + ` input also extracts span contexts from the headers of
+requests.
+
+When a span context is extracted its sampling decision is honoured, otherwise
+a ` + "`sample_ratio`" + ` of traces are sampled.
+
+Spans are exported in batches every ` + "`flush_interval`" + `, and spans that
+cannot be exported are dropped.`,
+		FieldSpecs: append(otlp.FieldSpecs(),
+			docs.FieldCommon("service_name", "A name to provide for this service."),
+			docs.FieldAdvanced("tags", "A map of attributes to add to the resource of all spans.", map[string]string{
+				"deployment.environment": "production",
+			}),
+			docs.FieldAdvanced("sample_ratio", "The ratio of traces to sample when a span context is not extracted from a message, between 0 and 1."),
+			docs.FieldAdvanced("flush_interval", "The period of time between each export of spans."),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetryConfig is config for the OpenTelemetry tracer type.
+type OpenTelemetryConfig struct {
+	otlp.Config   `json:",inline" yaml:",inline"`
+	ServiceName   string            `json:"service_name" yaml:"service_name"`
+	Tags          map[string]string `json:"tags" yaml:"tags"`
+	SampleRatio   float64           `json:"sample_ratio" yaml:"sample_ratio"`
+	FlushInterval string            `json:"flush_interval" yaml:"flush_interval"`
+}
+
+// NewOpenTelemetryConfig creates an OpenTelemetryConfig struct with default
+// values.
+func NewOpenTelemetryConfig() OpenTelemetryConfig {
+	return OpenTelemetryConfig{
+		Config:        otlp.NewConfig(),
+		ServiceName:   "benthos",
+		Tags:          map[string]string{},
+		SampleRatio:   1.0,
+		FlushInterval: "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,497 @@
+package tracer
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+// W3C Trace Context keys used for propagating span contexts.
+const (
+	traceParentKey = "traceparent"
+	traceStateKey  = "tracestate"
+)
+
+// otelSpanContext is the context of a span that is propagated in the W3C Trace
+// Context format.
+type otelSpanContext struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	sampled    bool
+	traceState string
+	baggage    map[string]string
+}
+
+// ForeachBaggageItem calls a handler for each baggage item until the handler
+// returns false.
+func (c otelSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+func (c otelSpanContext) traceParent() string {
+	flags := "00"
+	if c.sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(c.traceID[:]) + "-" + hex.EncodeToString(c.spanID[:]) + "-" + flags
+}
+
+func parseTraceParent(v string) (otelSpanContext, error) {
+	var c otelSpanContext
+
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 {
+		return c, fmt.Errorf("expected at least four fields, found %v", len(parts))
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 {
+		return c, fmt.Errorf("invalid version: %v", parts[0])
+	}
+	if version[0] == 0xff {
+		return c, errors.New("invalid version: ff")
+	}
+	if version[0] == 0 && len(parts) != 4 {
+		return c, fmt.Errorf("expected four fields, found %v", len(parts))
+	}
+
+	if len(parts[1]) != 32 {
+		return c, fmt.Errorf("invalid trace ID: %v", parts[1])
+	}
+	if _, err = hex.Decode(c.traceID[:], []byte(parts[1])); err != nil || c.traceID == [16]byte{} {
+		return c, fmt.Errorf("invalid trace ID: %v", parts[1])
+	}
+
+	if len(parts[2]) != 16 {
+		return c, fmt.Errorf("invalid span ID: %v", parts[2])
+	}
+	if _, err = hex.Decode(c.spanID[:], []byte(parts[2])); err != nil || c.spanID == [8]byte{} {
+		return c, fmt.Errorf("invalid span ID: %v", parts[2])
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return c, fmt.Errorf("invalid flags: %v", parts[3])
+	}
+	c.sampled = flags[0]&1 == 1
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+// otelTracer is an opentracing.Tracer that creates spans with W3C Trace Context
+// identifiers and passes sampled spans to a func once they are finished.
+type otelTracer struct {
+	sampleRatio float64
+	onFinish    func(otlp.Span)
+
+	randMut sync.Mutex
+	rand    *rand.Rand
+}
+
+func newOTelTracer(sampleRatio float64, onFinish func(otlp.Span)) *otelTracer {
+	var seed int64
+	var seedBytes [8]byte
+	if _, err := crand.Read(seedBytes[:]); err == nil {
+		seed = int64(binary.LittleEndian.Uint64(seedBytes[:]))
+	} else {
+		seed = time.Now().UnixNano()
+	}
+	return &otelTracer{
+		sampleRatio: sampleRatio,
+		onFinish:    onFinish,
+		rand:        rand.New(rand.NewSource(seed)),
+	}
+}
+
+// randomID fills a slice with random bytes that are not all zero.
+func (t *otelTracer) randomID(b []byte) {
+	t.randMut.Lock()
+	defer t.randMut.Unlock()
+	for {
+		t.rand.Read(b)
+		if !isZero(b) {
+			return
+		}
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldSample makes a sampling decision for a new trace from its ID, in the
+// same way as the OpenTelemetry trace ID ratio sampler.
+func (t *otelTracer) shouldSample(traceID [16]byte) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	if t.sampleRatio <= 0 {
+		return false
+	}
+	bound := uint64(t.sampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < bound
+}
+
+// StartSpan creates a new span, which is a child of the first referenced span
+// context created by this tracer.
+func (t *otelTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	sso := opentracing.StartSpanOptions{}
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+
+	s := &otelSpan{
+		tracer: t,
+		name:   operationName,
+		start:  sso.StartTime,
+		attrs:  map[string]interface{}{},
+	}
+	if s.start.IsZero() {
+		s.start = time.Now()
+	}
+
+	var parent *otelSpanContext
+	for _, ref := range sso.References {
+		if pCtx, ok := ref.ReferencedContext.(otelSpanContext); ok {
+			parent = &pCtx
+			break
+		}
+	}
+
+	if parent != nil {
+		s.ctx = otelSpanContext{
+			traceID:    parent.traceID,
+			sampled:    parent.sampled,
+			traceState: parent.traceState,
+			baggage:    parent.baggage,
+		}
+		s.parentID = parent.spanID
+	} else {
+		t.randomID(s.ctx.traceID[:])
+		s.ctx.sampled = t.shouldSample(s.ctx.traceID)
+	}
+	t.randomID(s.ctx.spanID[:])
+
+	for k, v := range sso.Tags {
+		s.SetTag(k, v)
+	}
+	return s
+}
+
+func textMapCarrier(format interface{}, carrier interface{}) (interface{}, error) {
+	switch format {
+	case opentracing.TextMap, opentracing.HTTPHeaders, tracing.MetadataFormat:
+		return carrier, nil
+	}
+	return nil, opentracing.ErrUnsupportedFormat
+}
+
+// Inject writes a span context into a carrier in the W3C Trace Context format.
+func (t *otelTracer) Inject(sm opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	spanCtx, ok := sm.(otelSpanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	c, err := textMapCarrier(format, carrier)
+	if err != nil {
+		return err
+	}
+	writer, ok := c.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	writer.Set(traceParentKey, spanCtx.traceParent())
+	if len(spanCtx.traceState) > 0 {
+		writer.Set(traceStateKey, spanCtx.traceState)
+	}
+	return nil
+}
+
+// Extract reads a span context from a carrier in the W3C Trace Context format.
+func (t *otelTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	c, err := textMapCarrier(format, carrier)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := c.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var traceParent, traceState string
+	if err = reader.ForeachKey(func(k, v string) error {
+		switch strings.ToLower(k) {
+		case traceParentKey:
+			traceParent = v
+		case traceStateKey:
+			traceState = v
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if len(traceParent) == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	spanCtx, err := parseTraceParent(traceParent)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	spanCtx.traceState = traceState
+	return spanCtx, nil
+}
+
+//------------------------------------------------------------------------------
+
+// otelSpan is an opentracing.Span created by an otelTracer.
+type otelSpan struct {
+	tracer *otelTracer
+
+	mut      sync.Mutex
+	ctx      otelSpanContext
+	parentID [8]byte
+	name     string
+	kind     otlp.SpanKind
+	start    time.Time
+	attrs    map[string]interface{}
+	events   []otlp.Event
+	status   otlp.StatusCode
+	finished bool
+}
+
+// Finish the span.
+func (s *otelSpan) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions finishes the span with custom options.
+func (s *otelSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	end := opts.FinishTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	for _, lr := range opts.LogRecords {
+		s.logFieldsWithTimestamp(lr.Timestamp, lr.Fields...)
+	}
+	for _, ld := range opts.BulkLogData {
+		lr := ld.ToLogRecord()
+		s.logFieldsWithTimestamp(lr.Timestamp, lr.Fields...)
+	}
+
+	s.mut.Lock()
+	if s.finished {
+		s.mut.Unlock()
+		return
+	}
+	s.finished = true
+	if !s.ctx.sampled {
+		s.mut.Unlock()
+		return
+	}
+
+	span := otlp.Span{
+		TraceID:      s.ctx.traceID,
+		SpanID:       s.ctx.spanID,
+		ParentSpanID: s.parentID,
+		TraceState:   s.ctx.traceState,
+		Name:         s.name,
+		Kind:         s.kind,
+		Start:        s.start,
+		End:          end,
+		Attributes:   make([]otlp.KeyValue, 0, len(s.attrs)),
+		Events:       s.events,
+		Status:       s.status,
+	}
+	for k, v := range s.attrs {
+		span.Attributes = append(span.Attributes, otlp.KeyValue{Key: k, Value: v})
+	}
+	s.mut.Unlock()
+
+	sort.Slice(span.Attributes, func(i, j int) bool {
+		return span.Attributes[i].Key < span.Attributes[j].Key
+	})
+	s.tracer.onFinish(span)
+}
+
+// Context returns the context of the span.
+func (s *otelSpan) Context() opentracing.SpanContext {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.ctx
+}
+
+// SetOperationName sets the name of the span.
+func (s *otelSpan) SetOperationName(operationName string) opentracing.Span {
+	s.mut.Lock()
+	s.name = operationName
+	s.mut.Unlock()
+	return s
+}
+
+func otelAttrValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case int8:
+		return int64(t)
+	case int16:
+		return int64(t)
+	case int32:
+		return int64(t)
+	case uint8:
+		return int64(t)
+	case uint16:
+		return int64(t)
+	case uint32:
+		return int64(t)
+	case uint:
+		return int64(t)
+	case uint64:
+		return int64(t)
+	case float32:
+		return float64(t)
+	case error:
+		return t.Error()
+	}
+	return v
+}
+
+// SetTag adds a tag to the span, where the span.kind and error tags set the
+// kind and status of the span respectively.
+func (s *otelSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	switch key {
+	case string(ext.SpanKind):
+		switch fmt.Sprintf("%v", value) {
+		case string(ext.SpanKindRPCServerEnum):
+			s.kind = otlp.SpanKindServer
+		case string(ext.SpanKindRPCClientEnum):
+			s.kind = otlp.SpanKindClient
+		case string(ext.SpanKindProducerEnum):
+			s.kind = otlp.SpanKindProducer
+		case string(ext.SpanKindConsumerEnum):
+			s.kind = otlp.SpanKindConsumer
+		}
+		return s
+	case string(ext.Error):
+		if isErr, ok := value.(bool); ok {
+			if isErr {
+				s.status = otlp.StatusError
+			} else {
+				s.status = otlp.StatusUnset
+			}
+			return s
+		}
+	}
+	s.attrs[key] = otelAttrValue(value)
+	return s
+}
+
+func (s *otelSpan) logFieldsWithTimestamp(ts time.Time, fields ...log.Field) {
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	event := otlp.Event{
+		Time: ts,
+		Name: "log",
+	}
+	for _, f := range fields {
+		if f.Key() == "event" {
+			event.Name = fmt.Sprintf("%v", f.Value())
+			continue
+		}
+		event.Attributes = append(event.Attributes, otlp.KeyValue{
+			Key:   f.Key(),
+			Value: otelAttrValue(f.Value()),
+		})
+	}
+	s.mut.Lock()
+	s.events = append(s.events, event)
+	s.mut.Unlock()
+}
+
+// LogFields adds an event to the span.
+func (s *otelSpan) LogFields(fields ...log.Field) {
+	s.logFieldsWithTimestamp(time.Time{}, fields...)
+}
+
+// LogKV adds an event to the span from alternating keys and values.
+func (s *otelSpan) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+// SetBaggageItem sets a baggage item on the span, which is inherited by its
+// children.
+func (s *otelSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	baggage := make(map[string]string, len(s.ctx.baggage)+1)
+	for k, v := range s.ctx.baggage {
+		baggage[k] = v
+	}
+	baggage[restrictedKey] = value
+	s.ctx.baggage = baggage
+	return s
+}
+
+// BaggageItem returns a baggage item of the span.
+func (s *otelSpan) BaggageItem(restrictedKey string) string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.ctx.baggage[restrictedKey]
+}
+
+// Tracer returns the tracer that created the span.
+func (s *otelSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent is deprecated.
+func (s *otelSpan) LogEvent(event string) {
+	s.LogFields(log.String("event", event))
+}
+
+// LogEventWithPayload is deprecated.
+func (s *otelSpan) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String("event", event), log.Object("payload", payload))
+}
+
+// Log is deprecated.
+func (s *otelSpan) Log(ld opentracing.LogData) {
+	lr := ld.ToLogRecord()
+	s.logFieldsWithTimestamp(lr.Timestamp, lr.Fields...)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,215 @@
+// +build !wasm
+
+package tracer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/util/otlp"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type finishedSpans struct {
+	sync.Mutex
+	spans []otlp.Span
+}
+
+func (f *finishedSpans) record(s otlp.Span) {
+	f.Lock()
+	f.spans = append(f.spans, s)
+	f.Unlock()
+}
+
+func TestOpenTelemetryPropagation(t *testing.T) {
+	finished := &finishedSpans{}
+	tracer := newOTelTracer(1, finished.record)
+
+	root := tracer.StartSpan("root")
+	ext.SpanKindProducer.Set(root)
+	ext.Error.Set(root, true)
+	root.SetTag("foo", int32(5))
+
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(root.Context(), opentracing.TextMap, carrier))
+	traceParent := carrier[traceParentKey]
+	require.Len(t, traceParent, 55)
+	assert.True(t, strings.HasPrefix(traceParent, "00-"))
+	assert.True(t, strings.HasSuffix(traceParent, "-01"))
+
+	spanCtx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{
+		"Traceparent": []string{traceParent},
+		"Tracestate":  []string{"foo=bar"},
+	}))
+	require.NoError(t, err)
+
+	child := tracer.StartSpan("child", opentracing.ChildOf(spanCtx))
+	child.LogKV("event", "thing", "bar", "baz")
+	child.Finish()
+	root.Finish()
+	root.Finish()
+
+	require.Len(t, finished.spans, 2)
+	childSpan, rootSpan := finished.spans[0], finished.spans[1]
+
+	assert.Equal(t, "root", rootSpan.Name)
+	assert.Equal(t, otlp.SpanKindProducer, rootSpan.Kind)
+	assert.Equal(t, otlp.StatusError, rootSpan.Status)
+	assert.Equal(t, []otlp.KeyValue{{Key: "foo", Value: int64(5)}}, rootSpan.Attributes)
+	assert.Equal(t, [8]byte{}, rootSpan.ParentSpanID)
+
+	assert.Equal(t, "child", childSpan.Name)
+	assert.Equal(t, rootSpan.TraceID, childSpan.TraceID)
+	assert.Equal(t, rootSpan.SpanID, childSpan.ParentSpanID)
+	assert.NotEqual(t, rootSpan.SpanID, childSpan.SpanID)
+	assert.Equal(t, "foo=bar", childSpan.TraceState)
+	require.Len(t, childSpan.Events, 1)
+	assert.Equal(t, "thing", childSpan.Events[0].Name)
+	assert.Equal(t, []otlp.KeyValue{{Key: "bar", Value: "baz"}}, childSpan.Events[0].Attributes)
+}
+
+func TestOpenTelemetryUnsampled(t *testing.T) {
+	finished := &finishedSpans{}
+	tracer := newOTelTracer(1, finished.record)
+
+	spanCtx, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		traceParentKey: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+	})
+	require.NoError(t, err)
+
+	child := tracer.StartSpan("child", opentracing.ChildOf(spanCtx))
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(child.Context(), opentracing.TextMap, carrier))
+	assert.True(t, strings.HasPrefix(carrier[traceParentKey], "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	assert.True(t, strings.HasSuffix(carrier[traceParentKey], "-00"))
+	child.Finish()
+
+	tracer = newOTelTracer(0, finished.record)
+	tracer.StartSpan("root").Finish()
+
+	assert.Empty(t, finished.spans)
+}
+
+func TestOpenTelemetryExtractErrors(t *testing.T) {
+	tracer := newOTelTracer(1, func(otlp.Span) {})
+
+	_, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{})
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = tracer.Extract(opentracing.Binary, &bytes.Buffer{})
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, err)
+
+	for _, v := range []string{
+		"nope",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	} {
+		_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+			traceParentKey: v,
+		})
+		assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, v)
+	}
+
+	// Future versions may append fields.
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		traceParentKey: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo",
+	})
+	assert.NoError(t, err)
+}
+
+func TestOpenTelemetryMetadataPropagation(t *testing.T) {
+	finished := &finishedSpans{}
+	tracer := newOTelTracer(1, finished.record)
+
+	prev := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(prev)
+
+	msg := message.New([][]byte{[]byte("foo")})
+	spans := tracing.CreateChildSpans("output_foo", msg)
+	injected := tracing.InjectSpans(spans, msg)
+	spans[0].Finish()
+
+	assert.Equal(t, "", msg.Get(0).Metadata().Get(traceParentKey))
+	assert.NotEqual(t, "", injected.Get(0).Metadata().Get(traceParentKey))
+
+	tracing.InitSpans("input_bar", injected)
+	tracing.FinishSpans(injected)
+
+	require.Len(t, finished.spans, 2)
+	assert.Equal(t, finished.spans[0].TraceID, finished.spans[1].TraceID)
+	assert.Equal(t, finished.spans[0].SpanID, finished.spans[1].ParentSpanID)
+}
+
+func TestOpenTelemetryExport(t *testing.T) {
+	reqChan := make(chan []byte, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		reqChan <- body
+	}))
+	defer ts.Close()
+
+	prev := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(prev)
+
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = otlp.ProtocolHTTP
+	conf.OpenTelemetry.Address = strings.TrimPrefix(ts.URL, "http://")
+	conf.OpenTelemetry.ServiceName = "foo_service"
+	conf.OpenTelemetry.FlushInterval = "1h"
+
+	tr, err := New(conf)
+	require.NoError(t, err)
+
+	opentracing.StartSpan("foo_span").Finish()
+	require.NoError(t, tr.Close())
+
+	select {
+	case body := <-reqChan:
+		assert.Contains(t, string(body), "foo_service")
+		assert.Contains(t, string(body), "foo_span")
+	default:
+		t.Fatal("expected spans to be exported on close")
+	}
+}
+
+func TestOpenTelemetryConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.SampleRatio = 2
+	_, err := New(conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.FlushInterval = "nope"
+	_, err = New(conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = "nope"
+	_, err = New(conf)
+	assert.Error(t, err)
+}
@@ -0,0 +1,14 @@
+// +build wasm
+
+package tracer
+
+import "errors"
+
+//------------------------------------------------------------------------------
+
+// NewOpenTelemetry creates and returns a new OpenTelemetry object.
+func NewOpenTelemetry(config Config, opts ...func(Type)) (Type, error) {
+	return nil, errors.New("OpenTelemetry tracing is disabled in WASM builds")
+}
+
+//------------------------------------------------------------------------------
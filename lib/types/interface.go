@@ -34,6 +34,50 @@ type Cache interface {
 	Closable
 }
 
+// CacheTTLItem is a value to be cached along with an optional TTL, where a nil
+// TTL results in the default TTL of the cache being used.
+type CacheTTLItem struct {
+	Value []byte
+	TTL   *time.Duration
+}
+
+// CacheExtended is a Cache that also supports per-call TTL overrides, atomic
+// operations and the retrieval of multiple keys in a single call. For each
+// method a nil TTL results in the default TTL of the cache being used.
+type CacheExtended interface {
+	// SetWithTTL attempts to set the value of a key with a TTL, returns an
+	// error if the command fails.
+	SetWithTTL(key string, value []byte, ttl *time.Duration) error
+
+	// SetMultiWithTTL attempts to set the value of multiple keys, each with
+	// their own TTL, returns an error if any of the keys fail.
+	SetMultiWithTTL(items map[string]CacheTTLItem) error
+
+	// AddWithTTL attempts to set the value of a key with a TTL only if the key
+	// does not already exist, returns an error if the key already exists or if
+	// the command fails.
+	AddWithTTL(key string, value []byte, ttl *time.Duration) error
+
+	// Incr atomically adds a delta, which may be negative, to the integer value
+	// of a key and returns the result. A key that does not exist is created
+	// with the TTL and a starting value of zero, the TTL of an existing key is
+	// not changed.
+	Incr(key string, delta int64, ttl *time.Duration) (int64, error)
+
+	// CompareAndSwap atomically sets the value of a key only if its current
+	// value matches old, where a nil old value matches only a key that does
+	// not exist. Returns ErrKeyNotFound, ErrKeyAlreadyExists or
+	// ErrKeyValueMismatch when the current value does not match.
+	CompareAndSwap(key string, old, new []byte, ttl *time.Duration) error
+
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys, keys that do not exist are omitted from the result. Returns an
+	// error if the command fails.
+	GetMulti(keys ...string) (map[string][]byte, error)
+
+	Cache
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
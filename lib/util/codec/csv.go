@@ -0,0 +1,91 @@
+package codec
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func csvDelimiter(str string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(str)
+	if r == utf8.RuneError || size != len(str) {
+		return 0, fmt.Errorf("csv delimiter must be a single character, found: %v", str)
+	}
+	return r, nil
+}
+
+func csvReaderCtor(delim rune, header bool) ReaderConstructor {
+	return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+		scanner := csv.NewReader(r)
+		scanner.Comma = delim
+		scanner.ReuseRecord = true
+		return &csvReader{
+			r:       r,
+			scanner: scanner,
+			acks:    newAckTracker(ackFn),
+			header:  header,
+		}, nil
+	}
+}
+
+// csvReader emits each row of a CSV stream as a structured message. When a
+// header is expected the first row provides the field names of an object,
+// otherwise each row is an array of strings.
+type csvReader struct {
+	r       io.ReadCloser
+	scanner *csv.Reader
+	acks    *ackTracker
+
+	header bool
+	names  []string
+}
+
+func (c *csvReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	record, err := c.scanner.Read()
+	if err == nil && c.header && c.names == nil {
+		c.names = append([]string{}, record...)
+		c.scanner.FieldsPerRecord = len(c.names)
+		record, err = c.scanner.Read()
+	}
+	if err != nil {
+		if err == io.EOF {
+			c.acks.finish(ctx)
+		}
+		return nil, nil, err
+	}
+
+	var doc interface{}
+	if c.header {
+		obj := make(map[string]interface{}, len(record))
+		for i, v := range record {
+			obj[c.names[i]] = v
+		}
+		doc = obj
+	} else {
+		arr := make([]interface{}, len(record))
+		for i, v := range record {
+			arr[i] = v
+		}
+		doc = arr
+	}
+
+	part := message.NewPart(nil)
+	if err = part.SetJSON(doc); err != nil {
+		return nil, nil, err
+	}
+	return []types.Part{part}, c.acks.next(), nil
+}
+
+func (c *csvReader) Close(ctx context.Context) error {
+	c.acks.close(ctx)
+	return c.r.Close()
+}
+
+//------------------------------------------------------------------------------
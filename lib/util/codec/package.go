@@ -0,0 +1,4 @@
+// Package codec implements readers that consume streams of bytes, such as
+// files, objects and sockets, and decode them into messages incrementally
+// without loading the full stream into memory.
+package codec
@@ -0,0 +1,343 @@
+package codec
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+	"github.com/klauspost/compress/zstd"
+)
+
+//------------------------------------------------------------------------------
+
+// ReaderDocs is a field spec describing a codec field of an input.
+var ReaderDocs = docs.FieldCommon(
+	"codec", `
+The way in which the bytes of consumed data are converted into messages. Codecs
+are useful for specifying how large files or continuous streams of data might
+be processed in small chunks rather than loading it all in memory.
+
+Available codecs are:
+
+- `+"`all-bytes`"+`: Consume the entire stream as a single message.
+- `+"`lines`"+`: Consume each line, delimited by a line feed, as a message.
+- `+"`delim:x`"+`: Consume messages delimited by a custom string `+"`x`"+`.
+- `+"`length-prefixed`"+`: Consume messages that are each prefixed with a four
+  byte big endian unsigned integer containing their length.
+- `+"`regex:x`"+`: Consume groups of lines as a single message, where a new
+  message begins with each line that matches the regular expression `+"`x`"+`.
+- `+"`csv`"+`: Consume structured rows of a CSV stream as JSON objects, using
+  the first row as the field names. A custom single character delimiter can be
+  specified with `+"`csv:x`"+`.
+- `+"`csv-noheader`"+`: Consume structured rows of a CSV stream as JSON arrays
+  of strings. A custom single character delimiter can be specified with
+  `+"`csv-noheader:x`"+`.
+- `+"`tar`"+`: Consume each file of a tar archive as a message, setting the
+  metadata field `+"`tar_name`"+` to the name of the file.
+
+Any codec can be prefixed with `+"`gzip/`"+` or `+"`zstd/`"+` in order to
+decompress the stream before it is decoded, e.g. `+"`gzip/csv`"+`. The codecs
+`+"`lines`"+` and `+"`delim:x`"+` can be suffixed with `+"`/multipart`"+`, in
+which case consecutive messages are consumed as parts of a batch, and an empty
+message indicates the end of the batch.`,
+	"lines", "delim:\t", "delim:foobar", "gzip/csv", "zstd/lines", "regex:^\\d{4}-\\d{2}-\\d{2}", "lines/multipart",
+)
+
+//------------------------------------------------------------------------------
+
+// ReaderAckFn is a function provided to a reader codec that it should call once
+// the underlying byte stream has been fully consumed and all resulting messages
+// have been acknowledged. The error is non-nil if any message failed to be
+// delivered, or if the reader was closed before the stream was fully consumed.
+type ReaderAckFn func(context.Context, error) error
+
+// Reader is a codec type that reads message parts from a byte stream.
+type Reader interface {
+	// Next returns the next message parts of the stream along with a function
+	// to acknowledge them. Returns io.EOF once the stream has been exhausted.
+	Next(context.Context) ([]types.Part, ReaderAckFn, error)
+
+	// Close the reader and the underlying byte stream. If the stream was not
+	// fully consumed then it is acknowledged with ErrNotFullyConsumed.
+	Close(context.Context) error
+}
+
+// ReaderConstructor creates a reader from a byte stream, where the provided
+// acknowledgement function is called once the stream has been consumed and all
+// messages read from it have been acknowledged.
+type ReaderConstructor func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error)
+
+// ReaderConfig is a general configuration struct that covers all reader codecs.
+type ReaderConfig struct {
+	MaxScanTokenSize int
+}
+
+// NewReaderConfig creates a reader configuration with default values.
+func NewReaderConfig() ReaderConfig {
+	return ReaderConfig{
+		MaxScanTokenSize: bufio.MaxScanTokenSize,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type streamWrapper func(r io.ReadCloser) (io.ReadCloser, error)
+
+type wrappedReadCloser struct {
+	io.Reader
+	closeFns []func() error
+}
+
+func (w *wrappedReadCloser) Close() error {
+	var err error
+	for _, fn := range w.closeFns {
+		if cerr := fn(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func gzipWrapper(r io.ReadCloser) (io.ReadCloser, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to read gzip header: %v", err)
+	}
+	return &wrappedReadCloser{
+		Reader:   gr,
+		closeFns: []func() error{gr.Close, r.Close},
+	}, nil
+}
+
+func zstdWrapper(r io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &wrappedReadCloser{
+		Reader: zr,
+		closeFns: []func() error{
+			func() error {
+				zr.Close()
+				return nil
+			},
+			r.Close,
+		},
+	}, nil
+}
+
+// GetReader returns a constructor that creates reader codecs.
+func GetReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
+	var wrappers []streamWrapper
+	for {
+		if strings.HasPrefix(codec, "gzip/") {
+			codec = strings.TrimPrefix(codec, "gzip/")
+			wrappers = append(wrappers, gzipWrapper)
+		} else if strings.HasPrefix(codec, "zstd/") {
+			codec = strings.TrimPrefix(codec, "zstd/")
+			wrappers = append(wrappers, zstdWrapper)
+		} else {
+			break
+		}
+	}
+
+	ctor, err := getBaseReader(codec, conf)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappers) == 0 {
+		return ctor, nil
+	}
+	return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+		for _, w := range wrappers {
+			var err error
+			if r, err = w(r); err != nil {
+				return nil, err
+			}
+		}
+		return ctor(r, ackFn)
+	}, nil
+}
+
+func getBaseReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
+	multipart := false
+	if strings.HasSuffix(codec, "/multipart") {
+		codec = strings.TrimSuffix(codec, "/multipart")
+		multipart = true
+		if codec != "lines" && !strings.HasPrefix(codec, "delim:") {
+			return nil, fmt.Errorf("codec '%v' does not support multipart", codec)
+		}
+	}
+
+	switch codec {
+	case "all-bytes":
+		return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+			return &allBytesReader{r: r, acks: newAckTracker(ackFn)}, nil
+		}, nil
+	case "lines":
+		return delimReaderCtor(conf, "\n", multipart), nil
+	case "length-prefixed":
+		return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+			return newScannerReader(conf, r, ackFn, lengthPrefixedSplit, false), nil
+		}, nil
+	case "tar":
+		return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+			return newTarReader(r, ackFn), nil
+		}, nil
+	case "csv":
+		return csvReaderCtor(',', true), nil
+	case "csv-noheader":
+		return csvReaderCtor(',', false), nil
+	}
+
+	switch {
+	case strings.HasPrefix(codec, "delim:"):
+		delim := strings.TrimPrefix(codec, "delim:")
+		if len(delim) == 0 {
+			return nil, errors.New("delim codec requires a non-empty delimiter")
+		}
+		return delimReaderCtor(conf, delim, multipart), nil
+	case strings.HasPrefix(codec, "regex:"):
+		re, err := regexp.Compile(strings.TrimPrefix(codec, "regex:"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile regex codec expression: %v", err)
+		}
+		return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+			return newRegexReader(conf, r, ackFn, re), nil
+		}, nil
+	case strings.HasPrefix(codec, "csv:"):
+		delim, err := csvDelimiter(strings.TrimPrefix(codec, "csv:"))
+		if err != nil {
+			return nil, err
+		}
+		return csvReaderCtor(delim, true), nil
+	case strings.HasPrefix(codec, "csv-noheader:"):
+		delim, err := csvDelimiter(strings.TrimPrefix(codec, "csv-noheader:"))
+		if err != nil {
+			return nil, err
+		}
+		return csvReaderCtor(delim, false), nil
+	}
+	return nil, fmt.Errorf("codec was not recognised: %v", codec)
+}
+
+//------------------------------------------------------------------------------
+
+// ackTracker calls the acknowledgement function of a byte stream once the
+// stream has been consumed and all messages read from it have been
+// acknowledged.
+type ackTracker struct {
+	mut      sync.Mutex
+	pending  int
+	finished bool
+	acked    bool
+	err      error
+
+	sourceAck ReaderAckFn
+}
+
+func newAckTracker(fn ReaderAckFn) *ackTracker {
+	return &ackTracker{sourceAck: fn}
+}
+
+func (a *ackTracker) ackSource(ctx context.Context) error {
+	if a.acked || !a.finished || a.pending > 0 {
+		return nil
+	}
+	a.acked = true
+	return a.sourceAck(ctx, a.err)
+}
+
+// next returns an acknowledgement function for a message read from the stream.
+func (a *ackTracker) next() ReaderAckFn {
+	a.mut.Lock()
+	a.pending++
+	a.mut.Unlock()
+
+	var once sync.Once
+	return func(ctx context.Context, err error) error {
+		var ackErr error
+		once.Do(func() {
+			a.mut.Lock()
+			defer a.mut.Unlock()
+
+			a.pending--
+			if err != nil {
+				a.err = err
+			}
+			ackErr = a.ackSource(ctx)
+		})
+		return ackErr
+	}
+}
+
+// finish marks the stream as fully consumed.
+func (a *ackTracker) finish(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.finished = true
+	return a.ackSource(ctx)
+}
+
+// ErrNotFullyConsumed is the error provided to the acknowledgement function of
+// a byte stream when the reader is closed before the stream was consumed.
+var ErrNotFullyConsumed = errors.New("the reader was closed before the stream was fully consumed")
+
+// close marks the stream as finished, and if it was not fully consumed the
+// source is acknowledged with an error once all messages read from it have been
+// acknowledged.
+func (a *ackTracker) close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		a.finished = true
+		if a.err == nil {
+			a.err = ErrNotFullyConsumed
+		}
+	}
+	return a.ackSource(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+type allBytesReader struct {
+	r        io.ReadCloser
+	acks     *ackTracker
+	consumed bool
+}
+
+func (a *allBytesReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	if a.consumed {
+		return nil, nil, io.EOF
+	}
+	a.consumed = true
+
+	b, err := ioutil.ReadAll(a.r)
+	if err != nil {
+		return nil, nil, err
+	}
+	ackFn := a.acks.next()
+	a.acks.finish(ctx)
+	return []types.Part{message.NewPart(b)}, ackFn, nil
+}
+
+func (a *allBytesReader) Close(ctx context.Context) error {
+	a.acks.close(ctx)
+	return a.r.Close()
+}
+
+//------------------------------------------------------------------------------
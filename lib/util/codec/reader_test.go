@@ -0,0 +1,348 @@
+package codec
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+// readAll consumes a codec until EOF, acknowledging each message, and returns
+// the contents of each batch along with the error passed to the stream ack.
+func readAll(t *testing.T, codec string, conf ReaderConfig, input []byte) ([][]string, error) {
+	t.Helper()
+
+	ctor, err := GetReader(codec, conf)
+	require.NoError(t, err)
+
+	acked := false
+	var ackErr error
+	src := &closeRecorder{Reader: bytes.NewReader(input)}
+	r, err := ctor(src, func(ctx context.Context, err error) error {
+		assert.False(t, acked, "stream acknowledged more than once")
+		acked, ackErr = true, err
+		return nil
+	})
+	require.NoError(t, err)
+
+	var batches [][]string
+	for {
+		parts, ackFn, err := r.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		var batch []string
+		for _, p := range parts {
+			batch = append(batch, string(p.Get()))
+		}
+		batches = append(batches, batch)
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	assert.True(t, acked, "stream was not acknowledged")
+
+	require.NoError(t, r.Close(context.Background()))
+	assert.True(t, src.closed)
+	return batches, ackErr
+}
+
+func TestReaderCodecs(t *testing.T) {
+	tests := map[string]struct {
+		codec  string
+		input  string
+		output [][]string
+	}{
+		"all-bytes": {
+			codec:  "all-bytes",
+			input:  "foo\nbar\n",
+			output: [][]string{{"foo\nbar\n"}},
+		},
+		"lines": {
+			codec:  "lines",
+			input:  "foo\n\nbar\nbaz",
+			output: [][]string{{"foo"}, {"bar"}, {"baz"}},
+		},
+		"lines empty": {
+			codec: "lines",
+			input: "",
+		},
+		"lines multipart": {
+			codec:  "lines/multipart",
+			input:  "foo\nbar\n\nbaz\n\n\nbuz",
+			output: [][]string{{"foo", "bar"}, {"baz"}, {"buz"}},
+		},
+		"custom delim": {
+			codec:  "delim:||",
+			input:  "foo||bar||||baz",
+			output: [][]string{{"foo"}, {"bar"}, {"baz"}},
+		},
+		"custom delim multipart": {
+			codec:  "delim:,/multipart",
+			input:  "foo,bar,,baz",
+			output: [][]string{{"foo", "bar"}, {"baz"}},
+		},
+		"length prefixed": {
+			codec:  "length-prefixed",
+			input:  "\x00\x00\x00\x03foo\x00\x00\x00\x00\x00\x00\x00\x06bar\nbz",
+			output: [][]string{{"foo"}, {"bar\nbz"}},
+		},
+		"regex": {
+			codec: "regex:^\\d{4}",
+			input: "2020 first\n  at foo\n  at bar\n2021 second\n2022 third\n  more",
+			output: [][]string{
+				{"2020 first\n  at foo\n  at bar"},
+				{"2021 second"},
+				{"2022 third\n  more"},
+			},
+		},
+		"regex leading unmatched": {
+			codec:  "regex:^BEGIN",
+			input:  "preamble\nBEGIN a\nBEGIN b",
+			output: [][]string{{"preamble"}, {"BEGIN a"}, {"BEGIN b"}},
+		},
+		"csv": {
+			codec:  "csv",
+			input:  "a,b\n1,2\n\"3,4\",5\n",
+			output: [][]string{{`{"a":"1","b":"2"}`}, {`{"a":"3,4","b":"5"}`}},
+		},
+		"csv custom delim": {
+			codec:  "csv:;",
+			input:  "a;b\n1;2\n",
+			output: [][]string{{`{"a":"1","b":"2"}`}},
+		},
+		"csv no header": {
+			codec:  "csv-noheader",
+			input:  "a,b\n1,2\n",
+			output: [][]string{{`["a","b"]`}, {`["1","2"]`}},
+		},
+		"csv header only": {
+			codec: "csv",
+			input: "a,b\n",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			output, err := readAll(t, test.codec, NewReaderConfig(), []byte(test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, test.output, output)
+		})
+	}
+}
+
+func TestReaderCompressed(t *testing.T) {
+	input := []byte("a,b\n1,2\n3,4\n")
+	exp := [][]string{{`{"a":"1","b":"2"}`}, {`{"a":"3","b":"4"}`}}
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, err := gw.Write(input)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	output, err := readAll(t, "gzip/csv", NewReaderConfig(), gzipped.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, exp, output)
+
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	zstded := enc.EncodeAll(input, nil)
+
+	output, err = readAll(t, "zstd/csv", NewReaderConfig(), zstded)
+	require.NoError(t, err)
+	assert.Equal(t, exp, output)
+
+	ctor, err := GetReader("gzip/lines", NewReaderConfig())
+	require.NoError(t, err)
+
+	_, err = ctor(ioutil.NopCloser(bytes.NewReader(input)), func(context.Context, error) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestReaderTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, body string }{
+		{"foo.txt", "foo content"},
+		{"bar.txt", "bar content"},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     f.name,
+			Mode:     0600,
+			Size:     int64(len(f.body)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(f.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	ctor, err := GetReader("tar", NewReaderConfig())
+	require.NoError(t, err)
+
+	r, err := ctor(ioutil.NopCloser(&buf), func(context.Context, error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	parts, _, err := r.Next(context.Background())
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "foo content", string(parts[0].Get()))
+	assert.Equal(t, "foo.txt", parts[0].Metadata().Get("tar_name"))
+
+	parts, _, err = r.Next(context.Background())
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "bar content", string(parts[0].Get()))
+	assert.Equal(t, "bar.txt", parts[0].Metadata().Get("tar_name"))
+
+	_, _, err = r.Next(context.Background())
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderMaxScanTokenSize(t *testing.T) {
+	conf := NewReaderConfig()
+	conf.MaxScanTokenSize = 5
+
+	ctor, err := GetReader("lines", conf)
+	require.NoError(t, err)
+
+	r, err := ctor(ioutil.NopCloser(bytes.NewReader([]byte("foo\nthis is too long\n"))), func(context.Context, error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	parts, _, err := r.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(parts[0].Get()))
+
+	_, _, err = r.Next(context.Background())
+	assert.Error(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
+
+func TestReaderAcks(t *testing.T) {
+	ctor, err := GetReader("lines", NewReaderConfig())
+	require.NoError(t, err)
+
+	var acks []error
+	r, err := ctor(ioutil.NopCloser(bytes.NewReader([]byte("foo\nbar\nbaz"))), func(ctx context.Context, err error) error {
+		acks = append(acks, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var ackFns []ReaderAckFn
+	for {
+		_, ackFn, err := r.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ackFns = append(ackFns, ackFn)
+	}
+	require.Len(t, ackFns, 3)
+
+	errNope := errors.New("nope")
+
+	require.NoError(t, ackFns[1](context.Background(), errNope))
+	require.NoError(t, ackFns[0](context.Background(), nil))
+	assert.Empty(t, acks)
+
+	// Acknowledging the same message twice has no effect.
+	require.NoError(t, ackFns[0](context.Background(), nil))
+	assert.Empty(t, acks)
+
+	require.NoError(t, ackFns[2](context.Background(), nil))
+	assert.Equal(t, []error{errNope}, acks)
+}
+
+func TestReaderClosedEarly(t *testing.T) {
+	for codec, readFirst := range map[string]bool{
+		"all-bytes":    false,
+		"lines":        true,
+		"regex:^foo":   true,
+		"csv-noheader": true,
+		"tar":          false,
+	} {
+		ctor, err := GetReader(codec, NewReaderConfig())
+		require.NoError(t, err)
+
+		var acks []error
+		r, err := ctor(ioutil.NopCloser(bytes.NewReader([]byte("foo\nbar\nbaz"))), func(ctx context.Context, err error) error {
+			acks = append(acks, err)
+			return nil
+		})
+		require.NoError(t, err)
+
+		var ackFn ReaderAckFn
+		if readFirst {
+			_, ackFn, err = r.Next(context.Background())
+			require.NoError(t, err, codec)
+		}
+
+		require.NoError(t, r.Close(context.Background()))
+		if ackFn != nil {
+			assert.Empty(t, acks, codec)
+			require.NoError(t, ackFn(context.Background(), nil))
+		}
+		assert.Equal(t, []error{ErrNotFullyConsumed}, acks, codec)
+	}
+}
+
+func TestReaderClosedAfterEOF(t *testing.T) {
+	var acks []error
+	r := newScannerReader(NewReaderConfig(), ioutil.NopCloser(bytes.NewReader([]byte("foo"))), func(ctx context.Context, err error) error {
+		acks = append(acks, err)
+		return nil
+	}, delimSplit([]byte("\n")), false)
+
+	_, ackFn, err := r.Next(context.Background())
+	require.NoError(t, err)
+	_, _, err = r.Next(context.Background())
+	require.Equal(t, io.EOF, err)
+
+	require.NoError(t, r.Close(context.Background()))
+	assert.Empty(t, acks)
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	assert.Equal(t, []error{nil}, acks)
+}
+
+func TestReaderBadCodecs(t *testing.T) {
+	for _, codec := range []string{
+		"nope",
+		"gzip/nope",
+		"delim:",
+		"csv:",
+		"csv:ab",
+		"regex:(",
+		"csv/multipart",
+		"all-bytes/multipart",
+	} {
+		_, err := GetReader(codec, NewReaderConfig())
+		assert.Error(t, err, codec)
+	}
+}
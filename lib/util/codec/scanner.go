@@ -0,0 +1,183 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"regexp"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func delimSplit(delim []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		if i := bytes.Index(data, delim); i >= 0 {
+			// We have a full terminated token.
+			return i + len(delim), data[0:i], nil
+		}
+
+		// If we're at EOF, we have a final, non-terminated token. Return it.
+		if atEOF {
+			return len(data), data, nil
+		}
+
+		// Request more data.
+		return 0, nil, nil
+	}
+}
+
+func lengthPrefixedSplit(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if len(data) >= 4 {
+		size := int(binary.BigEndian.Uint32(data))
+		if len(data) >= size+4 {
+			return size + 4, data[4 : size+4], nil
+		}
+	}
+	if atEOF {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+
+	// Request more data.
+	return 0, nil, nil
+}
+
+func newScanner(conf ReaderConfig, r io.Reader, split bufio.SplitFunc) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	if conf.MaxScanTokenSize != bufio.MaxScanTokenSize {
+		scanner.Buffer([]byte{}, conf.MaxScanTokenSize)
+	}
+	scanner.Split(split)
+	return scanner
+}
+
+//------------------------------------------------------------------------------
+
+// scannerReader emits each non-empty token of a scanner as a message. When
+// multipart is enabled consecutive tokens are emitted as a batch, where an
+// empty token ends the batch.
+type scannerReader struct {
+	r         io.ReadCloser
+	scanner   *bufio.Scanner
+	acks      *ackTracker
+	multipart bool
+}
+
+func newScannerReader(conf ReaderConfig, r io.ReadCloser, ackFn ReaderAckFn, split bufio.SplitFunc, multipart bool) *scannerReader {
+	return &scannerReader{
+		r:         r,
+		scanner:   newScanner(conf, r, split),
+		acks:      newAckTracker(ackFn),
+		multipart: multipart,
+	}
+}
+
+func delimReaderCtor(conf ReaderConfig, delim string, multipart bool) ReaderConstructor {
+	return func(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+		return newScannerReader(conf, r, ackFn, delimSplit([]byte(delim)), multipart), nil
+	}
+}
+
+func (s *scannerReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	var parts []types.Part
+	for s.scanner.Scan() {
+		if len(s.scanner.Bytes()) == 0 {
+			if s.multipart && len(parts) > 0 {
+				return parts, s.acks.next(), nil
+			}
+			continue
+		}
+
+		partBytes := make([]byte, len(s.scanner.Bytes()))
+		copy(partBytes, s.scanner.Bytes())
+		parts = append(parts, message.NewPart(partBytes))
+		if !s.multipart {
+			return parts, s.acks.next(), nil
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(parts) > 0 {
+		return parts, s.acks.next(), nil
+	}
+	s.acks.finish(ctx)
+	return nil, nil, io.EOF
+}
+
+func (s *scannerReader) Close(ctx context.Context) error {
+	s.acks.close(ctx)
+	return s.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// regexReader groups lines into messages, where each line that matches the
+// expression begins a new message.
+type regexReader struct {
+	r       io.ReadCloser
+	scanner *bufio.Scanner
+	acks    *ackTracker
+	re      *regexp.Regexp
+
+	pending []byte
+}
+
+func newRegexReader(conf ReaderConfig, r io.ReadCloser, ackFn ReaderAckFn, re *regexp.Regexp) *regexReader {
+	return &regexReader{
+		r:       r,
+		scanner: newScanner(conf, r, delimSplit([]byte("\n"))),
+		acks:    newAckTracker(ackFn),
+		re:      re,
+	}
+}
+
+func (r *regexReader) flush() ([]types.Part, ReaderAckFn) {
+	part := message.NewPart(r.pending)
+	r.pending = nil
+	return []types.Part{part}, r.acks.next()
+}
+
+func (r *regexReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if r.pending != nil && r.re.Match(line) {
+			parts, ackFn := r.flush()
+			r.pending = append([]byte{}, line...)
+			return parts, ackFn, nil
+		}
+		if r.pending == nil {
+			r.pending = append([]byte{}, line...)
+		} else {
+			r.pending = append(r.pending, '\n')
+			r.pending = append(r.pending, line...)
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if r.pending != nil {
+		parts, ackFn := r.flush()
+		return parts, ackFn, nil
+	}
+	r.acks.finish(ctx)
+	return nil, nil, io.EOF
+}
+
+func (r *regexReader) Close(ctx context.Context) error {
+	r.acks.close(ctx)
+	return r.r.Close()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// tarReader emits each regular file of a tar archive as a message. Only a
+// single file of the archive is held in memory at a time.
+type tarReader struct {
+	r       io.ReadCloser
+	archive *tar.Reader
+	acks    *ackTracker
+}
+
+func newTarReader(r io.ReadCloser, ackFn ReaderAckFn) *tarReader {
+	return &tarReader{
+		r:       r,
+		archive: tar.NewReader(r),
+		acks:    newAckTracker(ackFn),
+	}
+}
+
+func (t *tarReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	for {
+		hdr, err := t.archive.Next()
+		if err != nil {
+			if err == io.EOF {
+				t.acks.finish(ctx)
+			}
+			return nil, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		b, err := ioutil.ReadAll(t.archive)
+		if err != nil {
+			return nil, nil, err
+		}
+		part := message.NewPart(b)
+		part.Metadata().Set("tar_name", hdr.Name)
+		return []types.Part{part}, t.acks.next(), nil
+	}
+}
+
+func (t *tarReader) Close(ctx context.Context) error {
+	t.acks.close(ctx)
+	return t.r.Close()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,200 @@
+// +build !wasm
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+// Signals that can be exported to a collector, each with their respective gRPC
+// method and HTTP path.
+type signal struct {
+	method string
+	path   string
+}
+
+var (
+	signalTraces = signal{
+		method: "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+		path:   "/v1/traces",
+	}
+	signalMetrics = signal{
+		method: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+		path:   "/v1/metrics",
+	}
+)
+
+// rawCodec is a gRPC codec for payloads that are already protobuf encoded.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type: %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+//------------------------------------------------------------------------------
+
+// Client exports encoded telemetry to an OpenTelemetry collector.
+type Client struct {
+	headers map[string]string
+	timeout time.Duration
+
+	conn *grpc.ClientConn
+
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient creates a new OTLP client from a config.
+func NewClient(conf Config) (*Client, error) {
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+
+	c := &Client{
+		headers: conf.Headers,
+	}
+
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if c.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	tlsConf, err := conf.TLS.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	switch conf.Protocol {
+	case ProtocolGRPC:
+		creds := grpc.WithInsecure()
+		if conf.TLS.Enabled {
+			creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))
+		}
+		if c.conn, err = grpc.Dial(conf.Address, creds); err != nil {
+			return nil, err
+		}
+	case ProtocolHTTP:
+		c.httpClient = &http.Client{}
+		c.baseURL = "http://" + conf.Address
+		if conf.TLS.Enabled {
+			c.httpClient.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+			c.baseURL = "https://" + conf.Address
+		}
+	default:
+		return nil, fmt.Errorf("protocol not recognised: %v", conf.Protocol)
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ExportTraces sends a payload encoded with EncodeTraces to the collector.
+func (c *Client) ExportTraces(ctx context.Context, payload []byte) error {
+	return c.export(ctx, signalTraces, payload)
+}
+
+// ExportMetrics sends a payload encoded with EncodeMetrics to the collector.
+func (c *Client) ExportMetrics(ctx context.Context, payload []byte) error {
+	return c.export(ctx, signalMetrics, payload)
+}
+
+func (c *Client) export(ctx context.Context, sig signal, payload []byte) error {
+	if c.timeout > 0 {
+		var done func()
+		ctx, done = context.WithTimeout(ctx, c.timeout)
+		defer done()
+	}
+
+	var res []byte
+	var err error
+	if c.conn != nil {
+		res, err = c.exportGRPC(ctx, sig, payload)
+	} else {
+		res, err = c.exportHTTP(ctx, sig, payload)
+	}
+	if err != nil {
+		return err
+	}
+	return decodePartialSuccess(res)
+}
+
+func (c *Client) exportGRPC(ctx context.Context, sig signal, payload []byte) ([]byte, error) {
+	for k, v := range c.headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	var res []byte
+	if err := c.conn.Invoke(ctx, sig.method, &payload, &res, grpc.ForceCodec(rawCodec{})); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) exportHTTP(ctx context.Context, sig signal, payload []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", c.baseURL+sig.path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected response status %v: %s", res.StatusCode, body)
+	}
+	return body, nil
+}
+
+// Close the client and any underlying connections.
+func (c *Client) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
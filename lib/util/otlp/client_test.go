@@ -0,0 +1,158 @@
+// +build !wasm
+
+package otlp
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestClientHTTP(t *testing.T) {
+	var reqPath, reqType, reqAuth string
+	var reqBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqPath = r.URL.Path
+		reqType = r.Header.Get("Content-Type")
+		reqAuth = r.Header.Get("Authorization")
+		reqBody, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Protocol = ProtocolHTTP
+	conf.Address = strings.TrimPrefix(ts.URL, "http://")
+	conf.Headers = map[string]string{"Authorization": "Bearer foo"}
+
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.ExportTraces(context.Background(), []byte("foo")))
+	assert.Equal(t, "/v1/traces", reqPath)
+	assert.Equal(t, "application/x-protobuf", reqType)
+	assert.Equal(t, "Bearer foo", reqAuth)
+	assert.Equal(t, "foo", string(reqBody))
+
+	require.NoError(t, c.ExportMetrics(context.Background(), []byte("bar")))
+	assert.Equal(t, "/v1/metrics", reqPath)
+	assert.Equal(t, "bar", string(reqBody))
+}
+
+func TestClientHTTPErrors(t *testing.T) {
+	var partial []byte
+	partial = appendVarint(partial, 1, 1)
+	partial = appendString(partial, 2, "bad span")
+
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write(appendMessage(nil, 1, partial))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Protocol = ProtocolHTTP
+	conf.Address = strings.TrimPrefix(ts.URL, "http://")
+
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+	defer c.Close()
+
+	err = c.ExportTraces(context.Background(), []byte("foo"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad span")
+
+	status = http.StatusBadRequest
+	err = c.ExportTraces(context.Background(), []byte("foo"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
+
+// serverCodec is the raw codec with the additional method required by
+// grpc.CustomCodec.
+type serverCodec struct {
+	rawCodec
+}
+
+func (serverCodec) String() string {
+	return "proto"
+}
+
+func TestClientGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	type received struct {
+		method string
+		auth   []string
+		body   []byte
+	}
+	receivedChan := make(chan received, 1)
+
+	srv := grpc.NewServer(
+		grpc.CustomCodec(serverCodec{}),
+		grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+			var body []byte
+			if err := stream.RecvMsg(&body); err != nil {
+				return err
+			}
+			method, _ := grpc.MethodFromServerStream(stream)
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			receivedChan <- received{
+				method: method,
+				auth:   md.Get("authorization"),
+				body:   body,
+			}
+			res := []byte{}
+			return stream.SendMsg(&res)
+		}),
+	)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	conf := NewConfig()
+	conf.Address = ln.Addr().String()
+	conf.Headers = map[string]string{"Authorization": "Bearer foo"}
+
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.ExportTraces(context.Background(), []byte("foo")))
+	r := <-receivedChan
+	assert.Equal(t, "/opentelemetry.proto.collector.trace.v1.TraceService/Export", r.method)
+	assert.Equal(t, []string{"Bearer foo"}, r.auth)
+	assert.Equal(t, "foo", string(r.body))
+
+	require.NoError(t, c.ExportMetrics(context.Background(), []byte("bar")))
+	r = <-receivedChan
+	assert.Equal(t, "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export", r.method)
+	assert.Equal(t, "bar", string(r.body))
+}
+
+func TestClientConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Protocol = "nope"
+	_, err := NewClient(conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Address = ""
+	_, err = NewClient(conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Timeout = "nope"
+	_, err = NewClient(conf)
+	assert.Error(t, err)
+}
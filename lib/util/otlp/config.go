@@ -0,0 +1,52 @@
+package otlp
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+//------------------------------------------------------------------------------
+
+// Protocols supported for exporting to a collector.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Config contains configuration fields for an OTLP exporter.
+type Config struct {
+	Address  string            `json:"address" yaml:"address"`
+	Protocol string            `json:"protocol" yaml:"protocol"`
+	Headers  map[string]string `json:"headers" yaml:"headers"`
+	Timeout  string            `json:"timeout" yaml:"timeout"`
+	TLS      tls.Config        `json:"tls" yaml:"tls"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Address:  "localhost:4317",
+		Protocol: ProtocolGRPC,
+		Headers:  map[string]string{},
+		Timeout:  "10s",
+		TLS:      tls.NewConfig(),
+	}
+}
+
+// FieldSpecs returns documentation specs for OTLP exporter fields.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon(
+			"address", "The address of an OpenTelemetry collector. Collectors listen on port 4317 for gRPC and port 4318 for HTTP by default.",
+			"localhost:4317", "localhost:4318",
+		),
+		docs.FieldCommon("protocol", "The protocol to export with.").HasOptions(ProtocolGRPC, ProtocolHTTP),
+		docs.FieldAdvanced("headers", "A map of headers to add to each export request, which can be used for authentication.", map[string]string{
+			"Authorization": "Bearer foo",
+		}),
+		docs.FieldAdvanced("timeout", "The maximum period of time to wait for an export request to complete."),
+		tls.FieldSpec(),
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,391 @@
+package otlp
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+//------------------------------------------------------------------------------
+
+// KeyValue is an attribute of a resource, span, event or data point. Values of
+// type string, bool, int, int64, float64 and []byte are encoded as their
+// respective types, any other value is encoded as a string.
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// Resource describes the entity producing telemetry.
+type Resource struct {
+	Attributes []KeyValue
+}
+
+// Scope describes the instrumentation library producing telemetry.
+type Scope struct {
+	Name    string
+	Version string
+}
+
+// SpanKind describes the relationship of a span to its parent and children.
+type SpanKind int32
+
+// Span kinds.
+const (
+	SpanKindUnspecified SpanKind = iota
+	SpanKindInternal
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// StatusCode is the status of a finished span.
+type StatusCode int32
+
+// Span status codes.
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Event is a timestamped annotation of a span.
+type Event struct {
+	Time       time.Time
+	Name       string
+	Attributes []KeyValue
+}
+
+// Span is a single finished span.
+type Span struct {
+	TraceID       [16]byte
+	SpanID        [8]byte
+	ParentSpanID  [8]byte
+	TraceState    string
+	Name          string
+	Kind          SpanKind
+	Start         time.Time
+	End           time.Time
+	Attributes    []KeyValue
+	Events        []Event
+	Status        StatusCode
+	StatusMessage string
+}
+
+// MetricType is the type of data points within a metric.
+type MetricType int
+
+// Metric types, where sums and histograms are always cumulative.
+const (
+	MetricSum MetricType = iota
+	MetricGauge
+	MetricHistogram
+)
+
+// DataPoint is a single value of a metric for a set of attributes.
+type DataPoint struct {
+	Attributes []KeyValue
+	Start      time.Time
+	Time       time.Time
+
+	// Value is the value of sum and gauge data points.
+	Value int64
+
+	// Fields of histogram data points, where BucketCounts must contain one
+	// more count than Bounds.
+	Count        uint64
+	Sum          float64
+	Min          float64
+	Max          float64
+	Bounds       []float64
+	BucketCounts []uint64
+}
+
+// Metric is a named collection of data points.
+type Metric struct {
+	Name        string
+	Description string
+	Unit        string
+	Type        MetricType
+	Monotonic   bool
+	Points      []DataPoint
+}
+
+//------------------------------------------------------------------------------
+
+// EncodeTraces encodes spans as a protobuf ExportTraceServiceRequest.
+func EncodeTraces(res Resource, scope Scope, spans []Span) []byte {
+	var scopeSpans []byte
+	scopeSpans = appendMessage(scopeSpans, 1, encodeScope(scope))
+	for _, s := range spans {
+		scopeSpans = appendMessage(scopeSpans, 2, encodeSpan(s))
+	}
+
+	var resSpans []byte
+	resSpans = appendMessage(resSpans, 1, encodeResource(res))
+	resSpans = appendMessage(resSpans, 2, scopeSpans)
+
+	return appendMessage(nil, 1, resSpans)
+}
+
+// EncodeMetrics encodes metrics as a protobuf ExportMetricsServiceRequest.
+func EncodeMetrics(res Resource, scope Scope, metrics []Metric) []byte {
+	var scopeMetrics []byte
+	scopeMetrics = appendMessage(scopeMetrics, 1, encodeScope(scope))
+	for _, m := range metrics {
+		scopeMetrics = appendMessage(scopeMetrics, 2, encodeMetric(m))
+	}
+
+	var resMetrics []byte
+	resMetrics = appendMessage(resMetrics, 1, encodeResource(res))
+	resMetrics = appendMessage(resMetrics, 2, scopeMetrics)
+
+	return appendMessage(nil, 1, resMetrics)
+}
+
+//------------------------------------------------------------------------------
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	return appendFixed64(b, num, math.Float64bits(v))
+}
+
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	return appendFixed64(b, num, uint64(t.UnixNano()))
+}
+
+func encodeAnyValue(v interface{}) []byte {
+	var b []byte
+	switch t := v.(type) {
+	case string:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, t)
+	case bool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(t))
+	case int:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(t))
+	case int64:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(t))
+	case float64:
+		b = appendDouble(b, 4, t)
+	case []byte:
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendBytes(b, t)
+	default:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, fmt.Sprintf("%v", t))
+	}
+	return b
+}
+
+func appendAttributes(b []byte, num protowire.Number, attrs []KeyValue) []byte {
+	for _, kv := range attrs {
+		var kvBytes []byte
+		kvBytes = appendString(kvBytes, 1, kv.Key)
+		kvBytes = appendMessage(kvBytes, 2, encodeAnyValue(kv.Value))
+		b = appendMessage(b, num, kvBytes)
+	}
+	return b
+}
+
+func encodeResource(res Resource) []byte {
+	return appendAttributes(nil, 1, res.Attributes)
+}
+
+func encodeScope(scope Scope) []byte {
+	var b []byte
+	b = appendString(b, 1, scope.Name)
+	return appendString(b, 2, scope.Version)
+}
+
+func encodeSpan(s Span) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, s.TraceID[:])
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, s.SpanID[:])
+	b = appendString(b, 3, s.TraceState)
+	if s.ParentSpanID != [8]byte{} {
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, s.ParentSpanID[:])
+	}
+	b = appendString(b, 5, s.Name)
+	b = appendVarint(b, 6, uint64(s.Kind))
+	b = appendTime(b, 7, s.Start)
+	b = appendTime(b, 8, s.End)
+	b = appendAttributes(b, 9, s.Attributes)
+	for _, e := range s.Events {
+		var eBytes []byte
+		eBytes = appendTime(eBytes, 1, e.Time)
+		eBytes = appendString(eBytes, 2, e.Name)
+		eBytes = appendAttributes(eBytes, 3, e.Attributes)
+		b = appendMessage(b, 11, eBytes)
+	}
+	if s.Status != StatusUnset || len(s.StatusMessage) > 0 {
+		var sBytes []byte
+		sBytes = appendString(sBytes, 2, s.StatusMessage)
+		sBytes = appendVarint(sBytes, 3, uint64(s.Status))
+		b = appendMessage(b, 15, sBytes)
+	}
+	return b
+}
+
+// Aggregation temporality of sums and histograms.
+const aggregationCumulative = 2
+
+func encodeNumberPoint(p DataPoint) []byte {
+	var b []byte
+	b = appendTime(b, 2, p.Start)
+	b = appendTime(b, 3, p.Time)
+	b = protowire.AppendTag(b, 6, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(p.Value))
+	return appendAttributes(b, 7, p.Attributes)
+}
+
+func encodeHistogramPoint(p DataPoint) []byte {
+	var b []byte
+	b = appendTime(b, 2, p.Start)
+	b = appendTime(b, 3, p.Time)
+	b = appendFixed64(b, 4, p.Count)
+	b = appendDouble(b, 5, p.Sum)
+
+	var counts []byte
+	for _, c := range p.BucketCounts {
+		counts = protowire.AppendFixed64(counts, c)
+	}
+	b = appendMessage(b, 6, counts)
+
+	var bounds []byte
+	for _, v := range p.Bounds {
+		bounds = protowire.AppendFixed64(bounds, math.Float64bits(v))
+	}
+	b = appendMessage(b, 7, bounds)
+
+	b = appendAttributes(b, 9, p.Attributes)
+	if p.Count > 0 {
+		b = appendDouble(b, 11, p.Min)
+		b = appendDouble(b, 12, p.Max)
+	}
+	return b
+}
+
+func encodeMetric(m Metric) []byte {
+	var b []byte
+	b = appendString(b, 1, m.Name)
+	b = appendString(b, 2, m.Description)
+	b = appendString(b, 3, m.Unit)
+
+	var data []byte
+	switch m.Type {
+	case MetricGauge:
+		for _, p := range m.Points {
+			data = appendMessage(data, 1, encodeNumberPoint(p))
+		}
+		b = appendMessage(b, 5, data)
+	case MetricSum:
+		for _, p := range m.Points {
+			data = appendMessage(data, 1, encodeNumberPoint(p))
+		}
+		data = appendVarint(data, 2, aggregationCumulative)
+		if m.Monotonic {
+			data = appendVarint(data, 3, 1)
+		}
+		b = appendMessage(b, 7, data)
+	case MetricHistogram:
+		for _, p := range m.Points {
+			data = appendMessage(data, 1, encodeHistogramPoint(p))
+		}
+		data = appendVarint(data, 2, aggregationCumulative)
+		b = appendMessage(b, 9, data)
+	}
+	return b
+}
+
+//------------------------------------------------------------------------------
+
+// decodePartialSuccess extracts an error from the partial_success field of an
+// export response, which has the same layout for traces and metrics.
+func decodePartialSuccess(b []byte) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num != 1 || typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		var partial []byte
+		if partial, n = protowire.ConsumeBytes(b); n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var rejected uint64
+		var message string
+		for len(partial) > 0 {
+			pNum, pTyp, pn := protowire.ConsumeTag(partial)
+			if pn < 0 {
+				return protowire.ParseError(pn)
+			}
+			partial = partial[pn:]
+			switch {
+			case pNum == 1 && pTyp == protowire.VarintType:
+				rejected, pn = protowire.ConsumeVarint(partial)
+			case pNum == 2 && pTyp == protowire.BytesType:
+				message, pn = protowire.ConsumeString(partial)
+			default:
+				pn = protowire.ConsumeFieldValue(pNum, pTyp, partial)
+			}
+			if pn < 0 {
+				return protowire.ParseError(pn)
+			}
+			partial = partial[pn:]
+		}
+		if rejected > 0 || len(message) > 0 {
+			return fmt.Errorf("collector rejected %v items: %v", rejected, message)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
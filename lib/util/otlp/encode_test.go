@@ -0,0 +1,390 @@
+package otlp
+
+import (
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type testField struct {
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+func decodeFields(t *testing.T, b []byte) map[protowire.Number][]testField {
+	t.Helper()
+
+	fields := map[protowire.Number][]testField{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0, "bad tag")
+		b = b[n:]
+
+		f := testField{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.varint, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type: %v", typ)
+		}
+		require.True(t, n > 0, "bad field value")
+		b = b[n:]
+		fields[num] = append(fields[num], f)
+	}
+	return fields
+}
+
+func decodeAttributes(t *testing.T, fields []testField) map[string]map[protowire.Number][]testField {
+	t.Helper()
+
+	attrs := map[string]map[protowire.Number][]testField{}
+	for _, f := range fields {
+		kv := decodeFields(t, f.bytes)
+		attrs[string(kv[1][0].bytes)] = decodeFields(t, kv[2][0].bytes)
+	}
+	return attrs
+}
+
+// otlpMessage decodes a payload as an OTLP message using the descriptors of the
+// official OTLP protobuf definitions, which were extracted from the generated
+// code of go.opentelemetry.io/proto/otlp v1.0.0, and fails the test if any
+// field of the payload is not recognised.
+func otlpMessage(t *testing.T, name string, b []byte) *dynamicpb.Message {
+	t.Helper()
+
+	setBytes, err := ioutil.ReadFile("testdata/otlp_v1.0.0.binpb")
+	require.NoError(t, err)
+
+	var set descriptorpb.FileDescriptorSet
+	require.NoError(t, proto.Unmarshal(setBytes, &set))
+
+	files, err := protodesc.NewFiles(&set)
+	require.NoError(t, err)
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	require.NoError(t, err)
+
+	msg := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+	require.NoError(t, proto.Unmarshal(b, msg))
+	requireNoUnknownFields(t, msg)
+	return msg
+}
+
+func requireNoUnknownFields(t *testing.T, msg protoreflect.Message) {
+	t.Helper()
+
+	require.Empty(t, msg.GetUnknown(), "unknown fields in %v", msg.Descriptor().FullName())
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil {
+			return true
+		}
+		if fd.IsList() {
+			for i := 0; i < v.List().Len(); i++ {
+				requireNoUnknownFields(t, v.List().Get(i).Message())
+			}
+		} else {
+			requireNoUnknownFields(t, v.Message())
+		}
+		return true
+	})
+}
+
+func testSpan() Span {
+	start := time.Unix(10, 0)
+	end := time.Unix(11, 0)
+
+	return Span{
+		TraceID:      [16]byte{1, 2, 3},
+		SpanID:       [8]byte{4, 5, 6},
+		ParentSpanID: [8]byte{7, 8, 9},
+		TraceState:   "foo=bar",
+		Name:         "input_kafka",
+		Kind:         SpanKindConsumer,
+		Start:        start,
+		End:          end,
+		Attributes: []KeyValue{
+			{Key: "str", Value: "foo"},
+			{Key: "int", Value: int64(-5)},
+			{Key: "bool", Value: true},
+			{Key: "float", Value: 1.5},
+		},
+		Events: []Event{
+			{Time: end, Name: "log", Attributes: []KeyValue{{Key: "message", Value: "bar"}}},
+		},
+		Status:        StatusError,
+		StatusMessage: "nope",
+	}
+}
+
+func TestEncodeTraces(t *testing.T) {
+	span := testSpan()
+	start, end := span.Start, span.End
+
+	req := decodeFields(t, EncodeTraces(Resource{
+		Attributes: []KeyValue{{Key: "service.name", Value: "benthos"}},
+	}, Scope{Name: "benthos"}, []Span{span}))
+
+	require.Len(t, req[1], 1)
+	resSpans := decodeFields(t, req[1][0].bytes)
+
+	resAttrs := decodeAttributes(t, decodeFields(t, resSpans[1][0].bytes)[1])
+	assert.Equal(t, "benthos", string(resAttrs["service.name"][1][0].bytes))
+
+	require.Len(t, resSpans[2], 1)
+	scopeSpans := decodeFields(t, resSpans[2][0].bytes)
+	assert.Equal(t, "benthos", string(decodeFields(t, scopeSpans[1][0].bytes)[1][0].bytes))
+
+	require.Len(t, scopeSpans[2], 1)
+	s := decodeFields(t, scopeSpans[2][0].bytes)
+	assert.Equal(t, span.TraceID[:], s[1][0].bytes)
+	assert.Equal(t, span.SpanID[:], s[2][0].bytes)
+	assert.Equal(t, "foo=bar", string(s[3][0].bytes))
+	assert.Equal(t, span.ParentSpanID[:], s[4][0].bytes)
+	assert.Equal(t, "input_kafka", string(s[5][0].bytes))
+	assert.Equal(t, uint64(SpanKindConsumer), s[6][0].varint)
+	assert.Equal(t, uint64(start.UnixNano()), s[7][0].varint)
+	assert.Equal(t, uint64(end.UnixNano()), s[8][0].varint)
+
+	attrs := decodeAttributes(t, s[9])
+	assert.Equal(t, "foo", string(attrs["str"][1][0].bytes))
+	assert.Equal(t, int64(-5), int64(attrs["int"][3][0].varint))
+	assert.Equal(t, uint64(1), attrs["bool"][2][0].varint)
+	assert.Equal(t, 1.5, math.Float64frombits(attrs["float"][4][0].varint))
+
+	require.Len(t, s[11], 1)
+	event := decodeFields(t, s[11][0].bytes)
+	assert.Equal(t, "log", string(event[2][0].bytes))
+	assert.Equal(t, "bar", string(decodeAttributes(t, event[3])["message"][1][0].bytes))
+
+	status := decodeFields(t, s[15][0].bytes)
+	assert.Equal(t, "nope", string(status[2][0].bytes))
+	assert.Equal(t, uint64(StatusError), status[3][0].varint)
+
+	// Root spans have no parent span ID.
+	span.ParentSpanID = [8]byte{}
+	req = decodeFields(t, EncodeTraces(Resource{}, Scope{}, []Span{span}))
+	s = decodeFields(t, decodeFields(t, decodeFields(t, req[1][0].bytes)[2][0].bytes)[2][0].bytes)
+	assert.Empty(t, s[4])
+}
+
+func TestEncodeTracesProto(t *testing.T) {
+	span := testSpan()
+	root := testSpan()
+	root.ParentSpanID = [8]byte{}
+	root.Attributes = []KeyValue{{Key: "bytes", Value: []byte("foo")}}
+	root.Events = nil
+	root.Status, root.StatusMessage = StatusOK, ""
+
+	msg := otlpMessage(t, "opentelemetry.proto.collector.trace.v1.ExportTraceServiceRequest", EncodeTraces(Resource{
+		Attributes: []KeyValue{{Key: "service.name", Value: "benthos"}},
+	}, Scope{Name: "benthos", Version: "1.0.0"}, []Span{span, root}))
+
+	jBytes, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"resourceSpans": [{
+			"resource": {
+				"attributes": [{"key": "service.name", "value": {"stringValue": "benthos"}}]
+			},
+			"scopeSpans": [{
+				"scope": {"name": "benthos", "version": "1.0.0"},
+				"spans": [
+					{
+						"traceId": "AQIDAAAAAAAAAAAAAAAAAA==",
+						"spanId": "BAUGAAAAAAA=",
+						"traceState": "foo=bar",
+						"parentSpanId": "BwgJAAAAAAA=",
+						"name": "input_kafka",
+						"kind": "SPAN_KIND_CONSUMER",
+						"startTimeUnixNano": "10000000000",
+						"endTimeUnixNano": "11000000000",
+						"attributes": [
+							{"key": "str", "value": {"stringValue": "foo"}},
+							{"key": "int", "value": {"intValue": "-5"}},
+							{"key": "bool", "value": {"boolValue": true}},
+							{"key": "float", "value": {"doubleValue": 1.5}}
+						],
+						"events": [{
+							"timeUnixNano": "11000000000",
+							"name": "log",
+							"attributes": [{"key": "message", "value": {"stringValue": "bar"}}]
+						}],
+						"status": {"message": "nope", "code": "STATUS_CODE_ERROR"}
+					},
+					{
+						"traceId": "AQIDAAAAAAAAAAAAAAAAAA==",
+						"spanId": "BAUGAAAAAAA=",
+						"traceState": "foo=bar",
+						"name": "input_kafka",
+						"kind": "SPAN_KIND_CONSUMER",
+						"startTimeUnixNano": "10000000000",
+						"endTimeUnixNano": "11000000000",
+						"attributes": [{"key": "bytes", "value": {"bytesValue": "Zm9v"}}],
+						"status": {"code": "STATUS_CODE_OK"}
+					}
+				]
+			}]
+		}]
+	}`, string(jBytes))
+}
+
+func testMetrics() []Metric {
+	start := time.Unix(10, 0)
+	now := time.Unix(20, 0)
+
+	return []Metric{
+		{
+			Name:      "input.received",
+			Type:      MetricSum,
+			Monotonic: true,
+			Points: []DataPoint{
+				{Start: start, Time: now, Value: 5, Attributes: []KeyValue{{Key: "label", Value: "foo"}}},
+			},
+		},
+		{
+			Name: "buffer.backlog",
+			Type: MetricGauge,
+			Points: []DataPoint{
+				{Time: now, Value: -3},
+			},
+		},
+		{
+			Name: "input.latency",
+			Unit: "ns",
+			Type: MetricHistogram,
+			Points: []DataPoint{
+				{
+					Start: start, Time: now,
+					Count: 3, Sum: 6, Min: 1, Max: 3,
+					Bounds:       []float64{2},
+					BucketCounts: []uint64{2, 1},
+				},
+			},
+		},
+	}
+}
+
+func TestEncodeMetrics(t *testing.T) {
+	start := time.Unix(10, 0)
+	now := time.Unix(20, 0)
+	metrics := testMetrics()
+
+	req := decodeFields(t, EncodeMetrics(Resource{}, Scope{Name: "benthos"}, metrics))
+	scopeMetrics := decodeFields(t, decodeFields(t, req[1][0].bytes)[2][0].bytes)
+	require.Len(t, scopeMetrics[2], 3)
+
+	sum := decodeFields(t, scopeMetrics[2][0].bytes)
+	assert.Equal(t, "input.received", string(sum[1][0].bytes))
+	sumData := decodeFields(t, sum[7][0].bytes)
+	assert.Equal(t, uint64(aggregationCumulative), sumData[2][0].varint)
+	assert.Equal(t, uint64(1), sumData[3][0].varint)
+	point := decodeFields(t, sumData[1][0].bytes)
+	assert.Equal(t, uint64(start.UnixNano()), point[2][0].varint)
+	assert.Equal(t, uint64(now.UnixNano()), point[3][0].varint)
+	assert.Equal(t, uint64(5), point[6][0].varint)
+	assert.Equal(t, "foo", string(decodeAttributes(t, point[7])["label"][1][0].bytes))
+
+	gauge := decodeFields(t, scopeMetrics[2][1].bytes)
+	assert.Equal(t, "buffer.backlog", string(gauge[1][0].bytes))
+	point = decodeFields(t, decodeFields(t, gauge[5][0].bytes)[1][0].bytes)
+	assert.Equal(t, int64(-3), int64(point[6][0].varint))
+
+	hist := decodeFields(t, scopeMetrics[2][2].bytes)
+	assert.Equal(t, "input.latency", string(hist[1][0].bytes))
+	assert.Equal(t, "ns", string(hist[3][0].bytes))
+	point = decodeFields(t, decodeFields(t, hist[9][0].bytes)[1][0].bytes)
+	assert.Equal(t, uint64(3), point[4][0].varint)
+	assert.Equal(t, 6.0, math.Float64frombits(point[5][0].varint))
+	assert.Len(t, point[6][0].bytes, 16)
+	assert.Len(t, point[7][0].bytes, 8)
+	assert.Equal(t, 1.0, math.Float64frombits(point[11][0].varint))
+	assert.Equal(t, 3.0, math.Float64frombits(point[12][0].varint))
+}
+
+func TestEncodeMetricsProto(t *testing.T) {
+	msg := otlpMessage(t, "opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceRequest", EncodeMetrics(Resource{
+		Attributes: []KeyValue{{Key: "service.name", Value: "benthos"}},
+	}, Scope{Name: "benthos"}, testMetrics()))
+
+	jBytes, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"resourceMetrics": [{
+			"resource": {
+				"attributes": [{"key": "service.name", "value": {"stringValue": "benthos"}}]
+			},
+			"scopeMetrics": [{
+				"scope": {"name": "benthos"},
+				"metrics": [
+					{
+						"name": "input.received",
+						"sum": {
+							"dataPoints": [{
+								"startTimeUnixNano": "10000000000",
+								"timeUnixNano": "20000000000",
+								"asInt": "5",
+								"attributes": [{"key": "label", "value": {"stringValue": "foo"}}]
+							}],
+							"aggregationTemporality": "AGGREGATION_TEMPORALITY_CUMULATIVE",
+							"isMonotonic": true
+						}
+					},
+					{
+						"name": "buffer.backlog",
+						"gauge": {
+							"dataPoints": [{"timeUnixNano": "20000000000", "asInt": "-3"}]
+						}
+					},
+					{
+						"name": "input.latency",
+						"unit": "ns",
+						"histogram": {
+							"dataPoints": [{
+								"startTimeUnixNano": "10000000000",
+								"timeUnixNano": "20000000000",
+								"count": "3",
+								"sum": 6,
+								"bucketCounts": ["2", "1"],
+								"explicitBounds": [2],
+								"min": 1,
+								"max": 3
+							}],
+							"aggregationTemporality": "AGGREGATION_TEMPORALITY_CUMULATIVE"
+						}
+					}
+				]
+			}]
+		}]
+	}`, string(jBytes))
+}
+
+func TestDecodePartialSuccess(t *testing.T) {
+	assert.NoError(t, decodePartialSuccess(nil))
+
+	var partial []byte
+	partial = appendVarint(partial, 1, 2)
+	partial = appendString(partial, 2, "bad spans")
+	err := decodePartialSuccess(appendMessage(nil, 1, partial))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad spans")
+
+	assert.NoError(t, decodePartialSuccess(appendMessage(nil, 1, nil)))
+}
@@ -0,0 +1,4 @@
+// Package otlp implements a minimal client for exporting traces and metrics to
+// an OpenTelemetry collector using the OpenTelemetry Protocol (OTLP) over
+// either gRPC or HTTP.
+package otlp
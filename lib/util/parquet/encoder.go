@@ -0,0 +1,427 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+//------------------------------------------------------------------------------
+
+const magic = "PAR1"
+
+// Encodings, compression codecs and page types of the Parquet format.
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+	codecZstd         = 6
+
+	pageTypeData = 0
+)
+
+// ValidateCompression returns an error if a compression codec is not
+// supported by the encoder.
+func ValidateCompression(compression string) error {
+	switch compression {
+	case "uncompressed", "", "snappy", "gzip", "zstd":
+		return nil
+	}
+	return fmt.Errorf("compression not recognised: %v", compression)
+}
+
+// zstdEncoder is shared by all zstd compressors as EncodeAll is safe for
+// concurrent use, and each encoder holds goroutines until it is closed.
+var (
+	zstdEncoder     *zstd.Encoder
+	zstdEncoderErr  error
+	zstdEncoderOnce sync.Once
+)
+
+func getZstdEncoder() (*zstd.Encoder, error) {
+	zstdEncoderOnce.Do(func() {
+		zstdEncoder, zstdEncoderErr = zstd.NewWriter(nil)
+	})
+	return zstdEncoder, zstdEncoderErr
+}
+
+type compressor struct {
+	id int32
+	fn func([]byte) ([]byte, error)
+}
+
+func newCompressor(compression string) (compressor, error) {
+	if err := ValidateCompression(compression); err != nil {
+		return compressor{}, err
+	}
+	switch compression {
+	case "snappy":
+		return compressor{
+			id: codecSnappy,
+			fn: func(b []byte) ([]byte, error) { return snappy.Encode(nil, b), nil },
+		}, nil
+	case "gzip":
+		return compressor{
+			id: codecGzip,
+			fn: func(b []byte) ([]byte, error) {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				if _, err := w.Write(b); err != nil {
+					return nil, err
+				}
+				if err := w.Close(); err != nil {
+					return nil, err
+				}
+				return buf.Bytes(), nil
+			},
+		}, nil
+	case "zstd":
+		enc, err := getZstdEncoder()
+		if err != nil {
+			return compressor{}, err
+		}
+		return compressor{
+			id: codecZstd,
+			fn: func(b []byte) ([]byte, error) { return enc.EncodeAll(b, nil), nil },
+		}, nil
+	}
+	return compressor{
+		id: codecUncompressed,
+		fn: func(b []byte) ([]byte, error) { return b, nil },
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type column struct {
+	Column
+	physical int32
+}
+
+// Encoder encodes batches of documents as Parquet files.
+type Encoder struct {
+	columns    []column
+	names      map[string]struct{}
+	compressor compressor
+}
+
+// NewEncoder creates an encoder for a flat schema of columns with a
+// compression codec, which is one of uncompressed, snappy, gzip or zstd.
+func NewEncoder(columns []Column, compression string) (*Encoder, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("schema must contain at least one column")
+	}
+	e := &Encoder{
+		names: map[string]struct{}{},
+	}
+
+	for _, c := range columns {
+		if len(c.Name) == 0 {
+			return nil, fmt.Errorf("column names must not be empty")
+		}
+		if _, exists := e.names[c.Name]; exists {
+			return nil, fmt.Errorf("duplicate column name: %v", c.Name)
+		}
+		e.names[c.Name] = struct{}{}
+
+		physical, err := physicalType(c.Type)
+		if err != nil {
+			return nil, err
+		}
+		e.columns = append(e.columns, column{
+			Column:   c,
+			physical: physical,
+		})
+	}
+
+	var err error
+	if e.compressor, err = newCompressor(compression); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+func toFloat64(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case json.Number:
+		return t.Float64()
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	}
+	return 0, fmt.Errorf("expected number value, found %T", v)
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("expected integer value, found %v", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		return t.Int64()
+	case int:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	}
+	return 0, fmt.Errorf("expected number value, found %T", v)
+}
+
+func toBytes(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	}
+	return json.Marshal(v)
+}
+
+// appendPlain appends a value to a buffer with the plain encoding of a column.
+// Booleans are bit packed and are therefore handled by the caller.
+func (c column) appendPlain(buf []byte, v interface{}) ([]byte, error) {
+	var b [8]byte
+	switch c.physical {
+	case physicalInt32:
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		if i > math.MaxInt32 || i < math.MinInt32 {
+			return nil, fmt.Errorf("value %v overflows INT32", i)
+		}
+		binary.LittleEndian.PutUint32(b[:], uint32(int32(i)))
+		return append(buf, b[:4]...), nil
+	case physicalInt64:
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(i))
+		return append(buf, b[:8]...), nil
+	case physicalFloat:
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(f)))
+		return append(buf, b[:4]...), nil
+	case physicalDouble:
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+		return append(buf, b[:8]...), nil
+	case physicalByteArray:
+		bs, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint32(b[:], uint32(len(bs)))
+		buf = append(buf, b[:4]...)
+		return append(buf, bs...), nil
+	}
+	return nil, fmt.Errorf("unsupported physical type: %v", c.physical)
+}
+
+// encodeLevels encodes definition levels with the RLE/bit-packing hybrid
+// encoding using only RLE runs, prefixed with the length of the encoded data.
+func encodeLevels(levels []byte) []byte {
+	buf := make([]byte, 4)
+	var tmp [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(tmp[:], uint64(j-i)<<1)
+		buf = append(buf, tmp[:n]...)
+		buf = append(buf, levels[i])
+		i = j
+	}
+	binary.LittleEndian.PutUint32(buf, uint32(len(buf)-4))
+	return buf
+}
+
+// encodePage returns the uncompressed contents of a data page containing the
+// values of a column for each row.
+func (c column) encodePage(rows []map[string]interface{}) ([]byte, error) {
+	var levels []byte
+	var page []byte
+	var bits []bool
+
+	for i, row := range rows {
+		v := row[c.Name]
+		if v == nil {
+			if !c.Optional {
+				return nil, fmt.Errorf("row %v: required column '%v' is missing", i, c.Name)
+			}
+			levels = append(levels, 0)
+			continue
+		}
+		if c.Optional {
+			levels = append(levels, 1)
+		}
+		if c.physical == physicalBoolean {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("row %v: column '%v': expected bool value, found %T", i, c.Name, v)
+			}
+			bits = append(bits, b)
+			continue
+		}
+		var err error
+		if page, err = c.appendPlain(page, v); err != nil {
+			return nil, fmt.Errorf("row %v: column '%v': %v", i, c.Name, err)
+		}
+	}
+
+	if c.physical == physicalBoolean {
+		page = make([]byte, (len(bits)+7)/8)
+		for i, b := range bits {
+			if b {
+				page[i/8] |= 1 << uint(i%8)
+			}
+		}
+	}
+	if c.Optional {
+		page = append(encodeLevels(levels), page...)
+	}
+	return page, nil
+}
+
+//------------------------------------------------------------------------------
+
+type chunkMeta struct {
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// Encode a slice of rows, where each row is a map of column names to values,
+// into a Parquet file containing a single row group. Returns an error if a row
+// contains fields that are not columns of the schema.
+func (e *Encoder) Encode(rows []map[string]interface{}) ([]byte, error) {
+	for i, row := range rows {
+		var unknown []string
+		for k := range row {
+			if _, exists := e.names[k]; !exists {
+				unknown = append(unknown, k)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("row %v: fields not within the schema: %v", i, unknown)
+		}
+	}
+
+	buf := []byte(magic)
+	chunks := make([]chunkMeta, len(e.columns))
+
+	for i, c := range e.columns {
+		page, err := c.encodePage(rows)
+		if err != nil {
+			return nil, err
+		}
+		compressed, err := e.compressor.fn(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress column '%v': %v", c.Name, err)
+		}
+
+		w := thriftWriter{}
+		w.structBegin()
+		w.fieldI32(1, pageTypeData)
+		w.fieldI32(2, int32(len(page)))
+		w.fieldI32(3, int32(len(compressed)))
+		w.fieldStruct(5, func() {
+			w.fieldI32(1, int32(len(rows)))
+			w.fieldI32(2, encodingPlain)
+			w.fieldI32(3, encodingRLE)
+			w.fieldI32(4, encodingRLE)
+		})
+		w.structEnd()
+
+		chunks[i] = chunkMeta{
+			offset:           int64(len(buf)),
+			uncompressedSize: int64(len(w.buf) + len(page)),
+			compressedSize:   int64(len(w.buf) + len(compressed)),
+		}
+		buf = append(buf, w.buf...)
+		buf = append(buf, compressed...)
+	}
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.uncompressedSize
+	}
+
+	w := thriftWriter{}
+	w.structBegin()
+	w.fieldI32(1, 1)
+	w.fieldListStruct(2, len(e.columns)+1, func(i int) {
+		if i == 0 {
+			w.fieldString(4, "schema")
+			w.fieldI32(5, int32(len(e.columns)))
+			return
+		}
+		c := e.columns[i-1]
+		w.fieldI32(1, c.physical)
+		if c.Optional {
+			w.fieldI32(3, repetitionOptional)
+		} else {
+			w.fieldI32(3, repetitionRequired)
+		}
+		w.fieldString(4, c.Name)
+		if c.Type == TypeUTF8 {
+			w.fieldI32(6, convertedUTF8)
+		}
+	})
+	w.fieldI64(3, int64(len(rows)))
+	w.fieldListStruct(4, 1, func(int) {
+		w.fieldListStruct(1, len(e.columns), func(i int) {
+			c, chunk := e.columns[i], chunks[i]
+			w.fieldI64(2, chunk.offset)
+			w.fieldStruct(3, func() {
+				w.fieldI32(1, c.physical)
+				w.fieldListI32(2, []int32{encodingPlain, encodingRLE})
+				w.fieldListString(3, []string{c.Name})
+				w.fieldI32(4, e.compressor.id)
+				w.fieldI64(5, int64(len(rows)))
+				w.fieldI64(6, chunk.uncompressedSize)
+				w.fieldI64(7, chunk.compressedSize)
+				w.fieldI64(9, chunk.offset)
+			})
+		})
+		w.fieldI64(2, totalSize)
+		w.fieldI64(3, int64(len(rows)))
+	})
+	w.fieldString(6, "benthos")
+	w.structEnd()
+
+	buf = append(buf, w.buf...)
+	var footerLen [4]byte
+	binary.LittleEndian.PutUint32(footerLen[:], uint32(len(w.buf)))
+	buf = append(buf, footerLen[:]...)
+	return append(buf, magic...), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,366 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+// thriftReader decodes compact protocol structs into maps of field ids to
+// values, which is enough to verify the metadata written by the encoder.
+type thriftReader struct {
+	t   *testing.T
+	buf []byte
+}
+
+func (r *thriftReader) byte() byte {
+	require.NotEmpty(r.t, r.buf)
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	require.True(r.t, n > 0)
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftBoolTrue:
+		return true
+	case thriftBoolFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := r.varint()
+		v := string(r.buf[:n])
+		r.buf = r.buf[n:]
+		return v
+	case thriftList:
+		h := r.byte()
+		size, elemType := int(h>>4), h&0x0f
+		if size == 15 {
+			size = int(r.varint())
+		}
+		var vs []interface{}
+		for i := 0; i < size; i++ {
+			vs = append(vs, r.value(elemType))
+		}
+		return vs
+	case thriftStruct:
+		return r.readStruct()
+	}
+	r.t.Fatalf("unexpected thrift type: %v", typ)
+	return nil
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
+
+func readFooter(t *testing.T, file []byte) map[int16]interface{} {
+	t.Helper()
+
+	require.True(t, len(file) > 12)
+	require.Equal(t, magic, string(file[:4]))
+	require.Equal(t, magic, string(file[len(file)-4:]))
+
+	footerLen := binary.LittleEndian.Uint32(file[len(file)-8:])
+	r := &thriftReader{t: t, buf: file[len(file)-8-int(footerLen) : len(file)-8]}
+	footer := r.readStruct()
+	assert.Empty(t, r.buf)
+	return footer
+}
+
+// readPage returns the uncompressed body of the data page at an offset along
+// with its header.
+func readPage(t *testing.T, file []byte, offset int64, decompress func([]byte) []byte) ([]byte, map[int16]interface{}) {
+	t.Helper()
+
+	r := &thriftReader{t: t, buf: file[offset:]}
+	header := r.readStruct()
+	compressed := r.buf[:header[3].(int64)]
+	page := decompress(compressed)
+	assert.Equal(t, header[2].(int64), int64(len(page)))
+	return page, header
+}
+
+//------------------------------------------------------------------------------
+
+func TestEncoderBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		columns     []Column
+		compression string
+	}{
+		"no columns": {
+			compression: "uncompressed",
+		},
+		"bad type": {
+			columns:     []Column{{Name: "a", Type: "NOPE"}},
+			compression: "uncompressed",
+		},
+		"duplicate column": {
+			columns:     []Column{{Name: "a", Type: TypeInt64}, {Name: "a", Type: TypeUTF8}},
+			compression: "uncompressed",
+		},
+		"empty name": {
+			columns:     []Column{{Type: TypeInt64}},
+			compression: "uncompressed",
+		},
+		"bad compression": {
+			columns:     []Column{{Name: "a", Type: TypeInt64}},
+			compression: "nope",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := NewEncoder(test.columns, test.compression)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEncoderMetadata(t *testing.T) {
+	e, err := NewEncoder([]Column{
+		{Name: "id", Type: TypeInt64},
+		{Name: "name", Type: TypeUTF8, Optional: true},
+	}, "uncompressed")
+	require.NoError(t, err)
+
+	file, err := e.Encode([]map[string]interface{}{
+		{"id": float64(1), "name": "foo"},
+		{"id": float64(2)},
+		{"id": float64(3), "name": "bar"},
+	})
+	require.NoError(t, err)
+
+	footer := readFooter(t, file)
+	assert.Equal(t, int64(1), footer[1])
+	assert.Equal(t, int64(3), footer[3])
+	assert.Equal(t, "benthos", footer[6])
+	assert.Equal(t, []interface{}{
+		map[int16]interface{}{4: "schema", 5: int64(2)},
+		map[int16]interface{}{1: int64(physicalInt64), 3: int64(repetitionRequired), 4: "id"},
+		map[int16]interface{}{1: int64(physicalByteArray), 3: int64(repetitionOptional), 4: "name", 6: int64(convertedUTF8)},
+	}, footer[2])
+
+	rowGroups := footer[4].([]interface{})
+	require.Len(t, rowGroups, 1)
+	rowGroup := rowGroups[0].(map[int16]interface{})
+	assert.Equal(t, int64(3), rowGroup[3])
+
+	chunks := rowGroup[1].([]interface{})
+	require.Len(t, chunks, 2)
+
+	noop := func(b []byte) []byte { return b }
+
+	idMeta := chunks[0].(map[int16]interface{})[3].(map[int16]interface{})
+	assert.Equal(t, []interface{}{"id"}, idMeta[3])
+	assert.Equal(t, int64(codecUncompressed), idMeta[4])
+	assert.Equal(t, int64(3), idMeta[5])
+	assert.Equal(t, int64(4), idMeta[9])
+
+	page, header := readPage(t, file, idMeta[9].(int64), noop)
+	assert.Equal(t, int64(pageTypeData), header[1])
+	assert.Equal(t, map[int16]interface{}{
+		1: int64(3), 2: int64(encodingPlain), 3: int64(encodingRLE), 4: int64(encodingRLE),
+	}, header[5])
+	assert.Equal(t, []byte{
+		1, 0, 0, 0, 0, 0, 0, 0,
+		2, 0, 0, 0, 0, 0, 0, 0,
+		3, 0, 0, 0, 0, 0, 0, 0,
+	}, page)
+
+	nameMeta := chunks[1].(map[int16]interface{})[3].(map[int16]interface{})
+	assert.Equal(t, []interface{}{"name"}, nameMeta[3])
+
+	page, _ = readPage(t, file, nameMeta[9].(int64), noop)
+	assert.Equal(t, []byte{
+		6, 0, 0, 0, // Definition levels length
+		2, 1, 2, 0, 2, 1, // Runs of 1, 0, 1
+		3, 0, 0, 0, 'f', 'o', 'o',
+		3, 0, 0, 0, 'b', 'a', 'r',
+	}, page)
+}
+
+func TestEncoderTypes(t *testing.T) {
+	e, err := NewEncoder([]Column{
+		{Name: "b", Type: TypeBoolean},
+		{Name: "i32", Type: TypeInt32},
+		{Name: "f", Type: TypeFloat},
+		{Name: "d", Type: TypeDouble},
+		{Name: "raw", Type: TypeByteArray},
+	}, "uncompressed")
+	require.NoError(t, err)
+
+	file, err := e.Encode([]map[string]interface{}{
+		{"b": true, "i32": float64(-1), "f": 1.5, "d": 2.5, "raw": map[string]interface{}{"a": "b"}},
+		{"b": false, "i32": float64(2), "f": float64(0), "d": float64(-1), "raw": "c"},
+		{"b": true, "i32": float64(3), "f": float64(0), "d": float64(0), "raw": "d"},
+	})
+	require.NoError(t, err)
+
+	footer := readFooter(t, file)
+	chunks := footer[4].([]interface{})[0].(map[int16]interface{})[1].([]interface{})
+	pages := make([][]byte, len(chunks))
+	for i, c := range chunks {
+		offset := c.(map[int16]interface{})[3].(map[int16]interface{})[9].(int64)
+		pages[i], _ = readPage(t, file, offset, func(b []byte) []byte { return b })
+	}
+
+	assert.Equal(t, []byte{0x05}, pages[0])
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 2, 0, 0, 0, 3, 0, 0, 0}, pages[1])
+	assert.Equal(t, float32(1.5), math.Float32frombits(binary.LittleEndian.Uint32(pages[2])))
+	assert.Equal(t, float64(-1), math.Float64frombits(binary.LittleEndian.Uint64(pages[3][8:])))
+	assert.Equal(t, []byte{
+		9, 0, 0, 0, '{', '"', 'a', '"', ':', '"', 'b', '"', '}',
+		1, 0, 0, 0, 'c',
+		1, 0, 0, 0, 'd',
+	}, pages[4])
+}
+
+func TestEncoderErrors(t *testing.T) {
+	tests := map[string]struct {
+		column Column
+		value  interface{}
+	}{
+		"missing required": {
+			column: Column{Name: "a", Type: TypeInt64},
+		},
+		"bool mismatch": {
+			column: Column{Name: "a", Type: TypeBoolean},
+			value:  "true",
+		},
+		"int mismatch": {
+			column: Column{Name: "a", Type: TypeInt64},
+			value:  "1",
+		},
+		"int fraction": {
+			column: Column{Name: "a", Type: TypeInt64},
+			value:  1.5,
+		},
+		"int32 overflow": {
+			column: Column{Name: "a", Type: TypeInt32},
+			value:  float64(math.MaxInt32 + 1),
+		},
+		"double mismatch": {
+			column: Column{Name: "a", Type: TypeDouble},
+			value:  true,
+		},
+	}
+
+	for name, test := range tests {
+		e, err := NewEncoder([]Column{test.column}, "uncompressed")
+		require.NoError(t, err, name)
+
+		row := map[string]interface{}{}
+		if test.value != nil {
+			row["a"] = test.value
+		}
+		_, err = e.Encode([]map[string]interface{}{row})
+		assert.Error(t, err, name)
+	}
+
+	e, err := NewEncoder([]Column{{Name: "a", Type: TypeInt64}}, "uncompressed")
+	require.NoError(t, err)
+
+	_, err = e.Encode([]map[string]interface{}{
+		{"a": 1},
+		{"a": 2, "c": 3, "b": 4},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "row 1: fields not within the schema: [b c]", err.Error())
+}
+
+func TestEncoderCompression(t *testing.T) {
+	zstdDec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer zstdDec.Close()
+
+	tests := map[string]struct {
+		codec      int64
+		decompress func([]byte) ([]byte, error)
+	}{
+		"uncompressed": {
+			codec:      codecUncompressed,
+			decompress: func(b []byte) ([]byte, error) { return b, nil },
+		},
+		"snappy": {
+			codec: codecSnappy,
+			decompress: func(b []byte) ([]byte, error) {
+				return snappy.Decode(nil, b)
+			},
+		},
+		"gzip": {
+			codec: codecGzip,
+			decompress: func(b []byte) ([]byte, error) {
+				r, err := gzip.NewReader(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(r)
+			},
+		},
+		"zstd": {
+			codec: codecZstd,
+			decompress: func(b []byte) ([]byte, error) {
+				return zstdDec.DecodeAll(b, nil)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEncoder([]Column{{Name: "a", Type: TypeUTF8}}, name)
+			require.NoError(t, err)
+
+			file, err := e.Encode([]map[string]interface{}{{"a": "hello world"}})
+			require.NoError(t, err)
+
+			footer := readFooter(t, file)
+			meta := footer[4].([]interface{})[0].(map[int16]interface{})[1].([]interface{})[0].(map[int16]interface{})[3].(map[int16]interface{})
+			assert.Equal(t, test.codec, meta[4])
+
+			page, _ := readPage(t, file, meta[9].(int64), func(b []byte) []byte {
+				d, err := test.decompress(b)
+				require.NoError(t, err)
+				return d
+			})
+			assert.Equal(t, append([]byte{11, 0, 0, 0}, "hello world"...), page)
+		})
+	}
+}
+
+//------------------------------------------------------------------------------
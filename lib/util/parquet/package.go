@@ -0,0 +1,4 @@
+// Package parquet implements a minimal encoder of Apache Parquet files with
+// flat schemas, where each encoded batch of rows is written as a single row
+// group.
+package parquet
@@ -0,0 +1,167 @@
+package parquet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+//------------------------------------------------------------------------------
+
+// Column types supported by the encoder. UTF8 columns are byte arrays that are
+// annotated as strings.
+const (
+	TypeBoolean   = "BOOLEAN"
+	TypeInt32     = "INT32"
+	TypeInt64     = "INT64"
+	TypeFloat     = "FLOAT"
+	TypeDouble    = "DOUBLE"
+	TypeByteArray = "BYTE_ARRAY"
+	TypeUTF8      = "UTF8"
+)
+
+// Physical types, repetition types and converted types of the Parquet format.
+const (
+	physicalBoolean   = 0
+	physicalInt32     = 1
+	physicalInt64     = 2
+	physicalFloat     = 4
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedUTF8 = 0
+)
+
+// Column describes a column of a flat schema.
+type Column struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+	Optional bool   `json:"optional" yaml:"optional"`
+}
+
+func physicalType(typeStr string) (int32, error) {
+	switch typeStr {
+	case TypeBoolean:
+		return physicalBoolean, nil
+	case TypeInt32:
+		return physicalInt32, nil
+	case TypeInt64:
+		return physicalInt64, nil
+	case TypeFloat:
+		return physicalFloat, nil
+	case TypeDouble:
+		return physicalDouble, nil
+	case TypeByteArray, TypeUTF8:
+		return physicalByteArray, nil
+	}
+	return 0, fmt.Errorf("column type not recognised: %v", typeStr)
+}
+
+//------------------------------------------------------------------------------
+
+func inferType(v interface{}) string {
+	switch t := v.(type) {
+	case bool:
+		return TypeBoolean
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return TypeInt64
+		}
+		return TypeDouble
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return TypeInt64
+		}
+		return TypeDouble
+	case int, int32, int64:
+		return TypeInt64
+	}
+	return TypeUTF8
+}
+
+// InferColumns creates a schema from the fields of a slice of documents. Each
+// document must be an object, and each column of the resulting schema is
+// optional and sorted by name.
+//
+// Numbers are inferred as INT64 columns unless a value has a fractional part,
+// in which case the column is DOUBLE. Fields with values of differing types,
+// objects and arrays are inferred as UTF8 columns.
+func InferColumns(docs []interface{}) ([]Column, error) {
+	types := map[string]string{}
+	for i, doc := range docs {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("document %v is not an object: %T", i, doc)
+		}
+		for k, v := range obj {
+			if v == nil {
+				if _, exists := types[k]; !exists {
+					types[k] = ""
+				}
+				continue
+			}
+			t := inferType(v)
+			switch existing := types[k]; {
+			case existing == "" || existing == t:
+				types[k] = t
+			case (existing == TypeInt64 && t == TypeDouble) || (existing == TypeDouble && t == TypeInt64):
+				types[k] = TypeDouble
+			default:
+				types[k] = TypeUTF8
+			}
+		}
+	}
+
+	columns := make([]Column, 0, len(types))
+	for k, t := range types {
+		if t == "" {
+			t = TypeUTF8
+		}
+		columns = append(columns, Column{
+			Name:     k,
+			Type:     t,
+			Optional: true,
+		})
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].Name < columns[j].Name
+	})
+	return columns, nil
+}
+
+// MergeColumns returns a schema containing the columns of an existing schema
+// followed by any columns of another schema that it lacks. Columns present in
+// both schemas keep the type of the existing schema, unless an INT64 column is
+// merged with a DOUBLE column, in which case it is widened to DOUBLE. Returns
+// true if the resulting schema differs from the existing schema.
+func MergeColumns(existing, columns []Column) ([]Column, bool) {
+	merged := make([]Column, len(existing))
+	copy(merged, existing)
+
+	indexes := make(map[string]int, len(merged))
+	for i, c := range merged {
+		indexes[c.Name] = i
+	}
+
+	changed := false
+	for _, c := range columns {
+		i, exists := indexes[c.Name]
+		if !exists {
+			indexes[c.Name] = len(merged)
+			merged = append(merged, c)
+			changed = true
+			continue
+		}
+		if merged[i].Type == TypeInt64 && c.Type == TypeDouble {
+			merged[i].Type = TypeDouble
+			changed = true
+		}
+	}
+	return merged, changed
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,62 @@
+package parquet
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferColumns(t *testing.T) {
+	var docs []interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"id":1,"name":"foo","score":1,"ok":true,"tags":["a"],"mixed":1,"empty":null},
+		{"id":2,"name":"bar","score":1.5,"ok":false,"mixed":"a"}
+	]`), &docs))
+
+	columns, err := InferColumns(docs)
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Name: "empty", Type: TypeUTF8, Optional: true},
+		{Name: "id", Type: TypeInt64, Optional: true},
+		{Name: "mixed", Type: TypeUTF8, Optional: true},
+		{Name: "name", Type: TypeUTF8, Optional: true},
+		{Name: "ok", Type: TypeBoolean, Optional: true},
+		{Name: "score", Type: TypeDouble, Optional: true},
+		{Name: "tags", Type: TypeUTF8, Optional: true},
+	}, columns)
+}
+
+func TestInferColumnsNotObject(t *testing.T) {
+	_, err := InferColumns([]interface{}{map[string]interface{}{}, "nope"})
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "document 1 is not an object"), err.Error())
+}
+
+func TestMergeColumns(t *testing.T) {
+	existing := []Column{
+		{Name: "b", Type: TypeInt64, Optional: true},
+		{Name: "c", Type: TypeUTF8, Optional: true},
+	}
+
+	merged, changed := MergeColumns(existing, []Column{
+		{Name: "b", Type: TypeInt64, Optional: true},
+		{Name: "c", Type: TypeInt64, Optional: true},
+	})
+	assert.False(t, changed)
+	assert.Equal(t, existing, merged)
+
+	merged, changed = MergeColumns(existing, []Column{
+		{Name: "a", Type: TypeBoolean, Optional: true},
+		{Name: "b", Type: TypeDouble, Optional: true},
+	})
+	assert.True(t, changed)
+	assert.Equal(t, []Column{
+		{Name: "b", Type: TypeDouble, Optional: true},
+		{Name: "c", Type: TypeUTF8, Optional: true},
+		{Name: "a", Type: TypeBoolean, Optional: true},
+	}, merged)
+	assert.Equal(t, TypeInt64, existing[0].Type)
+}
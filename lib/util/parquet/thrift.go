@@ -0,0 +1,130 @@
+package parquet
+
+import (
+	"encoding/binary"
+)
+
+//------------------------------------------------------------------------------
+
+// Type identifiers of the Thrift compact protocol.
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftStruct    = 12
+)
+
+// thriftWriter serialises structures with the Thrift compact protocol, which
+// is used for all Parquet metadata.
+type thriftWriter struct {
+	buf       []byte
+	lastField []int16
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf = append(w.buf, b[:n]...)
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := w.lastField[len(w.lastField)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.zigzag(int64(id))
+	}
+	w.lastField[len(w.lastField)-1] = id
+}
+
+func (w *thriftWriter) structBegin() {
+	w.lastField = append(w.lastField, 0)
+}
+
+func (w *thriftWriter) structEnd() {
+	w.buf = append(w.buf, 0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+func (w *thriftWriter) listHeader(size int, elemType byte) {
+	if size < 15 {
+		w.buf = append(w.buf, byte(size)<<4|elemType)
+	} else {
+		w.buf = append(w.buf, 0xf0|elemType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) fieldBool(id int16, v bool) {
+	if v {
+		w.fieldHeader(id, thriftBoolTrue)
+	} else {
+		w.fieldHeader(id, thriftBoolFalse)
+	}
+}
+
+func (w *thriftWriter) fieldI32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) fieldI64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) binary(v []byte) {
+	w.varint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *thriftWriter) fieldString(id int16, v string) {
+	w.fieldHeader(id, thriftBinary)
+	w.binary([]byte(v))
+}
+
+// fieldStruct writes a struct field, where fn writes the fields of the struct.
+func (w *thriftWriter) fieldStruct(id int16, fn func()) {
+	w.fieldHeader(id, thriftStruct)
+	w.structBegin()
+	fn()
+	w.structEnd()
+}
+
+func (w *thriftWriter) fieldListI32(id int16, vs []int32) {
+	w.fieldHeader(id, thriftList)
+	w.listHeader(len(vs), thriftI32)
+	for _, v := range vs {
+		w.zigzag(int64(v))
+	}
+}
+
+func (w *thriftWriter) fieldListString(id int16, vs []string) {
+	w.fieldHeader(id, thriftList)
+	w.listHeader(len(vs), thriftBinary)
+	for _, v := range vs {
+		w.binary([]byte(v))
+	}
+}
+
+// fieldListStruct writes a list of n structs, where fn writes the fields of
+// the struct at an index.
+func (w *thriftWriter) fieldListStruct(id int16, n int, fn func(i int)) {
+	w.fieldHeader(id, thriftList)
+	w.listHeader(n, thriftStruct)
+	for i := 0; i < n; i++ {
+		w.structBegin()
+		fn(i)
+		w.structEnd()
+	}
+}
+
+//------------------------------------------------------------------------------
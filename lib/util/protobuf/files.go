@@ -0,0 +1,205 @@
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// Register the well known types so that schemas are able to import them.
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+//------------------------------------------------------------------------------
+
+// resolver looks up descriptors from a set of files, falling back to the
+// global registry, which contains the well known types.
+type resolver struct {
+	files *protoregistry.Files
+}
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	fd, err := r.files.FindFileByPath(path)
+	if err == protoregistry.NotFound {
+		fd, err = protoregistry.GlobalFiles.FindFileByPath(path)
+	}
+	return fd, err
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	d, err := r.files.FindDescriptorByName(name)
+	if err == protoregistry.NotFound {
+		d, err = protoregistry.GlobalFiles.FindDescriptorByName(name)
+	}
+	return d, err
+}
+
+//------------------------------------------------------------------------------
+
+// NewFiles creates a registry of file descriptors from a set of file
+// descriptor protos. Dependencies of each file must either be present within
+// the set or be one of the well known types.
+func NewFiles(protos []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	byPath := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, fdp := range protos {
+		byPath[fdp.GetName()] = fdp
+	}
+
+	files := &protoregistry.Files{}
+	r := resolver{files: files}
+	building := map[string]bool{}
+
+	var addFile func(fdp *descriptorpb.FileDescriptorProto) error
+	addFile = func(fdp *descriptorpb.FileDescriptorProto) error {
+		if _, err := r.FindFileByPath(fdp.GetName()); err == nil {
+			return nil
+		}
+		if building[fdp.GetName()] {
+			return fmt.Errorf("import cycle detected at file '%v'", fdp.GetName())
+		}
+		building[fdp.GetName()] = true
+
+		for _, dep := range fdp.GetDependency() {
+			depProto, exists := byPath[dep]
+			if !exists {
+				if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err != nil {
+					return fmt.Errorf("file '%v' imports '%v', which was not found", fdp.GetName(), dep)
+				}
+				continue
+			}
+			if err := addFile(depProto); err != nil {
+				return err
+			}
+		}
+
+		fd, err := protodesc.NewFile(fdp, r)
+		if err != nil {
+			return fmt.Errorf("failed to build file '%v': %v", fdp.GetName(), err)
+		}
+		return files.RegisterFile(fd)
+	}
+
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := addFile(byPath[path]); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// LoadFiles creates a registry of file descriptors from schemas on disk. Each
+// import path is a directory that is walked for .proto files, which are named
+// by their path relative to the directory, and each descriptor set is a file
+// containing a serialised FileDescriptorSet, as produced by protoc with the
+// flag --descriptor_set_out.
+func LoadFiles(importPaths, descriptorSets []string) (*protoregistry.Files, error) {
+	var protos []*descriptorpb.FileDescriptorProto
+
+	for _, setPath := range descriptorSets {
+		setBytes, err := ioutil.ReadFile(setPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err = proto.Unmarshal(setBytes, &set); err != nil {
+			return nil, fmt.Errorf("failed to parse descriptor set '%v': %v", setPath, err)
+		}
+		protos = append(protos, set.GetFile()...)
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for _, importPath := range importPaths {
+		if err := filepath.Walk(importPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(path, ".proto") {
+				return nil
+			}
+			relPath, err := filepath.Rel(importPath, path)
+			if err != nil {
+				return err
+			}
+			if relPath = filepath.ToSlash(relPath); !seen[relPath] {
+				seen[relPath] = true
+				paths = append(paths, relPath)
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to load import path '%v': %v", importPath, err)
+		}
+	}
+
+	if len(paths) > 0 {
+		// Files within descriptor sets can be imported by schemas on disk.
+		setFiles := map[string]*descriptorpb.FileDescriptorProto{}
+		for _, fdp := range protos {
+			setFiles[fdp.GetName()] = fdp
+		}
+		compiled, err := parseFiles(protoparse.Parser{
+			ImportPaths:  importPaths,
+			LookupImport: lookupProtos(protos),
+		}, paths)
+		if err != nil {
+			return nil, err
+		}
+		for _, fdp := range compiled {
+			if _, exists := setFiles[fdp.GetName()]; !exists {
+				protos = append(protos, fdp)
+			}
+		}
+	}
+
+	return NewFiles(protos)
+}
+
+// LoadPath creates a registry of file descriptors from a path on disk, which
+// is treated as an import path when it is a directory and otherwise as a
+// descriptor set.
+func LoadPath(path string) (*protoregistry.Files, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return LoadFiles([]string{path}, nil)
+	}
+	return LoadFiles(nil, []string{path})
+}
+
+// FindMessage returns the descriptor of a message from a registry of files by
+// its full name.
+func FindMessage(files *protoregistry.Files, name string) (protoreflect.MessageDescriptor, error) {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find message '%v': %v", name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("descriptor '%v' is not a message", name)
+	}
+	return md, nil
+}
+
+//------------------------------------------------------------------------------
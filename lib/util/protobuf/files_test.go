@@ -0,0 +1,85 @@
+package protobuf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestLoadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_protobuf_test_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	importDir := filepath.Join(dir, "protos")
+	require.NoError(t, os.MkdirAll(filepath.Join(importDir, "foo"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(importDir, "foo", "a.proto"), []byte(`
+syntax = "proto3";
+package foo;
+import "foo/b.proto";
+message A { B b = 1; }
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(importDir, "foo", "b.proto"), []byte(`
+syntax = "proto3";
+package foo;
+message B { string name = 1; }
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(importDir, "README.md"), []byte(`not a schema`), 0644))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(importDir, "foo", "d.proto"), []byte(`
+syntax = "proto3";
+package foo;
+import "bar/c.proto";
+message D { bar.C c = 1; }
+`), 0644))
+
+	setFiles, err := ParseFiles(map[string]string{
+		"bar/c.proto": `
+syntax = "proto3";
+package bar;
+message C { int64 id = 1; }
+`,
+	})
+	require.NoError(t, err)
+
+	fd, err := setFiles.FindFileByPath("bar/c.proto")
+	require.NoError(t, err)
+
+	setBytes, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(fd)},
+	})
+	require.NoError(t, err)
+
+	setPath := filepath.Join(dir, "set.pb")
+	require.NoError(t, ioutil.WriteFile(setPath, setBytes, 0644))
+
+	files, err := LoadFiles([]string{importDir}, []string{setPath})
+	require.NoError(t, err)
+
+	// Schemas on disk are able to import files of descriptor sets.
+	for _, name := range []string{"foo.A", "foo.B", "bar.C", "foo.D"} {
+		_, err = FindMessage(files, name)
+		assert.NoError(t, err, name)
+	}
+
+	_, err = LoadPath(importDir)
+	assert.Error(t, err)
+
+	files, err = LoadPath(setPath)
+	require.NoError(t, err)
+	_, err = FindMessage(files, "bar.C")
+	assert.NoError(t, err)
+
+	_, err = LoadPath(filepath.Join(dir, "nope"))
+	assert.Error(t, err)
+
+	_, err = LoadFiles(nil, []string{filepath.Join(importDir, "foo", "a.proto")})
+	assert.Error(t, err)
+}
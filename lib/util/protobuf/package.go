@@ -0,0 +1,4 @@
+// Package protobuf provides utilities for parsing protobuf schemas at runtime
+// and building descriptors from them, which can be used in order to encode
+// and decode messages without generated code.
+package protobuf
@@ -0,0 +1,72 @@
+package protobuf
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+//------------------------------------------------------------------------------
+
+// parseFiles parses and links a list of .proto files with protoparse, which
+// follows the same rules as protoc, and returns a file descriptor for each. The
+// well known types (google/protobuf/*.proto) can always be imported.
+func parseFiles(parser protoparse.Parser, paths []string) ([]*descriptorpb.FileDescriptorProto, error) {
+	fds, err := parser.ParseFiles(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schemas: %v", err)
+	}
+	protos := make([]*descriptorpb.FileDescriptorProto, 0, len(fds))
+	for _, fd := range fds {
+		protos = append(protos, fd.AsFileDescriptorProto())
+	}
+	return protos, nil
+}
+
+// lookupProtos returns a function that looks up descriptors of files by their
+// path from a set of file descriptor protos, which allows parsed schemas to
+// import them.
+func lookupProtos(protos []*descriptorpb.FileDescriptorProto) func(string) (*desc.FileDescriptor, error) {
+	var fds map[string]*desc.FileDescriptor
+	var err error
+	return func(path string) (*desc.FileDescriptor, error) {
+		if fds == nil && err == nil {
+			if fds, err = desc.CreateFileDescriptors(protos); err != nil {
+				err = fmt.Errorf("failed to build descriptor sets: %v", err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		fd, exists := fds[path]
+		if !exists {
+			return nil, fmt.Errorf("file '%v' was not found", path)
+		}
+		return fd, nil
+	}
+}
+
+// ParseFiles parses a map of file paths to protobuf schemas and creates a
+// registry of file descriptors from them. Imports are resolved by path from
+// within the map.
+func ParseFiles(schemas map[string]string) (*protoregistry.Files, error) {
+	paths := make([]string, 0, len(schemas))
+	for path := range schemas {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	protos, err := parseFiles(protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(schemas),
+	}, paths)
+	if err != nil {
+		return nil, err
+	}
+	return NewFiles(protos)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,291 @@
+package protobuf
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testSchema = `
+// A test schema.
+syntax = "proto3";
+
+package foo.bar;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/foo/bar";
+
+/* Some enums
+   and stuff. */
+enum Status {
+  option allow_alias = true;
+  UNKNOWN = 0;
+  ACTIVE = 1;
+  ENABLED = 1 [deprecated = true];
+  reserved 5 to 10;
+}
+
+message Person {
+  string name = 1 [json_name = "fullName"];
+  int32 age = 2;
+  repeated string emails = 3 [packed = false];
+  Status status = 4;
+  map<string, int64> scores = 5;
+  Address address = 6;
+  google.protobuf.Timestamp born = 7;
+  double height = 8;
+
+  oneof contact {
+    string phone = 9;
+    Address postal = 10;
+  }
+
+  message Address {
+    string street = 1;
+    uint64 number = 2;
+  }
+
+  reserved 20, 21;
+  reserved "old_field";
+}
+
+service People {
+  rpc Get (Person) returns (Person) {
+    option deprecated = true;
+  }
+}
+`
+
+func TestParseAndBuild(t *testing.T) {
+	files, err := ParseFiles(map[string]string{
+		"foo/bar.proto": testSchema,
+	})
+	require.NoError(t, err)
+
+	md, err := FindMessage(files, "foo.bar.Person")
+	require.NoError(t, err)
+
+	fields := md.Fields()
+	assert.Equal(t, 10, fields.Len())
+	assert.Equal(t, "fullName", fields.ByName("name").JSONName())
+	assert.True(t, fields.ByName("scores").IsMap())
+	assert.Equal(t, protoreflect.EnumKind, fields.ByName("status").Kind())
+	assert.Equal(t, protoreflect.FullName("foo.bar.Person.Address"), fields.ByName("address").Message().FullName())
+	assert.Equal(t, protoreflect.FullName("google.protobuf.Timestamp"), fields.ByName("born").Message().FullName())
+	assert.Equal(t, "contact", string(fields.ByName("postal").ContainingOneof().Name()))
+
+	msg := dynamicpb.NewMessage(md)
+	require.NoError(t, protojson.Unmarshal([]byte(`{
+		"fullName": "Ash",
+		"age": 30,
+		"status": "ENABLED",
+		"scores": {"a": "5"},
+		"address": {"street": "Main", "number": "10"},
+		"born": "2020-01-01T00:00:00Z",
+		"postal": {"street": "Side"}
+	}`), msg))
+
+	b, err := proto.Marshal(msg)
+	require.NoError(t, err)
+
+	result := dynamicpb.NewMessage(md)
+	require.NoError(t, proto.Unmarshal(b, result))
+	assert.True(t, proto.Equal(msg, result))
+}
+
+func TestParseImports(t *testing.T) {
+	files, err := ParseFiles(map[string]string{
+		"a.proto": `
+syntax = "proto3";
+package a;
+import public "b.proto";
+message A { b.B b = 1; }
+`,
+		"b.proto": `
+syntax = "proto2";
+package b;
+message B {
+  required string name = 1 [default = "foo"];
+  optional sint32 num = 2 [default = -5];
+}
+`,
+	})
+	require.NoError(t, err)
+
+	md, err := FindMessage(files, "a.A")
+	require.NoError(t, err)
+
+	b := md.Fields().ByName("b").Message()
+	assert.Equal(t, "foo", b.Fields().ByName("name").Default().String())
+	assert.Equal(t, int64(-5), b.Fields().ByName("num").Default().Int())
+}
+
+func TestParseEdgeCases(t *testing.T) {
+	files, err := ParseFiles(map[string]string{
+		"opts.proto": `
+syntax = "proto2";
+package opts;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.FieldOptions {
+  optional string tag = 50000;
+}
+`,
+		"foo.proto": `
+syntax = "proto2";
+package foo;
+import "opts.proto";
+
+message Outer {
+  message Inner {
+    extend Outer {
+      optional Inner inner = 100;
+    }
+    optional int32 id = 1 [(opts.tag) = "inner_id"];
+  }
+  optional group Result = 1 {
+    optional string url = 2;
+  }
+  map<string, Inner> inners = 3;
+  oneof choice {
+    Inner first = 4;
+    string second = 5 [default = "two"];
+  }
+  extensions 100 to 199;
+}
+`,
+	})
+	require.NoError(t, err)
+
+	md, err := FindMessage(files, "foo.Outer")
+	require.NoError(t, err)
+
+	fields := md.Fields()
+	assert.Equal(t, protoreflect.GroupKind, fields.ByName("result").Kind())
+	assert.Equal(t, protoreflect.FullName("foo.Outer.Result"), fields.ByName("result").Message().FullName())
+	assert.True(t, fields.ByName("inners").IsMap())
+	assert.Equal(t, "choice", string(fields.ByName("second").ContainingOneof().Name()))
+	assert.Equal(t, "two", fields.ByName("second").Default().String())
+
+	inner := md.Messages().ByName("Inner")
+	require.NotNil(t, inner)
+	require.Equal(t, 1, inner.Extensions().Len())
+	assert.Equal(t, protoreflect.FullName("foo.Outer"), inner.Extensions().Get(0).ContainingMessage().FullName())
+	assert.Equal(t, protoreflect.FieldNumber(100), inner.Extensions().Get(0).Number())
+
+	d, err := files.FindDescriptorByName("foo.Outer.Inner.inner")
+	require.NoError(t, err)
+	_, ok := d.(protoreflect.ExtensionDescriptor)
+	assert.True(t, ok)
+}
+
+// TestParseMatchesProtoc compares the descriptors of a schema that exercises
+// many edge cases of the language, including custom options, extensions,
+// groups and unusual identifiers, with the descriptors produced by protoc.
+func TestParseMatchesProtoc(t *testing.T) {
+	schema, err := ioutil.ReadFile("testdata/desc_test_complex.proto")
+	require.NoError(t, err)
+
+	files, err := ParseFiles(map[string]string{
+		"desc_test_complex.proto": string(schema),
+	})
+	require.NoError(t, err)
+
+	setBytes, err := ioutil.ReadFile("testdata/desc_test_complex.protoset")
+	require.NoError(t, err)
+
+	var set descriptorpb.FileDescriptorSet
+	require.NoError(t, proto.Unmarshal(setBytes, &set))
+
+	var exp *descriptorpb.FileDescriptorProto
+	for _, fdp := range set.GetFile() {
+		if fdp.GetName() == "desc_test_complex.proto" {
+			exp = fdp
+		}
+	}
+	require.NotNil(t, exp)
+
+	fd, err := files.FindFileByPath("desc_test_complex.proto")
+	require.NoError(t, err)
+
+	// Custom options are not retained by protoparse for this version of the
+	// protobuf runtime, they have no effect on encoding or decoding messages and
+	// are therefore removed from the descriptors produced by protoc.
+	var stripUnknown func(m protoreflect.Message)
+	stripUnknown = func(m protoreflect.Message) {
+		m.SetUnknown(nil)
+		m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+				return true
+			}
+			if fd.IsList() {
+				for i := 0; i < v.List().Len(); i++ {
+					stripUnknown(v.List().Get(i).Message())
+				}
+			} else if !fd.IsMap() {
+				stripUnknown(v.Message())
+			}
+			return true
+		})
+	}
+
+	normalise := func(fdp *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+		b, err := proto.Marshal(fdp)
+		require.NoError(t, err)
+
+		res := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, proto.UnmarshalOptions{Resolver: &protoregistry.Types{}}.Unmarshal(b, res))
+		stripUnknown(res.ProtoReflect())
+		return res
+	}
+
+	exp, act := normalise(exp), normalise(protodesc.ToFileDescriptorProto(fd))
+	assert.Equal(t, prototext.Format(exp), prototext.Format(act))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad syntax":        `syntax = "proto4";`,
+		"unterminated":      `message Foo {`,
+		"missing number":    `message Foo { string foo = ; }`,
+		"bad string":        `syntax = "proto3`,
+		"unexpected token":  `message Foo { string foo = 1; } }`,
+		"duplicate number":  `syntax = "proto3"; message Foo { string foo = 1; string bar = 1; }`,
+		"proto3 required":   `syntax = "proto3"; message Foo { required string foo = 1; }`,
+		"proto3 groups":     `syntax = "proto3"; message Foo { group Bar = 1 { } }`,
+		"unknown option":    `syntax = "proto3"; message Foo { string foo = 1 [(nope) = 1]; }`,
+		"bad extension":     `syntax = "proto2"; message Foo {} extend Foo { optional string bar = 1; }`,
+		"reserved conflict": `syntax = "proto3"; message Foo { reserved 1; string foo = 1; }`,
+	}
+
+	for name, schema := range tests {
+		_, err := ParseFiles(map[string]string{"test.proto": schema})
+		assert.Error(t, err, name)
+	}
+
+	_, err := ParseFiles(map[string]string{
+		"a.proto": `syntax = "proto3"; import "nope.proto"; message A {}`,
+	})
+	assert.Error(t, err)
+
+	_, err = ParseFiles(map[string]string{
+		"a.proto": `syntax = "proto3"; message A { B b = 1; }`,
+	})
+	assert.Error(t, err)
+
+	_, err = ParseFiles(map[string]string{
+		"a.proto": `syntax = "proto3"; import "b.proto"; message A {}`,
+		"b.proto": `syntax = "proto3"; import "a.proto"; message B {}`,
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,23 @@
+package schemaregistry
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/Jeffail/benthos/v3/lib/x/docs"
+)
+
+// FieldSpec returns a documentation spec for schema registry fields.
+func FieldSpec() docs.FieldSpec {
+	specs := docs.FieldSpecs{
+		docs.FieldCommon("url", "The base URL of a schema registry, when empty the registry is not used.", "http://localhost:8081"),
+		docs.FieldCommon("subject", "The subject to resolve the latest schema from when encoding documents. Decoded documents carry the ID of their schema and therefore do not use this field."),
+		docs.FieldAdvanced("subject_refresh_period", "The period after which the latest schema of a subject is resolved again. Schemas fetched by ID are immutable and cached for the lifetime of the component."),
+		docs.FieldAdvanced("timeout", "A static timeout to apply to requests."),
+	}
+	specs = append(specs, auth.FieldSpecs()...)
+	specs = append(specs, tls.FieldSpec())
+
+	return docs.FieldCommon(
+		"schema_registry", "Optional configuration for fetching schemas from a [Confluent compatible schema registry](https://docs.confluent.io/current/schema-registry/index.html).",
+	).WithChildren(specs...)
+}
@@ -0,0 +1,4 @@
+// Package schemaregistry implements a client for Confluent compatible schema
+// registries along with helpers for the wire format used by registry aware
+// serializers.
+package schemaregistry
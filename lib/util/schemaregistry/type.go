@@ -0,0 +1,240 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+	"github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration fields for a schema registry client.
+type Config struct {
+	URL                  string     `json:"url" yaml:"url"`
+	Subject              string     `json:"subject" yaml:"subject"`
+	SubjectRefreshPeriod string     `json:"subject_refresh_period" yaml:"subject_refresh_period"`
+	Timeout              string     `json:"timeout" yaml:"timeout"`
+	TLS                  tls.Config `json:"tls" yaml:"tls"`
+	auth.Config          `json:",inline" yaml:",inline"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		URL:                  "",
+		Subject:              "",
+		SubjectRefreshPeriod: "10m",
+		Timeout:              "5s",
+		TLS:                  tls.NewConfig(),
+		Config:               auth.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Schema types reported by a registry. Registries omit the type of Avro
+// schemas, and therefore an empty type is also considered Avro.
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// Reference is a reference from a schema to another schema registered under
+// a subject.
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema is a schema obtained from a registry.
+type Schema struct {
+	ID         int         `json:"id"`
+	Subject    string      `json:"subject"`
+	Version    int         `json:"version"`
+	Type       string      `json:"schemaType"`
+	Schema     string      `json:"schema"`
+	References []Reference `json:"references"`
+}
+
+//------------------------------------------------------------------------------
+
+const pathMetaKey = "schema_registry_path"
+
+type cachedSchema struct {
+	schema    *Schema
+	fetchedAt time.Time
+}
+
+// Client fetches schemas from a registry and caches them.
+type Client struct {
+	subject       string
+	refreshPeriod time.Duration
+
+	http *client.Type
+
+	cacheMut sync.Mutex
+	byID     map[int]*Schema
+	latest   map[string]cachedSchema
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// New creates a new schema registry client from a config.
+func New(conf Config, log log.Modular, stats metrics.Type) (*Client, error) {
+	if len(conf.URL) == 0 {
+		return nil, fmt.Errorf("schema registry url must not be empty")
+	}
+
+	c := &Client{
+		subject:   conf.Subject,
+		byID:      map[int]*Schema{},
+		latest:    map[string]cachedSchema{},
+		closeChan: make(chan struct{}),
+	}
+
+	if tout := conf.SubjectRefreshPeriod; len(tout) > 0 {
+		var err error
+		if c.refreshPeriod, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse subject refresh period string: %v", err)
+		}
+	}
+
+	hConf := client.NewConfig()
+	hConf.URL = strings.TrimSuffix(conf.URL, "/") + `${! meta("` + pathMetaKey + `") }`
+	hConf.Verb = "GET"
+	hConf.Headers = map[string]string{
+		"Accept": "application/vnd.schemaregistry.v1+json",
+	}
+	hConf.Timeout = conf.Timeout
+	hConf.TLS = conf.TLS
+	hConf.Config = conf.Config
+	hConf.DropOn = []int{404}
+
+	var err error
+	if c.http, err = client.New(
+		hConf,
+		client.OptSetCloseChan(c.closeChan),
+		client.OptSetLogger(log),
+		client.OptSetStats(metrics.Namespaced(stats, "schema_registry")),
+	); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Client) get(path string) (*Schema, error) {
+	msg := message.New([][]byte{nil})
+	msg.Get(0).Metadata().Set(pathMetaKey, path)
+
+	res, err := c.http.Send(msg)
+	if err != nil {
+		if resErr, ok := err.(types.ErrUnexpectedHTTPRes); ok && resErr.Code == 404 {
+			return nil, fmt.Errorf("schema not found at path '%v'", path)
+		}
+		return nil, err
+	}
+	if res == nil || res.Len() == 0 {
+		return nil, fmt.Errorf("empty response from schema registry at path '%v'", path)
+	}
+
+	var schema Schema
+	if err = json.Unmarshal(res.Get(0).Get(), &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema registry response: %v", err)
+	}
+	if len(schema.Type) == 0 {
+		schema.Type = TypeAvro
+	}
+	return &schema, nil
+}
+
+// GetByID returns the schema registered with an ID.
+func (c *Client) GetByID(id int) (*Schema, error) {
+	c.cacheMut.Lock()
+	schema, exists := c.byID[id]
+	c.cacheMut.Unlock()
+	if exists {
+		return schema, nil
+	}
+
+	schema, err := c.get("/schemas/ids/" + strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	schema.ID = id
+
+	c.cacheMut.Lock()
+	c.byID[id] = schema
+	c.cacheMut.Unlock()
+	return schema, nil
+}
+
+// GetBySubjectVersion returns the schema registered under a subject with a
+// specific version.
+func (c *Client) GetBySubjectVersion(subject string, version int) (*Schema, error) {
+	schema, err := c.get("/subjects/" + url.PathEscape(subject) + "/versions/" + strconv.Itoa(version))
+	if err != nil {
+		return nil, err
+	}
+
+	c.cacheMut.Lock()
+	c.byID[schema.ID] = schema
+	c.cacheMut.Unlock()
+	return schema, nil
+}
+
+// GetLatest returns the latest schema registered under a subject. When the
+// subject is empty the subject of the client config is used.
+func (c *Client) GetLatest(subject string) (*Schema, error) {
+	if len(subject) == 0 {
+		if subject = c.subject; len(subject) == 0 {
+			return nil, fmt.Errorf("a schema registry subject is required")
+		}
+	}
+
+	c.cacheMut.Lock()
+	cached, exists := c.latest[subject]
+	c.cacheMut.Unlock()
+	if exists && (c.refreshPeriod <= 0 || time.Since(cached.fetchedAt) < c.refreshPeriod) {
+		return cached.schema, nil
+	}
+
+	schema, err := c.get("/subjects/" + url.PathEscape(subject) + "/versions/latest")
+	if err != nil {
+		return nil, err
+	}
+
+	c.cacheMut.Lock()
+	c.byID[schema.ID] = schema
+	c.latest[subject] = cachedSchema{
+		schema:    schema,
+		fetchedAt: time.Now(),
+	}
+	c.cacheMut.Unlock()
+	return schema, nil
+}
+
+// Close shuts down the client, cancelling any pending requests.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+	})
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,78 @@
+package schemaregistry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientFetchAndCache(t *testing.T) {
+	var reqs int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		switch r.URL.EscapedPath() {
+		case "/schemas/ids/3":
+			w.Write([]byte(`{"schema":"\"string\""}`))
+		case "/subjects/foo%2Fbar/versions/latest":
+			w.Write([]byte(`{"subject":"foo/bar","id":4,"version":2,"schemaType":"PROTOBUF","schema":"syntax = \"proto3\";"}`))
+		case "/subjects/foo%2Fbar/versions/1":
+			w.Write([]byte(`{"subject":"foo/bar","id":2,"version":1,"schemaType":"PROTOBUF","schema":"syntax = \"proto2\";"}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/"
+	conf.Subject = "foo/bar"
+
+	c, err := New(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer c.Close()
+
+	schema, err := c.GetByID(3)
+	require.NoError(t, err)
+	assert.Equal(t, &Schema{ID: 3, Type: TypeAvro, Schema: `"string"`}, schema)
+
+	_, err = c.GetByID(3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reqs))
+
+	schema, err = c.GetLatest("")
+	require.NoError(t, err)
+	assert.Equal(t, 4, schema.ID)
+	assert.Equal(t, TypeProtobuf, schema.Type)
+
+	_, err = c.GetLatest("foo/bar")
+	require.NoError(t, err)
+	_, err = c.GetByID(4)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reqs))
+
+	schema, err = c.GetBySubjectVersion("foo/bar", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, schema.ID)
+	assert.Equal(t, `syntax = "proto2";`, schema.Schema)
+
+	_, err = c.GetByID(10)
+	assert.Error(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&reqs))
+}
+
+func TestClientBadConfig(t *testing.T) {
+	_, err := New(NewConfig(), log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf := NewConfig()
+	conf.URL = "http://localhost:8081"
+	conf.SubjectRefreshPeriod = "nope"
+	_, err = New(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrMissingMagicByte is returned when a payload does not begin with the magic
+// byte of the registry wire format.
+var ErrMissingMagicByte = errors.New("payload does not begin with the schema registry magic byte")
+
+const (
+	magicByte = byte(0)
+	headerLen = 5
+)
+
+// DecodeHeader reads the magic byte and schema ID from the beginning of a
+// payload, returning the schema ID and the remaining payload.
+func DecodeHeader(b []byte) (int, []byte, error) {
+	if len(b) < headerLen {
+		return 0, nil, fmt.Errorf("payload of length %v is too short for a schema registry header", len(b))
+	}
+	if b[0] != magicByte {
+		return 0, nil, ErrMissingMagicByte
+	}
+	return int(binary.BigEndian.Uint32(b[1:headerLen])), b[headerLen:], nil
+}
+
+// EncodeHeader returns a payload prefixed with the magic byte and schema ID.
+func EncodeHeader(id int, payload []byte) []byte {
+	b := make([]byte, headerLen, headerLen+len(payload))
+	b[0] = magicByte
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	return append(b, payload...)
+}
+
+//------------------------------------------------------------------------------
+
+// DecodeMessageIndexes reads the list of message indexes that follows the
+// header of protobuf payloads, which identifies the message type within the
+// schema by its path of indexes through the (nested) message declarations.
+// Returns the indexes and the remaining payload.
+func DecodeMessageIndexes(b []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(b)
+	if n <= 0 {
+		return nil, nil, errors.New("failed to read message index count")
+	}
+	b = b[n:]
+
+	// A count of zero is shorthand for the first message of the schema.
+	if count == 0 {
+		return []int{0}, b, nil
+	}
+	if count < 0 || count > int64(len(b)) {
+		return nil, nil, fmt.Errorf("invalid message index count: %v", count)
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(b)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("failed to read message index %v", i)
+		}
+		indexes[i] = int(index)
+		b = b[n:]
+	}
+	return indexes, b, nil
+}
+
+// EncodeMessageIndexes returns a payload prefixed with a list of message
+// indexes.
+func EncodeMessageIndexes(indexes []int, payload []byte) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append([]byte{0}, payload...)
+	}
+
+	b := make([]byte, 0, binary.MaxVarintLen64*(len(indexes)+1)+len(payload))
+	b = appendVarint(b, int64(len(indexes)))
+	for _, index := range indexes {
+		b = appendVarint(b, int64(index))
+	}
+	return append(b, payload...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+//------------------------------------------------------------------------------
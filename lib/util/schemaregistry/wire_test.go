@@ -0,0 +1,50 @@
+package schemaregistry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	b := EncodeHeader(258, []byte("foo"))
+	assert.Equal(t, []byte{0, 0, 0, 1, 2, 'f', 'o', 'o'}, b)
+
+	id, payload, err := DecodeHeader(b)
+	require.NoError(t, err)
+	assert.Equal(t, 258, id)
+	assert.Equal(t, "foo", string(payload))
+}
+
+func TestHeaderErrors(t *testing.T) {
+	_, _, err := DecodeHeader([]byte{0, 0, 0})
+	assert.Error(t, err)
+
+	_, _, err = DecodeHeader([]byte{1, 0, 0, 0, 1, 'f'})
+	assert.Equal(t, ErrMissingMagicByte, err)
+}
+
+func TestMessageIndexes(t *testing.T) {
+	tests := []struct {
+		indexes []int
+		encoded []byte
+	}{
+		{indexes: []int{0}, encoded: []byte{0}},
+		{indexes: []int{1}, encoded: []byte{2, 2}},
+		{indexes: []int{1, 0, 3}, encoded: []byte{6, 2, 0, 6}},
+	}
+
+	for _, test := range tests {
+		b := EncodeMessageIndexes(test.indexes, []byte("foo"))
+		assert.Equal(t, append(test.encoded, "foo"...), b)
+
+		indexes, payload, err := DecodeMessageIndexes(b)
+		require.NoError(t, err)
+		assert.Equal(t, test.indexes, indexes)
+		assert.Equal(t, "foo", string(payload))
+	}
+
+	_, _, err := DecodeMessageIndexes([]byte{10, 2})
+	assert.Error(t, err)
+}
@@ -120,6 +120,7 @@ module.exports = {
             'guides/streams_mode/about',
             'guides/streams_mode/using_config_files',
             'guides/streams_mode/using_rest_api',
+            'guides/streams_mode/persistence',
             'guides/streams_mode/streams_api',
           ],
         },